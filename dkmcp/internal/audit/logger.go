@@ -40,6 +40,36 @@ const (
 	// EventSecurityPolicy is logged when security policy is queried.
 	// EventSecurityPolicyはセキュリティポリシーが照会された時にログ記録されます。
 	EventSecurityPolicy EventType = "security_policy"
+
+	// EventConfigReload is logged when the configuration file is reloaded.
+	// It is always recorded because it can change what the AI may access.
+	//
+	// EventConfigReloadは設定ファイルが再読み込みされた時にログ記録されます。
+	// AIがアクセスできる範囲を変え得るため、常に記録されます。
+	EventConfigReload EventType = "config_reload"
+
+	// EventFileWrite is logged when write_file or apply_patch changes a file in a container.
+	// It is always recorded, with the diff, because it changes the container.
+	//
+	// EventFileWriteはwrite_fileまたはapply_patchがコンテナ内のファイルを変更した時に
+	// ログ記録されます。コンテナを変更するため、diffとともに常に記録されます。
+	EventFileWrite EventType = "file_write"
+
+	// EventFileCopy is logged when copy_from_container or copy_to_container moves files
+	// between the host workspace and a container. It is always recorded, with the size
+	// and SHA-256 of every file, so what left or entered a container can be verified later.
+	//
+	// EventFileCopyはcopy_from_containerまたはcopy_to_containerがホストのワークスペースと
+	// コンテナの間でファイルを移動した時にログ記録されます。コンテナから出た、または
+	// 入ったものを後で検証できるよう、すべてのファイルのサイズとSHA-256とともに常に記録されます。
+	EventFileCopy EventType = "file_copy"
+
+	// EventContainerState is logged when a watched container dies, is OOM killed or
+	// changes health, as seen on Docker's event stream.
+	//
+	// EventContainerStateは、Dockerのイベントストリームで観測された、監視対象のコンテナの
+	// 停止、OOMキル、またはヘルスの変化時にログ記録されます。
+	EventContainerState EventType = "container_state"
 )
 
 // Result represents the outcome of an operation.
@@ -50,6 +80,14 @@ const (
 	ResultSuccess Result = "success"
 	ResultDenied  Result = "denied"
 	ResultError   Result = "error"
+
+	// ResultTimeout is an operation stopped because it ran past its time limit.
+	// ResultTimeoutは時間制限を超えたために停止された操作です。
+	ResultTimeout Result = "timeout"
+
+	// ResultCancelled is an operation stopped because the client cancelled the request.
+	// ResultCancelledはクライアントがリクエストをキャンセルしたために停止された操作です。
+	ResultCancelled Result = "cancelled"
 )
 
 // Event represents an audit event.
@@ -212,6 +250,8 @@ func (l *Logger) shouldLog(eventType EventType) bool {
 		return l.cfg.Events.ClientConnections
 	case EventSecurityPolicy:
 		return l.cfg.Events.SecurityPolicy
+	case EventContainerState:
+		return l.cfg.Events.ContainerEvents
 	default:
 		return true
 	}
@@ -292,6 +332,79 @@ func LogSecurityPolicy(ctx context.Context, tool string, details map[string]any)
 	})
 }
 
+// LogConfigReload logs a configuration reload. details carries the source of the reload
+// and the settings that were loosened or tightened; errorMessage is set when the reload failed.
+//
+// LogConfigReloadは設定の再読み込みをログ記録します。detailsには再読み込みの契機と
+// 緩められた・厳しくされた設定を含めます。再読み込みが失敗した場合はerrorMessageを設定します。
+func LogConfigReload(ctx context.Context, result Result, details map[string]any, errorMessage string) {
+	if globalLogger == nil {
+		return
+	}
+	globalLogger.Log(ctx, Event{
+		Type:         EventConfigReload,
+		Result:       result,
+		Details:      details,
+		ErrorMessage: errorMessage,
+	})
+}
+
+// LogFileWrite logs a change made by write_file or apply_patch. details carries the path,
+// the backup and the diff; errorMessage is set when the write failed.
+//
+// LogFileWriteはwrite_fileまたはapply_patchによる変更をログ記録します。detailsには
+// パス、バックアップ、diffを含めます。書き込みが失敗した場合はerrorMessageを設定します。
+func LogFileWrite(ctx context.Context, tool, container string, result Result, details map[string]any, errorMessage string) {
+	if globalLogger == nil {
+		return
+	}
+	globalLogger.Log(ctx, Event{
+		Type:         EventFileWrite,
+		Tool:         tool,
+		Container:    container,
+		Result:       result,
+		Details:      details,
+		ErrorMessage: errorMessage,
+	})
+}
+
+// LogFileCopy logs files moved by copy_from_container or copy_to_container. details
+// carries the paths and the size and hash of each file; errorMessage is set when the
+// copy failed.
+//
+// LogFileCopyはcopy_from_containerまたはcopy_to_containerが移動したファイルを
+// ログ記録します。detailsにはパスと各ファイルのサイズとハッシュを含めます。コピーが
+// 失敗した場合はerrorMessageを設定します。
+func LogFileCopy(ctx context.Context, tool, container string, result Result, details map[string]any, errorMessage string) {
+	if globalLogger == nil {
+		return
+	}
+	globalLogger.Log(ctx, Event{
+		Type:         EventFileCopy,
+		Tool:         tool,
+		Container:    container,
+		Result:       result,
+		Details:      details,
+		ErrorMessage: errorMessage,
+	})
+}
+
+// LogContainerState logs a state change of a container from Docker's event stream.
+// details carries the action, exit code and health.
+//
+// LogContainerStateはDockerのイベントストリームからのコンテナの状態変化をログ記録します。
+// detailsにはアクション、終了コード、ヘルスを含めます。
+func LogContainerState(ctx context.Context, container string, details map[string]any) {
+	if globalLogger == nil {
+		return
+	}
+	globalLogger.Log(ctx, Event{
+		Type:      EventContainerState,
+		Container: container,
+		Details:   details,
+	})
+}
+
 // MeasureDuration is a helper to measure operation duration.
 // MeasureDurationは操作の所要時間を計測するヘルパーです。
 func MeasureDuration(start time.Time) int64 {
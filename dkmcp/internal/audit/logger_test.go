@@ -253,6 +253,36 @@ func TestShouldLog(t *testing.T) {
 			events:    config.AuditEvents{SecurityPolicy: false},
 			want:      false,
 		},
+		{
+			name:      "container_state enabled",
+			eventType: EventContainerState,
+			events:    config.AuditEvents{ContainerEvents: true},
+			want:      true,
+		},
+		{
+			name:      "container_state disabled",
+			eventType: EventContainerState,
+			events:    config.AuditEvents{ContainerEvents: false},
+			want:      false,
+		},
+		{
+			name:      "config_reload always logged",
+			eventType: EventConfigReload,
+			events:    config.AuditEvents{},
+			want:      true,
+		},
+		{
+			name:      "file_write always logged",
+			eventType: EventFileWrite,
+			events:    config.AuditEvents{},
+			want:      true,
+		},
+		{
+			name:      "file_copy always logged",
+			eventType: EventFileCopy,
+			events:    config.AuditEvents{},
+			want:      true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -313,6 +343,15 @@ func TestGlobalFunctions(t *testing.T) {
 	// Test LogSecurityPolicy
 	LogSecurityPolicy(ctx, "get_security_policy", nil)
 
+	// Test LogConfigReload
+	LogConfigReload(ctx, ResultSuccess, map[string]any{"source": "file"}, "")
+
+	// Test LogFileWrite
+	LogFileWrite(ctx, "apply_patch", "api", ResultSuccess, map[string]any{"path": "/app/config.yaml"}, "")
+
+	// Test LogFileCopy
+	LogFileCopy(ctx, "copy_from_container", "api", ResultSuccess, map[string]any{"path": "/app/heap.hprof"}, "")
+
 	// Verify logger was set
 	if GetLogger() == nil {
 		t.Error("expected global logger to be set")
@@ -342,9 +381,9 @@ func TestGlobalFunctions(t *testing.T) {
 		}
 	}
 
-	// Verify all 5 event types were logged
-	// 5つのイベントタイプ全てがログされたことを確認
-	expectedEvents := []string{"tool_call", "access_denied", "client_connect", "client_disconnect", "security_policy"}
+	// Verify all 8 event types were logged
+	// 8つのイベントタイプ全てがログされたことを確認
+	expectedEvents := []string{"tool_call", "access_denied", "client_connect", "client_disconnect", "security_policy", "config_reload", "file_write", "file_copy"}
 	for _, expected := range expectedEvents {
 		if !eventTypes[expected] {
 			t.Errorf("expected event type %q not found in log file", expected)
@@ -374,6 +413,9 @@ func TestNilLoggerSafety(t *testing.T) {
 	LogClientConnect(ctx, "client", "session")
 	LogClientDisconnect(ctx, "client", "session", 0)
 	LogSecurityPolicy(ctx, "tool", nil)
+	LogConfigReload(ctx, ResultError, nil, "reason")
+	LogFileWrite(ctx, "write_file", "api", ResultError, nil, "reason")
+	LogFileCopy(ctx, "copy_to_container", "api", ResultError, nil, "reason")
 
 	var nilLogger *Logger
 	nilLogger.Log(ctx, Event{Type: EventToolCall})
@@ -8,6 +8,7 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -53,7 +54,9 @@ type Backend interface {
 	// ホワイトリストに登録されたコマンドのみが許可されます（セキュリティポリシーで強制）。
 	// dangerouslyがtrueの場合、exec_dangerouslyリストのコマンドが
 	// blocked_pathsに対するファイルパス検証付きで許可されます。
-	Exec(ctx context.Context, container string, command string, dangerously bool) (*docker.ExecResult, error)
+	// opts sets the working directory, environment and user, within exec_options.
+	// optsはexec_optionsの範囲内で作業ディレクトリ、環境、ユーザーを設定します。
+	Exec(ctx context.Context, container string, command string, dangerously bool, opts security.ExecOptions) (*docker.ExecResult, error)
 
 	// Close releases any resources held by the backend.
 	// Should be called when the backend is no longer needed.
@@ -135,8 +138,8 @@ func (b *DirectBackend) GetLogs(ctx context.Context, container string, tail stri
 // Execは指定されたコンテナでコマンドを実行します。
 // コマンドはセキュリティポリシーでホワイトリストに登録されている必要があります。
 // dangerouslyがtrueの場合、exec_dangerouslyリストのコマンドが許可されます。
-func (b *DirectBackend) Exec(ctx context.Context, container string, command string, dangerously bool) (*docker.ExecResult, error) {
-	return b.docker.Exec(ctx, container, command, dangerously)
+func (b *DirectBackend) Exec(ctx context.Context, container string, command string, dangerously bool, opts security.ExecOptions) (*docker.ExecResult, error) {
+	return b.docker.Exec(ctx, container, command, dangerously, opts)
 }
 
 // Close closes the Docker client and releases associated resources.
@@ -179,6 +182,26 @@ func NewHTTPBackend(url string) (*HTTPBackend, error) {
 // NewHTTPBackendWithSuffixはオプションのクライアントサフィックス付きで新しいHTTPBackendを作成します。
 // サフィックスは識別目的でクライアント名に追加されます。
 func NewHTTPBackendWithSuffix(url string, suffix string) (*HTTPBackend, error) {
+	return newHTTPBackend(url, suffix, "")
+}
+
+// NewHTTPBackendResuming creates a new HTTPBackend on the Streamable HTTP session
+// sessionID when the server still has it, and on a new session otherwise.
+// SessionID tells which one was used.
+//
+// NewHTTPBackendResumingは、サーバーがまだStreamable HTTPセッションsessionIDを持っていれば
+// そのセッション上に、そうでなければ新しいセッション上に新しいHTTPBackendを作成します。
+// どちらが使われたかはSessionIDで分かります。
+func NewHTTPBackendResuming(url string, sessionID string) (*HTTPBackend, error) {
+	return newHTTPBackend(url, clientSuffix, sessionID)
+}
+
+// newHTTPBackend creates an HTTPBackend, resuming sessionID when it is set and still
+// known to the server.
+//
+// newHTTPBackendはHTTPBackendを作成し、sessionIDが設定されていてサーバーがまだ
+// 認識している場合はそれを再開します。
+func newHTTPBackend(url string, suffix string, sessionID string) (*HTTPBackend, error) {
 	// Create a new HTTP client for the specified URL.
 	// 指定されたURLの新しいHTTPクライアントを作成します。
 	c := client.NewClient(url)
@@ -188,6 +211,9 @@ func NewHTTPBackendWithSuffix(url string, suffix string) (*HTTPBackend, error) {
 	if suffix != "" {
 		c.SetClientSuffix(suffix)
 	}
+	if clientTransport != "" {
+		c.SetTransport(clientTransport)
+	}
 
 	// Perform health check to verify server is running.
 	// サーバーが実行中であることを確認するためにヘルスチェックを実行します。
@@ -196,8 +222,14 @@ func NewHTTPBackendWithSuffix(url string, suffix string) (*HTTPBackend, error) {
 		return nil, fmt.Errorf("server health check failed: %w", err)
 	}
 
-	// Establish SSE connection for MCP communication.
-	// MCP通信用のSSE接続を確立します。
+	// Resume the given session if the server still has it
+	// サーバーがまだ持っていれば指定されたセッションを再開します
+	if sessionID != "" && c.Resume(sessionID) == nil {
+		return &HTTPBackend{client: c}, nil
+	}
+
+	// Establish MCP connection (Streamable HTTP or SSE, per --transport).
+	// MCP接続を確立します（--transportに従いStreamable HTTPまたはSSE）。
 	if err := c.Connect(); err != nil {
 		c.Close()
 		return nil, fmt.Errorf("failed to connect to server: %w", err)
@@ -207,10 +239,10 @@ func NewHTTPBackendWithSuffix(url string, suffix string) (*HTTPBackend, error) {
 }
 
 // ListContainers retrieves the container list via the MCP 'list_containers' tool.
-// It parses the JSON response and returns the container information.
+// It reads the structured result, or parses the JSON text from older servers.
 //
 // ListContainersはMCPの'list_containers'ツール経由でコンテナリストを取得します。
-// JSONレスポンスを解析してコンテナ情報を返します。
+// 構造化された結果を読み取るか、古いサーバーの場合はJSONテキストを解析します。
 func (b *HTTPBackend) ListContainers(ctx context.Context) ([]docker.ContainerInfo, error) {
 	// Call the list_containers MCP tool.
 	// list_containers MCPツールを呼び出します。
@@ -219,6 +251,18 @@ func (b *HTTPBackend) ListContainers(ctx context.Context) ([]docker.ContainerInf
 		return nil, fmt.Errorf("failed to list containers: %w", err)
 	}
 
+	// Prefer the structured result.
+	// 構造化された結果を優先します。
+	if len(resp.StructuredContent) > 0 {
+		var result struct {
+			Containers []docker.ContainerInfo `json:"containers"`
+		}
+		if err := json.Unmarshal(resp.StructuredContent, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		return result.Containers, nil
+	}
+
 	// Handle empty response.
 	// 空のレスポンスを処理します。
 	if len(resp.Content) == 0 {
@@ -267,11 +311,98 @@ func (b *HTTPBackend) GetLogs(ctx context.Context, container string, tail string
 	return resp.Content[0].Text, nil
 }
 
-// parseExitCode extracts the exit code from MCP response text.
+// CorrelateLogs retrieves logs from several containers merged by timestamp via the
+// MCP 'correlate_logs' tool. An empty containers list selects all running accessible containers.
+//
+// CorrelateLogsはMCPの'correlate_logs'ツール経由で、複数コンテナのログをタイムスタンプで
+// マージして取得します。containersが空の場合、アクセス可能な実行中のすべてのコンテナを選択します。
+func (b *HTTPBackend) CorrelateLogs(ctx context.Context, containers []string, tail string, since string) (string, error) {
+	// Prepare arguments for the correlate_logs tool.
+	// correlate_logsツールの引数を準備します。
+	arguments := map[string]interface{}{
+		"tail": tail,
+	}
+	if len(containers) > 0 {
+		arguments["containers"] = containers
+	}
+	if since != "" {
+		arguments["since"] = since
+	}
+
+	resp, err := b.client.CallTool("correlate_logs", arguments)
+	if err != nil {
+		return "", fmt.Errorf("failed to correlate logs: %w", err)
+	}
+
+	// Handle empty response.
+	// 空のレスポンスを処理します。
+	if len(resp.Content) == 0 {
+		return "", nil
+	}
+
+	return resp.Content[0].Text, nil
+}
+
+// FollowLogs follows container logs via the MCP 'follow_logs' tool.
+// Each chunk of new lines streamed by the server is passed to onChunk as it arrives.
+// Following stops after durationSeconds, or earlier when ctx is cancelled.
+// The final tool result text (summary and all collected lines) is returned.
+//
+// FollowLogsはMCPの'follow_logs'ツール経由でコンテナログをフォローします。
+// サーバーがストリームする新しい行のチャンクは到着した時点でonChunkに渡されます。
+// フォローはdurationSeconds後、またはctxがキャンセルされた時点で停止します。
+// 最終的なツール結果のテキスト（概要と収集したすべての行）を返します。
+func (b *HTTPBackend) FollowLogs(ctx context.Context, container string, tail string, since string, durationSeconds int, onChunk func(string)) (string, error) {
+	// Prepare arguments for the follow_logs tool.
+	// follow_logsツールの引数を準備します。
+	arguments := map[string]interface{}{
+		"container":        container,
+		"tail":             tail,
+		"duration_seconds": durationSeconds,
+	}
+	if since != "" {
+		arguments["since"] = since
+	}
+
+	// Chunks arrive as progress messages, or as log messages from servers that ignore the progress token.
+	// チャンクは進捗メッセージ、または進捗トークンを無視するサーバーではログメッセージとして届きます。
+	resp, err := b.client.CallToolStream(ctx, "follow_logs", arguments, func(n client.Notification) {
+		var params struct {
+			Message string `json:"message"`
+			Data    struct {
+				Lines string `json:"lines"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(n.Params, &params); err != nil {
+			return
+		}
+		switch n.Method {
+		case "notifications/progress":
+			onChunk(params.Message)
+		case "notifications/message":
+			onChunk(params.Data.Lines)
+		}
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to follow logs: %w", err)
+	}
+
+	// Handle empty response.
+	// 空のレスポンスを処理します。
+	if len(resp.Content) == 0 {
+		return "", nil
+	}
+
+	return resp.Content[0].Text, nil
+}
+
+// parseExitCode extracts the exit code from MCP response text, for servers that do not
+// return structured results.
 // The expected format is: "Command: ...\nExit Code: N\n\nOutput:\n..."
 // Returns 0 if the exit code cannot be parsed (assumes success).
 //
-// parseExitCodeはMCPレスポンステキストから終了コードを抽出します。
+// parseExitCodeは構造化された結果を返さないサーバーのために、MCPレスポンステキストから
+// 終了コードを抽出します。
 // 期待される形式は："Command: ...\nExit Code: N\n\nOutput:\n..."
 // 終了コードを解析できない場合は0を返します（成功と見なす）。
 func parseExitCode(text string) int {
@@ -294,7 +425,7 @@ func parseExitCode(text string) int {
 // コマンドはサーバー側でホワイトリストに登録されている必要があります。
 // dangerouslyがtrueの場合、exec_dangerouslyリストのコマンドが許可されます。
 // 終了コードと出力を含む実行結果を返します。
-func (b *HTTPBackend) Exec(ctx context.Context, container string, command string, dangerously bool) (*docker.ExecResult, error) {
+func (b *HTTPBackend) Exec(ctx context.Context, container string, command string, dangerously bool, opts security.ExecOptions) (*docker.ExecResult, error) {
 	// Prepare arguments for the exec_command tool.
 	// exec_commandツールの引数を準備します。
 	arguments := map[string]interface{}{
@@ -302,6 +433,7 @@ func (b *HTTPBackend) Exec(ctx context.Context, container string, command string
 		"command":     command,
 		"dangerously": dangerously,
 	}
+	addExecOptionArguments(arguments, opts)
 
 	// Call the exec_command MCP tool.
 	// exec_command MCPツールを呼び出します。
@@ -310,6 +442,18 @@ func (b *HTTPBackend) Exec(ctx context.Context, container string, command string
 		return nil, fmt.Errorf("failed to execute command: %w", err)
 	}
 
+	// Prefer the structured result, which carries the exit code, the bare output split
+	// into stdout and stderr (from newer servers), the duration and the truncation flag.
+	// 終了コード、標準出力と標準エラー出力に分かれた出力そのもの（新しいサーバーの場合）、
+	// 実行時間、打ち切りフラグを運ぶ構造化された結果を優先します。
+	if len(resp.StructuredContent) > 0 {
+		var result docker.ExecResult
+		if err := json.Unmarshal(resp.StructuredContent, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		return &result, nil
+	}
+
 	// Handle empty response with success status.
 	// 空のレスポンスを成功ステータスで処理します。
 	if len(resp.Content) == 0 {
@@ -332,11 +476,26 @@ func (b *HTTPBackend) Close() error {
 	return b.client.Close()
 }
 
+// SessionID returns the MCP session ID of the backend's connection.
+// SessionIDはバックエンドの接続のMCPセッションIDを返します。
+func (b *HTTPBackend) SessionID() string {
+	return b.client.SessionID()
+}
+
+// Detach closes the connection but leaves a Streamable HTTP session open on the server,
+// so that NewHTTPBackendResuming can continue it.
+//
+// Detachは接続を閉じますが、NewHTTPBackendResumingが継続できるように
+// Streamable HTTPセッションをサーバー上で開いたままにします。
+func (b *HTTPBackend) Detach() error {
+	return b.client.Detach()
+}
+
 // GetStats retrieves container statistics via the MCP 'get_stats' tool.
-// Returns the raw JSON response from the server.
+// Returns the statistics as indented JSON.
 //
 // GetStatsはMCPの'get_stats'ツール経由でコンテナ統計を取得します。
-// サーバーからの生のJSONレスポンスを返します。
+// 統計をインデント付きJSONで返します。
 func (b *HTTPBackend) GetStats(ctx context.Context, container string) (string, error) {
 	// Prepare arguments for the get_stats tool.
 	// get_statsツールの引数を準備します。
@@ -351,6 +510,12 @@ func (b *HTTPBackend) GetStats(ctx context.Context, container string) (string, e
 		return "", fmt.Errorf("failed to get stats: %w", err)
 	}
 
+	// Prefer the structured result.
+	// 構造化された結果を優先します。
+	if text, err := structuredField(resp, "stats"); err != nil || text != "" {
+		return text, err
+	}
+
 	// Handle empty response.
 	// 空のレスポンスを処理します。
 	if len(resp.Content) == 0 {
@@ -361,10 +526,10 @@ func (b *HTTPBackend) GetStats(ctx context.Context, container string) (string, e
 }
 
 // InspectContainer retrieves container details via the MCP 'inspect_container' tool.
-// Returns the raw JSON response from the server.
+// Returns the container details as indented JSON.
 //
 // InspectContainerはMCPの'inspect_container'ツール経由でコンテナ詳細を取得します。
-// サーバーからの生のJSONレスポンスを返します。
+// コンテナ詳細をインデント付きJSONで返します。
 func (b *HTTPBackend) InspectContainer(ctx context.Context, container string) (string, error) {
 	// Prepare arguments for the inspect_container tool.
 	// inspect_containerツールの引数を準備します。
@@ -379,6 +544,12 @@ func (b *HTTPBackend) InspectContainer(ctx context.Context, container string) (s
 		return "", fmt.Errorf("failed to inspect container: %w", err)
 	}
 
+	// Prefer the structured result.
+	// 構造化された結果を優先します。
+	if text, err := structuredField(resp, "info"); err != nil || text != "" {
+		return text, err
+	}
+
 	// Handle empty response.
 	// 空のレスポンスを処理します。
 	if len(resp.Content) == 0 {
@@ -516,6 +687,133 @@ func (b *HTTPBackend) StartContainer(ctx context.Context, container string) (str
 	return resp.Content[0].Text, nil
 }
 
+// JobStatus is an exec job as reported by the DockMCP job tools. The output fields are
+// only set by get_job_output.
+//
+// JobStatusはDockMCPのジョブツールが報告するexecジョブです。出力のフィールドは
+// get_job_outputのみが設定します。
+type JobStatus struct {
+	JobID       string `json:"job_id"`
+	Container   string `json:"container"`
+	Command     string `json:"command"`
+	State       string `json:"state"`
+	StartedAt   string `json:"started_at"`
+	EndedAt     string `json:"ended_at,omitempty"`
+	DurationMs  int64  `json:"duration_ms"`
+	ExitCode    *int   `json:"exit_code,omitempty"`
+	OutputBytes int    `json:"output_bytes"`
+	Truncated   bool   `json:"truncated"`
+	Error       string `json:"error,omitempty"`
+
+	Offset     int    `json:"offset"`
+	NextOffset int    `json:"next_offset"`
+	Complete   bool   `json:"complete"`
+	Output     string `json:"output"`
+}
+
+// StartExecJob starts a command as a background job via the MCP 'start_exec_job' tool.
+// StartExecJobはMCPの'start_exec_job'ツール経由でコマンドをバックグラウンドジョブとして開始します。
+func (b *HTTPBackend) StartExecJob(ctx context.Context, container string, command string, dangerously bool, opts security.ExecOptions) (*JobStatus, error) {
+	arguments := map[string]interface{}{
+		"container":   container,
+		"command":     command,
+		"dangerously": dangerously,
+	}
+	addExecOptionArguments(arguments, opts)
+
+	var job JobStatus
+	err := b.callJobTool("start_exec_job", arguments, &job)
+	return &job, err
+}
+
+// addExecOptionArguments adds the workdir, env and user arguments of exec_command and
+// start_exec_job for the options that are set.
+//
+// addExecOptionArgumentsは設定されているオプションについて、exec_commandと
+// start_exec_jobのworkdir、env、user引数を追加します。
+func addExecOptionArguments(arguments map[string]interface{}, opts security.ExecOptions) {
+	if opts.Workdir != "" {
+		arguments["workdir"] = opts.Workdir
+	}
+	if len(opts.Env) > 0 {
+		arguments["env"] = opts.Env
+	}
+	if opts.User != "" {
+		arguments["user"] = opts.User
+	}
+}
+
+// GetJobOutput reads a job's state and output from offset via the MCP 'get_job_output' tool.
+// GetJobOutputはMCPの'get_job_output'ツール経由でoffsetからジョブの状態と出力を読み取ります。
+func (b *HTTPBackend) GetJobOutput(ctx context.Context, jobID string, offset int) (*JobStatus, error) {
+	var job JobStatus
+	err := b.callJobTool("get_job_output", map[string]interface{}{"job_id": jobID, "offset": offset}, &job)
+	return &job, err
+}
+
+// CancelJob cancels a job via the MCP 'cancel_job' tool.
+// CancelJobはMCPの'cancel_job'ツール経由でジョブをキャンセルします。
+func (b *HTTPBackend) CancelJob(ctx context.Context, jobID string) (*JobStatus, error) {
+	var job JobStatus
+	err := b.callJobTool("cancel_job", map[string]interface{}{"job_id": jobID}, &job)
+	return &job, err
+}
+
+// ListJobs lists this client's jobs via the MCP 'list_jobs' tool.
+// ListJobsはMCPの'list_jobs'ツール経由でこのクライアントのジョブを一覧表示します。
+func (b *HTTPBackend) ListJobs(ctx context.Context) ([]JobStatus, error) {
+	var result struct {
+		Jobs []JobStatus `json:"jobs"`
+	}
+	err := b.callJobTool("list_jobs", map[string]interface{}{}, &result)
+	return result.Jobs, err
+}
+
+// callJobTool calls a job tool and decodes its structured result into v. The job tools
+// always return structured content, so a server without it does not support jobs.
+//
+// callJobToolはジョブツールを呼び出し、構造化された結果をvにデコードします。ジョブツールは
+// 常に構造化コンテンツを返すため、それがないサーバーはジョブに対応していません。
+func (b *HTTPBackend) callJobTool(name string, arguments map[string]interface{}, v any) error {
+	resp, err := b.client.CallTool(name, arguments)
+	if err != nil {
+		return err
+	}
+	if len(resp.StructuredContent) == 0 {
+		return fmt.Errorf("the server does not support exec jobs (no structured result from %s)", name)
+	}
+	if err := json.Unmarshal(resp.StructuredContent, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// structuredField returns a field of a tool result's structuredContent as indented JSON.
+// It returns "" when the server sent no structured content, so the caller can fall back
+// to the text content.
+//
+// structuredFieldはツール結果のstructuredContentのフィールドをインデント付きJSONで返します。
+// サーバーが構造化コンテンツを送らなかった場合は""を返し、呼び出し元がテキストコンテンツに
+// フォールバックできるようにします。
+func structuredField(resp *client.ToolResult, name string) (string, error) {
+	if len(resp.StructuredContent) == 0 {
+		return "", nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(resp.StructuredContent, &fields); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	raw, ok := fields[name]
+	if !ok {
+		return "", fmt.Errorf("response has no %s field", name)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return "", fmt.Errorf("failed to format %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
 // parseJSON is a helper function to parse JSON string into a Go value.
 // It wraps json.Unmarshal for convenience.
 //
@@ -4,7 +4,10 @@
 package cli
 
 import (
+	"encoding/json"
 	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/client"
 )
 
 // TestParseExitCode tests the parseExitCode function.
@@ -79,3 +82,30 @@ func TestParseExitCode(t *testing.T) {
 		})
 	}
 }
+
+// TestStructuredField tests reading a field of a tool result's structuredContent.
+//
+// TestStructuredFieldはツール結果のstructuredContentのフィールドの読み取りをテストします。
+func TestStructuredField(t *testing.T) {
+	tests := []struct {
+		name       string // Test case name / テストケース名
+		structured string // structuredContent ("" = absent) / structuredContent（""は無し）
+		want       string // Expected text / 期待されるテキスト
+		wantErr    bool   // Whether an error is expected / エラーを期待するか
+	}{
+		{"absent", "", "", false},
+		{"object field", `{"container":"api","stats":{"cpu":1}}`, "{\n  \"cpu\": 1\n}", false},
+		{"missing field", `{"container":"api"}`, "", true},
+		{"not an object", `[1,2]`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &client.ToolResult{StructuredContent: json.RawMessage(tt.structured)}
+			got, err := structuredField(resp, "stats")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("structuredField() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -8,8 +8,10 @@
 package cli
 
 import (
+	"fmt"
 	"os"
 
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/client"
 	"github.com/spf13/cobra"
 )
 
@@ -36,6 +38,15 @@ var (
 	// フラグが環境変数より優先されます。
 	clientSuffix string
 
+	// clientTransport selects the MCP transport used to reach the server:
+	// "auto" (Streamable HTTP with fallback to SSE), "http" (Streamable HTTP only) or "sse" (legacy SSE only).
+	// Can be set via --transport flag or DOCKMCP_TRANSPORT environment variable.
+	//
+	// clientTransportはサーバーへの接続に使用するMCPトランスポートを選択します：
+	// "auto"（SSEへのフォールバック付きStreamable HTTP）、"http"（Streamable HTTPのみ）、"sse"（レガシーSSEのみ）。
+	// --transportフラグまたはDOCKMCP_TRANSPORT環境変数で設定できます。
+	clientTransport string
+
 	// clientCmd is the parent command for all client subcommands.
 	// It groups commands that communicate with the DockMCP server via HTTP/MCP.
 	//
@@ -70,6 +81,19 @@ where the Docker socket is not available.`,
 					clientSuffix = envSuffix
 				}
 			}
+			// Fall back to DOCKMCP_TRANSPORT if --transport was not explicitly set.
+			// --transportが明示的に指定されていない場合、DOCKMCP_TRANSPORTにフォールバックします。
+			if !cmd.Flags().Changed("transport") {
+				if envTransport := os.Getenv("DOCKMCP_TRANSPORT"); envTransport != "" {
+					clientTransport = envTransport
+				}
+			}
+			switch clientTransport {
+			case client.TransportAuto, client.TransportHTTP, client.TransportSSE:
+			default:
+				return fmt.Errorf("invalid transport %q: must be %q, %q or %q",
+					clientTransport, client.TransportAuto, client.TransportHTTP, client.TransportSSE)
+			}
 			return nil
 		},
 	}
@@ -103,4 +127,10 @@ func init() {
 	clientCmd.PersistentFlags().StringVarP(&clientSuffix, "client-suffix", "s", "",
 		"Suffix to append to client name (e.g., 'user-cli' becomes 'dkmcp-go-client_user-cli')\n"+
 			"Can also be set via DOCKMCP_CLIENT_SUFFIX environment variable")
+
+	// Add --transport flag to choose between Streamable HTTP and legacy SSE.
+	// Streamable HTTPとレガシーSSEを選択するための--transportフラグを追加します。
+	clientCmd.PersistentFlags().StringVar(&clientTransport, "transport", client.TransportAuto,
+		"MCP transport: auto (Streamable HTTP, falling back to SSE), http, or sse\n"+
+			"Can also be set via DOCKMCP_TRANSPORT environment variable")
 }
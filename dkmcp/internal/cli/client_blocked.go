@@ -55,6 +55,9 @@ func runClientBlocked(cmd *cobra.Command, args []string) error {
 	if clientSuffix != "" {
 		c.SetClientSuffix(clientSuffix)
 	}
+	if clientTransport != "" {
+		c.SetTransport(clientTransport)
+	}
 	defer c.Close()
 
 	// Perform health check to verify server is running.
@@ -63,8 +66,8 @@ func runClientBlocked(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("server health check failed: %w", err)
 	}
 
-	// Establish SSE connection for MCP communication.
-	// MCP通信用のSSE接続を確立します。
+	// Establish MCP connection (Streamable HTTP or SSE, per --transport).
+	// MCP接続を確立します（--transportに従いStreamable HTTPまたはSSE）。
 	if err := c.Connect(); err != nil {
 		return fmt.Errorf("failed to connect to server: %w", err)
 	}
@@ -28,7 +28,7 @@ var clientCommandsCmd = &cobra.Command{
 	Long: `List the whitelisted commands that can be executed in a container.
 
 If no container is specified, shows allowed commands for all containers.
-Commands with '*' wildcard match any suffix (e.g., 'echo *' matches 'echo hello').`,
+Commands are matched argument by argument after quote parsing; '*' matches within an argument, and a trailing '*' also accepts further arguments (e.g., 'npm run *' matches 'npm run test --watch').`,
 	RunE: runClientCommands,
 }
 
@@ -53,6 +53,9 @@ func runClientCommands(cmd *cobra.Command, args []string) error {
 	if clientSuffix != "" {
 		c.SetClientSuffix(clientSuffix)
 	}
+	if clientTransport != "" {
+		c.SetTransport(clientTransport)
+	}
 	defer c.Close()
 
 	// Perform health check to verify server is running.
@@ -61,8 +64,8 @@ func runClientCommands(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("server health check failed: %w", err)
 	}
 
-	// Establish SSE connection for MCP communication.
-	// MCP通信用のSSE接続を確立します。
+	// Establish MCP connection (Streamable HTTP or SSE, per --transport).
+	// MCP接続を確立します（--transportに従いStreamable HTTPまたはSSE）。
 	if err := c.Connect(); err != nil {
 		return fmt.Errorf("failed to connect to server: %w", err)
 	}
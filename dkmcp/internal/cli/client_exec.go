@@ -10,8 +10,13 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
 )
 
 // clientExecCmd represents the 'client exec' subcommand.
@@ -40,6 +45,35 @@ func init() {
 	// Add --dangerously flag for dangerous mode execution
 	// 危険モード実行用の--dangerouslyフラグを追加
 	clientExecCmd.Flags().Bool("dangerously", false, "Enable dangerous mode to execute commands from exec_dangerously list (file paths are still checked against blocked_paths)")
+	addExecOptionFlags(clientExecCmd)
+}
+
+// addExecOptionFlags adds the --workdir, --env and --user flags of the exec commands.
+// addExecOptionFlagsはexecコマンドの--workdir、--env、--userフラグを追加します。
+func addExecOptionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("workdir", "w", "", "Working directory in the container (must be allowed by security.exec_options)")
+	cmd.Flags().StringArrayP("env", "e", nil, "Environment variable NAME=value to set, repeatable (must be allowed by security.exec_options)")
+	cmd.Flags().StringP("user", "u", "", "User to run the command as (must be allowed by security.exec_options)")
+}
+
+// execOptionsFromFlags reads the options set by addExecOptionFlags.
+// execOptionsFromFlagsはaddExecOptionFlagsで設定されたオプションを読み取ります。
+func execOptionsFromFlags(cmd *cobra.Command) (security.ExecOptions, error) {
+	var opts security.ExecOptions
+	opts.Workdir, _ = cmd.Flags().GetString("workdir")
+	opts.User, _ = cmd.Flags().GetString("user")
+	env, _ := cmd.Flags().GetStringArray("env")
+	for _, entry := range env {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return opts, fmt.Errorf("invalid --env %q (expected NAME=value)", entry)
+		}
+		if opts.Env == nil {
+			opts.Env = make(map[string]string)
+		}
+		opts.Env[name] = value
+	}
+	return opts, nil
 }
 
 // runClientExec is the execution function for the client exec subcommand.
@@ -61,6 +95,10 @@ func runClientExec(cmd *cobra.Command, args []string) error {
 	// Get the dangerously flag value.
 	// dangerouslyフラグの値を取得します。
 	dangerously, _ := cmd.Flags().GetBool("dangerously")
+	opts, err := execOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Create an HTTPBackend for remote DockMCP server access.
 	// リモートDockMCPサーバーアクセス用のHTTPBackendを作成します。
@@ -78,28 +116,47 @@ func runClientExec(cmd *cobra.Command, args []string) error {
 	// サーバーはコマンドがホワイトリストに登録されているかを検証します。
 	// dangerouslyがtrueの場合、exec_dangerouslyコマンドが許可されます。
 	ctx := context.Background()
-	result, err := backend.Exec(ctx, containerName, command, dangerously)
+	result, err := backend.Exec(ctx, containerName, command, dangerously, opts)
 	if err != nil {
 		return fmt.Errorf("failed to execute command: %w", err)
 	}
 
-	// Print the command output.
-	// Using Print (not Println) to avoid double newline if output ends with newline.
-	//
-	// コマンド出力を表示します。
-	// 出力が改行で終わる場合の二重改行を避けるためPrint（Printlnではなく）を使用します。
-	if result.Output != "" {
-		fmt.Print(result.Output)
-	}
+	// Print the command output: stdout and stderr go to the matching streams.
+	// コマンド出力を表示します：標準出力と標準エラー出力はそれぞれ対応するストリームに出力します。
+	printExecResult(os.Stdout, os.Stderr, result)
 
-	// Return error if command exited with non-zero exit code.
+	// Return error if command timed out or exited with non-zero exit code.
 	// This ensures the CLI exits with non-zero status for failed commands.
 	//
-	// コマンドが非ゼロの終了コードで終了した場合はエラーを返します。
+	// コマンドがタイムアウトした、または非ゼロの終了コードで終了した場合はエラーを返します。
 	// これにより、失敗したコマンドに対してCLIが非ゼロステータスで終了します。
+	if result.TimedOut {
+		return fmt.Errorf("command timed out after %dms and was killed (security.exec_timeout)", result.DurationMs)
+	}
 	if result.ExitCode != 0 {
 		return fmt.Errorf("command exited with code %d", result.ExitCode)
 	}
 
 	return nil
 }
+
+// printExecResult writes the command's stdout to stdout and its stderr to stderr, and
+// notes on stderr when the server truncated the output. Servers that do not split the
+// streams only return the combined output, which is written to stdout.
+// Using Fprint (not Fprintln) avoids a double newline when output ends with a newline.
+//
+// printExecResultはコマンドの標準出力をstdoutに、標準エラー出力をstderrに書き込み、
+// サーバーが出力を打ち切った場合はstderrにその旨を書きます。ストリームを分けない
+// サーバーは組み合わせた出力のみを返すため、それをstdoutに書き込みます。
+// 出力が改行で終わる場合の二重改行を避けるためFprint（Fprintlnではなく）を使用します。
+func printExecResult(stdout, stderr io.Writer, result *docker.ExecResult) {
+	if result.Stdout == "" && result.Stderr == "" {
+		fmt.Fprint(stdout, result.Output)
+	} else {
+		fmt.Fprint(stdout, result.Stdout)
+		fmt.Fprint(stderr, result.Stderr)
+	}
+	if result.Truncated {
+		fmt.Fprintln(stderr, "dkmcp: output truncated at the server's exec_output limit")
+	}
+}
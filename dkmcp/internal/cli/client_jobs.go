@@ -0,0 +1,346 @@
+// client_jobs.go implements the 'client jobs' subcommands for exec jobs via HTTP.
+// Jobs run long commands in the background on the DockMCP server; these commands start,
+// follow, cancel and list them.
+//
+// client_jobs.goはHTTP経由でexecジョブを扱う'client jobs'サブコマンドを実装します。
+// ジョブはDockMCPサーバー上で長時間のコマンドをバックグラウンドで実行します。これらの
+// コマンドはジョブの開始、追跡、キャンセル、一覧表示を行います。
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/client"
+	"github.com/spf13/cobra"
+)
+
+// clientJobsCmd is the parent command for exec job subcommands.
+// clientJobsCmdはexecジョブサブコマンドの親コマンドです。
+var clientJobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Exec job commands via DockMCP server",
+	Long: `Commands for running long whitelisted commands as background jobs through the
+DockMCP server. Jobs belong to the MCP session that started them, so these commands
+keep their Streamable HTTP session open on the server and save its ID in
+~/.dkmcp/jobs-session; the next jobs command for the same server resumes it. The
+server expires the session after 30 minutes without requests.
+
+Examples:
+  dkmcp client jobs start --follow securenote-api "npm test"
+  dkmcp client jobs start -w /app/packages/web securenote-api "npm test"
+  dkmcp client jobs output --follow job-1a2b3c4d5e6f
+  dkmcp client jobs cancel job-1a2b3c4d5e6f
+  dkmcp client jobs list`,
+}
+
+// clientJobsStartCmd starts a job.
+// clientJobsStartCmdはジョブを開始します。
+var clientJobsStartCmd = &cobra.Command{
+	Use:   "start CONTAINER COMMAND",
+	Short: "Start a command as a background job",
+	Long:  `Start a whitelisted command in a container as a background job and print its job ID.`,
+	Args:  cobra.ExactArgs(2),
+	RunE:  runClientJobsStart,
+}
+
+// clientJobsOutputCmd prints a job's output.
+// clientJobsOutputCmdはジョブの出力を表示します。
+var clientJobsOutputCmd = &cobra.Command{
+	Use:   "output JOB_ID",
+	Short: "Print the output of a job",
+	Long:  `Print the output of a job so far, or with --follow until the job ends.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runClientJobsOutput,
+}
+
+// clientJobsCancelCmd cancels a job.
+// clientJobsCancelCmdはジョブをキャンセルします。
+var clientJobsCancelCmd = &cobra.Command{
+	Use:   "cancel JOB_ID",
+	Short: "Cancel a running job",
+	Long:  `Cancel a running job; its processes in the container are killed.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runClientJobsCancel,
+}
+
+// clientJobsListCmd lists jobs.
+// clientJobsListCmdはジョブを一覧表示します。
+var clientJobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your jobs",
+	Long:  `List the jobs started in the saved jobs session, oldest first.`,
+	Args:  cobra.NoArgs,
+	RunE:  runClientJobsList,
+}
+
+var (
+	// clientJobsFollow keeps printing output until the job ends.
+	// clientJobsFollowはジョブが終了するまで出力を表示し続けます。
+	clientJobsFollow bool
+
+	// clientJobsInterval is the polling interval of --follow.
+	// clientJobsIntervalは--followのポーリング間隔です。
+	clientJobsInterval time.Duration
+
+	// clientJobsOffset is the byte offset output starts from.
+	// clientJobsOffsetは出力を開始するバイトオフセットです。
+	clientJobsOffset int
+)
+
+func init() {
+	clientCmd.AddCommand(clientJobsCmd)
+	clientJobsCmd.AddCommand(clientJobsStartCmd)
+	clientJobsCmd.AddCommand(clientJobsOutputCmd)
+	clientJobsCmd.AddCommand(clientJobsCancelCmd)
+	clientJobsCmd.AddCommand(clientJobsListCmd)
+
+	clientJobsStartCmd.Flags().Bool("dangerously", false, "Enable dangerous mode to execute commands from exec_dangerously list (file paths are still checked against blocked_paths)")
+	addExecOptionFlags(clientJobsStartCmd)
+	clientJobsStartCmd.Flags().BoolVarP(&clientJobsFollow, "follow", "f", false, "Print the output until the job ends")
+	clientJobsOutputCmd.Flags().BoolVarP(&clientJobsFollow, "follow", "f", false, "Print the output until the job ends")
+	clientJobsOutputCmd.Flags().IntVar(&clientJobsOffset, "offset", 0, "Byte offset to start the output from")
+	for _, c := range []*cobra.Command{clientJobsStartCmd, clientJobsOutputCmd} {
+		c.Flags().DurationVar(&clientJobsInterval, "interval", 2*time.Second, "Polling interval for --follow")
+	}
+}
+
+// runClientJobsStart starts a job and optionally follows it.
+// runClientJobsStartはジョブを開始し、必要に応じて追跡します。
+func runClientJobsStart(cmd *cobra.Command, args []string) error {
+	dangerously, _ := cmd.Flags().GetBool("dangerously")
+	opts, err := execOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	backend, err := newJobsBackend()
+	if err != nil {
+		return err
+	}
+	defer closeJobsBackend(backend)
+
+	job, err := backend.StartExecJob(context.Background(), args[0], args[1], dangerously, opts)
+	if err != nil {
+		return fmt.Errorf("failed to start job: %w", err)
+	}
+	if !clientJobsFollow {
+		fmt.Println(job.JobID)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "dkmcp: started job %s\n", job.JobID)
+	return followClientJob(backend, job.JobID, 0)
+}
+
+// runClientJobsOutput prints a job's output, following it with --follow.
+// runClientJobsOutputはジョブの出力を表示し、--followでは追跡します。
+func runClientJobsOutput(cmd *cobra.Command, args []string) error {
+	backend, err := newJobsBackend()
+	if err != nil {
+		return err
+	}
+	defer closeJobsBackend(backend)
+
+	if clientJobsFollow {
+		return followClientJob(backend, args[0], clientJobsOffset)
+	}
+	job, err := backend.GetJobOutput(context.Background(), args[0], clientJobsOffset)
+	if err != nil {
+		return fmt.Errorf("failed to get job output: %w", err)
+	}
+	fmt.Print(job.Output)
+	if !job.Complete {
+		fmt.Fprintf(os.Stderr, "dkmcp: job %s is %s; continue with --offset %d\n", job.JobID, job.State, job.NextOffset)
+		return nil
+	}
+	return jobResultError(job)
+}
+
+// runClientJobsCancel cancels a job.
+// runClientJobsCancelはジョブをキャンセルします。
+func runClientJobsCancel(cmd *cobra.Command, args []string) error {
+	backend, err := newJobsBackend()
+	if err != nil {
+		return err
+	}
+	defer closeJobsBackend(backend)
+
+	job, err := backend.CancelJob(context.Background(), args[0])
+	if err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	fmt.Printf("Job %s: %s\n", job.JobID, job.State)
+	return nil
+}
+
+// runClientJobsList prints a table of the jobs of the saved jobs session.
+// runClientJobsListは保存されたジョブセッションのジョブの表を表示します。
+func runClientJobsList(cmd *cobra.Command, args []string) error {
+	backend, err := newJobsBackend()
+	if err != nil {
+		return err
+	}
+	defer closeJobsBackend(backend)
+
+	jobs, err := backend.ListJobs(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No jobs.")
+		return nil
+	}
+	printJobsTable(os.Stdout, jobs)
+	return nil
+}
+
+// jobsSessionFile is the file under the state directory holding the server URL and the
+// MCP session ID the jobs commands share.
+//
+// jobsSessionFileはjobsコマンドが共有するサーバーURLとMCPセッションIDを保持する
+// 状態ディレクトリ内のファイルです。
+const jobsSessionFile = "jobs-session"
+
+// newJobsBackend connects for a jobs command, resuming the session saved by the previous
+// jobs command for the same server, since jobs belong to the session that started them.
+// A new session is started when there is none or the server no longer has it.
+//
+// newJobsBackendはjobsコマンドのために接続します。ジョブは開始したセッションに属するため、
+// 同じサーバーに対する前回のjobsコマンドが保存したセッションを再開します。セッションが
+// ない場合やサーバーがもう持っていない場合は新しいセッションを開始します。
+func newJobsBackend() (*HTTPBackend, error) {
+	return NewHTTPBackendResuming(serverURL, readJobsSession(serverURL))
+}
+
+// closeJobsBackend leaves a Streamable HTTP session open for the next jobs command and
+// saves its ID. A session that cannot be saved is closed instead.
+//
+// closeJobsBackendは次のjobsコマンドのためにStreamable HTTPセッションを開いたままにし、
+// そのIDを保存します。保存できないセッションは代わりに閉じられます。
+func closeJobsBackend(backend *HTTPBackend) {
+	// Legacy SSE sessions end with their connection and cannot be resumed
+	// レガシーSSEセッションは接続とともに終了し、再開できない
+	if backend.client.Transport() != client.TransportHTTP {
+		backend.Close()
+		return
+	}
+	if err := writeJobsSession(serverURL, backend.SessionID()); err != nil {
+		fmt.Fprintf(os.Stderr, "dkmcp: cannot save the jobs session: %v\n", err)
+		backend.Close()
+		return
+	}
+	backend.Detach()
+}
+
+// readJobsSession returns the saved jobs session ID for serverURL, or "" if there is none.
+// readJobsSessionはserverURLに対して保存されたジョブセッションIDを返します。ない場合は""です。
+func readJobsSession(serverURL string) string {
+	dir, err := GetCurrentContainerDir()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(dir, jobsSessionFile))
+	if err != nil {
+		return ""
+	}
+	url, sessionID, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+	if url != serverURL {
+		return ""
+	}
+	return sessionID
+}
+
+// writeJobsSession saves the jobs session ID for serverURL. The ID alone authorizes
+// requests on the session, so the file is only readable by the user.
+//
+// writeJobsSessionはserverURLに対するジョブセッションIDを保存します。IDだけでセッション上の
+// リクエストが許可されるため、ファイルはユーザーのみが読み取れるようにします。
+func writeJobsSession(serverURL, sessionID string) error {
+	dir, err := GetCurrentContainerDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, jobsSessionFile), []byte(serverURL+"\n"+sessionID+"\n"), 0600)
+}
+
+// followClientJob polls a job every clientJobsInterval and prints new output until the job
+// ends or the user interrupts, which stops following but leaves the job running.
+//
+// followClientJobはclientJobsInterval毎にジョブをポーリングし、ジョブが終了するか
+// ユーザーが中断するまで新しい出力を表示します。中断は追跡のみを止め、ジョブは実行を続けます。
+func followClientJob(backend *HTTPBackend, jobID string, offset int) error {
+	if clientJobsInterval < 100*time.Millisecond {
+		return fmt.Errorf("--interval must be at least 100ms")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for {
+		job, err := backend.GetJobOutput(ctx, jobID, offset)
+		if err != nil {
+			return fmt.Errorf("failed to get job output: %w", err)
+		}
+		fmt.Print(job.Output)
+		offset = job.NextOffset
+		if job.Complete {
+			return jobResultError(job)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(os.Stderr, "\ndkmcp: stopped following; job %s is still running (resume with --offset %d)\n", jobID, offset)
+			return nil
+		case <-time.After(clientJobsInterval):
+		}
+	}
+}
+
+// jobResultError returns the error the CLI exits with for an ended job, or nil when the
+// command exited with code 0.
+//
+// jobResultErrorは終了したジョブについてCLIが終了時に返すエラーを返します。コマンドが
+// 終了コード0で終了した場合はnilです。
+func jobResultError(job *JobStatus) error {
+	if job.Truncated {
+		fmt.Fprintln(os.Stderr, "dkmcp: output truncated at the server's exec_output limit")
+	}
+	switch job.State {
+	case "timed_out":
+		return fmt.Errorf("job %s timed out after %dms and was killed (security.exec_jobs)", job.JobID, job.DurationMs)
+	case "cancelled":
+		return fmt.Errorf("job %s was cancelled", job.JobID)
+	case "failed":
+		return fmt.Errorf("job %s failed: %s", job.JobID, job.Error)
+	}
+	if job.ExitCode != nil && *job.ExitCode != 0 {
+		return fmt.Errorf("command exited with code %d", *job.ExitCode)
+	}
+	return nil
+}
+
+// printJobsTable writes one row per job: ID, container, state, exit code, duration and command.
+// printJobsTableはジョブごとに1行書き込みます：ID、コンテナ、状態、終了コード、実行時間、コマンドです。
+func printJobsTable(w io.Writer, jobs []JobStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "JOB ID\tCONTAINER\tSTATE\tEXIT\tDURATION\tCOMMAND")
+	for _, job := range jobs {
+		exit := "-"
+		if job.ExitCode != nil {
+			exit = fmt.Sprint(*job.ExitCode)
+		}
+		duration := (time.Duration(job.DurationMs) * time.Millisecond).Round(time.Second)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", job.JobID, job.Container, job.State, exit, duration, job.Command)
+	}
+	tw.Flush()
+}
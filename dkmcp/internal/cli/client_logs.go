@@ -8,6 +8,10 @@ package cli
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -28,26 +32,71 @@ var (
 	clientLogsSince string
 
 	// clientLogsFollow enables follow mode for continuous log output.
-	// Note: Follow mode has limited support via HTTP due to request-response nature.
+	// New lines are streamed by the server's follow_logs tool until the duration ends or Ctrl+C.
 	//
 	// clientLogsFollowは継続的なログ出力のフォローモードを有効にします。
-	// 注意：HTTPのリクエスト-レスポンスの性質のため、フォローモードのサポートは限定的です。
+	// 新しい行は期間が終わるかCtrl+Cまで、サーバーのfollow_logsツールによってストリームされます。
 	clientLogsFollow bool
+
+	// clientLogsFollowDuration is how long follow mode runs, in seconds.
+	// The server caps this at 600 seconds.
+	//
+	// clientLogsFollowDurationはフォローモードの実行時間（秒）です。
+	// サーバーは600秒を上限とします。
+	clientLogsFollowDuration int
+
+	// clientLogsAll merges logs from all running accessible containers into one timeline.
+	// clientLogsAllはアクセス可能な実行中のすべてのコンテナのログを1つの時系列にマージします。
+	clientLogsAll bool
+
+	// clientLogsContainers lists containers whose logs are merged into one timeline.
+	// clientLogsContainersはログを1つの時系列にマージするコンテナを列挙します。
+	clientLogsContainers []string
 )
 
 // clientLogsCmd represents the 'client logs' subcommand.
 // It retrieves and displays container logs via the DockMCP HTTP server.
-// Requires exactly one argument: the container name.
+// Requires exactly one argument (the container name) unless --all or --containers is used.
 //
 // clientLogsCmdは'client logs'サブコマンドを表します。
 // DockMCP HTTPサーバー経由でコンテナログを取得して表示します。
-// コンテナ名という1つの引数が必要です。
+// --allまたは--containersを使わない限り、コンテナ名という1つの引数が必要です。
 var clientLogsCmd = &cobra.Command{
-	Use:   "logs CONTAINER",
+	Use:   "logs [CONTAINER]",
 	Short: "Get logs from a container via DockMCP server",
-	Long:  `Retrieve logs from a Docker container through the DockMCP server.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runClientLogs,
+	Long: `Retrieve logs from a Docker container through the DockMCP server.
+
+With --follow (-f), the last --tail lines are shown and new lines are streamed
+until --duration elapses or Ctrl+C is pressed.
+
+With --all or --containers, logs from several containers are merged by timestamp
+into one timeline, each line prefixed with its container name (--tail applies per container).
+
+Examples:
+  dkmcp client logs securenote-api
+  dkmcp client logs -f securenote-api
+  dkmcp client logs --all --since 10m
+  dkmcp client logs --containers securenote-api,securenote-web`,
+	Args: validateClientLogsArgs,
+	RunE: runClientLogs,
+}
+
+// validateClientLogsArgs requires a container argument unless several containers are
+// selected with --all or --containers, which cannot be combined with one.
+//
+// validateClientLogsArgsは--allまたは--containersで複数コンテナを選択しない限り
+// コンテナ引数を必須とします。これらのフラグはコンテナ引数と併用できません。
+func validateClientLogsArgs(cmd *cobra.Command, args []string) error {
+	if clientLogsAll || len(clientLogsContainers) > 0 {
+		if len(args) > 0 {
+			return fmt.Errorf("a container argument cannot be combined with --all or --containers")
+		}
+		if clientLogsFollow {
+			return fmt.Errorf("--follow cannot be combined with --all or --containers")
+		}
+		return nil
+	}
+	return cobra.ExactArgs(1)(cmd, args)
 }
 
 // init registers the logs subcommand and its flags with the client command.
@@ -64,7 +113,10 @@ func init() {
 	// ログ取得オプションのフラグを登録します。
 	clientLogsCmd.Flags().IntVar(&clientLogsTail, "tail", 100, "Number of lines to show from the end of logs")
 	clientLogsCmd.Flags().StringVar(&clientLogsSince, "since", "", "Show logs since timestamp (e.g., 2024-01-01T00:00:00Z)")
-	clientLogsCmd.Flags().BoolVar(&clientLogsFollow, "follow", false, "Follow log output (note: not fully supported via HTTP)")
+	clientLogsCmd.Flags().BoolVarP(&clientLogsFollow, "follow", "f", false, "Follow log output (stream new lines until --duration or Ctrl+C)")
+	clientLogsCmd.Flags().IntVar(&clientLogsFollowDuration, "duration", 600, "Seconds to follow logs with --follow (server maximum: 600)")
+	clientLogsCmd.Flags().BoolVar(&clientLogsAll, "all", false, "Merge logs from all running accessible containers by timestamp")
+	clientLogsCmd.Flags().StringSliceVar(&clientLogsContainers, "containers", nil, "Merge logs from these containers by timestamp (comma-separated)")
 }
 
 // runClientLogs is the execution function for the client logs subcommand.
@@ -73,16 +125,6 @@ func init() {
 // runClientLogsはclient logsサブコマンドの実行関数です。
 // HTTPBackendを作成し、ログを取得して表示します。
 func runClientLogs(cmd *cobra.Command, args []string) error {
-	// Get the container name from command arguments.
-	// コマンド引数からコンテナ名を取得します。
-	containerName := args[0]
-
-	// Warn user about follow mode limitations via HTTP.
-	// HTTP経由でのフォローモードの制限についてユーザーに警告します。
-	if clientLogsFollow {
-		fmt.Println("Note: Follow mode is limited via HTTP client. Showing available logs only.")
-	}
-
 	// Create an HTTPBackend for remote DockMCP server access.
 	// リモートDockMCPサーバーアクセス用のHTTPBackendを作成します。
 	backend, err := NewHTTPBackend(serverURL)
@@ -91,6 +133,28 @@ func runClientLogs(cmd *cobra.Command, args []string) error {
 	}
 	defer backend.Close()
 
+	// Merge several containers into one timeline.
+	// An empty container list asks the server for all running accessible containers.
+	//
+	// 複数のコンテナを1つの時系列にマージします。
+	// コンテナリストが空の場合、サーバーにアクセス可能な実行中のすべてのコンテナを要求します。
+	if clientLogsAll || len(clientLogsContainers) > 0 {
+		logs, err := backend.CorrelateLogs(context.Background(), clientLogsContainers, fmt.Sprintf("%d", clientLogsTail), clientLogsSince)
+		if err != nil {
+			return fmt.Errorf("failed to get logs: %w", err)
+		}
+		fmt.Print(logs)
+		return nil
+	}
+
+	// Get the container name from command arguments.
+	// コマンド引数からコンテナ名を取得します。
+	containerName := args[0]
+
+	if clientLogsFollow {
+		return followClientLogs(backend, containerName)
+	}
+
 	// Retrieve logs from the container via MCP.
 	// Convert tail count to string for the backend API.
 	//
@@ -117,3 +181,38 @@ func runClientLogs(cmd *cobra.Command, args []string) error {
 	fmt.Print(logs)
 	return nil
 }
+
+// followClientLogs streams container logs via the follow_logs tool, printing each chunk
+// as it arrives. Ctrl+C cancels the call on the server and ends the command normally.
+//
+// followClientLogsはfollow_logsツール経由でコンテナログをストリームし、各チャンクを
+// 到着した時点で出力します。Ctrl+Cはサーバー上の呼び出しをキャンセルし、コマンドを正常終了します。
+func followClientLogs(backend *HTTPBackend, containerName string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	streamed := false
+	result, err := backend.FollowLogs(ctx, containerName, fmt.Sprintf("%d", clientLogsTail), clientLogsSince, clientLogsFollowDuration, func(chunk string) {
+		streamed = true
+		fmt.Println(chunk)
+	})
+	if ctx.Err() != nil {
+		// Interrupted by the user
+		// ユーザーによる中断
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The result repeats the streamed lines after a summary line; print only the summary
+	// unless nothing was streamed (e.g., notifications were not delivered).
+	// 結果は概要行の後にストリーム済みの行を繰り返すため、何もストリームされなかった場合
+	// （通知が届かなかった場合など）を除き概要のみを出力します。
+	summary, lines, _ := strings.Cut(result, "\n\n")
+	if !streamed && lines != "" {
+		fmt.Println(lines)
+	}
+	fmt.Fprintln(os.Stderr, summary)
+	return nil
+}
@@ -0,0 +1,141 @@
+// client_policy.go implements the 'client policy' subcommand for showing the security policy.
+// It queries the DockMCP server and prints the effective permissions of each container.
+//
+// client_policy.goはセキュリティポリシーを表示する'client policy'サブコマンドを実装します。
+// DockMCPサーバーに問い合わせて、各コンテナの実効権限を表示します。
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/client"
+)
+
+// clientPolicyCmd represents the 'client policy' subcommand.
+// It displays the security mode and the effective permission matrix.
+// Optionally accepts a container name to show a single container.
+//
+// clientPolicyCmdは'client policy'サブコマンドを表します。
+// セキュリティモードと実効権限マトリクスを表示します。
+// オプションでコンテナ名を受け取り、単一のコンテナを表示できます。
+var clientPolicyCmd = &cobra.Command{
+	Use:   "policy [container]",
+	Short: "Show the effective permissions for each container",
+	Long: `Show the security mode and the operations allowed on each accessible container.
+
+The global permissions apply to every container; container_permissions entries in
+dkmcp.yaml override them for matching containers.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runClientPolicy,
+}
+
+// init registers the policy subcommand with the client command.
+// initはpolicyサブコマンドをclientコマンドに登録します。
+func init() {
+	clientCmd.AddCommand(clientPolicyCmd)
+}
+
+// permissionColumns lists the permissions in the order they are displayed.
+// permissionColumnsは表示する順序で権限を列挙します。
+var permissionColumns = []string{"logs", "inspect", "stats", "exec", "lifecycle", "write", "copy"}
+
+// securityPolicy is the subset of the get_security_policy result used by 'client policy'.
+// securityPolicyは'client policy'が使用するget_security_policyの結果の一部です。
+type securityPolicy struct {
+	Mode                 string                     `json:"mode"`                  // Security mode / セキュリティモード
+	Permissions          map[string]bool            `json:"permissions"`           // Global permissions / グローバルな権限
+	EffectivePermissions map[string]map[string]bool `json:"effective_permissions"` // Per-container permissions / コンテナごとの権限
+}
+
+// runClientPolicy is the execution function for the policy subcommand.
+// runClientPolicyはpolicyサブコマンドの実行関数です。
+func runClientPolicy(cmd *cobra.Command, args []string) error {
+	c := client.NewClient(serverURL)
+	if clientSuffix != "" {
+		c.SetClientSuffix(clientSuffix)
+	}
+	if clientTransport != "" {
+		c.SetTransport(clientTransport)
+	}
+	defer c.Close()
+
+	if err := c.HealthCheck(); err != nil {
+		return fmt.Errorf("server health check failed: %w", err)
+	}
+
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+
+	resp, err := c.CallTool("get_security_policy", map[string]interface{}{})
+	if err != nil {
+		return fmt.Errorf("failed to get security policy: %w", err)
+	}
+
+	if len(resp.Content) == 0 {
+		fmt.Println("No response from server.")
+		return nil
+	}
+
+	// The policy is returned as JSON inside a Markdown code block
+	// ポリシーはMarkdownコードブロック内のJSONとして返される
+	var policy securityPolicy
+	if err := json.Unmarshal([]byte(extractJSONFromMarkdown(resp.Content[0].Text)), &policy); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	container := ""
+	if len(args) > 0 {
+		container = args[0]
+		if _, ok := policy.EffectivePermissions[container]; !ok {
+			return fmt.Errorf("container not found or not accessible: %s", container)
+		}
+	}
+
+	fmt.Printf("Security mode: %s\n\n", policy.Mode)
+	printPermissionMatrix(os.Stdout, policy, container)
+	return nil
+}
+
+// printPermissionMatrix writes the permission matrix as a table. The global row is
+// shown first; when container is set, only that container's row follows it.
+//
+// printPermissionMatrixは権限マトリクスを表として書き込みます。最初にグローバルの行を
+// 表示し、containerが設定されている場合はそのコンテナの行のみを続けます。
+func printPermissionMatrix(out io.Writer, policy securityPolicy, container string) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tLOGS\tINSPECT\tSTATS\tEXEC\tLIFECYCLE")
+	fmt.Fprintln(w, "---------\t----\t-------\t-----\t----\t---------")
+
+	writeRow := func(name string, perms map[string]bool) {
+		fmt.Fprint(w, name)
+		for _, column := range permissionColumns {
+			value := "-"
+			if perms[column] {
+				value = "yes"
+			}
+			fmt.Fprintf(w, "\t%s", value)
+		}
+		fmt.Fprintln(w)
+	}
+
+	writeRow("* (global)", policy.Permissions)
+
+	names := make([]string, 0, len(policy.EffectivePermissions))
+	for name := range policy.EffectivePermissions {
+		if container == "" || name == container {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writeRow(name, policy.EffectivePermissions[name])
+	}
+	w.Flush()
+}
@@ -7,31 +7,54 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/docker/docker/api/types/container"
 	"github.com/spf13/cobra"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
 )
 
 // clientStatsCmd represents the 'client stats' subcommand.
 // It retrieves and displays container resource statistics via the DockMCP HTTP server.
-// Requires exactly one argument: the container name.
+// Takes one container name, or several with --watch.
 //
 // clientStatsCmdは'client stats'サブコマンドを表します。
 // DockMCP HTTPサーバー経由でコンテナのリソース統計を取得して表示します。
-// コンテナ名という1つの引数が必要です。
+// コンテナ名を1つ、または--watchでは複数受け取ります。
 var clientStatsCmd = &cobra.Command{
-	Use:   "stats CONTAINER",
+	Use:   "stats CONTAINER...",
 	Short: "Get resource statistics from a container via DockMCP server",
 	Long: `Retrieve resource statistics (CPU, memory, network, disk I/O) from a Docker container
 through the DockMCP server.
 
+With --watch, a live table of CPU %, memory and network and block I/O rates is
+refreshed every --interval for one or more containers until Ctrl+C.
+
 Examples:
   dkmcp client stats securenote-api
-  dkmcp client stats --url http://host.docker.internal:8080 securenote-api`,
-	Args: cobra.ExactArgs(1),
+  dkmcp client stats --url http://host.docker.internal:8080 securenote-api
+  dkmcp client stats --watch securenote-api securenote-db`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runClientStats,
 }
 
+var (
+	// clientStatsWatch refreshes a live table instead of printing one snapshot.
+	// clientStatsWatchは1つのスナップショットを出力する代わりにライブの表を更新します。
+	clientStatsWatch bool
+
+	// clientStatsInterval is the refresh interval of --watch.
+	// clientStatsIntervalは--watchの更新間隔です。
+	clientStatsInterval time.Duration
+)
+
 // init registers the stats subcommand with the client command.
 // This function is automatically called when the package is imported.
 //
@@ -41,6 +64,9 @@ func init() {
 	// Add stats as a subcommand of client.
 	// statsをclientのサブコマンドとして追加します。
 	clientCmd.AddCommand(clientStatsCmd)
+
+	clientStatsCmd.Flags().BoolVarP(&clientStatsWatch, "watch", "w", false, "Refresh a live table of CPU, memory and I/O rates until Ctrl+C")
+	clientStatsCmd.Flags().DurationVar(&clientStatsInterval, "interval", 2*time.Second, "Refresh interval for --watch (minimum: 1s)")
 }
 
 // runClientStats is the execution function for the client stats subcommand.
@@ -49,6 +75,14 @@ func init() {
 // runClientStatsはclient statsサブコマンドの実行関数です。
 // HTTPBackendを作成し、統計を取得して表示します。
 func runClientStats(cmd *cobra.Command, args []string) error {
+	if clientStatsWatch {
+		if clientStatsInterval < time.Second {
+			return fmt.Errorf("--interval must be at least 1s")
+		}
+	} else if len(args) > 1 {
+		return fmt.Errorf("stats for several containers requires --watch")
+	}
+
 	// Get the container name from command arguments.
 	// コマンド引数からコンテナ名を取得します。
 	containerName := args[0]
@@ -61,6 +95,10 @@ func runClientStats(cmd *cobra.Command, args []string) error {
 	}
 	defer backend.Close()
 
+	if clientStatsWatch {
+		return watchClientStats(backend, args)
+	}
+
 	// Retrieve stats from the container via MCP.
 	// MCP経由でコンテナから統計を取得します。
 	ctx := context.Background()
@@ -85,3 +123,106 @@ func runClientStats(cmd *cobra.Command, args []string) error {
 	fmt.Println(stats)
 	return nil
 }
+
+// statsRow is one container's line in the --watch table.
+// statsRowは--watchの表における1つのコンテナの行です。
+type statsRow struct {
+	container string             // Container name / コンテナ名
+	point     docker.StatsPoint  // Latest sample / 最新のサンプル
+	rates     *docker.StatsRates // Rates since the previous refresh, nil on the first / 前回の更新からのレート。初回はnil
+	err       error              // Why the sample failed / サンプルが失敗した理由
+}
+
+// watchClientStats samples the containers every clientStatsInterval and redraws the
+// table until interrupted. Rates are computed between consecutive refreshes.
+//
+// watchClientStatsはclientStatsInterval毎にコンテナをサンプリングし、中断されるまで表を
+// 再描画します。レートは連続する更新の間で計算します。
+func watchClientStats(backend *HTTPBackend, containers []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(clientStatsInterval)
+	defer ticker.Stop()
+
+	prev := make(map[string]docker.StatsPoint)
+	for {
+		rows := make([]statsRow, 0, len(containers))
+		for _, name := range containers {
+			row := statsRow{container: name}
+			row.point, row.err = fetchStatsPoint(ctx, backend, name)
+			if row.err == nil {
+				if p, ok := prev[name]; ok {
+					rates := row.point.RatesSince(p)
+					row.rates = &rates
+				}
+				prev[name] = row.point
+			}
+			rows = append(rows, row)
+		}
+
+		// Clear the screen and move the cursor home before redrawing
+		// 再描画の前に画面を消去してカーソルを先頭に移動
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("Every %s: %s (Ctrl+C to stop)\n\n", clientStatsInterval, time.Now().Format(time.TimeOnly))
+		printStatsTable(os.Stdout, rows)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchStatsPoint takes one stats sample of a container via the MCP 'get_stats' tool.
+// fetchStatsPointはMCPの'get_stats'ツール経由でコンテナの統計サンプルを1つ取得します。
+func fetchStatsPoint(ctx context.Context, backend *HTTPBackend, name string) (docker.StatsPoint, error) {
+	text, err := backend.GetStats(ctx, name)
+	if err != nil {
+		return docker.StatsPoint{}, err
+	}
+	var stats container.StatsResponse
+	if err := json.Unmarshal([]byte(text), &stats); err != nil {
+		return docker.StatsPoint{}, fmt.Errorf("failed to parse stats: %w", err)
+	}
+	return docker.NewStatsPoint(&stats), nil
+}
+
+// printStatsTable prints the --watch table. Rates show "-" until a container has two samples.
+// printStatsTableは--watchの表を出力します。コンテナのサンプルが2つになるまでレートは"-"です。
+func printStatsTable(out io.Writer, rows []statsRow) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tCPU %\tMEM USAGE / LIMIT\tMEM %\tNET RX/s\tNET TX/s\tBLOCK R/s\tBLOCK W/s")
+	for _, r := range rows {
+		if r.err != nil {
+			fmt.Fprintf(w, "%s\terror: %v\n", r.container, r.err)
+			continue
+		}
+		rx, tx, read, write := "-", "-", "-", "-"
+		if r.rates != nil {
+			rx, tx = formatByteSize(r.rates.NetworkRx), formatByteSize(r.rates.NetworkTx)
+			read, write = formatByteSize(r.rates.BlockRead), formatByteSize(r.rates.BlockWrite)
+		}
+		fmt.Fprintf(w, "%s\t%.2f%%\t%s / %s\t%.2f%%\t%s\t%s\t%s\t%s\n",
+			r.container, r.point.CPUPercent,
+			formatByteSize(float64(r.point.MemoryUsage)), formatByteSize(float64(r.point.MemoryLimit)),
+			r.point.MemoryPercent, rx, tx, read, write)
+	}
+	w.Flush()
+}
+
+// formatByteSize formats a byte count with a binary unit, as docker stats does.
+// formatByteSizeはdocker statsと同様に、バイト数を2進接頭辞の単位でフォーマットします。
+func formatByteSize(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f%s", b, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", b, units[i])
+}
@@ -22,6 +22,8 @@
 //     環境変数の優先順位ロジックをテスト
 //   - TestExtractJSONFromMarkdown: Tests JSON extraction from markdown
 //     MarkdownからのJSON抽出をテスト
+//   - TestPrintStatsTable: Tests the 'client stats --watch' table
+//     'client stats --watch'の表をテスト
 //   - TestParseExitCode: Tests exit code parsing
 //     終了コード解析をテスト
 //
@@ -32,10 +34,15 @@
 package cli
 
 import (
+	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
 )
 
 // TestClientCommand verifies that the client command is properly configured.
@@ -101,7 +108,7 @@ func TestClientURLFlag(t *testing.T) {
 func TestClientSubcommands(t *testing.T) {
 	// Define the list of expected subcommands.
 	// 期待されるサブコマンドのリストを定義します。
-	expectedSubcommands := []string{"list", "logs", "exec", "stats", "inspect", "restart", "stop", "start", "host-tools", "host-exec"}
+	expectedSubcommands := []string{"list", "logs", "exec", "stats", "inspect", "restart", "stop", "start", "host-tools", "host-exec", "policy", "jobs"}
 
 	// Get all registered subcommands under client.
 	// client配下のすべての登録されたサブコマンドを取得します。
@@ -161,10 +168,45 @@ func TestClientLogsCommand(t *testing.T) {
 		t.Fatal("clientLogsCmd is nil")
 	}
 
-	// Verify the command usage string includes CONTAINER argument.
-	// コマンドの使用方法文字列がCONTAINER引数を含むことを確認します。
-	if clientLogsCmd.Use != "logs CONTAINER" {
-		t.Errorf("Expected Use to be 'logs CONTAINER', got %s", clientLogsCmd.Use)
+	// Verify the command usage string includes the optional CONTAINER argument.
+	// コマンドの使用方法文字列が省略可能なCONTAINER引数を含むことを確認します。
+	if clientLogsCmd.Use != "logs [CONTAINER]" {
+		t.Errorf("Expected Use to be 'logs [CONTAINER]', got %s", clientLogsCmd.Use)
+	}
+}
+
+// TestClientLogsArgs verifies that a container argument is required unless several
+// containers are selected, and that the selections cannot be mixed.
+//
+// TestClientLogsArgsは、複数コンテナを選択しない限りコンテナ引数が必須であり、
+// 選択方法を混在できないことを確認します。
+func TestClientLogsArgs(t *testing.T) {
+	tests := []struct {
+		name       string   // Test case name / テストケース名
+		args       []string // Positional arguments / 位置引数
+		all        bool     // --all flag / --allフラグ
+		containers []string // --containers flag / --containersフラグ
+		follow     bool     // --follow flag / --followフラグ
+		wantErr    bool     // Whether an error is expected / エラーが期待されるか
+	}{
+		{name: "single container", args: []string{"api"}},
+		{name: "missing container", wantErr: true},
+		{name: "all", all: true},
+		{name: "containers", containers: []string{"api", "db"}},
+		{name: "all with container", args: []string{"api"}, all: true, wantErr: true},
+		{name: "follow with all", all: true, follow: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientLogsAll, clientLogsContainers, clientLogsFollow = tt.all, tt.containers, tt.follow
+			defer func() { clientLogsAll, clientLogsContainers, clientLogsFollow = false, nil, false }()
+
+			err := validateClientLogsArgs(clientLogsCmd, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateClientLogsArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
 	}
 }
 
@@ -269,8 +311,50 @@ func TestClientStatsCommand(t *testing.T) {
 
 	// Verify the command usage string includes CONTAINER argument.
 	// コマンドの使用方法文字列がCONTAINER引数を含むことを確認します。
-	if clientStatsCmd.Use != "stats CONTAINER" {
-		t.Errorf("Expected Use to be 'stats CONTAINER', got %s", clientStatsCmd.Use)
+	if clientStatsCmd.Use != "stats CONTAINER..." {
+		t.Errorf("Expected Use to be 'stats CONTAINER...', got %s", clientStatsCmd.Use)
+	}
+
+	// Verify the --watch and --interval flags.
+	// --watchと--intervalフラグを確認します。
+	if flag := clientStatsCmd.Flags().Lookup("watch"); flag == nil || flag.DefValue != "false" {
+		t.Errorf("Expected watch flag defaulting to false, got %v", flag)
+	}
+	if flag := clientStatsCmd.Flags().Lookup("interval"); flag == nil || flag.DefValue != "2s" {
+		t.Errorf("Expected interval flag defaulting to 2s, got %v", flag)
+	}
+}
+
+// TestPrintStatsTable tests the live table printed by 'client stats --watch'.
+// TestPrintStatsTableは'client stats --watch'が出力するライブの表をテストします。
+func TestPrintStatsTable(t *testing.T) {
+	rows := []statsRow{
+		{
+			container: "api",
+			point:     docker.StatsPoint{CPUPercent: 12.5, MemoryUsage: 256 << 20, MemoryLimit: 1 << 30, MemoryPercent: 25},
+			rates:     &docker.StatsRates{NetworkRx: 2048, NetworkTx: 100, BlockWrite: 3 << 20},
+		},
+		{
+			container: "db",
+			point:     docker.StatsPoint{MemoryUsage: 512, MemoryLimit: 2 << 30},
+		},
+		{container: "worker", err: errors.New("stats permission denied")},
+	}
+
+	var buf bytes.Buffer
+	printStatsTable(&buf, rows)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 rows, got:\n%s", buf.String())
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "api 12.50% 256.0MiB / 1.0GiB 25.00% 2.0KiB 100B 0B 3.0MiB" {
+		t.Errorf("unexpected api row: %q", got)
+	}
+	if got := strings.Join(strings.Fields(lines[2]), " "); got != "db 0.00% 512B / 2.0GiB 0.00% - - - -" {
+		t.Errorf("unexpected db row: %q", got)
+	}
+	if !strings.Contains(lines[3], "error: stats permission denied") {
+		t.Errorf("unexpected worker row: %q", lines[3])
 	}
 }
 
@@ -645,3 +729,219 @@ func TestClientHostExecCommand(t *testing.T) {
 		t.Errorf("Expected dangerously default 'false', got %s", flag.DefValue)
 	}
 }
+
+// TestPrintPermissionMatrix tests the permission table printed by 'client policy'.
+// TestPrintPermissionMatrixは'client policy'が出力する権限の表をテストします。
+func TestPrintPermissionMatrix(t *testing.T) {
+	policy := securityPolicy{
+		Mode:        "moderate",
+		Permissions: map[string]bool{"logs": true, "inspect": true},
+		EffectivePermissions: map[string]map[string]bool{
+			"worker-1": {"logs": true, "inspect": true, "lifecycle": true},
+			"db":       {"inspect": true},
+		},
+	}
+
+	var buf bytes.Buffer
+	printPermissionMatrix(&buf, policy, "")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header, separator and 3 rows, got:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "* (global) yes yes - - - - -" {
+		t.Errorf("unexpected global row: %q", lines[2])
+	}
+	if fields := strings.Fields(lines[3]); strings.Join(fields, " ") != "db - yes - - - - -" {
+		t.Errorf("expected containers sorted with db first, got %q", lines[3])
+	}
+	if fields := strings.Fields(lines[4]); strings.Join(fields, " ") != "worker-1 yes yes - - yes - -" {
+		t.Errorf("unexpected worker-1 row: %q", lines[4])
+	}
+
+	buf.Reset()
+	printPermissionMatrix(&buf, policy, "db")
+	if strings.Contains(buf.String(), "worker-1") || !strings.Contains(buf.String(), "db") {
+		t.Errorf("expected only the db row, got:\n%s", buf.String())
+	}
+}
+
+// TestPrintExecResult tests that stdout and stderr go to their own streams, that the
+// combined output of older servers goes to stdout, and that truncation is noted.
+//
+// TestPrintExecResultは標準出力と標準エラー出力がそれぞれのストリームに出力され、古い
+// サーバーの組み合わせた出力がstdoutに出力され、打ち切りが示されることをテストします。
+func TestPrintExecResult(t *testing.T) {
+	tests := []struct {
+		name       string             // Test case name / テストケース名
+		result     *docker.ExecResult // Exec result / 実行結果
+		wantStdout string
+		wantStderr string
+	}{
+		{"split", &docker.ExecResult{Output: "ok\nfail\n", Stdout: "ok\n", Stderr: "fail\n"}, "ok\n", "fail\n"},
+		{"combined only", &docker.ExecResult{Output: "ok\nfail\n"}, "ok\nfail\n", ""},
+		{"truncated", &docker.ExecResult{Output: "ok", Stdout: "ok", Truncated: true}, "ok", "dkmcp: output truncated at the server's exec_output limit\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			printExecResult(&stdout, &stderr, tt.result)
+			if stdout.String() != tt.wantStdout || stderr.String() != tt.wantStderr {
+				t.Errorf("stdout = %q, stderr = %q; want %q, %q", stdout.String(), stderr.String(), tt.wantStdout, tt.wantStderr)
+			}
+		})
+	}
+}
+
+// TestExecOptionsFromFlags tests reading --workdir, --env and --user.
+// TestExecOptionsFromFlagsは--workdir、--env、--userの読み取りをテストします。
+func TestExecOptionsFromFlags(t *testing.T) {
+	tests := []struct {
+		name    string   // Test case name / テストケース名
+		args    []string // Command-line flags / コマンドラインフラグ
+		wantEnv map[string]string
+		wantErr bool
+	}{
+		{"none", nil, nil, false},
+		{"all", []string{"-w", "/app", "-e", "NODE_ENV=test", "--env", "CI=", "-u", "node"}, map[string]string{"NODE_ENV": "test", "CI": ""}, false},
+		{"env without value", []string{"-e", "NODE_ENV"}, nil, true},
+		{"env without name", []string{"-e", "=test"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			addExecOptionFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatalf("ParseFlags() error = %v", err)
+			}
+			opts, err := execOptionsFromFlags(cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("execOptionsFromFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(opts.Env) != len(tt.wantEnv) {
+				t.Errorf("Env = %v, want %v", opts.Env, tt.wantEnv)
+			}
+			for name, value := range tt.wantEnv {
+				if got, ok := opts.Env[name]; !ok || got != value {
+					t.Errorf("Env[%s] = %q, want %q", name, got, value)
+				}
+			}
+			if len(tt.args) > 0 && (opts.Workdir != "/app" || opts.User != "node") {
+				t.Errorf("opts = %+v", opts)
+			}
+		})
+	}
+
+	for _, c := range []*cobra.Command{execCmd, clientExecCmd, clientJobsStartCmd} {
+		for _, flag := range []string{"workdir", "env", "user"} {
+			if c.Flags().Lookup(flag) == nil {
+				t.Errorf("%s should have --%s", c.Name(), flag)
+			}
+		}
+	}
+}
+
+// TestClientJobsCommands verifies that the jobs subcommands are registered with their flags.
+// TestClientJobsCommandsはjobsサブコマンドがフラグとともに登録されていることを確認します。
+func TestClientJobsCommands(t *testing.T) {
+	names := make(map[string]bool)
+	for _, cmd := range clientJobsCmd.Commands() {
+		names[strings.Fields(cmd.Use)[0]] = true
+	}
+	for _, expected := range []string{"start", "output", "cancel", "list"} {
+		if !names[expected] {
+			t.Errorf("Missing jobs subcommand: %s", expected)
+		}
+	}
+	for _, flag := range []string{"dangerously", "follow", "interval"} {
+		if clientJobsStartCmd.Flags().Lookup(flag) == nil {
+			t.Errorf("jobs start should have --%s", flag)
+		}
+	}
+	for _, flag := range []string{"follow", "offset", "interval"} {
+		if clientJobsOutputCmd.Flags().Lookup(flag) == nil {
+			t.Errorf("jobs output should have --%s", flag)
+		}
+	}
+}
+
+// TestJobResultError tests the exit status of 'client jobs' for each way a job ends.
+// TestJobResultErrorはジョブの各終了方法に対する'client jobs'の終了ステータスをテストします。
+func TestJobResultError(t *testing.T) {
+	zero, one := 0, 1
+	tests := []struct {
+		name    string     // Test case name / テストケース名
+		job     *JobStatus // Ended job / 終了したジョブ
+		wantErr string     // Expected error ("" = none) / 期待されるエラー（""はなし）
+	}{
+		{"success", &JobStatus{JobID: "job-1", State: "exited", ExitCode: &zero}, ""},
+		{"failure", &JobStatus{JobID: "job-1", State: "exited", ExitCode: &one}, "command exited with code 1"},
+		{"timeout", &JobStatus{JobID: "job-1", State: "timed_out", DurationMs: 3600000}, "job job-1 timed out after 3600000ms"},
+		{"cancelled", &JobStatus{JobID: "job-1", State: "cancelled"}, "job job-1 was cancelled"},
+		{"failed", &JobStatus{JobID: "job-1", State: "failed", Error: "container not running"}, "job job-1 failed: container not running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := jobResultError(tt.job)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("jobResultError() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("jobResultError() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPrintJobsTable tests the 'client jobs list' table.
+// TestPrintJobsTableは'client jobs list'の表をテストします。
+func TestPrintJobsTable(t *testing.T) {
+	code := 1
+	var buf bytes.Buffer
+	printJobsTable(&buf, []JobStatus{
+		{JobID: "job-a", Container: "api", Command: "npm test", State: "exited", ExitCode: &code, DurationMs: 95400},
+		{JobID: "job-b", Container: "api", Command: "npm run lint", State: "running", DurationMs: 2000},
+	})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", buf.String())
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "job-a api exited 1 1m35s npm test" {
+		t.Errorf("unexpected job-a row: %q", got)
+	}
+	if got := strings.Join(strings.Fields(lines[2]), " "); got != "job-b api running - 2s npm run lint" {
+		t.Errorf("unexpected job-b row: %q", got)
+	}
+}
+
+// TestJobsSession verifies that the jobs session is saved per server URL and readable only
+// by the user.
+//
+// TestJobsSessionはジョブセッションがサーバーURLごとに保存され、ユーザーのみが読み取れることを検証します。
+func TestJobsSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if got := readJobsSession("http://localhost:8080"); got != "" {
+		t.Errorf("readJobsSession() without a file = %q, want empty", got)
+	}
+	if err := writeJobsSession("http://localhost:8080", "abc123"); err != nil {
+		t.Fatalf("writeJobsSession() error = %v", err)
+	}
+	if got := readJobsSession("http://localhost:8080"); got != "abc123" {
+		t.Errorf("readJobsSession() = %q, want abc123", got)
+	}
+	if got := readJobsSession("http://other:8080"); got != "" {
+		t.Errorf("readJobsSession() for another server = %q, want empty", got)
+	}
+
+	dir, _ := GetCurrentContainerDir()
+	info, err := os.Stat(filepath.Join(dir, jobsSessionFile))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("jobs session file mode = %v, %v, want 0600", info, err)
+	}
+}
@@ -40,7 +40,8 @@ Examples:
   dkmcp exec -c securenote-api "npm test"  # Execute in specific container
   dkmcp use securenote-api                 # Set current container
   dkmcp exec "npm test"                    # Execute in current container
-  dkmcp exec --dangerously "tail -f /var/log/app.log"  # Dangerous mode`,
+  dkmcp exec --dangerously "tail -f /var/log/app.log"  # Dangerous mode
+  dkmcp exec -w /app/packages/web -e NODE_ENV=test "npm test"  # Within exec_options`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runExec,
 }
@@ -60,6 +61,7 @@ func init() {
 	// Add --dangerously flag for dangerous mode execution
 	// 危険モード実行用の--dangerouslyフラグを追加
 	execCmd.Flags().Bool("dangerously", false, "Enable dangerous mode to execute commands from exec_dangerously list (file paths are still checked against blocked_paths)")
+	addExecOptionFlags(execCmd)
 }
 
 // runExec is the execution function for the exec command.
@@ -87,6 +89,10 @@ func runExec(cmd *cobra.Command, args []string) error {
 	// Get the dangerously flag value.
 	// dangerouslyフラグの値を取得します。
 	dangerously, _ := cmd.Flags().GetBool("dangerously")
+	opts, err := execOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
 
 	// Create a DirectBackend for Docker access.
 	// Docker接続用のDirectBackendを作成します。
@@ -102,7 +108,7 @@ func runExec(cmd *cobra.Command, args []string) error {
 	// コンテナ内でコマンドを実行します。
 	// dangerouslyがtrueの場合、exec_dangerouslyコマンドが許可されます。
 	ctx := context.Background()
-	result, err := backend.Exec(ctx, container, command, dangerously)
+	result, err := backend.Exec(ctx, container, command, dangerously, opts)
 	if err != nil {
 		return fmt.Errorf("failed to execute command: %w", err)
 	}
@@ -112,11 +118,14 @@ func runExec(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Exit Code: %d\n\n", result.ExitCode)
 	fmt.Println(result.Output)
 
-	// Return error if command exited with non-zero status.
+	// Return error if command timed out or exited with non-zero status.
 	// This allows scripts to detect command failures.
 	//
-	// コマンドが非ゼロステータスで終了した場合はエラーを返します。
+	// コマンドがタイムアウトした、または非ゼロステータスで終了した場合はエラーを返します。
 	// これによりスクリプトがコマンドの失敗を検出できます。
+	if result.TimedOut {
+		return fmt.Errorf("command timed out after %dms and was killed (security.exec_timeout)", result.DurationMs)
+	}
 	if result.ExitCode != 0 {
 		return fmt.Errorf("command exited with code %d", result.ExitCode)
 	}
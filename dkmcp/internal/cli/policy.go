@@ -0,0 +1,206 @@
+// policy.go implements the 'policy check' command for debugging the security policy.
+// It runs the same checks as exec_command, read_file and list_files against dkmcp.yaml
+// without executing anything, and prints the rule that decided the outcome.
+//
+// policy.goはセキュリティポリシーをデバッグするための'policy check'コマンドを実装します。
+// 何も実行せずにdkmcp.yamlに対してexec_command、read_file、list_filesと同じチェックを行い、
+// 結果を決めたルールを表示します。
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// policyCmd is the parent command for security policy utilities.
+// policyCmdはセキュリティポリシー関連ユーティリティの親コマンドです。
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Inspect the security policy",
+}
+
+// policyCheckCmd represents the 'policy check' command.
+// policyCheckCmdは'policy check'コマンドを表します。
+var policyCheckCmd = &cobra.Command{
+	Use:   "check CONTAINER [COMMAND]",
+	Short: "Explain whether a command or path is allowed, without executing it",
+	Long: `Run the exec_command (or read_file/list_files) security checks against the
+configuration without executing anything, and show which rule decided the outcome:
+the exec_whitelist or exec_dangerously entry, or the blocked path and the file it
+came from.
+
+The command exits with an error when the operation would be denied.
+
+Examples:
+  dkmcp policy check securenote-api "npm test"
+  dkmcp policy check securenote-api --dangerously "cat /app/.env"
+  dkmcp policy check securenote-api --workdir /app/web --user node "npm test"
+  dkmcp policy check securenote-api --path /app/.env`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runPolicyCheck,
+}
+
+var (
+	// policyCheckDangerously checks the command in dangerous mode
+	// policyCheckDangerouslyはコマンドを危険モードでチェックします
+	policyCheckDangerously bool
+
+	// policyCheckPath is a file path to check against blocked_paths
+	// policyCheckPathはblocked_pathsに対してチェックするファイルパスです
+	policyCheckPath string
+)
+
+// init registers the policy command with the root command.
+// initはpolicyコマンドをルートコマンドに登録します。
+func init() {
+	rootCmd.AddCommand(policyCmd)
+	policyCmd.AddCommand(policyCheckCmd)
+
+	policyCheckCmd.Flags().BoolVar(&policyCheckDangerously, "dangerously", false, "Check the command against the exec_dangerously list")
+	policyCheckCmd.Flags().StringVar(&policyCheckPath, "path", "", "Check a file path as read_file/list_files would")
+	addExecOptionFlags(policyCheckCmd)
+}
+
+// runPolicyCheck is the execution function for the policy check command.
+// runPolicyCheckはpolicy checkコマンドの実行関数です。
+func runPolicyCheck(cmd *cobra.Command, args []string) error {
+	containerRef := args[0]
+	command := strings.Join(args[1:], " ")
+	if command == "" && policyCheckPath == "" {
+		return fmt.Errorf("specify a command or --path to check")
+	}
+	opts, err := execOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	policy := security.NewPolicy(&cfg.Security)
+	ctx := context.Background()
+	dockerClient := preparePolicy(ctx, policy)
+
+	// With Docker, paths are also resolved in the container as the real tools resolve them
+	// Dockerがある場合、パスは実際のツールと同様にコンテナ内でも解決される
+	var explanations []security.Explanation
+	if dockerClient != nil {
+		defer dockerClient.Close()
+		if command != "" {
+			explanations = append(explanations, dockerClient.ExplainExec(ctx, containerRef, command, policyCheckDangerously, opts))
+		}
+		if policyCheckPath != "" {
+			explanations = append(explanations, dockerClient.ExplainPath(ctx, containerRef, policyCheckPath))
+		}
+	} else {
+		if command != "" {
+			explanations = append(explanations, policy.ExplainExec(containerRef, command, policyCheckDangerously, opts))
+		}
+		if policyCheckPath != "" {
+			explanations = append(explanations, policy.ExplainPath(containerRef, policyCheckPath))
+		}
+	}
+
+	denied := false
+	for i, e := range explanations {
+		if i > 0 {
+			fmt.Println()
+		}
+		printExplanation(os.Stdout, e)
+		denied = denied || !e.Allowed
+	}
+	if denied {
+		return fmt.Errorf("operation would be denied")
+	}
+	return nil
+}
+
+// preparePolicy resolves running containers and loads their blocked paths, as the server
+// does at startup, and returns the docker client for the checks that need the container.
+// Without Docker, it returns nil: only manually configured blocked paths are loaded,
+// container names are matched as given and paths are checked as given.
+//
+// preparePolicyはサーバーの起動時と同様に、実行中のコンテナを解決してブロックパスを読み込み、
+// コンテナを必要とするチェック用のDockerクライアントを返します。Dockerがない場合はnilを返し、
+// 手動設定のブロックパスのみを読み込み、コンテナ名とパスは指定どおりにチェックします。
+func preparePolicy(ctx context.Context, policy *security.Policy) *docker.Client {
+	dockerClient, err := docker.NewClient(policy)
+	if err == nil {
+		if err = dockerClient.ReloadPolicy(ctx, policy); err != nil {
+			dockerClient.Close()
+		}
+	}
+	if err != nil {
+		slog.Warn("Docker unavailable; checking against the configuration only, without resolving symlinks or mounts", "error", err)
+		if err := policy.InitBlockedPaths(nil); err != nil {
+			slog.Warn("Failed to initialize blocked paths", "error", err)
+		}
+		return nil
+	}
+	return dockerClient
+}
+
+// printExplanation writes a human-readable policy explanation.
+// printExplanationは人が読める形式でポリシーの説明を書き込みます。
+func printExplanation(w io.Writer, e security.Explanation) {
+	fmt.Fprintf(w, "Container: %s\n", e.Container)
+	if e.Command != "" {
+		mode := ""
+		if e.Dangerously {
+			mode = " (dangerously)"
+		}
+		fmt.Fprintf(w, "Command:   %s%s\n", e.Command, mode)
+	}
+	if e.Workdir != "" {
+		fmt.Fprintf(w, "Workdir:   %s\n", e.Workdir)
+	}
+	if len(e.Env) > 0 {
+		fmt.Fprintf(w, "Env:       %s\n", strings.Join(security.ExecOptions{Env: e.Env}.EnvList(), " "))
+	}
+	if e.User != "" {
+		fmt.Fprintf(w, "User:      %s\n", e.User)
+	}
+	if e.Path != "" {
+		fmt.Fprintf(w, "Path:      %s\n", e.Path)
+	}
+
+	decision := "DENIED"
+	if e.Allowed {
+		decision = "ALLOWED"
+	}
+	fmt.Fprintf(w, "Decision:  %s\n", decision)
+	fmt.Fprintf(w, "Mode:      %s\n", e.Mode)
+	fmt.Fprintf(w, "Access:    %s\n", e.Access)
+	if e.Rule != nil {
+		fmt.Fprintf(w, "Rule:      %s\n", e.Rule)
+	}
+	if e.ResolvedPath != "" {
+		fmt.Fprintf(w, "Resolved:  %s\n", e.ResolvedPath)
+	}
+	if e.MatchedPath != "" && e.MatchedPath != e.ResolvedPath {
+		fmt.Fprintf(w, "Matched:   %s\n", e.MatchedPath)
+	}
+	if b := e.BlockedPath; b != nil {
+		fmt.Fprintf(w, "Blocked:   %s (%s)\n", b.Pattern, b.Reason)
+		if b.Source != "" {
+			source := b.Source
+			if b.SourceLine > 0 {
+				source = fmt.Sprintf("%s:%d", source, b.SourceLine)
+			}
+			fmt.Fprintf(w, "Source:    %s\n", source)
+		}
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(w, "Reason:    %s\n", e.Reason)
+	}
+}
@@ -0,0 +1,81 @@
+// policy_test.go contains tests for the 'policy check' command output.
+// policy_test.goは'policy check'コマンドの出力のテストを含みます。
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// TestPrintExplanation tests that the decision, rule and blocked path source are printed.
+// TestPrintExplanationは判定、ルール、ブロックパスの定義元が表示されることをテストします。
+func TestPrintExplanation(t *testing.T) {
+	policy := security.NewPolicy(&config.SecurityConfig{
+		Mode:        "moderate",
+		Permissions: config.SecurityPermissions{Exec: true},
+		ExecWhitelist: map[string][]string{
+			"api": {"npm test"},
+		},
+		ExecDangerously: config.ExecDangerouslyConfig{
+			Enabled:  true,
+			Commands: map[string][]string{"*": {"cat"}},
+		},
+		BlockedPaths: config.BlockedPathsConfig{
+			Manual: map[string][]string{"api": {"/app/.env"}},
+		},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatalf("InitBlockedPaths failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string               // Test case name / テストケース名
+		explanation security.Explanation // Explanation to print / 表示する説明
+		want        []string             // Expected output lines / 期待される出力行
+	}{
+		{
+			name:        "allowed by whitelist",
+			explanation: policy.ExplainExec("api", "npm test", false, security.ExecOptions{}),
+			want:        []string{"Decision:  ALLOWED", `Rule:      exec_whitelist[api]: "npm test"`},
+		},
+		{
+			name:        "denied by blocked path",
+			explanation: policy.ExplainExec("api", "cat /app/.env", true, security.ExecOptions{}),
+			want:        []string{"Command:   cat /app/.env (dangerously)", "Decision:  DENIED", "Blocked:   /app/.env", "Source:    dkmcp.yaml", "Reason:    "},
+		},
+		{
+			name:        "denied by exec options",
+			explanation: policy.ExplainExec("api", "npm test", false, security.ExecOptions{Workdir: "/app", User: "root"}),
+			want:        []string{"Workdir:   /app", "User:      root", "Decision:  DENIED", "Reason:    workdir /app is not allowed"},
+		},
+		{
+			name: "denied through a symlink",
+			explanation: security.Explanation{
+				Container: "api", Path: "/app/link-to-env", ResolvedPath: "/app/.env", MatchedPath: "/app/.env",
+				BlockedPath: &security.BlockedPath{Pattern: "/app/.env", Reason: "manual_block"},
+			},
+			want: []string{"Path:      /app/link-to-env", "Decision:  DENIED", "Resolved:  /app/.env", "Blocked:   /app/.env"},
+		},
+		{
+			name:        "path check",
+			explanation: policy.ExplainPath("api", "/app/src/index.js"),
+			want:        []string{"Path:      /app/src/index.js", "Decision:  ALLOWED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printExplanation(&buf, tt.explanation)
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
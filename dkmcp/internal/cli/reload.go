@@ -0,0 +1,263 @@
+// reload.go implements hot reload of dkmcp.yaml for the serve command. The configuration
+// file is polled for changes (and reloaded on SIGHUP); a valid new configuration replaces
+// the security policy, output masking and host access settings of the running server,
+// connected sessions are told that the tool list changed, and the audit log records which
+// settings were loosened or tightened. An invalid configuration is rejected and the
+// current one stays in effect.
+//
+// reload.goはserveコマンドのdkmcp.yamlのホットリロードを実装します。設定ファイルの
+// 変更をポーリングし（SIGHUPでも再読み込みします）、有効な新しい設定で実行中のサーバーの
+// セキュリティポリシー、出力マスキング、ホストアクセス設定を置き換え、接続中のセッションに
+// ツール一覧の変更を通知し、どの設定が緩められ・厳しくされたかを監査ログに記録します。
+// 無効な設定は拒否され、現在の設定が有効なままです。
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/hosttools"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// configPollInterval is how often the configuration file is checked for changes.
+// configPollIntervalは設定ファイルの変更を確認する間隔です。
+const configPollInterval = 2 * time.Second
+
+// policyReloader swaps the security policy used for container operations (implemented by *docker.Client).
+// policyReloaderはコンテナ操作に使用するセキュリティポリシーを置き換えます（*docker.Clientが実装）。
+type policyReloader interface {
+	ReloadPolicy(ctx context.Context, policy *security.Policy) error
+}
+
+// toolsServer is the part of the MCP server updated on reload (implemented by *mcp.Server).
+// toolsServerは再読み込み時に更新されるMCPサーバーの部分です（*mcp.Serverが実装）。
+type toolsServer interface {
+	SetHostAccess(manager *hosttools.Manager, policy *security.HostCommandPolicy, workspaceRoot string, timeout time.Duration)
+	NotifyToolsListChanged() int
+}
+
+// configReloader reloads the configuration file and applies it to the running server.
+// configReloaderは設定ファイルを再読み込みし、実行中のサーバーに適用します。
+type configReloader struct {
+	// configPath is the --config flag value ("" searches the default locations)
+	// configPathは--configフラグの値です（""の場合はデフォルトの場所を検索）
+	configPath string
+
+	// docker receives the new security policy
+	// dockerは新しいセキュリティポリシーを受け取ります
+	docker policyReloader
+
+	// server receives the new host access settings and notifies sessions
+	// serverは新しいホストアクセス設定を受け取り、セッションに通知します
+	server toolsServer
+
+	// applyFlags re-applies command-line overrides to a freshly loaded configuration
+	// applyFlagsは読み込んだばかりの設定にコマンドラインの上書きを再適用します
+	applyFlags func(cfg *config.Config) error
+
+	// buildHostAccess builds host tools and host commands for a configuration
+	// buildHostAccessは設定に対するホストツールとホストコマンドを構築します
+	buildHostAccess func(cfg *config.Config) hostAccess
+
+	// mu serializes reloads and protects current
+	// muは再読み込みを直列化し、currentを保護します
+	mu sync.Mutex
+
+	// current is the configuration in effect
+	// currentは現在有効な設定です
+	current *config.Config
+
+	// fingerprint identifies the configuration file content last seen by the watcher
+	// fingerprintは監視処理が最後に確認した設定ファイルの内容を識別します
+	fingerprint fileFingerprint
+}
+
+// fileFingerprint identifies a version of the configuration file by path and content.
+// Hashing the content, rather than comparing modification times, ignores saves that
+// do not change the file and catches edits on filesystems with coarse timestamps.
+//
+// fileFingerprintはパスと内容で設定ファイルのバージョンを識別します。更新時刻の比較ではなく
+// 内容のハッシュを使うことで、ファイルを変更しない保存を無視し、タイムスタンプの粒度が
+// 粗いファイルシステムでの編集も検出します。
+type fileFingerprint struct {
+	path string
+	hash [sha256.Size]byte
+}
+
+// newConfigReloader creates a reloader for the configuration the server started with.
+// newConfigReloaderはサーバーの起動時の設定に対するリローダーを作成します。
+func newConfigReloader(cfg *config.Config, docker policyReloader, server toolsServer) *configReloader {
+	r := &configReloader{
+		configPath: cfgFile,
+		docker:     docker,
+		server:     server,
+		applyFlags: func(cfg *config.Config) error {
+			applyServerFlags(cfg)
+			return applyAccessFlags(cfg)
+		},
+		buildHostAccess: newHostAccess,
+		current:         cfg,
+	}
+	r.fingerprint, _ = fingerprintFile(config.ResolvePath(r.configPath))
+	return r
+}
+
+// run watches the configuration file and SIGHUP until ctx is cancelled.
+// runはctxがキャンセルされるまで設定ファイルとSIGHUPを監視します。
+func (r *configReloader) run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("Watching configuration for changes", "path", config.ResolvePath(r.configPath))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload(ctx, "sighup")
+		case <-ticker.C:
+			if r.fileChanged() {
+				r.reload(ctx, "file_change")
+			}
+		}
+	}
+}
+
+// fileChanged reports whether the configuration file differs from the version last seen.
+// A file that cannot be read (e.g., while an editor replaces it) is not a change.
+//
+// fileChangedは設定ファイルが最後に確認したバージョンと異なるかどうかを報告します。
+// 読み取れないファイル（エディタによる置き換え中など）は変更とみなしません。
+func (r *configReloader) fileChanged() bool {
+	fp, err := fingerprintFile(config.ResolvePath(r.configPath))
+	if err != nil {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if fp == r.fingerprint {
+		return false
+	}
+	r.fingerprint = fp
+	return true
+}
+
+// fingerprintFile reads path and returns its fingerprint. An empty path (no configuration
+// file, running on defaults) has an empty fingerprint.
+//
+// fingerprintFileはpathを読み込み、そのフィンガープリントを返します。空のパス
+// （設定ファイルがなくデフォルトで動作中）は空のフィンガープリントを持ちます。
+func fingerprintFile(path string) (fileFingerprint, error) {
+	if path == "" {
+		return fileFingerprint{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileFingerprint{}, err
+	}
+	return fileFingerprint{path: path, hash: sha256.Sum256(data)}, nil
+}
+
+// reload loads and validates the configuration file and, if it is valid, applies it.
+// source ("file_change" or "sighup") is recorded in the logs and the audit log.
+// On error the current configuration stays in effect.
+//
+// reloadは設定ファイルを読み込んで検証し、有効であれば適用します。
+// source（"file_change"または"sighup"）はログと監査ログに記録されます。
+// エラーの場合は現在の設定が有効なままです。
+func (r *configReloader) reload(ctx context.Context, source string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changes, restart, err := r.apply(ctx)
+	if err != nil {
+		slog.Error("Configuration reload failed; keeping the current configuration", "source", source, "error", err)
+		audit.LogConfigReload(ctx, audit.ResultError, map[string]any{"source": source}, err.Error())
+		return err
+	}
+
+	var loosened, tightened, changed []string
+	for _, c := range changes {
+		entry := fmt.Sprintf("%s %s", c.Setting, c.Detail)
+		switch c.Direction {
+		case config.ChangeLoosened:
+			loosened = append(loosened, entry)
+		case config.ChangeTightened:
+			tightened = append(tightened, entry)
+		default:
+			changed = append(changed, entry)
+		}
+	}
+
+	notified := r.server.NotifyToolsListChanged()
+	slog.Info("Configuration reloaded",
+		"source", source,
+		"changes", len(changes),
+		"sessions_notified", notified,
+	)
+	for _, entry := range loosened {
+		slog.Warn("  Loosened", "setting", entry)
+	}
+	for _, entry := range tightened {
+		slog.Info("  Tightened", "setting", entry)
+	}
+	for _, entry := range changed {
+		slog.Info("  Changed", "setting", entry)
+	}
+	if len(restart) > 0 {
+		slog.Warn("Some settings only take effect after a restart", "settings", restart)
+	}
+
+	audit.LogConfigReload(ctx, audit.ResultSuccess, map[string]any{
+		"source":           source,
+		"loosened":         loosened,
+		"tightened":        tightened,
+		"changed":          changed,
+		"restart_required": restart,
+	}, "")
+	return nil
+}
+
+// apply builds the new policy and host access settings and swaps them in. It returns
+// the policy changes and the settings that need a restart. Must be called with r.mu held.
+//
+// applyは新しいポリシーとホストアクセス設定を構築して置き換えます。ポリシーの変更と
+// 再起動が必要な設定を返します。r.muを保持した状態で呼び出す必要があります。
+func (r *configReloader) apply(ctx context.Context) ([]config.Change, []string, error) {
+	cfg, err := config.Load(r.configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.applyFlags(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	// The new policy carries its own output masker, so masking changes take effect with it
+	// 新しいポリシーは独自の出力マスカーを持つため、マスキングの変更もポリシーとともに有効になる
+	if err := r.docker.ReloadPolicy(ctx, security.NewPolicy(&cfg.Security)); err != nil {
+		return nil, nil, err
+	}
+	access := r.buildHostAccess(cfg)
+	r.server.SetHostAccess(access.tools, access.commands, cfg.HostAccess.WorkspaceRoot, access.timeout)
+
+	changes := config.DiffPolicy(r.current, cfg)
+	restart := config.RestartRequired(r.current, cfg)
+	r.current = cfg
+	return changes, restart, nil
+}
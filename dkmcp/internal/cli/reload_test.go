@@ -0,0 +1,171 @@
+// reload_test.go contains tests for hot reloading dkmcp.yaml while the server runs.
+// reload_test.goはサーバー実行中のdkmcp.yamlのホットリロードのテストを含みます。
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/hosttools"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// fakePolicyReloader records the policies swapped in by a reload.
+// fakePolicyReloaderは再読み込みで置き換えられたポリシーを記録します。
+type fakePolicyReloader struct {
+	policy *security.Policy
+	err    error
+}
+
+func (f *fakePolicyReloader) ReloadPolicy(ctx context.Context, policy *security.Policy) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.policy = policy
+	return nil
+}
+
+// fakeToolsServer records host access updates and notifications.
+// fakeToolsServerはホストアクセスの更新と通知を記録します。
+type fakeToolsServer struct {
+	commands      *security.HostCommandPolicy
+	notifications int
+}
+
+func (f *fakeToolsServer) SetHostAccess(manager *hosttools.Manager, policy *security.HostCommandPolicy, workspaceRoot string, timeout time.Duration) {
+	f.commands = policy
+}
+
+func (f *fakeToolsServer) NotifyToolsListChanged() int {
+	f.notifications++
+	return 1
+}
+
+// newTestReloader writes content to a config file and returns a reloader for it.
+// newTestReloaderは設定ファイルにcontentを書き込み、そのリローダーを返します。
+func newTestReloader(t *testing.T, content string) (*configReloader, string, *fakePolicyReloader, *fakeToolsServer) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dkmcp.yaml")
+	writeConfig(t, path, content)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("config.Load() error = %v", err)
+	}
+	docker := &fakePolicyReloader{}
+	server := &fakeToolsServer{}
+	r := &configReloader{
+		configPath:      path,
+		docker:          docker,
+		server:          server,
+		applyFlags:      func(*config.Config) error { return nil },
+		buildHostAccess: newHostAccess,
+		current:         cfg,
+	}
+	r.fingerprint, _ = fingerprintFile(path)
+	return r, path, docker, server
+}
+
+// writeConfig writes a configuration file, failing the test on error.
+// writeConfigは設定ファイルを書き込み、エラー時はテストを失敗させます。
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+const reloadBaseConfig = `
+security:
+  mode: "moderate"
+  allowed_containers:
+    - "api-*"
+`
+
+// TestConfigReloader_Reload tests that a valid change is applied, announced and audited.
+// TestConfigReloader_Reloadは有効な変更が適用・通知・監査されることをテストします。
+func TestConfigReloader_Reload(t *testing.T) {
+	audit.ResetLogger()
+	defer audit.ResetLogger()
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	if err := audit.Initialize(config.AuditConfig{Enabled: true, File: auditFile}); err != nil {
+		t.Fatalf("audit.Initialize() error = %v", err)
+	}
+
+	r, path, docker, server := newTestReloader(t, reloadBaseConfig)
+	if r.fileChanged() {
+		t.Error("fileChanged() = true before the file was modified")
+	}
+
+	writeConfig(t, path, reloadBaseConfig+`
+  exec_whitelist:
+    api-1:
+      - "npm test"
+host_access:
+  workspace_root: "`+t.TempDir()+`"
+  host_commands:
+    enabled: true
+`)
+	if !r.fileChanged() {
+		t.Fatal("fileChanged() = false after the file was modified")
+	}
+	if err := r.reload(context.Background(), "file_change"); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+
+	if docker.policy == nil || len(docker.policy.GetAllowedCommands("api-1")) != 1 {
+		t.Error("Expected the new policy with the api-1 whitelist to be swapped in")
+	}
+	if server.commands == nil {
+		t.Error("Expected host commands to be enabled on the server")
+	}
+	if server.notifications != 1 {
+		t.Errorf("Expected 1 tools/list_changed notification, got %d", server.notifications)
+	}
+	if !r.current.HostAccess.HostCommands.Enabled {
+		t.Error("Expected the reloaded config to become current")
+	}
+
+	audit.GetLogger().Close()
+	data, err := os.ReadFile(auditFile)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	for _, want := range []string{"config_reload", "file_change", "security.exec_whitelist[api-1] +npm test", "host_access.host_commands.enabled false -> true"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Audit log missing %q: %s", want, data)
+		}
+	}
+}
+
+// TestConfigReloader_RejectsInvalidConfig tests that an invalid file or a failed policy swap
+// keeps the current configuration.
+//
+// TestConfigReloader_RejectsInvalidConfigは無効なファイルやポリシー置き換えの失敗で
+// 現在の設定が維持されることをテストします。
+func TestConfigReloader_RejectsInvalidConfig(t *testing.T) {
+	r, path, docker, server := newTestReloader(t, reloadBaseConfig)
+	current := r.current
+
+	writeConfig(t, path, "security:\n  mode: \"reckless\"\n")
+	if err := r.reload(context.Background(), "sighup"); err == nil {
+		t.Error("reload() should reject an invalid security mode")
+	}
+
+	writeConfig(t, path, reloadBaseConfig)
+	docker.err = errors.New("docker unavailable")
+	if err := r.reload(context.Background(), "sighup"); err == nil {
+		t.Error("reload() should fail when the policy cannot be swapped")
+	}
+
+	if r.current != current || docker.policy != nil || server.notifications != 0 {
+		t.Error("A failed reload must keep the current configuration and notify nobody")
+	}
+}
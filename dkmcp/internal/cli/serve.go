@@ -18,8 +18,11 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/cmdrule"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/history"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/hosttools"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/mcp"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
@@ -230,23 +233,7 @@ func runServe(cmd *cobra.Command, args []string) error {
 	//
 	// コマンドラインフラグで設定を上書きします。
 	// CLIフラグは設定ファイルの設定よりも優先されます。
-	if flagPort > 0 {
-		cfg.Server.Port = flagPort
-	}
-	if flagHost != "" {
-		cfg.Server.Host = flagHost
-	}
-	if flagLogLevel != "" {
-		cfg.Logging.Level = flagLogLevel
-	}
-
-	// Override log level based on verbosity level
-	// verbosityレベルに基づいてログレベルを上書き
-	// -vv, -vvv, -vvvv set log level to debug
-	// -vv以上はログレベルをdebugに設定
-	if flagVerbosity >= 2 {
-		cfg.Logging.Level = "debug"
-	}
+	applyServerFlags(cfg)
 
 	// Parse and set the log level based on configuration.
 	// Convert string level (debug/info/warn/error) to slog.Level.
@@ -306,37 +293,9 @@ func runServe(cmd *cobra.Command, args []string) error {
 		slog.SetDefault(logger)
 	}
 
-	// Apply --workspace flag to override host_access.workspace_root
-	// --workspaceフラグでhost_access.workspace_rootを上書き
-	if flagWorkspace != "" {
-		cfg.HostAccess.WorkspaceRoot = flagWorkspace
-	}
-
-	// Resolve workspace root to absolute path for consistent logging and operations
-	// ログと操作の一貫性のためにワークスペースルートを絶対パスに変換
-	if cfg.HostAccess.WorkspaceRoot != "" {
-		absPath, err := filepath.Abs(cfg.HostAccess.WorkspaceRoot)
-		if err != nil {
-			return fmt.Errorf("failed to resolve workspace path %q: %w", cfg.HostAccess.WorkspaceRoot, err)
-		}
-		cfg.HostAccess.WorkspaceRoot = absPath
-	}
-
-	// Apply --host-dangerously flag to enable dangerous mode for host commands
-	// --host-dangerouslyフラグでホストコマンドの危険モードを有効化
-	if flagHostDangerously {
-		cfg.HostAccess.HostCommands.Dangerously.Enabled = true
-	}
-
-	// Parse and apply --allow-exec flags for temporary command whitelisting.
-	// --allow-execフラグを解析して一時的なコマンドホワイトリストを適用します。
-	if err := applyAllowExecFlags(cfg, flagAllowExec); err != nil {
-		return err
-	}
-
-	// Parse and apply --dangerously and --dangerously-all flags.
-	// --dangerouslyおよび--dangerously-allフラグを解析して適用します。
-	if err := applyDangerouslyFlags(cfg, flagDangerously, flagDangerouslyAll); err != nil {
+	// Apply the flags that adjust security and host access settings.
+	// セキュリティとホストアクセスの設定を調整するフラグを適用します。
+	if err := applyAccessFlags(cfg); err != nil {
 		return err
 	}
 
@@ -365,6 +324,15 @@ func runServe(cmd *cobra.Command, args []string) error {
 		slog.Info("Verbosity mode enabled", "level", flagVerbosity, "description", verbosityDesc[level])
 	}
 
+	// Initialize audit logging so security-relevant events such as access denials are recorded.
+	// アクセス拒否などのセキュリティ関連イベントが記録されるよう監査ログを初期化します。
+	if cfg.Audit.Enabled {
+		if err := audit.Initialize(cfg.Audit); err != nil {
+			return fmt.Errorf("failed to initialize audit logging: %w", err)
+		}
+		defer audit.GetLogger().Close()
+	}
+
 	// Create security policy from configuration.
 	// The policy enforces container access rules and command whitelisting.
 	//
@@ -399,8 +367,9 @@ func runServe(cmd *cobra.Command, args []string) error {
 			//
 			// 許可パターンに一致するコンテナがない場合は警告します。
 			// これは設定ミスを示している可能性があります。
-			slog.Warn("No accessible containers found matching the allowed patterns",
-				"patterns", cfg.Security.AllowedContainers)
+			slog.Warn("No accessible containers found matching the allowed patterns or labels",
+				"patterns", cfg.Security.AllowedContainers,
+				"labels", cfg.Security.AllowedLabels)
 		} else {
 			// Log the count and details of accessible containers.
 			// アクセス可能なコンテナの数と詳細をログに出力します。
@@ -445,12 +414,13 @@ func runServe(cmd *cobra.Command, args []string) error {
 		serverOpts = append(serverOpts, mcp.WithVerbosity(flagVerbosity))
 	}
 
-	// Configure host tools if enabled
-	// ホストツールが有効な場合は設定
-	if cfg.HostAccess.HostTools.Enabled {
-		// Run sync if --sync flag is set and secure mode is configured
-		// --syncフラグが設定されていてセキュアモードが構成されている場合に同期を実行
-		if flagSync && cfg.HostAccess.HostTools.IsSecureMode() {
+	// Run sync if --sync flag is set and secure mode is configured.
+	// Sync is interactive, so it only runs at startup and not on reload.
+	//
+	// --syncフラグが設定されていてセキュアモードが構成されている場合に同期を実行します。
+	// 同期は対話的なため、起動時のみ実行し再読み込み時には実行しません。
+	if cfg.HostAccess.HostTools.Enabled && flagSync {
+		if cfg.HostAccess.HostTools.IsSecureMode() {
 			syncMgr := hosttools.NewSyncManager(&cfg.HostAccess.HostTools, cfg.HostAccess.WorkspaceRoot)
 			synced, err := syncMgr.RunInteractiveSync()
 			if err != nil {
@@ -459,55 +429,42 @@ func runServe(cmd *cobra.Command, args []string) error {
 			if synced > 0 {
 				slog.Info("Host tools synced", "count", synced)
 			}
-		} else if flagSync && !cfg.HostAccess.HostTools.IsSecureMode() {
+		} else {
 			slog.Warn("--sync flag ignored: host_tools.approved_dir is not configured (legacy mode)")
 		}
+	}
 
-		htManager := hosttools.NewManager(&cfg.HostAccess.HostTools, cfg.HostAccess.WorkspaceRoot)
-
-		// Enable dev mode if --dev flag is set and secure mode is configured
-		// --devフラグが設定されていてセキュアモードが構成されている場合に開発モードを有効化
-		if flagDev && cfg.HostAccess.HostTools.IsSecureMode() {
-			htManager.SetDevMode(true)
-			slog.Warn("Development mode: staging tools are directly executable (not approved)",
-				"staging_dirs", cfg.HostAccess.HostTools.StagingDirs,
-			)
-		} else if flagDev && !cfg.HostAccess.HostTools.IsSecureMode() {
-			slog.Warn("--dev flag ignored: host_tools.approved_dir is not configured (legacy mode)")
-		}
-
-		serverOpts = append(serverOpts, mcp.WithHostToolsManager(htManager))
+	if cfg.HostAccess.WorkspaceRoot != "" {
+		serverOpts = append(serverOpts, mcp.WithWorkspaceRoot(cfg.HostAccess.WorkspaceRoot))
+	}
 
-		if cfg.HostAccess.HostTools.IsSecureMode() {
-			projectDir, _ := hosttools.ProjectApprovedDir(cfg.HostAccess.HostTools.ApprovedDir, cfg.HostAccess.WorkspaceRoot)
-			slog.Info("Host tools enabled (secure mode)",
-				"approved_dir", projectDir,
-				"staging_dirs", cfg.HostAccess.HostTools.StagingDirs,
-				"common", cfg.HostAccess.HostTools.Common,
-				"extensions", cfg.HostAccess.HostTools.AllowedExtensions,
-			)
-		} else {
-			slog.Info("Host tools enabled (legacy mode)",
-				"workspace", cfg.HostAccess.WorkspaceRoot,
-				"directories", cfg.HostAccess.HostTools.Directories,
-				"extensions", cfg.HostAccess.HostTools.AllowedExtensions,
-			)
-		}
+	// Configure host tools and host commands if enabled
+	// ホストツールとホストコマンドが有効な場合は設定
+	access := newHostAccess(cfg)
+	if access.tools != nil {
+		serverOpts = append(serverOpts, mcp.WithHostToolsManager(access.tools))
+	}
+	if access.commands != nil {
+		serverOpts = append(serverOpts, mcp.WithHostCommandPolicy(access.commands, cfg.HostAccess.WorkspaceRoot, access.timeout))
 	}
 
-	// Configure host commands if enabled
-	// ホストコマンドが有効な場合は設定
-	if cfg.HostAccess.HostCommands.Enabled {
-		hcPolicy := security.NewHostCommandPolicy(&cfg.HostAccess.HostCommands)
-		timeout := time.Duration(cfg.HostAccess.HostTools.Timeout) * time.Second
-		if timeout <= 0 {
-			timeout = 60 * time.Second
+	// Open the metrics history store if the recorder is enabled. The recorder itself
+	// starts with the shutdown context below.
+	//
+	// レコーダーが有効な場合はメトリクス履歴ストアを開きます。レコーダー自体は
+	// 下のシャットダウン用のコンテキストと共に開始します。
+	var historyStore *history.Store
+	if cfg.MetricsHistory.Enabled {
+		dir, err := history.ResolveDir(cfg.MetricsHistory.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve metrics history directory: %w", err)
 		}
-		serverOpts = append(serverOpts, mcp.WithHostCommandPolicy(hcPolicy, cfg.HostAccess.WorkspaceRoot, timeout))
-		slog.Info("Host commands enabled",
-			"workspace", cfg.HostAccess.WorkspaceRoot,
-			"dangerously", cfg.HostAccess.HostCommands.Dangerously.Enabled,
-		)
+		historyStore, err = history.OpenStore(dir, cfg.MetricsHistory.MaxBytes)
+		if err != nil {
+			return fmt.Errorf("failed to open metrics history: %w", err)
+		}
+		defer historyStore.Close()
+		serverOpts = append(serverOpts, mcp.WithMetricsHistory(historyStore))
 	}
 
 	mcpServer := mcp.NewServer(dockerClient, cfg.Server.Port, serverOpts...)
@@ -531,6 +488,7 @@ func runServe(cmd *cobra.Command, args []string) error {
 		"url", fmt.Sprintf("http://%s", addr),
 		"health_check", fmt.Sprintf("http://%s/health", addr),
 		"sse_endpoint", fmt.Sprintf("http://%s/sse", addr),
+		"mcp_endpoint", fmt.Sprintf("http://%s/mcp", addr),
 	)
 	slog.Info("Press Ctrl+C to stop")
 
@@ -542,6 +500,27 @@ func runServe(cmd *cobra.Command, args []string) error {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// Reload dkmcp.yaml when it changes or on SIGHUP, without restarting the server.
+	// dkmcp.yamlが変更された時やSIGHUP受信時に、サーバーを再起動せずに再読み込みします。
+	reloader := newConfigReloader(cfg, dockerClient, mcpServer)
+	go reloader.run(ctx, configPollInterval)
+
+	// Notify sessions when an allowed container dies, is OOM killed or changes health.
+	// 許可されたコンテナが停止、OOMキル、またはヘルス変化した時にセッションへ通知します。
+	go mcpServer.WatchContainerEvents(ctx)
+
+	// Record the stats and state of the accessible containers in the background.
+	// アクセス可能なコンテナの統計と状態をバックグラウンドで記録します。
+	if historyStore != nil {
+		interval := time.Duration(cfg.MetricsHistory.Interval) * time.Second
+		slog.Info("Metrics history enabled",
+			"dir", historyStore.Dir(),
+			"interval", interval,
+			"max_bytes", cfg.MetricsHistory.MaxBytes,
+		)
+		go history.NewRecorder(dockerClient, historyStore, interval).Run(ctx)
+	}
+
 	// Wait for either server error or shutdown signal.
 	// サーバーエラーまたはシャットダウンシグナルを待機します。
 	select {
@@ -568,6 +547,142 @@ func runServe(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// applyServerFlags overrides the server and logging settings with command-line flags.
+// These settings only take effect at startup.
+//
+// applyServerFlagsはサーバーとロギングの設定をコマンドラインフラグで上書きします。
+// これらの設定は起動時のみ有効です。
+func applyServerFlags(cfg *config.Config) {
+	if flagPort > 0 {
+		cfg.Server.Port = flagPort
+	}
+	if flagHost != "" {
+		cfg.Server.Host = flagHost
+	}
+	if flagLogLevel != "" {
+		cfg.Logging.Level = flagLogLevel
+	}
+
+	// Override log level based on verbosity level
+	// verbosityレベルに基づいてログレベルを上書き
+	// -vv, -vvv, -vvvv set log level to debug
+	// -vv以上はログレベルをdebugに設定
+	if flagVerbosity >= 2 {
+		cfg.Logging.Level = "debug"
+	}
+}
+
+// applyAccessFlags applies the command-line flags that adjust security and host access
+// settings. It runs at startup and again on every reload, so the flags keep taking
+// precedence over the reloaded configuration file.
+//
+// applyAccessFlagsはセキュリティとホストアクセスの設定を調整するコマンドラインフラグを
+// 適用します。起動時と再読み込みのたびに実行されるため、フラグは再読み込みされた
+// 設定ファイルよりも引き続き優先されます。
+func applyAccessFlags(cfg *config.Config) error {
+	// Apply --workspace flag to override host_access.workspace_root
+	// --workspaceフラグでhost_access.workspace_rootを上書き
+	if flagWorkspace != "" {
+		cfg.HostAccess.WorkspaceRoot = flagWorkspace
+	}
+
+	// Resolve workspace root to absolute path for consistent logging and operations
+	// ログと操作の一貫性のためにワークスペースルートを絶対パスに変換
+	if cfg.HostAccess.WorkspaceRoot != "" {
+		absPath, err := filepath.Abs(cfg.HostAccess.WorkspaceRoot)
+		if err != nil {
+			return fmt.Errorf("failed to resolve workspace path %q: %w", cfg.HostAccess.WorkspaceRoot, err)
+		}
+		cfg.HostAccess.WorkspaceRoot = absPath
+	}
+
+	// Apply --host-dangerously flag to enable dangerous mode for host commands
+	// --host-dangerouslyフラグでホストコマンドの危険モードを有効化
+	if flagHostDangerously {
+		cfg.HostAccess.HostCommands.Dangerously.Enabled = true
+	}
+
+	// Parse and apply --allow-exec flags for temporary command whitelisting.
+	// --allow-execフラグを解析して一時的なコマンドホワイトリストを適用します。
+	if err := applyAllowExecFlags(cfg, flagAllowExec); err != nil {
+		return err
+	}
+
+	// Parse and apply --dangerously and --dangerously-all flags.
+	// --dangerouslyおよび--dangerously-allフラグを解析して適用します。
+	return applyDangerouslyFlags(cfg, flagDangerously, flagDangerouslyAll)
+}
+
+// hostAccess holds the host tools manager and host command policy built from a configuration.
+// hostAccessは設定から構築したホストツールマネージャーとホストコマンドポリシーを保持します。
+type hostAccess struct {
+	// tools is nil when host tools are disabled
+	// toolsはホストツールが無効な場合nilです
+	tools *hosttools.Manager
+
+	// commands is nil when host commands are disabled
+	// commandsはホストコマンドが無効な場合nilです
+	commands *security.HostCommandPolicy
+
+	// timeout is the host command timeout
+	// timeoutはホストコマンドのタイムアウトです
+	timeout time.Duration
+}
+
+// newHostAccess builds the host tools manager and host command policy for cfg,
+// honoring the --dev flag. It is used at startup and on reload.
+//
+// newHostAccessはcfgに対するホストツールマネージャーとホストコマンドポリシーを
+// --devフラグを考慮して構築します。起動時と再読み込み時に使用されます。
+func newHostAccess(cfg *config.Config) hostAccess {
+	var access hostAccess
+
+	if cfg.HostAccess.HostTools.Enabled {
+		access.tools = hosttools.NewManager(&cfg.HostAccess.HostTools, cfg.HostAccess.WorkspaceRoot)
+
+		// Enable dev mode if --dev flag is set and secure mode is configured
+		// --devフラグが設定されていてセキュアモードが構成されている場合に開発モードを有効化
+		if flagDev && cfg.HostAccess.HostTools.IsSecureMode() {
+			access.tools.SetDevMode(true)
+			slog.Warn("Development mode: staging tools are directly executable (not approved)",
+				"staging_dirs", cfg.HostAccess.HostTools.StagingDirs,
+			)
+		} else if flagDev && !cfg.HostAccess.HostTools.IsSecureMode() {
+			slog.Warn("--dev flag ignored: host_tools.approved_dir is not configured (legacy mode)")
+		}
+
+		if cfg.HostAccess.HostTools.IsSecureMode() {
+			projectDir, _ := hosttools.ProjectApprovedDir(cfg.HostAccess.HostTools.ApprovedDir, cfg.HostAccess.WorkspaceRoot)
+			slog.Info("Host tools enabled (secure mode)",
+				"approved_dir", projectDir,
+				"staging_dirs", cfg.HostAccess.HostTools.StagingDirs,
+				"common", cfg.HostAccess.HostTools.Common,
+				"extensions", cfg.HostAccess.HostTools.AllowedExtensions,
+			)
+		} else {
+			slog.Info("Host tools enabled (legacy mode)",
+				"workspace", cfg.HostAccess.WorkspaceRoot,
+				"directories", cfg.HostAccess.HostTools.Directories,
+				"extensions", cfg.HostAccess.HostTools.AllowedExtensions,
+			)
+		}
+	}
+
+	if cfg.HostAccess.HostCommands.Enabled {
+		access.commands = security.NewHostCommandPolicy(&cfg.HostAccess.HostCommands)
+		access.timeout = time.Duration(cfg.HostAccess.HostTools.Timeout) * time.Second
+		if access.timeout <= 0 {
+			access.timeout = 60 * time.Second
+		}
+		slog.Info("Host commands enabled",
+			"workspace", cfg.HostAccess.WorkspaceRoot,
+			"dangerously", cfg.HostAccess.HostCommands.Dangerously.Enabled,
+		)
+	}
+
+	return access
+}
+
 // showBanner displays the ASCII art banner to stdout.
 //
 // showBannerはASCIIアートバナーをstdoutに表示します。
@@ -669,6 +784,12 @@ func applyAllowExecFlags(cfg *config.Config, allowExecFlags []string) error {
 			return fmt.Errorf("invalid --allow-exec format: %s (container and command cannot be empty)", entry)
 		}
 
+		// Reject rules the policy could not compile, as config.Validate does for the file.
+		// config.Validateがファイルに対して行うように、ポリシーがコンパイルできないルールを拒否します。
+		if _, err := cmdrule.Compile(command, nil); err != nil {
+			return fmt.Errorf("invalid --allow-exec rule: %s: %w", entry, err)
+		}
+
 		// Add to exec whitelist, creating the map if necessary.
 		// execホワイトリストに追加します。必要に応じてマップを作成します。
 		if cfg.Security.ExecWhitelist == nil {
@@ -180,6 +180,22 @@ func TestApplyAllowExecFlags(t *testing.T) {
 				// エラー時に設定は変更されないべきです。
 			},
 		},
+		{
+			// Test case: a rule the policy cannot compile should return error.
+			// テストケース：ポリシーがコンパイルできないルールがエラーを返すこと。
+			name:  "invalid rule - unknown placeholder",
+			flags: []string{"mycontainer:cat <file>"},
+			initialConfig: &config.Config{
+				Security: config.SecurityConfig{
+					ExecWhitelist: nil,
+				},
+			},
+			wantErr: true,
+			validate: func(t *testing.T, cfg *config.Config) {
+				// Config should not be modified on error.
+				// エラー時に設定は変更されないべきです。
+			},
+		},
 		{
 			// Test case: new flag should append to existing whitelist.
 			// テストケース：新しいフラグが既存のホワイトリストに追加されること。
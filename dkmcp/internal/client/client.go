@@ -1,10 +1,12 @@
 // Package client provides an HTTP client for interacting with the DockMCP server.
-// It implements the MCP (Model Context Protocol) over SSE (Server-Sent Events) and HTTP POST,
-// allowing AI assistants to call tools on the DockMCP server such as listing containers,
-// getting logs, executing commands, etc.
+// It implements the MCP (Model Context Protocol) over both the Streamable HTTP transport
+// and the legacy SSE (Server-Sent Events) + HTTP POST transport, allowing AI assistants
+// to call tools on the DockMCP server such as listing containers, getting logs,
+// executing commands, etc.
 //
 // clientパッケージはDockMCPサーバーと通信するためのHTTPクライアントを提供します。
-// MCP（Model Context Protocol）をSSE（Server-Sent Events）とHTTP POSTで実装し、
+// MCP（Model Context Protocol）をStreamable HTTPトランスポートと、レガシーの
+// SSE（Server-Sent Events）+ HTTP POSTトランスポートの両方で実装し、
 // AIアシスタントがDockMCPサーバー上のツール（コンテナ一覧取得、ログ取得、
 // コマンド実行など）を呼び出すことを可能にします。
 package client
@@ -14,6 +16,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -29,6 +32,33 @@ import (
 // これは通常、ビルド時にldflagsを使用して設定されます。
 var clientVersion string
 
+// Transport names accepted by SetTransport and reported by Transport.
+// SetTransportが受け付け、Transportが報告するトランスポート名です。
+const (
+	// TransportAuto tries Streamable HTTP first and falls back to legacy SSE.
+	// TransportAutoはまずStreamable HTTPを試し、レガシーSSEにフォールバックします。
+	TransportAuto = "auto"
+
+	// TransportHTTP is the single-endpoint Streamable HTTP transport (POST/GET/DELETE /mcp).
+	// TransportHTTPは単一エンドポイントのStreamable HTTPトランスポートです（POST/GET/DELETE /mcp）。
+	TransportHTTP = "http"
+
+	// TransportSSE is the legacy two-endpoint transport (GET /sse + POST /message).
+	// TransportSSEはレガシーの2エンドポイントトランスポートです（GET /sse + POST /message）。
+	TransportSSE = "sse"
+)
+
+// mcpSessionHeader is the HTTP header carrying the Streamable HTTP session ID.
+// mcpSessionHeaderはStreamable HTTPのセッションIDを運ぶHTTPヘッダーです。
+const mcpSessionHeader = "Mcp-Session-Id"
+
+// errStreamableUnsupported is returned when the server has no Streamable HTTP endpoint,
+// which signals that auto negotiation should fall back to legacy SSE.
+//
+// errStreamableUnsupportedはサーバーにStreamable HTTPエンドポイントがない場合に返され、
+// 自動ネゴシエーションがレガシーSSEにフォールバックすべきことを示します。
+var errStreamableUnsupported = errors.New("server does not support the Streamable HTTP transport")
+
 // Client is an HTTP client for DockMCP server that manages SSE connections
 // and JSON-RPC communication with the MCP server.
 //
@@ -36,8 +66,10 @@ var clientVersion string
 // - baseURL: The base URL of the DockMCP server (e.g., "http://localhost:8080")
 // - httpClient: Standard HTTP client with timeout for regular requests
 // - sseHTTPClient: HTTP client without timeout for long-lived SSE connections
-// - sessionID: Unique identifier for the MCP session, obtained during SSE connection
-// - sseConn: The active SSE connection response
+// - sessionID: Unique identifier for the MCP session, obtained during connection
+// - transport: Requested transport (TransportAuto, TransportHTTP or TransportSSE)
+// - connectedTransport: Transport actually negotiated by Connect
+// - sseConn: The active SSE connection response (legacy SSE transport only)
 // - messages: Channel for receiving parsed SSE messages
 // - errors: Channel for receiving SSE connection errors
 // - ctx/cancel: Context for managing client lifecycle and cancellation
@@ -50,24 +82,28 @@ var clientVersion string
 // - baseURL: DockMCPサーバーのベースURL（例："http://localhost:8080"）
 // - httpClient: 通常リクエスト用のタイムアウト付き標準HTTPクライアント
 // - sseHTTPClient: 長期接続のSSE用タイムアウトなしHTTPクライアント
-// - sessionID: SSE接続時に取得するMCPセッションの一意識別子
-// - sseConn: アクティブなSSE接続レスポンス
+// - sessionID: 接続時に取得するMCPセッションの一意識別子
+// - transport: 要求するトランスポート（TransportAuto、TransportHTTP、TransportSSE）
+// - connectedTransport: Connectで実際にネゴシエートされたトランスポート
+// - sseConn: アクティブなSSE接続レスポンス（レガシーSSEトランスポートのみ）
 // - messages: 解析済みSSEメッセージを受信するチャネル
 // - errors: SSE接続エラーを受信するチャネル
 // - ctx/cancel: クライアントのライフサイクルとキャンセル管理用コンテキスト
 // - mu: 共有状態へのスレッドセーフなアクセス用ミューテックス
 type Client struct {
-	baseURL       string
-	httpClient    *http.Client
-	sseHTTPClient *http.Client
-	sessionID     string
-	sseConn       *http.Response
-	messages      chan []byte
-	errors        chan error
-	ctx           context.Context
-	cancel        context.CancelFunc
-	mu            sync.Mutex
-	clientSuffix  string // Suffix appended to client name / クライアント名に追加されるサフィックス
+	baseURL            string
+	httpClient         *http.Client
+	sseHTTPClient      *http.Client
+	sessionID          string
+	transport          string
+	connectedTransport string
+	sseConn            *http.Response
+	messages           chan []byte
+	errors             chan error
+	ctx                context.Context
+	cancel             context.CancelFunc
+	mu                 sync.Mutex
+	clientSuffix       string // Suffix appended to client name / クライアント名に追加されるサフィックス
 }
 
 // NewClient creates a new DockMCP HTTP client configured to connect to the specified server.
@@ -110,9 +146,10 @@ func NewClient(baseURL string) *Client {
 		messages: make(chan []byte, 10),
 		// Error channel with capacity 1 for SSE connection errors
 		// SSE接続エラー用の容量1のエラーチャネル
-		errors: make(chan error, 1),
-		ctx:    ctx,
-		cancel: cancel,
+		errors:    make(chan error, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+		transport: TransportAuto,
 	}
 }
 
@@ -127,7 +164,131 @@ func (c *Client) SetClientSuffix(suffix string) {
 	c.clientSuffix = suffix
 }
 
-// Connect establishes an SSE connection to the DockMCP server, retrieves the session ID,
+// SetTransport selects which MCP transport Connect uses.
+// TransportAuto (the default) prefers Streamable HTTP and falls back to legacy SSE
+// when the server does not provide the /mcp endpoint.
+//
+// SetTransportはConnectが使用するMCPトランスポートを選択します。
+// TransportAuto（デフォルト）はStreamable HTTPを優先し、サーバーが/mcpエンドポイントを
+// 提供していない場合はレガシーSSEにフォールバックします。
+func (c *Client) SetTransport(transport string) {
+	c.transport = transport
+}
+
+// Transport returns the transport negotiated by Connect (TransportHTTP or TransportSSE),
+// or an empty string when the client is not connected.
+//
+// TransportはConnectでネゴシエートされたトランスポート（TransportHTTPまたはTransportSSE）を返します。
+// 未接続の場合は空文字列を返します。
+func (c *Client) Transport() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connectedTransport
+}
+
+// Connect connects to the DockMCP server using the configured transport and performs
+// the MCP initialization handshake. With TransportAuto, Streamable HTTP is tried first
+// and legacy SSE is used only if the server answers /mcp with 404 or 405.
+//
+// This method is idempotent - if already connected, it returns immediately without error.
+//
+// Connectは設定されたトランスポートでDockMCPサーバーに接続し、MCP初期化ハンドシェイクを
+// 実行します。TransportAutoの場合、まずStreamable HTTPを試し、サーバーが/mcpに
+// 404または405を返した場合にのみレガシーSSEを使用します。
+//
+// このメソッドは冪等です - 既に接続済みの場合、エラーなしで即座に戻ります。
+func (c *Client) Connect() error {
+	c.mu.Lock()
+	if c.sessionID != "" {
+		c.mu.Unlock()
+		return nil // Already connected / 既に接続済み
+	}
+	transport := c.transport
+	c.mu.Unlock()
+
+	switch transport {
+	case TransportSSE:
+		return c.connectSSE()
+	case TransportHTTP:
+		return c.connectStreamable()
+	default:
+		// Prefer Streamable HTTP; older servers only expose /sse
+		// Streamable HTTPを優先。古いサーバーは/sseのみ公開している
+		err := c.connectStreamable()
+		if errors.Is(err, errStreamableUnsupported) {
+			return c.connectSSE()
+		}
+		return err
+	}
+}
+
+// SessionID returns the MCP session ID of the connection, or "" when not connected.
+// SessionIDは接続のMCPセッションIDを返します。未接続の場合は""を返します。
+func (c *Client) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// Resume attaches the client to an existing Streamable HTTP session instead of starting a
+// new one, so a later process can continue a session that was left open with Detach.
+// The session is checked with a tools/list request; an error means the server no longer
+// has it and Connect should be used instead.
+//
+// ResumeはクライアントをStreamable HTTPの新しいセッションを開始する代わりに既存のセッションに
+// 接続し、Detachで開いたままにしたセッションを後のプロセスが継続できるようにします。
+// セッションはtools/listリクエストで確認されます。エラーはサーバーがそのセッションを
+// もう持っていないことを意味し、代わりにConnectを使用する必要があります。
+func (c *Client) Resume(sessionID string) error {
+	c.mu.Lock()
+	connected, transport := c.sessionID != "", c.transport
+	c.mu.Unlock()
+	if connected {
+		return fmt.Errorf("already connected")
+	}
+	if transport == TransportSSE {
+		return fmt.Errorf("only Streamable HTTP sessions can be resumed")
+	}
+
+	body, err := json.Marshal(JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	msg, err := c.sendStreamable(body, sessionID)
+	if err != nil {
+		return err
+	}
+	var resp JSONRPCResponse
+	if err := json.Unmarshal(msg, &resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("JSON-RPC error: %s (code %d)", resp.Error.Message, resp.Error.Code)
+	}
+
+	c.mu.Lock()
+	c.sessionID = sessionID
+	c.connectedTransport = TransportHTTP
+	c.mu.Unlock()
+	return nil
+}
+
+// Detach closes the client like Close, but leaves a Streamable HTTP session open on the
+// server so that it can be resumed with Resume. The server expires it once it is idle.
+//
+// DetachはCloseと同様にクライアントを閉じますが、Resumeで再開できるようにStreamable HTTPの
+// セッションをサーバー上で開いたままにします。サーバーはアイドル状態になると期限切れにします。
+func (c *Client) Detach() error {
+	c.mu.Lock()
+	if c.connectedTransport == TransportHTTP {
+		c.sessionID = ""
+		c.connectedTransport = ""
+	}
+	c.mu.Unlock()
+	return c.Close()
+}
+
+// connectSSE establishes an SSE connection to the DockMCP server, retrieves the session ID,
 // and performs the MCP initialization handshake.
 //
 // The connection process:
@@ -142,7 +303,7 @@ func (c *Client) SetClientSuffix(suffix string) {
 //   - nil on successful connection and initialization
 //   - error if connection or initialization fails
 //
-// ConnectはDockMCPサーバーへのSSE接続を確立し、セッションIDを取得し、
+// connectSSEはDockMCPサーバーへのSSE接続を確立し、セッションIDを取得し、
 // MCP初期化ハンドシェイクを実行します。
 //
 // 接続プロセス：
@@ -156,7 +317,7 @@ func (c *Client) SetClientSuffix(suffix string) {
 // 戻り値：
 //   - 接続と初期化が成功した場合はnil
 //   - 接続または初期化が失敗した場合はerror
-func (c *Client) Connect() error {
+func (c *Client) connectSSE() error {
 	c.mu.Lock()
 	// Unlock is deferred to the end of the function
 	// Unlockは関数の終わりまで遅延される
@@ -221,6 +382,7 @@ func (c *Client) Connect() error {
 						// エンドポイントURLからsessionIdを抽出（例：「/message?sessionId=client-123」）
 						if idx := strings.Index(endpoint, "sessionId="); idx != -1 {
 							c.sessionID = endpoint[idx+len("sessionId="):]
+							c.connectedTransport = TransportSSE
 							// Start background goroutine to read SSE messages
 							// SSEメッセージを読み取るバックグラウンドgoroutineを開始
 							go c.readSSEMessages()
@@ -274,32 +436,9 @@ func (c *Client) Connect() error {
 //   - 初期化が成功した場合はnil
 //   - ハンドシェイクが失敗またはタイムアウトした場合はerror
 func (c *Client) initialize() error {
-	// Build client name with optional suffix
-	// オプションのサフィックスを含むクライアント名を構築
-	clientName := "dkmcp-go-client"
-	if c.clientSuffix != "" {
-		clientName = clientName + "_" + c.clientSuffix
-	}
-
-	// Create the JSON-RPC initialize request following MCP
-	// MCPプロトコルに従ったJSON-RPC初期化リクエストを作成
-	req := JSONRPCRequest{
-		JSONRPC: "2.0",
-		ID:      0, // ID for initialize is often 0 / initializeのIDは通常0
-		Method:  "initialize",
-		Params: map[string]interface{}{
-			"clientInfo": map[string]string{
-				"name":    clientName,
-				"version": clientVersion,
-			},
-		},
-	}
-
-	// Marshal the request to JSON
-	// リクエストをJSONにマーシャル
-	body, err := json.Marshal(req)
+	body, err := c.initializeRequestBody()
 	if err != nil {
-		return fmt.Errorf("failed to marshal initialize request: %w", err)
+		return err
 	}
 
 	// Get session ID in a thread-safe manner
@@ -358,6 +497,191 @@ func (c *Client) initialize() error {
 	}
 }
 
+// initializeRequestBody builds the JSON-encoded MCP initialize request shared by both transports.
+// The client name is "dkmcp-go-client", with "_<suffix>" appended when a suffix is set.
+//
+// initializeRequestBodyは両トランスポートで共通のJSONエンコード済みMCP初期化リクエストを構築します。
+// クライアント名は"dkmcp-go-client"で、サフィックスが設定されている場合は"_<suffix>"が追加されます。
+func (c *Client) initializeRequestBody() ([]byte, error) {
+	// Build client name with optional suffix
+	// オプションのサフィックスを含むクライアント名を構築
+	clientName := "dkmcp-go-client"
+	if c.clientSuffix != "" {
+		clientName = clientName + "_" + c.clientSuffix
+	}
+
+	// Create the JSON-RPC initialize request following MCP
+	// MCPプロトコルに従ったJSON-RPC初期化リクエストを作成
+	req := JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      0, // ID for initialize is often 0 / initializeのIDは通常0
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"clientInfo": map[string]string{
+				"name":    clientName,
+				"version": clientVersion,
+			},
+		},
+	}
+
+	// Marshal the request to JSON
+	// リクエストをJSONにマーシャル
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal initialize request: %w", err)
+	}
+	return body, nil
+}
+
+// connectStreamable connects using the Streamable HTTP transport.
+//
+// The connection process:
+// 1. POSTs the initialize request to /mcp
+// 2. Takes the session ID from the Mcp-Session-Id response header
+// 3. Reads the initialize response (JSON body or SSE stream)
+// 4. Sends the notifications/initialized notification to complete the handshake
+//
+// Returns errStreamableUnsupported when the server answers with 404 or 405,
+// meaning it only provides the legacy SSE transport.
+//
+// connectStreamableはStreamable HTTPトランスポートで接続します。
+//
+// 接続プロセス：
+// 1. 初期化リクエストを/mcpにPOST
+// 2. Mcp-Session-IdレスポンスヘッダーからセッションIDを取得
+// 3. 初期化レスポンス（JSONボディまたはSSEストリーム）を読み取る
+// 4. notifications/initialized通知を送信してハンドシェイクを完了
+//
+// サーバーが404または405を返した場合（レガシーSSEトランスポートのみ提供）は
+// errStreamableUnsupportedを返します。
+func (c *Client) connectStreamable() error {
+	body, err := c.initializeRequestBody()
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.postStreamable(body, "")
+	if err != nil {
+		return fmt.Errorf("failed to send initialize request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return errStreamableUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned error on initialize: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	sessionID := resp.Header.Get(mcpSessionHeader)
+	if sessionID == "" {
+		return fmt.Errorf("server did not return an %s header on initialize", mcpSessionHeader)
+	}
+
+	msg, err := readStreamableMessage(resp)
+	if err != nil {
+		return fmt.Errorf("failed to read initialize response: %w", err)
+	}
+
+	// Parse the initialize response
+	// 初期化レスポンスを解析
+	var jsonrpcResp InitializeResponse
+	if err := json.Unmarshal(msg, &jsonrpcResp); err != nil {
+		return fmt.Errorf("failed to decode initialize response: %w", err)
+	}
+	if jsonrpcResp.Error != nil {
+		return fmt.Errorf("JSON-RPC error on initialize: %s (code %d)", jsonrpcResp.Error.Message, jsonrpcResp.Error.Code)
+	}
+
+	c.mu.Lock()
+	c.sessionID = sessionID
+	c.connectedTransport = TransportHTTP
+	c.mu.Unlock()
+
+	// Tell the server the handshake is complete
+	// ハンドシェイクの完了をサーバーに通知
+	notification, err := json.Marshal(jsonrpcNotification{JSONRPC: "2.0", Method: "notifications/initialized"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal initialized notification: %w", err)
+	}
+	notifyResp, err := c.postStreamable(notification, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to send initialized notification: %w", err)
+	}
+	notifyResp.Body.Close()
+	if notifyResp.StatusCode != http.StatusAccepted && notifyResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server rejected initialized notification: %d", notifyResp.StatusCode)
+	}
+	return nil
+}
+
+// postStreamable POSTs a JSON-RPC message to the Streamable HTTP endpoint.
+// The session header is omitted when sessionID is empty (initialize only).
+//
+// postStreamableはJSON-RPCメッセージをStreamable HTTPエンドポイントにPOSTします。
+// sessionIDが空の場合（initializeのみ）はセッションヘッダーを省略します。
+func (c *Client) postStreamable(body []byte, sessionID string) (*http.Response, error) {
+	httpReq, err := c.newStreamableRequest(c.ctx, body, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+// newStreamableRequest builds a POST request for the Streamable HTTP endpoint with the
+// headers required by the specification.
+//
+// newStreamableRequestは仕様で必要なヘッダーつきでStreamable HTTPエンドポイントへの
+// POSTリクエストを構築します。
+func (c *Client) newStreamableRequest(ctx context.Context, body []byte, sessionID string) (*http.Request, error) {
+	url := fmt.Sprintf("%s/mcp", c.baseURL)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	// The specification requires clients to accept both response forms
+	// 仕様ではクライアントが両方のレスポンス形式を受け付けることが求められる
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		httpReq.Header.Set(mcpSessionHeader, sessionID)
+	}
+	return httpReq, nil
+}
+
+// readStreamableMessage extracts the JSON-RPC response from a Streamable HTTP POST response.
+// A JSON body is returned as-is; for an SSE body, server notifications are skipped and
+// the first message without a method (the response) is returned.
+//
+// readStreamableMessageはStreamable HTTPのPOSTレスポンスからJSON-RPCレスポンスを取り出します。
+// JSONボディはそのまま返します。SSEボディの場合はサーバー通知をスキップし、
+// メソッドを持たない最初のメッセージ（レスポンス）を返します。
+func readStreamableMessage(resp *http.Response) ([]byte, error) {
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return io.ReadAll(resp.Body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	// Tool results (e.g., logs) can exceed the default 64KB line limit
+	// ツール結果（ログ等）はデフォルトの64KB行制限を超えることがある
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if !isNotification([]byte(data)) {
+			return []byte(data), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("event stream ended without a response")
+}
+
 // readSSEMessages continuously reads messages from the SSE stream in a background goroutine.
 // It parses SSE "data:" lines and sends the parsed content to the messages channel.
 // Any read errors are sent to the errors channel.
@@ -434,6 +758,20 @@ func (c *Client) readSSEMessages() {
 //   - 正常に閉じた場合または既に閉じている場合はnil
 //   - SSE接続のクローズに失敗した場合はerror
 func (c *Client) Close() error {
+	// Terminate a Streamable HTTP session explicitly so the server can release it
+	// サーバーが解放できるようにStreamable HTTPセッションを明示的に終了
+	c.mu.Lock()
+	var streamableSession string
+	if c.connectedTransport == TransportHTTP {
+		streamableSession = c.sessionID
+		c.sessionID = ""
+		c.connectedTransport = ""
+	}
+	c.mu.Unlock()
+	if streamableSession != "" {
+		c.terminateSession(streamableSession)
+	}
+
 	// Cancel the context to signal all goroutines to stop
 	// すべてのgoroutineに停止を通知するためにコンテキストをキャンセル
 	c.cancel()
@@ -448,6 +786,25 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// terminateSession sends DELETE /mcp for a Streamable HTTP session.
+// Errors are ignored: the server also expires idle sessions on its own.
+//
+// terminateSessionはStreamable HTTPセッションに対してDELETE /mcpを送信します。
+// サーバーもアイドルセッションを自動で期限切れにするため、エラーは無視します。
+func (c *Client) terminateSession(sessionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", fmt.Sprintf("%s/mcp", c.baseURL), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set(mcpSessionHeader, sessionID)
+	if resp, err := c.httpClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
 // JSONRPCRequest represents a JSON-RPC 2.0 request structure used for
 // communicating with the DockMCP server.
 //
@@ -472,6 +829,14 @@ type JSONRPCRequest struct {
 	Params  interface{} `json:"params,omitempty"`
 }
 
+// jsonrpcNotification represents a JSON-RPC 2.0 notification (a request without an ID).
+// jsonrpcNotificationはJSON-RPC 2.0の通知（IDを持たないリクエスト）を表します。
+type jsonrpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
 // JSONRPCResponse represents a JSON-RPC 2.0 response for tool calls.
 // It contains either a successful result or an error, but not both.
 //
@@ -556,6 +921,7 @@ type JSONRPCError struct {
 //
 // Fields:
 // - Content: Array of content blocks (typically text results)
+// - StructuredContent: The result as a JSON object, for tools that declare an outputSchema
 // - IsError: True if the tool execution failed (tool-level error, not JSON-RPC error)
 //
 // ToolResultはMCPツール呼び出しの結果を表します。
@@ -563,10 +929,12 @@ type JSONRPCError struct {
 //
 // フィールド：
 // - Content: コンテンツブロックの配列（通常はテキスト結果）
+// - StructuredContent: outputSchemaを宣言したツールの、JSONオブジェクトとしての結果
 // - IsError: ツール実行が失敗した場合はtrue（JSON-RPCエラーではなくツールレベルのエラー）
 type ToolResult struct {
-	Content []Content `json:"content"`
-	IsError bool      `json:"isError,omitempty"`
+	Content           []Content       `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
 }
 
 // Content represents a single content block in an MCP response.
@@ -588,13 +956,14 @@ type Content struct {
 }
 
 // CallTool calls an MCP tool on the DockMCP server using JSON-RPC 2.0 over HTTP POST.
-// The response is received asynchronously via the SSE channel.
+// With Streamable HTTP the response comes back on the POST itself; with legacy SSE it is
+// received asynchronously via the SSE channel.
 //
 // The tool call process:
 // 1. Validates that the client is connected (has session ID)
 // 2. Creates a JSON-RPC request with method "tools/call"
-// 3. Sends the request to /message endpoint with session ID
-// 4. Waits for response on the SSE channel (with 30-second timeout)
+// 3. Sends the request to /mcp (Streamable HTTP) or /message (SSE) with session ID
+// 4. Reads the response from the POST or the SSE channel (with 30-second timeout)
 // 5. Parses and returns the result or error
 //
 // Parameters:
@@ -606,13 +975,14 @@ type Content struct {
 //   - error: Error if not connected, request fails, or tool execution fails
 //
 // CallToolはJSON-RPC 2.0 over HTTP POSTを使用してDockMCPサーバー上のMCPツールを呼び出します。
-// レスポンスはSSEチャネル経由で非同期に受信されます。
+// Streamable HTTPではレスポンスはPOST自体で返り、レガシーSSEでは
+// SSEチャネル経由で非同期に受信されます。
 //
 // ツール呼び出しプロセス：
 // 1. クライアントが接続されている（セッションIDを持っている）ことを検証
 // 2. メソッド「tools/call」でJSON-RPCリクエストを作成
-// 3. セッションID付きで/messageエンドポイントにリクエストを送信
-// 4. SSEチャネルでレスポンスを待機（30秒タイムアウト付き）
+// 3. セッションID付きで/mcp（Streamable HTTP）または/message（SSE）にリクエストを送信
+// 4. POSTまたはSSEチャネルからレスポンスを読み取る（30秒タイムアウト付き）
 // 5. 結果またはエラーを解析して返却
 //
 // パラメータ：
@@ -627,6 +997,7 @@ func (c *Client) CallTool(name string, arguments map[string]interface{}) (*ToolR
 	// スレッドセーフな方法でセッションIDを取得
 	c.mu.Lock()
 	sessionID := c.sessionID
+	transport := c.connectedTransport
 	c.mu.Unlock()
 
 	// Verify that Connect() has been called successfully
@@ -654,6 +1025,84 @@ func (c *Client) CallTool(name string, arguments map[string]interface{}) (*ToolR
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	// Send the request over the negotiated transport and get the raw response message
+	// ネゴシエートされたトランスポートでリクエストを送信し、生のレスポンスメッセージを取得
+	var msg []byte
+	if transport == TransportHTTP {
+		msg, err = c.sendStreamable(body, sessionID)
+	} else {
+		msg, err = c.sendSSE(body, sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeToolResponse(msg)
+}
+
+// decodeToolResponse parses a tools/call JSON-RPC response, turning JSON-RPC errors and
+// tool-level errors (isError) into Go errors.
+//
+// decodeToolResponseはtools/callのJSON-RPCレスポンスを解析し、JSON-RPCエラーと
+// ツールレベルのエラー（isError）をGoのエラーに変換します。
+func decodeToolResponse(msg []byte) (*ToolResult, error) {
+	// Parse the JSON-RPC response
+	// JSON-RPCレスポンスを解析
+	var jsonrpcResp JSONRPCResponse
+	if err := json.Unmarshal(msg, &jsonrpcResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Check for JSON-RPC level error (protocol error)
+	// JSON-RPCレベルのエラーをチェック（プロトコルエラー）
+	if jsonrpcResp.Error != nil {
+		return nil, fmt.Errorf("JSON-RPC error: %s (code %d)", jsonrpcResp.Error.Message, jsonrpcResp.Error.Code)
+	}
+
+	// Check for tool execution error (tool returned error in result)
+	// ツール実行エラーをチェック（ツールが結果でエラーを返した）
+	if jsonrpcResp.Result != nil && jsonrpcResp.Result.IsError {
+		if len(jsonrpcResp.Result.Content) > 0 {
+			return nil, fmt.Errorf("tool call failed: %s", jsonrpcResp.Result.Content[0].Text)
+		}
+		return nil, fmt.Errorf("tool call failed with unknown error")
+	}
+
+	return jsonrpcResp.Result, nil
+}
+
+// sendStreamable sends a JSON-RPC request over Streamable HTTP and returns the response message.
+// sendStreamableはStreamable HTTPでJSON-RPCリクエストを送信し、レスポンスメッセージを返します。
+func (c *Client) sendStreamable(body []byte, sessionID string) ([]byte, error) {
+	resp, err := c.postStreamable(body, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// 404 means the server no longer knows the session (e.g., it was restarted)
+	// 404はサーバーがセッションを認識していないことを意味する（例：再起動された）
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("session expired: reconnect to the server")
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	msg, err := readStreamableMessage(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return msg, nil
+}
+
+// sendSSE sends a JSON-RPC request to the legacy /message endpoint and waits for the
+// response to arrive on the SSE channel.
+//
+// sendSSEはJSON-RPCリクエストをレガシーの/messageエンドポイントに送信し、
+// SSEチャネルにレスポンスが届くのを待ちます。
+func (c *Client) sendSSE(body []byte, sessionID string) ([]byte, error) {
 	// Include sessionId in URL for server to route the response correctly
 	// サーバーがレスポンスを正しくルーティングするためにURLにsessionIdを含める
 	url := fmt.Sprintf("%s/message?sessionId=%s", c.baseURL, sessionID)
@@ -679,48 +1128,250 @@ func (c *Client) CallTool(name string, arguments map[string]interface{}) (*ToolR
 		return nil, fmt.Errorf("server returned error: %d - %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	// Wait for response from SSE channel with timeout and cancellation handling
-	// タイムアウトとキャンセル処理付きでSSEチャネルからのレスポンスを待機
-	select {
-	case msg := <-c.messages:
-		// Parse the JSON-RPC response
-		// JSON-RPCレスポンスを解析
-		var jsonrpcResp JSONRPCResponse
-		if err := json.Unmarshal(msg, &jsonrpcResp); err != nil {
-			return nil, fmt.Errorf("failed to decode SSE response: %w", err)
+	// Wait for response from SSE channel with timeout and cancellation handling.
+	// Server notifications arriving ahead of the response are skipped.
+	// タイムアウトとキャンセル処理付きでSSEチャネルからのレスポンスを待機。
+	// レスポンスより先に届いたサーバー通知はスキップする。
+	timeout := time.After(30 * time.Second)
+	for {
+		select {
+		case msg := <-c.messages:
+			if isNotification(msg) {
+				continue
+			}
+			return msg, nil
+
+		case err := <-c.errors:
+			// SSE connection error occurred
+			// SSE接続エラーが発生
+			return nil, fmt.Errorf("SSE connection error: %w", err)
+
+		case <-timeout:
+			// Response timeout
+			// レスポンスタイムアウト
+			return nil, fmt.Errorf("timeout waiting for response")
+
+		case <-c.ctx.Done():
+			// Client was closed during the operation
+			// 操作中にクライアントが閉じられた
+			return nil, fmt.Errorf("client closed")
 		}
+	}
+}
 
-		// Check for JSON-RPC level error (protocol error)
-		// JSON-RPCレベルのエラーをチェック（プロトコルエラー）
-		if jsonrpcResp.Error != nil {
-			return nil, fmt.Errorf("JSON-RPC error: %s (code %d)", jsonrpcResp.Error.Message, jsonrpcResp.Error.Code)
+// isNotification reports whether a JSON-RPC message is a server notification
+// (it has a method) rather than a response.
+//
+// isNotificationはJSON-RPCメッセージがレスポンスではなくサーバー通知
+// （メソッドを持つ）かどうかを報告します。
+func isNotification(msg []byte) bool {
+	var probe struct {
+		Method string `json:"method"`
+	}
+	return json.Unmarshal(msg, &probe) == nil && probe.Method != ""
+}
+
+// Notification is a server-to-client JSON-RPC notification received while a tool runs,
+// such as notifications/progress or notifications/message.
+//
+// Notificationはツールの実行中に受信するサーバーからクライアントへのJSON-RPC通知です
+// （notifications/progressやnotifications/messageなど）。
+type Notification struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// CallToolStream calls an MCP tool that reports its work through notifications while it
+// runs (e.g., follow_logs). Each notification is passed to onNotification as it arrives,
+// and the final result is returned once the tool finishes.
+//
+// Unlike CallTool there is no response timeout: the call lasts as long as the tool does.
+// Cancelling ctx sends notifications/cancelled so the server stops the tool, and the call
+// returns ctx.Err() without waiting for a result.
+//
+// CallToolStreamは実行中に通知で作業を報告するMCPツール（follow_logsなど）を呼び出します。
+// 各通知は到着した時点でonNotificationに渡され、ツールが終了すると最終結果を返します。
+//
+// CallToolと異なりレスポンスのタイムアウトはなく、呼び出しはツールが実行される間続きます。
+// ctxをキャンセルするとnotifications/cancelledを送信してサーバーにツールを停止させ、
+// 結果を待たずにctx.Err()を返します。
+func (c *Client) CallToolStream(ctx context.Context, name string, arguments map[string]interface{}, onNotification func(Notification)) (*ToolResult, error) {
+	c.mu.Lock()
+	sessionID := c.sessionID
+	transport := c.connectedTransport
+	c.mu.Unlock()
+
+	if sessionID == "" {
+		return nil, fmt.Errorf("not connected: call Connect() first")
+	}
+
+	// The progress token asks the server to report progress for this request
+	// 進捗トークンはこのリクエストの進捗報告をサーバーに要求する
+	req := JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      name,
+			"arguments": arguments,
+			"_meta":     map[string]interface{}{"progressToken": name},
+		},
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var msg []byte
+	if transport == TransportHTTP {
+		msg, err = c.streamStreamable(ctx, body, sessionID, onNotification)
+	} else {
+		msg, err = c.streamSSE(ctx, body, sessionID, onNotification)
+	}
+	if ctx.Err() != nil {
+		c.cancelRequest(req.ID, sessionID, transport)
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeToolResponse(msg)
+}
+
+// streamStreamable POSTs a request over Streamable HTTP and reads the response stream,
+// passing notifications to onNotification until the response arrives.
+//
+// streamStreamableはStreamable HTTPでリクエストをPOSTしてレスポンスストリームを読み取り、
+// レスポンスが届くまで通知をonNotificationに渡します。
+func (c *Client) streamStreamable(ctx context.Context, body []byte, sessionID string, onNotification func(Notification)) ([]byte, error) {
+	httpReq, err := c.newStreamableRequest(ctx, body, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Long-running calls must not be cut off by the regular request timeout
+	// 長時間の呼び出しが通常のリクエストタイムアウトで切断されないようにする
+	resp, err := c.sseHTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("session expired: reconnect to the server")
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("server returned error: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return io.ReadAll(resp.Body)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := []byte(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		if !isNotification(data) {
+			return data, nil
 		}
+		deliverNotification(data, onNotification)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return nil, fmt.Errorf("event stream ended without a response")
+}
 
-		// Check for tool execution error (tool returned error in result)
-		// ツール実行エラーをチェック（ツールが結果でエラーを返した）
-		if jsonrpcResp.Result != nil && jsonrpcResp.Result.IsError {
-			if len(jsonrpcResp.Result.Content) > 0 {
-				return nil, fmt.Errorf("tool call failed: %s", jsonrpcResp.Result.Content[0].Text)
-			}
-			return nil, fmt.Errorf("tool call failed with unknown error")
+// streamSSE POSTs a request to the legacy /message endpoint and reads the SSE channel,
+// passing notifications to onNotification until the response arrives.
+// The legacy server answers the POST only after the tool finishes, so it is sent in
+// the background while the channel is read.
+//
+// streamSSEはレガシーの/messageエンドポイントにリクエストをPOSTしてSSEチャネルを読み取り、
+// レスポンスが届くまで通知をonNotificationに渡します。
+// レガシーサーバーはツールの終了後にPOSTへ応答するため、チャネルを読み取る間
+// POSTはバックグラウンドで送信します。
+func (c *Client) streamSSE(ctx context.Context, body []byte, sessionID string, onNotification func(Notification)) ([]byte, error) {
+	url := fmt.Sprintf("%s/message?sessionId=%s", c.baseURL, sessionID)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	postErr := make(chan error, 1)
+	go func() {
+		resp, err := c.sseHTTPClient.Do(httpReq)
+		if err != nil {
+			postErr <- fmt.Errorf("failed to send request: %w", err)
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			postErr <- fmt.Errorf("server returned error: %d - %s", resp.StatusCode, string(bodyBytes))
 		}
+	}()
 
-		return jsonrpcResp.Result, nil
+	for {
+		select {
+		case msg := <-c.messages:
+			if !isNotification(msg) {
+				return msg, nil
+			}
+			deliverNotification(msg, onNotification)
+		case err := <-postErr:
+			return nil, err
+		case err := <-c.errors:
+			return nil, fmt.Errorf("SSE connection error: %w", err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.ctx.Done():
+			return nil, fmt.Errorf("client closed")
+		}
+	}
+}
 
-	case err := <-c.errors:
-		// SSE connection error occurred
-		// SSE接続エラーが発生
-		return nil, fmt.Errorf("SSE connection error: %w", err)
+// deliverNotification decodes a notification message and passes it to onNotification.
+// deliverNotificationは通知メッセージをデコードしてonNotificationに渡します。
+func deliverNotification(msg []byte, onNotification func(Notification)) {
+	if onNotification == nil {
+		return
+	}
+	var n Notification
+	if err := json.Unmarshal(msg, &n); err == nil {
+		onNotification(n)
+	}
+}
 
-	case <-time.After(30 * time.Second):
-		// Response timeout
-		// レスポンスタイムアウト
-		return nil, fmt.Errorf("timeout waiting for response")
+// cancelRequest sends notifications/cancelled for an in-flight request.
+// Errors are ignored: the request is being abandoned either way.
+//
+// cancelRequestは実行中のリクエストに対してnotifications/cancelledを送信します。
+// いずれにせよリクエストは放棄されるため、エラーは無視します。
+func (c *Client) cancelRequest(requestID int, sessionID string, transport string) {
+	body, err := json.Marshal(jsonrpcNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  map[string]interface{}{"requestId": requestID, "reason": "cancelled by client"},
+	})
+	if err != nil {
+		return
+	}
 
-	case <-c.ctx.Done():
-		// Client was closed during the operation
-		// 操作中にクライアントが閉じられた
-		return nil, fmt.Errorf("client closed")
+	if transport == TransportHTTP {
+		if resp, err := c.postStreamable(body, sessionID); err == nil {
+			resp.Body.Close()
+		}
+		return
+	}
+	url := fmt.Sprintf("%s/message?sessionId=%s", c.baseURL, sessionID)
+	if resp, err := c.httpClient.Post(url, "application/json", bytes.NewReader(body)); err == nil {
+		resp.Body.Close()
 	}
 }
 
@@ -8,6 +8,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -433,3 +434,193 @@ func TestCallTool(t *testing.T) {
 		})
 	}
 }
+
+// mockStreamableServer creates a test server that simulates DockMCP's Streamable HTTP endpoint.
+// Tool calls are answered as an SSE stream that starts with a notification, so the test also
+// covers skipping server notifications. Received DELETE requests are reported on the deleted channel.
+//
+// mockStreamableServerはDockMCPのStreamable HTTPエンドポイントをシミュレートするテストサーバーを作成します。
+// ツール呼び出しには通知から始まるSSEストリームで応答するため、サーバー通知のスキップもテストできます。
+// 受信したDELETEリクエストはdeletedチャネルに通知されます。
+func mockStreamableServer(t *testing.T, deleted chan<- string) *httptest.Server {
+	const sessionID = "session-test"
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mcp" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case "DELETE":
+			deleted <- r.Header.Get(mcpSessionHeader)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "POST":
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Failed to decode request", http.StatusBadRequest)
+			return
+		}
+		method, _ := req["method"].(string)
+		if method != "initialize" && r.Header.Get(mcpSessionHeader) != sessionID {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		switch method {
+		case "initialize":
+			w.Header().Set(mcpSessionHeader, sessionID)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(InitializeResponse{JSONRPC: "2.0", ID: 0, Result: map[string]any{"protocolVersion": "2025-03-26"}})
+		case "notifications/initialized":
+			w.WriteHeader(http.StatusAccepted)
+		case "tools/list":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`)
+		case "tools/call":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "id: 1\nevent: message\ndata: %s\n\n", `{"jsonrpc":"2.0","method":"notifications/message","params":{"data":"working"}}`)
+			fmt.Fprintf(w, "id: 2\nevent: message\ndata: %s\n\n", `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"streamed"}]}}`)
+		default:
+			http.Error(w, "Unexpected method "+method, http.StatusBadRequest)
+		}
+	}))
+}
+
+// TestConnectNegotiatesTransport verifies that Connect prefers Streamable HTTP, falls back to
+// legacy SSE on servers without /mcp, and fails when Streamable HTTP is forced on such a server.
+//
+// TestConnectNegotiatesTransportは、ConnectがStreamable HTTPを優先し、/mcpのないサーバーでは
+// レガシーSSEにフォールバックし、そのようなサーバーでStreamable HTTPを強制すると失敗することを検証します。
+func TestConnectNegotiatesTransport(t *testing.T) {
+	t.Run("streamable HTTP preferred", func(t *testing.T) {
+		deleted := make(chan string, 1)
+		server := mockStreamableServer(t, deleted)
+		defer server.Close()
+
+		c := NewClient(server.URL)
+		if err := c.Connect(); err != nil {
+			t.Fatalf("Connect() failed: %v", err)
+		}
+		if got := c.Transport(); got != TransportHTTP {
+			t.Errorf("Expected transport %q, got %q", TransportHTTP, got)
+		}
+
+		result, err := c.CallTool("list_containers", map[string]interface{}{})
+		if err != nil {
+			t.Fatalf("CallTool() failed: %v", err)
+		}
+		if len(result.Content) != 1 || result.Content[0].Text != "streamed" {
+			t.Errorf("Expected streamed result, got %+v", result.Content)
+		}
+
+		// Close must terminate the session on the server
+		// Closeはサーバー上のセッションを終了しなければならない
+		c.Close()
+		select {
+		case id := <-deleted:
+			if id != "session-test" {
+				t.Errorf("Expected DELETE for session-test, got %q", id)
+			}
+		case <-time.After(3 * time.Second):
+			t.Error("Expected DELETE /mcp on Close")
+		}
+	})
+
+	t.Run("fallback to SSE", func(t *testing.T) {
+		server := mockSSEServer(t, map[string]JSONRPCResponse{})
+		defer server.Close()
+
+		c := NewClient(server.URL)
+		defer c.Close()
+		if err := c.Connect(); err != nil {
+			t.Fatalf("Connect() failed: %v", err)
+		}
+		if got := c.Transport(); got != TransportSSE {
+			t.Errorf("Expected transport %q, got %q", TransportSSE, got)
+		}
+	})
+
+	t.Run("forced HTTP on legacy server", func(t *testing.T) {
+		server := mockSSEServer(t, map[string]JSONRPCResponse{})
+		defer server.Close()
+
+		c := NewClient(server.URL)
+		defer c.Close()
+		c.SetTransport(TransportHTTP)
+		if err := c.Connect(); err == nil {
+			t.Error("Expected Connect() to fail when Streamable HTTP is forced on an SSE-only server")
+		}
+	})
+}
+
+// TestDetachAndResume verifies that Detach leaves a Streamable HTTP session open and that
+// another client can resume it, while resuming an unknown session fails.
+//
+// TestDetachAndResumeは、DetachがStreamable HTTPセッションを開いたままにし、別のクライアントが
+// それを再開できること、および未知のセッションの再開が失敗することを検証します。
+func TestDetachAndResume(t *testing.T) {
+	deleted := make(chan string, 1)
+	server := mockStreamableServer(t, deleted)
+	defer server.Close()
+
+	first := NewClient(server.URL)
+	if err := first.Connect(); err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+	sessionID := first.SessionID()
+	first.Detach()
+	select {
+	case id := <-deleted:
+		t.Fatalf("Detach terminated session %q", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := NewClient(server.URL).Resume("session-gone"); err == nil {
+		t.Error("Expected Resume() to fail for an unknown session")
+	}
+
+	second := NewClient(server.URL)
+	defer second.Close()
+	if err := second.Resume(sessionID); err != nil {
+		t.Fatalf("Resume() failed: %v", err)
+	}
+	result, err := second.CallTool("list_containers", map[string]interface{}{})
+	if err != nil || len(result.Content) != 1 || result.Content[0].Text != "streamed" {
+		t.Errorf("CallTool() after Resume = %+v, %v", result, err)
+	}
+}
+
+// TestCallToolStreamDeliversNotifications verifies that CallToolStream passes server
+// notifications sent ahead of the response to the callback and then returns the result.
+//
+// TestCallToolStreamDeliversNotificationsは、CallToolStreamがレスポンスに先立って送られた
+// サーバー通知をコールバックに渡し、その後結果を返すことを検証します。
+func TestCallToolStreamDeliversNotifications(t *testing.T) {
+	server := mockStreamableServer(t, make(chan string, 1))
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	defer c.Close()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("Connect() failed: %v", err)
+	}
+
+	var notifications []Notification
+	result, err := c.CallToolStream(context.Background(), "follow_logs", map[string]interface{}{}, func(n Notification) {
+		notifications = append(notifications, n)
+	})
+	if err != nil {
+		t.Fatalf("CallToolStream() failed: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].Method != "notifications/message" {
+		t.Errorf("Expected one notifications/message, got %+v", notifications)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "streamed" {
+		t.Errorf("Expected streamed result, got %+v", result.Content)
+	}
+}
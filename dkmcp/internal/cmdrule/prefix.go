@@ -0,0 +1,74 @@
+// prefix.go implements the deny patterns of the host_commands deny list. Unlike a Rule,
+// a deny pattern denies broadly: it only needs to match the start of the arguments.
+//
+// prefix.goはhost_commandsの拒否リストの拒否パターンを実装します。Ruleと異なり、
+// 拒否パターンは広く拒否します：引数の先頭に一致するだけで十分です。
+package cmdrule
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Prefix is a compiled deny pattern.
+// Prefixはコンパイルされた拒否パターンです。
+type Prefix struct {
+	words    []string
+	partial  string
+	wildcard bool
+}
+
+// CompilePrefix parses a deny pattern. A pattern without "*" must equal the arguments
+// word for word. With "*", only the part before the first "*" counts and the arguments
+// must start with it: "push --force*" matches "push --force-with-lease origin" and
+// "rm *" matches "rm". The pattern is split like a command, so it may use quotes.
+//
+// CompilePrefixは拒否パターンを解析します。"*"を含まないパターンは引数と単語ごとに一致する
+// 必要があります。"*"を含む場合は最初の"*"より前の部分のみが対象となり、引数がそれで
+// 始まる必要があります："push --force*"は"push --force-with-lease origin"に、"rm *"は
+// "rm"にマッチします。パターンはコマンドと同様に分割されるため、引用符を使用できます。
+func CompilePrefix(pattern string) (*Prefix, error) {
+	before, _, wildcard := strings.Cut(pattern, "*")
+	words, err := Split(before)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	p := &Prefix{words: words, wildcard: wildcard}
+
+	// A "*" directly after a word leaves that word open: "--force*" matches "--force-with-lease"
+	// 単語の直後の"*"はその単語を開いたままにする："--force*"は"--force-with-lease"にマッチ
+	if wildcard && len(words) > 0 && strings.TrimRight(before, " \t") == before {
+		p.partial = words[len(words)-1]
+		p.words = words[:len(words)-1]
+	}
+	return p, nil
+}
+
+// Match reports whether a command's argv matches the deny pattern.
+// Matchはコマンドのargvが拒否パターンに一致するかどうかを報告します。
+func (p *Prefix) Match(args []string) bool {
+	if !p.wildcard {
+		return equalWords(args, p.words)
+	}
+	if len(args) < len(p.words) || !equalWords(args[:len(p.words)], p.words) {
+		return false
+	}
+	if p.partial == "" {
+		return true
+	}
+	return len(args) > len(p.words) && strings.HasPrefix(args[len(p.words)], p.partial)
+}
+
+// equalWords reports whether a and b hold the same words in the same order.
+// equalWordsはaとbが同じ単語を同じ順序で保持しているかを返します。
+func equalWords(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
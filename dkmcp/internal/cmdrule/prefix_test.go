@@ -0,0 +1,44 @@
+// prefix_test.go contains tests for compiling and matching deny patterns.
+// prefix_test.goは拒否パターンのコンパイルと照合のテストを含みます。
+package cmdrule
+
+import "testing"
+
+// TestPrefixMatch tests that deny patterns match split arguments by their start.
+// TestPrefixMatchは拒否パターンが分割された引数の先頭で照合されることをテストします。
+func TestPrefixMatch(t *testing.T) {
+	tests := []struct {
+		pattern string // Deny pattern / 拒否パターン
+		command string // Arguments / 引数
+		want    bool   // Expected result / 期待される結果
+	}{
+		{"push --force", "push --force", true},
+		{"push --force", "push --force origin", false},
+		{"push --force*", `push "--force"`, true},
+		{"push --force*", "push  --force-with-lease origin", true},
+		{"push --force*", "push origin --force", false},
+		{"push --force *", "push --force", true},
+		{"push --force *", "push --force-with-lease", false},
+		{`commit -m "wip now"`, "commit -m 'wip now'", true},
+		{"rm *", "rm", true},
+		{"rm *", "rmi x", false},
+		{"*", "anything at all", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" ~ "+tt.command, func(t *testing.T) {
+			prefix, err := CompilePrefix(tt.pattern)
+			if err != nil {
+				t.Fatalf("CompilePrefix(%q) error = %v", tt.pattern, err)
+			}
+			args, _ := Split(tt.command)
+			if got := prefix.Match(args); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", args, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := CompilePrefix(`commit -m "wip`); err == nil {
+		t.Error("CompilePrefix() with an unclosed quote should fail")
+	}
+}
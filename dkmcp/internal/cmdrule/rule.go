@@ -0,0 +1,343 @@
+// Package cmdrule implements the whitelist rules shared by the container exec_whitelist
+// and the host_commands whitelist. A rule is a command pattern that is split into words
+// like the command itself and matched argument by argument. A word may be:
+//
+//   - literal text, which must equal the argument
+//   - text with "*", which matches any run of characters within the argument
+//     ("*" at the end of the last word also accepts any further arguments)
+//   - text with typed placeholders, each standing for part of one argument:
+//     <int> or <int:MIN-MAX> (a non-negative decimal number, optionally in a range),
+//     <path:PREFIX> (a path at or below PREFIX, never with a ".." element),
+//     <enum:a|b|c> (one of the listed values)
+//   - a whole-argument <regex:RE> placeholder (RE must match the entire argument;
+//     quote the word when RE contains spaces)
+//
+// A rule may also forbid flags: a command using one of them never matches the rule,
+// even where a placeholder or wildcard would accept it.
+//
+// cmdruleパッケージはコンテナのexec_whitelistとhost_commandsのホワイトリストで共有する
+// ホワイトリストのルールを実装します。ルールはコマンド自体と同じように単語に分割される
+// コマンドパターンで、引数ごとに照合されます。単語は次のいずれかです：
+//
+//   - リテラルテキスト（引数と等しくなければならない）
+//   - "*"を含むテキスト（"*"は引数内の任意の文字列に一致し、最後の単語の末尾の"*"は
+//     それ以降の任意の引数も受け付ける）
+//   - 型付きプレースホルダーを含むテキスト（それぞれが1つの引数の一部を表す）：
+//     <int>または<int:MIN-MAX>（範囲を指定できる非負の10進数）、
+//     <path:PREFIX>（PREFIX以下のパスで、".."要素を含まない）、
+//     <enum:a|b|c>（列挙された値のいずれか）
+//   - 引数全体を表す<regex:RE>プレースホルダー（REは引数全体に一致しなければならない。
+//     REが空白を含む場合は単語を引用符で囲む）
+//
+// ルールはフラグを禁止することもできます：それらを使用するコマンドは、プレースホルダーや
+// ワイルドカードが受け付ける位置であっても、決してルールに一致しません。
+package cmdrule
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// placeholderPattern finds the start of a placeholder such as "<int>" or "<path:".
+// placeholderPatternは"<int>"や"<path:"のようなプレースホルダーの開始を見つけます。
+var placeholderPattern = regexp.MustCompile(`<([a-z]+)([:>])`)
+
+// Rule is a compiled whitelist pattern.
+// Ruleはコンパイルされたホワイトリストのパターンです。
+type Rule struct {
+	words          []word
+	rest           bool
+	forbiddenFlags []string
+}
+
+// word matches one argument: literally, or with a regular expression whose groups are
+// checked by the corresponding checks (nil = no check).
+//
+// wordは1つの引数に一致します：リテラルとして、または対応するchecksでグループが
+// チェックされる正規表現で（nil = チェックなし）。
+type word struct {
+	literal string
+	re      *regexp.Regexp
+	checks  []func(string) bool
+}
+
+// Compile parses a whitelist pattern and its forbidden flags. It rejects malformed
+// placeholders and ambiguous rules: two placeholders or wildcards with no literal text
+// between them, a <path:...> followed by more text, a <regex:...> that is not a whole
+// argument, and a forbidden flag that the pattern itself requires.
+//
+// Compileはホワイトリストのパターンとその禁止フラグを解析します。不正なプレースホルダーと
+// 曖昧なルールを拒否します：間にリテラルテキストのない2つのプレースホルダーやワイルドカード、
+// 後ろにテキストが続く<path:...>、引数全体でない<regex:...>、パターン自体が必要とする
+// 禁止フラグです。
+func Compile(pattern string, forbiddenFlags []string) (*Rule, error) {
+	args, err := Split(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Rule{forbiddenFlags: forbiddenFlags}
+	for _, arg := range args {
+		w, err := compileWord(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		r.words = append(r.words, w)
+	}
+	if len(args) > 0 {
+		last := args[len(args)-1]
+		r.rest = strings.HasSuffix(last, "*") && !strings.HasPrefix(last, "<regex:")
+	}
+
+	for _, flag := range forbiddenFlags {
+		if !strings.HasPrefix(flag, "-") || flag == "-" || flag == "--" || strings.ContainsAny(flag, " \t=") {
+			return nil, fmt.Errorf("invalid forbidden flag %q for pattern %q (must be a flag such as -o or --output)", flag, pattern)
+		}
+		for _, arg := range args {
+			if usesFlag(arg, flag) {
+				return nil, fmt.Errorf("pattern %q requires its forbidden flag %s", pattern, flag)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Match reports whether a command's argv matches the rule's pattern and uses none of
+// its forbidden flags.
+//
+// Matchはコマンドのargvがルールのパターンに一致し、禁止フラグを一切使用していないかどうかを
+// 報告します。
+func (r *Rule) Match(args []string) bool {
+	return r.MatchPattern(args) && r.ForbiddenFlag(args) == ""
+}
+
+// MatchPattern reports whether a command's argv matches the rule's pattern, ignoring
+// the forbidden flags. Each pattern word matches exactly one argument; when the last
+// word ends with "*", any further arguments are accepted as well.
+//
+// MatchPatternは禁止フラグを無視して、コマンドのargvがルールのパターンに一致するかどうかを
+// 報告します。パターンの各単語はちょうど1つの引数に一致し、最後の単語が"*"で終わる場合は
+// それ以降の引数も受け付けます。
+func (r *Rule) MatchPattern(args []string) bool {
+	if len(args) < len(r.words) || (len(args) > len(r.words) && !r.rest) {
+		return false
+	}
+	for i, w := range r.words {
+		if !w.match(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ForbiddenFlag returns the first forbidden flag the argv uses, or "" if none.
+// "--output" is used by "--output" and "--output=x", and by abbreviations that
+// getopt_long accepts such as "--outp=x" (any prefix of at least 3 characters);
+// a short flag such as "-o" is also used by "-ox" and by combined short flags such as "-so".
+//
+// ForbiddenFlagはargvが使用する最初の禁止フラグを返し、なければ""を返します。
+// "--output"は"--output"と"--output=x"、およびgetopt_longが受け付ける"--outp=x"のような
+// 省略形（3文字以上の任意の前方部分）で使用され、"-o"のような短いフラグは"-ox"や
+// "-so"のような結合された短いフラグでも使用されます。
+func (r *Rule) ForbiddenFlag(args []string) string {
+	for _, arg := range args {
+		for _, flag := range r.forbiddenFlags {
+			if usesFlag(arg, flag) {
+				return flag
+			}
+		}
+	}
+	return ""
+}
+
+// usesFlag reports whether arg uses flag (see ForbiddenFlag).
+// usesFlagはargがflagを使用しているかどうかを報告します（ForbiddenFlagを参照）。
+func usesFlag(arg, flag string) bool {
+	if arg == flag || strings.HasPrefix(arg, flag+"=") {
+		return true
+	}
+	if strings.HasPrefix(flag, "--") && strings.HasPrefix(arg, "--") {
+		name, _, _ := strings.Cut(arg, "=")
+		return len(name) >= 3 && strings.HasPrefix(flag, name)
+	}
+	if len(flag) == 2 && flag[1] != '-' {
+		return strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsRune(arg[1:], rune(flag[1]))
+	}
+	return false
+}
+
+// match reports whether arg matches the word.
+// matchはargが単語に一致するかどうかを報告します。
+func (w word) match(arg string) bool {
+	if w.re == nil {
+		return arg == w.literal
+	}
+	groups := w.re.FindStringSubmatch(arg)
+	if groups == nil {
+		return false
+	}
+	for i, check := range w.checks {
+		if check != nil && !check(groups[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileWord compiles one pattern word.
+// compileWordはパターンの1つの単語をコンパイルします。
+func compileWord(arg string) (word, error) {
+	if strings.HasPrefix(arg, "<regex:") && strings.HasSuffix(arg, ">") {
+		expr := strings.TrimSuffix(strings.TrimPrefix(arg, "<regex:"), ">")
+		if expr == "" {
+			return word{}, fmt.Errorf("empty <regex:> placeholder")
+		}
+		re, err := regexp.Compile(`^(?:` + expr + `)$`)
+		if err != nil {
+			return word{}, fmt.Errorf("invalid <regex:> placeholder: %w", err)
+		}
+		return word{re: re, checks: make([]func(string) bool, re.NumSubexp())}, nil
+	}
+	if !strings.Contains(arg, "*") && !placeholderPattern.MatchString(arg) {
+		return word{literal: arg}, nil
+	}
+
+	var expr strings.Builder
+	var checks []func(string) bool
+	expr.WriteString(`^(?s:`)
+	// variable is true when the previous part matches a variable run of characters
+	// variableは直前の部分が可変長の文字列に一致する場合にtrue
+	variable := false
+	for rest := arg; rest != ""; {
+		loc := placeholderPattern.FindStringSubmatchIndex(rest)
+		star := strings.Index(rest, "*")
+		literalEnd := len(rest)
+		if loc != nil {
+			literalEnd = loc[0]
+		}
+		if star >= 0 && star < literalEnd {
+			literalEnd = star
+		}
+		if literalEnd > 0 {
+			expr.WriteString(regexp.QuoteMeta(rest[:literalEnd]))
+			rest = rest[literalEnd:]
+			variable = false
+			continue
+		}
+
+		if variable {
+			return word{}, fmt.Errorf("ambiguous word %q: placeholders and wildcards must be separated by literal text", arg)
+		}
+		variable = true
+		if star == 0 {
+			expr.WriteString(`(.*)`)
+			checks = append(checks, nil)
+			rest = rest[1:]
+			continue
+		}
+
+		kind := rest[loc[2]:loc[3]]
+		param := ""
+		end := loc[1]
+		if rest[loc[4]:loc[5]] == ":" {
+			n := strings.Index(rest[end:], ">")
+			if n < 0 {
+				return word{}, fmt.Errorf("unclosed <%s:> placeholder in %q", kind, arg)
+			}
+			param = rest[end : end+n]
+			end += n + 1
+		}
+		group, check, err := compilePlaceholder(kind, param)
+		if err != nil {
+			return word{}, err
+		}
+		if kind == "path" && end < len(rest) {
+			return word{}, fmt.Errorf("ambiguous word %q: <path:> must end the argument", arg)
+		}
+		expr.WriteString(group)
+		checks = append(checks, check)
+		rest = rest[end:]
+	}
+	expr.WriteString(`)$`)
+	return word{re: regexp.MustCompile(expr.String()), checks: checks}, nil
+}
+
+// compilePlaceholder returns the regular expression group of a placeholder and the
+// check its match must pass.
+//
+// compilePlaceholderはプレースホルダーの正規表現グループと、その一致が通過しなければ
+// ならないチェックを返します。
+func compilePlaceholder(kind, param string) (string, func(string) bool, error) {
+	switch kind {
+	case "int":
+		if param == "" {
+			return `([0-9]+)`, nil, nil
+		}
+		minText, maxText, ok := strings.Cut(param, "-")
+		min, minErr := strconv.Atoi(minText)
+		max, maxErr := strconv.Atoi(maxText)
+		if !ok || minErr != nil || maxErr != nil || min < 0 || min > max {
+			return "", nil, fmt.Errorf("invalid <int:%s> placeholder (expected <int:MIN-MAX> with 0 <= MIN <= MAX)", param)
+		}
+		return `([0-9]+)`, func(s string) bool {
+			n, err := strconv.Atoi(s)
+			return err == nil && n >= min && n <= max
+		}, nil
+
+	case "path":
+		if param == "" || hasDotDot(param) || strings.ContainsAny(param, "*?[") {
+			return "", nil, fmt.Errorf("invalid <path:%s> placeholder (expected a prefix path without .. or wildcards)", param)
+		}
+		prefix := path.Clean(param)
+		return `(.*)`, func(s string) bool { return pathUnder(s, prefix) }, nil
+
+	case "enum":
+		values := strings.Split(param, "|")
+		seen := make(map[string]bool, len(values))
+		for i, v := range values {
+			if v == "" || seen[v] {
+				return "", nil, fmt.Errorf("invalid <enum:%s> placeholder (values must be non-empty and distinct)", param)
+			}
+			seen[v] = true
+			values[i] = regexp.QuoteMeta(v)
+		}
+		return `(` + strings.Join(values, "|") + `)`, nil, nil
+
+	case "regex":
+		return "", nil, fmt.Errorf("<regex:%s> must be a whole argument", param)
+	}
+	return "", nil, fmt.Errorf("unknown placeholder <%s> (expected int, path, enum or regex)", kind)
+}
+
+// pathUnder reports whether p is prefix or below it. An absolute prefix only accepts
+// absolute paths and a relative one relative paths ("." = any relative path). Paths
+// with a ".." element never match, since a symbolic link could lead them elsewhere.
+//
+// pathUnderはpがprefixまたはその配下かどうかを報告します。絶対パスのprefixは絶対パスのみ、
+// 相対パスのprefixは相対パスのみを受け付けます（"." = 任意の相対パス）。".."要素を含む
+// パスはシンボリックリンクにより別の場所に至る可能性があるため、決して一致しません。
+func pathUnder(p, prefix string) bool {
+	if p == "" || hasDotDot(p) || path.IsAbs(p) != path.IsAbs(prefix) {
+		return false
+	}
+	p = path.Clean(p)
+	switch prefix {
+	case "/", ".":
+		return true
+	}
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// hasDotDot reports whether p has a ".." element.
+// hasDotDotはpが".."要素を持つかどうかを報告します。
+func hasDotDot(p string) bool {
+	for _, elem := range strings.Split(p, "/") {
+		if elem == ".." {
+			return true
+		}
+	}
+	return false
+}
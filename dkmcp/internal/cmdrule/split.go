@@ -0,0 +1,133 @@
+// split.go implements the shell-word splitting shared by the command policies, their
+// configuration checks and command execution, so a command is split into the same argv
+// wherever it is looked at.
+//
+// split.goはコマンドポリシー、その設定チェック、コマンド実行で共有するシェル単語分割を
+// 実装します。これによりコマンドはどこで扱われても同じargvに分割されます。
+package cmdrule
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Split splits a command string into arguments the way a POSIX shell splits
+// words, without any expansion ($VAR, globs and command substitution stay literal).
+//
+// Supported syntax:
+//   - Unquoted arguments are split by whitespace (spaces, tabs, newlines)
+//   - Single-quoted strings: 'arg with spaces' (content is literal)
+//   - Double-quoted strings: "arg with spaces" (\ escapes only ", \, $ and `)
+//   - Backslash escapes: arg\ with\ spaces (escapes the next character)
+//
+// An unclosed quote or a trailing backslash is an error.
+//
+// SplitはPOSIXシェルの単語分割と同じようにコマンド文字列を引数に分割しますが、
+// 展開は一切行いません（$VAR、glob、コマンド置換はリテラルのまま）。
+//
+// サポートされる構文：
+//   - 引用符なしの引数は空白（スペース、タブ、改行）で分割される
+//   - シングルクォート文字列: 'arg with spaces'（内容はリテラル）
+//   - ダブルクォート文字列: "arg with spaces"（\は"、\、$、`のみをエスケープ）
+//   - バックスラッシュエスケープ: arg\ with\ spaces（次の文字をエスケープ）
+//
+// 閉じられていない引用符や末尾のバックスラッシュはエラーです。
+func Split(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inSingleQuote := false
+	inDoubleQuote := false
+	escaped := false
+	// Track if we're building an argument (needed for empty quoted strings like "")
+	// 引数を構築中かどうかを追跡（""のような空の引用符文字列に必要）
+	hasContent := false
+
+	for i := 0; i < len(command); i++ {
+		ch := command[i]
+
+		// Handle escape sequences
+		// エスケープシーケンスを処理
+		if escaped {
+			current.WriteByte(ch)
+			escaped = false
+			hasContent = true
+			continue
+		}
+
+		// Handle backslash escape (outside quotes or inside double quotes)
+		// バックスラッシュエスケープを処理（引用符外またはダブルクォート内）
+		if ch == '\\' && !inSingleQuote {
+			// In double quotes, only escape certain characters (", \, $, `)
+			// ダブルクォート内では特定の文字のみエスケープ（", \, $, `）
+			if inDoubleQuote {
+				if i+1 < len(command) {
+					next := command[i+1]
+					if next == '"' || next == '\\' || next == '$' || next == '`' {
+						escaped = true
+						continue
+					}
+				}
+				// If not a special escape, treat backslash as literal
+				// 特殊エスケープでない場合、バックスラッシュをリテラルとして扱う
+				current.WriteByte(ch)
+				hasContent = true
+				continue
+			}
+			escaped = true
+			continue
+		}
+
+		// Handle single quotes (not inside double quotes)
+		// シングルクォートを処理（ダブルクォート内ではない場合）
+		if ch == '\'' && !inDoubleQuote {
+			inSingleQuote = !inSingleQuote
+			hasContent = true // Even empty quotes count as content
+			continue
+		}
+
+		// Handle double quotes (not inside single quotes)
+		// ダブルクォートを処理（シングルクォート内ではない場合）
+		if ch == '"' && !inSingleQuote {
+			inDoubleQuote = !inDoubleQuote
+			hasContent = true // Even empty quotes count as content
+			continue
+		}
+
+		// Handle whitespace (argument separator when not in quotes)
+		// 空白を処理（引用符内でない場合は引数の区切り）
+		if isSpace(ch) && !inSingleQuote && !inDoubleQuote {
+			if hasContent {
+				args = append(args, current.String())
+				current.Reset()
+				hasContent = false
+			}
+			continue
+		}
+
+		// Regular character
+		// 通常の文字
+		current.WriteByte(ch)
+		hasContent = true
+	}
+
+	if inSingleQuote || inDoubleQuote {
+		return nil, fmt.Errorf("unclosed quote in command: %s", command)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in command: %s", command)
+	}
+
+	// Add the last argument if any
+	// 最後の引数があれば追加
+	if hasContent {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// isSpace reports whether ch separates unquoted arguments.
+// isSpaceはchが引用符なしの引数を区切るかどうかを報告します。
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
@@ -16,7 +16,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/cmdrule"
 	"gopkg.in/yaml.v3"
 )
 
@@ -49,6 +52,10 @@ type Config struct {
 	// HostAccess contains settings for host OS access features (tools and commands).
 	// HostAccessはホストOSアクセス機能（ツールとコマンド）の設定を含みます。
 	HostAccess HostAccessConfig `yaml:"host_access"`
+
+	// MetricsHistory contains settings for recording container resource history.
+	// MetricsHistoryはコンテナのリソース履歴の記録の設定を含みます。
+	MetricsHistory MetricsHistoryConfig `yaml:"metrics_history"`
 }
 
 // ServerConfig holds server-related configuration.
@@ -94,19 +101,74 @@ type SecurityConfig struct {
 	// 空のリストはすべてのコンテナにアクセス可能を意味します（moderate/permissiveモード）。
 	AllowedContainers []string `yaml:"allowed_containers"`
 
+	// AllowedLabels grants access to containers carrying any of these label selectors,
+	// in addition to AllowedContainers. A selector is "key" (label present) or
+	// "key=value", where value supports glob patterns (e.g., "dkmcp.access=*").
+	// This lets teams opt containers in from their compose files.
+	//
+	// AllowedLabelsは、AllowedContainersに加えて、これらのラベルセレクタのいずれかを持つ
+	// コンテナへのアクセスを許可します。セレクタは"key"（ラベルが存在する）または
+	// "key=value"で、valueはglobパターンをサポートします（例: "dkmcp.access=*"）。
+	// これによりチームはcomposeファイルからコンテナをオプトインできます。
+	AllowedLabels []string `yaml:"allowed_labels"`
+
+	// DeniedLabels denies access to containers carrying any of these label selectors.
+	// Denial takes precedence over AllowedContainers and AllowedLabels.
+	//
+	// DeniedLabelsはこれらのラベルセレクタのいずれかを持つコンテナへのアクセスを拒否します。
+	// 拒否はAllowedContainersとAllowedLabelsより優先されます。
+	DeniedLabels []string `yaml:"denied_labels"`
+
 	// ExecWhitelist defines which commands can be executed in each container.
 	// Key: container name, Value: list of allowed commands.
 	// Example: {"api": ["npm test", "npm run lint"]}
+	// Commands may use typed placeholders such as <int> or <path:/app> (see cmdrule).
+	// In YAML an entry may also be a mapping with a timeout in seconds and flags the
+	// command must not use, e.g. {command: "npm test", timeout: 600,
+	// forbidden_flags: ["--inspect"]}; these go to ExecCommandTimeouts and
+	// ExecForbiddenFlags.
 	//
 	// ExecWhitelistは各コンテナで実行可能なコマンドを定義します。
 	// キー: コンテナ名、値: 許可されたコマンドのリスト。
 	// 例: {"api": ["npm test", "npm run lint"]}
+	// コマンドには<int>や<path:/app>のような型付きプレースホルダーを使用できます（cmdrule参照）。
+	// YAMLではエントリを秒単位のタイムアウトとコマンドが使用してはならないフラグを持つ
+	// マッピングにもできます。例: {command: "npm test", timeout: 600,
+	// forbidden_flags: ["--inspect"]}。これらはExecCommandTimeoutsとExecForbiddenFlagsに入ります。
 	ExecWhitelist map[string][]string `yaml:"exec_whitelist"`
 
+	// ExecCommandTimeouts holds the timeouts (seconds) given on exec_whitelist entries,
+	// keyed by the exec_whitelist key and then the command.
+	//
+	// ExecCommandTimeoutsはexec_whitelistのエントリに指定されたタイムアウト（秒）を、
+	// exec_whitelistのキー、次にコマンドをキーとして保持します。
+	ExecCommandTimeouts map[string]map[string]int `yaml:"-"`
+
+	// ExecForbiddenFlags holds the forbidden flags given on exec_whitelist entries,
+	// keyed by the exec_whitelist key and then the command.
+	//
+	// ExecForbiddenFlagsはexec_whitelistのエントリに指定された禁止フラグを、
+	// exec_whitelistのキー、次にコマンドをキーとして保持します。
+	ExecForbiddenFlags map[string]map[string][]string `yaml:"-"`
+
 	// Permissions defines which operations are globally allowed.
 	// Permissionsはグローバルに許可される操作を定義します。
 	Permissions SecurityPermissions `yaml:"permissions"`
 
+	// ContainerPermissions overrides Permissions for specific containers.
+	// Keys are container names, glob patterns (e.g., "worker-*"), Compose service
+	// names or "project/service". Only the fields set in an override change the
+	// global value; an exact name wins over a pattern, and a longer pattern over
+	// a shorter one.
+	// Example: {"worker-*": {lifecycle: true}, "db": {logs: false}}
+	//
+	// ContainerPermissionsは特定のコンテナについてPermissionsを上書きします。
+	// キーはコンテナ名、globパターン（例: "worker-*"）、Composeのサービス名、
+	// または"project/service"です。上書きで設定されたフィールドのみがグローバルな値を
+	// 変更します。完全一致の名前はパターンより、長いパターンは短いパターンより優先されます。
+	// 例: {"worker-*": {lifecycle: true}, "db": {logs: false}}
+	ContainerPermissions map[string]PermissionOverrides `yaml:"container_permissions"`
+
 	// BlockedPaths configures which file paths are blocked from access.
 	// BlockedPathsはアクセスをブロックするファイルパスを設定します。
 	BlockedPaths BlockedPathsConfig `yaml:"blocked_paths"`
@@ -130,6 +192,343 @@ type SecurityConfig struct {
 	// HostPathMaskingはMCPツール出力でのホストOSパスのマスキングを設定します。
 	// これによりAIアシスタントからホストOSのユーザー名やディレクトリ構造を隠します。
 	HostPathMasking HostPathMaskingConfig `yaml:"host_path_masking"`
+
+	// FileRead configures the response size limits of read_file.
+	// FileReadはread_fileのレスポンスサイズの上限を設定します。
+	FileRead FileReadConfig `yaml:"file_read"`
+
+	// WritablePaths lists the container paths write_file and apply_patch may modify,
+	// per container (name, Compose service or "project/service"; "*" = all containers).
+	// An entry is a directory, which allows everything below it, or a glob pattern.
+	// Blocked paths are never writable, even when listed here.
+	// Example: {"api": ["/app/config", "/app/*.json"]}
+	//
+	// WritablePathsはwrite_fileとapply_patchが変更できるコンテナ内パスをコンテナごとに
+	// 列挙します（名前、Composeのサービス、または"project/service"。"*" = すべてのコンテナ）。
+	// エントリはディレクトリ（配下すべてを許可）またはglobパターンです。
+	// ブロックされたパスは、ここに列挙されていても書き込めません。
+	// 例: {"api": ["/app/config", "/app/*.json"]}
+	WritablePaths map[string][]string `yaml:"writable_paths"`
+
+	// FileCopy configures the size limit of copy_from_container and copy_to_container.
+	// FileCopyはcopy_from_containerとcopy_to_containerのサイズ上限を設定します。
+	FileCopy FileCopyConfig `yaml:"file_copy"`
+
+	// ExecOutput configures the output size limits of exec_command.
+	// ExecOutputはexec_commandの出力サイズの上限を設定します。
+	ExecOutput ExecOutputConfig `yaml:"exec_output"`
+
+	// ExecTimeout configures how long exec_command may run.
+	// ExecTimeoutはexec_commandの実行時間の上限を設定します。
+	ExecTimeout ExecTimeoutConfig `yaml:"exec_timeout"`
+
+	// ExecJobs configures the background jobs started with start_exec_job.
+	// ExecJobsはstart_exec_jobで開始するバックグラウンドジョブを設定します。
+	ExecJobs ExecJobsConfig `yaml:"exec_jobs"`
+
+	// ExecOptions lists the working directories, environment variables and users that
+	// exec_command and start_exec_job may request, per container (name, Compose service
+	// or "project/service"; "*" = all containers). Without an entry a command runs with
+	// the container's defaults and requests for other values are denied.
+	// Example: {"api": {workdirs: ["/app", "/app/packages/*"], env: ["NODE_ENV"]}}
+	//
+	// ExecOptionsはexec_commandとstart_exec_jobが要求できる作業ディレクトリ、環境変数、
+	// ユーザーをコンテナごとに列挙します（名前、Composeのサービス、または"project/service"。
+	// "*" = すべてのコンテナ）。エントリがない場合、コマンドはコンテナのデフォルトで実行され、
+	// それ以外の値の要求は拒否されます。
+	// 例: {"api": {workdirs: ["/app", "/app/packages/*"], env: ["NODE_ENV"]}}
+	ExecOptions map[string]ExecOptionsConfig `yaml:"exec_options"`
+}
+
+// execWhitelistEntry is the mapping form of an exec_whitelist entry.
+// execWhitelistEntryはexec_whitelistのエントリのマッピング形式です。
+type execWhitelistEntry struct {
+	Command        string   `yaml:"command"`
+	Timeout        int      `yaml:"timeout"`
+	ForbiddenFlags []string `yaml:"forbidden_flags"`
+}
+
+// UnmarshalYAML decodes the security section. exec_whitelist entries that are a mapping
+// with command, timeout and forbidden_flags are reduced to their command, and the rest
+// is recorded in ExecCommandTimeouts and ExecForbiddenFlags, so ExecWhitelist stays a
+// plain list of commands.
+//
+// UnmarshalYAMLはsecurityセクションをデコードします。command、timeout、forbidden_flagsを
+// 持つマッピングのexec_whitelistエントリはコマンドに縮約され、残りはExecCommandTimeoutsと
+// ExecForbiddenFlagsに記録されるため、ExecWhitelistは単純なコマンドのリストのままです。
+func (s *SecurityConfig) UnmarshalYAML(node *yaml.Node) error {
+	timeouts := make(map[string]map[string]int)
+	forbidden := make(map[string]map[string][]string)
+	node, found, err := reduceListEntries(node, "exec_whitelist", func(key string, entry *yaml.Node) (string, error) {
+		var e execWhitelistEntry
+		if err := entry.Decode(&e); err != nil {
+			return "", fmt.Errorf("invalid exec_whitelist[%s] entry at line %d: %w", key, entry.Line, err)
+		}
+		if e.Command == "" {
+			return "", fmt.Errorf("invalid exec_whitelist[%s] entry at line %d: command is required", key, entry.Line)
+		}
+		if e.Timeout < 0 {
+			return "", fmt.Errorf("invalid exec_whitelist[%s] timeout for %q: %d (must not be negative)", key, e.Command, e.Timeout)
+		}
+		if e.Timeout > 0 {
+			if timeouts[key] == nil {
+				timeouts[key] = make(map[string]int)
+			}
+			timeouts[key][e.Command] = e.Timeout
+		}
+		if len(e.ForbiddenFlags) > 0 {
+			if forbidden[key] == nil {
+				forbidden[key] = make(map[string][]string)
+			}
+			forbidden[key][e.Command] = e.ForbiddenFlags
+		}
+		return e.Command, nil
+	})
+	if err != nil {
+		return err
+	}
+	type plain SecurityConfig
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	if found {
+		s.ExecCommandTimeouts = timeouts
+		s.ExecForbiddenFlags = forbidden
+	}
+	return nil
+}
+
+// reduceListEntries returns a copy of a mapping node in which the entries of the
+// map-of-lists under listKey (such as exec_whitelist) that are mappings are replaced by
+// the string reduce returns for them. found reports whether the node has listKey.
+//
+// reduceListEntriesは、listKeyの下のリストのマップ（exec_whitelistなど）のうち
+// マッピングであるエントリを、reduceがそれに対して返す文字列に置き換えたマッピングノードの
+// コピーを返します。foundはノードがlistKeyを持つかどうかを報告します。
+func reduceListEntries(node *yaml.Node, listKey string, reduce func(key string, entry *yaml.Node) (string, error)) (*yaml.Node, bool, error) {
+	if node.Kind != yaml.MappingNode {
+		return node, false, nil
+	}
+	out := *node
+	out.Content = append([]*yaml.Node(nil), node.Content...)
+	for i := 0; i+1 < len(out.Content); i += 2 {
+		if out.Content[i].Value != listKey || out.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		lists := *out.Content[i+1]
+		lists.Content = append([]*yaml.Node(nil), lists.Content...)
+		for j := 0; j+1 < len(lists.Content); j += 2 {
+			key, list := lists.Content[j].Value, lists.Content[j+1]
+			if list.Kind != yaml.SequenceNode {
+				continue
+			}
+			entries := *list
+			entries.Content = append([]*yaml.Node(nil), list.Content...)
+			for k, entry := range entries.Content {
+				if entry.Kind != yaml.MappingNode {
+					continue
+				}
+				value, err := reduce(key, entry)
+				if err != nil {
+					return nil, false, err
+				}
+				entries.Content[k] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Line: entry.Line, Column: entry.Column}
+			}
+			lists.Content[j+1] = &entries
+		}
+		out.Content[i+1] = &lists
+		return &out, true, nil
+	}
+	return &out, false, nil
+}
+
+// DefaultFileReadMaxBytes is the default cap on the content returned by one read_file call.
+// DefaultFileReadMaxBytesは1回のread_file呼び出しが返す内容のデフォルトの上限です。
+const DefaultFileReadMaxBytes = 1 << 20
+
+// FileReadConfig holds the response size limits of read_file. Content beyond the limit
+// is cut and a cursor is returned to continue reading.
+//
+// FileReadConfigはread_fileのレスポンスサイズの上限を保持します。上限を超える内容は
+// 打ち切られ、読み取りを続けるためのカーソルが返されます。
+type FileReadConfig struct {
+	// MaxBytes caps the content returned by one read_file call.
+	// Default: 1048576 (1 MiB, also used when 0)
+	//
+	// MaxBytesは1回のread_file呼び出しが返す内容の上限です。
+	// デフォルト: 1048576（1 MiB、0の場合も使用）
+	MaxBytes int64 `yaml:"max_bytes"`
+
+	// ContainerMaxBytes overrides MaxBytes for specific containers. Keys are matched
+	// like container_permissions keys (names, glob patterns, Compose services).
+	// Example: {"log-collector": 16777216}
+	//
+	// ContainerMaxBytesは特定のコンテナについてMaxBytesを上書きします。キーは
+	// container_permissionsのキーと同様に照合されます（名前、globパターン、Composeのサービス）。
+	// 例: {"log-collector": 16777216}
+	ContainerMaxBytes map[string]int64 `yaml:"container_max_bytes"`
+}
+
+// DefaultFileCopyMaxBytes is the default cap on the file content moved by one copy.
+// DefaultFileCopyMaxBytesは1回のコピーで移動するファイル内容のデフォルトの上限です。
+const DefaultFileCopyMaxBytes = 256 << 20
+
+// FileCopyConfig holds the size limit of the copy tools. A copy whose files add up to
+// more than the limit fails as a whole and leaves no partial copy behind.
+//
+// FileCopyConfigはコピーツールのサイズ上限を保持します。ファイルの合計が上限を超える
+// コピーは全体として失敗し、途中までのコピーは残りません。
+type FileCopyConfig struct {
+	// MaxBytes caps the total size of the files moved by one copy.
+	// Default: 268435456 (256 MiB, also used when 0)
+	//
+	// MaxBytesは1回のコピーで移動するファイルの合計サイズの上限です。
+	// デフォルト: 268435456（256 MiB、0の場合も使用）
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+// DefaultExecOutputMaxBytes is the default cap on the output kept from one command.
+// DefaultExecOutputMaxBytesは1つのコマンドから保持する出力のデフォルトの上限です。
+const DefaultExecOutputMaxBytes = 1 << 20
+
+// ExecOutputConfig holds the output size limits of exec_command. Output beyond the
+// limit is discarded, the command still runs to completion, and the result is marked
+// as truncated.
+//
+// ExecOutputConfigはexec_commandの出力サイズの上限を保持します。上限を超える出力は
+// 破棄され、コマンドは最後まで実行され、結果は打ち切りとしてマークされます。
+type ExecOutputConfig struct {
+	// MaxBytes caps the stdout and stderr kept from one command, together.
+	// Default: 1048576 (1 MiB, also used when 0)
+	//
+	// MaxBytesは1つのコマンドから保持する標準出力と標準エラー出力の合計の上限です。
+	// デフォルト: 1048576（1 MiB、0の場合も使用）
+	MaxBytes int64 `yaml:"max_bytes"`
+
+	// ContainerMaxBytes overrides MaxBytes for specific containers. Keys are matched
+	// like container_permissions keys (names, glob patterns, Compose services).
+	// Example: {"test-runner": 8388608}
+	//
+	// ContainerMaxBytesは特定のコンテナについてMaxBytesを上書きします。キーは
+	// container_permissionsのキーと同様に照合されます（名前、globパターン、Composeのサービス）。
+	// 例: {"test-runner": 8388608}
+	ContainerMaxBytes map[string]int64 `yaml:"container_max_bytes"`
+}
+
+// DefaultExecTimeoutSeconds is the default time limit of one exec_command.
+// DefaultExecTimeoutSecondsは1回のexec_commandのデフォルトの時間制限です。
+const DefaultExecTimeoutSeconds = 300
+
+// ExecTimeoutConfig holds the time limits of exec_command. A command that runs longer
+// is killed together with the processes it started, and the partial output is returned.
+// A timeout on the exec_whitelist entry of a command takes precedence.
+//
+// ExecTimeoutConfigはexec_commandの時間制限を保持します。それより長く実行されたコマンドは
+// 起動したプロセスとともに終了され、途中までの出力が返されます。
+// コマンドのexec_whitelistエントリのタイムアウトが優先されます。
+type ExecTimeoutConfig struct {
+	// Seconds limits every command.
+	// Default: 300 (also used when 0)
+	//
+	// Secondsはすべてのコマンドを制限します。
+	// デフォルト: 300（0の場合も使用）
+	Seconds int `yaml:"seconds"`
+
+	// ContainerSeconds overrides Seconds for specific containers. Keys are matched
+	// like container_permissions keys (names, glob patterns, Compose services).
+	// Example: {"test-runner": 900}
+	//
+	// ContainerSecondsは特定のコンテナについてSecondsを上書きします。キーは
+	// container_permissionsのキーと同様に照合されます（名前、globパターン、Composeのサービス）。
+	// 例: {"test-runner": 900}
+	ContainerSeconds map[string]int `yaml:"container_seconds"`
+}
+
+// DefaultExecJobsMax is the default number of jobs the server keeps at once.
+// DefaultExecJobsMaxはサーバーが同時に保持するジョブのデフォルト数です。
+const DefaultExecJobsMax = 16
+
+// DefaultExecJobTimeoutSeconds is the default time limit of one exec job.
+// DefaultExecJobTimeoutSecondsは1つのexecジョブのデフォルトの時間制限です。
+const DefaultExecJobTimeoutSeconds = 3600
+
+// ExecJobsConfig holds the limits of exec jobs, which run whitelisted commands in the
+// background and are polled with get_job_output. Their output is limited by exec_output.
+//
+// ExecJobsConfigはexecジョブの制限を保持します。execジョブはホワイトリストのコマンドを
+// バックグラウンドで実行し、get_job_outputでポーリングされます。出力はexec_outputで制限されます。
+type ExecJobsConfig struct {
+	// MaxJobs caps the jobs kept at once, running or finished. When the table is full
+	// the oldest finished job is dropped; when every job is running, new jobs are refused.
+	// Default: 16 (also used when 0)
+	//
+	// MaxJobsは同時に保持するジョブ（実行中または終了済み）の上限です。テーブルが満杯の場合は
+	// 最も古い終了済みジョブを破棄し、すべてのジョブが実行中の場合は新しいジョブを拒否します。
+	// デフォルト: 16（0の場合も使用）
+	MaxJobs int `yaml:"max_jobs"`
+
+	// TimeoutSeconds limits every job. A timeout on the exec_whitelist entry of the
+	// command takes precedence; exec_timeout does not apply to jobs.
+	// Default: 3600 (also used when 0)
+	//
+	// TimeoutSecondsはすべてのジョブを制限します。コマンドのexec_whitelistエントリの
+	// タイムアウトが優先されます。exec_timeoutはジョブには適用されません。
+	// デフォルト: 3600（0の場合も使用）
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+}
+
+// ExecOptionsConfig holds the values a command may request for its working directory,
+// environment and user in the containers of one exec_options entry.
+//
+// ExecOptionsConfigは1つのexec_optionsエントリのコンテナで、コマンドが作業ディレクトリ、
+// 環境、ユーザーとして要求できる値を保持します。
+type ExecOptionsConfig struct {
+	// Workdirs lists the allowed working directories: absolute paths, matched exactly,
+	// or glob patterns (e.g., "/app/packages/*").
+	//
+	// Workdirsは許可される作業ディレクトリを列挙します：完全一致で照合される絶対パス、
+	// またはglobパターン（例: "/app/packages/*"）です。
+	Workdirs []string `yaml:"workdirs"`
+
+	// Env lists the allowed environment variables: "NAME" allows any value and
+	// "NAME=value" only that value.
+	//
+	// Envは許可される環境変数を列挙します："NAME"は任意の値を、"NAME=value"は
+	// その値のみを許可します。
+	Env []string `yaml:"env"`
+
+	// Users lists the allowed users, as given to docker exec --user
+	// (e.g., "node", "1000", "1000:1000").
+	//
+	// Usersは許可されるユーザーをdocker exec --userに渡す形式で列挙します
+	// （例: "node"、"1000"、"1000:1000"）。
+	Users []string `yaml:"users"`
+}
+
+// ReservedEnvPrefix is the prefix of the environment variables DockMCP sets on its own
+// execs; exec_options cannot allow them.
+//
+// ReservedEnvPrefixはDockMCPが自身のexecに設定する環境変数の接頭辞です。
+// exec_optionsでそれらを許可することはできません。
+const ReservedEnvPrefix = "DKMCP_"
+
+// envNamePattern matches a valid environment variable name.
+// envNamePatternは有効な環境変数名にマッチします。
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvName checks that name is a valid environment variable name that is not
+// reserved for DockMCP.
+//
+// ValidateEnvNameはnameがDockMCP用に予約されていない有効な環境変数名であることを
+// チェックします。
+func ValidateEnvName(name string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	if strings.HasPrefix(name, ReservedEnvPrefix) {
+		return fmt.Errorf("environment variable %q is reserved (%s* is set by DockMCP)", name, ReservedEnvPrefix)
+	}
+	return nil
 }
 
 // BlockedPathsConfig holds configuration for blocked file paths.
@@ -396,6 +795,68 @@ type SecurityPermissions struct {
 	// Docker APIを直接使用（シェル実行なし）するため、インジェクションリスクはゼロです。
 	// デフォルト: false（安全なデフォルト - 読み取り操作のみ許可）。
 	Lifecycle bool `yaml:"lifecycle"`
+
+	// Write allows modifying files via the write_file and apply_patch tools, limited to
+	// writable_paths. Uses the Docker archive API (no shell execution).
+	// Default: false (safe by default - only read operations allowed).
+	//
+	// Writeはwrite_fileとapply_patchツールによるファイルの変更を、writable_pathsの範囲で
+	// 許可します。DockerのアーカイブAPIを使用します（シェル実行なし）。
+	// デフォルト: false（安全なデフォルト - 読み取り操作のみ許可）。
+	Write bool `yaml:"write"`
+
+	// Copy allows moving files between the host workspace and containers via the
+	// copy_from_container and copy_to_container tools. Copies into a container are
+	// also limited to writable_paths.
+	// Default: false (safe by default - nothing leaves or enters a container).
+	//
+	// Copyはcopy_from_containerとcopy_to_containerツールによるホストのワークスペースと
+	// コンテナ間のファイルの移動を許可します。コンテナへのコピーはwritable_pathsの
+	// 範囲にも制限されます。
+	// デフォルト: false（安全なデフォルト - コンテナとの間でファイルを移動しない）。
+	Copy bool `yaml:"copy"`
+}
+
+// PermissionOverrides holds per-container permission overrides.
+// A nil field keeps the value inherited from the global permissions.
+//
+// PermissionOverridesはコンテナごとの権限の上書きを保持します。
+// nilのフィールドはグローバルな権限から継承した値を維持します。
+type PermissionOverrides struct {
+	Logs      *bool `yaml:"logs,omitempty"`
+	Inspect   *bool `yaml:"inspect,omitempty"`
+	Stats     *bool `yaml:"stats,omitempty"`
+	Exec      *bool `yaml:"exec,omitempty"`
+	Lifecycle *bool `yaml:"lifecycle,omitempty"`
+	Write     *bool `yaml:"write,omitempty"`
+	Copy      *bool `yaml:"copy,omitempty"`
+}
+
+// Apply returns perms with the fields set in o replaced.
+// Applyはoで設定されたフィールドを置き換えたpermsを返します。
+func (o PermissionOverrides) Apply(perms SecurityPermissions) SecurityPermissions {
+	if o.Logs != nil {
+		perms.Logs = *o.Logs
+	}
+	if o.Inspect != nil {
+		perms.Inspect = *o.Inspect
+	}
+	if o.Stats != nil {
+		perms.Stats = *o.Stats
+	}
+	if o.Exec != nil {
+		perms.Exec = *o.Exec
+	}
+	if o.Lifecycle != nil {
+		perms.Lifecycle = *o.Lifecycle
+	}
+	if o.Write != nil {
+		perms.Write = *o.Write
+	}
+	if o.Copy != nil {
+		perms.Copy = *o.Copy
+	}
+	return perms
 }
 
 // LoggingConfig holds logging configuration.
@@ -462,6 +923,10 @@ type AuditEvents struct {
 	// SecurityPolicy logs when security policy is queried
 	// SecurityPolicyはセキュリティポリシーが照会された時をログ記録します
 	SecurityPolicy bool `yaml:"security_policy"`
+
+	// ContainerEvents logs when an allowed container dies, is OOM killed or changes health
+	// ContainerEventsは許可されたコンテナの停止、OOMキル、ヘルスの変化をログ記録します
+	ContainerEvents bool `yaml:"container_events"`
 }
 
 // CLIConfig holds CLI-specific configuration for human convenience features.
@@ -646,11 +1111,26 @@ type HostCommandsConfig struct {
 
 	// Whitelist defines allowed commands and their argument patterns.
 	// Key: base command name (e.g., "docker", "git"), Value: allowed argument patterns.
+	// Patterns are matched like exec_whitelist commands, typed placeholders included.
+	// In YAML an entry may also be a mapping with flags the command must not use,
+	// e.g. {args: "-s <regex:https?://localhost:[0-9]+/.*>", forbidden_flags: ["-o"]};
+	// these go to ForbiddenFlags.
 	//
 	// Whitelistは許可されるコマンドとその引数パターンを定義します。
 	// キー: ベースコマンド名（例: "docker", "git"）、値: 許可される引数パターン。
+	// パターンは型付きプレースホルダーを含めexec_whitelistのコマンドと同様に照合されます。
+	// YAMLではエントリをコマンドが使用してはならないフラグを持つマッピングにもできます。
+	// 例: {args: "-s <regex:https?://localhost:[0-9]+/.*>", forbidden_flags: ["-o"]}。
+	// これらはForbiddenFlagsに入ります。
 	Whitelist map[string][]string `yaml:"whitelist"`
 
+	// ForbiddenFlags holds the forbidden flags given on whitelist entries, keyed by the
+	// base command and then the argument pattern.
+	//
+	// ForbiddenFlagsはwhitelistのエントリに指定された禁止フラグを、ベースコマンド、
+	// 次に引数パターンをキーとして保持します。
+	ForbiddenFlags map[string]map[string][]string `yaml:"-"`
+
 	// Deny defines commands that are explicitly denied (overrides whitelist).
 	// Key: base command name, Value: denied argument patterns.
 	//
@@ -663,6 +1143,47 @@ type HostCommandsConfig struct {
 	Dangerously HostCommandsDangerously `yaml:"dangerously"`
 }
 
+// hostWhitelistEntry is the mapping form of a host_commands whitelist entry.
+// hostWhitelistEntryはhost_commandsのwhitelistエントリのマッピング形式です。
+type hostWhitelistEntry struct {
+	Args           string   `yaml:"args"`
+	ForbiddenFlags []string `yaml:"forbidden_flags"`
+}
+
+// UnmarshalYAML decodes the host_commands section. whitelist entries that are a mapping
+// with args and forbidden_flags are reduced to their argument pattern, and the flags
+// are recorded in ForbiddenFlags.
+//
+// UnmarshalYAMLはhost_commandsセクションをデコードします。argsとforbidden_flagsを持つ
+// マッピングのwhitelistエントリは引数パターンに縮約され、フラグはForbiddenFlagsに記録されます。
+func (h *HostCommandsConfig) UnmarshalYAML(node *yaml.Node) error {
+	forbidden := make(map[string]map[string][]string)
+	node, found, err := reduceListEntries(node, "whitelist", func(key string, entry *yaml.Node) (string, error) {
+		var e hostWhitelistEntry
+		if err := entry.Decode(&e); err != nil {
+			return "", fmt.Errorf("invalid host_commands.whitelist[%s] entry at line %d: %w", key, entry.Line, err)
+		}
+		if len(e.ForbiddenFlags) > 0 {
+			if forbidden[key] == nil {
+				forbidden[key] = make(map[string][]string)
+			}
+			forbidden[key][e.Args] = e.ForbiddenFlags
+		}
+		return e.Args, nil
+	})
+	if err != nil {
+		return err
+	}
+	type plain HostCommandsConfig
+	if err := node.Decode((*plain)(h)); err != nil {
+		return err
+	}
+	if found {
+		h.ForbiddenFlags = forbidden
+	}
+	return nil
+}
+
 // HostCommandsDangerously configures dangerous mode for host commands.
 // When enabled, additional commands can be executed with the dangerously=true parameter.
 //
@@ -681,6 +1202,51 @@ type HostCommandsDangerously struct {
 	Commands map[string][]string `yaml:"commands"`
 }
 
+// Defaults of the metrics history recorder.
+// メトリクス履歴レコーダーのデフォルト値です。
+const (
+	DefaultMetricsHistoryDir      = "~/.dkmcp/metrics"
+	DefaultMetricsHistoryInterval = 60
+	DefaultMetricsHistoryMaxBytes = 64 << 20
+)
+
+// MetricsHistoryConfig configures the recorder that periodically samples the stats and
+// state of the accessible containers into a size-bounded ring of files on disk, so the
+// query_metrics tool can answer questions about the past.
+//
+// MetricsHistoryConfigは、アクセス可能なコンテナの統計と状態を定期的にサンプリングし、
+// ディスク上のサイズ上限付きのファイルのリングに記録するレコーダーを設定します。
+// これによりquery_metricsツールが過去についての質問に答えられます。
+type MetricsHistoryConfig struct {
+	// Enabled starts the recorder in `dkmcp serve` and adds the query_metrics tool.
+	// Default: false
+	//
+	// Enabledは`dkmcp serve`でレコーダーを開始し、query_metricsツールを追加します。
+	// デフォルト: false
+	Enabled bool `yaml:"enabled"`
+
+	// Dir is the directory the history is stored in. Supports ~ for the home directory.
+	// Default: "~/.dkmcp/metrics"
+	//
+	// Dirは履歴を保存するディレクトリです。ホームディレクトリの~をサポートします。
+	// デフォルト: "~/.dkmcp/metrics"
+	Dir string `yaml:"dir"`
+
+	// Interval is the time between samples in seconds.
+	// Default: 60
+	//
+	// Intervalはサンプル間の時間（秒）です。
+	// デフォルト: 60
+	Interval int `yaml:"interval"`
+
+	// MaxBytes caps the size of the history on disk; the oldest records are removed first.
+	// Default: 67108864 (64 MiB)
+	//
+	// MaxBytesはディスク上の履歴のサイズの上限です。最も古いレコードから削除されます。
+	// デフォルト: 67108864（64 MiB）
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
 // NewDefaultConfig returns a Config with sensible default values.
 // These defaults provide a balance between security and usability.
 //
@@ -700,6 +1266,8 @@ func NewDefaultConfig() *Config {
 				Stats:     true,
 				Exec:      true,
 				Lifecycle: false,
+				Write:     false,
+				Copy:      false,
 			},
 			BlockedPaths: BlockedPathsConfig{
 				Manual: make(map[string][]string),
@@ -770,6 +1338,22 @@ func NewDefaultConfig() *Config {
 				Enabled:     true,
 				Replacement: "[HOST_PATH]",
 			},
+			FileRead: FileReadConfig{
+				MaxBytes: DefaultFileReadMaxBytes,
+			},
+			ExecOutput: ExecOutputConfig{
+				MaxBytes: DefaultExecOutputMaxBytes,
+			},
+			ExecTimeout: ExecTimeoutConfig{
+				Seconds: DefaultExecTimeoutSeconds,
+			},
+			ExecJobs: ExecJobsConfig{
+				MaxJobs:        DefaultExecJobsMax,
+				TimeoutSeconds: DefaultExecJobTimeoutSeconds,
+			},
+			FileCopy: FileCopyConfig{
+				MaxBytes: DefaultFileCopyMaxBytes,
+			},
 		},
 		Logging: LoggingConfig{
 			Level: "info",
@@ -782,6 +1366,7 @@ func NewDefaultConfig() *Config {
 				AccessDenied:      true,
 				ClientConnections: true,
 				SecurityPolicy:    false,
+				ContainerEvents:   true,
 			},
 		},
 		CLI: CLIConfig{
@@ -811,6 +1396,12 @@ func NewDefaultConfig() *Config {
 				},
 			},
 		},
+		MetricsHistory: MetricsHistoryConfig{
+			Enabled:  false,
+			Dir:      DefaultMetricsHistoryDir,
+			Interval: DefaultMetricsHistoryInterval,
+			MaxBytes: DefaultMetricsHistoryMaxBytes,
+		},
 	}
 }
 
@@ -832,35 +1423,7 @@ func Load(configPath string) (*Config, error) {
 	// Start with default configuration
 	// デフォルト設定から開始
 	cfg := NewDefaultConfig()
-	var fileToRead string
-
-	if configPath != "" {
-		// Use explicitly specified config file
-		// 明示的に指定された設定ファイルを使用
-		fileToRead = configPath
-	} else {
-		// Search for config in common locations
-		// 一般的な場所で設定を検索
-		searchPaths := []string{".", "./configs"}
-		if home, err := os.UserHomeDir(); err == nil {
-			searchPaths = append(searchPaths, filepath.Join(home, ".dkmcp"))
-		}
-
-		// Try each search path with both .yaml and .yml extensions
-		// 各検索パスで.yamlと.yml両方の拡張子を試行
-		for _, p := range searchPaths {
-			for _, ext := range []string{"yaml", "yml"} {
-				f := filepath.Join(p, "dkmcp."+ext)
-				if _, err := os.Stat(f); err == nil {
-					fileToRead = f
-					break
-				}
-			}
-			if fileToRead != "" {
-				break
-			}
-		}
-	}
+	fileToRead := ResolvePath(configPath)
 
 	// Load configuration from file if found
 	// ファイルが見つかった場合は設定を読み込み
@@ -883,6 +1446,40 @@ func Load(configPath string) (*Config, error) {
 	return cfg, nil
 }
 
+// ResolvePath returns the configuration file Load reads for configPath: configPath itself
+// when set, otherwise the first dkmcp.yaml or dkmcp.yml found in the search paths.
+// Returns an empty string when no configuration file is found.
+//
+// ResolvePathはconfigPathに対してLoadが読み込む設定ファイルを返します：設定されていれば
+// configPath自体、それ以外は検索パスで最初に見つかったdkmcp.yamlまたはdkmcp.ymlです。
+// 設定ファイルが見つからない場合は空文字列を返します。
+func ResolvePath(configPath string) string {
+	if configPath != "" {
+		// Use explicitly specified config file
+		// 明示的に指定された設定ファイルを使用
+		return configPath
+	}
+
+	// Search for config in common locations
+	// 一般的な場所で設定を検索
+	searchPaths := []string{".", "./configs"}
+	if home, err := os.UserHomeDir(); err == nil {
+		searchPaths = append(searchPaths, filepath.Join(home, ".dkmcp"))
+	}
+
+	// Try each search path with both .yaml and .yml extensions
+	// 各検索パスで.yamlと.yml両方の拡張子を試行
+	for _, p := range searchPaths {
+		for _, ext := range []string{"yaml", "yml"} {
+			f := filepath.Join(p, "dkmcp."+ext)
+			if _, err := os.Stat(f); err == nil {
+				return f
+			}
+		}
+	}
+	return ""
+}
+
 // Validate checks that the configuration is valid.
 // Returns an error describing the first validation failure found.
 //
@@ -906,6 +1503,124 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid security mode: %s (must be strict, moderate, or permissive)", c.Security.Mode)
 	}
 
+	// Validate label selectors
+	// ラベルセレクタを検証
+	for _, selector := range append(append([]string{}, c.Security.AllowedLabels...), c.Security.DeniedLabels...) {
+		if err := ValidateLabelSelector(selector); err != nil {
+			return err
+		}
+	}
+
+	// Validate exec_whitelist rules (malformed or ambiguous patterns)
+	// exec_whitelistのルールを検証（不正または曖昧なパターン）
+	for container, commands := range c.Security.ExecWhitelist {
+		for _, command := range commands {
+			if _, err := cmdrule.Compile(command, c.Security.ExecForbiddenFlags[container][command]); err != nil {
+				return fmt.Errorf("invalid exec_whitelist[%s] rule: %w", container, err)
+			}
+		}
+	}
+
+	// Validate container_permissions patterns
+	// container_permissionsのパターンを検証
+	for pattern := range c.Security.ContainerPermissions {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid container_permissions pattern %q: %w", pattern, err)
+		}
+	}
+
+	// Validate read_file size limits
+	// read_fileのサイズ上限を検証
+	if c.Security.FileRead.MaxBytes < 0 {
+		return fmt.Errorf("invalid file_read.max_bytes: %d (must not be negative)", c.Security.FileRead.MaxBytes)
+	}
+	for pattern, maxBytes := range c.Security.FileRead.ContainerMaxBytes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file_read.container_max_bytes pattern %q: %w", pattern, err)
+		}
+		if maxBytes <= 0 {
+			return fmt.Errorf("invalid file_read.container_max_bytes[%s]: %d (must be > 0)", pattern, maxBytes)
+		}
+	}
+
+	// Validate writable paths: absolute container paths or glob patterns
+	// 書き込み可能なパスを検証：絶対パスのコンテナ内パスまたはglobパターン
+	for container, paths := range c.Security.WritablePaths {
+		for _, p := range paths {
+			if !strings.HasPrefix(p, "/") {
+				return fmt.Errorf("invalid writable_paths[%s] entry %q (must be an absolute path)", container, p)
+			}
+			if _, err := filepath.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid writable_paths[%s] pattern %q: %w", container, p, err)
+			}
+		}
+	}
+
+	if c.Security.FileCopy.MaxBytes < 0 {
+		return fmt.Errorf("invalid file_copy.max_bytes: %d (must not be negative)", c.Security.FileCopy.MaxBytes)
+	}
+
+	// Validate exec_command time limits
+	// exec_commandの時間制限を検証
+	if c.Security.ExecTimeout.Seconds < 0 {
+		return fmt.Errorf("invalid exec_timeout.seconds: %d (must not be negative)", c.Security.ExecTimeout.Seconds)
+	}
+	for pattern, seconds := range c.Security.ExecTimeout.ContainerSeconds {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exec_timeout.container_seconds pattern %q: %w", pattern, err)
+		}
+		if seconds <= 0 {
+			return fmt.Errorf("invalid exec_timeout.container_seconds[%s]: %d (must be > 0)", pattern, seconds)
+		}
+	}
+
+	// Validate exec job limits
+	// execジョブの制限を検証
+	if c.Security.ExecJobs.MaxJobs < 0 {
+		return fmt.Errorf("invalid exec_jobs.max_jobs: %d (must not be negative)", c.Security.ExecJobs.MaxJobs)
+	}
+	if c.Security.ExecJobs.TimeoutSeconds < 0 {
+		return fmt.Errorf("invalid exec_jobs.timeout_seconds: %d (must not be negative)", c.Security.ExecJobs.TimeoutSeconds)
+	}
+
+	// Validate exec options: absolute working directories, valid environment variables
+	// execオプションを検証：絶対パスの作業ディレクトリ、有効な環境変数
+	for container, opts := range c.Security.ExecOptions {
+		for _, dir := range opts.Workdirs {
+			if !strings.HasPrefix(dir, "/") {
+				return fmt.Errorf("invalid exec_options[%s] workdir %q (must be an absolute path)", container, dir)
+			}
+			if _, err := filepath.Match(dir, ""); err != nil {
+				return fmt.Errorf("invalid exec_options[%s] workdir pattern %q: %w", container, dir, err)
+			}
+		}
+		for _, entry := range opts.Env {
+			name, _, _ := strings.Cut(entry, "=")
+			if err := ValidateEnvName(name); err != nil {
+				return fmt.Errorf("invalid exec_options[%s] env entry: %w", container, err)
+			}
+		}
+		for _, user := range opts.Users {
+			if user == "" {
+				return fmt.Errorf("invalid exec_options[%s] user: must not be empty", container)
+			}
+		}
+	}
+
+	// Validate exec_command output limits
+	// exec_commandの出力上限を検証
+	if c.Security.ExecOutput.MaxBytes < 0 {
+		return fmt.Errorf("invalid exec_output.max_bytes: %d (must not be negative)", c.Security.ExecOutput.MaxBytes)
+	}
+	for pattern, maxBytes := range c.Security.ExecOutput.ContainerMaxBytes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exec_output.container_max_bytes pattern %q: %w", pattern, err)
+		}
+		if maxBytes <= 0 {
+			return fmt.Errorf("invalid exec_output.container_max_bytes[%s]: %d (must be > 0)", pattern, maxBytes)
+		}
+	}
+
 	// Validate logging level
 	// ログレベルを検証
 	validLevels := map[string]bool{
@@ -935,6 +1650,37 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("host_access.workspace_root is required when host_commands is enabled")
 	}
 
+	// Validate host command whitelist and deny rules (malformed or ambiguous patterns)
+	// ホストコマンドのホワイトリストと拒否リストのルールを検証（不正または曖昧なパターン）
+	for baseCmd, patterns := range c.HostAccess.HostCommands.Whitelist {
+		for _, pattern := range patterns {
+			if _, err := cmdrule.Compile(pattern, c.HostAccess.HostCommands.ForbiddenFlags[baseCmd][pattern]); err != nil {
+				return fmt.Errorf("invalid host_commands.whitelist[%s] rule: %w", baseCmd, err)
+			}
+		}
+	}
+	for baseCmd, patterns := range c.HostAccess.HostCommands.Deny {
+		for _, pattern := range patterns {
+			if _, err := cmdrule.CompilePrefix(pattern); err != nil {
+				return fmt.Errorf("invalid host_commands.deny[%s] rule: %w", baseCmd, err)
+			}
+		}
+	}
+
+	// Validate the metrics history recorder (only when enabled)
+	// メトリクス履歴レコーダーを検証（有効な場合のみ）
+	if c.MetricsHistory.Enabled {
+		if c.MetricsHistory.Dir == "" {
+			return fmt.Errorf("metrics_history.dir is required when metrics_history is enabled")
+		}
+		if c.MetricsHistory.Interval < 1 {
+			return fmt.Errorf("invalid metrics_history.interval: %d (must be >= 1)", c.MetricsHistory.Interval)
+		}
+		if c.MetricsHistory.MaxBytes <= 0 {
+			return fmt.Errorf("invalid metrics_history.max_bytes: %d (must be > 0)", c.MetricsHistory.MaxBytes)
+		}
+	}
+
 	return nil
 }
 
@@ -946,3 +1692,19 @@ func (c *Config) Validate() error {
 func (c *Config) GetAddress() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
+
+// ValidateLabelSelector checks that a label selector is "key" or "key=value"
+// with a non-empty key and a valid glob pattern as value.
+//
+// ValidateLabelSelectorはラベルセレクタが空でないキーを持つ"key"または"key=value"で、
+// 値が有効なglobパターンであることをチェックします。
+func ValidateLabelSelector(selector string) error {
+	key, value, _ := strings.Cut(selector, "=")
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("invalid label selector %q: label key is empty", selector)
+	}
+	if _, err := filepath.Match(value, ""); err != nil {
+		return fmt.Errorf("invalid label selector %q: %w", selector, err)
+	}
+	return nil
+}
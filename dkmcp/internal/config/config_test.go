@@ -5,6 +5,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -385,6 +386,230 @@ func TestValidate_EmptyAllowedContainers(t *testing.T) {
 	}
 }
 
+// TestValidate_FileRead tests validation of the read_file size limits.
+// TestValidate_FileReadはread_fileのサイズ上限の検証をテストします。
+func TestValidate_FileRead(t *testing.T) {
+	tests := []struct {
+		name     string         // Test case name / テストケース名
+		fileRead FileReadConfig // Limits / 上限
+		wantErr  bool           // Whether an error is expected / エラーを期待するか
+	}{
+		{"defaults", FileReadConfig{MaxBytes: DefaultFileReadMaxBytes}, false},
+		{"unset", FileReadConfig{}, false},
+		{"container override", FileReadConfig{ContainerMaxBytes: map[string]int64{"logs-*": 1 << 24}}, false},
+		{"negative limit", FileReadConfig{MaxBytes: -1}, true},
+		{"zero override", FileReadConfig{ContainerMaxBytes: map[string]int64{"api": 0}}, true},
+		{"invalid pattern", FileReadConfig{ContainerMaxBytes: map[string]int64{"[api": 1024}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Security.FileRead = tt.fileRead
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidate_ExecOutput tests validation of the exec_command output limits.
+// TestValidate_ExecOutputはexec_commandの出力上限の検証をテストします。
+func TestValidate_ExecOutput(t *testing.T) {
+	tests := []struct {
+		name       string           // Test case name / テストケース名
+		execOutput ExecOutputConfig // Limits / 上限
+		wantErr    bool             // Whether an error is expected / エラーを期待するか
+	}{
+		{"defaults", ExecOutputConfig{MaxBytes: DefaultExecOutputMaxBytes}, false},
+		{"container override", ExecOutputConfig{ContainerMaxBytes: map[string]int64{"test-*": 1 << 23}}, false},
+		{"negative limit", ExecOutputConfig{MaxBytes: -1}, true},
+		{"zero override", ExecOutputConfig{ContainerMaxBytes: map[string]int64{"api": 0}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Security.ExecOutput = tt.execOutput
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidate_ExecJobs tests the defaults and validation of the exec job limits.
+// TestValidate_ExecJobsはexecジョブの制限のデフォルトと検証をテストします。
+func TestValidate_ExecJobs(t *testing.T) {
+	cfg := NewDefaultConfig()
+	if cfg.Security.ExecJobs.MaxJobs != DefaultExecJobsMax || cfg.Security.ExecJobs.TimeoutSeconds != DefaultExecJobTimeoutSeconds {
+		t.Errorf("unexpected defaults: %+v", cfg.Security.ExecJobs)
+	}
+
+	tests := []struct {
+		name     string         // Test case name / テストケース名
+		execJobs ExecJobsConfig // Limits / 制限
+		wantErr  bool           // Whether an error is expected / エラーを期待するか
+	}{
+		{"zero uses defaults", ExecJobsConfig{}, false},
+		{"custom", ExecJobsConfig{MaxJobs: 4, TimeoutSeconds: 1800}, false},
+		{"negative max_jobs", ExecJobsConfig{MaxJobs: -1}, true},
+		{"negative timeout", ExecJobsConfig{TimeoutSeconds: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Security.ExecJobs = tt.execJobs
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidate_ExecOptions tests validation of exec_options entries.
+// TestValidate_ExecOptionsはexec_optionsのエントリの検証をテストします。
+func TestValidate_ExecOptions(t *testing.T) {
+	tests := []struct {
+		name    string            // Test case name / テストケース名
+		opts    ExecOptionsConfig // Options of the "api" entry / "api"エントリのオプション
+		wantErr bool              // Whether an error is expected / エラーを期待するか
+	}{
+		{"valid", ExecOptionsConfig{Workdirs: []string{"/app", "/app/packages/*"}, Env: []string{"NODE_ENV", "CI=true"}, Users: []string{"node"}}, false},
+		{"relative workdir", ExecOptionsConfig{Workdirs: []string{"app"}}, true},
+		{"invalid workdir pattern", ExecOptionsConfig{Workdirs: []string{"/app/[x"}}, true},
+		{"invalid env name", ExecOptionsConfig{Env: []string{"NODE-ENV"}}, true},
+		{"reserved env name", ExecOptionsConfig{Env: []string{"DKMCP_EXEC_ID"}}, true},
+		{"empty user", ExecOptionsConfig{Users: []string{""}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Security.ExecOptions = map[string]ExecOptionsConfig{"api": tt.opts}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidate_WhitelistRules tests that malformed and ambiguous exec_whitelist and
+// host_commands whitelist rules are rejected.
+//
+// TestValidate_WhitelistRulesは不正で曖昧なexec_whitelistとhost_commandsのwhitelistの
+// ルールが拒否されることをテストします。
+func TestValidate_WhitelistRules(t *testing.T) {
+	tests := []struct {
+		name      string   // Test case name / テストケース名
+		rule      string   // Rule of the "api" entry and the "curl" host command / "api"エントリと"curl"ホストコマンドのルール
+		forbidden []string // Forbidden flags of the rule / ルールの禁止フラグ
+		wantErr   bool     // Whether an error is expected / エラーを期待するか
+	}{
+		{"plain", "npm test", nil, false},
+		{"placeholders", "curl -s http://localhost:<int:1024-65535>/health", []string{"-o"}, false},
+		{"unknown placeholder", "cat <file>", nil, true},
+		{"adjacent placeholders", "echo <int><int>", nil, true},
+		{"requires forbidden flag", "curl -o *", []string{"-o"}, true},
+		{"invalid forbidden flag", "curl *", []string{"output"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Security.ExecWhitelist = map[string][]string{"api": {tt.rule}}
+			cfg.Security.ExecForbiddenFlags = map[string]map[string][]string{"api": {tt.rule: tt.forbidden}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() exec_whitelist error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			cfg = NewDefaultConfig()
+			cfg.HostAccess.HostCommands.Whitelist = map[string][]string{"curl": {tt.rule}}
+			cfg.HostAccess.HostCommands.ForbiddenFlags = map[string]map[string][]string{"curl": {tt.rule: tt.forbidden}}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() host_commands error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := NewDefaultConfig()
+	cfg.HostAccess.HostCommands.Deny = map[string][]string{"git": {`commit -m "wip *`}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "host_commands.deny[git]") {
+		t.Errorf("Validate() deny error = %v, want an invalid deny rule", err)
+	}
+}
+
+// TestValidate_WritablePaths tests validation of writable_paths entries.
+// TestValidate_WritablePathsはwritable_pathsのエントリの検証をテストします。
+func TestValidate_WritablePaths(t *testing.T) {
+	tests := []struct {
+		name    string              // Test case name / テストケース名
+		paths   map[string][]string // Writable paths / 書き込み可能なパス
+		wantErr bool                // Whether an error is expected / エラーを期待するか
+	}{
+		{"unset", nil, false},
+		{"directory and glob", map[string][]string{"api": {"/app/config", "/app/*.json"}}, false},
+		{"relative path", map[string][]string{"api": {"app/config"}}, true},
+		{"invalid pattern", map[string][]string{"*": {"/app/[config"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			cfg.Security.WritablePaths = tt.paths
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidate_FileCopy tests validation of the copy size limit.
+// TestValidate_FileCopyはコピーのサイズ上限の検証をテストします。
+func TestValidate_FileCopy(t *testing.T) {
+	cfg := NewDefaultConfig()
+	if cfg.Security.FileCopy.MaxBytes != DefaultFileCopyMaxBytes || cfg.Security.Permissions.Copy {
+		t.Errorf("unexpected defaults: max_bytes=%d copy=%v", cfg.Security.FileCopy.MaxBytes, cfg.Security.Permissions.Copy)
+	}
+
+	cfg.Security.FileCopy.MaxBytes = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for negative file_copy.max_bytes")
+	}
+}
+
+// TestValidate_MetricsHistory tests the defaults and validation of the history recorder.
+// TestValidate_MetricsHistoryは履歴レコーダーのデフォルトと検証をテストします。
+func TestValidate_MetricsHistory(t *testing.T) {
+	cfg := NewDefaultConfig()
+	h := cfg.MetricsHistory
+	if h.Enabled || h.Dir != DefaultMetricsHistoryDir || h.Interval != DefaultMetricsHistoryInterval || h.MaxBytes != DefaultMetricsHistoryMaxBytes {
+		t.Errorf("unexpected defaults: %+v", h)
+	}
+
+	tests := []struct {
+		name    string                      // Test case name / テストケース名
+		modify  func(*MetricsHistoryConfig) // Change to the defaults / デフォルトへの変更
+		wantErr bool
+	}{
+		{"disabled ignores settings", func(h *MetricsHistoryConfig) { h.Interval = 0 }, false},
+		{"enabled with defaults", func(h *MetricsHistoryConfig) { h.Enabled = true }, false},
+		{"empty dir", func(h *MetricsHistoryConfig) { h.Enabled, h.Dir = true, "" }, true},
+		{"zero interval", func(h *MetricsHistoryConfig) { h.Enabled, h.Interval = true, 0 }, true},
+		{"zero max bytes", func(h *MetricsHistoryConfig) { h.Enabled, h.MaxBytes = true, 0 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			tt.modify(&cfg.MetricsHistory)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // TestLoad_WithDefaults tests that missing config values are filled with defaults.
 // This ensures users don't need to specify every option.
 //
@@ -502,6 +727,122 @@ security:
 	}
 }
 
+// TestLoad_ExecWhitelistTimeouts tests exec_whitelist entries given as a mapping with
+// a timeout, next to plain command entries.
+//
+// TestLoad_ExecWhitelistTimeoutsは、単純なコマンドのエントリと並んで、タイムアウト付きの
+// マッピングとして指定されたexec_whitelistのエントリをテストします。
+func TestLoad_ExecWhitelistTimeouts(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "dkmcp.yaml")
+	configContent := `
+security:
+  mode: "moderate"
+  exec_timeout:
+    seconds: 60
+    container_seconds:
+      "test-*": 900
+  exec_whitelist:
+    "api":
+      - "npm run lint"
+      - command: "npm test"
+        timeout: 600
+    "*":
+      - "pwd"
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to create test config file: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Security.ExecWhitelist["api"]; len(got) != 2 || got[0] != "npm run lint" || got[1] != "npm test" {
+		t.Errorf("ExecWhitelist[api] = %v, want the two commands", got)
+	}
+	if got := cfg.Security.ExecCommandTimeouts; len(got) != 1 || got["api"]["npm test"] != 600 {
+		t.Errorf("ExecCommandTimeouts = %v, want npm test at 600", got)
+	}
+	if cfg.Security.ExecTimeout.Seconds != 60 || cfg.Security.ExecTimeout.ContainerSeconds["test-*"] != 900 {
+		t.Errorf("ExecTimeout = %+v", cfg.Security.ExecTimeout)
+	}
+
+	invalid := filepath.Join(tmpDir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("security:\n  exec_whitelist:\n    \"api\":\n      - timeout: 10\n"), 0644); err != nil {
+		t.Fatalf("failed to create test config file: %v", err)
+	}
+	if _, err := Load(invalid); err == nil || !strings.Contains(err.Error(), "command is required") {
+		t.Errorf("Load() error = %v, want a missing command error", err)
+	}
+}
+
+// TestLoad_WhitelistForbiddenFlags tests loading forbidden_flags from the mapping form of
+// exec_whitelist and host_commands whitelist entries.
+//
+// TestLoad_WhitelistForbiddenFlagsはexec_whitelistとhost_commandsのwhitelistのエントリの
+// マッピング形式からforbidden_flagsを読み込むことをテストします。
+func TestLoad_WhitelistForbiddenFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	configFile := filepath.Join(tmpDir, "dkmcp.yaml")
+	configContent := `
+security:
+  mode: "moderate"
+  exec_whitelist:
+    "api":
+      - "npm test"
+      - command: "npm run <enum:build|lint> *"
+        timeout: 300
+        forbidden_flags: ["--inspect"]
+host_access:
+  workspace_root: "/workspace"
+  host_commands:
+    enabled: true
+    whitelist:
+      "curl":
+        - args: "-s http://localhost:<int:1024-65535>/*"
+          forbidden_flags: ["-o", "--output"]
+      "uptime":
+        - ""
+`
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to create test config file: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Security.ExecWhitelist["api"]; len(got) != 2 || got[1] != "npm run <enum:build|lint> *" {
+		t.Errorf("ExecWhitelist[api] = %v", got)
+	}
+	if got := cfg.Security.ExecForbiddenFlags["api"]["npm run <enum:build|lint> *"]; len(got) != 1 || got[0] != "--inspect" {
+		t.Errorf("ExecForbiddenFlags = %v", cfg.Security.ExecForbiddenFlags)
+	}
+	if got := cfg.Security.ExecCommandTimeouts["api"]["npm run <enum:build|lint> *"]; got != 300 {
+		t.Errorf("ExecCommandTimeouts = %v", cfg.Security.ExecCommandTimeouts)
+	}
+
+	hc := cfg.HostAccess.HostCommands
+	if got := hc.Whitelist["curl"]; len(got) != 1 || got[0] != "-s http://localhost:<int:1024-65535>/*" {
+		t.Errorf("Whitelist[curl] = %v", got)
+	}
+	if got := hc.ForbiddenFlags["curl"]["-s http://localhost:<int:1024-65535>/*"]; len(got) != 2 {
+		t.Errorf("ForbiddenFlags = %v", hc.ForbiddenFlags)
+	}
+	if got := hc.Whitelist["uptime"]; len(got) != 1 || got[0] != "" {
+		t.Errorf("Whitelist[uptime] = %v", got)
+	}
+
+	invalid := filepath.Join(tmpDir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("security:\n  exec_whitelist:\n    \"api\":\n      - command: \"curl -o *\"\n        forbidden_flags: [\"-o\"]\n"), 0644); err != nil {
+		t.Fatalf("failed to create test config file: %v", err)
+	}
+	if _, err := Load(invalid); err == nil || !strings.Contains(err.Error(), "forbidden flag") {
+		t.Errorf("Load() error = %v, want a forbidden flag error", err)
+	}
+}
+
 // TestHostAccessConfig_Defaults tests that HostAccessConfig has correct default values.
 // Both host_tools and host_commands should be disabled by default.
 //
@@ -836,3 +1177,36 @@ func TestHostAccessConfig_Validation(t *testing.T) {
 		})
 	}
 }
+
+// TestValidateLabelSelector tests validation of allowed_labels and denied_labels selectors.
+// TestValidateLabelSelectorはallowed_labelsとdenied_labelsのセレクタの検証をテストします。
+func TestValidateLabelSelector(t *testing.T) {
+	tests := []struct {
+		selector string // Label selector / ラベルセレクタ
+		wantErr  bool   // Whether an error is expected / エラーが期待されるか
+	}{
+		{"dkmcp.access", false},
+		{"dkmcp.access=readonly", false},
+		{"dkmcp.access=read*", false},
+		{"dkmcp.access=", false},
+		{"", true},
+		{"=readonly", true},
+		{"dkmcp.access=[", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateLabelSelector(tt.selector)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateLabelSelector(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+		}
+	}
+
+	cfg := &Config{
+		Server:   ServerConfig{Port: 8080},
+		Security: SecurityConfig{Mode: "moderate", DeniedLabels: []string{"=x"}},
+		Logging:  LoggingConfig{Level: "info"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject an invalid denied_labels selector")
+	}
+}
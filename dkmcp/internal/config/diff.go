@@ -0,0 +1,297 @@
+// diff.go compares two configurations and reports how the security-relevant settings
+// changed. It is used when dkmcp.yaml is reloaded, so the audit log records whether
+// each change loosened or tightened what AI assistants can do.
+//
+// diff.goは2つの設定を比較し、セキュリティに関わる設定がどう変わったかを報告します。
+// dkmcp.yamlの再読み込み時に使用され、各変更がAIアシスタントにできることを
+// 緩めたか厳しくしたかを監査ログに記録します。
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Directions of a configuration change.
+// 設定変更の方向です。
+const (
+	ChangeLoosened  = "loosened"
+	ChangeTightened = "tightened"
+	ChangeModified  = "changed"
+)
+
+// Change describes a single setting that differs between two configurations.
+// Changeは2つの設定の間で異なる1つの設定を表します。
+type Change struct {
+	// Setting is the YAML path of the setting (e.g., "security.exec_whitelist[api]")
+	// Settingは設定のYAMLパスです（例: "security.exec_whitelist[api]"）
+	Setting string `json:"setting"`
+
+	// Direction is ChangeLoosened, ChangeTightened or ChangeModified
+	// DirectionはChangeLoosened、ChangeTightened、またはChangeModifiedです
+	Direction string `json:"direction"`
+
+	// Detail describes the change (e.g., "+npm test" or "false -> true")
+	// Detailは変更内容を表します（例: "+npm test"や"false -> true"）
+	Detail string `json:"detail"`
+}
+
+// securityModeRank orders security modes from strictest to most permissive.
+// securityModeRankはセキュリティモードを最も厳格なものから最も緩いものへ順序付けます。
+var securityModeRank = map[string]int{"strict": 0, "moderate": 1, "permissive": 2}
+
+// DiffPolicy returns the changes to security and host access settings between old and new.
+// Settings that cannot be applied without a restart are reported by RestartRequired.
+//
+// DiffPolicyはoldとnewの間のセキュリティとホストアクセス設定の変更を返します。
+// 再起動なしに適用できない設定はRestartRequiredで報告されます。
+func DiffPolicy(old, new *Config) []Change {
+	var d differ
+	oldSec, newSec := &old.Security, &new.Security
+
+	if oldSec.Mode != newSec.Mode {
+		direction := ChangeTightened
+		if securityModeRank[newSec.Mode] > securityModeRank[oldSec.Mode] {
+			direction = ChangeLoosened
+		}
+		d.add("security.mode", direction, fmt.Sprintf("%s -> %s", oldSec.Mode, newSec.Mode))
+	}
+
+	// An empty allowed_containers list allows every container
+	// 空のallowed_containersリストはすべてのコンテナを許可する
+	switch {
+	case len(oldSec.AllowedContainers) > 0 && len(newSec.AllowedContainers) == 0:
+		d.add("security.allowed_containers", ChangeLoosened, "all containers allowed")
+	case len(oldSec.AllowedContainers) == 0 && len(newSec.AllowedContainers) > 0:
+		d.add("security.allowed_containers", ChangeTightened, fmt.Sprintf("restricted to %v", newSec.AllowedContainers))
+	default:
+		d.list("security.allowed_containers", oldSec.AllowedContainers, newSec.AllowedContainers, true)
+	}
+	d.list("security.allowed_labels", oldSec.AllowedLabels, newSec.AllowedLabels, true)
+	d.list("security.denied_labels", oldSec.DeniedLabels, newSec.DeniedLabels, false)
+
+	d.permissions("security.permissions", oldSec.Permissions, newSec.Permissions)
+	for _, key := range unionKeys(oldSec.ContainerPermissions, newSec.ContainerPermissions) {
+		d.permissions(fmt.Sprintf("security.container_permissions[%s]", key),
+			oldSec.ContainerPermissions[key].Apply(oldSec.Permissions),
+			newSec.ContainerPermissions[key].Apply(newSec.Permissions))
+	}
+
+	d.listMap("security.exec_whitelist", oldSec.ExecWhitelist, newSec.ExecWhitelist, true)
+	for _, key := range unionKeys(oldSec.ExecCommandTimeouts, newSec.ExecCommandTimeouts) {
+		for _, command := range unionKeys(oldSec.ExecCommandTimeouts[key], newSec.ExecCommandTimeouts[key]) {
+			d.limit(fmt.Sprintf("security.exec_whitelist[%s] timeout of %q", key, command),
+				int64(oldSec.ExecCommandTimeouts[key][command]), int64(newSec.ExecCommandTimeouts[key][command]))
+		}
+	}
+	for _, key := range unionKeys(oldSec.ExecForbiddenFlags, newSec.ExecForbiddenFlags) {
+		for _, command := range unionKeys(oldSec.ExecForbiddenFlags[key], newSec.ExecForbiddenFlags[key]) {
+			d.list(fmt.Sprintf("security.exec_whitelist[%s] forbidden_flags of %q", key, command),
+				oldSec.ExecForbiddenFlags[key][command], newSec.ExecForbiddenFlags[key][command], false)
+		}
+	}
+	d.limit("security.exec_timeout.seconds", int64(oldSec.ExecTimeout.Seconds), int64(newSec.ExecTimeout.Seconds))
+	for _, key := range unionKeys(oldSec.ExecTimeout.ContainerSeconds, newSec.ExecTimeout.ContainerSeconds) {
+		d.limit(fmt.Sprintf("security.exec_timeout.container_seconds[%s]", key),
+			int64(oldSec.ExecTimeout.ContainerSeconds[key]), int64(newSec.ExecTimeout.ContainerSeconds[key]))
+	}
+	d.limit("security.exec_jobs.max_jobs", int64(oldSec.ExecJobs.MaxJobs), int64(newSec.ExecJobs.MaxJobs))
+	d.limit("security.exec_jobs.timeout_seconds", int64(oldSec.ExecJobs.TimeoutSeconds), int64(newSec.ExecJobs.TimeoutSeconds))
+	for _, key := range unionKeys(oldSec.ExecOptions, newSec.ExecOptions) {
+		oldOpts, newOpts := oldSec.ExecOptions[key], newSec.ExecOptions[key]
+		d.list(fmt.Sprintf("security.exec_options[%s].workdirs", key), oldOpts.Workdirs, newOpts.Workdirs, true)
+		d.list(fmt.Sprintf("security.exec_options[%s].env", key), oldOpts.Env, newOpts.Env, true)
+		d.list(fmt.Sprintf("security.exec_options[%s].users", key), oldOpts.Users, newOpts.Users, true)
+	}
+	d.flag("security.exec_dangerously.enabled", oldSec.ExecDangerously.Enabled, newSec.ExecDangerously.Enabled, true)
+	d.listMap("security.exec_dangerously.commands", oldSec.ExecDangerously.Commands, newSec.ExecDangerously.Commands, true)
+	d.listMap("security.writable_paths", oldSec.WritablePaths, newSec.WritablePaths, true)
+
+	d.listMap("security.blocked_paths.manual", oldSec.BlockedPaths.Manual, newSec.BlockedPaths.Manual, false)
+	if !reflect.DeepEqual(oldSec.BlockedPaths.AutoImport, newSec.BlockedPaths.AutoImport) {
+		d.add("security.blocked_paths.auto_import", ChangeModified, "auto-import settings changed")
+	}
+
+	d.flag("security.output_masking.enabled", oldSec.OutputMasking.Enabled, newSec.OutputMasking.Enabled, false)
+	d.list("security.output_masking.patterns", oldSec.OutputMasking.Patterns, newSec.OutputMasking.Patterns, false)
+	d.flag("security.host_path_masking.enabled", oldSec.HostPathMasking.Enabled, newSec.HostPathMasking.Enabled, false)
+	d.limit("security.file_read.max_bytes", oldSec.FileRead.MaxBytes, newSec.FileRead.MaxBytes)
+	for _, key := range unionKeys(oldSec.FileRead.ContainerMaxBytes, newSec.FileRead.ContainerMaxBytes) {
+		d.limit(fmt.Sprintf("security.file_read.container_max_bytes[%s]", key),
+			oldSec.FileRead.ContainerMaxBytes[key], newSec.FileRead.ContainerMaxBytes[key])
+	}
+	d.limit("security.file_copy.max_bytes", oldSec.FileCopy.MaxBytes, newSec.FileCopy.MaxBytes)
+	d.limit("security.exec_output.max_bytes", oldSec.ExecOutput.MaxBytes, newSec.ExecOutput.MaxBytes)
+	for _, key := range unionKeys(oldSec.ExecOutput.ContainerMaxBytes, newSec.ExecOutput.ContainerMaxBytes) {
+		d.limit(fmt.Sprintf("security.exec_output.container_max_bytes[%s]", key),
+			oldSec.ExecOutput.ContainerMaxBytes[key], newSec.ExecOutput.ContainerMaxBytes[key])
+	}
+
+	oldHost, newHost := &old.HostAccess, &new.HostAccess
+	if oldHost.WorkspaceRoot != newHost.WorkspaceRoot {
+		d.add("host_access.workspace_root", ChangeModified, fmt.Sprintf("%s -> %s", oldHost.WorkspaceRoot, newHost.WorkspaceRoot))
+	}
+	d.flag("host_access.host_tools.enabled", oldHost.HostTools.Enabled, newHost.HostTools.Enabled, true)
+	if oldHost.HostTools.Enabled && newHost.HostTools.Enabled && !reflect.DeepEqual(oldHost.HostTools, newHost.HostTools) {
+		d.add("host_access.host_tools", ChangeModified, "host tools settings changed")
+	}
+	d.flag("host_access.host_commands.enabled", oldHost.HostCommands.Enabled, newHost.HostCommands.Enabled, true)
+	d.list("host_access.host_commands.allowed_containers", oldHost.HostCommands.AllowedContainers, newHost.HostCommands.AllowedContainers, true)
+	d.list("host_access.host_commands.allowed_projects", oldHost.HostCommands.AllowedProjects, newHost.HostCommands.AllowedProjects, true)
+	d.listMap("host_access.host_commands.whitelist", oldHost.HostCommands.Whitelist, newHost.HostCommands.Whitelist, true)
+	for _, baseCmd := range unionKeys(oldHost.HostCommands.ForbiddenFlags, newHost.HostCommands.ForbiddenFlags) {
+		oldFlags, newFlags := oldHost.HostCommands.ForbiddenFlags[baseCmd], newHost.HostCommands.ForbiddenFlags[baseCmd]
+		for _, pattern := range unionKeys(oldFlags, newFlags) {
+			d.list(fmt.Sprintf("host_access.host_commands.whitelist[%s] forbidden_flags of %q", baseCmd, pattern),
+				oldFlags[pattern], newFlags[pattern], false)
+		}
+	}
+	d.listMap("host_access.host_commands.deny", oldHost.HostCommands.Deny, newHost.HostCommands.Deny, false)
+	d.flag("host_access.host_commands.dangerously.enabled", oldHost.HostCommands.Dangerously.Enabled, newHost.HostCommands.Dangerously.Enabled, true)
+	d.listMap("host_access.host_commands.dangerously.commands", oldHost.HostCommands.Dangerously.Commands, newHost.HostCommands.Dangerously.Commands, true)
+
+	return d.changes
+}
+
+// RestartRequired returns the settings that changed between old and new but only take
+// effect after restarting the server.
+//
+// RestartRequiredはoldとnewの間で変更されたが、サーバーの再起動後にのみ
+// 有効になる設定を返します。
+func RestartRequired(old, new *Config) []string {
+	var settings []string
+	if old.Server != new.Server {
+		settings = append(settings, "server")
+	}
+	if old.Logging != new.Logging {
+		settings = append(settings, "logging")
+	}
+	if old.Audit != new.Audit {
+		settings = append(settings, "audit")
+	}
+	if old.MetricsHistory != new.MetricsHistory {
+		settings = append(settings, "metrics_history")
+	}
+	return settings
+}
+
+// differ accumulates changes.
+// differは変更を蓄積します。
+type differ struct {
+	changes []Change
+}
+
+// add records a change.
+// addは変更を記録します。
+func (d *differ) add(setting, direction, detail string) {
+	d.changes = append(d.changes, Change{Setting: setting, Direction: direction, Detail: detail})
+}
+
+// flag records a change of a boolean setting. looserWhenTrue tells whether enabling
+// the setting grants more access.
+//
+// flagは真偽値の設定の変更を記録します。looserWhenTrueは設定を有効にすると
+// より多くのアクセスを許可するかどうかを示します。
+func (d *differ) flag(setting string, old, new, looserWhenTrue bool) {
+	if old == new {
+		return
+	}
+	direction := ChangeTightened
+	if new == looserWhenTrue {
+		direction = ChangeLoosened
+	}
+	d.add(setting, direction, fmt.Sprintf("%t -> %t", old, new))
+}
+
+// list records entries added to or removed from a list. addedLoosens tells whether
+// adding an entry grants more access (as in a whitelist) or less (as in a deny list).
+//
+// listはリストに追加または削除されたエントリを記録します。addedLoosensはエントリの追加が
+// （ホワイトリストのように）より多くのアクセスを許可するか、（拒否リストのように）
+// より少なくするかを示します。
+func (d *differ) list(setting string, old, new []string, addedLoosens bool) {
+	added, removed := ChangeTightened, ChangeLoosened
+	if addedLoosens {
+		added, removed = ChangeLoosened, ChangeTightened
+	}
+	oldSet := toSet(old)
+	newSet := toSet(new)
+	for _, entry := range new {
+		if !oldSet[entry] {
+			d.add(setting, added, "+"+entry)
+		}
+	}
+	for _, entry := range old {
+		if !newSet[entry] {
+			d.add(setting, removed, "-"+entry)
+		}
+	}
+}
+
+// listMap records list changes for every key of a per-container map.
+// listMapはコンテナ単位のマップのすべてのキーについてリストの変更を記録します。
+func (d *differ) listMap(setting string, old, new map[string][]string, addedLoosens bool) {
+	for _, key := range unionKeys(old, new) {
+		d.list(fmt.Sprintf("%s[%s]", setting, key), old[key], new[key], addedLoosens)
+	}
+}
+
+// limit records a change of a size limit. Raising a limit loosens it; adding or removing
+// an entry (0 = not set) is reported as a plain change, since the inherited limit applies.
+//
+// limitはサイズ上限の変更を記録します。上限を上げると緩めることになります。エントリの
+// 追加や削除（0 = 未設定）は継承した上限が適用されるため、単なる変更として報告します。
+func (d *differ) limit(setting string, old, new int64) {
+	if old == new {
+		return
+	}
+	direction := ChangeTightened
+	switch {
+	case old == 0 || new == 0:
+		direction = ChangeModified
+	case new > old:
+		direction = ChangeLoosened
+	}
+	d.add(setting, direction, fmt.Sprintf("%d -> %d", old, new))
+}
+
+// permissions records changes of each operation permission.
+// permissionsは各操作の権限の変更を記録します。
+func (d *differ) permissions(setting string, old, new SecurityPermissions) {
+	d.flag(setting+".logs", old.Logs, new.Logs, true)
+	d.flag(setting+".inspect", old.Inspect, new.Inspect, true)
+	d.flag(setting+".stats", old.Stats, new.Stats, true)
+	d.flag(setting+".exec", old.Exec, new.Exec, true)
+	d.flag(setting+".lifecycle", old.Lifecycle, new.Lifecycle, true)
+	d.flag(setting+".write", old.Write, new.Write, true)
+	d.flag(setting+".copy", old.Copy, new.Copy, true)
+}
+
+// toSet converts a list to a set.
+// toSetはリストを集合に変換します。
+func toSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, entry := range list {
+		set[entry] = true
+	}
+	return set
+}
+
+// unionKeys returns the sorted keys present in either map.
+// unionKeysはいずれかのマップに存在するキーをソートして返します。
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		seen[key] = true
+	}
+	for key := range b {
+		seen[key] = true
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
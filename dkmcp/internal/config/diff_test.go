@@ -0,0 +1,186 @@
+// diff_test.go contains tests for comparing configurations on reload.
+// diff_test.goは再読み込み時の設定比較のテストを含みます。
+package config
+
+import (
+	"testing"
+)
+
+// TestDiffPolicy tests that each kind of change is reported with the right direction.
+// TestDiffPolicyは各種類の変更が正しい方向で報告されることをテストします。
+func TestDiffPolicy(t *testing.T) {
+	tests := []struct {
+		name   string          // Test case name / テストケース名
+		modify func(c *Config) // Change applied to the new config / 新しい設定に適用する変更
+		want   []Change        // Expected changes / 期待される変更
+	}{
+		{
+			name:   "no change",
+			modify: func(c *Config) {},
+			want:   nil,
+		},
+		{
+			name:   "mode to permissive loosens",
+			modify: func(c *Config) { c.Security.Mode = "permissive" },
+			want:   []Change{{"security.mode", ChangeLoosened, "moderate -> permissive"}},
+		},
+		{
+			name:   "mode to strict tightens",
+			modify: func(c *Config) { c.Security.Mode = "strict" },
+			want:   []Change{{"security.mode", ChangeTightened, "moderate -> strict"}},
+		},
+		{
+			name:   "allowed container added",
+			modify: func(c *Config) { c.Security.AllowedContainers = append(c.Security.AllowedContainers, "web-*") },
+			want:   []Change{{"security.allowed_containers", ChangeLoosened, "+web-*"}},
+		},
+		{
+			name:   "allowed containers emptied allows all",
+			modify: func(c *Config) { c.Security.AllowedContainers = nil },
+			want:   []Change{{"security.allowed_containers", ChangeLoosened, "all containers allowed"}},
+		},
+		{
+			name:   "denied label added",
+			modify: func(c *Config) { c.Security.DeniedLabels = []string{"dkmcp.deny"} },
+			want:   []Change{{"security.denied_labels", ChangeTightened, "+dkmcp.deny"}},
+		},
+		{
+			name:   "permission disabled",
+			modify: func(c *Config) { c.Security.Permissions.Logs = false },
+			want:   []Change{{"security.permissions.logs", ChangeTightened, "true -> false"}},
+		},
+		{
+			name: "container permission override",
+			modify: func(c *Config) {
+				enabled := true
+				c.Security.ContainerPermissions = map[string]PermissionOverrides{"api": {Exec: &enabled}}
+			},
+			want: []Change{{"security.container_permissions[api].exec", ChangeLoosened, "false -> true"}},
+		},
+		{
+			name: "whitelist command removed and added",
+			modify: func(c *Config) {
+				c.Security.ExecWhitelist = map[string][]string{"api-1": {"npm test", "npm run lint"}}
+			},
+			want: []Change{
+				{"security.exec_whitelist[api-1]", ChangeLoosened, "+npm run lint"},
+				{"security.exec_whitelist[api-1]", ChangeTightened, "-npm run build"},
+			},
+		},
+		{
+			name: "forbidden flag removed",
+			modify: func(c *Config) {
+				c.Security.ExecForbiddenFlags = map[string]map[string][]string{"*": {"curl *": {"-o"}}}
+			},
+			want: []Change{{`security.exec_whitelist[*] forbidden_flags of "curl *"`, ChangeLoosened, "--K"}},
+		},
+		{
+			name:   "blocked path added",
+			modify: func(c *Config) { c.Security.BlockedPaths.Manual = map[string][]string{"*": {"/secrets/*"}} },
+			want:   []Change{{"security.blocked_paths.manual[*]", ChangeTightened, "+/secrets/*"}},
+		},
+		{
+			name:   "output masking disabled",
+			modify: func(c *Config) { c.Security.OutputMasking.Enabled = false },
+			want:   []Change{{"security.output_masking.enabled", ChangeLoosened, "true -> false"}},
+		},
+		{
+			name: "read size limits changed",
+			modify: func(c *Config) {
+				c.Security.FileRead.MaxBytes = 4096
+				c.Security.FileRead.ContainerMaxBytes = map[string]int64{"logs": 1 << 24}
+			},
+			want: []Change{
+				{"security.file_read.max_bytes", ChangeTightened, "1048576 -> 4096"},
+				{"security.file_read.container_max_bytes[logs]", ChangeModified, "0 -> 16777216"},
+			},
+		},
+		{
+			name: "writes enabled",
+			modify: func(c *Config) {
+				c.Security.Permissions.Write = true
+				c.Security.WritablePaths = map[string][]string{"api": {"/app/config"}}
+			},
+			want: []Change{
+				{"security.permissions.write", ChangeLoosened, "false -> true"},
+				{"security.writable_paths[api]", ChangeLoosened, "+/app/config"},
+			},
+		},
+		{
+			name: "copies enabled with a larger limit",
+			modify: func(c *Config) {
+				enabled := true
+				c.Security.ContainerPermissions = map[string]PermissionOverrides{"api": {Copy: &enabled}}
+				c.Security.FileCopy.MaxBytes = 1 << 30
+			},
+			want: []Change{
+				{"security.container_permissions[api].copy", ChangeLoosened, "false -> true"},
+				{"security.file_copy.max_bytes", ChangeLoosened, "268435456 -> 1073741824"},
+			},
+		},
+		{
+			name: "host commands enabled with deny list",
+			modify: func(c *Config) {
+				c.HostAccess.HostCommands.Enabled = true
+				c.HostAccess.HostCommands.Deny = map[string][]string{"git": {"push"}}
+			},
+			want: []Change{
+				{"host_access.host_commands.enabled", ChangeLoosened, "false -> true"},
+				{"host_access.host_commands.deny[git]", ChangeTightened, "+push"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := diffTestConfig()
+			new := diffTestConfig()
+			tt.modify(new)
+
+			got := DiffPolicy(old, new)
+			if len(got) != len(tt.want) {
+				t.Fatalf("DiffPolicy() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestRestartRequired tests that settings applied only at startup are reported.
+// TestRestartRequiredは起動時のみ適用される設定が報告されることをテストします。
+func TestRestartRequired(t *testing.T) {
+	old := diffTestConfig()
+	new := diffTestConfig()
+	if got := RestartRequired(old, new); len(got) != 0 {
+		t.Errorf("RestartRequired() = %v for identical configs, want none", got)
+	}
+
+	new.Server.Port = 9090
+	new.Logging.Level = "debug"
+	new.Security.Mode = "strict"
+	new.MetricsHistory.Enabled = true
+	got := RestartRequired(old, new)
+	if len(got) != 3 || got[0] != "server" || got[1] != "logging" || got[2] != "metrics_history" {
+		t.Errorf("RestartRequired() = %v, want [server logging metrics_history]", got)
+	}
+}
+
+// diffTestConfig returns the baseline configuration the diff tests modify.
+// diffTestConfigは差分テストが変更する基準の設定を返します。
+func diffTestConfig() *Config {
+	cfg := NewDefaultConfig()
+	cfg.Security.Mode = "moderate"
+	cfg.Security.AllowedContainers = []string{"api-*"}
+	cfg.Security.Permissions = SecurityPermissions{Logs: true, Inspect: true, Stats: true}
+	cfg.Security.ExecWhitelist = map[string][]string{"api-1": {"npm test", "npm run build"}}
+	cfg.Security.ExecForbiddenFlags = map[string]map[string][]string{"*": {"curl *": {"-o", "-K"}}}
+	cfg.Security.BlockedPaths.Manual = nil
+	cfg.Security.OutputMasking.Enabled = true
+	cfg.HostAccess.HostCommands.Enabled = false
+	cfg.HostAccess.HostCommands.Deny = nil
+	return cfg
+}
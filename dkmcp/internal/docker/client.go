@@ -22,7 +22,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -48,9 +51,12 @@ type Client struct {
 	// dockerはコンテナ操作用の基盤となるDocker SDKクライアントです。
 	docker *client.Client
 
-	// policy defines the security rules for container access.
-	// policyはコンテナアクセスのセキュリティルールを定義します。
-	policy *security.Policy
+	// policy defines the security rules for container access. It is replaced
+	// atomically by ReloadPolicy when the configuration is reloaded.
+	//
+	// policyはコンテナアクセスのセキュリティルールを定義します。設定の再読み込み時に
+	// ReloadPolicyによってアトミックに置き換えられます。
+	policy atomic.Pointer[security.Policy]
 }
 
 // NewClient creates a new Docker client with security policy enforcement.
@@ -91,10 +97,9 @@ func NewClient(policy *security.Policy) (*Client, error) {
 		return nil, fmt.Errorf("failed to create docker client: %w", err)
 	}
 
-	return &Client{
-		docker: dockerClient,
-		policy: policy,
-	}, nil
+	c := &Client{docker: dockerClient}
+	c.policy.Store(policy)
+	return c, nil
 }
 
 // Close closes the Docker client and releases associated resources.
@@ -112,7 +117,7 @@ func (c *Client) Close() error {
 // GetPolicyはこのクライアントに関連付けられたセキュリティポリシーを返します。
 // これは出力マスキングなどのポリシー機能にアクセスするために使用できます。
 func (c *Client) GetPolicy() *security.Policy {
-	return c.policy
+	return c.policy.Load()
 }
 
 // ContainerInfo represents simplified container information returned
@@ -151,6 +156,20 @@ type ContainerInfo struct {
 	// Labelsはコンテナのラベルをキーと値のペアとして含みます。
 	Labels map[string]string `json:"labels,omitempty"`
 
+	// Project is the Docker Compose project (empty for standalone containers).
+	// ProjectはDocker Composeのプロジェクトです（単独のコンテナでは空）。
+	Project string `json:"project,omitempty"`
+
+	// Service is the Docker Compose service (empty for standalone containers).
+	// ServiceはDocker Composeのサービスです（単独のコンテナでは空）。
+	Service string `json:"service,omitempty"`
+
+	// Health is the health check status ("healthy", "unhealthy", "starting"),
+	// empty when the container has no health check.
+	// Healthはヘルスチェックの状態（"healthy"、"unhealthy"、"starting"）です。
+	// ヘルスチェックのないコンテナでは空です。
+	Health string `json:"health,omitempty"`
+
 	// Ports contains the port mappings as formatted strings.
 	// Example: ["0.0.0.0:80->80/tcp", "443/tcp"]
 	// Portsはフォーマットされた文字列としてのポートマッピングを含みます。
@@ -178,7 +197,8 @@ type ContainerInfo struct {
 func (c *Client) ListContainers(ctx context.Context) ([]ContainerInfo, error) {
 	// Check if the policy allows container inspection.
 	// ポリシーがコンテナ検査を許可しているかチェックします。
-	if !c.policy.CanInspect() {
+	policy := c.GetPolicy()
+	if !policy.CanListContainers() {
 		return nil, fmt.Errorf("inspect permission denied")
 	}
 
@@ -189,6 +209,10 @@ func (c *Client) ListContainers(ctx context.Context) ([]ContainerInfo, error) {
 		return nil, fmt.Errorf("failed to list containers: %w", err)
 	}
 
+	// Refresh the Compose identities first so policy entries naming services match.
+	// サービスを指定するポリシーのエントリがマッチするよう、先にComposeのIDを更新します。
+	c.registerContainers(containers)
+
 	// Filter containers based on security policy and build result slice.
 	// セキュリティポリシーに基づいてコンテナをフィルタリングし、結果スライスを構築します。
 	var result []ContainerInfo
@@ -198,10 +222,10 @@ func (c *Client) ListContainers(ctx context.Context) ([]ContainerInfo, error) {
 		name := strings.TrimPrefix(ctr.Names[0], "/")
 
 		// Check if container is accessible according to security policy.
-		// Skip containers that don't match allowed patterns.
+		// Skip containers that don't match allowed patterns or may not be inspected.
 		// セキュリティポリシーに従ってコンテナがアクセス可能かチェックします。
-		// 許可パターンに一致しないコンテナはスキップします。
-		if !c.policy.CanAccessContainer(name) {
+		// 許可パターンに一致しない、または検査できないコンテナはスキップします。
+		if !policy.CanAccessContainer(name) || !policy.CanInspect(name) {
 			continue
 		}
 
@@ -216,12 +240,105 @@ func (c *Client) ListContainers(ctx context.Context) ([]ContainerInfo, error) {
 			Created: ctr.Created,
 			Labels:  ctr.Labels,
 			Ports:   formatPorts(ctr.Ports),
+			Project: ctr.Labels[security.ComposeProjectLabel],
+			Service: ctr.Labels[security.ComposeServiceLabel],
+			Health:  parseHealth(ctr.Status),
 		})
 	}
 
 	return result, nil
 }
 
+// parseHealth extracts the health check status from a container status string
+// such as "Up 2 hours (healthy)" or "Up 5 seconds (health: starting)".
+//
+// parseHealthは"Up 2 hours (healthy)"や"Up 5 seconds (health: starting)"のような
+// コンテナの状態文字列からヘルスチェックの状態を取り出します。
+func parseHealth(status string) string {
+	switch {
+	case strings.Contains(status, "(unhealthy)"):
+		return "unhealthy"
+	case strings.Contains(status, "(healthy)"):
+		return "healthy"
+	case strings.Contains(status, "(health: starting)"):
+		return "starting"
+	}
+	return ""
+}
+
+// registerContainers passes the names and Compose labels of all containers on the
+// host to the policy, so that policy entries can refer to services.
+//
+// registerContainersはホスト上のすべてのコンテナの名前とComposeラベルをポリシーに渡し、
+// ポリシーのエントリがサービスを参照できるようにします。
+func (c *Client) registerContainers(containers []types.Container) {
+	c.GetPolicy().SetContainerIdentities(containerIdentities(containers))
+}
+
+// containerIdentities builds the identities of containers returned by the Docker API.
+// containerIdentitiesはDocker APIが返したコンテナのIDを構築します。
+func containerIdentities(containers []types.Container) []security.ContainerIdentity {
+	ids := make([]security.ContainerIdentity, 0, len(containers))
+	for _, ctr := range containers {
+		if len(ctr.Names) == 0 {
+			continue
+		}
+		ids = append(ids, security.NewContainerIdentity(strings.TrimPrefix(ctr.Names[0], "/"), ctr.Labels))
+	}
+	return ids
+}
+
+// ResolveContainer resolves a container reference the way the container tools do.
+// ResolveContainerはコンテナツールと同じ方法でコンテナ参照を解決します。
+func (c *Client) ResolveContainer(ctx context.Context, ref string) string {
+	return c.resolveContainer(ctx, ref)
+}
+
+// resolveContainer maps a container reference to a container name. The reference may
+// be a container name, a container ID, a Compose service ("api") or "project/service".
+// A service with several replicas resolves to its lowest-numbered running replica.
+// References that match nothing are returned unchanged, so Docker reports the error.
+//
+// resolveContainerはコンテナ参照をコンテナ名に対応付けます。参照にはコンテナ名、
+// コンテナID、Composeのサービス（"api"）、または"project/service"を使用できます。
+// 複数のレプリカを持つサービスは、実行中で最も番号の小さいレプリカに解決されます。
+// 何にもマッチしない参照はそのまま返し、Dockerにエラーを報告させます。
+func (c *Client) resolveContainer(ctx context.Context, ref string) string {
+	containers, err := c.docker.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return ref
+	}
+	c.registerContainers(containers)
+
+	var running, stopped []security.ContainerIdentity
+	for _, ctr := range containers {
+		if len(ctr.Names) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(ctr.Names[0], "/")
+		if name == ref || (len(ref) >= 12 && strings.HasPrefix(ctr.ID, ref)) {
+			return name
+		}
+		id := security.NewContainerIdentity(name, ctr.Labels)
+		if !id.MatchesRef(ref) {
+			continue
+		}
+		if ctr.State == "running" {
+			running = append(running, id)
+		} else {
+			stopped = append(stopped, id)
+		}
+	}
+
+	for _, replicas := range [][]security.ContainerIdentity{running, stopped} {
+		if len(replicas) > 0 {
+			security.SortReplicas(replicas)
+			return replicas[0].Name
+		}
+	}
+	return ref
+}
+
 // formatPorts converts Docker SDK port bindings to human-readable strings.
 // Example outputs: "0.0.0.0:80->80/tcp", "443/tcp", "0.0.0.0:8080->80/tcp, 80/tcp"
 //
@@ -274,16 +391,20 @@ func formatPorts(ports []types.Port) []string {
 // このメソッドはセキュリティポリシーに従って"logs"権限と
 // 指定されたコンテナへのアクセスの両方が必要です。
 func (c *Client) GetLogs(ctx context.Context, containerName string, tail string, since string, follow bool) (string, error) {
+	// Resolve Compose service references (e.g., "api" or "shop/api") to a container name.
+	// Composeのサービス参照（例："api"や"shop/api"）をコンテナ名に解決します。
+	containerName = c.resolveContainer(ctx, containerName)
+
 	// Verify logs permission is granted by policy.
 	// ポリシーによってログ権限が付与されているか確認します。
-	if !c.policy.CanGetLogs() {
+	if !c.GetPolicy().CanGetLogs(containerName) {
 		return "", fmt.Errorf("logs permission denied")
 	}
 
 	// Verify the specific container is accessible.
 	// 特定のコンテナがアクセス可能か確認します。
-	if !c.policy.CanAccessContainer(containerName) {
-		return "", fmt.Errorf("access denied to container: %s", containerName)
+	if err := c.GetPolicy().CheckContainerAccess(containerName); err != nil {
+		return "", err
 	}
 
 	// Configure log retrieval options.
@@ -315,6 +436,161 @@ func (c *Client) GetLogs(ctx context.Context, containerName string, tail string,
 	return buf.String(), nil
 }
 
+// GetLogLines retrieves the logs of a container split into lines, keeping track of
+// whether each line came from stdout or stderr. Each line keeps its Docker timestamp prefix.
+//
+// Parameters:
+//   - containerName: The name or ID of the container
+//   - tail: Number of lines to retrieve from the end (e.g., "100", "all")
+//   - since: Show logs since timestamp or relative time (e.g., "42m"). Empty string disables filter.
+//   - until: Show logs before timestamp or relative time (e.g., "10m"). Empty string disables filter.
+//
+// This method requires both "logs" permission and access to the
+// specified container according to the security policy.
+//
+// GetLogLinesはコンテナのログを行に分割して取得し、各行がstdoutとstderrのどちらから
+// 来たかを保持します。各行はDockerのタイムスタンプ接頭辞を保持します。
+//
+// パラメータ:
+//   - containerName: コンテナの名前またはID
+//   - tail: 末尾から取得する行数（例："100"、"all"）
+//   - since: ログ表示開始のタイムスタンプまたは相対時間（例："42m"）。空文字列でフィルタ無効。
+//   - until: ログ表示終了のタイムスタンプまたは相対時間（例："10m"）。空文字列でフィルタ無効。
+//
+// このメソッドはセキュリティポリシーに従って"logs"権限と
+// 指定されたコンテナへのアクセスの両方が必要です。
+func (c *Client) GetLogLines(ctx context.Context, containerName string, tail string, since string, until string) ([]LogLine, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	// Verify logs permission is granted by policy.
+	// ポリシーによってログ権限が付与されているか確認します。
+	if !c.GetPolicy().CanGetLogs(containerName) {
+		return nil, fmt.Errorf("logs permission denied")
+	}
+
+	// Verify the specific container is accessible.
+	// 特定のコンテナがアクセス可能か確認します。
+	if err := c.GetPolicy().CheckContainerAccess(containerName); err != nil {
+		return nil, err
+	}
+
+	tty, err := c.hasTTY(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+		Since:      since,
+		Until:      until,
+		Timestamps: true,
+	}
+
+	logs, err := c.docker.ContainerLogs(ctx, containerName, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", err)
+	}
+	defer logs.Close()
+
+	var lines []LogLine
+	if err := readLogLines(logs, tty, func(line LogLine) error {
+		lines = append(lines, line)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to read logs: %w", err)
+	}
+	return lines, nil
+}
+
+// hasTTY reports whether a container was started with a TTY.
+// The log stream is only multiplexed when the container has no TTY.
+//
+// hasTTYはコンテナがTTY付きで起動されたかどうかを報告します。
+// ログストリームはコンテナがTTYを持たない場合のみ多重化されます。
+func (c *Client) hasTTY(ctx context.Context, containerName string) (bool, error) {
+	info, err := c.docker.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return info.Config != nil && info.Config.Tty, nil
+}
+
+// FollowLogs streams the logs of a container line by line.
+// Unlike GetLogs, lines are delivered to onLine as soon as Docker emits them,
+// so a caller can forward them while the container keeps running.
+//
+// The stream ends when ctx is cancelled (reported as success), when the container
+// stops, or when onLine returns an error (which is returned as-is).
+//
+// This method requires both "logs" permission and access to the
+// specified container according to the security policy.
+//
+// FollowLogsはコンテナのログを1行ずつストリームします。
+// GetLogsと異なり、Dockerがログを出力した時点で行がonLineに渡されるため、
+// 呼び出し元はコンテナの実行中に行を転送できます。
+//
+// ストリームはctxがキャンセルされた場合（成功として扱う）、コンテナが停止した場合、
+// またはonLineがエラーを返した場合（そのまま返す）に終了します。
+//
+// このメソッドはセキュリティポリシーに従って"logs"権限と
+// 指定されたコンテナへのアクセスの両方が必要です。
+func (c *Client) FollowLogs(ctx context.Context, containerName string, tail string, since string, onLine func(LogLine) error) error {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	// Verify logs permission is granted by policy.
+	// ポリシーによってログ権限が付与されているか確認します。
+	if !c.GetPolicy().CanGetLogs(containerName) {
+		return fmt.Errorf("logs permission denied")
+	}
+
+	// Verify the specific container is accessible.
+	// 特定のコンテナがアクセス可能か確認します。
+	if err := c.GetPolicy().CheckContainerAccess(containerName); err != nil {
+		return err
+	}
+
+	tty, err := c.hasTTY(ctx, containerName)
+	if err != nil {
+		return err
+	}
+
+	options := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       tail,
+		Since:      since,
+		Follow:     true,
+		Timestamps: true,
+	}
+
+	logs, err := c.docker.ContainerLogs(ctx, containerName, options)
+	if err != nil {
+		return fmt.Errorf("failed to follow logs: %w", err)
+	}
+	defer logs.Close()
+
+	// Keep the callback's error apart from stream errors so it can be returned unwrapped.
+	// コールバックのエラーをストリームエラーと区別し、ラップせずに返せるようにします。
+	var callbackErr error
+	err = readLogLines(logs, tty, func(line LogLine) error {
+		callbackErr = onLine(line)
+		return callbackErr
+	})
+	switch {
+	case callbackErr != nil:
+		return callbackErr
+	case err != nil && ctx.Err() != nil:
+		// Cancellation is the normal way for a caller to stop following.
+		// キャンセルは呼び出し元がフォローを停止する通常の方法です。
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read logs: %w", err)
+	}
+	return nil
+}
+
 // GetStats retrieves resource usage statistics for a container.
 // This includes CPU, memory, network, and I/O statistics.
 //
@@ -331,16 +607,18 @@ func (c *Client) GetLogs(ctx context.Context, containerName string, tail string,
 // このメソッドはセキュリティポリシーに従って"stats"権限と
 // 指定されたコンテナへのアクセスの両方が必要です。
 func (c *Client) GetStats(ctx context.Context, containerName string) (*container.StatsResponse, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
 	// Verify stats permission is granted by policy.
 	// ポリシーによって統計権限が付与されているか確認します。
-	if !c.policy.CanGetStats() {
+	if !c.GetPolicy().CanGetStats(containerName) {
 		return nil, fmt.Errorf("stats permission denied")
 	}
 
 	// Verify the specific container is accessible.
 	// 特定のコンテナがアクセス可能か確認します。
-	if !c.policy.CanAccessContainer(containerName) {
-		return nil, fmt.Errorf("access denied to container: %s", containerName)
+	if err := c.GetPolicy().CheckContainerAccess(containerName); err != nil {
+		return nil, err
 	}
 
 	// Get one-shot stats (stream=false means single response).
@@ -362,18 +640,45 @@ func (c *Client) GetStats(ctx context.Context, containerName string) (*container
 }
 
 // ExecResult represents the result of executing a command in a container.
-// It contains the exit code and the combined stdout/stderr output.
+// It contains the exit code, the output as separate stdout and stderr and combined,
+// how long the command ran, and whether the output was cut at the size limit.
 //
 // ExecResultはコンテナ内でコマンドを実行した結果を表します。
-// 終了コードと標準出力/標準エラー出力の組み合わせを含みます。
+// 終了コード、個別の標準出力と標準エラー出力およびその組み合わせ、
+// コマンドの実行時間、出力がサイズ上限で打ち切られたかどうかを含みます。
 type ExecResult struct {
-	// ExitCode is the exit status of the command (0 typically means success).
-	// ExitCodeはコマンドの終了ステータスです（0は通常成功を意味します）。
+	// ExitCode is the exit status of the command (0 typically means success;
+	// -1 when the command timed out).
+	// ExitCodeはコマンドの終了ステータスです（0は通常成功を意味します。
+	// コマンドがタイムアウトした場合は-1）。
 	ExitCode int `json:"exit_code"`
 
-	// Output contains the combined stdout and stderr from the command.
-	// Outputはコマンドからの標準出力と標準エラー出力の組み合わせを含みます。
+	// Output contains the stdout and stderr from the command, interleaved in the order
+	// they were written.
+	// Outputはコマンドからの標準出力と標準エラー出力を、書き込まれた順に交互に含みます。
 	Output string `json:"output"`
+
+	// Stdout contains the standard output of the command.
+	// Stdoutはコマンドの標準出力を含みます。
+	Stdout string `json:"stdout"`
+
+	// Stderr contains the standard error of the command.
+	// Stderrはコマンドの標準エラー出力を含みます。
+	Stderr string `json:"stderr"`
+
+	// DurationMs is how long the command ran, in milliseconds.
+	// DurationMsはコマンドの実行時間（ミリ秒）です。
+	DurationMs int64 `json:"duration_ms"`
+
+	// Truncated reports that output beyond the exec_output limit was discarded.
+	// Truncatedはexec_outputの上限を超える出力が破棄されたことを報告します。
+	Truncated bool `json:"truncated"`
+
+	// TimedOut reports that the command ran past its exec_timeout and was killed;
+	// the output is what it wrote until then.
+	// TimedOutはコマンドがexec_timeoutを超えて終了されたことを報告します。
+	// 出力はそれまでに書き込まれたものです。
+	TimedOut bool `json:"timed_out"`
 }
 
 // Exec executes a whitelisted command in a container.
@@ -384,8 +689,10 @@ type ExecResult struct {
 // execution while still allowing specific, pre-approved commands
 // (e.g., "npm test", "npm run lint").
 //
-// The command string is parsed by splitting on whitespace. For complex
-// commands with quoted arguments, this parsing may need enhancement.
+// The command string is parsed once by the security policy (quotes and
+// escapes, no shell expansion) and the argv the policy approved is executed
+// directly, without a shell. It runs for at most the policy's exec timeout, with the
+// working directory, environment and user in opts when exec_options allows them.
 //
 // Execはコンテナ内でホワイトリストに登録されたコマンドを実行します。
 // コマンドは特定のコンテナに対するセキュリティポリシーのexec_whitelistで
@@ -395,67 +702,78 @@ type ExecResult struct {
 // 特定の事前承認されたコマンド（例："npm test"、"npm run lint"）を
 // 許可します。
 //
-// コマンド文字列は空白で分割して解析されます。引用符付き引数を含む
-// 複雑なコマンドの場合、この解析は強化が必要かもしれません。
-func (c *Client) Exec(ctx context.Context, containerName string, command string, dangerously bool) (*ExecResult, error) {
-	// Check if the command is allowed for this container.
-	// The policy validates container access and command permissions.
-	// このコンテナに対してコマンドが許可されているかチェックします。
-	// ポリシーはコンテナアクセスとコマンド権限を検証します。
-	var allowed bool
-	var err error
-
-	if dangerously {
-		// Dangerous mode: allows commands from exec_dangerously list with path blocking
-		// 危険モード: パスブロック付きでexec_dangerouslyリストのコマンドを許可
-		allowed, err = c.policy.CanExecDangerously(containerName, command)
-	} else {
-		// Normal mode: only whitelisted commands
-		// 通常モード: ホワイトリストのコマンドのみ
-		allowed, err = c.policy.CanExec(containerName, command)
-	}
-
-	if err != nil || !allowed {
-		if err == nil {
-			err = fmt.Errorf("exec permission denied")
-		}
+// コマンド文字列はセキュリティポリシーによって一度だけ解析され（引用符と
+// エスケープを処理し、シェル展開は行わない）、ポリシーが承認したargvが
+// シェルを介さずに直接実行されます。実行時間はポリシーのexecタイムアウトまでで、
+// exec_optionsが許可する場合はopts内の作業ディレクトリ、環境、ユーザーで実行されます。
+func (c *Client) Exec(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions) (*ExecResult, error) {
+	containerName, cmdParts, opts, err := c.authorizeExec(ctx, containerName, command, dangerously, opts)
+	if err != nil {
 		return nil, err
 	}
 
-	// Parse the command string into individual arguments.
-	// コマンド文字列を個々の引数に解析します。
-	cmdParts := parseCommand(command)
-	if len(cmdParts) == 0 {
-		return nil, fmt.Errorf("empty command")
-	}
-
 	// Delegate to execInternal for actual Docker execution.
 	// 実際のDocker実行をexecInternalに委譲します。
-	return c.execInternal(ctx, containerName, cmdParts)
+	return c.execInternal(ctx, containerName, cmdParts, opts, c.GetPolicy().ExecTimeout(containerName, command))
 }
 
-// parseCommand parses a command string into individual argument parts.
-// It splits the command by whitespace (spaces, tabs, newlines).
-//
-// Design Decision: This implementation intentionally uses simple whitespace splitting.
-// Complex commands with quotes (e.g., `echo "hello world"`) are NOT supported.
-// This is by design - whitelisted commands should be simple, single-purpose commands
-// for security reasons. If you need complex shell parsing, the command is likely
-// too complex to be safely whitelisted.
-//
-// parseCommandはコマンド文字列を個々の引数部分に解析します。
-// コマンドを空白（スペース、タブ、改行）で分割します。
-//
-// 設計上の決定: この実装は意図的に単純な空白分割を使用しています。
-// 引用符を含む複雑なコマンド（例: `echo "hello world"`）はサポートしません。
-// これは意図的な設計です - ホワイトリストコマンドはセキュリティ上の理由から
-// シンプルで単一目的のコマンドであるべきです。複雑なシェル解析が必要な場合、
-// そのコマンドはホワイトリストに登録するには複雑すぎる可能性があります。
-func parseCommand(command string) []string {
-	// Split by whitespace - handles spaces, tabs, and multiple spaces.
-	// 空白で分割 - スペース、タブ、複数のスペースを処理します。
-	parts := strings.Fields(command)
-	return parts
+// StartExec authorizes a command like Exec and starts it without waiting for it to end,
+// for commands that outlive a tool call. The output is written to w as it arrives, up to
+// the container's exec_output limit, and the command is limited by the exec job timeout
+// instead of exec_timeout. wait blocks until the command ends and must be called;
+// cancelling ctx kills the command.
+//
+// StartExecはExecと同様にコマンドを認可し、終了を待たずに開始します。ツール呼び出しより
+// 長く実行されるコマンド用です。出力は到着次第コンテナのexec_outputの上限までwに書き込まれ、
+// コマンドはexec_timeoutではなくexecジョブのタイムアウトで制限されます。waitはコマンドが
+// 終了するまでブロックし、必ず呼び出す必要があります。ctxをキャンセルするとコマンドを終了します。
+func (c *Client) StartExec(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions, w io.Writer) (wait func() (*ExecResult, error), err error) {
+	containerName, cmdParts, opts, err := c.authorizeExec(ctx, containerName, command, dangerously, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.startExec(ctx, containerName, cmdParts, opts, c.GetPolicy().ExecJobTimeout(containerName, command), w)
+}
+
+// authorizeExec resolves the container reference and checks that the command may run
+// there with the requested options, returning the container name, the argv to run and
+// the options to run it with.
+//
+// authorizeExecはコンテナ参照を解決し、コマンドを要求されたオプションでそこで実行できるか
+// チェックして、コンテナ名、実行するargv、実行時のオプションを返します。
+func (c *Client) authorizeExec(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions) (string, []string, security.ExecOptions, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	// Check if the command is allowed for this container and get the argv to run.
+	// Dangerous mode allows commands from the exec_dangerously list with path blocking.
+	//
+	// このコンテナに対してコマンドが許可されているかチェックし、実行するargvを取得します。
+	// 危険モードではパスブロック付きでexec_dangerouslyリストのコマンドを許可します。
+	cmdParts, err := c.GetPolicy().AuthorizeExec(containerName, command, dangerously)
+	if err != nil {
+		return "", nil, security.ExecOptions{}, err
+	}
+
+	// The working directory, environment and user must be allowed by exec_options.
+	// 作業ディレクトリ、環境、ユーザーはexec_optionsで許可されている必要があります。
+	opts, err = c.GetPolicy().CheckExecOptions(containerName, opts)
+	if err != nil {
+		return "", nil, security.ExecOptions{}, err
+	}
+
+	// In dangerous mode the file arguments are also checked after resolving symlinks and
+	// mounts, so `cat /app/link-to-env` cannot read a blocked file through a link.
+	// Relative paths are resolved from the requested workdir the command will run in.
+	//
+	// 危険モードではファイル引数をシンボリックリンクとマウントの解決後にもチェックし、
+	// `cat /app/link-to-env`がリンク経由でブロックされたファイルを読めないようにします。
+	// 相対パスはコマンドが実行される要求された作業ディレクトリから解決します。
+	if dangerously {
+		if err := c.checkResolvedCommandPaths(ctx, containerName, opts.Workdir, cmdParts); err != nil {
+			return "", nil, security.ExecOptions{}, err
+		}
+	}
+	return containerName, cmdParts, opts, nil
 }
 
 // InspectContainer retrieves detailed information about a specific container.
@@ -476,16 +794,18 @@ func parseCommand(command string) []string {
 // このメソッドはセキュリティポリシーに従って"inspect"権限と
 // 指定されたコンテナへのアクセスの両方が必要です。
 func (c *Client) InspectContainer(ctx context.Context, containerName string) (*types.ContainerJSON, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
 	// Verify inspect permission is granted by policy.
 	// ポリシーによって検査権限が付与されているか確認します。
-	if !c.policy.CanInspect() {
+	if !c.GetPolicy().CanInspect(containerName) {
 		return nil, fmt.Errorf("inspect permission denied")
 	}
 
 	// Verify the specific container is accessible.
 	// 特定のコンテナがアクセス可能か確認します。
-	if !c.policy.CanAccessContainer(containerName) {
-		return nil, fmt.Errorf("access denied to container: %s", containerName)
+	if err := c.GetPolicy().CheckContainerAccess(containerName); err != nil {
+		return nil, err
 	}
 
 	// Retrieve detailed container information from Docker API.
@@ -504,7 +824,9 @@ func (c *Client) InspectContainer(ctx context.Context, containerName string) (*t
 // RestartContainerはDocker APIを直接使用してコンテナを再起動します。
 // 実行前にlifecycleパーミッションをチェックします。
 func (c *Client) RestartContainer(ctx context.Context, containerName string, timeout *int) error {
-	if _, err := c.policy.CanLifecycle(containerName); err != nil {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	if _, err := c.GetPolicy().CanLifecycle(containerName); err != nil {
 		return err
 	}
 	return c.docker.ContainerRestart(ctx, containerName, container.StopOptions{Timeout: timeout})
@@ -516,7 +838,9 @@ func (c *Client) RestartContainer(ctx context.Context, containerName string, tim
 // StopContainerはDocker APIを直接使用して実行中のコンテナを停止します。
 // 実行前にlifecycleパーミッションをチェックします。
 func (c *Client) StopContainer(ctx context.Context, containerName string, timeout *int) error {
-	if _, err := c.policy.CanLifecycle(containerName); err != nil {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	if _, err := c.GetPolicy().CanLifecycle(containerName); err != nil {
 		return err
 	}
 	return c.docker.ContainerStop(ctx, containerName, container.StopOptions{Timeout: timeout})
@@ -528,7 +852,9 @@ func (c *Client) StopContainer(ctx context.Context, containerName string, timeou
 // StartContainerはDocker APIを直接使用して停止中のコンテナを起動します。
 // 実行前にlifecycleパーミッションをチェックします。
 func (c *Client) StartContainer(ctx context.Context, containerName string) error {
-	if _, err := c.policy.CanLifecycle(containerName); err != nil {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	if _, err := c.GetPolicy().CanLifecycle(containerName); err != nil {
 		return err
 	}
 	return c.docker.ContainerStart(ctx, containerName, container.StartOptions{})
@@ -546,7 +872,7 @@ func (c *Client) StartContainer(ctx context.Context, containerName string) error
 // これはAIアシスタントが特定のコンテナで実行できるコマンドを
 // 発見するのに役立ちます。
 func (c *Client) GetAllowedCommands(containerName string) []string {
-	return c.policy.GetAllowedCommands(containerName)
+	return c.GetPolicy().GetAllowedCommands(containerName)
 }
 
 // GetSecurityPolicy returns the current security policy configuration
@@ -561,7 +887,7 @@ func (c *Client) GetAllowedCommands(containerName string) []string {
 // 返されるマップにはモード、権限、許可コンテナ、
 // その他のポリシー詳細が含まれます。
 func (c *Client) GetSecurityPolicy() map[string]any {
-	return c.policy.GetSecurityPolicy()
+	return c.GetPolicy().GetSecurityPolicy()
 }
 
 // GetAllContainersWithCommands returns a map of all containers and
@@ -576,7 +902,7 @@ func (c *Client) GetSecurityPolicy() map[string]any {
 // マップのキーはコンテナ名（または"securenote-*"のようなパターン）、
 // 値は許可されたコマンド文字列のスライスです。
 func (c *Client) GetAllContainersWithCommands() map[string][]string {
-	return c.policy.GetAllContainersWithCommands()
+	return c.GetPolicy().GetAllContainersWithCommands()
 }
 
 // IsDangerousModeEnabled returns whether dangerous mode is globally enabled.
@@ -589,7 +915,7 @@ func (c *Client) GetAllContainersWithCommands() map[string][]string {
 // 危険モードが有効な場合、dangerously=trueパラメータを使用して
 // exec_dangerouslyリストのコマンドを実行できます。
 func (c *Client) IsDangerousModeEnabled() bool {
-	return c.policy.IsDangerousModeEnabled()
+	return c.GetPolicy().IsDangerousModeEnabled()
 }
 
 // GetDangerousCommandsForContainer returns the dangerous commands allowed for a container.
@@ -604,7 +930,7 @@ func (c *Client) IsDangerousModeEnabled() bool {
 // これはdangerously=trueで特定のコンテナで実行できるコマンドを
 // 発見するのに役立ちます。
 func (c *Client) GetDangerousCommandsForContainer(containerName string) []string {
-	return c.policy.GetDangerousCommandsForContainer(containerName)
+	return c.GetPolicy().GetDangerousCommandsForContainer(containerName)
 }
 
 // GetAllDangerousCommands returns a map of all containers and their dangerous commands.
@@ -615,178 +941,60 @@ func (c *Client) GetDangerousCommandsForContainer(containerName string) []string
 // コンテナ名から危険コマンドリストへのマップを返します。
 // グローバルコマンドの特別な"*"エントリを含みます。
 func (c *Client) GetAllDangerousCommands() map[string][]string {
-	return c.policy.GetAllDangerousCommands()
-}
-
-// FileAccessResult represents the result of a file access operation
-// (listing or reading files) in a container.
-//
-// It indicates whether the operation succeeded, was blocked by policy,
-// or failed with an error.
-//
-// FileAccessResultはコンテナ内でのファイルアクセス操作
-// （ファイルの一覧表示または読み取り）の結果を表します。
-//
-// 操作が成功したか、ポリシーによってブロックされたか、
-// エラーで失敗したかを示します。
-type FileAccessResult struct {
-	// Success indicates whether the file operation completed successfully.
-	// Successはファイル操作が正常に完了したかどうかを示します。
-	Success bool `json:"success"`
-
-	// Data contains the file content or directory listing if successful.
-	// Dataは成功した場合のファイル内容またはディレクトリ一覧を含みます。
-	Data string `json:"data,omitempty"`
-
-	// Blocked indicates if the path was blocked by security policy.
-	// Blockedはパスがセキュリティポリシーによってブロックされたかを示します。
-	Blocked bool `json:"blocked,omitempty"`
-
-	// Block contains details about why the path was blocked.
-	// Blockはパスがブロックされた理由の詳細を含みます。
-	Block *security.BlockedPath `json:"block_info,omitempty"`
-
-	// Error contains the error message if the operation failed.
-	// Errorは操作が失敗した場合のエラーメッセージを含みます。
-	Error string `json:"error,omitempty"`
-}
-
-// ListFiles lists files and directories in a container at the specified path.
-// The path is checked against the security policy's blocked paths before access.
-//
-// If the path is blocked, the result will indicate the block with details
-// about why access was denied (e.g., security-sensitive directory).
-//
-// This method internally executes "ls -la" in the container.
-//
-// ListFilesはコンテナ内の指定されたパスにあるファイルとディレクトリを一覧表示します。
-// アクセス前にパスはセキュリティポリシーのブロックパスに対してチェックされます。
-//
-// パスがブロックされている場合、結果はアクセスが拒否された理由
-// （例：セキュリティ上重要なディレクトリ）の詳細とともにブロックを示します。
-//
-// このメソッドは内部的にコンテナ内で"ls -la"を実行します。
-func (c *Client) ListFiles(ctx context.Context, containerName string, path string) (*FileAccessResult, error) {
-	// Verify the container is accessible according to policy.
-	// ポリシーに従ってコンテナがアクセス可能か確認します。
-	if !c.policy.CanAccessContainer(containerName) {
-		return nil, fmt.Errorf("access denied to container: %s", containerName)
-	}
-
-	// Check if the requested path is blocked by security policy.
-	// This prevents access to sensitive directories like /etc/secrets.
-	// 要求されたパスがセキュリティポリシーによってブロックされているかチェックします。
-	// これは/etc/secretsのような機密ディレクトリへのアクセスを防ぎます。
-	if blocked := c.policy.IsPathBlocked(containerName, path); blocked != nil {
-		return &FileAccessResult{
-			Success: false,
-			Blocked: true,
-			Block:   blocked,
-		}, nil
-	}
-
-	// Execute ls command internally (bypasses whitelist check).
-	// lsコマンドを内部的に実行します（ホワイトリストチェックをバイパス）。
-	result, err := c.execInternal(ctx, containerName, []string{"ls", "-la", path})
-	if err != nil {
-		return &FileAccessResult{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
-	}
-
-	return &FileAccessResult{
-		Success: true,
-		Data:    result.Output,
-	}, nil
-}
-
-// ReadFile reads the contents of a file from a container.
-// The path is checked against the security policy's blocked paths before access.
-//
-// Parameters:
-//   - containerName: The name or ID of the container
-//   - path: The absolute path to the file in the container
-//   - maxLines: Maximum number of lines to read (0 = all lines)
-//
-// If maxLines > 0, uses "head -n" to limit output; otherwise uses "cat".
-//
-// ReadFileはコンテナからファイルの内容を読み取ります。
-// アクセス前にパスはセキュリティポリシーのブロックパスに対してチェックされます。
-//
-// パラメータ:
-//   - containerName: コンテナの名前またはID
-//   - path: コンテナ内のファイルへの絶対パス
-//   - maxLines: 読み取る最大行数（0 = すべての行）
-//
-// maxLines > 0の場合は"head -n"を使用して出力を制限し、そうでなければ"cat"を使用します。
-func (c *Client) ReadFile(ctx context.Context, containerName string, path string, maxLines int) (*FileAccessResult, error) {
-	// Verify the container is accessible according to policy.
-	// ポリシーに従ってコンテナがアクセス可能か確認します。
-	if !c.policy.CanAccessContainer(containerName) {
-		return nil, fmt.Errorf("access denied to container: %s", containerName)
-	}
-
-	// Check if the requested path is blocked by security policy.
-	// 要求されたパスがセキュリティポリシーによってブロックされているかチェックします。
-	if blocked := c.policy.IsPathBlocked(containerName, path); blocked != nil {
-		return &FileAccessResult{
-			Success: false,
-			Blocked: true,
-			Block:   blocked,
-		}, nil
-	}
-
-	// Build the appropriate command based on maxLines parameter.
-	// maxLinesパラメータに基づいて適切なコマンドを構築します。
-	var cmd []string
-	if maxLines > 0 {
-		// Use head to limit output to specified number of lines.
-		// headを使用して出力を指定行数に制限します。
-		cmd = []string{"head", "-n", fmt.Sprintf("%d", maxLines), path}
-	} else {
-		// Read the entire file with cat.
-		// catでファイル全体を読み取ります。
-		cmd = []string{"cat", path}
-	}
-
-	// Execute the read command internally.
-	// 読み取りコマンドを内部的に実行します。
-	result, err := c.execInternal(ctx, containerName, cmd)
-	if err != nil {
-		return &FileAccessResult{
-			Success: false,
-			Error:   err.Error(),
-		}, nil
-	}
-
-	return &FileAccessResult{
-		Success: true,
-		Data:    result.Output,
-	}, nil
+	return c.GetPolicy().GetAllDangerousCommands()
 }
 
 // execInternal executes a command in a container without checking
-// the command whitelist. This is used internally for file operations
-// (ls, cat, head) that are controlled by path blocking instead.
+// the command whitelist. It runs the argv approved by the security policy
+// for Exec.
 //
 // IMPORTANT: This method should only be used for trusted internal
 // commands. User-provided commands must go through the Exec method
 // which validates against the whitelist.
 //
 // execInternalはコマンドホワイトリストをチェックせずにコンテナ内で
-// コマンドを実行します。これは代わりにパスブロッキングで制御される
-// ファイル操作（ls、cat、head）に内部的に使用されます。
+// コマンドを実行します。Execのためにセキュリティポリシーが承認した
+// argvを実行します。
 //
 // 重要: このメソッドは信頼できる内部コマンドにのみ使用すべきです。
 // ユーザー提供のコマンドはホワイトリストに対して検証する
 // Execメソッドを経由する必要があります。
-func (c *Client) execInternal(ctx context.Context, containerName string, cmd []string) (*ExecResult, error) {
-	// Configure exec with stdout/stderr capture.
-	// 標準出力/標準エラー出力キャプチャでexecを設定します。
+//
+// When timeout passes, or ctx is cancelled (e.g. by notifications/cancelled), the
+// attach stream is closed and the processes the command started are killed. A timeout
+// returns the output so far with TimedOut set; a cancellation returns ctx's error.
+//
+// timeoutが経過するか、ctxがキャンセルされると（notifications/cancelledなど）、
+// アタッチストリームを閉じ、コマンドが起動したプロセスを終了します。タイムアウトは
+// それまでの出力をTimedOutを設定して返し、キャンセルはctxのエラーを返します。
+func (c *Client) execInternal(ctx context.Context, containerName string, cmd []string, opts security.ExecOptions, timeout time.Duration) (*ExecResult, error) {
+	wait, err := c.startExec(ctx, containerName, cmd, opts, timeout, nil)
+	if err != nil {
+		return nil, err
+	}
+	return wait()
+}
+
+// startExec creates and attaches the exec of execInternal and returns a function that
+// reads its output, copying it to w when w is not nil, and waits for it to end.
+//
+// startExecはexecInternalのexecを作成してアタッチし、その出力を読み取り（wがnilでなければ
+// wにもコピーし）、終了を待つ関数を返します。
+func (c *Client) startExec(ctx context.Context, containerName string, cmd []string, opts security.ExecOptions, timeout time.Duration, w io.Writer) (func() (*ExecResult, error), error) {
+	start := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+
+	// Configure exec with stdout/stderr capture and the requested options, and tag its
+	// processes for cleanup. The marker comes last so a requested variable cannot replace it.
+	// 標準出力/標準エラー出力キャプチャと要求されたオプションでexecを設定し、クリーンアップ用に
+	// プロセスに目印を付けます。要求された変数で置き換えられないよう目印は最後に置きます。
+	marker := newExecMarker()
 	execConfig := container.ExecOptions{
 		AttachStdout: true,
 		AttachStderr: true,
+		User:         opts.User,
+		WorkingDir:   opts.Workdir,
+		Env:          append(opts.EnvList(), marker),
 		Cmd:          cmd,
 	}
 
@@ -794,34 +1002,76 @@ func (c *Client) execInternal(ctx context.Context, containerName string, cmd []s
 	// コンテナ内にexecインスタンスを作成します。
 	execID, err := c.docker.ContainerExecCreate(ctx, containerName, execConfig)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to create exec: %w", err)
 	}
 
 	// Attach to the exec instance to capture output.
 	// 出力をキャプチャするためにexecインスタンスにアタッチします。
-	resp, err := c.docker.ContainerExecAttach(ctx, execID.ID, container.ExecStartOptions{})
+	resp, err := c.docker.ContainerExecAttach(runCtx, execID.ID, container.ExecStartOptions{})
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to attach exec: %w", err)
 	}
-	defer resp.Close()
 
-	// Read all output from the command.
-	// コマンドからすべての出力を読み取ります。
-	output := new(strings.Builder)
-	if _, err := io.Copy(output, resp.Reader); err != nil {
-		return nil, fmt.Errorf("failed to read exec output: %w", err)
-	}
+	// Closing the connection is what unblocks the read below when runCtx ends.
+	// runCtxが終了した時に下の読み取りのブロックを解くのは接続のクローズです。
+	stop := context.AfterFunc(runCtx, resp.Close)
+
+	return func() (*ExecResult, error) {
+		defer cancel()
+		defer resp.Close()
+		defer stop()
+
+		// Read all output from the command, split into stdout and stderr and kept up to
+		// the container's exec_output limit.
+		// コマンドからすべての出力を読み取ります。標準出力と標準エラー出力に分け、
+		// コンテナのexec_outputの上限まで保持します。
+		output := newExecOutput(c.GetPolicy().MaxExecOutputBytes(containerName))
+		output.tee = w
+		readErr := output.readFrom(resp.Reader)
+		duration := time.Since(start)
+
+		if runCtx.Err() != nil {
+			if err := c.killExec(containerName, marker); err != nil {
+				slog.Warn("Failed to kill the processes of a stopped exec",
+					"container", containerName,
+					"command", cmd[0],
+					"error", err,
+				)
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return &ExecResult{
+				ExitCode:   -1,
+				Output:     output.combined.String(),
+				Stdout:     output.stdout.String(),
+				Stderr:     output.stderr.String(),
+				DurationMs: duration.Milliseconds(),
+				Truncated:  output.truncated,
+				TimedOut:   true,
+			}, nil
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read exec output: %w", readErr)
+		}
 
-	// Get the exit code from the completed exec.
-	// 完了したexecから終了コードを取得します。
-	inspect, err := c.docker.ContainerExecInspect(ctx, execID.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to inspect exec: %w", err)
-	}
+		// Get the exit code from the completed exec.
+		// 完了したexecから終了コードを取得します。
+		inspect, err := c.docker.ContainerExecInspect(ctx, execID.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect exec: %w", err)
+		}
 
-	return &ExecResult{
-		ExitCode: inspect.ExitCode,
-		Output:   output.String(),
+		return &ExecResult{
+			ExitCode:   inspect.ExitCode,
+			Output:     output.combined.String(),
+			Stdout:     output.stdout.String(),
+			Stderr:     output.stderr.String(),
+			DurationMs: duration.Milliseconds(),
+			Truncated:  output.truncated,
+		}, nil
 	}, nil
 }
 
@@ -832,7 +1082,7 @@ func (c *Client) execInternal(ctx context.Context, containerName string, cmd []s
 // ブロックされたファイルパスを返します。
 // これらはListFilesまたはReadFileを通じてアクセスできないパスです。
 func (c *Client) GetBlockedPaths() []security.BlockedPath {
-	return c.policy.GetBlockedPaths()
+	return c.GetPolicy().GetBlockedPaths()
 }
 
 // GetBlockedPathsForContainer returns blocked paths for a specific container.
@@ -841,7 +1091,7 @@ func (c *Client) GetBlockedPaths() []security.BlockedPath {
 // GetBlockedPathsForContainerは特定のコンテナのブロックパスを返します。
 // これにはコンテナ固有とグローバルの両方のブロックパスが含まれます。
 func (c *Client) GetBlockedPathsForContainer(containerName string) []security.BlockedPath {
-	return c.policy.GetBlockedPathsForContainer(containerName)
+	return c.GetPolicy().GetBlockedPathsForContainer(containerName)
 }
 
 // InitBlockedPaths initializes the blocked paths manager with a list of containers.
@@ -850,5 +1100,42 @@ func (c *Client) GetBlockedPathsForContainer(containerName string) []security.Bl
 // InitBlockedPathsはコンテナのリストでブロックパスマネージャを初期化します。
 // パスブロッキングを設定するために起動時に呼び出す必要があります。
 func (c *Client) InitBlockedPaths(containers []string) error {
-	return c.policy.InitBlockedPaths(containers)
+	return c.GetPolicy().InitBlockedPaths(containers)
+}
+
+// ReloadPolicy replaces the security policy while the server is running, e.g. after
+// dkmcp.yaml changed. The new policy learns the containers on the host and loads its
+// blocked paths before it is swapped in, so no request is ever checked against a
+// partially initialized policy. On error the current policy stays in effect.
+//
+// ReloadPolicyはサーバーの実行中にセキュリティポリシーを置き換えます（dkmcp.yamlの
+// 変更後など）。新しいポリシーは置き換えの前にホスト上のコンテナを把握し、ブロックパスを
+// 読み込むため、初期化途中のポリシーでリクエストが検査されることはありません。
+// エラーの場合は現在のポリシーが有効なままです。
+func (c *Client) ReloadPolicy(ctx context.Context, policy *security.Policy) error {
+	if policy == nil {
+		return fmt.Errorf("security policy cannot be nil")
+	}
+
+	containers, err := c.docker.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+	ids := containerIdentities(containers)
+	policy.SetContainerIdentities(ids)
+
+	// Blocked paths are loaded for the containers the new policy can access, as at startup
+	// 起動時と同様に、新しいポリシーでアクセス可能なコンテナのブロックパスを読み込む
+	var names []string
+	for _, id := range ids {
+		if policy.CanAccessContainer(id.Name) {
+			names = append(names, id.Name)
+		}
+	}
+	if err := policy.InitBlockedPaths(names); err != nil {
+		return fmt.Errorf("failed to initialize blocked paths: %w", err)
+	}
+
+	c.policy.Store(policy)
+	return nil
 }
@@ -3,7 +3,7 @@
 // used by the Docker client.
 //
 // The test suite includes:
-// - Unit tests for helper functions (parseCommand, formatBytes, etc.)
+// - Unit tests for helper functions (formatBytes, etc.)
 // - Data structure field validation tests
 // - Integration tests (when Docker daemon is available)
 //
@@ -12,7 +12,7 @@
 // ヘルパー関数を検証します。
 //
 // テストスイートには以下が含まれます:
-// - ヘルパー関数のユニットテスト（parseCommand、formatBytesなど）
+// - ヘルパー関数のユニットテスト（formatBytesなど）
 // - データ構造フィールド検証テスト
 // - 統合テスト（Dockerデーモンが利用可能な場合）
 //
@@ -465,103 +465,6 @@ func parseCPUPercent(cpuDelta, sysDelta uint64, numCPUs uint32) float64 {
 	return cpuPercent
 }
 
-// TestParseCommand tests the parseCommand function which splits a command
-// string into individual argument parts for execution via Docker exec.
-//
-// This is important for the Exec functionality which requires commands
-// to be passed as a string slice to the Docker API.
-//
-// TestParseCommandはparseCommand関数をテストします。
-// これはDocker exec経由での実行のためにコマンド文字列を
-// 個々の引数部分に分割します。
-//
-// これはDockerAPIにコマンドを文字列スライスとして渡す必要がある
-// Exec機能にとって重要です。
-func TestParseCommand(t *testing.T) {
-	// Define test cases covering various command formats.
-	// 様々なコマンド形式をカバーするテストケースを定義します。
-	tests := []struct {
-		name    string   // Test case name / テストケース名
-		command string   // Input command string / 入力コマンド文字列
-		want    []string // Expected parsed parts / 期待される解析結果
-	}{
-		{
-			// Simple single-word command
-			// 単純な単一単語コマンド
-			name:    "simple command",
-			command: "ls",
-			want:    []string{"ls"},
-		},
-		{
-			// Command with a single argument
-			// 単一引数を持つコマンド
-			name:    "command with args",
-			command: "npm test",
-			want:    []string{"npm", "test"},
-		},
-		{
-			// Command with multiple arguments including flags
-			// フラグを含む複数の引数を持つコマンド
-			name:    "command with multiple args",
-			command: "npm run build --production",
-			want:    []string{"npm", "run", "build", "--production"},
-		},
-		{
-			// Command with extra whitespace (should be trimmed)
-			// 余分な空白を持つコマンド（トリミングされるべき）
-			name:    "command with extra spaces",
-			command: "  npm   test  ",
-			want:    []string{"npm", "test"},
-		},
-		{
-			// Empty command string
-			// 空のコマンド文字列
-			name:    "empty command",
-			command: "",
-			want:    []string{},
-		},
-		{
-			// Whitespace-only command (should result in empty slice)
-			// 空白のみのコマンド（空のスライスになるべき）
-			name:    "whitespace only",
-			command: "   ",
-			want:    []string{},
-		},
-		{
-			// Command with tabs as separators
-			// タブを区切りとして持つコマンド
-			name:    "command with tabs",
-			command: "npm\ttest",
-			want:    []string{"npm", "test"},
-		},
-	}
-
-	// Run each test case.
-	// 各テストケースを実行します。
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Call the function under test.
-			// テスト対象の関数を呼び出します。
-			got := parseCommand(tt.command)
-
-			// Verify the number of parts matches.
-			// 部分の数が一致することを検証します。
-			if len(got) != len(tt.want) {
-				t.Errorf("parseCommand(%q) returned %d parts, want %d", tt.command, len(got), len(tt.want))
-				return
-			}
-
-			// Verify each part matches the expected value.
-			// 各部分が期待値と一致することを検証します。
-			for i := range got {
-				if got[i] != tt.want[i] {
-					t.Errorf("parseCommand(%q)[%d] = %q, want %q", tt.command, i, got[i], tt.want[i])
-				}
-			}
-		})
-	}
-}
-
 // TestContainerInfo_Fields verifies that the ContainerInfo struct
 // correctly stores and retrieves all field values.
 //
@@ -805,3 +708,24 @@ func TestFormatPorts(t *testing.T) {
 		})
 	}
 }
+
+// TestParseHealth tests extracting the health check state from a container status.
+// TestParseHealthはコンテナのステータスからヘルスチェックの状態を取り出す処理をテストします。
+func TestParseHealth(t *testing.T) {
+	tests := []struct {
+		status   string // Container status / コンテナのステータス
+		expected string // Expected health / 期待されるヘルス
+	}{
+		{"Up 5 minutes (healthy)", "healthy"},
+		{"Up 5 minutes (unhealthy)", "unhealthy"},
+		{"Up 3 seconds (health: starting)", "starting"},
+		{"Up 5 minutes", ""},
+		{"Exited (0) 2 hours ago", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parseHealth(tt.status); got != tt.expected {
+			t.Errorf("parseHealth(%q) = %q, want %q", tt.status, got, tt.expected)
+		}
+	}
+}
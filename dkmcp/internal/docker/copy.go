@@ -0,0 +1,481 @@
+// copy.go implements copy_from_container and copy_to_container, which move files between
+// a container and the host workspace as tar streams through the Docker archive API. The
+// container side follows the same rules as the other file tools: blocked paths are never
+// copied out, and copies into a container are limited to writable_paths. The host side is
+// resolved by the caller with security.ResolveWorkspacePath, and host files matched by
+// blocked paths are neither read nor replaced. Every copied file is hashed so the audit
+// log records exactly what moved.
+//
+// copy.goはcopy_from_containerとcopy_to_containerを実装し、DockerのアーカイブAPIを通じて
+// tarストリームとしてコンテナとホストのワークスペースの間でファイルを移動します。コンテナ側は
+// 他のファイルツールと同じルールに従います：ブロックされたパスは決してコピーされず、
+// コンテナへのコピーはwritable_pathsに制限されます。ホスト側は呼び出し側が
+// security.ResolveWorkspacePathで解決し、ブロックパスに一致するホストのファイルは
+// 読み取りも置き換えもされません。監査ログに移動した内容を正確に記録するため、
+// コピーしたすべてのファイルのハッシュを計算します。
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// CopiedFile describes a file moved by CopyFromContainer or CopyToContainer.
+// CopiedFileはCopyFromContainerまたはCopyToContainerが移動したファイルを表します。
+type CopiedFile struct {
+	// Path is the path of the file in the container
+	// Pathはコンテナ内のファイルのパスです
+	Path string `json:"path"`
+
+	// Size is the size in bytes
+	// Sizeはバイト単位のサイズです
+	Size int64 `json:"size"`
+
+	// SHA256 is the hex-encoded SHA-256 hash of the content
+	// SHA256は内容の16進エンコードされたSHA-256ハッシュです
+	SHA256 string `json:"sha256"`
+}
+
+// CopyFromContainer copies a file or directory from a container to hostPath. Blocked
+// entries inside a directory are skipped, symlinks and special files are not copied,
+// and the copy is aborted if the files exceed the file_copy limit. The files are staged
+// next to hostPath and moved into place only when the whole copy succeeded.
+//
+// Parameters:
+//   - containerName: Name or ID of the container
+//   - src: Path of the file or directory in the container
+//   - hostPath: Absolute host path inside the workspace, from security.ResolveWorkspacePath
+//   - overwrite: Replace hostPath if it is an existing file
+//
+// CopyFromContainerはコンテナからファイルまたはディレクトリをhostPathにコピーします。
+// ディレクトリ内のブロックされたエントリはスキップされ、シンボリックリンクと特殊ファイルは
+// コピーされず、ファイルがfile_copyの上限を超えるとコピーは中止されます。ファイルは
+// hostPathの隣に一時的に置かれ、コピー全体が成功した場合にのみ所定の位置に移動されます。
+//
+// パラメータ:
+//   - containerName: コンテナの名前またはID
+//   - src: コンテナ内のファイルまたはディレクトリのパス
+//   - hostPath: security.ResolveWorkspacePathで得たワークスペース内の絶対ホストパス
+//   - overwrite: hostPathが既存のファイルの場合に置き換える
+func (c *Client) CopyFromContainer(ctx context.Context, containerName, src, hostPath string, overwrite bool) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+	policy := c.GetPolicy()
+
+	if _, err := policy.CanCopy(containerName); err != nil {
+		return nil, err
+	}
+	if blocked := policy.IsHostPathBlocked(containerName, hostPath); blocked != nil {
+		return &FileAccessResult{Success: false, Blocked: true, Block: blocked, MatchedPath: hostPath}, nil
+	}
+
+	target, stat, result, err := c.statAllowedPath(ctx, containerName, src)
+	if result != nil || err != nil {
+		return result, err
+	}
+	if err := checkHostDestination(hostPath, stat.Mode.IsDir(), overwrite); err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	limit := policy.MaxCopyBytes()
+	if !stat.Mode.IsDir() && stat.Size > limit {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("%s is too large to copy: %d bytes (max %d)", target, stat.Size, limit)}, nil
+	}
+
+	info, err := c.docker.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to inspect container: %v", err)}, nil
+	}
+	reader, _, err := c.docker.CopyFromContainer(ctx, containerName, target)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(filepath.Dir(hostPath), 0755); err != nil {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to create %s: %v", filepath.Dir(hostPath), err)}, nil
+	}
+	staging, err := os.MkdirTemp(filepath.Dir(hostPath), ".dkmcp-copy-")
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to create staging directory: %v", err)}, nil
+	}
+	defer os.RemoveAll(staging)
+	staged := filepath.Join(staging, "data")
+
+	extract := &hostExtractor{
+		root:  staged,
+		limit: limit,
+		blocked: func(rel string) bool {
+			return policy.IsHostPathBlocked(containerName, filepath.Join(hostPath, filepath.FromSlash(rel))) != nil
+		},
+	}
+	blocked := func(p string) bool {
+		return isBlockedEntry(policy, containerName, p, info.Mounts)
+	}
+	_, skipped, err := walkArchive(reader, stat.Name, target, 0, math.MaxInt64, blocked, extract.add)
+	if err == nil {
+		err = extract.err
+	}
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+
+	if overwrite && !stat.Mode.IsDir() {
+		if err := os.Remove(hostPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to replace %s: %v", hostPath, err)}, nil
+		}
+	}
+	if err := os.Rename(staged, hostPath); err != nil {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to move the copy into place: %v", err)}, nil
+	}
+
+	entry := fileEntryFromStat(stat)
+	return &FileAccessResult{
+		Success:        true,
+		File:           &entry,
+		Copied:         extract.copied,
+		SkippedBlocked: skipped + extract.skippedBlocked,
+		SkippedSpecial: extract.skippedSpecial,
+		ResolvedPath:   resolvedIfChanged(target, src),
+	}, nil
+}
+
+// checkHostDestination refuses to replace anything at hostPath except an existing file
+// when overwrite is set; directories are never merged.
+//
+// checkHostDestinationはoverwriteが設定されている場合の既存のファイルを除き、hostPathに
+// あるものの置き換えを拒否します。ディレクトリがマージされることはありません。
+func checkHostDestination(hostPath string, dir, overwrite bool) error {
+	info, err := os.Lstat(hostPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !overwrite {
+		return fmt.Errorf("%s already exists (set overwrite to replace a file)", hostPath)
+	}
+	if dir || !info.Mode().IsRegular() {
+		return fmt.Errorf("%s already exists and only a file can be overwritten", hostPath)
+	}
+	return nil
+}
+
+// resolvedIfChanged returns resolved, or "" when it is the requested path itself.
+// resolvedIfChangedはresolvedを返し、要求されたパスそのものの場合は""を返します。
+func resolvedIfChanged(resolved, requested string) string {
+	if resolved == requested {
+		return ""
+	}
+	return resolved
+}
+
+// hostExtractor writes the entries of a container archive below root on the host,
+// hashing every file. It only creates directories and regular files, so nothing it
+// writes can redirect a later entry outside root.
+//
+// hostExtractorはコンテナのアーカイブのエントリをホストのroot配下に書き込み、すべての
+// ファイルのハッシュを計算します。ディレクトリと通常ファイルのみを作成するため、
+// 書き込んだものが後続のエントリをroot外に向けることはありません。
+type hostExtractor struct {
+	root    string                // Host path of the archive root / アーカイブのルートのホストパス
+	limit   int64                 // Cap on the total file size / ファイルサイズの合計の上限
+	blocked func(rel string) bool // Reports blocked host destinations / ブロックされたホストの書き込み先を報告する
+
+	copied         []CopiedFile
+	pruned         []string
+	total          int64
+	skippedBlocked int
+	skippedSpecial int
+	err            error
+}
+
+// add writes one archive entry; it is the callback of walkArchive.
+// addはアーカイブのエントリを1つ書き込みます。walkArchiveのコールバックです。
+func (e *hostExtractor) add(p, rel string, hdr *tar.Header, body io.Reader) bool {
+	if underAny(rel, e.pruned) {
+		return true
+	}
+	dest := filepath.Join(e.root, filepath.FromSlash(rel))
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if rel != "" && e.blocked(rel) {
+			e.skippedBlocked++
+			e.pruned = append(e.pruned, rel)
+			return true
+		}
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			e.err = err
+			return false
+		}
+		return true
+	case tar.TypeReg:
+	default:
+		e.skippedSpecial++
+		return true
+	}
+
+	if e.blocked(rel) {
+		e.skippedBlocked++
+		return true
+	}
+	e.total += hdr.Size
+	if e.total > e.limit {
+		e.err = fmt.Errorf("copy exceeds the size limit of %d bytes", e.limit)
+		return false
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		e.err = err
+		return false
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, hostFileMode(hdr.FileInfo().Mode()))
+	if err != nil {
+		e.err = err
+		return false
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		e.err = fmt.Errorf("failed to write %s: %w", p, err)
+		return false
+	}
+	e.copied = append(e.copied, CopiedFile{Path: p, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+	return true
+}
+
+// hostFileMode returns the mode of a copied host file: readable and writable by the
+// owner, keeping only the execute bits of the original.
+//
+// hostFileModeはコピーしたホストのファイルのモードを返します：所有者が読み書きでき、
+// 元のモードからは実行ビットのみを維持します。
+func hostFileMode(mode fs.FileMode) fs.FileMode {
+	return 0644 | mode.Perm()&0111
+}
+
+// CopyToContainer copies a host file or directory to dest in a container. Every file's
+// container path must be covered by writable_paths and not blocked, and blocked host
+// files, symlinks and special files are skipped. The copy is refused before anything is
+// uploaded if the files exceed the file_copy limit.
+//
+// Parameters:
+//   - containerName: Name or ID of the container
+//   - hostPath: Absolute host path inside the workspace, from security.ResolveWorkspacePath
+//   - dest: Path in the container to create; its real path must be covered by writable_paths
+//   - overwrite: Replace dest if it already exists
+//
+// CopyToContainerはホストのファイルまたはディレクトリをコンテナ内のdestにコピーします。
+// すべてのファイルのコンテナ内パスはwritable_pathsでカバーされ、ブロックされていない
+// 必要があり、ブロックされたホストのファイル、シンボリックリンク、特殊ファイルは
+// スキップされます。ファイルがfile_copyの上限を超える場合、何もアップロードする前に
+// コピーは拒否されます。
+//
+// パラメータ:
+//   - containerName: コンテナの名前またはID
+//   - hostPath: security.ResolveWorkspacePathで得たワークスペース内の絶対ホストパス
+//   - dest: 作成するコンテナ内のパス。実パスはwritable_pathsでカバーされている必要があります
+//   - overwrite: destが既に存在する場合に置き換える
+func (c *Client) CopyToContainer(ctx context.Context, containerName, hostPath, dest string, overwrite bool) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+	policy := c.GetPolicy()
+
+	if _, err := policy.CanCopy(containerName); err != nil {
+		return nil, err
+	}
+	if blocked := policy.IsHostPathBlocked(containerName, hostPath); blocked != nil {
+		return &FileAccessResult{Success: false, Blocked: true, Block: blocked, MatchedPath: hostPath}, nil
+	}
+
+	target, denied, err := c.writableTarget(ctx, containerName, dest)
+	if denied != nil || err != nil {
+		return denied, err
+	}
+	if _, err := c.docker.ContainerStatPath(ctx, containerName, target); err == nil && !overwrite {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("%s already exists (set overwrite to replace it)", target)}, nil
+	} else if err != nil && !client.IsErrNotFound(err) {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+
+	plan, err := c.planUpload(containerName, hostPath, target)
+	if err != nil {
+		return nil, err
+	}
+	if plan.total > policy.MaxCopyBytes() {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("copy is too large: %d bytes (max %d)", plan.total, policy.MaxCopyBytes())}, nil
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan []CopiedFile, 1)
+	go func() {
+		copied, err := writeUploadArchive(pw, plan)
+		pw.CloseWithError(err)
+		done <- copied
+	}()
+	err = c.docker.CopyToContainer(ctx, containerName, path.Dir(target), pr, container.CopyToContainerOptions{})
+	pr.CloseWithError(io.ErrClosedPipe)
+	copied := <-done
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to copy to %s: %v", target, err)}, nil
+	}
+
+	return &FileAccessResult{
+		Success:        true,
+		Copied:         copied,
+		SkippedBlocked: plan.skippedBlocked,
+		SkippedSpecial: plan.skippedSpecial,
+		ResolvedPath:   resolvedIfChanged(target, dest),
+	}, nil
+}
+
+// uploadEntry is a host directory or file to be uploaded.
+// uploadEntryはアップロードするホストのディレクトリまたはファイルです。
+type uploadEntry struct {
+	hostPath string      // Path on the host / ホスト上のパス
+	name     string      // Name in the archive / アーカイブ内の名前
+	target   string      // Path in the container / コンテナ内のパス
+	mode     fs.FileMode // Host mode / ホストのモード
+	size     int64       // Size of a file / ファイルのサイズ
+	modTime  time.Time   // Modification time / 更新日時
+}
+
+// uploadPlan lists what CopyToContainer uploads.
+// uploadPlanはCopyToContainerがアップロードするものを列挙します。
+type uploadPlan struct {
+	entries        []uploadEntry
+	total          int64
+	skippedBlocked int
+	skippedSpecial int
+}
+
+// planUpload walks hostPath and checks every entry against the policy before anything is
+// uploaded. A file outside writable_paths fails the whole copy, while blocked entries are
+// skipped like in the other tools.
+//
+// planUploadはhostPathを走査し、何かをアップロードする前にすべてのエントリをポリシーに
+// 対してチェックします。writable_paths外のファイルはコピー全体を失敗させ、ブロックされた
+// エントリは他のツールと同様にスキップされます。
+func (c *Client) planUpload(containerName, hostPath, target string) (*uploadPlan, error) {
+	policy := c.GetPolicy()
+	plan := &uploadPlan{}
+	base := path.Base(target)
+
+	err := filepath.WalkDir(hostPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(hostPath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			plan.skippedSpecial++
+			return nil
+		}
+
+		containerPath := path.Join(target, rel)
+		if rel != "" && (policy.IsHostPathBlocked(containerName, p) != nil || policy.IsPathBlocked(containerName, containerPath) != nil) {
+			plan.skippedBlocked++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if _, ok := policy.IsPathWritable(containerName, containerPath); !ok {
+				return fmt.Errorf("path is not writable: %s is not covered by writable_paths for container %s", containerPath, containerName)
+			}
+			plan.total += info.Size()
+		}
+		plan.entries = append(plan.entries, uploadEntry{
+			hostPath: p,
+			name:     path.Join(base, rel),
+			target:   containerPath,
+			mode:     info.Mode(),
+			size:     info.Size(),
+			modTime:  info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// writeUploadArchive streams the entries of plan as a tar archive to w and returns the
+// files it wrote with their hashes.
+//
+// writeUploadArchiveはplanのエントリをtarアーカイブとしてwにストリーミングし、
+// 書き込んだファイルをハッシュとともに返します。
+func writeUploadArchive(w io.Writer, plan *uploadPlan) ([]CopiedFile, error) {
+	tw := tar.NewWriter(w)
+	var copied []CopiedFile
+	for _, entry := range plan.entries {
+		hdr := &tar.Header{
+			Name:    entry.name,
+			Mode:    int64(entry.mode.Perm()),
+			ModTime: entry.modTime,
+		}
+		if entry.mode.IsDir() {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			if err := tw.WriteHeader(hdr); err != nil {
+				return copied, err
+			}
+			continue
+		}
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = entry.size
+		if err := tw.WriteHeader(hdr); err != nil {
+			return copied, err
+		}
+		sum, err := copyHashed(tw, entry.hostPath, entry.size)
+		if err != nil {
+			return copied, fmt.Errorf("failed to read %s: %w", entry.hostPath, err)
+		}
+		copied = append(copied, CopiedFile{Path: entry.target, Size: entry.size, SHA256: sum})
+	}
+	return copied, tw.Close()
+}
+
+// copyHashed writes exactly size bytes of the host file p to w and returns their SHA-256.
+// copyHashedはホストのファイルpのちょうどsizeバイトをwに書き込み、そのSHA-256を返します。
+func copyHashed(w io.Writer, p string, size int64) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(w, h), f, size); err != nil {
+		return "", fmt.Errorf("file changed while copying: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
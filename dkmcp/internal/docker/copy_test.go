@@ -0,0 +1,214 @@
+// copy_test.go contains tests for copying files between containers and the host workspace.
+// copy_test.goはコンテナとホストのワークスペース間のファイルのコピーのテストを含みます。
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// sha256Hex returns the hex-encoded SHA-256 of s.
+// sha256HexはsのSHA-256を16進エンコードで返します。
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// reportArchive returns the archive of a container directory "reports" holding a file,
+// a directory the test treats as blocked on the host, and a symlink.
+//
+// reportArchiveはファイル、テストがホスト上でブロックされたものとして扱うディレクトリ、
+// シンボリックリンクを含むコンテナのディレクトリ"reports"のアーカイブを返します。
+func reportArchive(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	add := func(hdr *tar.Header, content string) {
+		hdr.Size = int64(len(content))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, content)
+	}
+	add(&tar.Header{Name: "reports/", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	add(&tar.Header{Name: "reports/junit.xml", Typeflag: tar.TypeReg, Mode: 0644}, "<testsuite/>\n")
+	add(&tar.Header{Name: "reports/run.sh", Typeflag: tar.TypeReg, Mode: 0755}, "#!/bin/sh\n")
+	add(&tar.Header{Name: "reports/private/", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	add(&tar.Header{Name: "reports/private/nested/", Typeflag: tar.TypeDir, Mode: 0755}, "")
+	add(&tar.Header{Name: "reports/private/nested/key.pem", Typeflag: tar.TypeReg, Mode: 0600}, "KEY")
+	add(&tar.Header{Name: "reports/latest", Typeflag: tar.TypeSymlink, Linkname: "junit.xml"}, "")
+	tw.Close()
+	return &buf
+}
+
+// TestHostExtractor tests writing a container archive to the host.
+// TestHostExtractorはコンテナのアーカイブのホストへの書き込みをテストします。
+func TestHostExtractor(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "data")
+	extract := &hostExtractor{
+		root:    root,
+		limit:   1 << 20,
+		blocked: func(rel string) bool { return rel == "private" },
+	}
+	noneBlocked := func(string) bool { return false }
+
+	_, skipped, err := walkArchive(reportArchive(t), "reports", "/app/reports", 0, math.MaxInt64, noneBlocked, extract.add)
+	if err != nil || extract.err != nil {
+		t.Fatalf("walkArchive() error = %v, %v", err, extract.err)
+	}
+
+	want := []CopiedFile{
+		{Path: "/app/reports/junit.xml", Size: 13, SHA256: sha256Hex("<testsuite/>\n")},
+		{Path: "/app/reports/run.sh", Size: 10, SHA256: sha256Hex("#!/bin/sh\n")},
+	}
+	if len(extract.copied) != len(want) {
+		t.Fatalf("copied = %+v, want %+v", extract.copied, want)
+	}
+	for i := range want {
+		if extract.copied[i] != want[i] {
+			t.Errorf("copied[%d] = %+v, want %+v", i, extract.copied[i], want[i])
+		}
+	}
+	if skipped != 0 || extract.skippedBlocked != 1 || extract.skippedSpecial != 1 {
+		t.Errorf("skipped = %d, %d blocked, %d special", skipped, extract.skippedBlocked, extract.skippedSpecial)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "private")); !os.IsNotExist(err) {
+		t.Errorf("blocked directory was created: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(root, "run.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("run.sh mode = %v, %v", info, err)
+	}
+}
+
+// TestHostExtractor_SizeLimit tests that a copy over the size limit fails.
+// TestHostExtractor_SizeLimitはサイズ上限を超えるコピーが失敗することをテストします。
+func TestHostExtractor_SizeLimit(t *testing.T) {
+	extract := &hostExtractor{
+		root:    filepath.Join(t.TempDir(), "data"),
+		limit:   16,
+		blocked: func(string) bool { return false },
+	}
+	walkArchive(reportArchive(t), "reports", "/app/reports", 0, math.MaxInt64, func(string) bool { return false }, extract.add)
+	if extract.err == nil || !strings.Contains(extract.err.Error(), "size limit") {
+		t.Errorf("expected size limit error, got %v", extract.err)
+	}
+}
+
+// TestCheckHostDestination tests which existing host paths a copy may replace.
+// TestCheckHostDestinationはコピーが置き換えられる既存のホストパスをテストします。
+func TestCheckHostDestination(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "heap.hprof")
+	if err := os.WriteFile(file, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string // Test case name / テストケース名
+		hostPath  string // Destination / コピー先
+		dir       bool   // Source is a directory / コピー元がディレクトリ
+		overwrite bool   // Overwrite flag / 上書きフラグ
+		wantErr   bool   // Whether an error is expected / エラーを期待するか
+	}{
+		{"new path", filepath.Join(dir, "new"), false, false, false},
+		{"existing file", file, false, false, true},
+		{"existing file with overwrite", file, false, true, false},
+		{"existing directory with overwrite", dir, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkHostDestination(tt.hostPath, tt.dir, tt.overwrite); (err != nil) != tt.wantErr {
+				t.Errorf("checkHostDestination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPlanUpload tests checking host files against the policy before an upload, and the
+// archive built from the plan.
+//
+// TestPlanUploadはアップロード前のホストのファイルのポリシーに対するチェックと、
+// 計画から構築したアーカイブをテストします。
+func TestPlanUpload(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"fixtures/users.json": `[{"id":1}]`,
+		"fixtures/.env":       "TOKEN=secret",
+		"README":              "fixtures\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(src, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("README", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := security.NewPolicy(&config.SecurityConfig{
+		BlockedPaths:  config.BlockedPathsConfig{Manual: map[string][]string{"api": {"*.env"}}},
+		WritablePaths: map[string][]string{"api": {"/app/testdata"}},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{}
+	c.policy.Store(policy)
+
+	if _, err := c.planUpload("api", src, "/app/src"); err == nil || !strings.Contains(err.Error(), "not writable") {
+		t.Errorf("expected writable_paths error, got %v", err)
+	}
+
+	plan, err := c.planUpload("api", src, "/app/testdata/seed")
+	if err != nil {
+		t.Fatalf("planUpload() error = %v", err)
+	}
+	if plan.skippedBlocked != 1 || plan.skippedSpecial != 1 {
+		t.Errorf("skipped = %d blocked, %d special", plan.skippedBlocked, plan.skippedSpecial)
+	}
+	if plan.total != int64(len(files["fixtures/users.json"])+len(files["README"])) {
+		t.Errorf("total = %d", plan.total)
+	}
+
+	var buf bytes.Buffer
+	copied, err := writeUploadArchive(&buf, plan)
+	if err != nil {
+		t.Fatalf("writeUploadArchive() error = %v", err)
+	}
+	if len(copied) != 2 || copied[0].Path != "/app/testdata/seed/README" || copied[1].SHA256 != sha256Hex(files["fixtures/users.json"]) {
+		t.Errorf("copied = %+v", copied)
+	}
+
+	var names []string
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	want := "seed/ seed/README seed/fixtures/ seed/fixtures/users.json"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("archive = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,225 @@
+// events.go reads Docker's event stream for the containers the security policy allows:
+// the recent history for get_events, and a live subscription used to notify sessions
+// when a container dies or changes health.
+//
+// events.goはセキュリティポリシーが許可するコンテナについてDockerのイベントストリームを
+// 読み取ります：get_events用の最近の履歴と、コンテナが停止したりヘルスが変化した時に
+// セッションへ通知するためのライブ購読です。
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// DefaultEventTypes are the container actions returned when no types are requested:
+// lifecycle and health changes, without noise such as exec, attach or resize.
+//
+// DefaultEventTypesはタイプが指定されない場合に返すコンテナのアクションです：
+// exec、attach、resizeなどのノイズを除いた、ライフサイクルとヘルスの変化です。
+var DefaultEventTypes = []string{
+	"create", "start", "restart", "stop", "kill", "die", "oom",
+	"pause", "unpause", "destroy", "health_status",
+}
+
+// MaxEvents caps the events GetEvents returns; the newest are kept.
+// MaxEventsはGetEventsが返すイベント数を制限します。新しいものが残されます。
+const MaxEvents = 1000
+
+// ContainerEvent is one event of a container from Docker's event stream.
+// ContainerEventはDockerのイベントストリームからのコンテナの1つのイベントです。
+type ContainerEvent struct {
+	// Time is when the event happened
+	// Timeはイベントが発生した時刻です
+	Time time.Time `json:"time"`
+
+	// Container is the container name
+	// Containerはコンテナ名です
+	Container string `json:"container"`
+
+	// Action is the event type, e.g. "die", "oom" or "health_status"
+	// Actionはイベントのタイプです。例："die"、"oom"、"health_status"
+	Action string `json:"action"`
+
+	// Health is the new health status ("healthy", "unhealthy") of a health_status event
+	// Healthはhealth_statusイベントの新しいヘルス状態（"healthy"、"unhealthy"）です
+	Health string `json:"health,omitempty"`
+
+	// ExitCode is set for die events
+	// ExitCodeはdieイベントで設定されます
+	ExitCode *int `json:"exit_code,omitempty"`
+
+	// Image is the image of the container
+	// Imageはコンテナのイメージです
+	Image string `json:"image,omitempty"`
+}
+
+// newContainerEvent converts a Docker event message. Actions with a detail after a
+// colon, such as "health_status: healthy", are split into the action and the detail.
+//
+// newContainerEventはDockerのイベントメッセージを変換します。"health_status: healthy"の
+// ようにコロンの後に詳細を持つアクションは、アクションと詳細に分割されます。
+func newContainerEvent(m events.Message) ContainerEvent {
+	e := ContainerEvent{
+		Time:      time.Unix(0, m.TimeNano).UTC(),
+		Container: m.Actor.Attributes["name"],
+		Action:    string(m.Action),
+		Image:     m.Actor.Attributes["image"],
+	}
+	if m.TimeNano == 0 {
+		e.Time = time.Unix(m.Time, 0).UTC()
+	}
+	if action, detail, ok := strings.Cut(e.Action, ":"); ok {
+		e.Action = action
+		if action == "health_status" {
+			e.Health = strings.TrimSpace(detail)
+		}
+	}
+	if code, err := strconv.Atoi(m.Actor.Attributes["exitCode"]); err == nil && e.Action == "die" {
+		e.ExitCode = &code
+	}
+	return e
+}
+
+// IsStateChange reports whether the event is one sessions are notified of: the
+// container died, was OOM killed, or its health changed.
+//
+// IsStateChangeはイベントがセッションに通知されるものかどうかを報告します：
+// コンテナの停止、OOMキル、またはヘルスの変化です。
+func (e ContainerEvent) IsStateChange() bool {
+	switch e.Action {
+	case "die", "oom", "health_status":
+		return true
+	}
+	return false
+}
+
+// Summary returns a one-line description of the event.
+// Summaryはイベントの1行の説明を返します。
+func (e ContainerEvent) Summary() string {
+	switch {
+	case e.Action == "die" && e.ExitCode != nil:
+		return fmt.Sprintf("container %s died with exit code %d", e.Container, *e.ExitCode)
+	case e.Action == "oom":
+		return fmt.Sprintf("container %s ran out of memory (OOM killed)", e.Container)
+	case e.Action == "health_status":
+		return fmt.Sprintf("container %s is now %s", e.Container, e.Health)
+	default:
+		return fmt.Sprintf("container %s: %s", e.Container, e.Action)
+	}
+}
+
+// eventFilters returns the Docker filters for container events of the given types,
+// optionally limited to one container.
+//
+// eventFiltersは指定されたタイプのコンテナイベント用のDockerフィルタを返します。
+// 1つのコンテナに限定することもできます。
+func eventFilters(containerName string, types []string) filters.Args {
+	args := filters.NewArgs(filters.Arg("type", string(events.ContainerEventType)))
+	for _, t := range types {
+		args.Add("event", t)
+	}
+	if containerName != "" {
+		args.Add("container", containerName)
+	}
+	return args
+}
+
+// canSeeEvent reports whether the policy allows the event's container to be listed.
+// canSeeEventはポリシーがイベントのコンテナの一覧表示を許可するかどうかを報告します。
+func (c *Client) canSeeEvent(e ContainerEvent) bool {
+	policy := c.GetPolicy()
+	return e.Container != "" && policy.CanAccessContainer(e.Container) && policy.CanInspect(e.Container)
+}
+
+// GetEvents returns the container events between since and until (timestamps or
+// relative times such as "42m"; until defaults to now) for the allowed containers,
+// oldest first. types selects the actions (default DefaultEventTypes); containerName
+// limits the events to one container. Docker only keeps a limited number of past events.
+//
+// GetEventsは許可されたコンテナについて、sinceからuntilまで（タイムスタンプまたは
+// "42m"のような相対時間。untilのデフォルトは現在）のコンテナイベントを古い順に返します。
+// typesはアクションを選択し（デフォルトはDefaultEventTypes）、containerNameはイベントを
+// 1つのコンテナに限定します。Dockerが保持する過去のイベント数には上限があります。
+func (c *Client) GetEvents(ctx context.Context, containerName, since, until string, types []string) ([]ContainerEvent, error) {
+	if !c.GetPolicy().CanListContainers() {
+		return nil, fmt.Errorf("inspect permission denied")
+	}
+	if containerName != "" {
+		containerName = c.resolveContainer(ctx, containerName)
+		if err := c.GetPolicy().CheckContainerAccess(containerName); err != nil {
+			return nil, err
+		}
+	}
+	if len(types) == 0 {
+		types = DefaultEventTypes
+	}
+	if until == "" {
+		until = strconv.FormatInt(time.Now().Unix(), 10)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	messages, errs := c.docker.Events(ctx, events.ListOptions{
+		Since:   since,
+		Until:   until,
+		Filters: eventFilters(containerName, types),
+	})
+
+	var result []ContainerEvent
+	for {
+		select {
+		case m := <-messages:
+			e := newContainerEvent(m)
+			if !c.canSeeEvent(e) {
+				continue
+			}
+			result = append(result, e)
+			if len(result) > MaxEvents {
+				result = result[1:]
+			}
+		case err := <-errs:
+			// The stream ends with EOF once until is reached
+			// untilに達するとストリームはEOFで終了する
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to read events: %w", err)
+			}
+			return result, nil
+		}
+	}
+}
+
+// WatchEvents calls onEvent for every container event of the allowed containers from
+// now on, until ctx is cancelled or the stream fails. The policy in effect when an
+// event arrives decides whether it is passed on.
+//
+// WatchEventsはctxがキャンセルされるかストリームが失敗するまで、今以降の許可された
+// コンテナのすべてのコンテナイベントについてonEventを呼び出します。イベント到着時に
+// 有効なポリシーが、それを渡すかどうかを決めます。
+func (c *Client) WatchEvents(ctx context.Context, onEvent func(ContainerEvent)) error {
+	messages, errs := c.docker.Events(ctx, events.ListOptions{Filters: eventFilters("", nil)})
+	for {
+		select {
+		case m := <-messages:
+			if e := newContainerEvent(m); c.canSeeEvent(e) {
+				onEvent(e)
+			}
+		case err := <-errs:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				err = io.EOF
+			}
+			return fmt.Errorf("event stream ended: %w", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// events_test.go contains tests for converting and describing Docker container events.
+// events_test.goはDockerのコンテナイベントの変換と説明のテストを含みます。
+package docker
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/events"
+)
+
+// TestNewContainerEvent tests splitting health details and reading the exit code.
+// TestNewContainerEventはヘルスの詳細の分割と終了コードの読み取りをテストします。
+func TestNewContainerEvent(t *testing.T) {
+	tests := []struct {
+		name        string         // Test case name / テストケース名
+		msg         events.Message // Docker event / Dockerイベント
+		wantAction  string
+		wantHealth  string
+		wantExit    int // -1 for no exit code / 終了コードなしは-1
+		wantChange  bool
+		wantSummary string
+	}{
+		{
+			name:        "die",
+			msg:         events.Message{Action: "die", Actor: events.Actor{Attributes: map[string]string{"name": "api", "exitCode": "137"}}, TimeNano: 1},
+			wantAction:  "die",
+			wantExit:    137,
+			wantChange:  true,
+			wantSummary: "container api died with exit code 137",
+		},
+		{
+			name:        "health",
+			msg:         events.Message{Action: "health_status: unhealthy", Actor: events.Actor{Attributes: map[string]string{"name": "api"}}},
+			wantAction:  "health_status",
+			wantHealth:  "unhealthy",
+			wantExit:    -1,
+			wantChange:  true,
+			wantSummary: "container api is now unhealthy",
+		},
+		{
+			name:        "start",
+			msg:         events.Message{Action: "start", Actor: events.Actor{Attributes: map[string]string{"name": "api", "image": "nginx"}}},
+			wantAction:  "start",
+			wantExit:    -1,
+			wantSummary: "container api: start",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newContainerEvent(tt.msg)
+			if e.Container != "api" || e.Action != tt.wantAction || e.Health != tt.wantHealth {
+				t.Errorf("event = %+v", e)
+			}
+			if (e.ExitCode == nil) != (tt.wantExit < 0) || (e.ExitCode != nil && *e.ExitCode != tt.wantExit) {
+				t.Errorf("exit code = %v, want %d", e.ExitCode, tt.wantExit)
+			}
+			if e.IsStateChange() != tt.wantChange {
+				t.Errorf("IsStateChange() = %v, want %v", e.IsStateChange(), tt.wantChange)
+			}
+			if got := e.Summary(); got != tt.wantSummary {
+				t.Errorf("Summary() = %q, want %q", got, tt.wantSummary)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// exec_output.go captures the output of an exec: it splits Docker's multiplexed attach
+// stream into stdout and stderr and stops keeping output once a size limit is reached.
+//
+// exec_output.goはexecの出力をキャプチャします：Dockerの多重化されたアタッチストリームを
+// 標準出力と標準エラー出力に分け、サイズ上限に達すると出力の保持をやめます。
+package docker
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// execOutput collects the stdout and stderr of an exec, and both interleaved in the
+// order they arrived, up to limit bytes in total. Output beyond the limit is read and
+// discarded so the command can run to completion.
+//
+// execOutputはexecの標準出力と標準エラー出力、および到着順に交互に並べた両方を、
+// 合計limitバイトまで収集します。上限を超える出力は読み取って破棄し、
+// コマンドが最後まで実行できるようにします。
+type execOutput struct {
+	limit     int64
+	used      int64
+	truncated bool
+	stdout    strings.Builder
+	stderr    strings.Builder
+	combined  strings.Builder
+
+	// tee, when set, also receives the kept output as it arrives.
+	// teeが設定されている場合、保持する出力を到着次第受け取ります。
+	tee io.Writer
+}
+
+// newExecOutput creates an execOutput that keeps at most limit bytes.
+// newExecOutputは最大limitバイトを保持するexecOutputを作成します。
+func newExecOutput(limit int64) *execOutput {
+	return &execOutput{limit: limit}
+}
+
+// execStream is the writer for one of the streams of an execOutput.
+// execStreamはexecOutputのストリームの1つに対するライターです。
+type execStream struct {
+	out *execOutput
+	dst *strings.Builder
+}
+
+// Write keeps as much of p as the limit allows, cut at a UTF-8 character boundary,
+// and always reports p as written.
+//
+// Writeは上限が許す範囲でpを保持し（UTF-8の文字境界で切る）、常にp全体を
+// 書き込んだと報告します。
+func (w execStream) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := w.out.limit - w.out.used; int64(len(p)) > room {
+		cut := int(max(room, 0))
+		for cut > 0 && !utf8.RuneStart(p[cut]) {
+			cut--
+		}
+		p = p[:cut]
+		w.out.truncated = true
+	}
+	w.dst.Write(p)
+	w.out.combined.Write(p)
+	if w.out.tee != nil && len(p) > 0 {
+		w.out.tee.Write(p)
+	}
+	w.out.used += int64(len(p))
+	return n, nil
+}
+
+// readFrom demultiplexes an attach stream (without a TTY) until it ends.
+// readFromはTTYなしのアタッチストリームを終わりまで多重分離します。
+func (o *execOutput) readFrom(r io.Reader) error {
+	_, err := stdcopy.StdCopy(execStream{o, &o.stdout}, execStream{o, &o.stderr}, r)
+	return err
+}
@@ -0,0 +1,93 @@
+// exec_output_test.go contains tests for capturing the output of an exec.
+// exec_output_test.goはexecの出力のキャプチャのテストを含みます。
+package docker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// attachStream encodes frames like Docker's multiplexed attach stream; odd frames go to stderr.
+// attachStreamはDockerの多重化されたアタッチストリームのようにフレームをエンコードします。奇数番目のフレームは標準エラー出力です。
+func attachStream(frames ...string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	stdout := stdcopy.NewStdWriter(buf, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(buf, stdcopy.Stderr)
+	for i, f := range frames {
+		if i%2 == 0 {
+			stdout.Write([]byte(f))
+		} else {
+			stderr.Write([]byte(f))
+		}
+	}
+	return buf
+}
+
+// TestExecOutput tests splitting the streams, keeping their order in the combined
+// output and the tee, and truncating at the limit on a character boundary.
+//
+// TestExecOutputはストリームの分割、組み合わせた出力とteeでの順序の保持、
+// 文字境界での上限による打ち切りをテストします。
+func TestExecOutput(t *testing.T) {
+	tests := []struct {
+		name          string   // Test case name / テストケース名
+		limit         int64    // Output limit / 出力上限
+		frames        []string // Frames, alternating stdout and stderr / 標準出力と標準エラー出力が交互のフレーム
+		wantStdout    string
+		wantStderr    string
+		wantCombined  string
+		wantTruncated bool
+	}{
+		{
+			name:         "split",
+			limit:        1024,
+			frames:       []string{"ok 1\n", "warn\n", "ok 2\n"},
+			wantStdout:   "ok 1\nok 2\n",
+			wantStderr:   "warn\n",
+			wantCombined: "ok 1\nwarn\nok 2\n",
+		},
+		{
+			name:          "truncated",
+			limit:         8,
+			frames:        []string{"12345", "67890", "abc"},
+			wantStdout:    "12345",
+			wantStderr:    "678",
+			wantCombined:  "12345678",
+			wantTruncated: true,
+		},
+		{
+			name:          "character boundary",
+			limit:         4,
+			frames:        []string{"abcé"},
+			wantStdout:    "abc",
+			wantCombined:  "abc",
+			wantTruncated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := newExecOutput(tt.limit)
+			tee := new(bytes.Buffer)
+			out.tee = tee
+			if err := out.readFrom(attachStream(tt.frames...)); err != nil {
+				t.Fatalf("readFrom() error = %v", err)
+			}
+			if out.stdout.String() != tt.wantStdout || out.stderr.String() != tt.wantStderr || out.combined.String() != tt.wantCombined {
+				t.Errorf("stdout=%q stderr=%q combined=%q", out.stdout.String(), out.stderr.String(), out.combined.String())
+			}
+			if tee.String() != tt.wantCombined {
+				t.Errorf("tee = %q, want the combined output %q", tee.String(), tt.wantCombined)
+			}
+			if out.truncated != tt.wantTruncated {
+				t.Errorf("truncated = %v, want %v", out.truncated, tt.wantTruncated)
+			}
+		})
+	}
+
+	if err := newExecOutput(10).readFrom(strings.NewReader("not a multiplexed stream")); err == nil {
+		t.Error("expected an error for a stream without frame headers")
+	}
+}
@@ -0,0 +1,102 @@
+// exec_timeout.go stops commands that outlive their exec: when an exec times out or its
+// request is cancelled, the processes it started inside the container are killed.
+//
+// Docker has no API to kill an exec, and the PID it reports is in the host's namespace.
+// Every exec therefore carries a unique environment variable, which its child processes
+// inherit, and a short cleanup exec kills every process in the container that has it.
+//
+// exec_timeout.goはexecより長く生き残るコマンドを停止します：execがタイムアウトするか
+// リクエストがキャンセルされると、それがコンテナ内で起動したプロセスを終了します。
+//
+// Dockerにはexecを終了するAPIがなく、報告されるPIDはホストの名前空間のものです。
+// そのため各execは子プロセスにも継承される一意の環境変数を持ち、短いクリーンアップ用の
+// execがコンテナ内でそれを持つすべてのプロセスを終了します。
+package docker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// execMarkerEnv is the environment variable that tags the processes of one exec.
+// execMarkerEnvは1つのexecのプロセスに目印を付ける環境変数です。
+const execMarkerEnv = "DKMCP_EXEC_ID"
+
+// execKillTimeout bounds the cleanup exec.
+// execKillTimeoutはクリーンアップ用のexecの時間を制限します。
+const execKillTimeout = 10 * time.Second
+
+// execKillScript kills every process whose environment contains the line given as $1,
+// and exits with 2 if one of them could not be killed and is still running.
+// It needs sh, tr and grep in the container (busybox is enough).
+//
+// execKillScriptは環境に$1として渡された行を含むすべてのプロセスを終了し、いずれかを
+// 終了できずにまだ実行中の場合は2で終了します。
+// コンテナ内にsh、tr、grepが必要です（busyboxで十分です）。
+const execKillScript = `status=0
+for p in /proc/[0-9]*; do
+  if tr '\0' '\n' < "$p/environ" 2>/dev/null | grep -qxF "$1"; then
+    kill -9 "${p#/proc/}" 2>/dev/null || { [ -d "$p" ] && status=2; }
+  fi
+done
+exit $status`
+
+// execKillUser is the user the cleanup exec runs as. It is root, so it can read the
+// environment of and kill the processes of an exec run as any user (exec_options).
+//
+// execKillUserはクリーンアップ用のexecを実行するユーザーです。rootであるため、任意の
+// ユーザー（exec_options）で実行されたexecのプロセスの環境を読み取り、終了できます。
+const execKillUser = "0"
+
+// newExecMarker returns a fresh "DKMCP_EXEC_ID=<random hex>" environment entry.
+// newExecMarkerは新しい"DKMCP_EXEC_ID=<ランダムな16進数>"の環境変数エントリを返します。
+func newExecMarker() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return execMarkerEnv + "=" + hex.EncodeToString(b)
+}
+
+// killExec kills the processes of the exec tagged with marker. It runs on its own
+// context because the context of the exec is usually already done.
+//
+// killExecはmarkerで目印を付けたexecのプロセスを終了します。execのコンテキストは
+// 通常すでに終了しているため、独自のコンテキストで実行します。
+func (c *Client) killExec(containerName, marker string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), execKillTimeout)
+	defer cancel()
+
+	execID, err := c.docker.ContainerExecCreate(ctx, containerName, container.ExecOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		User:         execKillUser,
+		Cmd:          []string{"sh", "-c", execKillScript, "sh", marker},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create cleanup exec: %w", err)
+	}
+	resp, err := c.docker.ContainerExecAttach(ctx, execID.ID, container.ExecStartOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to start cleanup exec: %w", err)
+	}
+	defer resp.Close()
+	io.Copy(io.Discard, resp.Reader)
+
+	inspect, err := c.docker.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect cleanup exec: %w", err)
+	}
+	switch inspect.ExitCode {
+	case 0:
+	case 2:
+		return fmt.Errorf("cleanup exec could not kill every process of the exec")
+	default:
+		return fmt.Errorf("cleanup exec exited with code %d (the container may lack sh, tr or grep)", inspect.ExitCode)
+	}
+	return nil
+}
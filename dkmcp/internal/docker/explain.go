@@ -0,0 +1,85 @@
+// explain.go completes the policy dry run of explain_policy and 'policy check' with the
+// checks that need the container: the path given to read_file or list_files, and the
+// file arguments of a dangerous command, are resolved through symlinks,
+// /proc/<pid>/root and mounts exactly as the real operations resolve them, so the
+// explanation cannot allow what the real operation blocks.
+//
+// explain.goはexplain_policyと'policy check'のポリシーのドライランを、コンテナを必要とする
+// チェックで補完します：read_fileやlist_filesに渡すパスと危険コマンドのファイル引数は、
+// 実際の操作と同じようにシンボリックリンク、/proc/<pid>/root、マウントを通じて解決される
+// ため、実際の操作がブロックするものを説明が許可することはありません。
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// ExplainExec runs the exec_command checks for a command without executing it. In
+// dangerous mode the file arguments are also resolved in the container, relative paths
+// from the requested workdir, as Exec does.
+//
+// ExplainExecはコマンドを実行せずにexec_commandのチェックを実行します。危険モードでは
+// Execと同様に、ファイル引数もコンテナ内で（相対パスは要求された作業ディレクトリから）
+// 解決します。
+func (c *Client) ExplainExec(ctx context.Context, containerName, command string, dangerously bool, opts security.ExecOptions) security.Explanation {
+	containerName = c.resolveContainer(ctx, containerName)
+	policy := c.GetPolicy()
+
+	e := policy.ExplainExec(containerName, command, dangerously, opts)
+	if !e.Allowed || !dangerously {
+		return e
+	}
+
+	args, err := policy.AuthorizeExec(containerName, command, dangerously)
+	if err == nil {
+		err = c.checkResolvedCommandPaths(ctx, containerName, e.Workdir, args)
+	}
+	var blocked *blockedPathError
+	if errors.As(err, &blocked) {
+		e.BlockedPath = blocked.block
+		e.ResolvedPath = blocked.resolved
+		e.MatchedPath = blocked.matched
+	}
+	if err != nil {
+		e.Allowed = false
+		e.Reason = err.Error()
+	}
+	return e
+}
+
+// ExplainPath runs the read_file and list_files checks for a path without reading it,
+// including the checks of the real path statAllowedPath makes.
+//
+// ExplainPathはパスを読み取らずに、statAllowedPathが行う実パスのチェックを含めて
+// read_fileとlist_filesのチェックを実行します。
+func (c *Client) ExplainPath(ctx context.Context, containerName, path string) security.Explanation {
+	containerName = c.resolveContainer(ctx, containerName)
+	policy := c.GetPolicy()
+
+	e := policy.ExplainPath(containerName, path)
+	if !e.Allowed {
+		return e
+	}
+
+	resolution, err := c.resolvePath(ctx, containerName, "", path)
+	if err != nil {
+		e.Allowed = false
+		e.Reason = err.Error()
+		return e
+	}
+	if resolution.Resolved != path {
+		e.ResolvedPath = resolution.Resolved
+	}
+	if blocked, matched := policy.IsResolvedPathBlocked(containerName, resolution); blocked != nil {
+		e.Allowed = false
+		e.BlockedPath = blocked
+		e.ResolvedPath = resolution.Resolved
+		e.MatchedPath = matched
+		e.Reason = fmt.Sprintf("path is blocked: %s resolves to %s (reason: %s)", path, matched, blocked.Reason)
+	}
+	return e
+}
@@ -0,0 +1,104 @@
+// explain_test.go contains tests for the policy dry run that resolves paths in the container.
+// explain_test.goはコンテナ内でパスを解決するポリシーのドライランのテストを含みます。
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// newFakeDockerClient returns a Client talking to a fake Docker API serving one container,
+// "api", whose files are described by stat.
+//
+// newFakeDockerClientは1つのコンテナ"api"を提供する偽のDocker APIと通信するClientを
+// 返します。コンテナのファイルはstatで記述されます。
+func newFakeDockerClient(t *testing.T, policy *security.Policy, stat statFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			json.NewEncoder(w).Encode([]container.Summary{{ID: "0123456789abcdef", Names: []string{"/api"}, State: "running"}})
+		case strings.HasSuffix(r.URL.Path, "/containers/api/json"):
+			json.NewEncoder(w).Encode(container.InspectResponse{
+				ContainerJSONBase: &container.ContainerJSONBase{ID: "0123456789abcdef", Name: "/api"},
+				Config:            &container.Config{WorkingDir: "/app"},
+			})
+		case strings.HasSuffix(r.URL.Path, "/containers/api/archive") && r.Method == http.MethodHead:
+			st, err := stat(r.URL.Query().Get("path"))
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			data, _ := json.Marshal(st)
+			w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(data))
+		default:
+			t.Errorf("unexpected Docker API request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dockerClient, err := client.NewClientWithOpts(client.WithHost("tcp://"+server.Listener.Addr().String()), client.WithVersion("1.47"))
+	if err != nil {
+		t.Fatalf("NewClientWithOpts() error = %v", err)
+	}
+	c := &Client{docker: dockerClient}
+	c.policy.Store(policy)
+	return c
+}
+
+// TestExplain_Symlink tests that a symlink to a blocked file is denied by read_file and
+// explained as denied, for the path check and for a dangerous command.
+//
+// TestExplain_Symlinkはブロックされたファイルへのシンボリックリンクがread_fileで拒否され、
+// パスのチェックと危険コマンドの両方で拒否として説明されることをテストします。
+func TestExplain_Symlink(t *testing.T) {
+	policy := security.NewPolicy(&config.SecurityConfig{
+		Mode:        "moderate",
+		Permissions: config.SecurityPermissions{Exec: true},
+		ExecDangerously: config.ExecDangerouslyConfig{
+			Enabled:  true,
+			Commands: map[string][]string{"*": {"cat"}},
+		},
+		BlockedPaths: config.BlockedPathsConfig{Manual: map[string][]string{"*": {"/app/.env"}}},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatal(err)
+	}
+	c := newFakeDockerClient(t, policy, fakeStat([]string{"/app"}, map[string]string{"/app/link-to-env": "/app/.env"}))
+	ctx := context.Background()
+
+	result, err := c.ReadFile(ctx, "api", "/app/link-to-env", ReadOptions{})
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !result.Blocked || result.ResolvedPath != "/app/.env" {
+		t.Fatalf("ReadFile() = %+v, want blocked at /app/.env", result)
+	}
+
+	e := c.ExplainPath(ctx, "api", "/app/link-to-env")
+	if e.Allowed || e.BlockedPath == nil || e.ResolvedPath != "/app/.env" || e.MatchedPath != "/app/.env" {
+		t.Errorf("ExplainPath() = %+v, want denied with the resolved path", e)
+	}
+	if e := policy.ExplainPath("api", "/app/link-to-env"); !e.Allowed {
+		t.Errorf("the policy alone cannot see the link, got %+v", e)
+	}
+
+	e = c.ExplainExec(ctx, "api", "cat link-to-env", true, security.ExecOptions{})
+	if e.Allowed || e.ResolvedPath != "/app/.env" || !strings.Contains(e.Reason, "resolves to /app/.env") {
+		t.Errorf("ExplainExec() = %+v, want denied with the resolved path", e)
+	}
+	if e := c.ExplainPath(ctx, "api", "/app/package.json"); !e.Allowed || e.ResolvedPath != "" {
+		t.Errorf("ExplainPath(package.json) = %+v, want allowed", e)
+	}
+}
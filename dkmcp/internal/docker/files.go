@@ -0,0 +1,799 @@
+// files.go implements list_files and read_file on Docker's archive API
+// (ContainerStatPath and CopyFromContainer) instead of running ls, cat or head inside
+// the container, so file access also works in distroless and scratch images that have
+// no shell or coreutils. Paths are checked against blocked_paths before any access,
+// both as given and after resolving symlinks and mounts (see resolve.go).
+//
+// files.goはコンテナ内でls、cat、headを実行する代わりに、DockerのアーカイブAPI
+// （ContainerStatPathとCopyFromContainer）でlist_filesとread_fileを実装します。
+// これによりシェルやcoreutilsのないdistrolessやscratchイメージでもファイルアクセスが
+// 動作します。パスはアクセス前に、指定されたままの形とシンボリックリンクやマウントを
+// 解決した後の形の両方でblocked_pathsに対してチェックされます（resolve.goを参照）。
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+const (
+	// binarySniffBytes is the size of the sample at the start of a file used to detect
+	// binary content.
+	// binarySniffBytesはバイナリ内容の検出に使用するファイル先頭のサンプルのサイズです。
+	binarySniffBytes = 8000
+
+	// maxListScanBytes caps the archive data scanned by ListFiles. The archive API returns
+	// a directory recursively, so listing a large tree stops early and is marked truncated.
+	//
+	// maxListScanBytesはListFilesが走査するアーカイブデータの上限です。アーカイブAPIは
+	// ディレクトリを再帰的に返すため、大きなツリーの一覧は途中で打ち切られtruncatedになります。
+	maxListScanBytes = 64 << 20
+
+	// maxListEntries caps the entries ListFiles returns for a directory.
+	// maxListEntriesはListFilesがディレクトリに対して返すエントリの上限です。
+	maxListEntries = 1000
+
+	// maxListScanEntries caps the archive entries ListFiles reads. Entries below the
+	// listed directory only cost time, so a deep tree stops early and is marked truncated.
+	//
+	// maxListScanEntriesはListFilesが読み取るアーカイブエントリの上限です。一覧表示する
+	// ディレクトリより下のエントリは時間を消費するだけのため、深いツリーは途中で打ち切られ
+	// truncatedになります。
+	maxListScanEntries = 10000
+)
+
+// FileAccessResult represents the result of a file access operation
+// (listing or reading files) in a container.
+//
+// It indicates whether the operation succeeded, was blocked by policy,
+// or failed with an error.
+//
+// FileAccessResultはコンテナ内でのファイルアクセス操作
+// （ファイルの一覧表示または読み取り）の結果を表します。
+//
+// 操作が成功したか、ポリシーによってブロックされたか、
+// エラーで失敗したかを示します。
+type FileAccessResult struct {
+	// Success indicates whether the file operation completed successfully.
+	// Successはファイル操作が正常に完了したかどうかを示します。
+	Success bool `json:"success"`
+
+	// Data contains the file content if successful (ReadFile).
+	// Dataは成功した場合のファイル内容を含みます（ReadFile）。
+	Data string `json:"data,omitempty"`
+
+	// File describes the listed directory or the read or written file.
+	// Fileは一覧表示したディレクトリ、または読み取った・書き込んだファイルを表します。
+	File *FileEntry `json:"file,omitempty"`
+
+	// Entries contains the directory entries if successful (ListFiles), or the files
+	// found (FindFiles).
+	// Entriesは成功した場合のディレクトリエントリ（ListFiles）、または見つかった
+	// ファイル（FindFiles）を含みます。
+	Entries []FileEntry `json:"entries,omitempty"`
+
+	// Matches contains the matching lines (GrepFiles).
+	// Matchesはマッチした行を含みます（GrepFiles）。
+	Matches []GrepMatch `json:"matches,omitempty"`
+
+	// SkippedBlocked is the number of blocked files and directories a search or copy skipped.
+	// SkippedBlockedは検索またはコピーがスキップしたブロックされたファイルとディレクトリの数です。
+	SkippedBlocked int `json:"skipped_blocked,omitempty"`
+
+	// SkippedSpecial is the number of symlinks and special files a copy skipped.
+	// SkippedSpecialはコピーがスキップしたシンボリックリンクと特殊ファイルの数です。
+	SkippedSpecial int `json:"skipped_special,omitempty"`
+
+	// Copied lists the files moved by CopyFromContainer or CopyToContainer.
+	// CopiedはCopyFromContainerまたはCopyToContainerが移動したファイルを列挙します。
+	Copied []CopiedFile `json:"copied,omitempty"`
+
+	// Truncated reports that the listing or content was cut at a size limit.
+	// Truncatedは一覧または内容がサイズ上限で打ち切られたことを報告します。
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Offset is the byte offset of Data in the file (ReadFile).
+	// OffsetはファイルにおけるDataのバイトオフセットです（ReadFile）。
+	Offset int64 `json:"offset,omitempty"`
+
+	// StartLine is the line number of the first line of Data, for line and tail reads.
+	// StartLineは行単位および末尾の読み取りにおけるDataの最初の行の行番号です。
+	StartLine int `json:"start_line,omitempty"`
+
+	// NextCursor continues the read where Data ended; empty when the end of the file
+	// was reached.
+	// NextCursorはDataの終わりから読み取りを続けます。ファイルの終わりに達した場合は空です。
+	NextCursor string `json:"next_cursor,omitempty"`
+
+	// Binary reports that the file holds binary content, which is not returned; the
+	// file's size and SHA256 are reported instead (only the size for a file larger than
+	// the read limit).
+	// Binaryはファイルがバイナリ内容を持つことを報告します。内容は返されず、
+	// 代わりにファイルのサイズとSHA256が報告されます（読み取り上限より大きいファイルは
+	// サイズのみ）。
+	Binary bool `json:"binary,omitempty"`
+
+	// SHA256 is the hex-encoded SHA-256 hash of a binary file; empty when the file is
+	// larger than the read limit and was not hashed.
+	// SHA256はバイナリファイルの16進エンコードされたSHA-256ハッシュです。ファイルが
+	// 読み取り上限より大きくハッシュされなかった場合は空です。
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Created reports that WriteFile created a new file.
+	// CreatedはWriteFileが新しいファイルを作成したことを報告します。
+	Created bool `json:"created,omitempty"`
+
+	// BackupPath is where WriteFile and ApplyPatch saved the previous content.
+	// BackupPathはWriteFileとApplyPatchが以前の内容を保存した場所です。
+	BackupPath string `json:"backup_path,omitempty"`
+
+	// Diff is the unified diff of a write; empty when the content did not change.
+	// Diffは書き込みのunified diffです。内容が変わらなかった場合は空です。
+	Diff string `json:"diff,omitempty"`
+
+	// Blocked indicates if the path was blocked by security policy.
+	// Blockedはパスがセキュリティポリシーによってブロックされたかを示します。
+	Blocked bool `json:"blocked,omitempty"`
+
+	// Block contains details about why the path was blocked.
+	// Blockはパスがブロックされた理由の詳細を含みます。
+	Block *security.BlockedPath `json:"block_info,omitempty"`
+
+	// ResolvedPath is the real path in the container the requested path leads to,
+	// reported when the path was blocked.
+	// ResolvedPathは要求されたパスが繋がるコンテナ内の実パスで、ブロック時に報告されます。
+	ResolvedPath string `json:"resolved_path,omitempty"`
+
+	// MatchedPath is the name of the file that matched the blocked pattern: the requested
+	// or resolved path, another mount of it, or its workspace-relative host path.
+	// MatchedPathはブロックパターンに一致したファイルの名前です：要求されたパスまたは
+	// 解決したパス、その別のマウント、またはワークスペースからの相対ホストパスです。
+	MatchedPath string `json:"matched_path,omitempty"`
+
+	// Error contains the error message if the operation failed.
+	// Errorは操作が失敗した場合のエラーメッセージを含みます。
+	Error string `json:"error,omitempty"`
+}
+
+// FileEntry describes a file or directory in a container.
+// FileEntryはコンテナ内のファイルまたはディレクトリを表します。
+type FileEntry struct {
+	// Name is the base name of the entry
+	// Nameはエントリのベース名です
+	Name string `json:"name"`
+
+	// Path is the full path of the entry in the container (FindFiles)
+	// Pathはコンテナ内のエントリのフルパスです（FindFiles）
+	Path string `json:"path,omitempty"`
+
+	// Type is "file", "dir", "symlink" or "other"
+	// Typeは"file"、"dir"、"symlink"、"other"のいずれかです
+	Type string `json:"type"`
+
+	// Size is the size in bytes
+	// Sizeはバイト単位のサイズです
+	Size int64 `json:"size"`
+
+	// Mode is the permission string (e.g., "-rw-r--r--")
+	// Modeはパーミッション文字列です（例: "-rw-r--r--"）
+	Mode string `json:"mode"`
+
+	// ModTime is the modification time
+	// ModTimeは更新日時です
+	ModTime time.Time `json:"mtime"`
+
+	// LinkTarget is the target of a symlink
+	// LinkTargetはシンボリックリンクのリンク先です
+	LinkTarget string `json:"link_target,omitempty"`
+}
+
+// ReadOptions selects the part of a file ReadFile returns: a byte range (Offset,
+// Length), a line range (StartLine, MaxLines), the last Tail lines, or the continuation
+// of a previous read (Cursor, with Length or MaxLines). The zero value reads the whole
+// file. The result is always capped at the container's file_read limit.
+//
+// ReadOptionsはReadFileが返すファイルの範囲を選択します：バイト範囲（Offset、Length）、
+// 行範囲（StartLine、MaxLines）、最後のTail行、または前回の読み取りの続き（Cursor、
+// LengthまたはMaxLinesと併用）です。ゼロ値はファイル全体を読み取ります。結果は常に
+// コンテナのfile_readの上限で制限されます。
+type ReadOptions struct {
+	// Offset is the first byte to read (0-based)
+	// Offsetは読み取る最初のバイトです（0始まり）
+	Offset int64
+
+	// Length is the maximum number of bytes to read (0 = to the end of the file)
+	// Lengthは読み取る最大バイト数です（0 = ファイルの終わりまで）
+	Length int64
+
+	// StartLine is the first line to read (1-based, 0 = first line)
+	// StartLineは読み取る最初の行です（1始まり、0 = 最初の行）
+	StartLine int
+
+	// MaxLines is the maximum number of lines to read (0 = to the end of the file)
+	// MaxLinesは読み取る最大行数です（0 = ファイルの終わりまで）
+	MaxLines int
+
+	// Tail reads the last Tail lines of the file
+	// Tailはファイルの最後のTail行を読み取ります
+	Tail int
+
+	// Cursor is a FileAccessResult.NextCursor to continue reading from
+	// Cursorは読み取りを続けるためのFileAccessResult.NextCursorです
+	Cursor string
+}
+
+// validate rejects negative values and combinations of read modes.
+// validateは負の値と読み取りモードの組み合わせを拒否します。
+func (o ReadOptions) validate() error {
+	if o.Offset < 0 || o.Length < 0 || o.StartLine < 0 || o.MaxLines < 0 || o.Tail < 0 {
+		return fmt.Errorf("read range values must not be negative")
+	}
+	if (o.Offset > 0 || o.Length > 0) && (o.StartLine > 0 || o.MaxLines > 0) {
+		return fmt.Errorf("byte range (offset, length) and line range (start_line, max_lines) cannot be combined")
+	}
+	if o.Tail > 0 && (o.Offset > 0 || o.Length > 0 || o.StartLine > 0 || o.MaxLines > 0 || o.Cursor != "") {
+		return fmt.Errorf("tail cannot be combined with other read ranges")
+	}
+	if o.Cursor != "" && (o.Offset > 0 || o.StartLine > 0) {
+		return fmt.Errorf("cursor cannot be combined with offset or start_line")
+	}
+	if o.Cursor != "" {
+		if _, err := decodeCursor(o.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCursor is the position a read stopped at: the byte offset and, for line reads,
+// the number of the line at that offset.
+//
+// readCursorは読み取りが停止した位置です：バイトオフセットと、行単位の読み取りでは
+// そのオフセットにある行の番号です。
+type readCursor struct {
+	offset int64
+	line   int
+}
+
+// encodeCursor returns the opaque cursor string for c.
+// encodeCursorはcに対する不透明なカーソル文字列を返します。
+func encodeCursor(c readCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.offset, c.line)))
+}
+
+// decodeCursor parses a cursor string returned by encodeCursor.
+// decodeCursorはencodeCursorが返したカーソル文字列を解析します。
+func decodeCursor(s string) (readCursor, error) {
+	var c readCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		_, err = fmt.Sscanf(string(data), "%d:%d", &c.offset, &c.line)
+	}
+	if err != nil || c.offset < 0 || c.line < 0 {
+		return readCursor{}, fmt.Errorf("invalid cursor: %q", s)
+	}
+	return c, nil
+}
+
+// ListFiles lists files and directories in a container at the specified path.
+// The path is checked against the security policy's blocked paths before access.
+//
+// If the path is blocked, the result will indicate the block with details
+// about why access was denied (e.g., security-sensitive directory).
+//
+// The listing is read from the archive API, so it does not need any binary in
+// the container. Listing a file returns that single entry.
+//
+// ListFilesはコンテナ内の指定されたパスにあるファイルとディレクトリを一覧表示します。
+// アクセス前にパスはセキュリティポリシーのブロックパスに対してチェックされます。
+//
+// パスがブロックされている場合、結果はアクセスが拒否された理由
+// （例：セキュリティ上重要なディレクトリ）の詳細とともにブロックを示します。
+//
+// 一覧はアーカイブAPIから読み取るため、コンテナ内にバイナリは不要です。
+// ファイルを指定した場合はそのエントリのみを返します。
+func (c *Client) ListFiles(ctx context.Context, containerName string, path string) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	target, stat, result, err := c.statAllowedPath(ctx, containerName, path)
+	if result != nil || err != nil {
+		return result, err
+	}
+
+	entry := fileEntryFromStat(stat)
+	if !stat.Mode.IsDir() {
+		return &FileAccessResult{Success: true, File: &entry, Entries: []FileEntry{entry}}, nil
+	}
+
+	reader, _, err := c.docker.CopyFromContainer(ctx, containerName, target)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	defer reader.Close()
+
+	entries, truncated, err := listArchive(reader, stat.Name, maxListScanBytes, maxListEntries, maxListScanEntries)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+
+	return &FileAccessResult{
+		Success:   true,
+		File:      &entry,
+		Entries:   entries,
+		Truncated: truncated,
+	}, nil
+}
+
+// ReadFile reads the contents of a file from a container.
+// The path is checked against the security policy's blocked paths before access.
+//
+// Parameters:
+//   - containerName: The name or ID of the container
+//   - path: The absolute path to the file in the container
+//   - opts: The byte or line range to read (zero value = whole file)
+//
+// The content is read from the archive API, so it does not need any binary in
+// the container.
+//
+// ReadFileはコンテナからファイルの内容を読み取ります。
+// アクセス前にパスはセキュリティポリシーのブロックパスに対してチェックされます。
+//
+// パラメータ:
+//   - containerName: コンテナの名前またはID
+//   - path: コンテナ内のファイルへの絶対パス
+//   - opts: 読み取るバイト範囲または行範囲（ゼロ値 = ファイル全体）
+//
+// 内容はアーカイブAPIから読み取るため、コンテナ内にバイナリは不要です。
+func (c *Client) ReadFile(ctx context.Context, containerName string, path string, opts ReadOptions) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	target, stat, result, err := c.statAllowedPath(ctx, containerName, path)
+	if result != nil || err != nil {
+		return result, err
+	}
+
+	entry := fileEntryFromStat(stat)
+	if stat.Mode.IsDir() {
+		return &FileAccessResult{Success: false, File: &entry, Error: fmt.Sprintf("%s is a directory", path)}, nil
+	}
+
+	reader, _, err := c.docker.CopyFromContainer(ctx, containerName, target)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	if _, err := tr.Next(); err != nil {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to read archive: %v", err)}, nil
+	}
+	br := bufio.NewReaderSize(tr, 64<<10)
+
+	// Binary content is summarized by its size and hash instead of being returned; a
+	// file larger than the read limit is not read at all, so only its size is reported
+	// バイナリ内容は返さずに、サイズとハッシュで要約する。読み取り上限より大きい
+	// ファイルは一切読み取らないため、サイズのみを報告する
+	limit := c.GetPolicy().MaxReadBytes(containerName)
+	sample, err := br.Peek(binarySniffBytes)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+	}
+	if isBinary(sample) {
+		binary := &FileAccessResult{Success: true, File: &entry, Binary: true}
+		if stat.Size > limit {
+			return binary, nil
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, io.LimitReader(br, limit+1))
+		if err != nil {
+			return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to read file: %v", err)}, nil
+		}
+		// The file may have grown since it was statted
+		// statの後にファイルが大きくなった可能性がある
+		if n <= limit {
+			binary.SHA256 = hex.EncodeToString(h.Sum(nil))
+		}
+		return binary, nil
+	}
+
+	rr, err := readRange(br, opts, limit)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+
+	read := &FileAccessResult{
+		Success:   true,
+		Data:      rr.data,
+		File:      &entry,
+		Truncated: rr.truncated,
+		Offset:    rr.start,
+		StartLine: rr.startLine,
+	}
+	if !rr.eof {
+		read.NextCursor = encodeCursor(readCursor{offset: rr.end, line: rr.endLine})
+	}
+	return read, nil
+}
+
+// statAllowedPath checks container access and blocked paths for path, resolves it to its
+// real path (see resolvePath), checks every name of the resolved path and stats it. It
+// returns the path to read and its stat, or a result to return as is (blocked or failed).
+//
+// statAllowedPathはpathに対するコンテナアクセスとブロックパスをチェックし、実パスに解決して
+// （resolvePathを参照）解決したパスのすべての名前をチェックしてstatします。読み取るパスと
+// そのstat、またはそのまま返す結果（ブロックまたは失敗）を返します。
+func (c *Client) statAllowedPath(ctx context.Context, containerName, path string) (string, container.PathStat, *FileAccessResult, error) {
+	policy := c.GetPolicy()
+
+	// Verify the container is accessible according to policy.
+	// ポリシーに従ってコンテナがアクセス可能か確認します。
+	if err := policy.CheckContainerAccess(containerName); err != nil {
+		return "", container.PathStat{}, nil, err
+	}
+
+	// Check if the requested path is blocked by security policy.
+	// This prevents access to sensitive directories like /etc/secrets.
+	// 要求されたパスがセキュリティポリシーによってブロックされているかチェックします。
+	// これは/etc/secretsのような機密ディレクトリへのアクセスを防ぎます。
+	if blocked := policy.IsPathBlocked(containerName, path); blocked != nil {
+		return "", container.PathStat{}, &FileAccessResult{Success: false, Blocked: true, Block: blocked}, nil
+	}
+
+	// The real path must not be blocked either (e.g., /app/config -> /secrets,
+	// /proc/1/root/app/.env, or the same host file mounted at another path)
+	// 実パスもブロックされていてはならない（例: /app/config -> /secrets、
+	// /proc/1/root/app/.env、または別のパスにマウントされた同じホストファイル）
+	resolution, err := c.resolvePath(ctx, containerName, "", path)
+	if err != nil {
+		return "", container.PathStat{}, &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	if blocked, matched := policy.IsResolvedPathBlocked(containerName, resolution); blocked != nil {
+		return "", container.PathStat{}, &FileAccessResult{
+			Success:      false,
+			Blocked:      true,
+			Block:        blocked,
+			ResolvedPath: resolution.Resolved,
+			MatchedPath:  matched,
+		}, nil
+	}
+
+	target := resolution.Resolved
+	stat, err := c.docker.ContainerStatPath(ctx, containerName, target)
+	if err != nil {
+		return "", container.PathStat{}, &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	return target, stat, nil, nil
+}
+
+// fileEntryFromStat converts a Docker path stat to a FileEntry.
+// fileEntryFromStatはDockerのパスのstatをFileEntryに変換します。
+func fileEntryFromStat(stat container.PathStat) FileEntry {
+	return FileEntry{
+		Name:       stat.Name,
+		Type:       fileType(stat.Mode),
+		Size:       stat.Size,
+		Mode:       stat.Mode.String(),
+		ModTime:    stat.Mtime,
+		LinkTarget: stat.LinkTarget,
+	}
+}
+
+// fileType returns the FileEntry type for a file mode.
+// fileTypeはファイルモードに対するFileEntryの種別を返します。
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsRegular():
+		return "file"
+	}
+	return "other"
+}
+
+// listArchive returns the direct children of the directory archived in r. The archive
+// holds the whole tree under a top-level entry named after the directory (dirName);
+// scanning stops after limit bytes or maxScanned archive entries, or once maxEntries
+// children are found, in which case truncated is true.
+//
+// listArchiveはrにアーカイブされたディレクトリの直下のエントリを返します。アーカイブは
+// ディレクトリ名（dirName）のトップレベルエントリの下にツリー全体を含みます。
+// limitバイトまたはmaxScanned個のアーカイブエントリを超えるか、maxEntries個の子が
+// 見つかると走査を打ち切り、truncatedがtrueになります。
+func listArchive(r io.Reader, dirName string, limit int64, maxEntries, maxScanned int) (entries []FileEntry, truncated bool, err error) {
+	counter := &countingReader{r: r}
+	tr := tar.NewReader(counter)
+
+	root := strings.Trim(dirName, "/")
+	for scanned := 0; ; scanned++ {
+		if counter.n > limit {
+			truncated = true
+			break
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if scanned >= maxScanned {
+			truncated = true
+			break
+		}
+
+		rel, ok := archiveRelativePath(hdr.Name, root)
+		if !ok || rel == "" || strings.Contains(rel, "/") {
+			continue
+		}
+		if len(entries) >= maxEntries {
+			truncated = true
+			break
+		}
+		info := hdr.FileInfo()
+		entries = append(entries, FileEntry{
+			Name:       rel,
+			Type:       fileType(info.Mode()),
+			Size:       hdr.Size,
+			Mode:       info.Mode().String(),
+			ModTime:    hdr.ModTime,
+			LinkTarget: hdr.Linkname,
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, truncated, nil
+}
+
+// archiveRelativePath returns name relative to the archive's top-level directory root
+// ("" for the root directory itself, which archives as "/" or ".").
+//
+// archiveRelativePathはnameをアーカイブのトップレベルディレクトリrootからの相対パスで返します
+// （ルートディレクトリ自体は"/"または"."としてアーカイブされるため""）。
+func archiveRelativePath(name, root string) (string, bool) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if root == "" || root == "." {
+		return name, true
+	}
+	if name == root {
+		return "", true
+	}
+	rel := strings.TrimPrefix(name, root+"/")
+	return rel, rel != name
+}
+
+// readResult is the content readRange selected and where it is in the file.
+// readResultはreadRangeが選択した内容と、そのファイル内の位置です。
+type readResult struct {
+	data      string // Selected content / 選択した内容
+	truncated bool   // Cut by the size limit rather than the range / 範囲ではなくサイズ上限で打ち切られた
+	start     int64  // Byte offset of data / dataのバイトオフセット
+	end       int64  // Byte offset just after data / dataの直後のバイトオフセット
+	startLine int    // Line number of the first line (line and tail reads) / 最初の行の行番号（行単位・末尾の読み取り）
+	endLine   int    // Line number at end (line reads) / endにある行の行番号（行単位の読み取り）
+	eof       bool   // data reaches the end of the file / dataがファイルの終わりに達している
+}
+
+// readRange reads the range selected by opts from r, capped at limit bytes; truncated
+// reports that the cap, rather than the requested range, cut the content.
+//
+// readRangeはoptsで選択された範囲をrからlimitバイトを上限に読み取ります。truncatedは
+// 要求した範囲ではなく上限で内容が打ち切られたことを報告します。
+func readRange(r io.Reader, opts ReadOptions, limit int64) (readResult, error) {
+	br := bufio.NewReader(r)
+	if opts.Tail > 0 {
+		return readTail(br, opts.Tail, limit)
+	}
+
+	start, line := opts.Offset, 0
+	lineMode := opts.StartLine > 0 || opts.MaxLines > 0
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return readResult{}, err
+		}
+		start, line = c.offset, c.line
+		lineMode = lineMode || c.line > 0
+	}
+
+	if start > 0 {
+		n, err := io.CopyN(io.Discard, br, start)
+		if errors.Is(err, io.EOF) {
+			return readResult{start: n, end: n, eof: true}, nil
+		}
+		if err != nil {
+			return readResult{}, fmt.Errorf("failed to read file: %w", err)
+		}
+	}
+
+	if lineMode {
+		if line == 0 {
+			line = 1
+		}
+		return readLines(br, start, line, opts.StartLine, opts.MaxLines, limit)
+	}
+
+	n := limit
+	if opts.Length > 0 && opts.Length <= limit {
+		n = opts.Length
+	}
+	data, err := io.ReadAll(io.LimitReader(br, n+1))
+	if err != nil {
+		return readResult{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	rr := readResult{start: start, eof: int64(len(data)) <= n}
+	if !rr.eof {
+		data = data[:n]
+		rr.truncated = n == limit && opts.Length != n
+	}
+	rr.data = string(data)
+	rr.end = start + int64(len(data))
+	return rr, nil
+}
+
+// readLines reads maxLines lines (0 = all) starting at the 1-based startLine from br,
+// which is positioned at byte offset pos and line number line.
+//
+// readLinesはバイトオフセットposかつ行番号lineに位置するbrから、1始まりのstartLine行目
+// 以降をmaxLines行（0 = すべて）読み取ります。
+func readLines(br *bufio.Reader, pos int64, line, startLine, maxLines int, limit int64) (readResult, error) {
+	if startLine < line {
+		startLine = line
+	}
+	rr := readResult{startLine: startLine}
+	var out bytes.Buffer
+
+	for count := 0; maxLines == 0 || count < maxLines; {
+		chunk, err := br.ReadSlice('\n')
+		if len(chunk) > 0 && line >= startLine {
+			if out.Len() == 0 {
+				rr.start = pos
+			}
+			if int64(out.Len()+len(chunk)) > limit {
+				n := int(limit) - out.Len()
+				out.Write(chunk[:n])
+				rr.data, rr.truncated = out.String(), true
+				rr.end, rr.endLine = pos+int64(n), line
+				return rr, nil
+			}
+			out.Write(chunk)
+		}
+		pos += int64(len(chunk))
+
+		switch {
+		case err == nil:
+			// A complete line was read
+			// 行全体を読み取った
+			if line >= startLine {
+				count++
+			}
+			line++
+		case errors.Is(err, bufio.ErrBufferFull):
+			// The line continues in the next chunk
+			// 行は次のチャンクに続く
+		case errors.Is(err, io.EOF):
+			rr.eof = true
+		default:
+			return readResult{}, fmt.Errorf("failed to read file: %w", err)
+		}
+		if rr.eof {
+			break
+		}
+	}
+
+	if out.Len() == 0 {
+		rr.start = pos
+	}
+	if !rr.eof {
+		_, err := br.Peek(1)
+		rr.eof = errors.Is(err, io.EOF)
+	}
+	rr.data, rr.end, rr.endLine = out.String(), pos, line
+	return rr, nil
+}
+
+// readTail reads the last n lines of br, keeping at most limit bytes of them in memory.
+// readTailはbrの最後のn行を読み取り、そのうち最大limitバイトのみをメモリに保持します。
+func readTail(br *bufio.Reader, n int, limit int64) (readResult, error) {
+	var lines [][]byte
+	var size, pos int64
+	var partial []byte
+	line := 0
+	truncated := false
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		pos += int64(len(chunk))
+		partial = append(partial, chunk...)
+		// Only the end of a line longer than the limit can be returned
+		// 上限より長い行はその末尾のみを返せる
+		if int64(len(partial)) > limit {
+			partial = append(partial[:0], partial[int64(len(partial))-limit:]...)
+			truncated = true
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return readResult{}, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		if len(partial) > 0 {
+			line++
+			lines = append(lines, partial)
+			size += int64(len(partial))
+			partial = nil
+			for len(lines) > n || (size > limit && len(lines) > 1) {
+				truncated = truncated || len(lines) <= n
+				size -= int64(len(lines[0]))
+				lines = lines[1:]
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	data := bytes.Join(lines, nil)
+	return readResult{
+		data:      string(data),
+		truncated: truncated,
+		start:     pos - int64(len(data)),
+		end:       pos,
+		startLine: line - len(lines) + 1,
+		endLine:   line + 1,
+		eof:       true,
+	}, nil
+}
+
+// isBinary reports whether a sample from the start of a file looks like binary content:
+// it contains a NUL byte or mostly control characters.
+//
+// isBinaryはファイル先頭のサンプルがバイナリ内容に見えるかどうかを報告します：
+// NULバイトを含むか、大部分が制御文字である場合です。
+func isBinary(sample []byte) bool {
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	control := 0
+	for _, b := range sample {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\b' && b != 0x1b {
+			control++
+		}
+	}
+	return control*10 > len(sample)
+}
+
+// countingReader counts the bytes read through it.
+// countingReaderは読み取られたバイト数を数えます。
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
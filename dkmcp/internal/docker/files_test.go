@@ -0,0 +1,370 @@
+// files_test.go contains tests for reading directory listings and file ranges from
+// Docker archives.
+//
+// files_test.goはDockerアーカイブからのディレクトリ一覧とファイル範囲の読み取りの
+// テストを含みます。
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveEntry describes an entry written by buildArchive.
+// archiveEntryはbuildArchiveが書き込むエントリを表します。
+type archiveEntry struct {
+	name     string // Entry name / エントリ名
+	typeflag byte   // tar type flag / tarの種別フラグ
+	body     string // File content / ファイル内容
+	linkname string // Symlink target / シンボリックリンクのリンク先
+}
+
+// buildArchive returns a tar archive like the one CopyFromContainer returns.
+// buildArchiveはCopyFromContainerが返すものと同様のtarアーカイブを返します。
+func buildArchive(t *testing.T, entries []archiveEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Mode:     0644,
+			Size:     int64(len(e.body)),
+			Linkname: e.linkname,
+			ModTime:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if e.typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader failed: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil && e.typeflag == tar.TypeReg {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return &buf
+}
+
+// TestListArchive tests that only the direct children of the archived directory are listed.
+// TestListArchiveはアーカイブされたディレクトリの直下のエントリのみが一覧されることをテストします。
+func TestListArchive(t *testing.T) {
+	archive := []archiveEntry{
+		{name: "app/", typeflag: tar.TypeDir},
+		{name: "app/package.json", typeflag: tar.TypeReg, body: `{"name":"app"}`},
+		{name: "app/current", typeflag: tar.TypeSymlink, linkname: "releases/2"},
+		{name: "app/src/", typeflag: tar.TypeDir},
+		{name: "app/src/index.js", typeflag: tar.TypeReg, body: "console.log(1)"},
+	}
+
+	entries, truncated, err := listArchive(buildArchive(t, archive), "app", maxListScanBytes, maxListEntries, maxListScanEntries)
+	if err != nil {
+		t.Fatalf("listArchive() error = %v", err)
+	}
+	if truncated {
+		t.Error("listArchive() truncated a small archive")
+	}
+
+	want := []FileEntry{
+		{Name: "current", Type: "symlink", LinkTarget: "releases/2"},
+		{Name: "package.json", Type: "file", Size: 14},
+		{Name: "src", Type: "dir"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("listArchive() = %+v, want %d entries", entries, len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Name != w.Name || e.Type != w.Type || e.Size != w.Size || e.LinkTarget != w.LinkTarget {
+			t.Errorf("entry[%d] = %+v, want %+v", i, e, w)
+		}
+		if e.ModTime.IsZero() || e.Mode == "" {
+			t.Errorf("entry[%d] is missing mode or mtime: %+v", i, e)
+		}
+	}
+}
+
+// TestListArchive_Root tests listing the container's root directory.
+// TestListArchive_Rootはコンテナのルートディレクトリの一覧をテストします。
+func TestListArchive_Root(t *testing.T) {
+	archive := []archiveEntry{
+		{name: "/", typeflag: tar.TypeDir},
+		{name: "/bin/", typeflag: tar.TypeDir},
+		{name: "/bin/app", typeflag: tar.TypeReg, body: "binary"},
+		{name: "/etc/", typeflag: tar.TypeDir},
+	}
+
+	entries, _, err := listArchive(buildArchive(t, archive), "/", maxListScanBytes, maxListEntries, maxListScanEntries)
+	if err != nil {
+		t.Fatalf("listArchive() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "bin" || entries[1].Name != "etc" {
+		t.Errorf("listArchive() = %+v, want bin and etc", entries)
+	}
+}
+
+// TestListArchive_Truncated tests that scanning stops at the byte limit.
+// TestListArchive_Truncatedはバイト上限で走査が打ち切られることをテストします。
+func TestListArchive_Truncated(t *testing.T) {
+	archive := []archiveEntry{
+		{name: "data/", typeflag: tar.TypeDir},
+		{name: "data/a.bin", typeflag: tar.TypeReg, body: strings.Repeat("x", 4096)},
+		{name: "data/b.bin", typeflag: tar.TypeReg, body: strings.Repeat("x", 4096)},
+		{name: "data/c.bin", typeflag: tar.TypeReg, body: "x"},
+	}
+
+	entries, truncated, err := listArchive(buildArchive(t, archive), "data", 2048, maxListEntries, maxListScanEntries)
+	if err != nil {
+		t.Fatalf("listArchive() error = %v", err)
+	}
+	if !truncated {
+		t.Error("Expected the listing to be truncated")
+	}
+	if len(entries) == 0 || len(entries) == 3 {
+		t.Errorf("Expected a partial listing, got %+v", entries)
+	}
+}
+
+// TestListArchive_EntryLimits tests that scanning stops at the entry caps.
+// TestListArchive_EntryLimitsはエントリの上限で走査が打ち切られることをテストします。
+func TestListArchive_EntryLimits(t *testing.T) {
+	archive := []archiveEntry{
+		{name: "app/", typeflag: tar.TypeDir},
+		{name: "app/a", typeflag: tar.TypeReg},
+		{name: "app/b/", typeflag: tar.TypeDir},
+		{name: "app/b/1", typeflag: tar.TypeReg},
+		{name: "app/b/2", typeflag: tar.TypeReg},
+		{name: "app/b/3", typeflag: tar.TypeReg},
+		{name: "app/c", typeflag: tar.TypeReg},
+	}
+
+	tests := []struct {
+		name          string // Test case name / テストケース名
+		maxEntries    int    // Cap on returned entries / 返すエントリの上限
+		maxScanned    int    // Cap on scanned entries / 走査するエントリの上限
+		wantNames     string // Expected entry names / 期待されるエントリ名
+		wantTruncated bool
+	}{
+		{"within the caps", 3, 7, "a,b,c", false},
+		{"entry cap", 2, 100, "a,b", true},
+		{"scan cap inside a subdirectory", 100, 5, "a,b", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, truncated, err := listArchive(buildArchive(t, archive), "app", maxListScanBytes, tt.maxEntries, tt.maxScanned)
+			if err != nil {
+				t.Fatalf("listArchive() error = %v", err)
+			}
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name)
+			}
+			if got := strings.Join(names, ","); got != tt.wantNames || truncated != tt.wantTruncated {
+				t.Errorf("listArchive() = %s, truncated %v; want %s, truncated %v", got, truncated, tt.wantNames, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+// TestReadRange tests byte and line ranges.
+// TestReadRangeはバイト範囲と行範囲をテストします。
+func TestReadRange(t *testing.T) {
+	content := "line 1\nline 2\nline 3\nline 4\n"
+
+	tests := []struct {
+		name      string      // Test case name / テストケース名
+		opts      ReadOptions // Range to read / 読み取る範囲
+		want      string      // Expected content / 期待される内容
+		wantStart int64       // Expected byte offset / 期待されるバイトオフセット
+		wantEOF   bool        // Expected end of file / ファイルの終わりに達することを期待
+	}{
+		{"whole file", ReadOptions{}, content, 0, true},
+		{"byte range", ReadOptions{Offset: 7, Length: 6}, "line 2", 7, false},
+		{"offset to end", ReadOptions{Offset: 21}, "line 4\n", 21, true},
+		{"offset past end", ReadOptions{Offset: 100}, "", 28, true},
+		{"first lines", ReadOptions{MaxLines: 2}, "line 1\nline 2\n", 0, false},
+		{"line range", ReadOptions{StartLine: 2, MaxLines: 2}, "line 2\nline 3\n", 7, false},
+		{"from line to end", ReadOptions{StartLine: 4}, "line 4\n", 21, true},
+		{"last line exactly", ReadOptions{StartLine: 4, MaxLines: 1}, "line 4\n", 21, true},
+		{"start past end", ReadOptions{StartLine: 10}, "", 28, true},
+		{"tail", ReadOptions{Tail: 2}, "line 3\nline 4\n", 14, true},
+		{"tail longer than file", ReadOptions{Tail: 10}, content, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr, err := readRange(strings.NewReader(content), tt.opts, 1024)
+			if err != nil {
+				t.Fatalf("readRange() error = %v", err)
+			}
+			if rr.data != tt.want || rr.truncated {
+				t.Errorf("readRange() = %q (truncated %v), want %q", rr.data, rr.truncated, tt.want)
+			}
+			if rr.start != tt.wantStart || rr.eof != tt.wantEOF {
+				t.Errorf("readRange() start = %d, eof = %v, want %d, %v", rr.start, rr.eof, tt.wantStart, tt.wantEOF)
+			}
+		})
+	}
+}
+
+// TestReadRange_Tail tests the line numbers and size limit of tail reads.
+// TestReadRange_Tailは末尾の読み取りの行番号とサイズ上限をテストします。
+func TestReadRange_Tail(t *testing.T) {
+	content := "one\ntwo\nthree\nfour"
+
+	rr, err := readRange(strings.NewReader(content), ReadOptions{Tail: 2}, 1024)
+	if err != nil {
+		t.Fatalf("readRange() error = %v", err)
+	}
+	if rr.data != "three\nfour" || rr.startLine != 3 {
+		t.Errorf("readRange(tail 2) = %q from line %d, want \"three\\nfour\" from line 3", rr.data, rr.startLine)
+	}
+
+	rr, err = readRange(strings.NewReader(content), ReadOptions{Tail: 3}, 8)
+	if err != nil {
+		t.Fatalf("readRange() error = %v", err)
+	}
+	if rr.data != "four" || !rr.truncated {
+		t.Errorf("readRange(tail 3, limit 8) = %q (truncated %v), want \"four\" truncated", rr.data, rr.truncated)
+	}
+}
+
+// TestReadRange_Cursor tests continuing byte and line reads with a cursor.
+// TestReadRange_Cursorはカーソルによるバイト単位と行単位の読み取りの継続をテストします。
+func TestReadRange_Cursor(t *testing.T) {
+	content := "line 1\nline 2\nline 3\nline 4\nline 5\n"
+
+	tests := []struct {
+		name  string      // Test case name / テストケース名
+		first ReadOptions // First read / 最初の読み取り
+		next  ReadOptions // Options for the following reads / 続きの読み取りのオプション
+		limit int64       // Size limit / サイズ上限
+	}{
+		{"lines", ReadOptions{MaxLines: 2}, ReadOptions{MaxLines: 2}, 1024},
+		{"bytes", ReadOptions{Length: 10}, ReadOptions{Length: 10}, 1024},
+		{"size limit", ReadOptions{}, ReadOptions{}, 9},
+		{"line size limit", ReadOptions{StartLine: 2}, ReadOptions{MaxLines: 1}, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			opts := tt.first
+			for i := 0; ; i++ {
+				if i > 20 {
+					t.Fatal("the read did not reach the end of the file")
+				}
+				rr, err := readRange(strings.NewReader(content), opts, tt.limit)
+				if err != nil {
+					t.Fatalf("readRange() error = %v", err)
+				}
+				got.WriteString(rr.data)
+				if rr.eof {
+					break
+				}
+				opts = tt.next
+				opts.Cursor = encodeCursor(readCursor{offset: rr.end, line: rr.endLine})
+			}
+
+			want := content
+			if tt.first.StartLine == 2 {
+				want = content[7:]
+			}
+			if got.String() != want {
+				t.Errorf("continued reads = %q, want %q", got.String(), want)
+			}
+		})
+	}
+}
+
+// TestReadRange_Cap tests that content beyond the limit is truncated and reported.
+// TestReadRange_Capは上限を超える内容が打ち切られて報告されることをテストします。
+func TestReadRange_Cap(t *testing.T) {
+	const limit = 4096
+	content := strings.Repeat("0123456789abcde\n", limit/16+10)
+
+	for _, opts := range []ReadOptions{{}, {MaxLines: 1 << 30}, {Length: 1 << 20}} {
+		rr, err := readRange(strings.NewReader(content), opts, limit)
+		if err != nil {
+			t.Fatalf("readRange(%+v) error = %v", opts, err)
+		}
+		if len(rr.data) != limit || !rr.truncated || rr.eof {
+			t.Errorf("readRange(%+v) returned %d bytes (truncated %v), want %d truncated", opts, len(rr.data), rr.truncated, limit)
+		}
+	}
+
+	// A range that ends before the limit is not truncated
+	// 上限より前に終わる範囲は打ち切りではない
+	rr, err := readRange(strings.NewReader(content), ReadOptions{Length: limit}, limit)
+	if err != nil || rr.truncated {
+		t.Errorf("readRange(length = limit) truncated %v, error %v", rr.truncated, err)
+	}
+}
+
+// TestDecodeCursor tests that malformed cursors are rejected.
+// TestDecodeCursorは不正なカーソルが拒否されることをテストします。
+func TestDecodeCursor(t *testing.T) {
+	c := readCursor{offset: 1234, line: 56}
+	if got, err := decodeCursor(encodeCursor(c)); err != nil || got != c {
+		t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v, %v", c, got, err)
+	}
+	for _, s := range []string{"not a cursor", "LTE6MA", ""} {
+		if _, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) should fail", s)
+		}
+	}
+}
+
+// TestIsBinary tests binary content detection.
+// TestIsBinaryはバイナリ内容の検出をテストします。
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name   string // Test case name / テストケース名
+		sample string // Sample / サンプル
+		want   bool   // Expected result / 期待される結果
+	}{
+		{"text", "hello\tworld\r\n", false},
+		{"utf-8", "こんにちは\n", false},
+		{"ansi colors", "\x1b[31mERROR\x1b[0m\n", false},
+		{"empty", "", false},
+		{"nul byte", "ELF\x00\x01\x02", true},
+		{"control characters", "\x01\x02\x03\x04abc", true},
+	}
+	for _, tt := range tests {
+		if got := isBinary([]byte(tt.sample)); got != tt.want {
+			t.Errorf("isBinary(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestReadOptionsValidate tests that mixed or negative ranges are rejected.
+// TestReadOptionsValidateは混在した範囲や負の範囲が拒否されることをテストします。
+func TestReadOptionsValidate(t *testing.T) {
+	cursor := encodeCursor(readCursor{offset: 10, line: 2})
+	valid := []ReadOptions{{}, {Offset: 10, Length: 5}, {StartLine: 3, MaxLines: 2}, {Tail: 20}, {Cursor: cursor, MaxLines: 5}}
+	for _, opts := range valid {
+		if err := opts.validate(); err != nil {
+			t.Errorf("validate(%+v) error = %v", opts, err)
+		}
+	}
+
+	invalid := []ReadOptions{
+		{Offset: -1}, {MaxLines: -5}, {Offset: 10, MaxLines: 2}, {Tail: -1},
+		{Tail: 5, MaxLines: 2}, {Cursor: cursor, Offset: 3}, {Cursor: "garbage"},
+	}
+	for _, opts := range invalid {
+		if err := opts.validate(); err == nil {
+			t.Errorf("validate(%+v) should fail", opts)
+		}
+	}
+}
@@ -9,6 +9,7 @@ package docker
 
 import (
 	"context"
+	"io"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -38,6 +39,13 @@ type DockerClientInterface interface {
 	// すべてのコンテナのリストを取得します。
 	ListContainers(ctx context.Context) ([]ContainerInfo, error)
 
+	// ResolveContainer resolves a container reference, which may be a Compose service
+	// ("api" or "shop/api"), to a container name. Unknown references are returned as is.
+	//
+	// ResolveContainerはComposeのサービス（"api"や"shop/api"）であり得るコンテナ参照を
+	// コンテナ名に解決します。未知の参照はそのまま返されます。
+	ResolveContainer(ctx context.Context, ref string) string
+
 	// GetLogs retrieves logs from a specified container.
 	// The since parameter filters logs to only show entries after the given timestamp
 	// (e.g., "2024-01-01T00:00:00Z") or relative time (e.g., "42m" for 42 minutes ago).
@@ -49,13 +57,51 @@ type DockerClientInterface interface {
 	// フィルタを無効にするには空文字列を渡します。
 	GetLogs(ctx context.Context, containerName string, tail string, since string, follow bool) (string, error)
 
+	// GetLogLines retrieves logs from a container as separate stdout/stderr lines.
+	// The since and until parameters bound the time window (timestamps or relative times
+	// such as "42m"); pass empty strings to disable them.
+	//
+	// GetLogLinesはコンテナのログをstdout/stderrを区別した行として取得します。
+	// sinceとuntilパラメータは時間範囲を指定します（タイムスタンプまたは"42m"のような相対時間）。
+	// 無効にするには空文字列を渡します。
+	GetLogLines(ctx context.Context, containerName string, tail string, since string, until string) ([]LogLine, error)
+
+	// FollowLogs streams logs from a container line by line until ctx is cancelled,
+	// the container stops, or onLine returns an error.
+	// The tail and since parameters select where the stream starts, as in GetLogs.
+	//
+	// FollowLogsはctxがキャンセルされる、コンテナが停止する、またはonLineがエラーを返すまで
+	// コンテナのログを1行ずつストリームします。
+	// tailとsinceパラメータはGetLogsと同様にストリームの開始位置を選択します。
+	FollowLogs(ctx context.Context, containerName string, tail string, since string, onLine func(LogLine) error) error
+
 	// GetStats retrieves resource usage statistics for a container.
 	// GetStatsはコンテナのリソース使用統計を取得します。
 	GetStats(ctx context.Context, containerName string) (*container.StatsResponse, error)
 
+	// GetEvents returns the recent container events of the allowed containers between
+	// since and until, optionally limited to one container and to some event types.
+	//
+	// GetEventsはsinceからuntilまでの許可されたコンテナの最近のコンテナイベントを
+	// 返します。1つのコンテナや一部のイベントタイプに限定することもできます。
+	GetEvents(ctx context.Context, containerName, since, until string, types []string) ([]ContainerEvent, error)
+
+	// WatchEvents calls onEvent for each new container event of the allowed containers
+	// until ctx is cancelled or the event stream fails.
+	//
+	// WatchEventsはctxがキャンセルされるかイベントストリームが失敗するまで、許可された
+	// コンテナの新しいコンテナイベントごとにonEventを呼び出します。
+	WatchEvents(ctx context.Context, onEvent func(ContainerEvent)) error
+
 	// Exec executes a whitelisted command in a container.
 	// Execはコンテナ内でホワイトリストに登録されたコマンドを実行します。
-	Exec(ctx context.Context, containerName string, command string, dangerously bool) (*ExecResult, error)
+	Exec(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions) (*ExecResult, error)
+
+	// StartExec starts a whitelisted command without waiting for it, writing its output
+	// to w as it arrives. wait returns the result once the command ends.
+	// StartExecはホワイトリストのコマンドを待たずに開始し、出力を到着次第wに書き込みます。
+	// waitはコマンドの終了後に結果を返します。
+	StartExec(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions, w io.Writer) (wait func() (*ExecResult, error), err error)
 
 	// InspectContainer retrieves detailed information about a container.
 	// InspectContainerはコンテナの詳細情報を取得します。
@@ -85,9 +131,33 @@ type DockerClientInterface interface {
 	// ListFilesはコンテナディレクトリ内のファイルを一覧表示します。
 	ListFiles(ctx context.Context, containerName string, path string) (*FileAccessResult, error)
 
-	// ReadFile reads the contents of a file from a container.
-	// ReadFileはコンテナからファイルの内容を読み取ります。
-	ReadFile(ctx context.Context, containerName string, path string, maxLines int) (*FileAccessResult, error)
+	// ReadFile reads a byte or line range of a file from a container.
+	// ReadFileはコンテナからファイルのバイト範囲または行範囲を読み取ります。
+	ReadFile(ctx context.Context, containerName string, path string, opts ReadOptions) (*FileAccessResult, error)
+
+	// FindFiles searches a container directory tree for entries by name and type.
+	// FindFilesはコンテナのディレクトリツリーから名前と種別でエントリを検索します。
+	FindFiles(ctx context.Context, containerName string, dir string, opts FindOptions) (*FileAccessResult, error)
+
+	// GrepFiles searches the files under a container directory for matching lines.
+	// GrepFilesはコンテナのディレクトリ配下のファイルからマッチする行を検索します。
+	GrepFiles(ctx context.Context, containerName string, dir string, opts GrepOptions) (*FileAccessResult, error)
+
+	// WriteFile replaces or creates a file in a container within writable_paths.
+	// WriteFileはwritable_pathsの範囲でコンテナ内のファイルを置き換えるか作成します。
+	WriteFile(ctx context.Context, containerName string, path string, content string) (*FileAccessResult, error)
+
+	// ApplyPatch applies a unified diff to a file in a container within writable_paths.
+	// ApplyPatchはwritable_pathsの範囲でコンテナ内のファイルにunified diffを適用します。
+	ApplyPatch(ctx context.Context, containerName string, path string, patch string) (*FileAccessResult, error)
+
+	// CopyFromContainer copies a container file or directory to a host workspace path.
+	// CopyFromContainerはコンテナのファイルまたはディレクトリをホストのワークスペースのパスにコピーします。
+	CopyFromContainer(ctx context.Context, containerName, src, hostPath string, overwrite bool) (*FileAccessResult, error)
+
+	// CopyToContainer copies a host workspace file or directory into a container within writable_paths.
+	// CopyToContainerはホストのワークスペースのファイルまたはディレクトリをwritable_pathsの範囲でコンテナにコピーします。
+	CopyToContainer(ctx context.Context, containerName, hostPath, dest string, overwrite bool) (*FileAccessResult, error)
 
 	// Policy and Security Operations
 	// ポリシーとセキュリティ操作
@@ -96,6 +166,16 @@ type DockerClientInterface interface {
 	// GetPolicyはこのクライアントに関連付けられたセキュリティポリシーを返します。
 	GetPolicy() *security.Policy
 
+	// ExplainExec dry-runs the exec_command checks, including the resolved file arguments
+	// of a dangerous command, without executing it.
+	// ExplainExecは危険コマンドの解決したファイル引数を含めて、実行せずにexec_commandの
+	// チェックをドライランします。
+	ExplainExec(ctx context.Context, containerName, command string, dangerously bool, opts security.ExecOptions) security.Explanation
+
+	// ExplainPath dry-runs the read_file and list_files checks, including the resolved path.
+	// ExplainPathは解決したパスを含めて、read_fileとlist_filesのチェックをドライランします。
+	ExplainPath(ctx context.Context, containerName, path string) security.Explanation
+
 	// GetAllowedCommands returns the whitelisted commands for a container.
 	// GetAllowedCommandsはコンテナのホワイトリストコマンドを返します。
 	GetAllowedCommands(containerName string) []string
@@ -0,0 +1,131 @@
+// logstream.go splits a Docker log stream into lines.
+// Containers without a TTY return a multiplexed stream where each frame carries an
+// 8-byte header (stream type + payload size); TTY containers return raw bytes.
+//
+// logstream.goはDockerのログストリームを行に分割します。
+// TTYのないコンテナは各フレームに8バイトのヘッダー（ストリーム種別 + ペイロードサイズ）を持つ
+// 多重化ストリームを返し、TTYコンテナは生のバイト列を返します。
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LogLine is a single line read from a container's log stream.
+// LogLineはコンテナのログストリームから読み取った1行です。
+type LogLine struct {
+	// Stream is "stdout" or "stderr" (always "stdout" for TTY containers)
+	// Streamは"stdout"または"stderr"です（TTYコンテナでは常に"stdout"）
+	Stream string
+
+	// Text is the line content without the trailing newline, including the Docker timestamp prefix
+	// Textは末尾の改行を除いた行の内容で、Dockerのタイムスタンプ接頭辞を含みます
+	Text string
+}
+
+// SplitTimestamp separates the RFC 3339 timestamp Docker prefixes to each line
+// (when logs are requested with timestamps) from the message.
+// ok is false when the line has no parsable timestamp, in which case message is the whole line.
+//
+// SplitTimestampは（タイムスタンプ付きでログを要求した場合に）Dockerが各行に付ける
+// RFC 3339タイムスタンプをメッセージから分離します。
+// 解析可能なタイムスタンプがない場合okはfalseで、messageは行全体になります。
+func SplitTimestamp(text string) (ts time.Time, message string, ok bool) {
+	prefix, rest, found := strings.Cut(text, " ")
+	if !found {
+		prefix, rest = text, ""
+	}
+	ts, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, text, false
+	}
+	return ts, rest, true
+}
+
+// Multiplexed stream types from the Docker frame header.
+// Dockerフレームヘッダーの多重化ストリーム種別です。
+const (
+	streamTypeStdout    = 1
+	streamTypeStderr    = 2
+	streamTypeSystemErr = 3
+)
+
+// readLogLines reads r until EOF and calls onLine for every complete line.
+// When tty is false, r is demultiplexed according to the Docker frame format.
+// A partial last line is delivered at EOF. Reading stops early if onLine returns an error.
+//
+// readLogLinesはrをEOFまで読み取り、完全な行ごとにonLineを呼び出します。
+// ttyがfalseの場合、rはDockerのフレーム形式に従って多重分離されます。
+// 最後の不完全な行はEOF時に配信されます。onLineがエラーを返すと読み取りを早期に終了します。
+func readLogLines(r io.Reader, tty bool, onLine func(LogLine) error) error {
+	if tty {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			if err := onLine(LogLine{Stream: "stdout", Text: strings.TrimSuffix(scanner.Text(), "\r")}); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+
+	// Pending partial lines per stream, since a frame may end mid-line
+	// フレームが行の途中で終わることがあるため、ストリームごとに未完了の行を保持
+	pending := map[string]*bytes.Buffer{"stdout": {}, "stderr": {}}
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				for _, stream := range []string{"stdout", "stderr"} {
+					if buf := pending[stream]; buf.Len() > 0 {
+						if err := onLine(LogLine{Stream: stream, Text: buf.String()}); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			}
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(header[4:8])
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+
+		var stream string
+		switch header[0] {
+		case streamTypeStdout:
+			stream = "stdout"
+		case streamTypeStderr:
+			stream = "stderr"
+		case streamTypeSystemErr:
+			return fmt.Errorf("docker log stream error: %s", strings.TrimSpace(string(payload)))
+		default:
+			// Ignore stdin or unknown frames
+			// stdinや不明なフレームは無視
+			continue
+		}
+
+		buf := pending[stream]
+		buf.Write(payload)
+		for {
+			idx := bytes.IndexByte(buf.Bytes(), '\n')
+			if idx < 0 {
+				break
+			}
+			line := strings.TrimSuffix(string(buf.Next(idx + 1)[:idx]), "\r")
+			if err := onLine(LogLine{Stream: stream, Text: line}); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// logstream_test.go contains tests for splitting Docker log streams into lines,
+// covering both the multiplexed (non-TTY) frame format and raw TTY output.
+//
+// logstream_test.goはDockerログストリームを行に分割する処理のテストを含みます。
+// 多重化（非TTY）フレーム形式と生のTTY出力の両方を対象とします。
+package docker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// frame builds one multiplexed log frame with the given stream type and payload.
+// frameは指定されたストリーム種別とペイロードで多重化ログフレームを1つ構築します。
+func frame(streamType byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = streamType
+	binary.BigEndian.PutUint32(header[4:8], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+// TestReadLogLines tests line splitting for multiplexed and TTY streams.
+// TestReadLogLinesは多重化ストリームとTTYストリームの行分割をテストします。
+func TestReadLogLines(t *testing.T) {
+	tests := []struct {
+		name     string    // Test case name / テストケース名
+		input    []byte    // Raw stream / 生のストリーム
+		tty      bool      // Whether the container has a TTY / コンテナがTTYを持つか
+		expected []LogLine // Expected lines / 期待される行
+	}{
+		{
+			name:  "multiplexed stdout and stderr",
+			input: append(frame(streamTypeStdout, "out 1\n"), frame(streamTypeStderr, "err 1\n")...),
+			expected: []LogLine{
+				{Stream: "stdout", Text: "out 1"},
+				{Stream: "stderr", Text: "err 1"},
+			},
+		},
+		{
+			name:  "line split across frames",
+			input: append(frame(streamTypeStdout, "hel"), frame(streamTypeStdout, "lo\nworld\n")...),
+			expected: []LogLine{
+				{Stream: "stdout", Text: "hello"},
+				{Stream: "stdout", Text: "world"},
+			},
+		},
+		{
+			name:  "partial last line delivered at EOF",
+			input: frame(streamTypeStdout, "done\nno newline"),
+			expected: []LogLine{
+				{Stream: "stdout", Text: "done"},
+				{Stream: "stdout", Text: "no newline"},
+			},
+		},
+		{
+			name:  "TTY stream with CRLF",
+			input: []byte("line 1\r\nline 2\n"),
+			tty:   true,
+			expected: []LogLine{
+				{Stream: "stdout", Text: "line 1"},
+				{Stream: "stdout", Text: "line 2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []LogLine
+			err := readLogLines(bytes.NewReader(tt.input), tt.tty, func(line LogLine) error {
+				got = append(got, line)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readLogLines() error = %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("readLogLines() returned %d lines, want %d: got %v", len(got), len(tt.expected), got)
+			}
+			for i, line := range got {
+				if line != tt.expected[i] {
+					t.Errorf("line[%d] = %+v, want %+v", i, line, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+// TestReadLogLines_Errors tests that system error frames and callback errors stop reading.
+// TestReadLogLines_Errorsはシステムエラーフレームとコールバックのエラーで読み取りが停止することをテストします。
+func TestReadLogLines_Errors(t *testing.T) {
+	t.Run("system error frame", func(t *testing.T) {
+		err := readLogLines(bytes.NewReader(frame(streamTypeSystemErr, "boom\n")), false, func(LogLine) error { return nil })
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Errorf("Expected stream error containing 'boom', got %v", err)
+		}
+	})
+
+	t.Run("callback error stops reading", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		input := append(frame(streamTypeStdout, "a\nb\n"), frame(streamTypeStdout, "c\n")...)
+		err := readLogLines(bytes.NewReader(input), false, func(LogLine) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) {
+			t.Errorf("Expected callback error, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("Expected 1 callback call, got %d", calls)
+		}
+	})
+}
+
+// TestSplitTimestamp tests separating Docker timestamps from log messages.
+// TestSplitTimestampはDockerのタイムスタンプとログメッセージの分離をテストします。
+func TestSplitTimestamp(t *testing.T) {
+	tests := []struct {
+		name        string // Test case name / テストケース名
+		text        string // Log line / ログ行
+		wantOK      bool   // Whether a timestamp is found / タイムスタンプが見つかるか
+		wantTime    string // Expected timestamp (RFC 3339) / 期待されるタイムスタンプ
+		wantMessage string // Expected message / 期待されるメッセージ
+	}{
+		{"nanosecond timestamp", "2024-01-01T10:00:00.123456789Z hello world", true, "2024-01-01T10:00:00.123456789Z", "hello world"},
+		{"second timestamp", "2024-01-01T10:00:00Z hello", true, "2024-01-01T10:00:00Z", "hello"},
+		{"timestamp only", "2024-01-01T10:00:00Z", true, "2024-01-01T10:00:00Z", ""},
+		{"no timestamp", "plain line", false, "", "plain line"},
+		{"empty line", "", false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, message, ok := SplitTimestamp(tt.text)
+			if ok != tt.wantOK || message != tt.wantMessage {
+				t.Fatalf("SplitTimestamp(%q) = (%v, %q, %v), want ok=%v message=%q", tt.text, ts, message, ok, tt.wantOK, tt.wantMessage)
+			}
+			if ok && ts.Format(time.RFC3339Nano) != tt.wantTime {
+				t.Errorf("SplitTimestamp(%q) time = %s, want %s", tt.text, ts.Format(time.RFC3339Nano), tt.wantTime)
+			}
+		})
+	}
+}
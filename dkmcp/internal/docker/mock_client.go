@@ -10,6 +10,8 @@ package docker
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -35,13 +37,37 @@ type MockClient struct {
 	// GetLogsFuncが設定されている場合、GetLogsから呼び出されます。
 	GetLogsFunc func(ctx context.Context, containerName string, tail string, since string, follow bool) (string, error)
 
+	// GetLogLinesFunc is called by GetLogLines if set.
+	// GetLogLinesFuncが設定されている場合、GetLogLinesから呼び出されます。
+	GetLogLinesFunc func(ctx context.Context, containerName string, tail string, since string, until string) ([]LogLine, error)
+
+	// FollowLogsFunc is called by FollowLogs if set.
+	// FollowLogsFuncが設定されている場合、FollowLogsから呼び出されます。
+	FollowLogsFunc func(ctx context.Context, containerName string, tail string, since string, onLine func(LogLine) error) error
+
 	// GetStatsFunc is called by GetStats if set.
 	// GetStatsFuncが設定されている場合、GetStatsから呼び出されます。
 	GetStatsFunc func(ctx context.Context, containerName string) (*container.StatsResponse, error)
 
+	// GetEventsFunc is called by GetEvents if set.
+	// GetEventsFuncが設定されている場合、GetEventsから呼び出されます。
+	GetEventsFunc func(ctx context.Context, containerName, since, until string, types []string) ([]ContainerEvent, error)
+
+	// ResolveContainerFunc is called by ResolveContainer if set.
+	// ResolveContainerFuncが設定されている場合、ResolveContainerから呼び出されます。
+	ResolveContainerFunc func(ctx context.Context, ref string) string
+
+	// WatchEventsFunc is called by WatchEvents if set.
+	// WatchEventsFuncが設定されている場合、WatchEventsから呼び出されます。
+	WatchEventsFunc func(ctx context.Context, onEvent func(ContainerEvent)) error
+
 	// ExecFunc is called by Exec if set.
 	// ExecFuncが設定されている場合、Execから呼び出されます。
-	ExecFunc func(ctx context.Context, containerName string, command string, dangerously bool) (*ExecResult, error)
+	ExecFunc func(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions) (*ExecResult, error)
+
+	// StartExecFunc is called by StartExec if set.
+	// StartExecFuncが設定されている場合、StartExecから呼び出されます。
+	StartExecFunc func(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions, w io.Writer) (func() (*ExecResult, error), error)
 
 	// InspectContainerFunc is called by InspectContainer if set.
 	// InspectContainerFuncが設定されている場合、InspectContainerから呼び出されます。
@@ -65,7 +91,39 @@ type MockClient struct {
 
 	// ReadFileFunc is called by ReadFile if set.
 	// ReadFileFuncが設定されている場合、ReadFileから呼び出されます。
-	ReadFileFunc func(ctx context.Context, containerName string, path string, maxLines int) (*FileAccessResult, error)
+	ReadFileFunc func(ctx context.Context, containerName string, path string, opts ReadOptions) (*FileAccessResult, error)
+
+	// FindFilesFunc is called by FindFiles if set.
+	// FindFilesFuncが設定されている場合、FindFilesから呼び出されます。
+	FindFilesFunc func(ctx context.Context, containerName string, dir string, opts FindOptions) (*FileAccessResult, error)
+
+	// GrepFilesFunc is called by GrepFiles if set.
+	// GrepFilesFuncが設定されている場合、GrepFilesから呼び出されます。
+	GrepFilesFunc func(ctx context.Context, containerName string, dir string, opts GrepOptions) (*FileAccessResult, error)
+
+	// WriteFileFunc is called by WriteFile if set.
+	// WriteFileFuncが設定されている場合、WriteFileから呼び出されます。
+	WriteFileFunc func(ctx context.Context, containerName string, path string, content string) (*FileAccessResult, error)
+
+	// ApplyPatchFunc is called by ApplyPatch if set.
+	// ApplyPatchFuncが設定されている場合、ApplyPatchから呼び出されます。
+	ApplyPatchFunc func(ctx context.Context, containerName string, path string, patch string) (*FileAccessResult, error)
+
+	// CopyFromContainerFunc is called by CopyFromContainer if set.
+	// CopyFromContainerFuncが設定されている場合、CopyFromContainerから呼び出されます。
+	CopyFromContainerFunc func(ctx context.Context, containerName, src, hostPath string, overwrite bool) (*FileAccessResult, error)
+
+	// CopyToContainerFunc is called by CopyToContainer if set.
+	// CopyToContainerFuncが設定されている場合、CopyToContainerから呼び出されます。
+	CopyToContainerFunc func(ctx context.Context, containerName, hostPath, dest string, overwrite bool) (*FileAccessResult, error)
+
+	// ExplainExecFunc is called by ExplainExec if set.
+	// ExplainExecFuncが設定されている場合、ExplainExecから呼び出されます。
+	ExplainExecFunc func(ctx context.Context, containerName, command string, dangerously bool, opts security.ExecOptions) security.Explanation
+
+	// ExplainPathFunc is called by ExplainPath if set.
+	// ExplainPathFuncが設定されている場合、ExplainPathから呼び出されます。
+	ExplainPathFunc func(ctx context.Context, containerName, path string) security.Explanation
 
 	// policy is the security policy used by this mock client.
 	// policyはこのモッククライアントが使用するセキュリティポリシーです。
@@ -107,6 +165,41 @@ func (m *MockClient) GetLogs(ctx context.Context, containerName string, tail str
 	return "", fmt.Errorf("GetLogs not implemented in mock")
 }
 
+// GetLogLines returns the result of GetLogLinesFunc if set. Otherwise, if GetLogsFunc
+// is set, its output is split into stdout lines; if neither is set it returns an error.
+//
+// GetLogLinesはGetLogLinesFuncが設定されている場合はその結果を返します。そうでなく
+// GetLogsFuncが設定されている場合はその出力をstdoutの行に分割し、どちらもなければエラーを返します。
+func (m *MockClient) GetLogLines(ctx context.Context, containerName string, tail string, since string, until string) ([]LogLine, error) {
+	if m.GetLogLinesFunc != nil {
+		return m.GetLogLinesFunc(ctx, containerName, tail, since, until)
+	}
+	if m.GetLogsFunc != nil {
+		logs, err := m.GetLogsFunc(ctx, containerName, tail, since, false)
+		if err != nil {
+			return nil, err
+		}
+		var lines []LogLine
+		for _, text := range strings.Split(strings.TrimSuffix(logs, "\n"), "\n") {
+			lines = append(lines, LogLine{Stream: "stdout", Text: text})
+		}
+		return lines, nil
+	}
+	return nil, fmt.Errorf("GetLogLines not implemented in mock")
+}
+
+// FollowLogs returns the result of FollowLogsFunc if set,
+// otherwise returns an error.
+//
+// FollowLogsはFollowLogsFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) FollowLogs(ctx context.Context, containerName string, tail string, since string, onLine func(LogLine) error) error {
+	if m.FollowLogsFunc != nil {
+		return m.FollowLogsFunc(ctx, containerName, tail, since, onLine)
+	}
+	return fmt.Errorf("FollowLogs not implemented in mock")
+}
+
 // GetStats returns the result of GetStatsFunc if set,
 // otherwise returns an error.
 //
@@ -119,18 +212,67 @@ func (m *MockClient) GetStats(ctx context.Context, containerName string) (*conta
 	return nil, fmt.Errorf("GetStats not implemented in mock")
 }
 
+// GetEvents returns the result of GetEventsFunc if set,
+// otherwise returns an error.
+//
+// GetEventsはGetEventsFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) GetEvents(ctx context.Context, containerName, since, until string, types []string) ([]ContainerEvent, error) {
+	if m.GetEventsFunc != nil {
+		return m.GetEventsFunc(ctx, containerName, since, until, types)
+	}
+	return nil, fmt.Errorf("GetEvents not implemented in mock")
+}
+
+// ResolveContainer returns the result of ResolveContainerFunc if set,
+// otherwise returns ref unchanged.
+//
+// ResolveContainerはResolveContainerFuncが設定されている場合はその結果を返し、
+// そうでなければrefをそのまま返します。
+func (m *MockClient) ResolveContainer(ctx context.Context, ref string) string {
+	if m.ResolveContainerFunc != nil {
+		return m.ResolveContainerFunc(ctx, ref)
+	}
+	return ref
+}
+
+// WatchEvents returns the result of WatchEventsFunc if set,
+// otherwise blocks until ctx is cancelled.
+//
+// WatchEventsはWatchEventsFuncが設定されている場合はその結果を返し、
+// そうでなければctxがキャンセルされるまでブロックします。
+func (m *MockClient) WatchEvents(ctx context.Context, onEvent func(ContainerEvent)) error {
+	if m.WatchEventsFunc != nil {
+		return m.WatchEventsFunc(ctx, onEvent)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
 // Exec returns the result of ExecFunc if set,
 // otherwise returns an error.
 //
 // ExecはExecFuncが設定されている場合はその結果を返し、
 // そうでなければエラーを返します。
-func (m *MockClient) Exec(ctx context.Context, containerName string, command string, dangerously bool) (*ExecResult, error) {
+func (m *MockClient) Exec(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions) (*ExecResult, error) {
 	if m.ExecFunc != nil {
-		return m.ExecFunc(ctx, containerName, command, dangerously)
+		return m.ExecFunc(ctx, containerName, command, dangerously, opts)
 	}
 	return nil, fmt.Errorf("Exec not implemented in mock")
 }
 
+// StartExec returns the result of StartExecFunc if set,
+// otherwise returns an error.
+//
+// StartExecはStartExecFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) StartExec(ctx context.Context, containerName string, command string, dangerously bool, opts security.ExecOptions, w io.Writer) (func() (*ExecResult, error), error) {
+	if m.StartExecFunc != nil {
+		return m.StartExecFunc(ctx, containerName, command, dangerously, opts, w)
+	}
+	return nil, fmt.Errorf("StartExec not implemented in mock")
+}
+
 // InspectContainer returns the result of InspectContainerFunc if set,
 // otherwise returns an error.
 //
@@ -187,13 +329,85 @@ func (m *MockClient) ListFiles(ctx context.Context, containerName string, path s
 //
 // ReadFileはReadFileFuncが設定されている場合はその結果を返し、
 // そうでなければエラーを返します。
-func (m *MockClient) ReadFile(ctx context.Context, containerName string, path string, maxLines int) (*FileAccessResult, error) {
+func (m *MockClient) ReadFile(ctx context.Context, containerName string, path string, opts ReadOptions) (*FileAccessResult, error) {
 	if m.ReadFileFunc != nil {
-		return m.ReadFileFunc(ctx, containerName, path, maxLines)
+		return m.ReadFileFunc(ctx, containerName, path, opts)
 	}
 	return nil, fmt.Errorf("ReadFile not implemented in mock")
 }
 
+// FindFiles returns the result of FindFilesFunc if set,
+// otherwise returns an error.
+//
+// FindFilesはFindFilesFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) FindFiles(ctx context.Context, containerName string, dir string, opts FindOptions) (*FileAccessResult, error) {
+	if m.FindFilesFunc != nil {
+		return m.FindFilesFunc(ctx, containerName, dir, opts)
+	}
+	return nil, fmt.Errorf("FindFiles not implemented in mock")
+}
+
+// GrepFiles returns the result of GrepFilesFunc if set,
+// otherwise returns an error.
+//
+// GrepFilesはGrepFilesFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) GrepFiles(ctx context.Context, containerName string, dir string, opts GrepOptions) (*FileAccessResult, error) {
+	if m.GrepFilesFunc != nil {
+		return m.GrepFilesFunc(ctx, containerName, dir, opts)
+	}
+	return nil, fmt.Errorf("GrepFiles not implemented in mock")
+}
+
+// WriteFile returns the result of WriteFileFunc if set,
+// otherwise returns an error.
+//
+// WriteFileはWriteFileFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) WriteFile(ctx context.Context, containerName string, path string, content string) (*FileAccessResult, error) {
+	if m.WriteFileFunc != nil {
+		return m.WriteFileFunc(ctx, containerName, path, content)
+	}
+	return nil, fmt.Errorf("WriteFile not implemented in mock")
+}
+
+// ApplyPatch returns the result of ApplyPatchFunc if set,
+// otherwise returns an error.
+//
+// ApplyPatchはApplyPatchFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) ApplyPatch(ctx context.Context, containerName string, path string, patch string) (*FileAccessResult, error) {
+	if m.ApplyPatchFunc != nil {
+		return m.ApplyPatchFunc(ctx, containerName, path, patch)
+	}
+	return nil, fmt.Errorf("ApplyPatch not implemented in mock")
+}
+
+// CopyFromContainer returns the result of CopyFromContainerFunc if set,
+// otherwise returns an error.
+//
+// CopyFromContainerはCopyFromContainerFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) CopyFromContainer(ctx context.Context, containerName, src, hostPath string, overwrite bool) (*FileAccessResult, error) {
+	if m.CopyFromContainerFunc != nil {
+		return m.CopyFromContainerFunc(ctx, containerName, src, hostPath, overwrite)
+	}
+	return nil, fmt.Errorf("CopyFromContainer not implemented in mock")
+}
+
+// CopyToContainer returns the result of CopyToContainerFunc if set,
+// otherwise returns an error.
+//
+// CopyToContainerはCopyToContainerFuncが設定されている場合はその結果を返し、
+// そうでなければエラーを返します。
+func (m *MockClient) CopyToContainer(ctx context.Context, containerName, hostPath, dest string, overwrite bool) (*FileAccessResult, error) {
+	if m.CopyToContainerFunc != nil {
+		return m.CopyToContainerFunc(ctx, containerName, hostPath, dest, overwrite)
+	}
+	return nil, fmt.Errorf("CopyToContainer not implemented in mock")
+}
+
 // GetPolicy returns the security policy associated with this mock client.
 //
 // GetPolicyはこのモッククライアントに関連付けられたセキュリティポリシーを返します。
@@ -201,6 +415,30 @@ func (m *MockClient) GetPolicy() *security.Policy {
 	return m.policy
 }
 
+// ExplainExec returns the result of ExplainExecFunc if set, otherwise the policy's
+// explanation, which does not resolve paths in a container.
+//
+// ExplainExecはExplainExecFuncが設定されている場合はその結果を返し、そうでなければ
+// コンテナ内のパスを解決しないポリシーの説明を返します。
+func (m *MockClient) ExplainExec(ctx context.Context, containerName, command string, dangerously bool, opts security.ExecOptions) security.Explanation {
+	if m.ExplainExecFunc != nil {
+		return m.ExplainExecFunc(ctx, containerName, command, dangerously, opts)
+	}
+	return m.policy.ExplainExec(containerName, command, dangerously, opts)
+}
+
+// ExplainPath returns the result of ExplainPathFunc if set, otherwise the policy's
+// explanation, which does not resolve the path in a container.
+//
+// ExplainPathはExplainPathFuncが設定されている場合はその結果を返し、そうでなければ
+// コンテナ内のパスを解決しないポリシーの説明を返します。
+func (m *MockClient) ExplainPath(ctx context.Context, containerName, path string) security.Explanation {
+	if m.ExplainPathFunc != nil {
+		return m.ExplainPathFunc(ctx, containerName, path)
+	}
+	return m.policy.ExplainPath(containerName, path)
+}
+
 // GetAllowedCommands returns the whitelisted commands for a container.
 // Delegates to the policy if available.
 //
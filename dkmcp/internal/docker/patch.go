@@ -0,0 +1,385 @@
+// patch.go applies and produces unified diffs for apply_patch and write_file. Patches
+// are applied hunk by hunk; a hunk's context must match exactly, but it may have moved
+// by a few lines, as with patch(1) without fuzz. The diff of every write is returned and
+// audit-logged so that changes made by the AI can be reviewed and reverted.
+//
+// patch.goはapply_patchとwrite_file用にunified diffを適用・生成します。パッチはハンク
+// ごとに適用され、ハンクのコンテキストは完全に一致する必要がありますが、fuzzなしの
+// patch(1)と同様に数行ずれていても構いません。すべての書き込みのdiffは返却され監査ログに
+// 記録されるため、AIによる変更をレビューして元に戻すことができます。
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change.
+	// diffContextLinesは各変更の前後に表示する変更されていない行の数です。
+	diffContextLines = 3
+
+	// maxDiffCells bounds the line comparison table; larger changed regions are shown
+	// as a whole replacement instead of a minimal diff.
+	//
+	// maxDiffCellsは行の比較表の大きさを制限します。より大きな変更領域は最小のdiffではなく
+	// 全体の置き換えとして表示されます。
+	maxDiffCells = 1 << 22
+
+	// noNewlineMarker marks a last line without a trailing newline in a unified diff.
+	// noNewlineMarkerはunified diffで末尾に改行のない最終行を示します。
+	noNewlineMarker = `\ No newline at end of file`
+)
+
+// hunkHeader matches "@@ -start,count +start,count @@" (counts are optional).
+// hunkHeaderは"@@ -start,count +start,count @@"にマッチします（countは省略可能）。
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// diffOp is one line of an edit script: ' ' (kept), '-' (removed) or '+' (added).
+// diffOpは編集スクリプトの1行です：' '（維持）、'-'（削除）、'+'（追加）。
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// hunk is a parsed hunk of a unified diff.
+// hunkはunified diffの解析済みのハンクです。
+type hunk struct {
+	oldStart int      // First old line, 1-based / 変更前の最初の行（1始まり）
+	old      []string // Context and removed lines / コンテキストと削除される行
+	new      []string // Context and added lines / コンテキストと追加される行
+}
+
+// splitLines splits s into lines that keep their "\n"; only the last may lack it.
+// splitLinesはsを"\n"を保持した行に分割します。最後の行のみ"\n"を欠く場合があります。
+func splitLines(s string) []string {
+	var lines []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// unifiedDiff returns the unified diff from old to new for the file name, or "" when
+// they are equal.
+//
+// unifiedDiffはファイルnameについてoldからnewへのunified diffを返し、
+// 等しい場合は""を返します。
+func unifiedDiff(name, old, new string) string {
+	if old == new {
+		return ""
+	}
+	ops := diffLines(splitLines(old), splitLines(new))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a%s\n+++ b%s\n", name, name)
+
+	// Line numbers before each op / 各opの前の行番号
+	oldNo := make([]int, len(ops)+1)
+	newNo := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldNo[i+1], newNo[i+1] = oldNo[i], newNo[i]
+		if op.kind != '+' {
+			oldNo[i+1]++
+		}
+		if op.kind != '-' {
+			newNo[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// Extend the hunk over changes separated by at most twice the context
+		// コンテキストの2倍以内で隔てられた変更までハンクを広げる
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				break
+			}
+			end = next
+		}
+
+		start := max(i-diffContextLines, 0)
+		stop := min(end+diffContextLines, len(ops))
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(oldNo[start], oldNo[stop]-oldNo[start]),
+			hunkRange(newNo[start], newNo[stop]-newNo[start]))
+		for _, op := range ops[start:stop] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				b.WriteString("\n" + noNewlineMarker + "\n")
+			}
+		}
+		i = stop
+	}
+	return b.String()
+}
+
+// hunkRange formats the range of a hunk header from the lines before it and its count.
+// hunkRangeはハンクの前の行数と行数からハンクヘッダーの範囲を整形します。
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// diffLines returns an edit script turning a into b. The common prefix and suffix are
+// kept; the rest is compared by longest common subsequence when small enough.
+//
+// diffLinesはaをbに変える編集スクリプトを返します。共通の先頭と末尾は維持し、
+// 残りは十分に小さい場合に最長共通部分列で比較します。
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(am)*len(bm) <= maxDiffCells {
+		ops = append(ops, lcsDiff(am, bm)...)
+	} else {
+		for _, line := range am {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range bm {
+			ops = append(ops, diffOp{'+', line})
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// lcsDiff returns a minimal edit script from a to b using a longest common subsequence table.
+// lcsDiffは最長共通部分列の表を使ってaからbへの最小の編集スクリプトを返します。
+func lcsDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	// lcs[i*(m+1)+j] is the LCS length of a[i:] and b[j:]
+	// lcs[i*(m+1)+j]はa[i:]とb[j:]のLCSの長さ
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else {
+				lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// parsePatch parses the hunks of a unified diff for a single file. File headers
+// ("---", "+++", "diff ...") and other lines outside hunks are ignored.
+//
+// parsePatchは単一ファイルのunified diffのハンクを解析します。ファイルヘッダー
+// （"---"、"+++"、"diff ..."）やハンク外のその他の行は無視します。
+func parsePatch(patch string) ([]hunk, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var hunks []hunk
+	for i := 0; i < len(lines); i++ {
+		m := hunkHeader.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		h := hunk{oldStart: atoiDefault(m[1], 0)}
+		oldCount, newCount := atoiDefault(m[2], 1), atoiDefault(m[4], 1)
+
+		// The counts in the header tell where the hunk ends
+		// ヘッダーの行数がハンクの終わりを示す
+		var last *string
+		for oldCount > 0 || newCount > 0 || (i+1 < len(lines) && lines[i+1] == noNewlineMarker) {
+			i++
+			if i == len(lines) {
+				return nil, fmt.Errorf("hunk %d is truncated", len(hunks)+1)
+			}
+			line := lines[i]
+			if line == noNewlineMarker {
+				if last != nil {
+					*last = strings.TrimSuffix(*last, "\n")
+				}
+				continue
+			}
+			kind, text := byte(' '), ""
+			if line != "" {
+				kind, text = line[0], line[1:]
+			}
+			text += "\n"
+			switch kind {
+			case ' ':
+				if oldCount == 0 || newCount == 0 {
+					return nil, fmt.Errorf("hunk %d has more lines than its header says", len(hunks)+1)
+				}
+				h.old = append(h.old, text)
+				h.new = append(h.new, text)
+				oldCount--
+				newCount--
+				last = nil
+			case '-':
+				if oldCount == 0 {
+					return nil, fmt.Errorf("hunk %d has more lines than its header says", len(hunks)+1)
+				}
+				h.old = append(h.old, text)
+				oldCount--
+				last = &h.old[len(h.old)-1]
+			case '+':
+				if newCount == 0 {
+					return nil, fmt.Errorf("hunk %d has more lines than its header says", len(hunks)+1)
+				}
+				h.new = append(h.new, text)
+				newCount--
+				last = &h.new[len(h.new)-1]
+			default:
+				return nil, fmt.Errorf("hunk %d has an invalid line: %q", len(hunks)+1, line)
+			}
+			// A context line without newline ends both sides
+			// 改行のないコンテキスト行は両方の側を終える
+			if kind == ' ' && i+1 < len(lines) && lines[i+1] == noNewlineMarker {
+				h.old[len(h.old)-1] = strings.TrimSuffix(text, "\n")
+				h.new[len(h.new)-1] = strings.TrimSuffix(text, "\n")
+				i++
+			}
+		}
+		hunks = append(hunks, h)
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch contains no hunks (expected unified diff format with @@ headers)")
+	}
+	return hunks, nil
+}
+
+// applyPatch applies a unified diff to content. Each hunk must match exactly, at the
+// line its header gives or the nearest line after the previous hunk where it does.
+//
+// applyPatchはunified diffをcontentに適用します。各ハンクは、ヘッダーが示す行、
+// または前のハンクより後で一致する最も近い行で、完全に一致する必要があります。
+func applyPatch(content, patch string) (string, error) {
+	hunks, err := parsePatch(patch)
+	if err != nil {
+		return "", err
+	}
+
+	src := splitLines(content)
+	var out []string
+	cursor, offset := 0, 0
+	for n, h := range hunks {
+		want := h.oldStart - 1 + offset
+		if len(h.old) == 0 {
+			// Pure additions are placed after the given line
+			// 追加のみの場合は指定された行の後に配置する
+			want = h.oldStart + offset
+		}
+		at := findHunk(src, h.old, cursor, want)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %d (@@ -%d) does not apply: its context does not match the file", n+1, h.oldStart)
+		}
+		out = append(out, src[cursor:at]...)
+		out = append(out, h.new...)
+		cursor = at + len(h.old)
+		offset = at - (want - offset)
+	}
+	out = append(out, src[cursor:]...)
+	return strings.Join(out, ""), nil
+}
+
+// findHunk returns the line at or after from where old matches src, closest to want,
+// or -1 if it matches nowhere.
+//
+// findHunkはfrom以降でoldがsrcに一致する行のうちwantに最も近いものを返し、
+// どこにも一致しない場合は-1を返します。
+func findHunk(src, old []string, from, want int) int {
+	want = min(max(want, from), len(src))
+	for d := 0; want-d >= from || want+d <= len(src); d++ {
+		for _, at := range []int{want - d, want + d} {
+			if at < from || at+len(old) > len(src) {
+				continue
+			}
+			if linesEqual(src[at:at+len(old)], old) {
+				return at
+			}
+		}
+	}
+	return -1
+}
+
+// linesEqual reports whether two line slices are equal.
+// linesEqualは2つの行のスライスが等しいかどうかを報告します。
+func linesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// atoiDefault parses s, returning def when it is empty.
+// atoiDefaultはsを解析し、空の場合はdefを返します。
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,223 @@
+// patch_test.go contains tests for producing and applying unified diffs.
+// patch_test.goはunified diffの生成と適用のテストを含みます。
+package docker
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns n lines "line 1\n" ... "line n\n".
+// numberedLinesはn行の"line 1\n" ... "line n\n"を返します。
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d\n", i+1)
+	}
+	return lines
+}
+
+// TestUnifiedDiff tests the hunks and headers of generated diffs.
+// TestUnifiedDiffは生成したdiffのハンクとヘッダーをテストします。
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string // Test case name / テストケース名
+		old  string // Old content / 変更前の内容
+		new  string // New content / 変更後の内容
+		want string // Expected diff / 期待されるdiff
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "new file",
+			old:  "",
+			new:  "a\n",
+			want: "--- a/f\n+++ b/f\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+		{
+			name: "missing final newline",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("/f", tt.old, tt.new); got != tt.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyPatch tests applying hunks, including moved hunks and failures.
+// TestApplyPatchはずれたハンクや失敗を含むハンクの適用をテストします。
+func TestApplyPatch(t *testing.T) {
+	content := "port: 8080\nhost: localhost\ndebug: false\nworkers: 4\n"
+
+	tests := []struct {
+		name    string // Test case name / テストケース名
+		content string // Content to patch / パッチを当てる内容
+		patch   string // Unified diff / unified diff
+		want    string // Expected content / 期待される内容
+		wantErr string // Expected error substring / 期待されるエラーの部分文字列
+	}{
+		{
+			name:    "single hunk with headers",
+			content: content,
+			patch:   "--- a/app/config.yaml\n+++ b/app/config.yaml\n@@ -2,2 +2,2 @@\n host: localhost\n-debug: false\n+debug: true\n",
+			want:    "port: 8080\nhost: localhost\ndebug: true\nworkers: 4\n",
+		},
+		{
+			name:    "moved hunk",
+			content: "# comment\n# comment\n" + content,
+			patch:   "@@ -2,2 +2,2 @@\n host: localhost\n-debug: false\n+debug: true\n",
+			want:    "# comment\n# comment\nport: 8080\nhost: localhost\ndebug: true\nworkers: 4\n",
+		},
+		{
+			name:    "append at end",
+			content: content,
+			patch:   "@@ -4,0 +5,1 @@\n+timeout: 30\n",
+			want:    content + "timeout: 30\n",
+		},
+		{
+			name:    "add final newline",
+			content: "a\nb",
+			patch:   "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			want:    "a\nb\n",
+		},
+		{
+			name:    "context mismatch",
+			content: content,
+			patch:   "@@ -2,2 +2,2 @@\n host: example.com\n-debug: false\n+debug: true\n",
+			wantErr: "does not apply",
+		},
+		{
+			name:    "no hunks",
+			content: content,
+			patch:   "debug: true\n",
+			wantErr: "no hunks",
+		},
+		{
+			name:    "truncated hunk",
+			content: content,
+			patch:   "@@ -2,3 +2,3 @@\n host: localhost\n",
+			wantErr: "truncated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(tt.content, tt.patch)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyPatch() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPatch() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("applyPatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyPatch_RoundTrip tests that applying a generated diff reproduces the new content.
+// TestApplyPatch_RoundTripは生成したdiffの適用で変更後の内容が再現されることをテストします。
+func TestApplyPatch_RoundTrip(t *testing.T) {
+	lines := numberedLines(60)
+	old := strings.Join(lines, "")
+	changed := append([]string{}, lines...)
+	changed[0] = "first\n"
+	changed[30] = "middle\n"
+	changed = append(changed[:45], changed[47:]...)
+	changed = append(changed, "last")
+	new := strings.Join(changed, "")
+
+	got, err := applyPatch(old, unifiedDiff("/f", old, new))
+	if err != nil {
+		t.Fatalf("applyPatch() error = %v", err)
+	}
+	if got != new {
+		t.Errorf("round trip mismatch:\n%q\nwant\n%q", got, new)
+	}
+}
+
+// TestFileArchive tests that uploaded files keep the mode and owner of the original.
+// TestFileArchiveはアップロードするファイルが元のモードと所有者を維持することをテストします。
+func TestFileArchive(t *testing.T) {
+	buf, err := fileArchive("config.yaml", &tar.Header{Mode: 0600, Uid: 1000, Gid: 1000}, "port: 8080\n")
+	if err != nil {
+		t.Fatalf("fileArchive() error = %v", err)
+	}
+
+	tr := tar.NewReader(buf)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if hdr.Name != "config.yaml" || hdr.Mode != 0600 || hdr.Uid != 1000 || hdr.Gid != 1000 {
+		t.Errorf("header = %+v", hdr)
+	}
+	data, _ := io.ReadAll(tr)
+	if string(data) != "port: 8080\n" {
+		t.Errorf("content = %q", data)
+	}
+}
+
+// TestNewFileHeader tests that new files take the owner and permissions of their directory.
+// TestNewFileHeaderは新しいファイルがディレクトリの所有者とパーミッションを受け継ぐことをテストします。
+func TestNewFileHeader(t *testing.T) {
+	tests := []struct {
+		name     string // Test case name / テストケース名
+		dirMode  int64  // Directory mode / ディレクトリのモード
+		wantMode int64  // Expected file mode / 期待されるファイルのモード
+	}{
+		{"public directory", 0755, 0644},
+		{"group directory", 0770, 0660},
+		{"private directory", 0700, 0600},
+		{"sticky directory", 01777, 0666},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hdr := newFileHeader(&tar.Header{Typeflag: tar.TypeDir, Mode: tt.dirMode, Uid: 1000, Gid: 1001, Uname: "node", Gname: "staff"})
+			if hdr.Mode != tt.wantMode || hdr.Uid != 1000 || hdr.Gid != 1001 || hdr.Uname != "node" || hdr.Gname != "staff" {
+				t.Errorf("newFileHeader() = %+v", hdr)
+			}
+		})
+	}
+}
+
+// TestBackupName tests the names of a file's backups.
+// TestBackupNameはファイルのバックアップの名前をテストします。
+func TestBackupName(t *testing.T) {
+	if got := backupName("/app/config.yaml", 0); got != "/app/config.yaml.dkmcp.bak" {
+		t.Errorf("backupName(0) = %q", got)
+	}
+	if got := backupName("/app/config.yaml", 2); got != "/app/config.yaml.dkmcp.bak.2" {
+		t.Errorf("backupName(2) = %q", got)
+	}
+}
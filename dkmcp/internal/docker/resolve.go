@@ -0,0 +1,266 @@
+// resolve.go resolves a path requested in a container to the file it really names, so
+// blocked_paths cannot be bypassed through a symlink, "..", /proc/<pid>/root or another
+// mount of the same host directory. Symlinks are resolved one component at a time with
+// ContainerStatPath, which works without any binary in the container, and the result is
+// mapped through the container's mounts to its host path.
+//
+// resolve.goはコンテナ内で要求されたパスを実際に指すファイルに解決し、シンボリックリンク、
+// ".."、/proc/<pid>/root、または同じホストディレクトリの別のマウントを通じて
+// blocked_pathsを迂回できないようにします。シンボリックリンクはコンテナ内にバイナリがなくても
+// 動作するContainerStatPathで1要素ずつ解決し、結果はコンテナのマウントを通じて
+// ホストのパスに対応付けます。
+package docker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// maxSymlinkHops limits the symlinks followed while resolving a path, like the kernel's
+// limit for nested links (ELOOP).
+//
+// maxSymlinkHopsはパスの解決中に辿るシンボリックリンクの数を、カーネルのネストした
+// リンクの上限（ELOOP）と同様に制限します。
+const maxSymlinkHops = 40
+
+// statFunc stats a path in a container without following a final symlink.
+// statFuncは最後のシンボリックリンクを辿らずにコンテナ内のパスをstatします。
+type statFunc func(p string) (container.PathStat, error)
+
+// resolvePath resolves requested in a container: relative paths are taken from workDir
+// (the working directory the command runs in; "" = the image's), /proc/<pid>/root and
+// /proc/<pid>/cwd are rewritten, symlinks and ".." are resolved, and the result is mapped
+// through the container's mounts.
+//
+// resolvePathはコンテナ内でrequestedを解決します：相対パスはworkDir（コマンドが実行される
+// 作業ディレクトリ。"" = イメージのもの）から解釈し、/proc/<pid>/rootと/proc/<pid>/cwdを
+// 書き換え、シンボリックリンクと".."を解決し、結果をコンテナのマウントを通じて対応付けます。
+func (c *Client) resolvePath(ctx context.Context, containerName, workDir, requested string) (security.PathResolution, error) {
+	info, err := c.docker.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return security.PathResolution{}, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return resolveContainerPath(requested, execWorkDir(workDir, info.Config), info.Mounts, func(p string) (container.PathStat, error) {
+		return c.docker.ContainerStatPath(ctx, containerName, p)
+	})
+}
+
+// execWorkDir returns the working directory a command runs in: the requested one, else
+// the image's WorkingDir, else "/".
+//
+// execWorkDirはコマンドが実行される作業ディレクトリを返します：要求されたもの、なければ
+// イメージのWorkingDir、なければ"/"です。
+func execWorkDir(requested string, cfg *container.Config) string {
+	if requested != "" {
+		return requested
+	}
+	if cfg != nil && cfg.WorkingDir != "" {
+		return cfg.WorkingDir
+	}
+	return "/"
+}
+
+// resolveContainerPath resolves requested relative to workDir with stat and maps the
+// result through mounts (see resolvePath).
+//
+// resolveContainerPathはrequestedをworkDirを基準にstatで解決し、結果をmountsを通じて
+// 対応付けます（resolvePathを参照）。
+func resolveContainerPath(requested, workDir string, mounts []container.MountPoint, stat statFunc) (security.PathResolution, error) {
+	p := requested
+	if !path.IsAbs(p) {
+		p = path.Join(workDir, p)
+	}
+	p = procPath(p, workDir)
+
+	resolved, err := resolveSymlinks(p, stat)
+	if err != nil {
+		return security.PathResolution{}, err
+	}
+
+	hostPath, aliases := mapMounts(resolved, mounts)
+	return security.PathResolution{
+		Requested: requested,
+		Resolved:  resolved,
+		HostPath:  hostPath,
+		Aliases:   aliases,
+	}, nil
+}
+
+// checkResolvedCommandPaths resolves the file arguments of a dangerous command, relative
+// paths from the working directory it runs in, and returns an error if any of them
+// leads to a blocked path.
+//
+// checkResolvedCommandPathsは危険コマンドのファイル引数を（相対パスはそれが実行される
+// 作業ディレクトリから）解決し、いずれかがブロックされたパスに繋がる場合はエラーを返します。
+func (c *Client) checkResolvedCommandPaths(ctx context.Context, containerName, workDir string, args []string) error {
+	return checkCommandPaths(c.GetPolicy(), containerName, args, func(p string) (security.PathResolution, error) {
+		return c.resolvePath(ctx, containerName, workDir, p)
+	})
+}
+
+// checkCommandPaths checks the operands of a command, each resolved with resolve, against
+// the blocked paths.
+//
+// checkCommandPathsはresolveで解決したコマンドのオペランドをブロックされたパスと照合します。
+func checkCommandPaths(policy *security.Policy, containerName string, args []string, resolve func(p string) (security.PathResolution, error)) error {
+	for _, p := range security.CommandOperands(args) {
+		resolution, err := resolve(p)
+		if err != nil {
+			return fmt.Errorf("failed to resolve path %s: %w", p, err)
+		}
+		if blocked, matched := policy.IsResolvedPathBlocked(containerName, resolution); blocked != nil {
+			return &blockedPathError{path: p, resolved: resolution.Resolved, matched: matched, block: blocked}
+		}
+	}
+	return nil
+}
+
+// blockedPathError reports a command operand that leads to a blocked path.
+// blockedPathErrorはブロックされたパスに繋がるコマンドのオペランドを報告します。
+type blockedPathError struct {
+	path     string                // Operand as given / 指定されたオペランド
+	resolved string                // Real path of the operand / オペランドの実パス
+	matched  string                // Name that matched the block / ブロックにマッチした名前
+	block    *security.BlockedPath // Matching blocked path / マッチしたブロックパス
+}
+
+// Error implements the error interface.
+// Errorはerrorインターフェースを実装します。
+func (e *blockedPathError) Error() string {
+	return fmt.Sprintf("path is blocked: %s resolves to %s (reason: %s)", e.path, e.matched, e.block.Reason)
+}
+
+// procPath rewrites /proc/<pid>/root/... to the path it names in the container's root
+// and /proc/<pid>/cwd/... to the working directory. Processes in a container normally
+// share its root, and the daemon cannot follow these links itself.
+//
+// procPathは/proc/<pid>/root/...をコンテナのルート内で指すパスに、/proc/<pid>/cwd/...を
+// 作業ディレクトリに書き換えます。コンテナ内のプロセスは通常ルートを共有しており、
+// デーモン自身はこれらのリンクを辿れません。
+func procPath(p, workDir string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path.Clean(p), "/"), "/", 4)
+	if len(parts) < 3 || parts[0] != "proc" {
+		return p
+	}
+	rest := ""
+	if len(parts) == 4 {
+		rest = parts[3]
+	}
+	switch parts[2] {
+	case "root":
+		return path.Join("/", rest)
+	case "cwd":
+		return path.Join(workDir, rest)
+	}
+	return p
+}
+
+// resolveSymlinks resolves symlinks and ".." in the absolute path p one component at a
+// time, like realpath. Once a component does not exist, the rest of the path is joined
+// as is, since nothing below it can be a symlink.
+//
+// resolveSymlinksは絶対パスp内のシンボリックリンクと".."をrealpathと同様に1要素ずつ
+// 解決します。存在しない要素に達した後は、その下にシンボリックリンクはあり得ないため、
+// 残りのパスをそのまま連結します。
+func resolveSymlinks(p string, stat statFunc) (string, error) {
+	cur := "/"
+	rest := splitPath(p)
+	hops := 0
+
+	for len(rest) > 0 {
+		name := rest[0]
+		rest = rest[1:]
+
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			cur = path.Dir(cur)
+			continue
+		}
+
+		next := path.Join(cur, name)
+		st, err := stat(next)
+		if err != nil {
+			return path.Join(append([]string{next}, rest...)...), nil
+		}
+		if st.Mode&os.ModeSymlink == 0 || st.LinkTarget == "" {
+			cur = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", fmt.Errorf("too many levels of symbolic links: %s", p)
+		}
+		// The daemon reports absolute targets; relative ones are resolved from the link's directory
+		// デーモンは絶対パスのリンク先を報告する。相対パスの場合はリンクのディレクトリから解決する
+		if path.IsAbs(st.LinkTarget) {
+			cur = "/"
+		}
+		rest = append(splitPath(st.LinkTarget), rest...)
+	}
+	return cur, nil
+}
+
+// splitPath splits a slash-separated path into its components.
+// splitPathはスラッシュ区切りのパスを要素に分割します。
+func splitPath(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+// mapMounts returns the host path backing p through the mount with the longest matching
+// destination, and the other container paths the same host path is mounted at.
+// Anonymous mounts without a source (tmpfs) have no host path.
+//
+// mapMountsは最も長く一致する宛先のマウントを通じてpを提供するホストのパスと、
+// 同じホストパスがマウントされている他のコンテナ内パスを返します。
+// ソースのないマウント（tmpfs）にはホストのパスはありません。
+func mapMounts(p string, mounts []container.MountPoint) (string, []string) {
+	var best *container.MountPoint
+	bestRel := ""
+	for i := range mounts {
+		m := &mounts[i]
+		rel, ok := pathWithin(p, m.Destination)
+		if !ok || (best != nil && len(m.Destination) <= len(best.Destination)) {
+			continue
+		}
+		best, bestRel = m, rel
+	}
+	if best == nil || best.Source == "" {
+		return "", nil
+	}
+	hostPath := path.Join(best.Source, bestRel)
+
+	var aliases []string
+	for i := range mounts {
+		m := &mounts[i]
+		if m == best || m.Source == "" {
+			continue
+		}
+		if rel, ok := pathWithin(hostPath, m.Source); ok {
+			aliases = append(aliases, path.Join(m.Destination, rel))
+		}
+	}
+	return hostPath, aliases
+}
+
+// pathWithin reports whether p is dir or inside it, and returns p relative to dir.
+// pathWithinはpがdirまたはその配下にあるかを報告し、dirからのpの相対パスを返します。
+func pathWithin(p, dir string) (string, bool) {
+	dir = path.Clean(dir)
+	if p == dir {
+		return "", true
+	}
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	if !strings.HasPrefix(p, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(p, prefix), true
+}
@@ -0,0 +1,175 @@
+// resolve_test.go contains tests for resolving container paths through symlinks and mounts.
+// resolve_test.goはシンボリックリンクとマウントを通じたコンテナ内パスの解決のテストを含みます。
+package docker
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// fakeStat returns a statFunc over a set of directories and symlinks (path -> target).
+// fakeStatはディレクトリとシンボリックリンク（パス -> リンク先）の集合に対するstatFuncを返します。
+func fakeStat(dirs []string, links map[string]string) statFunc {
+	return func(p string) (container.PathStat, error) {
+		if target, ok := links[p]; ok {
+			return container.PathStat{Mode: os.ModeSymlink | 0777, LinkTarget: target}, nil
+		}
+		for _, d := range dirs {
+			if d == p {
+				return container.PathStat{Mode: os.ModeDir | 0755}, nil
+			}
+		}
+		if p == "/app/.env" || p == "/app/package.json" {
+			return container.PathStat{Mode: 0644}, nil
+		}
+		return container.PathStat{}, errors.New("no such file or directory")
+	}
+}
+
+// TestResolveSymlinks tests resolving symlinks and ".." component by component.
+// TestResolveSymlinksはシンボリックリンクと".."の要素ごとの解決をテストします。
+func TestResolveSymlinks(t *testing.T) {
+	stat := fakeStat([]string{"/app", "/app/config", "/data"}, map[string]string{
+		"/app/link-to-env": "/app/.env",
+		"/app/relative":    ".env",
+		"/app/current":     "/app/config",
+		"/data/app":        "/app",
+		"/app/loop":        "/app/loop",
+	})
+
+	tests := []struct {
+		name string // Test case name / テストケース名
+		path string // Path to resolve / 解決するパス
+		want string // Expected real path / 期待される実パス
+	}{
+		{"plain file", "/app/package.json", "/app/package.json"},
+		{"symlink to file", "/app/link-to-env", "/app/.env"},
+		{"relative symlink", "/app/relative", "/app/.env"},
+		{"symlinked directory", "/data/app/.env", "/app/.env"},
+		{"dot dot after symlink", "/app/current/../.env", "/app/.env"},
+		{"dot dot", "/app/config/../.env", "/app/.env"},
+		{"missing component", "/app/missing/../.env", "/app/.env"},
+		{"missing below symlink", "/data/app/new/file", "/app/new/file"},
+		{"root", "/", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSymlinks(tt.path, stat)
+			if err != nil {
+				t.Fatalf("resolveSymlinks(%q) error = %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveSymlinks(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := resolveSymlinks("/app/loop", stat); err == nil {
+		t.Error("resolveSymlinks() should fail on a symlink loop")
+	}
+}
+
+// TestProcPath tests rewriting /proc/<pid>/root and /proc/<pid>/cwd paths.
+// TestProcPathは/proc/<pid>/rootと/proc/<pid>/cwdのパスの書き換えをテストします。
+func TestProcPath(t *testing.T) {
+	tests := []struct {
+		path string // Requested path / 要求されたパス
+		want string // Expected path / 期待されるパス
+	}{
+		{"/proc/1/root/app/.env", "/app/.env"},
+		{"/proc/self/root/app/../app/.env", "/app/.env"},
+		{"/proc/thread-self/root", "/"},
+		{"/proc/42/cwd/.env", "/srv/app/.env"},
+		{"/proc/1/environ", "/proc/1/environ"},
+		{"/app/proc/1/root/x", "/app/proc/1/root/x"},
+	}
+
+	for _, tt := range tests {
+		if got := procPath(tt.path, "/srv/app"); got != tt.want {
+			t.Errorf("procPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestMapMounts tests mapping a container path to its host path and other mounts.
+// TestMapMountsはコンテナ内パスのホストのパスと他のマウントへの対応付けをテストします。
+func TestMapMounts(t *testing.T) {
+	mounts := []container.MountPoint{
+		{Source: "/home/user/ws", Destination: "/workspace"},
+		{Source: "/home/user/ws/api", Destination: "/app"},
+		{Source: "", Destination: "/app/tmp"},
+	}
+
+	tests := []struct {
+		name        string   // Test case name / テストケース名
+		path        string   // Container path / コンテナ内パス
+		wantHost    string   // Expected host path / 期待されるホストのパス
+		wantAliases []string // Expected aliases / 期待される別名
+	}{
+		{"nested mount", "/app/.env", "/home/user/ws/api/.env", []string{"/workspace/api/.env"}},
+		{"parent mount", "/workspace/api/.env", "/home/user/ws/api/.env", []string{"/app/.env"}},
+		{"outside the nested mount", "/workspace/README.md", "/home/user/ws/README.md", nil},
+		{"tmpfs mount", "/app/tmp/x", "", nil},
+		{"not mounted", "/etc/hosts", "", nil},
+		{"prefix is not a parent", "/application/x", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, aliases := mapMounts(tt.path, mounts)
+			if host != tt.wantHost {
+				t.Errorf("host path = %q, want %q", host, tt.wantHost)
+			}
+			if len(aliases) != len(tt.wantAliases) {
+				t.Fatalf("aliases = %v, want %v", aliases, tt.wantAliases)
+			}
+			for i := range aliases {
+				if aliases[i] != tt.wantAliases[i] {
+					t.Errorf("aliases[%d] = %q, want %q", i, aliases[i], tt.wantAliases[i])
+				}
+			}
+		})
+	}
+}
+
+// TestCheckCommandPaths_Workdir tests that relative file arguments of a dangerous command
+// are checked in the workdir the command runs in, not the image's working directory.
+//
+// TestCheckCommandPaths_Workdirは危険コマンドの相対パスのファイル引数が、イメージの
+// 作業ディレクトリではなくコマンドが実行される作業ディレクトリでチェックされることをテストします。
+func TestCheckCommandPaths_Workdir(t *testing.T) {
+	policy := security.NewPolicy(&config.SecurityConfig{
+		BlockedPaths: config.BlockedPathsConfig{Manual: map[string][]string{"*": {"/secrets/*"}}},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatal(err)
+	}
+	stat := fakeStat([]string{"/app", "/secrets"}, nil)
+	image := &container.Config{WorkingDir: "/app"}
+
+	tests := []struct {
+		name    string // Test case name / テストケース名
+		workdir string // Requested workdir / 要求された作業ディレクトリ
+		wantErr bool   // Whether the path is blocked / パスがブロックされるか
+	}{
+		{"image workdir", "", false},
+		{"requested workdir", "/secrets", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCommandPaths(policy, "api", []string{"cat", "key.pem"}, func(p string) (security.PathResolution, error) {
+				return resolveContainerPath(p, execWorkDir(tt.workdir, image), nil, stat)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkCommandPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
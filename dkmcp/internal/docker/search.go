@@ -0,0 +1,430 @@
+// search.go implements find_files and grep_files on the Docker archive API, like
+// list_files and read_file, so searching works without find or grep in the container.
+// The directory is streamed as a tar archive; every entry is checked against
+// blocked_paths (including other mounts of the same host file), and a blocked directory
+// prunes its whole subtree, so a search never returns or reads anything inside it.
+// Lines are masked with the output masking patterns before they are matched, so a search
+// cannot be used to probe masked secrets.
+//
+// search.goはlist_filesやread_fileと同様に、DockerのアーカイブAPIでfind_filesと
+// grep_filesを実装するため、コンテナ内にfindやgrepがなくても検索できます。
+// ディレクトリはtarアーカイブとしてストリームされ、各エントリは（同じホストファイルの
+// 別のマウントを含めて）blocked_pathsに対してチェックされます。ブロックされたディレクトリは
+// サブツリー全体を除外するため、検索がその中のものを返したり読んだりすることはありません。
+// 行はマッチングの前に出力マスキングのパターンでマスクされるため、検索を使って
+// マスクされたシークレットを探ることはできません。
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+const (
+	// defaultSearchResults is the number of results returned when no limit is given.
+	// defaultSearchResultsは上限が指定されない場合に返す結果の数です。
+	defaultSearchResults = 100
+
+	// maxSearchResults caps the results of FindFiles and GrepFiles.
+	// maxSearchResultsはFindFilesとGrepFilesの結果の上限です。
+	maxSearchResults = 1000
+
+	// maxGrepLineBytes caps the length of a matched line in the result.
+	// maxGrepLineBytesは結果に含めるマッチした行の長さの上限です。
+	maxGrepLineBytes = 500
+
+	// maxGrepScanLineBytes is the longest line GrepFiles scans; files with longer lines
+	// (minified code, data) are searched up to that line.
+	// maxGrepScanLineBytesはGrepFilesが走査する最長の行です。より長い行を持つファイル
+	// （minifyされたコード、データ）はその行の手前まで検索されます。
+	maxGrepScanLineBytes = 1 << 20
+)
+
+// FindOptions selects the entries FindFiles returns.
+// FindOptionsはFindFilesが返すエントリを選択します。
+type FindOptions struct {
+	// Name is a glob matched against the base name (e.g., "*.go"; "" = any)
+	// Nameはベース名に対して照合するglobです（例: "*.go"、"" = すべて）
+	Name string
+
+	// Type is "file", "dir", "symlink" or "" for any type
+	// Typeは"file"、"dir"、"symlink"、またはすべての種別の場合は""です
+	Type string
+
+	// MaxDepth limits how deep below the directory to search (0 = unlimited)
+	// MaxDepthはディレクトリからどの深さまで検索するかを制限します（0 = 無制限）
+	MaxDepth int
+
+	// MaxResults caps the number of entries (0 = 100, at most 1000)
+	// MaxResultsはエントリ数の上限です（0 = 100、最大1000）
+	MaxResults int
+}
+
+// GrepOptions selects the lines GrepFiles returns.
+// GrepOptionsはGrepFilesが返す行を選択します。
+type GrepOptions struct {
+	// Pattern is the regular expression to search for (RE2 syntax)
+	// Patternは検索する正規表現です（RE2構文）
+	Pattern string
+
+	// IgnoreCase makes the pattern case-insensitive
+	// IgnoreCaseはパターンの大文字小文字を区別しないようにします
+	IgnoreCase bool
+
+	// Include is a glob matched against the base name of files to search ("" = all)
+	// Includeは検索するファイルのベース名に対して照合するglobです（"" = すべて）
+	Include string
+
+	// MaxDepth limits how deep below the directory to search (0 = unlimited)
+	// MaxDepthはディレクトリからどの深さまで検索するかを制限します（0 = 無制限）
+	MaxDepth int
+
+	// MaxResults caps the number of matching lines (0 = 100, at most 1000)
+	// MaxResultsはマッチした行数の上限です（0 = 100、最大1000）
+	MaxResults int
+}
+
+// GrepMatch is a line matched by GrepFiles.
+// GrepMatchはGrepFilesがマッチした行です。
+type GrepMatch struct {
+	// Path is the file in the container
+	// Pathはコンテナ内のファイルです
+	Path string `json:"path"`
+
+	// Line is the 1-based line number
+	// Lineは1始まりの行番号です
+	Line int `json:"line"`
+
+	// Text is the masked line, cut at maxGrepLineBytes
+	// TextはマスクしてmaxGrepLineBytesで切り詰めた行です
+	Text string `json:"text"`
+}
+
+// resultLimit returns the effective result cap for a requested maximum.
+// resultLimitは要求された最大数に対する実際の結果の上限を返します。
+func resultLimit(requested int) int {
+	switch {
+	case requested <= 0:
+		return defaultSearchResults
+	case requested > maxSearchResults:
+		return maxSearchResults
+	}
+	return requested
+}
+
+// validate rejects unknown types, malformed globs and negative limits.
+// validateは不明な種別、不正なglob、負の上限を拒否します。
+func (o FindOptions) validate() error {
+	switch o.Type {
+	case "", "file", "dir", "symlink":
+	default:
+		return fmt.Errorf("invalid type %q (must be file, dir or symlink)", o.Type)
+	}
+	if _, err := path.Match(o.Name, ""); err != nil {
+		return fmt.Errorf("invalid name pattern %q: %w", o.Name, err)
+	}
+	if o.MaxDepth < 0 || o.MaxResults < 0 {
+		return fmt.Errorf("max_depth and max_results must not be negative")
+	}
+	return nil
+}
+
+// compile validates the options and returns the compiled pattern.
+// compileはオプションを検証し、コンパイルしたパターンを返します。
+func (o GrepOptions) compile() (*regexp.Regexp, error) {
+	if o.Pattern == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	if _, err := path.Match(o.Include, ""); err != nil {
+		return nil, fmt.Errorf("invalid include pattern %q: %w", o.Include, err)
+	}
+	if o.MaxDepth < 0 || o.MaxResults < 0 {
+		return nil, fmt.Errorf("max_depth and max_results must not be negative")
+	}
+	pattern := o.Pattern
+	if o.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// FindFiles searches a directory tree in a container for entries matching opts.
+// Blocked entries are skipped, and blocked directories are not descended into.
+//
+// FindFilesはコンテナ内のディレクトリツリーからoptsにマッチするエントリを検索します。
+// ブロックされたエントリはスキップされ、ブロックされたディレクトリの中は検索されません。
+func (c *Client) FindFiles(ctx context.Context, containerName string, dir string, opts FindOptions) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	limit := resultLimit(opts.MaxResults)
+	result := &FileAccessResult{Success: true}
+	err := c.walkAllowedTree(ctx, containerName, dir, opts.MaxDepth, result, func(p, rel string, hdr *tar.Header, _ io.Reader) bool {
+		if rel == "" {
+			return true
+		}
+		info := hdr.FileInfo()
+		entryType := fileType(info.Mode())
+		if opts.Type != "" && entryType != opts.Type {
+			return true
+		}
+		if opts.Name != "" {
+			if matched, _ := path.Match(opts.Name, info.Name()); !matched {
+				return true
+			}
+		}
+		if len(result.Entries) == limit {
+			result.Truncated = true
+			return false
+		}
+		result.Entries = append(result.Entries, FileEntry{
+			Name:       info.Name(),
+			Path:       p,
+			Type:       entryType,
+			Size:       hdr.Size,
+			Mode:       info.Mode().String(),
+			ModTime:    hdr.ModTime,
+			LinkTarget: hdr.Linkname,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GrepFiles searches the regular files under a directory (or a single file) in a
+// container for lines matching opts.Pattern. Binary files and blocked paths are skipped.
+//
+// GrepFilesはコンテナ内のディレクトリ配下（または単一のファイル）の通常ファイルから
+// opts.Patternにマッチする行を検索します。バイナリファイルとブロックされたパスはスキップされます。
+func (c *Client) GrepFiles(ctx context.Context, containerName string, dir string, opts GrepOptions) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	re, err := opts.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := c.GetPolicy()
+	limit := resultLimit(opts.MaxResults)
+	result := &FileAccessResult{Success: true}
+	err = c.walkAllowedTree(ctx, containerName, dir, opts.MaxDepth, result, func(p, _ string, hdr *tar.Header, body io.Reader) bool {
+		if hdr.Typeflag != tar.TypeReg {
+			return true
+		}
+		if opts.Include != "" {
+			if matched, _ := path.Match(opts.Include, path.Base(p)); !matched {
+				return true
+			}
+		}
+		matches, full, err := grepReader(body, re, policy.MaskOutput, p, limit-len(result.Matches))
+		if err != nil {
+			return true
+		}
+		result.Matches = append(result.Matches, matches...)
+		if full {
+			result.Truncated = true
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// walkAllowedTree checks and resolves root like ListFiles, then calls fn for every entry
+// of its archive that is not blocked, up to maxDepth levels below root (0 = unlimited).
+// fn receives the entry's container path, its path relative to root ("" for root itself)
+// and its content, and returns false to stop. Blocked and failed results are stored in
+// result; errors are returned only for policy denials.
+//
+// walkAllowedTreeはListFilesと同様にrootをチェックして解決し、そのアーカイブのうち
+// ブロックされていないすべてのエントリについて、rootからmaxDepth階層まで（0 = 無制限）
+// fnを呼び出します。fnはエントリのコンテナ内パス、rootからの相対パス（root自体は""）、
+// その内容を受け取り、falseを返すと停止します。ブロックと失敗の結果はresultに格納し、
+// エラーはポリシーによる拒否の場合のみ返します。
+func (c *Client) walkAllowedTree(ctx context.Context, containerName, root string, maxDepth int, result *FileAccessResult, fn func(p, rel string, hdr *tar.Header, body io.Reader) bool) error {
+	target, stat, denied, err := c.statAllowedPath(ctx, containerName, root)
+	if err != nil {
+		return err
+	}
+	if denied != nil {
+		*result = *denied
+		return nil
+	}
+
+	info, err := c.docker.ContainerInspect(ctx, containerName)
+	if err != nil {
+		*result = FileAccessResult{Success: false, Error: fmt.Sprintf("failed to inspect container: %v", err)}
+		return nil
+	}
+
+	reader, _, err := c.docker.CopyFromContainer(ctx, containerName, target)
+	if err != nil {
+		*result = FileAccessResult{Success: false, Error: err.Error()}
+		return nil
+	}
+	defer reader.Close()
+
+	policy := c.GetPolicy()
+	blocked := func(p string) bool {
+		return isBlockedEntry(policy, containerName, p, info.Mounts)
+	}
+	truncated, skipped, err := walkArchive(reader, stat.Name, target, maxDepth, maxListScanBytes, blocked, fn)
+	if err != nil {
+		*result = FileAccessResult{Success: false, Error: err.Error()}
+		return nil
+	}
+	result.Truncated = result.Truncated || truncated
+	result.SkippedBlocked = skipped
+	return nil
+}
+
+// isBlockedEntry reports whether a path found while walking a tree is blocked, under its
+// own name or through another mount of the same host file.
+//
+// isBlockedEntryはツリーの走査中に見つかったパスが、その名前で、または同じホストファイルの
+// 別のマウントを通じてブロックされているかどうかを報告します。
+func isBlockedEntry(policy *security.Policy, containerName, p string, mounts []container.MountPoint) bool {
+	hostPath, aliases := mapMounts(p, mounts)
+	blocked, _ := policy.IsResolvedPathBlocked(containerName, security.PathResolution{
+		Requested: p,
+		Resolved:  p,
+		HostPath:  hostPath,
+		Aliases:   aliases,
+	})
+	return blocked != nil
+}
+
+// walkArchive calls fn for the entries of the archive of the directory (or file) dirName
+// read from r, which is located at base in the container. Entries for which blocked
+// returns true are skipped, together with everything below them. Scanning stops after
+// limit bytes (truncated is true) or when fn returns false. skipped counts the blocked
+// entries.
+//
+// walkArchiveはrから読み取ったディレクトリ（またはファイル）dirNameのアーカイブの
+// エントリについてfnを呼び出します。アーカイブはコンテナ内のbaseに位置します。blockedが
+// trueを返すエントリは、その配下すべてとともにスキップされます。limitバイトを超えると
+// （truncatedがtrue）、またはfnがfalseを返すと走査を停止します。skippedはブロックされた
+// エントリを数えます。
+func walkArchive(r io.Reader, dirName, base string, maxDepth int, limit int64, blocked func(p string) bool, fn func(p, rel string, hdr *tar.Header, body io.Reader) bool) (truncated bool, skipped int, err error) {
+	counter := &countingReader{r: r}
+	tr := tar.NewReader(counter)
+
+	root := strings.Trim(dirName, "/")
+	var pruned []string
+	for {
+		if counter.n > limit {
+			return true, skipped, nil
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return false, skipped, nil
+		}
+		if err != nil {
+			return false, skipped, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		rel, ok := archiveRelativePath(hdr.Name, root)
+		if !ok || underAny(rel, pruned) {
+			continue
+		}
+		if maxDepth > 0 && rel != "" && strings.Count(rel, "/")+1 > maxDepth {
+			continue
+		}
+
+		p := path.Join(base, rel)
+		if rel != "" && blocked(p) {
+			skipped++
+			if hdr.Typeflag == tar.TypeDir {
+				pruned = append(pruned, rel)
+			}
+			continue
+		}
+		if !fn(p, rel, hdr, tr) {
+			return false, skipped, nil
+		}
+	}
+}
+
+// underAny reports whether rel is inside one of the directories in dirs.
+// underAnyはrelがdirsのいずれかのディレクトリ内にあるかどうかを報告します。
+func underAny(rel string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// grepReader returns up to max lines of r matching re, reported as file p. Each line is
+// masked before matching; the match must survive masking, so masked secrets cannot be
+// probed. Binary content is skipped. full reports that max matches were found.
+//
+// grepReaderはrのうちreにマッチする行を最大max行、ファイルpとして返します。各行は
+// マッチングの前にマスクされ、マッチはマスク後も残る必要があるため、マスクされた
+// シークレットを探ることはできません。バイナリ内容はスキップします。fullはmax件の
+// マッチが見つかったことを報告します。
+func grepReader(r io.Reader, re *regexp.Regexp, mask func(string) string, p string, max int) (matches []GrepMatch, full bool, err error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	sample, err := br.Peek(binarySniffBytes)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, err
+	}
+	if isBinary(sample) {
+		return nil, false, nil
+	}
+
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxGrepScanLineBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if !re.MatchString(text) {
+			continue
+		}
+		text = mask(text)
+		if !re.MatchString(text) {
+			continue
+		}
+		if len(text) > maxGrepLineBytes {
+			// Cut on a character boundary so no character is split
+			// 文字が分割されないように文字境界で切る
+			n := maxGrepLineBytes
+			for n > 0 && !utf8.RuneStart(text[n]) {
+				n--
+			}
+			text = text[:n] + "..."
+		}
+		matches = append(matches, GrepMatch{Path: p, Line: line, Text: text})
+		if len(matches) >= max {
+			return matches, true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
+		return matches, false, err
+	}
+	return matches, false, nil
+}
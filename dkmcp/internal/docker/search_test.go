@@ -0,0 +1,171 @@
+// search_test.go contains tests for walking archives and searching file contents.
+// search_test.goはアーカイブの走査とファイル内容の検索のテストを含みます。
+package docker
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestWalkArchive tests pruning blocked directories and limiting the depth.
+// TestWalkArchiveはブロックされたディレクトリの除外と深さの制限をテストします。
+func TestWalkArchive(t *testing.T) {
+	entries := []archiveEntry{
+		{name: "app/", typeflag: tar.TypeDir},
+		{name: "app/server.js", typeflag: tar.TypeReg, body: "listen"},
+		{name: "app/secrets/", typeflag: tar.TypeDir},
+		{name: "app/secrets/key.pem", typeflag: tar.TypeReg, body: "KEY"},
+		{name: "app/secrets/nested/", typeflag: tar.TypeDir},
+		{name: "app/secrets/nested/token", typeflag: tar.TypeReg, body: "TOKEN"},
+		{name: "app/.env", typeflag: tar.TypeReg, body: "SECRET=1"},
+		{name: "app/src/", typeflag: tar.TypeDir},
+		{name: "app/src/lib/", typeflag: tar.TypeDir},
+		{name: "app/src/lib/util.js", typeflag: tar.TypeReg, body: "util"},
+	}
+	blocked := func(p string) bool {
+		return p == "/srv/app/secrets" || p == "/srv/app/.env"
+	}
+
+	tests := []struct {
+		name        string   // Test case name / テストケース名
+		maxDepth    int      // Depth limit / 深さの上限
+		wantPaths   []string // Expected visited paths / 期待される訪問したパス
+		wantSkipped int      // Expected blocked entries / 期待されるブロックされたエントリ数
+	}{
+		{
+			name:        "unlimited depth",
+			wantPaths:   []string{"/srv/app", "/srv/app/server.js", "/srv/app/src", "/srv/app/src/lib", "/srv/app/src/lib/util.js"},
+			wantSkipped: 2,
+		},
+		{
+			name:        "depth one",
+			maxDepth:    1,
+			wantPaths:   []string{"/srv/app", "/srv/app/server.js", "/srv/app/src"},
+			wantSkipped: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			truncated, skipped, err := walkArchive(buildArchive(t, entries), "app", "/srv/app", tt.maxDepth, maxListScanBytes, blocked,
+				func(p, rel string, hdr *tar.Header, body io.Reader) bool {
+					got = append(got, p)
+					return true
+				})
+			if err != nil {
+				t.Fatalf("walkArchive() error = %v", err)
+			}
+			if truncated {
+				t.Error("walkArchive() should not truncate")
+			}
+			if skipped != tt.wantSkipped {
+				t.Errorf("skipped = %d, want %d", skipped, tt.wantSkipped)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantPaths, ",") {
+				t.Errorf("visited %v, want %v", got, tt.wantPaths)
+			}
+		})
+	}
+}
+
+// TestGrepReader tests matching lines, masking and the result limit.
+// TestGrepReaderは行のマッチング、マスキング、結果の上限をテストします。
+func TestGrepReader(t *testing.T) {
+	content := "port: 8080\npassword: hunter2\nhost: db\nport: 5432\n"
+	mask := func(s string) string {
+		return regexp.MustCompile(`password: \S+`).ReplaceAllString(s, "password: ****")
+	}
+
+	tests := []struct {
+		name      string   // Test case name / テストケース名
+		pattern   string   // Pattern to search for / 検索するパターン
+		max       int      // Result limit / 結果の上限
+		wantLines []string // Expected matches as "line:text" / 期待されるマッチ（"行:テキスト"）
+		wantFull  bool     // Expected limit reached / 上限に達することを期待するか
+	}{
+		{"matches", "port", 10, []string{"1:port: 8080", "4:port: 5432"}, false},
+		{"limit", "port", 1, []string{"1:port: 8080"}, true},
+		{"masked line", "password", 10, []string{"2:password: ****"}, false},
+		{"secret value cannot be probed", "hunter", 10, nil, false},
+		{"no match", "missing", 10, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, full, err := grepReader(strings.NewReader(content), regexp.MustCompile(tt.pattern), mask, "/app/config.yaml", tt.max)
+			if err != nil {
+				t.Fatalf("grepReader() error = %v", err)
+			}
+			var got []string
+			for _, m := range matches {
+				if m.Path != "/app/config.yaml" {
+					t.Errorf("match path = %q", m.Path)
+				}
+				got = append(got, fmt.Sprintf("%d:%s", m.Line, m.Text))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.wantLines, "|") {
+				t.Errorf("matches = %v, want %v", got, tt.wantLines)
+			}
+			if full != tt.wantFull {
+				t.Errorf("full = %v, want %v", full, tt.wantFull)
+			}
+		})
+	}
+
+	binary := "ELF\x00\x01\x02port"
+	if matches, _, _ := grepReader(strings.NewReader(binary), regexp.MustCompile("port"), mask, "/bin/app", 10); len(matches) != 0 {
+		t.Errorf("binary content should be skipped, got %v", matches)
+	}
+
+	// A long line is cut on a character boundary
+	// 長い行は文字境界で切られる
+	long := "msg: " + strings.Repeat("é", maxGrepLineBytes) + "\n"
+	matches, _, err := grepReader(strings.NewReader(long), regexp.MustCompile("msg"), mask, "/app/log", 10)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("grepReader() = %v, %v; want one match", matches, err)
+	}
+	text := strings.TrimSuffix(matches[0].Text, "...")
+	if !utf8.ValidString(text) || len(text) > maxGrepLineBytes || len(text) < maxGrepLineBytes-1 {
+		t.Errorf("long line cut to %d bytes, valid UTF-8 %v", len(text), utf8.ValidString(text))
+	}
+}
+
+// TestFindOptionsValidate tests rejecting invalid find options.
+// TestFindOptionsValidateは不正なfindオプションの拒否をテストします。
+func TestFindOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string      // Test case name / テストケース名
+		opts    FindOptions // Options to validate / 検証するオプション
+		wantErr bool        // Expect an error / エラーを期待するか
+	}{
+		{"empty", FindOptions{}, false},
+		{"all fields", FindOptions{Name: "*.go", Type: "dir", MaxDepth: 2, MaxResults: 10}, false},
+		{"unknown type", FindOptions{Type: "socket"}, true},
+		{"bad glob", FindOptions{Name: "["}, true},
+		{"negative depth", FindOptions{MaxDepth: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := (GrepOptions{Pattern: "("}).compile(); err == nil {
+		t.Error("compile() should reject an invalid regular expression")
+	}
+	if re, err := (GrepOptions{Pattern: "port", IgnoreCase: true}).compile(); err != nil || !re.MatchString("PORT") {
+		t.Errorf("compile() with IgnoreCase = %v, %v", re, err)
+	}
+	if got := resultLimit(5000); got != maxSearchResults {
+		t.Errorf("resultLimit(5000) = %d, want %d", got, maxSearchResults)
+	}
+}
@@ -0,0 +1,290 @@
+// stats.go turns the one-shot snapshots of GetStats into a time series. A snapshot holds
+// cumulative counters, so CPU and memory percentages are computed the way `docker stats`
+// does, and network and block I/O rates come from the difference between two samples.
+//
+// stats.goはGetStatsのワンショットのスナップショットを時系列に変換します。スナップショットは
+// 累積カウンタを持つため、CPUとメモリの割合は`docker stats`と同じ方法で計算し、ネットワークと
+// ブロックI/Oのレートは2つのサンプルの差から求めます。
+package docker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// StatsPoint is one sample of a container's resource usage. The network and block I/O
+// values are cumulative byte counters.
+//
+// StatsPointはコンテナのリソース使用量の1つのサンプルです。ネットワークとブロックI/Oの
+// 値は累積のバイトカウンタです。
+type StatsPoint struct {
+	// Time is when the Docker daemon read the sample
+	// TimeはDockerデーモンがサンプルを読み取った時刻です
+	Time time.Time `json:"time"`
+
+	// CPUPercent is the CPU usage, where 100 is one full core
+	// CPUPercentはCPU使用率で、100が1コア分に相当します
+	CPUPercent float64 `json:"cpu_percent"`
+
+	// MemoryUsage is the memory in use, excluding the page cache
+	// MemoryUsageはページキャッシュを除いた使用中のメモリです
+	MemoryUsage uint64 `json:"memory_usage"`
+
+	// MemoryLimit is the memory limit of the container
+	// MemoryLimitはコンテナのメモリ上限です
+	MemoryLimit uint64 `json:"memory_limit"`
+
+	// MemoryPercent is MemoryUsage as a percentage of MemoryLimit
+	// MemoryPercentはMemoryLimitに対するMemoryUsageの割合です
+	MemoryPercent float64 `json:"memory_percent"`
+
+	// NetworkRx and NetworkTx are the bytes received and sent on all networks
+	// NetworkRxとNetworkTxはすべてのネットワークで受信・送信したバイト数です
+	NetworkRx uint64 `json:"network_rx_bytes"`
+	NetworkTx uint64 `json:"network_tx_bytes"`
+
+	// BlockRead and BlockWrite are the bytes read from and written to block devices
+	// BlockReadとBlockWriteはブロックデバイスから読み取った・書き込んだバイト数です
+	BlockRead  uint64 `json:"block_read_bytes"`
+	BlockWrite uint64 `json:"block_write_bytes"`
+}
+
+// NewStatsPoint computes a StatsPoint from a stats snapshot.
+// NewStatsPointは統計のスナップショットからStatsPointを計算します。
+func NewStatsPoint(s *container.StatsResponse) StatsPoint {
+	p := StatsPoint{
+		Time:        s.Read,
+		MemoryLimit: s.MemoryStats.Limit,
+	}
+
+	// CPU usage over the interval the daemon measured, scaled by the online CPUs
+	// デーモンが計測した区間のCPU使用量を、オンラインのCPU数で換算
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	cpus := float64(s.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(len(s.CPUStats.CPUUsage.PercpuUsage))
+	}
+	if cpuDelta > 0 && systemDelta > 0 {
+		p.CPUPercent = cpuDelta / systemDelta * cpus * 100
+	}
+
+	// The page cache can be reclaimed, so it is not counted as used (cgroup v1 and v2 keys)
+	// ページキャッシュは回収できるため使用中には数えない（cgroup v1とv2のキー）
+	p.MemoryUsage = s.MemoryStats.Usage
+	cache := s.MemoryStats.Stats["total_inactive_file"]
+	if v, ok := s.MemoryStats.Stats["inactive_file"]; ok {
+		cache = v
+	}
+	if cache < p.MemoryUsage {
+		p.MemoryUsage -= cache
+	}
+	if p.MemoryLimit > 0 {
+		p.MemoryPercent = float64(p.MemoryUsage) / float64(p.MemoryLimit) * 100
+	}
+
+	for _, n := range s.Networks {
+		p.NetworkRx += n.RxBytes
+		p.NetworkTx += n.TxBytes
+	}
+	for _, e := range s.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(e.Op) {
+		case "read":
+			p.BlockRead += e.Value
+		case "write":
+			p.BlockWrite += e.Value
+		}
+	}
+	return p
+}
+
+// StatsRates holds the network and block I/O rates between two samples, in bytes per second.
+// StatsRatesは2つのサンプル間のネットワークとブロックI/Oのレートを毎秒バイト数で保持します。
+type StatsRates struct {
+	NetworkRx  float64 `json:"network_rx_bytes_per_sec"`
+	NetworkTx  float64 `json:"network_tx_bytes_per_sec"`
+	BlockRead  float64 `json:"block_read_bytes_per_sec"`
+	BlockWrite float64 `json:"block_write_bytes_per_sec"`
+}
+
+// RatesSince returns the rates from prev to p. A counter that went down, as after a
+// container restart, counts as zero.
+//
+// RatesSinceはprevからpまでのレートを返します。コンテナの再起動後のように減少した
+// カウンタはゼロとして数えます。
+func (p StatsPoint) RatesSince(prev StatsPoint) StatsRates {
+	seconds := p.Time.Sub(prev.Time).Seconds()
+	if seconds <= 0 {
+		return StatsRates{}
+	}
+	rate := func(cur, old uint64) float64 {
+		if cur < old {
+			return 0
+		}
+		return float64(cur-old) / seconds
+	}
+	return StatsRates{
+		NetworkRx:  rate(p.NetworkRx, prev.NetworkRx),
+		NetworkTx:  rate(p.NetworkTx, prev.NetworkTx),
+		BlockRead:  rate(p.BlockRead, prev.BlockRead),
+		BlockWrite: rate(p.BlockWrite, prev.BlockWrite),
+	}
+}
+
+// Trend values of MetricSummary.
+// MetricSummaryのトレンドの値です。
+const (
+	TrendRising  = "rising"
+	TrendFalling = "falling"
+	TrendStable  = "stable"
+)
+
+// trendThreshold is the fraction of the largest value that the fitted change over the
+// window must exceed to count as rising or falling.
+//
+// trendThresholdは上昇または下降と数えるために、期間全体での近似した変化量が超える
+// 必要がある最大値に対する割合です。
+const trendThreshold = 0.05
+
+// MetricSummary summarizes one metric over a series of samples.
+// MetricSummaryは一連のサンプルにわたる1つのメトリクスを要約します。
+type MetricSummary struct {
+	Min  float64 `json:"min"`
+	Avg  float64 `json:"avg"`
+	Max  float64 `json:"max"`
+	Last float64 `json:"last"`
+
+	// Trend is rising, falling or stable, from a least-squares fit; empty with fewer
+	// than three values
+	// Trendは最小二乗法による近似からのrising、falling、stableです。値が3つ未満の場合は空です
+	Trend string `json:"trend,omitempty"`
+}
+
+// SummarizeMetric returns the min, average, max, last value and trend of values.
+// SummarizeMetricはvaluesの最小、平均、最大、最後の値、トレンドを返します。
+func SummarizeMetric(values []float64) MetricSummary {
+	if len(values) == 0 {
+		return MetricSummary{}
+	}
+	s := MetricSummary{Min: values[0], Max: values[0], Last: values[len(values)-1]}
+	var sum float64
+	for _, v := range values {
+		s.Min = math.Min(s.Min, v)
+		s.Max = math.Max(s.Max, v)
+		sum += v
+	}
+	n := float64(len(values))
+	s.Avg = sum / n
+	if len(values) < 3 {
+		return s
+	}
+
+	// Slope of the least-squares line through (i, values[i])
+	// (i, values[i])を通る最小二乗直線の傾き
+	meanX := (n - 1) / 2
+	var num, den float64
+	for i, v := range values {
+		dx := float64(i) - meanX
+		num += dx * (v - s.Avg)
+		den += dx * dx
+	}
+	change := num / den * (n - 1)
+	scale := math.Max(math.Abs(s.Max), math.Abs(s.Min))
+	switch {
+	case scale == 0 || math.Abs(change) <= trendThreshold*scale:
+		s.Trend = TrendStable
+	case change > 0:
+		s.Trend = TrendRising
+	default:
+		s.Trend = TrendFalling
+	}
+	return s
+}
+
+// StatsSummary summarizes a container's resource usage over a sampling window.
+// StatsSummaryはサンプリング期間にわたるコンテナのリソース使用量を要約します。
+type StatsSummary struct {
+	Container     string        `json:"container"`
+	Samples       int           `json:"samples"`
+	WindowSeconds float64       `json:"window_seconds"`
+	CPUPercent    MetricSummary `json:"cpu_percent"`
+	MemoryPercent MetricSummary `json:"memory_percent"`
+	MemoryUsage   MetricSummary `json:"memory_usage_bytes"`
+	MemoryLimit   uint64        `json:"memory_limit_bytes"`
+
+	// The rates are computed between consecutive samples, so they have one value fewer
+	// レートは連続するサンプル間で計算されるため、値が1つ少なくなります
+	NetworkRxRate  MetricSummary `json:"network_rx_bytes_per_sec"`
+	NetworkTxRate  MetricSummary `json:"network_tx_bytes_per_sec"`
+	BlockReadRate  MetricSummary `json:"block_read_bytes_per_sec"`
+	BlockWriteRate MetricSummary `json:"block_write_bytes_per_sec"`
+}
+
+// SummarizeStats summarizes the samples of a container in time order.
+// SummarizeStatsは時刻順に並んだコンテナのサンプルを要約します。
+func SummarizeStats(containerName string, points []StatsPoint) StatsSummary {
+	s := StatsSummary{Container: containerName, Samples: len(points)}
+	if len(points) == 0 {
+		return s
+	}
+	s.WindowSeconds = points[len(points)-1].Time.Sub(points[0].Time).Seconds()
+	s.MemoryLimit = points[len(points)-1].MemoryLimit
+
+	var cpu, memPercent, mem []float64
+	for _, p := range points {
+		cpu = append(cpu, p.CPUPercent)
+		memPercent = append(memPercent, p.MemoryPercent)
+		mem = append(mem, float64(p.MemoryUsage))
+	}
+	s.CPUPercent = SummarizeMetric(cpu)
+	s.MemoryPercent = SummarizeMetric(memPercent)
+	s.MemoryUsage = SummarizeMetric(mem)
+
+	var rx, tx, read, write []float64
+	for i := 1; i < len(points); i++ {
+		r := points[i].RatesSince(points[i-1])
+		rx = append(rx, r.NetworkRx)
+		tx = append(tx, r.NetworkTx)
+		read = append(read, r.BlockRead)
+		write = append(write, r.BlockWrite)
+	}
+	s.NetworkRxRate = SummarizeMetric(rx)
+	s.NetworkTxRate = SummarizeMetric(tx)
+	s.BlockReadRate = SummarizeMetric(read)
+	s.BlockWriteRate = SummarizeMetric(write)
+	return s
+}
+
+// SampleStats takes samples snapshots of a container through c.GetStats, which applies
+// the policy checks, starting one every interval, and returns them as points. It stops
+// early with an error when a snapshot fails or ctx is cancelled.
+//
+// SampleStatsはポリシーのチェックを適用するc.GetStatsを通じてコンテナのスナップショットを
+// interval毎に開始してsamples回取得し、ポイントとして返します。スナップショットが失敗するか
+// ctxがキャンセルされた場合はエラーで早期に終了します。
+func SampleStats(ctx context.Context, c DockerClientInterface, containerName string, samples int, interval time.Duration) ([]StatsPoint, error) {
+	points := make([]StatsPoint, 0, samples)
+	start := time.Now()
+	for i := 0; i < samples; i++ {
+		if i > 0 {
+			timer := time.NewTimer(time.Until(start.Add(time.Duration(i) * interval)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return points, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		stats, err := c.GetStats(ctx, containerName)
+		if err != nil {
+			return points, fmt.Errorf("sample %d: %w", i+1, err)
+		}
+		points = append(points, NewStatsPoint(stats))
+	}
+	return points, nil
+}
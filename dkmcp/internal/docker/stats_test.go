@@ -0,0 +1,163 @@
+// stats_test.go contains tests for computing and summarizing container stats samples.
+// stats_test.goはコンテナの統計サンプルの計算と要約のテストを含みます。
+package docker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// TestNewStatsPoint tests the CPU and memory percentages and the summed counters.
+// TestNewStatsPointはCPUとメモリの割合、および合計したカウンタをテストします。
+func TestNewStatsPoint(t *testing.T) {
+	read := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	p := NewStatsPoint(&container.StatsResponse{
+		Read: read,
+		CPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 3_000_000},
+			SystemUsage: 20_000_000,
+			OnlineCPUs:  2,
+		},
+		PreCPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 1_000_000},
+			SystemUsage: 10_000_000,
+		},
+		MemoryStats: container.MemoryStats{
+			Usage: 300 << 20,
+			Limit: 1 << 30,
+			Stats: map[string]uint64{"inactive_file": 44 << 20},
+		},
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 1000, TxBytes: 200},
+			"eth1": {RxBytes: 500, TxBytes: 100},
+		},
+		BlkioStats: container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+			{Op: "read", Value: 4096},
+			{Op: "Write", Value: 8192},
+			{Op: "Total", Value: 12288},
+		}},
+	})
+
+	want := StatsPoint{
+		Time:          read,
+		CPUPercent:    40,
+		MemoryUsage:   256 << 20,
+		MemoryLimit:   1 << 30,
+		MemoryPercent: 25,
+		NetworkRx:     1500,
+		NetworkTx:     300,
+		BlockRead:     4096,
+		BlockWrite:    8192,
+	}
+	if p != want {
+		t.Errorf("NewStatsPoint() = %+v, want %+v", p, want)
+	}
+}
+
+// TestRatesSince tests per-second rates and counters reset by a restart.
+// TestRatesSinceは毎秒のレートと再起動でリセットされたカウンタをテストします。
+func TestRatesSince(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	prev := StatsPoint{Time: start, NetworkRx: 1000, NetworkTx: 5000, BlockRead: 0, BlockWrite: 100}
+	cur := StatsPoint{Time: start.Add(2 * time.Second), NetworkRx: 3000, NetworkTx: 10, BlockRead: 4096, BlockWrite: 100}
+
+	got := cur.RatesSince(prev)
+	want := StatsRates{NetworkRx: 1000, NetworkTx: 0, BlockRead: 2048, BlockWrite: 0}
+	if got != want {
+		t.Errorf("RatesSince() = %+v, want %+v", got, want)
+	}
+	if got := prev.RatesSince(prev); got != (StatsRates{}) {
+		t.Errorf("RatesSince() over no time = %+v, want zero", got)
+	}
+}
+
+// TestSummarizeMetric tests min/avg/max and the trend classification.
+// TestSummarizeMetricは最小・平均・最大とトレンドの分類をテストします。
+func TestSummarizeMetric(t *testing.T) {
+	tests := []struct {
+		name   string    // Test case name / テストケース名
+		values []float64 // Samples / サンプル
+		want   MetricSummary
+	}{
+		{"empty", nil, MetricSummary{}},
+		{"two values have no trend", []float64{1, 3}, MetricSummary{Min: 1, Avg: 2, Max: 3, Last: 3}},
+		{"rising", []float64{100, 110, 120, 130}, MetricSummary{Min: 100, Avg: 115, Max: 130, Last: 130, Trend: TrendRising}},
+		{"falling", []float64{40, 30, 20, 10}, MetricSummary{Min: 10, Avg: 25, Max: 40, Last: 10, Trend: TrendFalling}},
+		{"noise is stable", []float64{100, 101, 99, 100}, MetricSummary{Min: 99, Avg: 100, Max: 101, Last: 100, Trend: TrendStable}},
+		{"all zero is stable", []float64{0, 0, 0}, MetricSummary{Trend: TrendStable}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummarizeMetric(tt.values); got != tt.want {
+				t.Errorf("SummarizeMetric(%v) = %+v, want %+v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSummarizeStats tests summarizing a series, including the rates between samples.
+// TestSummarizeStatsはサンプル間のレートを含む系列の要約をテストします。
+func TestSummarizeStats(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	var points []StatsPoint
+	for i := 0; i < 4; i++ {
+		points = append(points, StatsPoint{
+			Time:          start.Add(time.Duration(i) * time.Second),
+			MemoryUsage:   uint64(100+10*i) << 20,
+			MemoryLimit:   1 << 30,
+			MemoryPercent: float64(10 + i),
+			NetworkRx:     uint64(1000 * i),
+		})
+	}
+
+	s := SummarizeStats("api", points)
+	if s.Container != "api" || s.Samples != 4 || s.WindowSeconds != 3 || s.MemoryLimit != 1<<30 {
+		t.Errorf("SummarizeStats() = %+v", s)
+	}
+	if s.MemoryUsage.Trend != TrendRising || s.MemoryPercent.Max != 13 {
+		t.Errorf("memory = %+v, %+v", s.MemoryUsage, s.MemoryPercent)
+	}
+	if s.NetworkRxRate.Avg != 1000 || s.NetworkRxRate.Trend != TrendStable {
+		t.Errorf("network rx rate = %+v", s.NetworkRxRate)
+	}
+	if math.IsNaN(s.CPUPercent.Avg) || s.CPUPercent.Trend != TrendStable {
+		t.Errorf("cpu = %+v", s.CPUPercent)
+	}
+}
+
+// TestSampleStats tests taking samples at an interval and stopping on an error.
+// TestSampleStatsは間隔を空けたサンプルの取得とエラー時の停止をテストします。
+func TestSampleStats(t *testing.T) {
+	calls := 0
+	mock := NewMockClient(nil)
+	mock.GetStatsFunc = func(ctx context.Context, name string) (*container.StatsResponse, error) {
+		calls++
+		if name != "api" {
+			return nil, errors.New("stats permission denied")
+		}
+		return &container.StatsResponse{Read: time.Now(), MemoryStats: container.MemoryStats{Usage: uint64(calls)}}, nil
+	}
+
+	begin := time.Now()
+	points, err := SampleStats(context.Background(), mock, "api", 3, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("SampleStats() error = %v", err)
+	}
+	if len(points) != 3 || points[2].MemoryUsage != 3 {
+		t.Errorf("points = %+v", points)
+	}
+	if elapsed := time.Since(begin); elapsed < 40*time.Millisecond {
+		t.Errorf("3 samples 20ms apart took %v", elapsed)
+	}
+
+	if _, err := SampleStats(context.Background(), mock, "db", 3, time.Millisecond); err == nil || !strings.Contains(err.Error(), "sample 1") {
+		t.Errorf("expected the first sample to fail, got %v", err)
+	}
+}
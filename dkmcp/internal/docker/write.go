@@ -0,0 +1,356 @@
+// write.go implements write_file and apply_patch with the Docker archive API
+// (CopyFromContainer and CopyToContainer), so hot-fixing a file in a container no longer
+// requires a dangerous exec whitelist entry. A write needs the write permission, a path
+// covered by writable_paths and a path that is not blocked under any of its names. The
+// previous content is saved next to the file before it is replaced, and the diff is
+// returned for the audit log.
+//
+// write.goはDockerのアーカイブAPI（CopyFromContainerとCopyToContainer）でwrite_fileと
+// apply_patchを実装するため、コンテナ内のファイルの修正に危険なexecホワイトリストの
+// エントリが不要になります。書き込みにはwrite権限、writable_pathsでカバーされたパス、
+// どの名前でもブロックされていないパスが必要です。以前の内容は置き換える前にファイルの
+// 隣に保存され、diffは監査ログ用に返されます。
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+const (
+	// maxWriteBytes caps the size of a file WriteFile and ApplyPatch read or write.
+	// maxWriteBytesはWriteFileとApplyPatchが読み書きするファイルのサイズの上限です。
+	maxWriteBytes = 1 << 20
+
+	// backupSuffix is appended to a file's name for the backup of its previous content.
+	// backupSuffixは以前の内容のバックアップとしてファイル名に付加されます。
+	backupSuffix = ".dkmcp.bak"
+
+	// maxBackups is how many backups of one file are kept before writes are refused.
+	// maxBackupsは書き込みが拒否されるまでに保持される1ファイルあたりのバックアップ数です。
+	maxBackups = 10
+)
+
+// WriteFile replaces the content of a file in a container, or creates it. The previous
+// content is kept in a backup next to the file.
+//
+// Parameters:
+//   - containerName: Name or ID of the container
+//   - path: Path of the file; the real path must be covered by writable_paths
+//   - content: New content of the file
+//
+// WriteFileはコンテナ内のファイルの内容を置き換えるか、ファイルを作成します。以前の
+// 内容はファイルの隣のバックアップに保持されます。
+//
+// パラメータ:
+//   - containerName: コンテナの名前またはID
+//   - path: ファイルのパス。実パスはwritable_pathsでカバーされている必要があります
+//   - content: ファイルの新しい内容
+func (c *Client) WriteFile(ctx context.Context, containerName string, path string, content string) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	if len(content) > maxWriteBytes {
+		return nil, fmt.Errorf("content is too large: %d bytes (max %d)", len(content), maxWriteBytes)
+	}
+	return c.modifyFile(ctx, containerName, path, true, func(string) (string, error) {
+		return content, nil
+	})
+}
+
+// ApplyPatch applies a unified diff to an existing file in a container. The previous
+// content is kept in a backup next to the file.
+//
+// ApplyPatchはコンテナ内の既存のファイルにunified diffを適用します。以前の内容は
+// ファイルの隣のバックアップに保持されます。
+func (c *Client) ApplyPatch(ctx context.Context, containerName string, path string, patch string) (*FileAccessResult, error) {
+	containerName = c.resolveContainer(ctx, containerName)
+
+	if _, err := parsePatch(patch); err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	return c.modifyFile(ctx, containerName, path, false, func(old string) (string, error) {
+		return applyPatch(old, patch)
+	})
+}
+
+// modifyFile checks that p may be written, reads its current content, computes the new
+// content with edit, backs up the old content and uploads the new one. create allows p
+// not to exist yet.
+//
+// modifyFileはpに書き込めることをチェックし、現在の内容を読み取り、editで新しい内容を
+// 計算し、古い内容をバックアップして新しい内容をアップロードします。createはpがまだ
+// 存在しないことを許可します。
+func (c *Client) modifyFile(ctx context.Context, containerName, p string, create bool, edit func(old string) (string, error)) (*FileAccessResult, error) {
+	if _, err := c.GetPolicy().CanWrite(containerName); err != nil {
+		return nil, err
+	}
+
+	target, denied, err := c.writableTarget(ctx, containerName, p)
+	if denied != nil || err != nil {
+		return denied, err
+	}
+
+	hdr, old, exists, err := c.readForWrite(ctx, containerName, target)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	if !exists && !create {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("%s does not exist", p)}, nil
+	}
+
+	updated, err := edit(old)
+	if err != nil {
+		return &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	if len(updated) > maxWriteBytes {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("result is too large: %d bytes (max %d)", len(updated), maxWriteBytes)}, nil
+	}
+
+	result := &FileAccessResult{
+		Success: true,
+		Created: !exists,
+		File: &FileEntry{
+			Name:    path.Base(target),
+			Path:    target,
+			Type:    "file",
+			Size:    int64(len(updated)),
+			Mode:    hdr.FileInfo().Mode().String(),
+			ModTime: hdr.ModTime,
+		},
+	}
+	if target != p {
+		result.ResolvedPath = target
+	}
+	if exists && updated == old {
+		return result, nil
+	}
+
+	if exists {
+		backup, denied, err := c.backupTarget(ctx, containerName, target)
+		if denied != nil || err != nil {
+			return denied, err
+		}
+		if err := c.uploadFile(ctx, containerName, backup, hdr, old); err != nil {
+			return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to write backup %s: %v", backup, err)}, nil
+		}
+		result.BackupPath = backup
+	}
+	if err := c.uploadFile(ctx, containerName, target, hdr, updated); err != nil {
+		return &FileAccessResult{Success: false, Error: fmt.Sprintf("failed to write %s: %v", target, err), BackupPath: result.BackupPath}, nil
+	}
+	result.Diff = unifiedDiff(target, old, updated)
+	return result, nil
+}
+
+// writableTarget checks blocked paths and writable_paths for p and returns its real
+// path, or a blocked result. Unlike statAllowedPath, p need not exist.
+//
+// writableTargetはpに対するブロックパスとwritable_pathsをチェックし、その実パス、
+// またはブロックの結果を返します。statAllowedPathと異なり、pは存在しなくても構いません。
+func (c *Client) writableTarget(ctx context.Context, containerName, p string) (string, *FileAccessResult, error) {
+	policy := c.GetPolicy()
+
+	if blocked := policy.IsPathBlocked(containerName, p); blocked != nil {
+		return "", &FileAccessResult{Success: false, Blocked: true, Block: blocked}, nil
+	}
+
+	// The real path decides both the block and the allowlist, so a symlink cannot
+	// redirect a write outside writable_paths
+	// ブロックと許可リストの両方を実パスで判定するため、シンボリックリンクで
+	// 書き込みをwritable_paths外に向けることはできない
+	resolution, err := c.resolvePath(ctx, containerName, "", p)
+	if err != nil {
+		return "", &FileAccessResult{Success: false, Error: err.Error()}, nil
+	}
+	if blocked, matched := policy.IsResolvedPathBlocked(containerName, resolution); blocked != nil {
+		return "", &FileAccessResult{
+			Success:      false,
+			Blocked:      true,
+			Block:        blocked,
+			ResolvedPath: resolution.Resolved,
+			MatchedPath:  matched,
+		}, nil
+	}
+	if _, ok := policy.IsPathWritable(containerName, resolution.Resolved); !ok {
+		return "", nil, fmt.Errorf("path is not writable: %s is not covered by writable_paths for container %s", resolution.Resolved, containerName)
+	}
+	return resolution.Resolved, nil, nil
+}
+
+// backupTarget returns the path the previous content of target is saved to: the first
+// of target.dkmcp.bak, target.dkmcp.bak.1, ... that does not exist yet, so an earlier
+// backup is never overwritten. The backup path is checked like the target itself and
+// must not be a symlink.
+//
+// backupTargetはtargetの以前の内容を保存するパスを返します：target.dkmcp.bak、
+// target.dkmcp.bak.1、...のうちまだ存在しない最初のものであり、以前のバックアップが
+// 上書きされることはありません。バックアップのパスはtarget自体と同様にチェックされ、
+// シンボリックリンクであってはなりません。
+func (c *Client) backupTarget(ctx context.Context, containerName, target string) (string, *FileAccessResult, error) {
+	for i := 0; i < maxBackups; i++ {
+		backup := backupName(target, i)
+		resolved, denied, err := c.writableTarget(ctx, containerName, backup)
+		if denied != nil || err != nil {
+			if denied != nil {
+				denied.Error = fmt.Sprintf("backup %s is not writable", backup)
+			}
+			return "", denied, err
+		}
+		if resolved != backup {
+			return "", nil, fmt.Errorf("backup %s is a symbolic link to %s; remove it first", backup, resolved)
+		}
+		if _, err := c.docker.ContainerStatPath(ctx, containerName, backup); client.IsErrNotFound(err) {
+			return backup, nil, nil
+		} else if err != nil {
+			return "", nil, fmt.Errorf("failed to check backup %s: %w", backup, err)
+		}
+	}
+	return "", nil, fmt.Errorf("%s already has %d backups; remove old %s files first", target, maxBackups, backupSuffix)
+}
+
+// backupName returns the name of the i-th backup of target.
+// backupNameはtargetのi番目のバックアップの名前を返します。
+func backupName(target string, i int) string {
+	if i == 0 {
+		return target + backupSuffix
+	}
+	return fmt.Sprintf("%s%s.%d", target, backupSuffix, i)
+}
+
+// readForWrite returns the tar header and content of the file at target, or a header for
+// a new file when it does not exist. Directories, binary files and files larger than
+// maxWriteBytes cannot be modified.
+//
+// readForWriteはtargetにあるファイルのtarヘッダーと内容を返し、存在しない場合は新しい
+// ファイル用のヘッダーを返します。ディレクトリ、バイナリファイル、maxWriteBytesより
+// 大きいファイルは変更できません。
+func (c *Client) readForWrite(ctx context.Context, containerName, target string) (*tar.Header, string, bool, error) {
+	stat, err := c.docker.ContainerStatPath(ctx, containerName, target)
+	if client.IsErrNotFound(err) {
+		dir, err := c.readDirHeader(ctx, containerName, path.Dir(target))
+		if err != nil {
+			return nil, "", false, err
+		}
+		return newFileHeader(dir), "", false, nil
+	}
+	if err != nil {
+		return nil, "", false, err
+	}
+	if !stat.Mode.IsRegular() {
+		return nil, "", false, fmt.Errorf("%s is not a regular file", target)
+	}
+	if stat.Size > maxWriteBytes {
+		return nil, "", false, fmt.Errorf("%s is too large to modify: %d bytes (max %d)", target, stat.Size, maxWriteBytes)
+	}
+
+	reader, _, err := c.docker.CopyFromContainer(ctx, containerName, target)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer reader.Close()
+
+	tr := tar.NewReader(reader)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to read archive: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(tr, maxWriteBytes+1))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(data) > maxWriteBytes {
+		return nil, "", false, fmt.Errorf("%s is too large to modify (max %d bytes)", target, maxWriteBytes)
+	}
+	if isBinary(data[:min(len(data), binarySniffBytes)]) {
+		return nil, "", false, fmt.Errorf("%s is a binary file, which cannot be modified", target)
+	}
+	return hdr, string(data), true, nil
+}
+
+// readDirHeader returns the tar header of the directory dir, which holds its owner and
+// mode. Only the first entry of the archive is read.
+//
+// readDirHeaderはディレクトリdirのtarヘッダーを返し、これはその所有者とモードを保持
+// します。アーカイブの最初のエントリのみを読み取ります。
+func (c *Client) readDirHeader(ctx context.Context, containerName, dir string) (*tar.Header, error) {
+	reader, _, err := c.docker.CopyFromContainer(ctx, containerName, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	defer reader.Close()
+
+	hdr, err := tar.NewReader(reader).Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return hdr, nil
+}
+
+// newFileHeader returns the header of a new file in the directory dir: the file is owned
+// by the directory's owner and gets the directory's permissions without the execute
+// bits, so a file created in a 0750 directory becomes 0640.
+//
+// newFileHeaderはディレクトリdir内の新しいファイルのヘッダーを返します：ファイルは
+// ディレクトリの所有者が所有し、ディレクトリのパーミッションから実行ビットを除いたものを
+// 得るため、0750のディレクトリに作成されたファイルは0640になります。
+func newFileHeader(dir *tar.Header) *tar.Header {
+	return &tar.Header{
+		Mode:    dir.Mode & 0666,
+		Uid:     dir.Uid,
+		Gid:     dir.Gid,
+		Uname:   dir.Uname,
+		Gname:   dir.Gname,
+		ModTime: time.Now(),
+	}
+}
+
+// uploadFile writes content to target through the archive API, keeping the mode and
+// owner of hdr.
+//
+// uploadFileはアーカイブAPIを通じてcontentをtargetに書き込み、hdrのモードと所有者を維持します。
+func (c *Client) uploadFile(ctx context.Context, containerName, target string, hdr *tar.Header, content string) error {
+	archive, err := fileArchive(path.Base(target), hdr, content)
+	if err != nil {
+		return err
+	}
+	return c.docker.CopyToContainer(ctx, containerName, path.Dir(target), archive, container.CopyToContainerOptions{})
+}
+
+// fileArchive returns a tar archive holding a single file name with content, taking the
+// mode and owner from hdr.
+//
+// fileArchiveはcontentを持つ単一のファイルnameを含むtarアーカイブを返し、モードと
+// 所有者はhdrから取ります。
+func fileArchive(name string, hdr *tar.Header, content string) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Typeflag: tar.TypeReg,
+		Mode:     hdr.Mode,
+		Uid:      hdr.Uid,
+		Gid:      hdr.Gid,
+		Uname:    hdr.Uname,
+		Gname:    hdr.Gname,
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build archive: %w", err)
+	}
+	if _, err := io.WriteString(tw, content); err != nil {
+		return nil, fmt.Errorf("failed to build archive: %w", err)
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build archive: %w", err)
+	}
+	return &buf, nil
+}
@@ -0,0 +1,112 @@
+// recorder.go samples the state and resource usage of the accessible containers at a
+// fixed interval and appends them to a Store.
+//
+// recorder.goはアクセス可能なコンテナの状態とリソース使用量を一定間隔でサンプリングし、
+// Storeに追記します。
+package history
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+)
+
+// Recorder periodically records the containers the security policy allows.
+// Recorderはセキュリティポリシーが許可するコンテナを定期的に記録します。
+type Recorder struct {
+	client   docker.DockerClientInterface
+	store    *Store
+	interval time.Duration
+}
+
+// NewRecorder returns a recorder that samples through client every interval into store.
+// NewRecorderはclientを通じてinterval毎にサンプリングしstoreに記録するレコーダーを返します。
+func NewRecorder(client docker.DockerClientInterface, store *Store, interval time.Duration) *Recorder {
+	return &Recorder{client: client, store: store, interval: interval}
+}
+
+// Run records once immediately and then every interval until ctx is cancelled.
+// Failures are logged and do not stop the recorder.
+//
+// Runは直ちに1回記録し、その後ctxがキャンセルされるまでinterval毎に記録します。
+// 失敗はログに記録され、レコーダーは停止しません。
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.RecordOnce(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("Failed to record container history", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecordOnce samples every accessible container once and appends the records. The
+// containers are listed and checked through the client, so the policy in effect at
+// the time of sampling decides what is recorded.
+//
+// RecordOnceはアクセス可能なすべてのコンテナを1回サンプリングしてレコードを追記します。
+// コンテナはクライアントを通じて列挙・チェックされるため、サンプリング時点で有効な
+// ポリシーが記録される内容を決めます。
+func (r *Recorder) RecordOnce(ctx context.Context) error {
+	// Keep one slow round from overlapping the next
+	// 遅い回が次の回と重ならないようにする
+	ctx, cancel := context.WithTimeout(ctx, r.interval)
+	defer cancel()
+
+	now := time.Now().UTC()
+	containers, err := r.client.ListContainers(ctx)
+	if err != nil {
+		return err
+	}
+
+	records := make([]Record, len(containers))
+	var wg sync.WaitGroup
+	for i, c := range containers {
+		wg.Add(1)
+		go func(i int, c docker.ContainerInfo) {
+			defer wg.Done()
+			records[i] = r.sample(ctx, now, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	return r.store.Append(records)
+}
+
+// sample builds the record of one container. Inspect and stats failures leave the
+// corresponding fields empty rather than dropping the record.
+//
+// sampleは1つのコンテナのレコードを構築します。検査や統計の失敗時はレコードを
+// 捨てずに対応するフィールドを空のままにします。
+func (r *Recorder) sample(ctx context.Context, now time.Time, c docker.ContainerInfo) Record {
+	rec := Record{Time: now, Container: c.Name, State: c.State}
+
+	if info, err := r.client.InspectContainer(ctx, c.Name); err == nil && info.ContainerJSONBase != nil {
+		rec.RestartCount = info.RestartCount
+		if info.State != nil {
+			rec.ExitCode = info.State.ExitCode
+			rec.OOMKilled = info.State.OOMKilled
+		}
+	} else if err != nil {
+		slog.Debug("History: failed to inspect container", "container", c.Name, "error", err)
+	}
+
+	if c.State == "running" && r.client.GetPolicy().CanGetStats(c.Name) {
+		stats, err := r.client.GetStats(ctx, c.Name)
+		if err != nil {
+			slog.Debug("History: failed to get stats", "container", c.Name, "error", err)
+		} else {
+			point := docker.NewStatsPoint(stats)
+			rec.Stats = &point
+		}
+	}
+	return rec
+}
@@ -0,0 +1,82 @@
+// recorder_test.go contains tests for sampling containers into the history.
+// recorder_test.goはコンテナを履歴にサンプリングするテストを含みます。
+package history
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// TestRecorder_RecordOnce tests that state, inspect details and stats are recorded,
+// and that a failing container still gets a record.
+//
+// TestRecorder_RecordOnceは状態、検査の詳細、統計が記録され、失敗したコンテナにも
+// レコードが作られることをテストします。
+func TestRecorder_RecordOnce(t *testing.T) {
+	mock := docker.NewMockClient(security.NewPolicy(&config.SecurityConfig{
+		Mode:              "moderate",
+		AllowedContainers: []string{"*"},
+		Permissions:       config.SecurityPermissions{Inspect: true, Stats: true},
+	}))
+	mock.ListContainersFunc = func(ctx context.Context) ([]docker.ContainerInfo, error) {
+		return []docker.ContainerInfo{
+			{Name: "api", State: "running"},
+			{Name: "worker", State: "exited"},
+			{Name: "cache", State: "running"},
+		}, nil
+	}
+	mock.InspectContainerFunc = func(ctx context.Context, name string) (*types.ContainerJSON, error) {
+		if name != "worker" {
+			return nil, errors.New("not found")
+		}
+		return &types.ContainerJSON{ContainerJSONBase: &types.ContainerJSONBase{
+			RestartCount: 2,
+			State:        &types.ContainerState{Status: "exited", ExitCode: 137, OOMKilled: true},
+		}}, nil
+	}
+	mock.GetStatsFunc = func(ctx context.Context, name string) (*container.StatsResponse, error) {
+		if name == "cache" {
+			return nil, errors.New("stats unavailable")
+		}
+		return &container.StatsResponse{Read: time.Now(), MemoryStats: container.MemoryStats{Usage: 42, Limit: 100}}, nil
+	}
+
+	store, err := OpenStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+
+	begin := time.Now().Add(-time.Second)
+	if err := NewRecorder(mock, store, time.Minute).RecordOnce(context.Background()); err != nil {
+		t.Fatalf("RecordOnce() error = %v", err)
+	}
+	end := time.Now().Add(time.Second)
+
+	query := func(name string) Record {
+		t.Helper()
+		records, err := store.Query(name, begin, end)
+		if err != nil || len(records) != 1 {
+			t.Fatalf("Query(%s) = %+v, %v; want one record", name, records, err)
+		}
+		return records[0]
+	}
+	if api := query("api"); api.Stats == nil || api.Stats.MemoryUsage != 42 {
+		t.Errorf("api record = %+v", api)
+	}
+	if worker := query("worker"); worker.Stats != nil || worker.ExitCode != 137 || !worker.OOMKilled || worker.RestartCount != 2 {
+		t.Errorf("worker record = %+v", worker)
+	}
+	if cache := query("cache"); cache.Stats != nil || cache.State != "running" {
+		t.Errorf("cache record = %+v", cache)
+	}
+}
@@ -0,0 +1,242 @@
+// series.go turns the records of a container into a time series of one metric, with a
+// summary over the range and the state changes (restarts, exits, OOM kills) in it.
+//
+// series.goはコンテナのレコードを1つのメトリクスの時系列に変換し、期間全体の要約と
+// その中の状態の変化（再起動、終了、OOMキル）を付けます。
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+)
+
+// metricValue extracts a metric from a record with stats. Rates need the previous
+// record with stats and are computed the way docker.SummarizeStats does.
+//
+// metricValueは統計を持つレコードからメトリクスを取り出します。レートは統計を持つ
+// 直前のレコードが必要で、docker.SummarizeStatsと同じ方法で計算されます。
+type metricValue func(cur, prev *docker.StatsPoint) (float64, bool)
+
+// gauge returns a metricValue that reads the current sample only.
+// gaugeは現在のサンプルのみを読むmetricValueを返します。
+func gauge(read func(p *docker.StatsPoint) float64) metricValue {
+	return func(cur, _ *docker.StatsPoint) (float64, bool) {
+		return read(cur), true
+	}
+}
+
+// rate returns a metricValue that reads a rate between the previous and current sample.
+// rateは直前と現在のサンプル間のレートを読むmetricValueを返します。
+func rate(read func(r docker.StatsRates) float64) metricValue {
+	return func(cur, prev *docker.StatsPoint) (float64, bool) {
+		if prev == nil {
+			return 0, false
+		}
+		return read(cur.RatesSince(*prev)), true
+	}
+}
+
+// metrics maps the metric names query_metrics accepts to their extractors.
+// metricsはquery_metricsが受け付けるメトリクス名を取り出し関数に対応付けます。
+var metrics = map[string]metricValue{
+	"cpu_percent":               gauge(func(p *docker.StatsPoint) float64 { return p.CPUPercent }),
+	"memory_percent":            gauge(func(p *docker.StatsPoint) float64 { return p.MemoryPercent }),
+	"memory_usage_bytes":        gauge(func(p *docker.StatsPoint) float64 { return float64(p.MemoryUsage) }),
+	"network_rx_bytes_per_sec":  rate(func(r docker.StatsRates) float64 { return r.NetworkRx }),
+	"network_tx_bytes_per_sec":  rate(func(r docker.StatsRates) float64 { return r.NetworkTx }),
+	"block_read_bytes_per_sec":  rate(func(r docker.StatsRates) float64 { return r.BlockRead }),
+	"block_write_bytes_per_sec": rate(func(r docker.StatsRates) float64 { return r.BlockWrite }),
+}
+
+// MetricNames lists the metrics a series can be built for.
+// MetricNamesは系列を構築できるメトリクスを列挙します。
+var MetricNames = []string{
+	"cpu_percent",
+	"memory_percent",
+	"memory_usage_bytes",
+	"network_rx_bytes_per_sec",
+	"network_tx_bytes_per_sec",
+	"block_read_bytes_per_sec",
+	"block_write_bytes_per_sec",
+}
+
+// MaxPoints caps the points of a series. Longer ranges are averaged into wider steps.
+// MaxPointsは系列のポイント数を制限します。より長い期間はより広いステップで平均化されます。
+const MaxPoints = 500
+
+// Point is one value of a series. With a step, Time is the start of the step and
+// Value the average over it.
+//
+// Pointは系列の1つの値です。ステップがある場合、Timeはステップの開始でValueはその平均です。
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Event reasons.
+// イベントの理由です。
+const (
+	EventStateChanged = "state_changed"
+	EventRestarted    = "restarted"
+	EventOOMKilled    = "oom_killed"
+)
+
+// Event is a change in the state of a container between two records.
+// Eventは2つのレコード間のコンテナの状態の変化です。
+type Event struct {
+	Time         time.Time `json:"time"`
+	Reason       string    `json:"reason"`
+	State        string    `json:"state"`
+	ExitCode     int       `json:"exit_code"`
+	RestartCount int       `json:"restart_count"`
+}
+
+// Series is one metric of a container over a time range.
+// Seriesは時間範囲にわたるコンテナの1つのメトリクスです。
+type Series struct {
+	Container string    `json:"container"`
+	Metric    string    `json:"metric"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+
+	// StepSeconds is the width the points were averaged over; 0 means raw samples
+	// StepSecondsはポイントを平均化した幅です。0は生のサンプルを意味します
+	StepSeconds int     `json:"step_seconds"`
+	Points      []Point `json:"points"`
+
+	// Summary covers every recorded value in the range, before averaging into steps
+	// Summaryはステップへの平均化の前の、期間内に記録されたすべての値を対象とします
+	Summary docker.MetricSummary `json:"summary"`
+	Events  []Event              `json:"events"`
+}
+
+// BuildSeries builds the series of metric from the records of one container in time
+// order. step averages the points over fixed-width buckets starting at since; when it
+// is 0 and there are more than MaxPoints values, a step is chosen to fit.
+//
+// BuildSeriesは時刻順に並んだ1つのコンテナのレコードからmetricの系列を構築します。
+// stepはsinceから始まる固定幅のバケットでポイントを平均化します。0でMaxPointsを超える
+// 値がある場合は、収まるステップが選ばれます。
+func BuildSeries(containerName, metric string, records []Record, since, until time.Time, step time.Duration) (*Series, error) {
+	value, ok := metrics[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q (valid: %s)", metric, strings.Join(MetricNames, ", "))
+	}
+
+	series := &Series{
+		Container: containerName,
+		Metric:    metric,
+		Since:     since,
+		Until:     until,
+		Points:    []Point{},
+		Events:    []Event{},
+	}
+
+	var values []float64
+	var prevStats *docker.StatsPoint
+	for i, rec := range records {
+		if i > 0 {
+			series.Events = append(series.Events, changes(records[i-1], rec)...)
+		} else if rec.OOMKilled {
+			series.Events = append(series.Events, newEvent(EventOOMKilled, rec))
+		}
+
+		// A gap in the stats, such as a stopped container, restarts the rates
+		// 停止したコンテナなど統計が途切れた場合はレートを計算し直す
+		if rec.Stats == nil {
+			prevStats = nil
+			continue
+		}
+		if v, ok := value(rec.Stats, prevStats); ok {
+			series.Points = append(series.Points, Point{Time: rec.Time, Value: v})
+			values = append(values, v)
+		}
+		prevStats = rec.Stats
+	}
+	series.Summary = docker.SummarizeMetric(values)
+
+	if step == 0 && len(series.Points) > MaxPoints {
+		step = (until.Sub(since) + MaxPoints - 1) / MaxPoints
+		step = ((step + time.Second - 1) / time.Second) * time.Second
+	}
+	if step > 0 {
+		series.Points = downsample(series.Points, since, step)
+		series.StepSeconds = int(step / time.Second)
+	}
+	return series, nil
+}
+
+// changes returns the events between two consecutive records of a container.
+// changesはコンテナの連続する2つのレコード間のイベントを返します。
+func changes(prev, cur Record) []Event {
+	var events []Event
+	if cur.OOMKilled && (!prev.OOMKilled || cur.RestartCount != prev.RestartCount) {
+		events = append(events, newEvent(EventOOMKilled, cur))
+	}
+	if cur.RestartCount > prev.RestartCount {
+		events = append(events, newEvent(EventRestarted, cur))
+	}
+	if cur.State != prev.State {
+		events = append(events, newEvent(EventStateChanged, cur))
+	}
+	return events
+}
+
+// newEvent returns an event for rec.
+// newEventはrecに対するイベントを返します。
+func newEvent(reason string, rec Record) Event {
+	return Event{
+		Time:         rec.Time,
+		Reason:       reason,
+		State:        rec.State,
+		ExitCode:     rec.ExitCode,
+		RestartCount: rec.RestartCount,
+	}
+}
+
+// downsample averages points in time order over buckets of step starting at since.
+// Empty buckets produce no point.
+//
+// downsampleは時刻順のポイントをsinceから始まるstep幅のバケットで平均化します。
+// 空のバケットはポイントを生成しません。
+func downsample(points []Point, since time.Time, step time.Duration) []Point {
+	out := []Point{}
+	var sum float64
+	var n int
+	var bucket int64 = -1
+	flush := func() {
+		if n > 0 {
+			out = append(out, Point{Time: since.Add(time.Duration(bucket) * step), Value: sum / float64(n)})
+		}
+	}
+	for _, p := range points {
+		b := int64(p.Time.Sub(since) / step)
+		if b != bucket {
+			flush()
+			bucket, sum, n = b, 0, 0
+		}
+		sum += p.Value
+		n++
+	}
+	flush()
+	return out
+}
+
+// ParseTime parses a time argument: an RFC 3339 timestamp or a duration before now
+// (e.g., "42m", "2h").
+//
+// ParseTimeは時刻の引数を解析します：RFC 3339のタイムスタンプ、またはnowより前の
+// 期間（例："42m"、"2h"）です。
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q (use a timestamp like 2024-01-01T10:00:00Z or a duration like 2h)", value)
+	}
+	return now.Add(-d), nil
+}
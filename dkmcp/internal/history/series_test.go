@@ -0,0 +1,135 @@
+// series_test.go contains tests for building metric series and events from records.
+// series_test.goはレコードからのメトリクス系列とイベントの構築のテストを含みます。
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+)
+
+// seriesTestRecords returns a minute of samples for a container that is OOM killed
+// and restarted halfway through.
+//
+// seriesTestRecordsは途中でOOMキルされ再起動されるコンテナの1分間のサンプルを返します。
+func seriesTestRecords(start time.Time) []Record {
+	var records []Record
+	for i := 0; i < 6; i++ {
+		at := start.Add(time.Duration(i) * 10 * time.Second)
+		rec := Record{Time: at, Container: "worker", State: "running"}
+		switch {
+		case i == 3:
+			rec.State, rec.ExitCode, rec.OOMKilled = "exited", 137, true
+		case i > 3:
+			// Docker clears OOMKilled when the container starts again
+			// Dockerはコンテナが再び開始するとOOMKilledをクリアする
+			rec.RestartCount = 1
+		}
+		if rec.State == "running" {
+			rec.Stats = &docker.StatsPoint{Time: at, MemoryUsage: uint64(100 * (i + 1)), NetworkRx: uint64(1000 * i)}
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+// TestBuildSeries tests gauges, rates across a gap and the events of a restart.
+// TestBuildSeriesはゲージ、途切れをまたぐレート、再起動のイベントをテストします。
+func TestBuildSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	records := seriesTestRecords(start)
+
+	mem, err := BuildSeries("worker", "memory_usage_bytes", records, start, start.Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("BuildSeries() error = %v", err)
+	}
+	if len(mem.Points) != 5 || mem.Summary.Max != 600 || mem.Summary.Min != 100 || mem.StepSeconds != 0 {
+		t.Errorf("memory series = %+v", mem)
+	}
+
+	wantEvents := []Event{
+		{Time: start.Add(30 * time.Second), Reason: EventOOMKilled, State: "exited", ExitCode: 137},
+		{Time: start.Add(30 * time.Second), Reason: EventStateChanged, State: "exited", ExitCode: 137},
+		{Time: start.Add(40 * time.Second), Reason: EventRestarted, State: "running", RestartCount: 1},
+		{Time: start.Add(40 * time.Second), Reason: EventStateChanged, State: "running", RestartCount: 1},
+	}
+	if len(mem.Events) != len(wantEvents) {
+		t.Fatalf("events = %+v, want %+v", mem.Events, wantEvents)
+	}
+	for i := range wantEvents {
+		if mem.Events[i] != wantEvents[i] {
+			t.Errorf("events[%d] = %+v, want %+v", i, mem.Events[i], wantEvents[i])
+		}
+	}
+
+	// Rates need two consecutive samples, so the stopped sample breaks the series
+	// レートは連続する2つのサンプルが必要なため、停止中のサンプルで系列が途切れる
+	rx, err := BuildSeries("worker", "network_rx_bytes_per_sec", records, start, start.Add(time.Minute), 0)
+	if err != nil {
+		t.Fatalf("BuildSeries() error = %v", err)
+	}
+	if len(rx.Points) != 3 || rx.Summary.Avg != 100 {
+		t.Errorf("rx series = %+v", rx)
+	}
+
+	if _, err := BuildSeries("worker", "disk_usage", records, start, start.Add(time.Minute), 0); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
+
+// TestBuildSeries_Step tests averaging into steps, chosen or automatic.
+// TestBuildSeries_Stepは指定または自動のステップへの平均化をテストします。
+func TestBuildSeries_Step(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var records []Record
+	for i := 0; i < 2*MaxPoints; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		records = append(records, Record{Time: at, Container: "api", State: "running", Stats: &docker.StatsPoint{CPUPercent: float64(i % 2)}})
+	}
+	until := start.Add(2 * MaxPoints * time.Second)
+
+	stepped, err := BuildSeries("api", "cpu_percent", records, start, until, 10*time.Second)
+	if err != nil {
+		t.Fatalf("BuildSeries() error = %v", err)
+	}
+	if stepped.StepSeconds != 10 || len(stepped.Points) != 2*MaxPoints/10 || stepped.Points[1].Value != 0.5 || !stepped.Points[1].Time.Equal(start.Add(10*time.Second)) {
+		t.Errorf("stepped series: step=%d points=%d first=%+v", stepped.StepSeconds, len(stepped.Points), stepped.Points[:2])
+	}
+	if stepped.Summary.Max != 1 {
+		t.Errorf("summary = %+v, want the raw max", stepped.Summary)
+	}
+
+	auto, err := BuildSeries("api", "cpu_percent", records, start, until, 0)
+	if err != nil {
+		t.Fatalf("BuildSeries() error = %v", err)
+	}
+	if auto.StepSeconds != 2 || len(auto.Points) > MaxPoints {
+		t.Errorf("automatic step = %d with %d points", auto.StepSeconds, len(auto.Points))
+	}
+}
+
+// TestParseTime tests timestamps and relative durations.
+// TestParseTimeはタイムスタンプと相対的な期間をテストします。
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string // Test case name / テストケース名
+		value   string // Argument / 引数
+		want    time.Time
+		wantErr bool
+	}{
+		{"timestamp", "2024-01-01T22:00:00Z", time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC), false},
+		{"relative", "12h", now.Add(-12 * time.Hour), false},
+		{"negative", "-1h", time.Time{}, true},
+		{"garbage", "yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.value, now)
+			if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, %v", tt.value, got, err)
+			}
+		})
+	}
+}
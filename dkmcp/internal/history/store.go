@@ -0,0 +1,316 @@
+// Package history records the resource usage and state of containers over time so
+// questions about the past (did the worker run out of memory last night?) can be answered.
+// Records are kept in a bounded ring of append-only JSON Lines segment files.
+//
+// historyパッケージはコンテナのリソース使用量と状態を時系列で記録し、過去についての質問
+// （昨夜ワーカーはメモリ不足になったか？）に答えられるようにします。レコードは追記専用の
+// JSON Linesのセグメントファイルによる上限付きのリングに保持されます。
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+)
+
+// Record is the state and resource usage of one container at one sampling round.
+// Recordは1回のサンプリングにおける1つのコンテナの状態とリソース使用量です。
+type Record struct {
+	// Time is when the sampling round started; all records of a round share it
+	// Timeはサンプリングの開始時刻です。同じ回のレコードはすべて同じ値を持ちます
+	Time time.Time `json:"time"`
+
+	// Container is the container name
+	// Containerはコンテナ名です
+	Container string `json:"container"`
+
+	// State is the container state (e.g., "running", "exited")
+	// Stateはコンテナの状態です（例："running"、"exited"）
+	State string `json:"state"`
+
+	// ExitCode, OOMKilled and RestartCount come from inspecting the container
+	// ExitCode、OOMKilled、RestartCountはコンテナの検査から得られます
+	ExitCode     int  `json:"exit_code,omitempty"`
+	OOMKilled    bool `json:"oom_killed,omitempty"`
+	RestartCount int  `json:"restart_count,omitempty"`
+
+	// Stats is nil when the container was not running or its stats could not be read
+	// Statsはコンテナが実行中でないか統計を読み取れなかった場合nilです
+	Stats *docker.StatsPoint `json:"stats,omitempty"`
+}
+
+// segmentCount is the number of segment files the size limit is split into. When the
+// limit is reached the oldest segment is removed, so at least (segmentCount-1)/segmentCount
+// of the limit is always history.
+//
+// segmentCountはサイズ上限を分割するセグメントファイルの数です。上限に達すると最も古い
+// セグメントが削除されるため、常に上限の(segmentCount-1)/segmentCount以上が履歴として残ります。
+const segmentCount = 8
+
+// minSegmentBytes keeps segments from becoming tiny with a small size limit.
+// minSegmentBytesは小さなサイズ上限でセグメントが極端に小さくなるのを防ぎます。
+const minSegmentBytes = 64 << 10
+
+// Segment files are named metrics-<start>.jsonl, where start is the Unix time in
+// nanoseconds of the first record, zero-padded so names sort by time.
+//
+// セグメントファイルの名前はmetrics-<start>.jsonlで、startは最初のレコードのUnix時刻
+// （ナノ秒）です。名前が時刻順に並ぶようゼロ埋めされます。
+const (
+	segmentPrefix = "metrics-"
+	segmentSuffix = ".jsonl"
+)
+
+// Store is a bounded on-disk ring of records. It is safe for concurrent use.
+// Storeはレコードのディスク上の上限付きリングです。並行して使用しても安全です。
+type Store struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+
+	// mu protects the current segment
+	// muは現在のセグメントを保護します
+	mu          sync.Mutex
+	current     *os.File
+	currentSize int64
+}
+
+// segment is a segment file and the time of its first record.
+// segmentはセグメントファイルとその最初のレコードの時刻です。
+type segment struct {
+	path  string
+	start time.Time
+	size  int64
+}
+
+// OpenStore opens the store in dir, creating the directory if needed. The segment
+// files together are kept under maxBytes.
+//
+// OpenStoreはdirのストアを開き、必要に応じてディレクトリを作成します。
+// セグメントファイルの合計はmaxBytes未満に保たれます。
+func OpenStore(dir string, maxBytes int64) (*Store, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("invalid history size limit: %d", maxBytes)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	segmentBytes := maxBytes / segmentCount
+	if segmentBytes < minSegmentBytes {
+		segmentBytes = minSegmentBytes
+	}
+	return &Store{dir: dir, maxBytes: maxBytes, segmentBytes: segmentBytes}, nil
+}
+
+// Dir returns the directory of the store.
+// Dirはストアのディレクトリを返します。
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Append writes records to the current segment, starting a new segment when the
+// current one is full and removing the oldest segments beyond the size limit.
+//
+// Appendはレコードを現在のセグメントに書き込みます。現在のセグメントが一杯の場合は
+// 新しいセグメントを開始し、サイズ上限を超えた最も古いセグメントを削除します。
+func (s *Store) Append(records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buf []byte
+	for _, r := range records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current == nil || s.currentSize >= s.segmentBytes {
+		if err := s.rotate(records[0].Time); err != nil {
+			return err
+		}
+	}
+	n, err := s.current.Write(buf)
+	s.currentSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// rotate closes the current segment, starts a new one named after start and prunes
+// the oldest segments. The caller holds s.mu.
+//
+// rotateは現在のセグメントを閉じ、startにちなんだ名前の新しいセグメントを開始して、
+// 最も古いセグメントを削除します。呼び出し元はs.muを保持します。
+func (s *Store) rotate(start time.Time) error {
+	if s.current != nil {
+		s.current.Close()
+		s.current = nil
+	}
+	name := fmt.Sprintf("%s%020d%s", segmentPrefix, start.UnixNano(), segmentSuffix)
+	f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create history segment: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to create history segment: %w", err)
+	}
+	s.current = f
+	s.currentSize = info.Size()
+
+	// Remove the oldest segments until the rest fit in the limit, never the new one
+	// 残りが上限に収まるまで最も古いセグメントを削除。新しいセグメントは削除しない
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, seg := range segments {
+		total += seg.size
+	}
+	for i := 0; i < len(segments)-1 && total+s.segmentBytes > s.maxBytes; i++ {
+		if err := os.Remove(segments[i].path); err != nil {
+			return fmt.Errorf("failed to remove old history segment: %w", err)
+		}
+		total -= segments[i].size
+	}
+	return nil
+}
+
+// segments lists the segment files in time order.
+// segmentsはセグメントファイルを時刻順に列挙します。
+func (s *Store) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %w", err)
+	}
+	var segments []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{
+			path:  filepath.Join(s.dir, name),
+			start: time.Unix(0, nanos),
+			size:  info.Size(),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].start.Before(segments[j].start) })
+	return segments, nil
+}
+
+// Query returns the records of a container from since up to and including until, in
+// time order. Lines that cannot be decoded, such as one cut short by a crash, are skipped.
+//
+// Queryはsinceからuntilまで（untilを含む）のコンテナのレコードを時刻順に返します。
+// クラッシュで途中で切れた行など、デコードできない行はスキップされます。
+func (s *Store) Query(containerName string, since, until time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for i, seg := range segments {
+		// A segment only holds records from before the next one started
+		// セグメントは次のセグメントの開始より前のレコードのみを持つ
+		if i+1 < len(segments) && !segments[i+1].start.After(since) {
+			continue
+		}
+		if seg.start.After(until) {
+			break
+		}
+		found, err := readSegment(seg.path, containerName, since, until)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, found...)
+	}
+	return records, nil
+}
+
+// readSegment reads the matching records of one segment file.
+// readSegmentは1つのセグメントファイルから一致するレコードを読み取ります。
+func readSegment(path, containerName string, since, until time.Time) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history segment: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if r.Container != containerName || r.Time.Before(since) || r.Time.After(until) {
+			continue
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history segment: %w", err)
+	}
+	return records, nil
+}
+
+// Close closes the current segment.
+// Closeは現在のセグメントを閉じます。
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	return err
+}
+
+// ResolveDir returns the absolute path of a history directory, expanding ~ to the
+// user's home directory.
+//
+// ResolveDirは履歴ディレクトリの絶対パスを返します。~をユーザーのホームディレクトリに展開します。
+func ResolveDir(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot resolve home directory: %w", err)
+		}
+		dir = filepath.Join(home, strings.TrimPrefix(dir[1:], "/"))
+	}
+	return filepath.Abs(dir)
+}
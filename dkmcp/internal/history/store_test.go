@@ -0,0 +1,133 @@
+// store_test.go contains tests for the on-disk history ring.
+// store_test.goはディスク上の履歴リングのテストを含みます。
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+)
+
+// TestStore_AppendQuery tests that records are read back by container and time range.
+// TestStore_AppendQueryはレコードがコンテナと時間範囲で読み戻されることをテストします。
+func TestStore_AppendQuery(t *testing.T) {
+	store, err := OpenStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		err := store.Append([]Record{
+			{Time: at, Container: "api", State: "running", Stats: &docker.StatsPoint{Time: at, MemoryUsage: uint64(i)}},
+			{Time: at, Container: "db", State: "exited", ExitCode: 1},
+		})
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	got, err := store.Query("api", start.Add(time.Minute), start.Add(3*time.Minute))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) != 3 || got[0].Stats.MemoryUsage != 1 || got[2].Stats.MemoryUsage != 3 {
+		t.Errorf("Query(api) = %+v, want minutes 1 to 3", got)
+	}
+
+	got, err = store.Query("db", start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) != 5 || got[0].ExitCode != 1 || got[0].Stats != nil {
+		t.Errorf("Query(db) = %+v", got)
+	}
+}
+
+// TestStore_Ring tests that the oldest segments are removed to stay under the size limit.
+// TestStore_Ringはサイズ上限内に収まるよう最も古いセグメントが削除されることをテストします。
+func TestStore_Ring(t *testing.T) {
+	dir := t.TempDir()
+	const maxBytes = 4 * minSegmentBytes
+	store, err := OpenStore(dir, maxBytes)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+
+	// Each record is about 100 bytes, so this writes several times the limit
+	// 各レコードは約100バイトのため、上限の数倍を書き込む
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	padding := strings.Repeat("x", 60)
+	const rounds = 40000
+	for i := 0; i < rounds; i++ {
+		if err := store.Append([]Record{{Time: start.Add(time.Duration(i) * time.Second), Container: "api", State: padding}}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	var total int64
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		info, _ := e.Info()
+		total += info.Size()
+	}
+	if total > maxBytes+minSegmentBytes/8 {
+		t.Errorf("history uses %d bytes, limit %d", total, maxBytes)
+	}
+
+	got, err := store.Query("api", start, start.Add(rounds*time.Second))
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if len(got) == 0 || got[0].Time.Equal(start) || !got[len(got)-1].Time.Equal(start.Add((rounds-1)*time.Second)) {
+		t.Errorf("expected only the newest records to remain, got %d from %v", len(got), got[0].Time)
+	}
+}
+
+// TestStore_SkipsBrokenLines tests that a line cut short by a crash does not fail queries.
+// TestStore_SkipsBrokenLinesはクラッシュで途中で切れた行がクエリを失敗させないことをテストします。
+func TestStore_SkipsBrokenLines(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(dir, 1<<20)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := store.Append([]Record{{Time: at, Container: "api", State: "running"}}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	store.Close()
+
+	entries, _ := os.ReadDir(dir)
+	f, err := os.OpenFile(filepath.Join(dir, entries[0].Name()), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2024-01-01T00:01:00Z","contai`)
+	f.Close()
+
+	got, err := store.Query("api", at, at.Add(time.Hour))
+	if err != nil || len(got) != 1 {
+		t.Errorf("Query() = %+v, %v; want the one complete record", got, err)
+	}
+}
+
+// TestResolveDir tests expanding ~ in the history directory.
+// TestResolveDirは履歴ディレクトリの~の展開をテストします。
+func TestResolveDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	got, err := ResolveDir("~/.dkmcp/metrics")
+	if err != nil || got != filepath.Join(home, ".dkmcp/metrics") {
+		t.Errorf("ResolveDir() = %q, %v", got, err)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/cmdrule"
 )
 
 // Result holds the output of a tool/command execution.
@@ -140,58 +142,11 @@ func getExtension(name string) string {
 	return ""
 }
 
-// parseCommandArgs splits a command string into arguments.
-// Handles quoted strings (single and double quotes).
+// parseCommandArgs splits a command string into arguments with cmdrule.Split, the same
+// splitting the host command whitelist is matched against.
 //
-// parseCommandArgsはコマンド文字列を引数に分割します。
-// クォート文字列（シングルおよびダブルクォート）を処理します。
+// parseCommandArgsはホストコマンドのホワイトリストの照合に使われるのと同じ分割である
+// cmdrule.Splitでコマンド文字列を引数に分割します。
 func parseCommandArgs(command string) ([]string, error) {
-	var args []string
-	var current strings.Builder
-	inSingle := false
-	inDouble := false
-	escaped := false
-
-	for _, ch := range command {
-		if escaped {
-			current.WriteRune(ch)
-			escaped = false
-			continue
-		}
-
-		if ch == '\\' && !inSingle {
-			escaped = true
-			continue
-		}
-
-		if ch == '\'' && !inDouble {
-			inSingle = !inSingle
-			continue
-		}
-
-		if ch == '"' && !inSingle {
-			inDouble = !inDouble
-			continue
-		}
-
-		if ch == ' ' && !inSingle && !inDouble {
-			if current.Len() > 0 {
-				args = append(args, current.String())
-				current.Reset()
-			}
-			continue
-		}
-
-		current.WriteRune(ch)
-	}
-
-	if inSingle || inDouble {
-		return nil, fmt.Errorf("unclosed quote in command: %s", command)
-	}
-
-	if current.Len() > 0 {
-		args = append(args, current.String())
-	}
-
-	return args, nil
+	return cmdrule.Split(command)
 }
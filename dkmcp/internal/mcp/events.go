@@ -0,0 +1,129 @@
+// events.go provides the get_events MCP handler and the watcher that turns state changes
+// on Docker's event stream into notifications/message for every connected session.
+//
+// events.goはget_events MCPハンドラーと、Dockerのイベントストリーム上の状態変化を
+// 接続中のすべてのセッションへのnotifications/messageに変換するウォッチャーを提供します。
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+)
+
+const (
+	// getEventsDefaultSince is the start of the range when since is not given
+	// getEventsDefaultSinceはsinceが指定されない場合の範囲の開始です
+	getEventsDefaultSince = "1h"
+
+	// eventsLoggerName is the logger of the notifications/message sent for container events
+	// eventsLoggerNameはコンテナイベントについて送るnotifications/messageのロガーです
+	eventsLoggerName = "docker_events"
+
+	// eventsRetryDelay is the wait before resubscribing after the event stream fails
+	// eventsRetryDelayはイベントストリームが失敗した後に再購読するまでの待ち時間です
+	eventsRetryDelay = 5 * time.Second
+)
+
+// toolGetEvents implements the get_events MCP tool.
+// toolGetEventsはget_events MCPツールを実装します。
+func (s *Server) toolGetEvents(ctx context.Context, args map[string]any) (any, error) {
+	container, _ := args["container"].(string)
+	var types []string
+	if raw, ok := args["types"].([]any); ok {
+		for _, t := range raw {
+			if name, ok := t.(string); ok && name != "" {
+				types = append(types, name)
+			}
+		}
+	}
+	since := getEventsDefaultSince
+	if v, ok := args["since"].(string); ok && v != "" {
+		since = v
+	}
+	until, _ := args["until"].(string)
+
+	slog.Debug("Getting events", "container", container, "types", types, "since", since, "until", until)
+	events, err := s.docker.GetEvents(ctx, container, since, until, types)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []docker.ContainerEvent{}
+	}
+	return structuredResponse(map[string]any{
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// eventLevel returns the notifications/message level for a state change: error for an
+// OOM kill, warning for a failed exit or an unhealthy container, info otherwise.
+//
+// eventLevelは状態変化に対するnotifications/messageのレベルを返します：OOMキルはerror、
+// 失敗した終了または異常なコンテナはwarning、それ以外はinfoです。
+func eventLevel(e docker.ContainerEvent) string {
+	switch {
+	case e.Action == "oom":
+		return "error"
+	case e.Action == "die" && e.ExitCode != nil && *e.ExitCode != 0:
+		return "warning"
+	case e.Action == "health_status" && e.Health == "unhealthy":
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// handleContainerEvent records a state change in the audit log and notifies the sessions.
+// Other events are ignored.
+//
+// handleContainerEventは状態変化を監査ログに記録し、セッションに通知します。
+// その他のイベントは無視されます。
+func (s *Server) handleContainerEvent(ctx context.Context, e docker.ContainerEvent) {
+	if !e.IsStateChange() {
+		return
+	}
+	details := map[string]any{"action": e.Action}
+	if e.ExitCode != nil {
+		details["exit_code"] = *e.ExitCode
+	}
+	if e.Health != "" {
+		details["health"] = e.Health
+	}
+	audit.LogContainerState(ctx, e.Container, details)
+
+	level := eventLevel(e)
+	notified := s.NotifyMessage(level, eventsLoggerName, map[string]any{
+		"message": e.Summary(),
+		"event":   e,
+	})
+	slog.Info("Container state changed", "container", e.Container, "action", e.Action, "level", level, "notified", notified)
+}
+
+// WatchContainerEvents follows Docker's event stream until ctx is cancelled, notifying
+// sessions when an allowed container dies, is OOM killed or changes health. The stream
+// is resubscribed after a short delay when it fails, e.g. while Docker restarts.
+//
+// WatchContainerEventsはctxがキャンセルされるまでDockerのイベントストリームを追跡し、
+// 許可されたコンテナが停止、OOMキル、またはヘルス変化した時にセッションへ通知します。
+// Dockerの再起動中などストリームが失敗した場合は、少し待ってから再購読します。
+func (s *Server) WatchContainerEvents(ctx context.Context) {
+	for {
+		err := s.docker.WatchEvents(ctx, func(e docker.ContainerEvent) {
+			s.handleContainerEvent(ctx, e)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Docker event stream failed, resubscribing", "error", err, "retry", eventsRetryDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(eventsRetryDelay):
+		}
+	}
+}
@@ -0,0 +1,513 @@
+// jobs.go implements exec jobs: whitelisted commands started with start_exec_job that keep
+// running after the tool call returns, so a long test suite does not tie up the session.
+// Their output is polled with get_job_output and they are stopped with cancel_job.
+// Jobs are kept in a bounded table in the server and belong to the session that started them.
+//
+// jobs.goはexecジョブを実装します。execジョブはstart_exec_jobで開始され、ツール呼び出しが
+// 戻った後も実行を続けるホワイトリストのコマンドで、長いテストスイートがセッションを
+// 占有しないようにします。出力はget_job_outputでポーリングし、cancel_jobで停止します。
+// ジョブはサーバー内の上限付きテーブルに保持され、開始したセッションに属します。
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+const (
+	// jobRetention is how long a finished job stays in the table for get_job_output
+	// jobRetentionは終了したジョブがget_job_outputのためにテーブルに残る時間です
+	jobRetention = time.Hour
+
+	// jobOutputDefaultMaxBytes is the output one get_job_output returns when max_bytes is not given
+	// jobOutputDefaultMaxBytesはmax_bytesが指定されない場合に1回のget_job_outputが返す出力です
+	jobOutputDefaultMaxBytes = 64 << 10
+)
+
+// Job states reported by the job tools.
+// ジョブツールが報告するジョブの状態です。
+const (
+	jobRunning   = "running"
+	jobExited    = "exited"
+	jobTimedOut  = "timed_out"
+	jobCancelled = "cancelled"
+	jobFailed    = "failed"
+)
+
+// execJob is one command running, or having run, in the background.
+// execJobはバックグラウンドで実行中、または実行済みの1つのコマンドです。
+type execJob struct {
+	id          string
+	owner       string
+	container   string
+	command     string
+	dangerously bool
+	opts        security.ExecOptions
+	startedAt   time.Time
+
+	// ctx is the job's context, which cancel ends to stop the exec; done is closed once
+	// the job has ended
+	// ctxはジョブのコンテキストで、cancelで終了してexecを停止します。doneはジョブの
+	// 終了後にクローズされます
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// mu protects the fields below, which change while the job runs
+	// muは以下のフィールドを保護します。これらはジョブの実行中に変化します
+	mu        sync.Mutex
+	output    []byte
+	state     string
+	exitCode  int
+	truncated bool
+	err       string
+	endedAt   time.Time
+}
+
+// Write appends output of the running command. The exec keeps it within exec_output.
+// Writeは実行中のコマンドの出力を追加します。execがexec_outputの範囲内に保ちます。
+func (j *execJob) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.output = append(j.output, p...)
+	return len(p), nil
+}
+
+// running reports whether the job has not ended yet.
+// runningはジョブがまだ終了していないかどうかを報告します。
+func (j *execJob) running() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state == jobRunning
+}
+
+// info returns the job's metadata for the tool results (without the output).
+// infoはツール結果用のジョブのメタデータを（出力なしで）返します。
+func (j *execJob) info() map[string]any {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	end := time.Now()
+	if j.state != jobRunning {
+		end = j.endedAt
+	}
+	info := map[string]any{
+		"job_id":       j.id,
+		"container":    j.container,
+		"command":      j.command,
+		"state":        j.state,
+		"started_at":   j.startedAt.UTC().Format(time.RFC3339),
+		"duration_ms":  end.Sub(j.startedAt).Milliseconds(),
+		"output_bytes": len(j.output),
+		"truncated":    j.truncated,
+	}
+	if j.state != jobRunning {
+		info["ended_at"] = j.endedAt.UTC().Format(time.RFC3339)
+	}
+	if j.state == jobExited || j.state == jobTimedOut {
+		info["exit_code"] = j.exitCode
+	}
+	if j.err != "" {
+		info["error"] = j.err
+	}
+	addExecOptions(info, j.opts)
+	return info
+}
+
+// readOutput returns the output from offset, at most maxBytes of it. While the job runs,
+// or when the chunk is cut by maxBytes, it ends after the last complete line so masking
+// patterns never see half a line; a single line longer than maxBytes is cut on a
+// character boundary instead, returning at least one whole character. complete is true
+// once the job has ended and the chunk reaches the end of the output.
+//
+// readOutputはoffsetからの出力を最大maxBytes返します。ジョブの実行中、またはチャンクが
+// maxBytesで切られる場合は、マスキングのパターンが行の途中を見ないよう最後の完全な行で
+// 終わります。maxBytesより長い1行は代わりに文字境界で切り、少なくとも1文字全体を返します。completeはジョブが終了し、
+// チャンクが出力の末尾に達した時にtrueです。
+func (j *execJob) readOutput(offset, maxBytes int) (chunk string, next int, complete bool, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if offset < 0 || offset > len(j.output) {
+		return "", 0, false, fmt.Errorf("offset %d is outside the job output (%d bytes)", offset, len(j.output))
+	}
+	rest := j.output[offset:]
+	cut := len(rest) > maxBytes
+	if cut {
+		rest = rest[:maxBytes]
+	}
+	if cut || j.state == jobRunning {
+		if i := bytes.LastIndexByte(rest, '\n'); i >= 0 {
+			rest = rest[:i+1]
+		} else if cut {
+			n := len(rest)
+			for n > 0 && !utf8.RuneStart(j.output[offset+n]) {
+				n--
+			}
+			// A character wider than maxBytes is returned whole, so polling always advances
+			// maxBytesより幅の広い文字はそのまま返し、ポーリングが常に進むようにする
+			if n == 0 && (utf8.FullRune(j.output[offset:]) || j.state != jobRunning) {
+				_, n = utf8.DecodeRune(j.output[offset:])
+			}
+			rest = j.output[offset : offset+n]
+		} else {
+			rest = nil
+		}
+	}
+	next = offset + len(rest)
+	return string(rest), next, j.state != jobRunning && next == len(j.output), nil
+}
+
+// finish records how the job ended.
+// finishはジョブがどのように終了したかを記録します。
+func (j *execJob) finish(state string, exitCode int, truncated bool, errMsg string) {
+	j.mu.Lock()
+	j.state = state
+	j.exitCode = exitCode
+	j.truncated = truncated
+	j.err = errMsg
+	j.endedAt = time.Now()
+	j.mu.Unlock()
+	close(j.done)
+}
+
+// jobTable holds the exec jobs of a server. Its context is the parent of every job's
+// context, so stopping the table stops every job.
+//
+// jobTableはサーバーのexecジョブを保持します。そのコンテキストはすべてのジョブの
+// コンテキストの親であり、テーブルを停止するとすべてのジョブが停止します。
+type jobTable struct {
+	mu     sync.Mutex
+	jobs   map[string]*execJob
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newJobTable creates an empty job table.
+// newJobTableは空のジョブテーブルを作成します。
+func newJobTable() *jobTable {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &jobTable{jobs: make(map[string]*execJob), ctx: ctx, cancel: cancel}
+}
+
+// add inserts a job, first dropping finished jobs past their retention. When the table
+// holds max jobs the oldest finished one is dropped; when all of them are running the
+// job is refused.
+//
+// addはジョブを挿入します。先に保持期間を過ぎた終了済みジョブを破棄します。テーブルが
+// max個のジョブを保持している場合は最も古い終了済みジョブを破棄し、すべて実行中の場合は
+// ジョブを拒否します。
+func (t *jobTable) add(j *execJob, max int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var oldest *execJob
+	for id, other := range t.jobs {
+		other.mu.Lock()
+		finished, endedAt := other.state != jobRunning, other.endedAt
+		other.mu.Unlock()
+		if !finished {
+			continue
+		}
+		if now.Sub(endedAt) > jobRetention {
+			delete(t.jobs, id)
+			continue
+		}
+		if oldest == nil || other.startedAt.Before(oldest.startedAt) {
+			oldest = other
+		}
+	}
+	if len(t.jobs) >= max {
+		if oldest == nil {
+			return fmt.Errorf("too many running jobs (exec_jobs.max_jobs: %d); wait for one to end or cancel one", max)
+		}
+		delete(t.jobs, oldest.id)
+	}
+	t.jobs[j.id] = j
+	return nil
+}
+
+// remove drops a job from the table.
+// removeはテーブルからジョブを破棄します。
+func (t *jobTable) remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.jobs, id)
+}
+
+// get returns the job with the given ID. Jobs of other clients are reported as not found.
+// getは指定されたIDのジョブを返します。他のクライアントのジョブは見つからないと報告します。
+func (t *jobTable) get(id, owner string) (*execJob, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	j, ok := t.jobs[id]
+	if !ok || j.owner != owner {
+		return nil, fmt.Errorf("job not found: %s", id)
+	}
+	return j, nil
+}
+
+// list returns the jobs of a client, oldest first.
+// listはクライアントのジョブを古い順に返します。
+func (t *jobTable) list(owner string) []*execJob {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var jobs []*execJob
+	for _, j := range t.jobs {
+		if j.owner == owner {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].startedAt.Before(jobs[b].startedAt) })
+	return jobs
+}
+
+// stop cancels every running job.
+// stopは実行中のすべてのジョブをキャンセルします。
+func (t *jobTable) stop() {
+	t.cancel()
+}
+
+// newJobID returns a fresh random job ID.
+// newJobIDは新しいランダムなジョブIDを返します。
+func newJobID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "job-" + hex.EncodeToString(b)
+}
+
+// jobOwnerKey is the context key under which the owner of the calling client is stored.
+// jobOwnerKeyは呼び出し元クライアントの所有者を格納するコンテキストキーです。
+type jobOwnerKey struct{}
+
+// withJobOwner returns a copy of ctx carrying the job owner of the calling client.
+// withJobOwnerは呼び出し元クライアントのジョブ所有者を保持するctxのコピーを返します。
+func withJobOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, jobOwnerKey{}, owner)
+}
+
+// jobOwnerFromContext returns the job owner stored in ctx, or "".
+// jobOwnerFromContextはctxに格納されたジョブ所有者を返します。ない場合は""です。
+func jobOwnerFromContext(ctx context.Context) string {
+	owner, _ := ctx.Value(jobOwnerKey{}).(string)
+	return owner
+}
+
+// jobOwner identifies the owner of the jobs a client starts: its session ID. The ID is
+// issued by the server from crypto/rand for both legacy SSE and Streamable HTTP sessions,
+// so unlike the self-reported client name it is hard to guess, and only the session
+// that started a job (or a process resuming that Streamable HTTP session, as the
+// `dkmcp client jobs` commands do) can see or cancel it.
+//
+// jobOwnerはクライアントが開始するジョブの所有者を識別します：そのセッションIDです。
+// IDはレガシーSSEとStreamable HTTPのどちらのセッションでもサーバーがcrypto/randから発行し、
+// 自己申告のクライアント名と異なり推測困難なため、ジョブを開始した
+// セッション（または`dkmcp client jobs`コマンドのようにそのStreamable HTTPセッションを
+// 再開するプロセス）だけがジョブを参照またはキャンセルできます。
+func jobOwner(c *client) string {
+	return c.id
+}
+
+// jobIDArg extracts the required job_id argument.
+// jobIDArgは必須のjob_id引数を抽出します。
+func jobIDArg(args map[string]any) (string, error) {
+	id, ok := args["job_id"].(string)
+	if !ok || id == "" {
+		return "", fmt.Errorf("missing or invalid job_id parameter")
+	}
+	return id, nil
+}
+
+// toolStartExecJob implements the start_exec_job MCP tool.
+// toolStartExecJobはstart_exec_job MCPツールを実装します。
+func (s *Server) toolStartExecJob(ctx context.Context, args map[string]any) (any, error) {
+	container, ok := args["container"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+	command, ok := args["command"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid command parameter")
+	}
+	dangerously, _ := args["dangerously"].(bool)
+	opts, err := execOptionsArg(args)
+	if err != nil {
+		return nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(s.jobs.ctx)
+	j := &execJob{
+		id:          newJobID(),
+		owner:       jobOwnerFromContext(ctx),
+		container:   container,
+		command:     command,
+		dangerously: dangerously,
+		opts:        opts,
+		startedAt:   time.Now(),
+		ctx:         jobCtx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+		state:       jobRunning,
+	}
+
+	// Reserve the slot first so concurrent starts cannot overfill the table
+	// 同時の開始がテーブルを溢れさせないよう、先に枠を確保する
+	if err := s.jobs.add(j, s.docker.GetPolicy().MaxExecJobs()); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The Docker client checks the whitelist (or exec_dangerously list if dangerously=true)
+	// Dockerクライアントはホワイトリスト（dangerously=trueの場合はexec_dangerouslyリスト）をチェック
+	wait, err := s.docker.StartExec(j.ctx, container, command, dangerously, opts, j)
+	if err != nil {
+		cancel()
+		s.jobs.remove(j.id)
+		slog.Warn("Exec job blocked", "container", container, "command", command, "dangerously", dangerously, "error", err.Error())
+		return nil, err
+	}
+	slog.Info("Started exec job", "job_id", j.id, "container", container, "command", command, "dangerously", dangerously)
+
+	go s.runJob(j, wait)
+
+	return structuredResponse(j.info())
+}
+
+// runJob waits for a job to end, records the outcome and audits it as a start_exec_job call.
+// runJobはジョブの終了を待ち、結果を記録して、start_exec_jobの呼び出しとして監査します。
+func (s *Server) runJob(j *execJob, wait func() (*docker.ExecResult, error)) {
+	defer j.cancel()
+	result, err := wait()
+
+	details := map[string]any{"job_id": j.id, "command": j.command, "dangerously": j.dangerously}
+	addExecOptions(details, j.opts)
+	auditResult := audit.ResultSuccess
+	switch {
+	case err != nil && j.ctx.Err() != nil:
+		j.finish(jobCancelled, 0, false, "")
+		auditResult = audit.ResultCancelled
+	case err != nil:
+		j.finish(jobFailed, 0, false, err.Error())
+		auditResult = audit.ResultError
+	case result.TimedOut:
+		j.finish(jobTimedOut, result.ExitCode, result.Truncated, "")
+		details["exit_code"] = result.ExitCode
+		auditResult = audit.ResultTimeout
+	default:
+		j.finish(jobExited, result.ExitCode, result.Truncated, "")
+		details["exit_code"] = result.ExitCode
+	}
+	durationMs := audit.MeasureDuration(j.startedAt)
+	audit.LogToolCall(context.Background(), "start_exec_job", j.container, auditResult, durationMs, details)
+	slog.Info("Exec job ended", "job_id", j.id, "container", j.container, "command", j.command, "result", auditResult, "duration_ms", durationMs)
+}
+
+// toolGetJobOutput implements the get_job_output MCP tool.
+// toolGetJobOutputはget_job_output MCPツールを実装します。
+func (s *Server) toolGetJobOutput(ctx context.Context, args map[string]any) (any, error) {
+	id, err := jobIDArg(args)
+	if err != nil {
+		return nil, err
+	}
+	offset := 0
+	if o, ok := args["offset"].(float64); ok {
+		offset = int(o)
+	}
+	maxBytes := jobOutputDefaultMaxBytes
+	if m, ok := args["max_bytes"].(float64); ok && m > 0 {
+		maxBytes = int(m)
+	}
+
+	j, err := s.jobs.get(id, jobOwnerFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	chunk, next, complete, err := j.readOutput(offset, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mask the chunk like exec_command output; chunks end on line boundaries
+	// チャンクをexec_commandの出力と同様にマスクする。チャンクは行の境界で終わる
+	policy := s.docker.GetPolicy()
+	masked := policy.MaskHostPaths(policy.MaskExec(chunk))
+
+	info := j.info()
+	info["offset"] = offset
+	info["next_offset"] = next
+	info["complete"] = complete
+	info["output"] = masked
+
+	content := fmt.Sprintf("Job: %s\nContainer: %s\nCommand: %s\nState: %s\n", j.id, j.container, j.command, info["state"])
+	if code, ok := info["exit_code"]; ok {
+		content += fmt.Sprintf("Exit Code: %d\n", code)
+	}
+	if errMsg, ok := info["error"]; ok {
+		content += fmt.Sprintf("Error: %s\n", errMsg)
+	}
+	if info["truncated"] == true {
+		content += "Output truncated: security.exec_output limit reached\n"
+	}
+	if complete {
+		content += "Output complete.\n"
+	} else {
+		content += fmt.Sprintf("More output may follow: call again with offset %d.\n", next)
+	}
+	content += "\nOutput:\n" + masked
+
+	return withStructuredContent(textResponse(content), info), nil
+}
+
+// toolCancelJob implements the cancel_job MCP tool. It waits for the job's processes
+// to be killed, unless the request itself is cancelled first.
+//
+// toolCancelJobはcancel_job MCPツールを実装します。リクエスト自体が先にキャンセル
+// されない限り、ジョブのプロセスが終了されるまで待ちます。
+func (s *Server) toolCancelJob(ctx context.Context, args map[string]any) (any, error) {
+	id, err := jobIDArg(args)
+	if err != nil {
+		return nil, err
+	}
+	j, err := s.jobs.get(id, jobOwnerFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	wasRunning := j.running()
+	if wasRunning {
+		slog.Info("Cancelling exec job", "job_id", j.id, "container", j.container, "command", j.command)
+		j.cancel()
+		select {
+		case <-j.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	info := j.info()
+	info["was_running"] = wasRunning
+	return structuredResponse(info)
+}
+
+// toolListJobs implements the list_jobs MCP tool.
+// toolListJobsはlist_jobs MCPツールを実装します。
+func (s *Server) toolListJobs(ctx context.Context, args map[string]any) (any, error) {
+	jobs := []map[string]any{}
+	for _, j := range s.jobs.list(jobOwnerFromContext(ctx)) {
+		jobs = append(jobs, j.info())
+	}
+	return structuredResponse(map[string]any{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
@@ -0,0 +1,211 @@
+// jobs_test.go contains tests for the exec job table and the job tools.
+// jobs_test.goはexecジョブテーブルとジョブツールのテストを含みます。
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// newTestJob returns a job in the given state holding output.
+// newTestJobは指定された状態でoutputを保持するジョブを返します。
+func newTestJob(id, owner, state, output string) *execJob {
+	j := &execJob{id: id, owner: owner, startedAt: time.Now(), done: make(chan struct{}), state: state, output: []byte(output)}
+	if state != jobRunning {
+		j.endedAt = time.Now()
+		close(j.done)
+	}
+	return j
+}
+
+// TestExecJobReadOutput tests reading job output in line-aligned chunks from an offset.
+// TestExecJobReadOutputはオフセットから行単位のチャンクでジョブ出力を読み取ることをテストします。
+func TestExecJobReadOutput(t *testing.T) {
+	tests := []struct {
+		name         string // Test case name / テストケース名
+		state        string // Job state / ジョブの状態
+		output       string // Job output / ジョブの出力
+		offset       int    // Offset to read from / 読み取り開始オフセット
+		maxBytes     int    // Chunk limit / チャンクの上限
+		wantChunk    string
+		wantNext     int
+		wantComplete bool
+	}{
+		{"running stops at the last line", jobRunning, "ok 1\nok 2\npart", 0, 100, "ok 1\nok 2\n", 10, false},
+		{"running without a full line", jobRunning, "ok 1\npart", 5, 100, "", 5, false},
+		{"ended returns the rest", jobExited, "ok 1\npart", 5, 100, "part", 9, true},
+		{"cut by max_bytes", jobExited, "ok 1\nok 2\n", 0, 7, "ok 1\n", 5, false},
+		{"long line cut on a character boundary", jobRunning, "ééé\n", 0, 3, "é", 2, false},
+		{"character wider than max_bytes", jobRunning, "€€\n", 0, 1, "€", 3, false},
+		{"character wider than max_bytes at an offset", jobExited, "€€", 3, 2, "€", 6, true},
+		{"partly written character", jobRunning, "\xe2\x82", 0, 1, "", 0, false},
+		{"at the end", jobExited, "ok\n", 3, 100, "", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJob("job-1", "", tt.state, tt.output)
+			chunk, next, complete, err := j.readOutput(tt.offset, tt.maxBytes)
+			if err != nil {
+				t.Fatalf("readOutput() error = %v", err)
+			}
+			if chunk != tt.wantChunk || next != tt.wantNext || complete != tt.wantComplete {
+				t.Errorf("readOutput() = %q, %d, %v; want %q, %d, %v", chunk, next, complete, tt.wantChunk, tt.wantNext, tt.wantComplete)
+			}
+		})
+	}
+
+	if _, _, _, err := newTestJob("job-1", "", jobExited, "ok\n").readOutput(4, 100); err == nil {
+		t.Error("expected an error for an offset past the output")
+	}
+}
+
+// TestJobTable tests the table bound, the eviction of finished jobs and ownership.
+// TestJobTableはテーブルの上限、終了済みジョブの破棄、所有権をテストします。
+func TestJobTable(t *testing.T) {
+	table := newJobTable()
+	if err := table.add(newTestJob("job-old", "a", jobExited, ""), 2); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if err := table.add(newTestJob("job-run", "a", jobRunning, ""), 2); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+
+	// A full table drops the oldest finished job
+	// 満杯のテーブルは最も古い終了済みジョブを破棄する
+	if err := table.add(newTestJob("job-new", "b", jobRunning, ""), 2); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if _, err := table.get("job-old", "a"); err == nil {
+		t.Error("expected the oldest finished job to be dropped")
+	}
+
+	// Only running jobs left: new jobs are refused
+	// 実行中のジョブのみ：新しいジョブは拒否される
+	if err := table.add(newTestJob("job-more", "a", jobRunning, ""), 2); err == nil || !strings.Contains(err.Error(), "too many running jobs") {
+		t.Errorf("expected a too many running jobs error, got %v", err)
+	}
+
+	// Jobs of other sessions are not visible
+	// 他のセッションのジョブは見えない
+	if _, err := table.get("job-new", "a"); err == nil {
+		t.Error("expected job-new to be hidden from client a")
+	}
+	if jobs := table.list("a"); len(jobs) != 1 || jobs[0].id != "job-run" {
+		t.Errorf("list(a) = %v, want job-run only", jobs)
+	}
+
+	// Ownership follows the session, not the self-reported client name and host
+	// 所有権は自己申告のクライアント名とホストではなく、セッションに従う
+	first := &client{id: "session-1", clientName: "claude-code", remoteAddr: "127.0.0.1:50000"}
+	second := &client{id: "session-2", clientName: "claude-code", remoteAddr: "127.0.0.1:50000"}
+	if jobOwner(first) == jobOwner(second) {
+		t.Errorf("two sessions of the same client share the job owner %q", jobOwner(first))
+	}
+
+	// Legacy SSE client IDs are random, not timestamps
+	// レガシーSSEのクライアントIDはタイムスタンプではなくランダム
+	id := generateClientID()
+	if !regexp.MustCompile(`^client-[0-9a-f]{32}$`).MatchString(id) || id == generateClientID() {
+		t.Errorf("generateClientID() = %q, want a random client-<32 hex> ID", id)
+	}
+}
+
+// TestExecJobTools_Functional tests starting, polling and cancelling a job through the tools.
+// TestExecJobTools_Functionalはツールを通じたジョブの開始、ポーリング、キャンセルをテストします。
+func TestExecJobTools_Functional(t *testing.T) {
+	release := make(chan struct{})
+	mockClient := docker.NewMockClient(createTestPolicy())
+	mockClient.StartExecFunc = func(ctx context.Context, name, cmd string, danger bool, opts security.ExecOptions, w io.Writer) (func() (*docker.ExecResult, error), error) {
+		if cmd != "npm test" && cmd != "npm run lint" {
+			return nil, fmt.Errorf("command not whitelisted: %s", cmd)
+		}
+		w.Write([]byte("PASS a\nrunning"))
+		return func() (*docker.ExecResult, error) {
+			select {
+			case <-release:
+				w.Write([]byte(" b\nFAIL c\n"))
+				return &docker.ExecResult{ExitCode: 1}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}, nil
+	}
+	server := createTestServer(mockClient)
+	defer server.jobs.stop()
+	ctx := withJobOwner(context.Background(), "session-1")
+
+	if _, err := server.toolStartExecJob(ctx, map[string]any{"container": "test-api", "command": "rm -rf /"}); err == nil {
+		t.Fatal("expected a non-whitelisted command to be refused")
+	}
+	if jobs := server.jobs.list("session-1"); len(jobs) != 0 {
+		t.Fatalf("a refused job should not stay in the table, got %d", len(jobs))
+	}
+
+	start := func(command string) string {
+		t.Helper()
+		result, err := server.toolStartExecJob(ctx, map[string]any{"container": "test-api", "command": command})
+		if err != nil {
+			t.Fatalf("toolStartExecJob() error = %v", err)
+		}
+		structured := result.(map[string]any)["structuredContent"].(map[string]any)
+		if structured["state"] != jobRunning {
+			t.Errorf("state = %v, want running", structured["state"])
+		}
+		return structured["job_id"].(string)
+	}
+	output := func(id string, offset int) map[string]any {
+		t.Helper()
+		result, err := server.toolGetJobOutput(ctx, map[string]any{"job_id": id, "offset": float64(offset)})
+		if err != nil {
+			t.Fatalf("toolGetJobOutput() error = %v", err)
+		}
+		return result.(map[string]any)["structuredContent"].(map[string]any)
+	}
+
+	// Poll a job that runs to completion
+	// 最後まで実行されるジョブをポーリングする
+	id := start("npm test")
+	first := output(id, 0)
+	if first["output"] != "PASS a\n" || first["next_offset"] != 7 || first["complete"] != false {
+		t.Errorf("first poll = %v", first)
+	}
+	if _, err := server.toolGetJobOutput(withJobOwner(context.Background(), "session-2"), map[string]any{"job_id": id}); err == nil {
+		t.Error("expected the job to be hidden from another session")
+	}
+	close(release)
+	j, _ := server.jobs.get(id, "session-1")
+	<-j.done
+	last := output(id, 7)
+	if last["output"] != "running b\nFAIL c\n" || last["complete"] != true || last["state"] != jobExited || last["exit_code"] != 1 {
+		t.Errorf("last poll = %v", last)
+	}
+
+	// Cancel a running job
+	// 実行中のジョブをキャンセルする
+	release = make(chan struct{})
+	id = start("npm run lint")
+	result, err := server.toolCancelJob(ctx, map[string]any{"job_id": id})
+	if err != nil {
+		t.Fatalf("toolCancelJob() error = %v", err)
+	}
+	cancelled := result.(map[string]any)["structuredContent"].(map[string]any)
+	if cancelled["state"] != jobCancelled || cancelled["was_running"] != true {
+		t.Errorf("cancel_job = %v", cancelled)
+	}
+
+	result, err = server.toolListJobs(ctx, map[string]any{})
+	if err != nil {
+		t.Fatalf("toolListJobs() error = %v", err)
+	}
+	if count := result.(map[string]any)["structuredContent"].(map[string]any)["count"]; count != 2 {
+		t.Errorf("list_jobs count = %v, want 2", count)
+	}
+}
@@ -0,0 +1,160 @@
+// logfilter.go implements the line filters used by the search_logs tool:
+// substring or regular expression patterns, an exclude pattern, stdout/stderr
+// selection, and a structured-log mode that parses JSON log lines and filters on fields.
+//
+// logfilter.goはsearch_logsツールで使用する行フィルタを実装します：
+// 部分文字列または正規表現のパターン、除外パターン、stdout/stderrの選択、
+// およびJSONログ行を解析してフィールドで絞り込む構造化ログモード。
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// logFilter decides which log lines a search returns.
+// logFilterは検索が返すログ行を決定します。
+type logFilter struct {
+	// match reports whether a message matches the search pattern
+	// matchはメッセージが検索パターンにマッチするかを報告します
+	match func(string) bool
+
+	// exclude reports whether a message must be dropped (nil when no exclude pattern is set)
+	// excludeはメッセージを除外すべきかを報告します（除外パターンがない場合はnil）
+	exclude func(string) bool
+
+	// stream is "stdout" or "stderr" to select one stream, or empty for both
+	// streamは片方のストリームを選択する場合"stdout"または"stderr"、両方の場合は空です
+	stream string
+
+	// structured enables JSON log parsing; lines that are not JSON objects never match
+	// structuredはJSONログの解析を有効にします。JSONオブジェクトでない行はマッチしません
+	structured bool
+
+	// fields maps JSON field names (dotted for nested fields) to required values
+	// fieldsはJSONフィールド名（ネストしたフィールドはドット区切り）を必要な値に対応付けます
+	fields map[string]string
+}
+
+// newLogFilter builds a logFilter from search_logs arguments.
+// newLogFilterはsearch_logsの引数からlogFilterを構築します。
+func newLogFilter(pattern string, args map[string]any) (*logFilter, error) {
+	useRegex, _ := args["regex"].(bool)
+	caseSensitive, _ := args["case_sensitive"].(bool)
+
+	match, err := newTextMatcher(pattern, useRegex, caseSensitive)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	f := &logFilter{match: match}
+
+	if exclude, ok := args["exclude"].(string); ok && exclude != "" {
+		if f.exclude, err = newTextMatcher(exclude, useRegex, caseSensitive); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+	}
+
+	if stream, ok := args["stream"].(string); ok {
+		switch stream {
+		case "stdout", "stderr":
+			f.stream = stream
+		case "", "all":
+		default:
+			return nil, fmt.Errorf("invalid stream %q: must be stdout, stderr or all", stream)
+		}
+	}
+
+	f.structured, _ = args["structured"].(bool)
+	if fields, ok := args["fields"].(map[string]any); ok && len(fields) > 0 {
+		// Field filters only make sense on JSON lines
+		// フィールドフィルタはJSON行でのみ意味を持つ
+		f.structured = true
+		f.fields = make(map[string]string, len(fields))
+		for key, value := range fields {
+			f.fields[key] = fmt.Sprint(value)
+		}
+	}
+
+	return f, nil
+}
+
+// newTextMatcher returns a matcher for pattern. Without useRegex the pattern is a
+// substring; matching is case-insensitive unless caseSensitive is set.
+//
+// newTextMatcherはpatternのマッチャーを返します。useRegexがない場合パターンは部分文字列です。
+// caseSensitiveが設定されていない限り、大文字小文字を区別せずにマッチします。
+func newTextMatcher(pattern string, useRegex, caseSensitive bool) (func(string) bool, error) {
+	if useRegex {
+		if !caseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	if caseSensitive {
+		return func(s string) bool { return strings.Contains(s, pattern) }, nil
+	}
+	patternLower := strings.ToLower(pattern)
+	return func(s string) bool { return strings.Contains(strings.ToLower(s), patternLower) }, nil
+}
+
+// selectsStream reports whether lines from the given stream are searched.
+// selectsStreamは指定されたストリームの行が検索対象かどうかを報告します。
+func (f *logFilter) selectsStream(stream string) bool {
+	return f.stream == "" || f.stream == stream
+}
+
+// matches reports whether a log message (without its timestamp) is selected.
+// In structured mode, isJSON reports whether the message parsed as a JSON object.
+//
+// matchesはログメッセージ（タイムスタンプを除く）が選択されるかどうかを報告します。
+// 構造化モードでは、isJSONはメッセージがJSONオブジェクトとして解析できたかを報告します。
+func (f *logFilter) matches(message string) (matched, isJSON bool) {
+	if f.structured {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(message), &entry); err != nil {
+			return false, false
+		}
+		isJSON = true
+		for key, want := range f.fields {
+			value, ok := jsonField(entry, key)
+			if !ok || !strings.EqualFold(fmt.Sprint(value), want) {
+				return false, true
+			}
+		}
+	}
+
+	if !f.match(message) {
+		return false, isJSON
+	}
+	if f.exclude != nil && f.exclude(message) {
+		return false, isJSON
+	}
+	return true, isJSON
+}
+
+// jsonField looks up a field in a decoded JSON object. Dots in key address nested
+// objects (e.g., "http.status"); a key that exists literally takes precedence.
+//
+// jsonFieldはデコード済みJSONオブジェクトのフィールドを検索します。keyのドットは
+// ネストしたオブジェクトを指します（例："http.status"）。文字どおりに存在するキーが優先されます。
+func jsonField(entry map[string]any, key string) (any, bool) {
+	if value, ok := entry[key]; ok {
+		return value, true
+	}
+	head, rest, found := strings.Cut(key, ".")
+	if !found {
+		return nil, false
+	}
+	nested, ok := entry[head].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return jsonField(nested, rest)
+}
@@ -0,0 +1,74 @@
+// logfilter_test.go contains tests for the search_logs line filters.
+// logfilter_test.goはsearch_logsの行フィルタのテストを含みます。
+package mcp
+
+import (
+	"testing"
+)
+
+// TestLogFilter_Matches tests pattern, regex, exclude and structured field matching.
+// TestLogFilter_Matchesはパターン、正規表現、除外、構造化フィールドのマッチングをテストします。
+func TestLogFilter_Matches(t *testing.T) {
+	tests := []struct {
+		name       string         // Test case name / テストケース名
+		pattern    string         // Search pattern / 検索パターン
+		args       map[string]any // Extra search_logs arguments / 追加のsearch_logs引数
+		message    string         // Log message / ログメッセージ
+		wantMatch  bool           // Expected match result / 期待されるマッチ結果
+		wantIsJSON bool           // Expected JSON detection / 期待されるJSON判定
+	}{
+		{"substring case-insensitive", "error", nil, "ERROR: failed", true, false},
+		{"substring case-sensitive", "error", map[string]any{"case_sensitive": true}, "ERROR: failed", false, false},
+		{"empty pattern matches all", "", nil, "anything", true, false},
+		{"regex", `status=5\d\d`, map[string]any{"regex": true}, "GET / status=503", true, false},
+		{"regex no match", `status=5\d\d`, map[string]any{"regex": true}, "GET / status=200", false, false},
+		{"exclude", "error", map[string]any{"exclude": "healthcheck"}, "error in healthcheck", false, false},
+		{"exclude regex", "error", map[string]any{"regex": true, "exclude": "^debug"}, "debug: error", false, false},
+		{"structured skips plain text", "", map[string]any{"structured": true}, "plain text", false, false},
+		{"structured matches JSON", "", map[string]any{"structured": true}, `{"level":"info"}`, true, true},
+		{"field match", "", map[string]any{"fields": map[string]any{"level": "error"}}, `{"level":"ERROR","msg":"x"}`, true, true},
+		{"field mismatch", "", map[string]any{"fields": map[string]any{"level": "error"}}, `{"level":"info"}`, false, true},
+		{"missing field", "", map[string]any{"fields": map[string]any{"request_id": "abc"}}, `{"level":"error"}`, false, true},
+		{"nested numeric field", "", map[string]any{"fields": map[string]any{"http.status": float64(500)}}, `{"http":{"status":500}}`, true, true},
+		{"field and pattern", "timeout", map[string]any{"fields": map[string]any{"level": "error"}}, `{"level":"error","msg":"db timeout"}`, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if args == nil {
+				args = map[string]any{}
+			}
+			filter, err := newLogFilter(tt.pattern, args)
+			if err != nil {
+				t.Fatalf("newLogFilter() error = %v", err)
+			}
+			matched, isJSON := filter.matches(tt.message)
+			if matched != tt.wantMatch || isJSON != tt.wantIsJSON {
+				t.Errorf("matches(%q) = (%v, %v), want (%v, %v)", tt.message, matched, isJSON, tt.wantMatch, tt.wantIsJSON)
+			}
+		})
+	}
+}
+
+// TestNewLogFilter_Errors tests that invalid arguments are rejected.
+// TestNewLogFilter_Errorsは不正な引数が拒否されることをテストします。
+func TestNewLogFilter_Errors(t *testing.T) {
+	tests := []struct {
+		name    string         // Test case name / テストケース名
+		pattern string         // Search pattern / 検索パターン
+		args    map[string]any // Extra arguments / 追加の引数
+	}{
+		{"invalid regex", "(", map[string]any{"regex": true}},
+		{"invalid exclude regex", "ok", map[string]any{"regex": true, "exclude": "["}},
+		{"invalid stream", "ok", map[string]any{"stream": "stdin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newLogFilter(tt.pattern, tt.args); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
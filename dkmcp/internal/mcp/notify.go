@@ -0,0 +1,376 @@
+// notify.go implements server-to-client notifications sent while a request is running
+// (notifications/progress and notifications/message) and client-initiated cancellation
+// of in-flight requests (notifications/cancelled).
+//
+// On the legacy SSE transport notifications travel over the client's SSE channel ahead of
+// the response. On Streamable HTTP the POST response is upgraded to an SSE stream the first
+// time a notification is sent, so notifications and the final response share one stream.
+//
+// notify.goはリクエスト実行中にサーバーからクライアントへ送る通知
+// （notifications/progressとnotifications/message）と、クライアント起点の
+// 実行中リクエストのキャンセル（notifications/cancelled）を実装します。
+//
+// レガシーSSEトランスポートでは、通知はレスポンスに先立ってクライアントのSSEチャネルで送られます。
+// Streamable HTTPでは最初の通知を送る時点でPOSTレスポンスをSSEストリームに切り替え、
+// 通知と最終レスポンスが1つのストリームを共有します。
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errRequestCancelled is returned by processRequest when the client cancelled the request
+// with notifications/cancelled. Per the MCP specification no response is sent in that case.
+//
+// errRequestCancelledはクライアントがnotifications/cancelledでリクエストをキャンセルした場合に
+// processRequestが返します。MCP仕様に従い、その場合レスポンスは送信しません。
+var errRequestCancelled = errors.New("request cancelled by client")
+
+// sendFunc delivers one encoded JSON-RPC message to the client of the running request.
+// sendFuncは実行中リクエストのクライアントにエンコード済みJSON-RPCメッセージを1つ配信します。
+type sendFunc func(msg []byte) error
+
+// jsonrpcNotification is a JSON-RPC 2.0 notification (a request without an ID).
+// jsonrpcNotificationはJSON-RPC 2.0の通知（IDを持たないリクエスト）です。
+type jsonrpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// requestNotifier sends notifications on behalf of a single tools/call request.
+// A nil *requestNotifier is valid and silently drops every notification, so tool
+// handlers can notify unconditionally (e.g., when called directly in tests).
+//
+// requestNotifierは単一のtools/callリクエストに代わって通知を送信します。
+// nilの*requestNotifierも有効で、すべての通知を黙って破棄するため、ツールハンドラーは
+// 無条件に通知できます（テストで直接呼び出される場合など）。
+type requestNotifier struct {
+	// send delivers encoded notifications over the request's transport
+	// sendはエンコード済みの通知をリクエストのトランスポートで配信します
+	send sendFunc
+
+	// progressToken is the _meta.progressToken of the request (nil when not requested)
+	// progressTokenはリクエストの_meta.progressTokenです（要求されていない場合はnil）
+	progressToken any
+}
+
+// notifierKey is the context key under which the request notifier is stored.
+// notifierKeyはリクエスト通知者を格納するコンテキストキーです。
+type notifierKey struct{}
+
+// withNotifier returns a copy of ctx carrying n.
+// withNotifierはnを保持するctxのコピーを返します。
+func withNotifier(ctx context.Context, n *requestNotifier) context.Context {
+	return context.WithValue(ctx, notifierKey{}, n)
+}
+
+// notifierFromContext returns the request notifier stored in ctx, or nil.
+// notifierFromContextはctxに格納されたリクエスト通知者を返します。ない場合はnilです。
+func notifierFromContext(ctx context.Context) *requestNotifier {
+	n, _ := ctx.Value(notifierKey{}).(*requestNotifier)
+	return n
+}
+
+// progressTokenFromParams extracts _meta.progressToken from tools/call params.
+// progressTokenFromParamsはtools/callのパラメータから_meta.progressTokenを取り出します。
+func progressTokenFromParams(params any) any {
+	paramsMap, ok := params.(map[string]any)
+	if !ok {
+		return nil
+	}
+	meta, ok := paramsMap["_meta"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	return meta["progressToken"]
+}
+
+// hasProgressToken reports whether the client asked for progress notifications.
+// hasProgressTokenはクライアントが進捗通知を要求したかどうかを報告します。
+func (n *requestNotifier) hasProgressToken() bool {
+	return n != nil && n.progressToken != nil
+}
+
+// notify sends a JSON-RPC notification with the given method and params.
+// notifyは指定されたメソッドとパラメータでJSON-RPC通知を送信します。
+func (n *requestNotifier) notify(method string, params any) error {
+	if n == nil || n.send == nil {
+		return nil
+	}
+	msg, err := json.Marshal(jsonrpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	return n.send(msg)
+}
+
+// progress sends notifications/progress when the request carried a progress token.
+// total is omitted when it is zero.
+//
+// progressはリクエストが進捗トークンを持っている場合にnotifications/progressを送信します。
+// totalが0の場合は省略されます。
+func (n *requestNotifier) progress(progress, total float64, message string) error {
+	if !n.hasProgressToken() {
+		return nil
+	}
+	params := map[string]any{
+		"progressToken": n.progressToken,
+		"progress":      progress,
+	}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	return n.notify("notifications/progress", params)
+}
+
+// log sends a notifications/message log entry at the given level.
+// logは指定されたレベルでnotifications/messageログエントリを送信します。
+func (n *requestNotifier) log(level, logger string, data any) error {
+	return n.notify("notifications/message", map[string]any{
+		"level":  level,
+		"logger": logger,
+		"data":   data,
+	})
+}
+
+// sseSender returns a sendFunc that queues messages on a legacy SSE client's channel,
+// giving up after the same 5 second timeout used for responses.
+//
+// sseSenderはレガシーSSEクライアントのチャネルにメッセージを積むsendFuncを返します。
+// レスポンスと同じ5秒のタイムアウトで諦めます。
+func sseSender(c *client) sendFunc {
+	return func(msg []byte) error {
+		select {
+		case c.messages <- msg:
+			return nil
+		case <-c.ctx.Done():
+			return fmt.Errorf("client disconnected")
+		case <-time.After(5 * time.Second):
+			return fmt.Errorf("timeout sending notification")
+		}
+	}
+}
+
+// postStream is the response side of a single Streamable HTTP POST. It stays a plain
+// JSON response until the request sends a notification; at that point, if the client
+// accepts text/event-stream, the response is switched to an SSE stream carrying the
+// notifications followed by the final response.
+//
+// Clients that only accept JSON get notifications on their GET stream instead,
+// and they are dropped when no GET stream is draining the session's channel.
+//
+// postStreamは単一のStreamable HTTP POSTのレスポンス側です。リクエストが通知を送るまでは
+// 通常のJSONレスポンスのままで、その時点でクライアントがtext/event-streamを受け付けるなら、
+// 通知と最終レスポンスを運ぶSSEストリームに切り替えます。
+//
+// JSONのみを受け付けるクライアントには代わりにGETストリームで通知を送り、
+// セッションのチャネルを読み出すGETストリームがない場合は破棄します。
+type postStream struct {
+	w       http.ResponseWriter
+	c       *client
+	canSSE  bool
+	mu      sync.Mutex
+	started bool
+}
+
+// newPostStream creates the response side for a Streamable HTTP POST.
+// newPostStreamはStreamable HTTP POSTのレスポンス側を作成します。
+func newPostStream(w http.ResponseWriter, r *http.Request, c *client) *postStream {
+	return &postStream{
+		w:      w,
+		c:      c,
+		canSSE: strings.Contains(r.Header.Get("Accept"), "text/event-stream"),
+	}
+}
+
+// send is the sendFunc for notifications emitted while the POST is being processed.
+// sendはPOSTの処理中に発行される通知用のsendFuncです。
+func (p *postStream) send(msg []byte) error {
+	if !p.canSSE {
+		select {
+		case p.c.messages <- msg:
+		default:
+			slog.Debug("Dropping notification: no stream open", "clientID", p.c.id)
+		}
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		p.w.Header().Set("Content-Type", "text/event-stream")
+		p.w.Header().Set("Cache-Control", "no-cache")
+		p.w.WriteHeader(http.StatusOK)
+		p.started = true
+	}
+	writeStreamEvent(p.w, p.c.events.append(msg), msg)
+	return nil
+}
+
+// finish writes the final response on the SSE stream if the POST was upgraded to one.
+// It reports whether it did, in which case the caller must not write anything else.
+//
+// finishはPOSTがSSEストリームに切り替わっている場合、最終レスポンスをそのストリームに書き込みます。
+// 書き込んだかどうかを報告し、書き込んだ場合は呼び出し元はそれ以上何も書き込んではなりません。
+func (p *postStream) finish(msg []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		return false
+	}
+	if msg != nil {
+		writeStreamEvent(p.w, p.c.events.append(msg), msg)
+	}
+	return true
+}
+
+// trackRequest records the cancel function of an in-flight request so that
+// notifications/cancelled can stop it. The returned function untracks the request.
+//
+// trackRequestは実行中リクエストのキャンセル関数を記録し、notifications/cancelledで
+// 停止できるようにします。返される関数はリクエストの記録を解除します。
+func (c *client) trackRequest(id any, cancel context.CancelFunc) func() {
+	key := fmt.Sprint(id)
+	c.inflightMu.Lock()
+	if c.inflight == nil {
+		c.inflight = make(map[string]context.CancelFunc)
+	}
+	c.inflight[key] = cancel
+	c.inflightMu.Unlock()
+
+	return func() {
+		c.inflightMu.Lock()
+		delete(c.inflight, key)
+		c.inflightMu.Unlock()
+	}
+}
+
+// cancelRequest cancels the in-flight request with the given ID.
+// It reports whether such a request was running.
+//
+// cancelRequestは指定されたIDの実行中リクエストをキャンセルします。
+// そのようなリクエストが実行中だったかどうかを報告します。
+func (c *client) cancelRequest(id any) bool {
+	c.inflightMu.Lock()
+	cancel, ok := c.inflight[fmt.Sprint(id)]
+	c.inflightMu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// handleNotification processes a notification (a message without an ID) from a client.
+// Only notifications/cancelled has an effect; other notifications such as
+// notifications/initialized are acknowledged and ignored.
+//
+// handleNotificationはクライアントからの通知（IDのないメッセージ）を処理します。
+// 効果があるのはnotifications/cancelledのみで、notifications/initializedなど
+// その他の通知は受理して無視します。
+func (s *Server) handleNotification(c *client, req *JSONRPCRequest) {
+	if req.Method != "notifications/cancelled" {
+		slog.Debug("Received client notification", "method", req.Method, "clientID", c.id)
+		return
+	}
+
+	params, _ := req.Params.(map[string]any)
+	requestID := params["requestId"]
+	reason, _ := params["reason"].(string)
+	if requestID != nil && c.cancelRequest(requestID) {
+		slog.Info("Request cancelled by client",
+			append([]any{"requestId", requestID, "reason", reason, "clientID", c.id}, clientLogAttrs(c)...)...,
+		)
+	}
+}
+
+// logLevelRank orders the MCP log levels (RFC 5424 severities) from least to most severe.
+// logLevelRankはMCPのログレベル（RFC 5424の重大度）を重大度の低い順に並べます。
+var logLevelRank = map[string]int{
+	"debug":     0,
+	"info":      1,
+	"notice":    2,
+	"warning":   3,
+	"error":     4,
+	"critical":  5,
+	"alert":     6,
+	"emergency": 7,
+}
+
+// wantsLevel reports whether the client's logging/setLevel lets a message at level through.
+// The caller must hold clientsMu.
+//
+// wantsLevelはクライアントのlogging/setLevelがlevelのメッセージを通すかどうかを報告します。
+// 呼び出し元はclientsMuを保持している必要があります。
+func (c *client) wantsLevel(level string) bool {
+	return c.logLevel == "" || logLevelRank[level] >= logLevelRank[c.logLevel]
+}
+
+// broadcast queues msg on every initialized session accepted by want (nil accepts all).
+// Sessions whose channel is full are skipped rather than blocking the caller.
+// It returns the number of sessions notified.
+//
+// broadcastはwantが受け入れる初期化済みのすべてのセッションにmsgを積みます（nilはすべて受け入れ）。
+// チャネルが満杯のセッションは呼び出し元をブロックせずにスキップします。
+// 通知したセッション数を返します。
+func (s *Server) broadcast(msg []byte, want func(c *client) bool) int {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	notified := 0
+	for _, c := range s.clients {
+		if !c.initialized || (want != nil && !want(c)) {
+			continue
+		}
+		select {
+		case c.messages <- msg:
+			notified++
+		default:
+			slog.Debug("Dropping notification: channel full", "clientID", c.id)
+		}
+	}
+	return notified
+}
+
+// NotifyToolsListChanged sends notifications/tools/list_changed to every initialized
+// session so clients fetch the tool list again. Sessions whose channel is full are
+// skipped rather than blocking the caller. It returns the number of sessions notified.
+//
+// NotifyToolsListChangedは初期化済みのすべてのセッションにnotifications/tools/list_changedを
+// 送信し、クライアントにツール一覧を再取得させます。チャネルが満杯のセッションは
+// 呼び出し元をブロックせずにスキップします。通知したセッション数を返します。
+func (s *Server) NotifyToolsListChanged() int {
+	msg, err := json.Marshal(jsonrpcNotification{JSONRPC: "2.0", Method: "notifications/tools/list_changed"})
+	if err != nil {
+		return 0
+	}
+	return s.broadcast(msg, nil)
+}
+
+// NotifyMessage sends a notifications/message log entry to every initialized session
+// whose log level admits level. It returns the number of sessions notified.
+//
+// NotifyMessageはログレベルがlevelを許可する初期化済みのすべてのセッションに
+// notifications/messageログエントリを送信します。通知したセッション数を返します。
+func (s *Server) NotifyMessage(level, logger string, data any) int {
+	msg, err := json.Marshal(jsonrpcNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/message",
+		Params:  map[string]any{"level": level, "logger": logger, "data": data},
+	})
+	if err != nil {
+		return 0
+	}
+	return s.broadcast(msg, func(c *client) bool { return c.wantsLevel(level) })
+}
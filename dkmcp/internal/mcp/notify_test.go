@@ -0,0 +1,305 @@
+// notify_test.go contains tests for notifications sent while a request runs and for
+// client cancellation with notifications/cancelled, on both transports.
+//
+// notify_test.goはリクエスト実行中に送信される通知と、notifications/cancelledによる
+// クライアントのキャンセルを、両方のトランスポートについてテストします。
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
+)
+
+// followLogsCall builds a tools/call request for follow_logs with a progress token.
+// followLogsCallは進捗トークンつきのfollow_logsのtools/callリクエストを構築します。
+func followLogsCall(id int) JSONRPCRequest {
+	return JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  "tools/call",
+		Params: map[string]any{
+			"name":      "follow_logs",
+			"arguments": map[string]any{"container": "test-api"},
+			"_meta":     map[string]any{"progressToken": "follow-1"},
+		},
+	}
+}
+
+// TestStreamablePostUpgradesToStream verifies that a POST whose tool sends notifications
+// is answered as an SSE stream carrying the notifications followed by the response.
+//
+// TestStreamablePostUpgradesToStreamは、ツールが通知を送信するPOSTが、通知に続けて
+// レスポンスを運ぶSSEストリームとして応答されることを検証します。
+func TestStreamablePostUpgradesToStream(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	mockClient.FollowLogsFunc = func(ctx context.Context, name, tail, since string, onLine func(docker.LogLine) error) error {
+		onLine(docker.LogLine{Stream: "stdout", Text: "first"})
+		onLine(docker.LogLine{Stream: "stdout", Text: "second"})
+		return nil
+	}
+	_, ts := newStreamableTestServerWithDocker(t, mockClient)
+	sessionID := initializeStreamable(t, ts)
+
+	resp := postMCP(t, ts, sessionID, "application/json, text/event-stream", followLogsCall(3))
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream response, got %q", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	progressIdx := strings.Index(text, `"method":"notifications/progress"`)
+	responseIdx := strings.Index(text, `"id":3`)
+	if progressIdx < 0 || responseIdx < 0 {
+		t.Fatalf("Expected progress notification and response, got %q", text)
+	}
+	if progressIdx > responseIdx {
+		t.Errorf("Expected notification before response, got %q", text)
+	}
+	if !strings.Contains(text, `"progressToken":"follow-1"`) || !strings.Contains(text, "log stream ended") {
+		t.Errorf("Expected progress token and final result, got %q", text)
+	}
+}
+
+// TestStreamableCancelledRequest verifies that notifications/cancelled stops a running
+// tool call and that the cancelled request receives no JSON-RPC response.
+//
+// TestStreamableCancelledRequestは、notifications/cancelledが実行中のツール呼び出しを停止し、
+// キャンセルされたリクエストにJSON-RPCレスポンスが返されないことを検証します。
+func TestStreamableCancelledRequest(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	mockClient.FollowLogsFunc = func(ctx context.Context, name, tail, since string, onLine func(docker.LogLine) error) error {
+		<-ctx.Done()
+		return nil
+	}
+	server, ts := newStreamableTestServerWithDocker(t, mockClient)
+	sessionID := initializeStreamable(t, ts)
+
+	type result struct {
+		status int
+		body   string
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp := postMCP(t, ts, sessionID, "application/json, text/event-stream", followLogsCall(9))
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		done <- result{resp.StatusCode, string(body)}
+	}()
+
+	// Wait until the call is registered as in flight
+	// 呼び出しが実行中として登録されるまで待機
+	server.clientsMu.RLock()
+	c := server.clients[sessionID]
+	server.clientsMu.RUnlock()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		c.inflightMu.Lock()
+		n := len(c.inflight)
+		c.inflightMu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the tool call to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp := postMCP(t, ts, sessionID, "application/json, text/event-stream", map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "notifications/cancelled",
+		"params":  map[string]any{"requestId": 9, "reason": "user interrupted"},
+	})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected 202 for cancel notification, got %d", resp.StatusCode)
+	}
+
+	select {
+	case r := <-done:
+		if r.status != http.StatusAccepted || r.body != "" {
+			t.Errorf("Expected 202 with no response for cancelled request, got %d %q", r.status, r.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Cancelled request did not finish")
+	}
+}
+
+// TestSSENotificationsAndCancel verifies that on the legacy SSE transport notifications are
+// queued on the SSE channel ahead of the response, and that client notifications are
+// acknowledged with 202 without queuing anything.
+//
+// TestSSENotificationsAndCancelは、レガシーSSEトランスポートで通知がレスポンスに先立って
+// SSEチャネルに積まれることと、クライアントの通知が何も積まずに202で受理されることを検証します。
+func TestSSENotificationsAndCancel(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	mockClient.FollowLogsFunc = func(ctx context.Context, name, tail, since string, onLine func(docker.LogLine) error) error {
+		return onLine(docker.LogLine{Stream: "stdout", Text: "hello"})
+	}
+	server, ts := newStreamableTestServerWithDocker(t, mockClient)
+
+	// Register a legacy SSE session directly; nothing drains its channel during the test
+	// レガシーSSEセッションを直接登録。テスト中はチャネルを読み出さない
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &client{id: "client-test", messages: make(chan []byte, 10), ctx: ctx, cancel: cancel, initialized: true, transport: transportSSE}
+	server.clientsMu.Lock()
+	server.clients[c.id] = c
+	server.clientsMu.Unlock()
+
+	post := func(msg any) *http.Response {
+		body, _ := json.Marshal(msg)
+		resp, err := http.Post(ts.URL+"/message?sessionId="+c.id, "application/json", strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatalf("POST /message failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := post(map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"})
+	if resp.StatusCode != http.StatusAccepted || len(c.messages) != 0 {
+		t.Errorf("Expected 202 and nothing queued for notification, got %d with %d queued", resp.StatusCode, len(c.messages))
+	}
+
+	resp = post(followLogsCall(4))
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202 for tool call, got %d", resp.StatusCode)
+	}
+	if len(c.messages) != 2 {
+		t.Fatalf("Expected notification and response queued, got %d messages", len(c.messages))
+	}
+	if first := string(<-c.messages); !strings.Contains(first, "notifications/progress") || !strings.Contains(first, "hello") {
+		t.Errorf("Expected progress notification first, got %s", first)
+	}
+	if second := string(<-c.messages); !strings.Contains(second, `"id":4`) {
+		t.Errorf("Expected response second, got %s", second)
+	}
+}
+
+// TestSetHostAccessNotifiesToolsListChanged verifies that replacing the host access settings
+// changes the tool list, and that NotifyToolsListChanged reaches only initialized sessions.
+//
+// TestSetHostAccessNotifiesToolsListChangedは、ホストアクセス設定の置き換えでツール一覧が変わり、
+// NotifyToolsListChangedが初期化済みのセッションにのみ届くことを検証します。
+func TestSetHostAccessNotifiesToolsListChanged(t *testing.T) {
+	server := NewServer(docker.NewMockClient(createTestPolicy()), 8080)
+
+	hasHostCommand := func() bool {
+		result, _ := server.listTools()
+		for _, tool := range result.(map[string]any)["tools"].([]Tool) {
+			if tool.Name == "exec_host_command" {
+				return true
+			}
+		}
+		return false
+	}
+	if hasHostCommand() {
+		t.Fatal("exec_host_command listed before host commands were enabled")
+	}
+
+	hcPolicy := security.NewHostCommandPolicy(&config.HostCommandsConfig{Enabled: true})
+	server.SetHostAccess(nil, hcPolicy, t.TempDir(), 30*time.Second)
+	if !hasHostCommand() {
+		t.Error("exec_host_command not listed after SetHostAccess")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := &client{id: "ready", messages: make(chan []byte, 1), ctx: ctx, cancel: cancel, initialized: true}
+	pending := &client{id: "pending", messages: make(chan []byte, 1), ctx: ctx, cancel: cancel}
+	server.clientsMu.Lock()
+	server.clients[ready.id] = ready
+	server.clients[pending.id] = pending
+	server.clientsMu.Unlock()
+
+	if n := server.NotifyToolsListChanged(); n != 1 {
+		t.Errorf("NotifyToolsListChanged() = %d, want 1", n)
+	}
+	if len(pending.messages) != 0 {
+		t.Error("Uninitialized session should not be notified")
+	}
+	if msg := string(<-ready.messages); !strings.Contains(msg, `"method":"notifications/tools/list_changed"`) {
+		t.Errorf("Expected tools/list_changed notification, got %s", msg)
+	}
+
+	// A full channel is skipped instead of blocking
+	// 満杯のチャネルはブロックせずにスキップされる
+	ready.messages <- []byte("queued")
+	if n := server.NotifyToolsListChanged(); n != 0 {
+		t.Errorf("NotifyToolsListChanged() with a full channel = %d, want 0", n)
+	}
+}
+
+// TestWatchContainerEvents verifies that state changes are broadcast as notifications/message
+// honoring each session's logging/setLevel, and that other events are not.
+//
+// TestWatchContainerEventsは、状態変化が各セッションのlogging/setLevelに従って
+// notifications/messageとして配信され、その他のイベントは配信されないことを検証します。
+func TestWatchContainerEvents(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	exitCode := 1
+	mockClient.WatchEventsFunc = func(ctx context.Context, onEvent func(docker.ContainerEvent)) error {
+		onEvent(docker.ContainerEvent{Container: "test-worker", Action: "start"})
+		onEvent(docker.ContainerEvent{Container: "test-worker", Action: "die", ExitCode: &exitCode})
+		onEvent(docker.ContainerEvent{Container: "test-worker", Action: "health_status", Health: "healthy"})
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	server := NewServer(mockClient, 8080)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	all := &client{id: "all", messages: make(chan []byte, 4), ctx: ctx, cancel: cancel, initialized: true}
+	warnings := &client{id: "warnings", messages: make(chan []byte, 4), ctx: ctx, cancel: cancel, initialized: true}
+	server.clientsMu.Lock()
+	server.clients[all.id] = all
+	server.clients[warnings.id] = warnings
+	server.clientsMu.Unlock()
+
+	if _, err := server.processRequest(warnings, &JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "logging/setLevel", Params: map[string]any{"level": "warning"}}, nil); err != nil {
+		t.Fatalf("logging/setLevel error = %v", err)
+	}
+	if _, err := server.processRequest(warnings, &JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "logging/setLevel", Params: map[string]any{"level": "loud"}}, nil); err == nil {
+		t.Error("expected an error for an unknown log level")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.WatchContainerEvents(ctx)
+		close(done)
+	}()
+	deadline := time.After(2 * time.Second)
+	for len(all.messages) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("got %d notifications, want 2", len(all.messages))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	died := string(<-all.messages)
+	for _, want := range []string{`"method":"notifications/message"`, `"level":"warning"`, `"logger":"docker_events"`, "died with exit code 1"} {
+		if !strings.Contains(died, want) {
+			t.Errorf("die notification %s does not contain %s", died, want)
+		}
+	}
+	if healthy := string(<-all.messages); !strings.Contains(healthy, `"level":"info"`) || !strings.Contains(healthy, "is now healthy") {
+		t.Errorf("health notification = %s", healthy)
+	}
+	if len(warnings.messages) != 1 {
+		t.Errorf("session at warning level got %d notifications, want only the die event", len(warnings.messages))
+	}
+}
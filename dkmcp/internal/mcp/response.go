@@ -10,6 +10,7 @@ package mcp
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // textResponse creates a standard MCP text response.
@@ -47,6 +48,47 @@ func jsonTextResponse(data any) (map[string]any, error) {
 	return textResponse(string(jsonData)), nil
 }
 
+// structuredResponse creates an MCP response that carries data both as pretty-printed
+// JSON text and as structuredContent, so clients that understand structured tool
+// results can use the fields directly while others still read the text.
+//
+// structuredResponseはデータを整形されたJSONテキストとstructuredContentの両方で運ぶ
+// MCPレスポンスを作成します。構造化されたツール結果を理解するクライアントはフィールドを
+// 直接利用でき、それ以外のクライアントは引き続きテキストを読めます。
+func structuredResponse(data map[string]any) (map[string]any, error) {
+	resp, err := jsonTextResponse(data)
+	if err != nil {
+		return nil, err
+	}
+	resp["structuredContent"] = data
+	return resp, nil
+}
+
+// withStructuredContent adds data as the structuredContent of a response whose text
+// block keeps its own format for clients that do not read structured tool results.
+//
+// withStructuredContentはレスポンスにdataをstructuredContentとして追加します。
+// テキストブロックは構造化されたツール結果を読まないクライアントのために元の形式を保ちます。
+func withStructuredContent(resp map[string]any, data map[string]any) map[string]any {
+	resp["structuredContent"] = data
+	return resp
+}
+
+// decodeStructured decodes JSON text that a response returns, after masking, so its
+// structuredContent carries the same masked values. Numbers keep their literal form.
+//
+// decodeStructuredはレスポンスがマスキング後に返すJSONテキストをデコードし、
+// structuredContentが同じマスク済みの値を運ぶようにします。数値は元の表記を保ちます。
+func decodeStructured(text string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode structured content: %w", err)
+	}
+	return v, nil
+}
+
 // jsonCodeBlockResponse creates an MCP response with JSON in a markdown code block.
 // It includes a title and wraps the JSON data in a ```json code block for
 // better rendering in markdown-capable displays.
@@ -8,6 +8,7 @@
 package mcp
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -136,6 +137,50 @@ func TestJsonCodeBlockResponse(t *testing.T) {
 	}
 }
 
+// TestStructuredResponse verifies that structuredResponse returns the data both as
+// JSON text content and as structuredContent.
+//
+// TestStructuredResponseは、structuredResponseがデータをJSONテキストコンテンツと
+// structuredContentの両方で返すことを検証します。
+func TestStructuredResponse(t *testing.T) {
+	data := map[string]any{"matches_count": 2}
+
+	resp, err := structuredResponse(data)
+	if err != nil {
+		t.Fatalf("structuredResponse returned error: %v", err)
+	}
+
+	content := resp["content"].([]map[string]any)
+	if text := content[0]["text"].(string); !strings.Contains(text, `"matches_count": 2`) {
+		t.Errorf("text should contain the JSON data, got %q", text)
+	}
+
+	structured, ok := resp["structuredContent"].(map[string]any)
+	if !ok || structured["matches_count"] != 2 {
+		t.Errorf("structuredContent = %v, want the original data", resp["structuredContent"])
+	}
+}
+
+// TestDecodeStructured verifies that decodeStructured keeps large numbers exact and
+// rejects text that is not JSON.
+//
+// TestDecodeStructuredは、decodeStructuredが大きな数値を正確に保ち、JSONでない
+// テキストを拒否することを検証します。
+func TestDecodeStructured(t *testing.T) {
+	decoded, err := decodeStructured(`{"usage": 9007199254740993, "name": "[HOST_PATH]/app"}`)
+	if err != nil {
+		t.Fatalf("decodeStructured returned error: %v", err)
+	}
+	data := decoded.(map[string]any)
+	if data["usage"] != json.Number("9007199254740993") || data["name"] != "[HOST_PATH]/app" {
+		t.Errorf("decodeStructured() = %v", data)
+	}
+
+	if _, err := decodeStructured("Error formatting stats: boom"); err == nil {
+		t.Error("expected an error for text that is not JSON")
+	}
+}
+
 // TestErrorTextResponse verifies that errorTextResponse correctly formats
 // error messages using printf-style formatting.
 //
@@ -1,15 +1,18 @@
 // Package mcp provides the MCP (Model Context Protocol) server implementation for DockMCP.
-// This package handles JSON-RPC communication over Server-Sent Events (SSE) to enable
-// AI assistants to interact with Docker containers in a controlled manner.
+// This package handles JSON-RPC communication over Server-Sent Events (SSE) and the
+// Streamable HTTP transport to enable AI assistants to interact with Docker containers
+// in a controlled manner.
 //
 // mcpパッケージはDockMCPのMCP（Model Context Protocol）サーバー実装を提供します。
-// このパッケージはServer-Sent Events（SSE）を介したJSON-RPC通信を処理し、
-// AIアシスタントが制御された方法でDockerコンテナと対話できるようにします。
+// このパッケージはServer-Sent Events（SSE）およびStreamable HTTPトランスポートを介した
+// JSON-RPC通信を処理し、AIアシスタントが制御された方法でDockerコンテナと対話できるようにします。
 package mcp
 
 import (
 	"context"
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,7 +25,9 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/history"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/hosttools"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
 )
@@ -93,13 +98,34 @@ type Server struct {
 	// ホストコマンドが設定されていない場合はnilです。
 	hostCommandPolicy *security.HostCommandPolicy
 
-	// workspaceRoot is the host-side workspace root directory for host commands.
-	// workspaceRootはホストコマンド用のホスト側ワークスペースルートディレクトリです。
+	// workspaceRoot is the host-side workspace root directory for host commands and
+	// the copy tools. Empty when no workspace is configured.
+	//
+	// workspaceRootはホストコマンドとコピーツール用のホスト側ワークスペースルート
+	// ディレクトリです。ワークスペースが設定されていない場合は空です。
 	workspaceRoot string
 
 	// hostCommandTimeout is the timeout for host command execution.
 	// hostCommandTimeoutはホストコマンド実行のタイムアウトです。
 	hostCommandTimeout time.Duration
+
+	// hostMu protects the host access fields above, which SetHostAccess replaces
+	// when the configuration is reloaded.
+	//
+	// hostMuは上記のホストアクセスフィールドを保護します。これらは設定の再読み込み時に
+	// SetHostAccessによって置き換えられます。
+	hostMu sync.RWMutex
+
+	// metricsHistory holds the recorded container history for query_metrics.
+	// nil when the metrics history recorder is not enabled.
+	//
+	// metricsHistoryはquery_metrics用に記録されたコンテナの履歴を保持します。
+	// メトリクス履歴レコーダーが有効でない場合はnilです。
+	metricsHistory *history.Store
+
+	// jobs holds the exec jobs started with start_exec_job
+	// jobsはstart_exec_jobで開始したexecジョブを保持します
+	jobs *jobTable
 }
 
 // client represents a connected MCP client session. Each client maintains its own
@@ -129,6 +155,13 @@ type client struct {
 	// initializedはこのクライアントがMCP初期化を完了したかどうかを示します
 	initialized bool
 
+	// logLevel is the minimum level of notifications/message the client asked for with
+	// logging/setLevel; empty means every level (protected by clientsMu)
+	//
+	// logLevelはクライアントがlogging/setLevelで要求したnotifications/messageの最小レベルです。
+	// 空の場合はすべてのレベルです（clientsMuで保護）
+	logLevel string
+
 	// clientName is the name of the connected client (e.g., "claude-code", "dkmcp-go-client")
 	// clientNameは接続されたクライアントの名前です（例："claude-code"、"dkmcp-go-client"）
 	clientName string
@@ -144,6 +177,36 @@ type client struct {
 	// connectedAt is the time when this client connected (for calculating session duration)
 	// connectedAtはこのクライアントが接続した時刻です（セッション時間の計算用）
 	connectedAt time.Time
+
+	// transport is the MCP transport this session uses (transportSSE or transportStreamable)
+	// transportはこのセッションが使用するMCPトランスポートです（transportSSEまたはtransportStreamable）
+	transport string
+
+	// events records messages sent on Streamable HTTP streams so a client can resume
+	// a broken stream with Last-Event-ID. nil for legacy SSE sessions.
+	//
+	// eventsはStreamable HTTPストリームで送信したメッセージを記録し、クライアントが
+	// Last-Event-IDで切断したストリームを再開できるようにします。レガシーSSEセッションではnilです。
+	events *eventLog
+
+	// lastActivity is the time of the last request on a Streamable HTTP session (protected by clientsMu)
+	// lastActivityはStreamable HTTPセッションで最後にリクエストがあった時刻です（clientsMuで保護）
+	lastActivity time.Time
+
+	// openStreams is the number of GET streams currently open for this session (protected by clientsMu)
+	// openStreamsはこのセッションで現在開いているGETストリームの数です（clientsMuで保護）
+	openStreams int
+
+	// inflight maps the IDs of running tools/call requests to their cancel functions,
+	// so that notifications/cancelled can stop them (protected by inflightMu)
+	//
+	// inflightは実行中のtools/callリクエストのIDをキャンセル関数に対応付け、
+	// notifications/cancelledで停止できるようにします（inflightMuで保護）
+	inflight map[string]context.CancelFunc
+
+	// inflightMu protects concurrent access to the inflight map
+	// inflightMuはinflightマップへの並行アクセスを保護します
+	inflightMu sync.Mutex
 }
 
 // ServerOption is a functional option for configuring the MCP server.
@@ -178,6 +241,66 @@ func WithHostCommandPolicy(policy *security.HostCommandPolicy, workspaceRoot str
 	}
 }
 
+// WithWorkspaceRoot sets the host workspace root the copy tools are confined to.
+// WithWorkspaceRootはコピーツールが限定されるホストのワークスペースルートを設定します。
+func WithWorkspaceRoot(workspaceRoot string) ServerOption {
+	return func(s *Server) {
+		s.workspaceRoot = workspaceRoot
+	}
+}
+
+// WithMetricsHistory sets the history store the query_metrics tool reads from.
+// WithMetricsHistoryはquery_metricsツールが読み取る履歴ストアを設定します。
+func WithMetricsHistory(store *history.Store) ServerOption {
+	return func(s *Server) {
+		s.metricsHistory = store
+	}
+}
+
+// SetHostAccess replaces the host tools manager and host command settings while the
+// server is running. nil disables the corresponding tools. It is used when the
+// configuration is reloaded; call NotifyToolsListChanged afterwards so clients
+// refresh their tool list.
+//
+// SetHostAccessはサーバーの実行中にホストツールマネージャーとホストコマンドの設定を
+// 置き換えます。nilは対応するツールを無効にします。設定の再読み込み時に使用されます。
+// その後NotifyToolsListChangedを呼び出して、クライアントにツール一覧を更新させてください。
+func (s *Server) SetHostAccess(manager *hosttools.Manager, policy *security.HostCommandPolicy, workspaceRoot string, timeout time.Duration) {
+	s.hostMu.Lock()
+	defer s.hostMu.Unlock()
+	s.hostToolsManager = manager
+	s.hostCommandPolicy = policy
+	s.workspaceRoot = workspaceRoot
+	s.hostCommandTimeout = timeout
+}
+
+// hostTools returns the current host tools manager, or nil when host tools are not configured.
+// hostToolsは現在のホストツールマネージャーを返します。ホストツールが設定されていない場合はnilです。
+func (s *Server) hostTools() *hosttools.Manager {
+	s.hostMu.RLock()
+	defer s.hostMu.RUnlock()
+	return s.hostToolsManager
+}
+
+// hostCommands returns the current host command policy, workspace root and timeout.
+// The policy is nil when host commands are not configured.
+//
+// hostCommandsは現在のホストコマンドポリシー、ワークスペースルート、タイムアウトを返します。
+// ホストコマンドが設定されていない場合、ポリシーはnilです。
+func (s *Server) hostCommands() (*security.HostCommandPolicy, string, time.Duration) {
+	s.hostMu.RLock()
+	defer s.hostMu.RUnlock()
+	return s.hostCommandPolicy, s.workspaceRoot, s.hostCommandTimeout
+}
+
+// workspace returns the current host workspace root, or "" when none is configured.
+// workspaceは現在のホストのワークスペースルートを返します。設定されていない場合は""です。
+func (s *Server) workspace() string {
+	s.hostMu.RLock()
+	defer s.hostMu.RUnlock()
+	return s.workspaceRoot
+}
+
 // NewServer creates a new MCP server with the given Docker client and port.
 // The Docker client is used to execute container operations, while the port
 // specifies which HTTP port the server will listen on.
@@ -198,6 +321,7 @@ func NewServer(dockerClient docker.DockerClientInterface, port int, opts ...Serv
 		docker:  dockerClient,
 		port:    port,
 		clients: make(map[string]*client),
+		jobs:    newJobTable(),
 	}
 
 	// Apply optional configurations
@@ -210,18 +334,20 @@ func NewServer(dockerClient docker.DockerClientInterface, port int, opts ...Serv
 }
 
 // Start starts the MCP server and begins listening for connections.
-// It sets up three HTTP endpoints:
+// It sets up the following HTTP endpoints:
 // - GET /sse: SSE endpoint for establishing client connections
 // - POST /message: JSON-RPC endpoint for receiving client requests
+// - POST/GET/DELETE /mcp: Streamable HTTP endpoint (single-endpoint transport)
 // - GET /health: Health check endpoint for monitoring
 //
 // The server runs with logging and CORS middleware applied.
 // This method blocks until the server is stopped.
 //
 // StartはMCPサーバーを起動し、接続のリッスンを開始します。
-// 3つのHTTPエンドポイントを設定します：
+// 以下のHTTPエンドポイントを設定します：
 // - GET /sse: クライアント接続確立用のSSEエンドポイント
 // - POST /message: クライアントリクエスト受信用のJSON-RPCエンドポイント
+// - POST/GET/DELETE /mcp: Streamable HTTPエンドポイント（単一エンドポイントのトランスポート）
 // - GET /health: 監視用のヘルスチェックエンドポイント
 //
 // サーバーはロギングとCORSミドルウェアを適用して実行されます。
@@ -237,6 +363,14 @@ func (s *Server) Start() error {
 	// MCP用のJSON-RPCエンドポイント - クライアントはここにリクエストを送信します
 	mux.HandleFunc("POST /message", s.handleMessage)
 
+	// Streamable HTTP endpoint for MCP - newer clients use this single endpoint for
+	// requests (POST), server-initiated messages (GET) and session termination (DELETE)
+	// MCP用のStreamable HTTPエンドポイント - 新しいクライアントはこの単一エンドポイントを
+	// リクエスト（POST）、サーバー起点のメッセージ（GET）、セッション終了（DELETE）に使用します
+	mux.HandleFunc("POST /mcp", s.handleStreamablePost)
+	mux.HandleFunc("GET /mcp", s.handleStreamableGet)
+	mux.HandleFunc("DELETE /mcp", s.handleStreamableDelete)
+
 	// Health check endpoint for monitoring and diagnostics
 	// 監視と診断のためのヘルスチェックエンドポイント
 	mux.HandleFunc("GET /health", s.handleHealth)
@@ -289,6 +423,10 @@ func (s *Server) Stop(ctx context.Context) error {
 	}
 	s.clientsMu.Unlock()
 
+	// Stop the exec jobs; their processes are killed in the background
+	// execジョブを停止する。そのプロセスはバックグラウンドで終了される
+	s.jobs.stop()
+
 	if clientCount > 0 {
 		slog.Debug("Cancelled client contexts", "count", clientCount, "initialized", initializedCount, "uninitialized", clientCount-initializedCount)
 
@@ -373,6 +511,7 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 		remoteAddr:  r.RemoteAddr,
 		userAgent:   r.UserAgent(),
 		connectedAt: time.Now(),
+		transport:   transportSSE,
 	}
 
 	// Register the client in the server's client map
@@ -384,40 +523,7 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	// Cleanup: Remove client from map and cancel context when connection closes
 	// クリーンアップ：接続が閉じられたときにマップからクライアントを削除しコンテキストをキャンセル
 	defer func() {
-		// Calculate session duration
-		// セッション時間を計算
-		duration := time.Since(c.connectedAt)
-
-		// Noise filtering based on verbosity level:
-		// - Uninitialized connections (noise) are only shown at verbosity >= 3
-		// - dkmcp-go-client is always Debug level (frequent short-lived connections)
-		// - Other initialized clients are Info level
-		//
-		// verbosityレベルに基づくノイズフィルタリング：
-		// - 未初期化接続（ノイズ）はverbosity >= 3でのみ表示
-		// - dkmcp-go-clientは常にDebugレベル（頻繁な短期接続）
-		// - その他の初期化済みクライアントはInfoレベル
-		if !c.initialized {
-			// Noise: uninitialized connection - only show at verbosity >= 3
-			// ノイズ：未初期化接続 - verbosity >= 3でのみ表示
-			if s.verbosity >= 3 {
-				slog.Debug("[-] Client disconnected",
-					append([]any{"clientID", clientID, "duration", duration.String(), "remote", c.remoteAddr}, clientLogAttrs(c)...)...,
-				)
-			}
-		} else if strings.HasPrefix(c.clientName, "dkmcp-go-client") {
-			// CLI client (including with suffix) - always Debug level
-			// CLIクライアント（サフィックス付き含む）- 常にDebugレベル
-			slog.Debug("[-] Client disconnected",
-				append([]any{"clientID", clientID, "duration", duration.String(), "remote", c.remoteAddr}, clientLogAttrs(c)...)...,
-			)
-		} else {
-			// Initialized client (Claude Code, etc.) - Info level
-			// 初期化済みクライアント（Claude Code等）- Infoレベル
-			slog.Info("[-] Client disconnected",
-				append([]any{"clientID", clientID, "duration", duration.String(), "remote", c.remoteAddr}, clientLogAttrs(c)...)...,
-			)
-		}
+		s.logClientDisconnect(c)
 		s.clientsMu.Lock()
 		delete(s.clients, clientID)
 		s.clientsMu.Unlock()
@@ -462,6 +568,44 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// logClientDisconnect logs the end of a client session (SSE connection closed or
+// Streamable HTTP session terminated) at a level that depends on the client type.
+//
+// Noise filtering based on verbosity level:
+// - Uninitialized connections (noise) are only shown at verbosity >= 3
+// - dkmcp-go-client is always Debug level (frequent short-lived connections)
+// - Other initialized clients are Info level
+//
+// logClientDisconnectはクライアントセッションの終了（SSE接続の切断または
+// Streamable HTTPセッションの終了）を、クライアント種別に応じたレベルでログ出力します。
+//
+// verbosityレベルに基づくノイズフィルタリング：
+// - 未初期化接続（ノイズ）はverbosity >= 3でのみ表示
+// - dkmcp-go-clientは常にDebugレベル（頻繁な短期接続）
+// - その他の初期化済みクライアントはInfoレベル
+func (s *Server) logClientDisconnect(c *client) {
+	// Calculate session duration
+	// セッション時間を計算
+	duration := time.Since(c.connectedAt)
+	attrs := append([]any{"clientID", c.id, "duration", duration.String(), "remote", c.remoteAddr}, clientLogAttrs(c)...)
+
+	if !c.initialized {
+		// Noise: uninitialized connection - only show at verbosity >= 3
+		// ノイズ：未初期化接続 - verbosity >= 3でのみ表示
+		if s.verbosity >= 3 {
+			slog.Debug("[-] Client disconnected", attrs...)
+		}
+	} else if strings.HasPrefix(c.clientName, "dkmcp-go-client") {
+		// CLI client (including with suffix) - always Debug level
+		// CLIクライアント（サフィックス付き含む）- 常にDebugレベル
+		slog.Debug("[-] Client disconnected", attrs...)
+	} else {
+		// Initialized client (Claude Code, etc.) - Info level
+		// 初期化済みクライアント（Claude Code等）- Infoレベル
+		slog.Info("[-] Client disconnected", attrs...)
+	}
+}
+
 // handleMessage handles JSON-RPC messages from MCP clients.
 // Messages are received via POST request and responses are sent via the SSE channel.
 //
@@ -498,7 +642,7 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 	// マップ内のクライアントを検索してセッションの存在を確認
 	s.clientsMu.RLock()
 	client, exists := s.clients[sessionID]
-	if !exists {
+	if !exists || client.transport != transportSSE {
 		s.clientsMu.RUnlock()
 		sendError(w, nil, -32600, "Invalid session ID")
 		return
@@ -538,6 +682,14 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 		s.logVerboseRequest(client, &req, bodyBytes, reqNum)
 	}
 
+	// Notifications (no ID) expect no reply; acknowledge them without touching the SSE channel
+	// 通知（IDなし）は返信を必要としないため、SSEチャネルを使わずに受理する
+	if req.ID == nil {
+		s.handleNotification(client, &req)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	// Enforce initialization: Only "initialize" method is allowed before initialization
 	// 初期化の強制：初期化前は "initialize" メソッドのみが許可される
 	// Use the copied value to avoid race condition with concurrent initialization
@@ -549,7 +701,16 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 
 	// Process the request and get the result
 	// リクエストを処理して結果を取得
-	result, err := s.processRequest(client, &req)
+	// Notifications emitted by the tool (progress, log lines) go out on the SSE channel
+	// ahead of the response
+	// ツールが発行する通知（進捗、ログ行）はレスポンスに先立ってSSEチャネルで送られる
+	result, err := s.processRequest(client, &req, sseSender(client))
+	if errors.Is(err, errRequestCancelled) {
+		// A cancelled request gets no response; just acknowledge the POST
+		// キャンセルされたリクエストにはレスポンスを返さず、POSTの受理のみ通知
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
 	if err != nil {
 		sendErrorViaSSE(w, client, req.ID, -32603, err.Error())
 		return
@@ -602,17 +763,21 @@ func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
 }
 
 // processRequest processes a JSON-RPC request and routes it to the appropriate handler.
+// Notifications emitted while a tool runs are delivered with send.
 // It supports the following MCP methods:
 // - tools/list: Returns the list of available tools
 // - tools/call: Executes a specific tool
 // - initialize: Initializes the MCP session
+// - logging/setLevel: Accepts the client's log level (logging capability)
 //
 // processRequestはJSON-RPCリクエストを処理し、適切なハンドラにルーティングします。
+// ツール実行中に発行される通知はsendで配信されます。
 // 以下のMCPメソッドをサポートしています：
 // - tools/list: 利用可能なツールのリストを返す
 // - tools/call: 特定のツールを実行する
 // - initialize: MCPセッションを初期化する
-func (s *Server) processRequest(c *client, req *JSONRPCRequest) (any, error) {
+// - logging/setLevel: クライアントのログレベルを受け付ける（loggingケイパビリティ）
+func (s *Server) processRequest(c *client, req *JSONRPCRequest, send sendFunc) (any, error) {
 	logger := slog.Default()
 	logger.Info("Processing JSON-RPC request",
 		append([]any{"method", req.Method, "clientID", c.id}, clientLogAttrs(c)...)...,
@@ -633,7 +798,23 @@ func (s *Server) processRequest(c *client, req *JSONRPCRequest) (any, error) {
 				)
 			}
 		}
-		return s.callTool(c.ctx, req.Params)
+		// Each call gets its own context so notifications/cancelled can stop it,
+		// and a notifier so long-running tools can report progress
+		// 各呼び出しは独自のコンテキストを持ちnotifications/cancelledで停止でき、
+		// 長時間実行ツールが進捗を報告できるよう通知者を持つ
+		ctx, cancel := context.WithCancel(c.ctx)
+		defer cancel()
+		untrack := c.trackRequest(req.ID, cancel)
+		defer untrack()
+		ctx = withNotifier(ctx, &requestNotifier{send: send, progressToken: progressTokenFromParams(req.Params)})
+		ctx = withJobOwner(ctx, jobOwner(c))
+
+		result, err := s.callTool(ctx, req.Params)
+		if ctx.Err() != nil && c.ctx.Err() == nil {
+			return nil, errRequestCancelled
+		}
+		auditAccessDenied(ctx, c, req.Params, err)
+		return result, err
 	case "initialize":
 		// Handle MCP initialization and update client context with client name
 		// MCP初期化を処理し、クライアント名でクライアントコンテキストを更新
@@ -641,6 +822,11 @@ func (s *Server) processRequest(c *client, req *JSONRPCRequest) (any, error) {
 		if err != nil {
 			return nil, err
 		}
+		// Streamable HTTP sessions advertise the protocol revision that introduced the transport
+		// Streamable HTTPセッションはこのトランスポートを導入したプロトコルリビジョンを通知
+		if m, ok := result.(map[string]any); ok && c.transport == transportStreamable {
+			m["protocolVersion"] = streamableProtocolVersion
+		}
 		if clientName != "" {
 			c.clientName = clientName
 		}
@@ -674,6 +860,20 @@ func (s *Server) processRequest(c *client, req *JSONRPCRequest) (any, error) {
 		}
 
 		return result, nil
+	case "logging/setLevel":
+		// Required by the logging capability. The level filters the container event
+		// notifications broadcast to the session; follow_logs only emits info-level messages
+		// loggingケイパビリティで必須。レベルはセッションに配信するコンテナイベント通知を
+		// フィルタする。follow_logsはinfoレベルのメッセージのみ発行する
+		params, _ := req.Params.(map[string]any)
+		level, _ := params["level"].(string)
+		if _, ok := logLevelRank[level]; !ok {
+			return nil, fmt.Errorf("invalid log level: %q", level)
+		}
+		s.clientsMu.Lock()
+		c.logLevel = level
+		s.clientsMu.Unlock()
+		return map[string]any{}, nil
 	default:
 		return nil, fmt.Errorf("method not found: %s", req.Method)
 	}
@@ -793,6 +993,33 @@ func clientLogAttrs(c *client) []any {
 	return []any{"client_name", name, "user_agent", c.userAgent}
 }
 
+// auditAccessDenied records an access_denied audit event when a tool call failed because
+// the target container is not accessible, including the rule that decided the denial.
+//
+// auditAccessDeniedは対象コンテナにアクセスできずにツール呼び出しが失敗した場合に、
+// 拒否を決定したルールを含むaccess_denied監査イベントを記録します。
+func auditAccessDenied(ctx context.Context, c *client, params any, err error) {
+	var denied *security.AccessDeniedError
+	if !errors.As(err, &denied) {
+		return
+	}
+	toolName := ""
+	if paramsMap, ok := params.(map[string]any); ok {
+		toolName, _ = paramsMap["name"].(string)
+	}
+	details := map[string]any{
+		"reason":     denied.Decision.Reason,
+		"session_id": c.id,
+	}
+	if denied.Decision.Rule != "" {
+		details["rule"] = denied.Decision.Rule
+	}
+	if c.clientName != "" {
+		details["client_name"] = c.clientName
+	}
+	audit.LogAccessDenied(ctx, toolName, denied.Container, err.Error(), details)
+}
+
 // corsMiddleware adds CORS headers to allow cross-origin requests.
 // This is necessary for web-based MCP clients to connect to the server.
 // It handles preflight OPTIONS requests and adds appropriate headers to all responses.
@@ -811,8 +1038,9 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 		if origin != "" && isAllowedOrigin(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+mcpSessionHeader+", Last-Event-ID")
+		w.Header().Set("Access-Control-Expose-Headers", mcpSessionHeader)
 
 		// Handle preflight OPTIONS request
 		// プリフライトOPTIONSリクエストを処理
@@ -893,6 +1121,19 @@ func (s *Server) originValidationMiddleware(next http.Handler) http.Handler {
 // これはSSEチャネルが利用できない場合や、セッションが確立される前に
 // エラーが発生した場合のフォールバックとして使用されます。
 func sendError(w http.ResponseWriter, id any, code int, message string) {
+	// JSON-RPC errors are sent with HTTP 200 OK status
+	// JSON-RPCエラーはHTTP 200 OKステータスで送信される
+	sendErrorWithStatus(w, http.StatusOK, id, code, message)
+}
+
+// sendErrorWithStatus sends a JSON-RPC error response with the given HTTP status code.
+// The Streamable HTTP transport uses this for session errors, which the specification
+// maps to HTTP 400 (missing session ID) and 404 (unknown session).
+//
+// sendErrorWithStatusは指定されたHTTPステータスコードでJSON-RPCエラーレスポンスを送信します。
+// Streamable HTTPトランスポートは、仕様でHTTP 400（セッションIDなし）と
+// 404（不明なセッション）に対応付けられたセッションエラーにこれを使用します。
+func sendErrorWithStatus(w http.ResponseWriter, status int, id any, code int, message string) {
 	resp := JSONRPCResponse{
 		JSONRPC: "2.0",
 		ID:      id,
@@ -903,9 +1144,7 @@ func sendError(w http.ResponseWriter, id any, code int, message string) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	// JSON-RPC errors are sent with HTTP 200 OK status
-	// JSON-RPCエラーはHTTP 200 OKステータスで送信される
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(resp)
 }
 
@@ -954,15 +1193,25 @@ func sendErrorViaSSE(w http.ResponseWriter, c *client, id any, code int, message
 	}
 }
 
-// generateClientID generates a unique client ID using the current timestamp.
+// generateClientID generates a cryptographically random client ID.
 // Each client session needs a unique identifier to track its SSE connection
-// and associate incoming JSON-RPC requests with the correct session.
+// and associate incoming JSON-RPC requests with the correct session. The ID also
+// owns the session's exec jobs, so it must be hard to guess, like a Streamable HTTP
+// session ID (see generateSessionID).
 //
-// generateClientIDは現在のタイムスタンプを使用して一意のクライアントIDを生成します。
+// generateClientIDは暗号学的に安全なランダムクライアントIDを生成します。
 // 各クライアントセッションはSSE接続を追跡し、受信したJSON-RPCリクエストを
-// 正しいセッションに関連付けるために一意の識別子が必要です。
+// 正しいセッションに関連付けるために一意の識別子が必要です。IDはセッションのexecジョブの
+// 所有者でもあるため、Streamable HTTPのセッションID（generateSessionIDを参照）と同様に
+// 推測困難でなければなりません。
 func generateClientID() string {
-	return fmt.Sprintf("client-%d", time.Now().UnixNano())
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to the timestamp
+		// crypto/randはサポート対象プラットフォームでは失敗しない。タイムスタンプにフォールバック
+		return fmt.Sprintf("client-%d", time.Now().UnixNano())
+	}
+	return "client-" + hex.EncodeToString(b)
 }
 
 // JSONRPCRequest represents a JSON-RPC 2.0 request message.
@@ -10,15 +10,23 @@ package mcp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
 )
 
 // TestWithVerbosity tests that the WithVerbosity option correctly sets verbosity levels.
@@ -2006,3 +2014,50 @@ func containsLogField(logOutput, key, value string) bool {
 		strings.Contains(logOutput, key+`="`+value+`"`)
 }
 
+
+// TestAuditAccessDenied tests that access denials are recorded with the deciding rule.
+// TestAuditAccessDeniedはアクセス拒否が決定したルールとともに記録されることをテストします。
+func TestAuditAccessDenied(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+	audit.ResetLogger()
+	if err := audit.Initialize(config.AuditConfig{
+		Enabled: true,
+		File:    logFile,
+		Events:  config.AuditEvents{AccessDenied: true},
+	}); err != nil {
+		t.Fatalf("audit.Initialize failed: %v", err)
+	}
+	defer audit.ResetLogger()
+
+	c := &client{id: "client-1", clientName: "test-client"}
+	params := map[string]any{"name": "get_logs"}
+
+	// Errors other than access denials are not audited
+	// アクセス拒否以外のエラーは監査されない
+	auditAccessDenied(context.Background(), c, params, errors.New("container not found"))
+	denied := &security.AccessDeniedError{
+		Container: "vault",
+		Decision:  security.AccessDecision{Reason: security.AccessReasonDeniedLabel, Rule: "dkmcp.access=none"},
+	}
+	auditAccessDenied(context.Background(), c, params, fmt.Errorf("get logs: %w", denied))
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 audit event, got %d: %s", len(lines), data)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse audit event: %v", err)
+	}
+	if entry["event_type"] != "access_denied" || entry["tool"] != "get_logs" || entry["container"] != "vault" {
+		t.Errorf("unexpected audit event: %v", entry)
+	}
+	details, _ := entry["details"].(map[string]any)
+	if details["reason"] != "denied_label" || details["rule"] != "dkmcp.access=none" || details["client_name"] != "test-client" {
+		t.Errorf("unexpected audit details: %v", details)
+	}
+}
@@ -0,0 +1,473 @@
+// streamable.go implements the MCP Streamable HTTP transport (protocol revision 2025-03-26).
+// Unlike the legacy SSE transport (GET /sse + POST /message), Streamable HTTP uses a single
+// endpoint: clients POST JSON-RPC messages and receive the response either as a JSON body or
+// as an SSE stream, open an optional GET stream for server-initiated messages, and terminate
+// the session with DELETE. Sessions are identified by the Mcp-Session-Id header.
+//
+// streamable.goはMCP Streamable HTTPトランスポート（プロトコルリビジョン2025-03-26）を実装します。
+// レガシーSSEトランスポート（GET /sse + POST /message）と異なり、Streamable HTTPは単一の
+// エンドポイントを使用します：クライアントはJSON-RPCメッセージをPOSTし、レスポンスをJSONボディ
+// またはSSEストリームとして受け取ります。サーバー起点のメッセージ用に任意でGETストリームを開き、
+// DELETEでセッションを終了します。セッションはMcp-Session-Idヘッダーで識別されます。
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// transportSSE identifies sessions using the legacy two-endpoint SSE transport.
+	// transportSSEはレガシーの2エンドポイントSSEトランスポートを使用するセッションを識別します。
+	transportSSE = "sse"
+
+	// transportStreamable identifies sessions using the Streamable HTTP transport.
+	// transportStreamableはStreamable HTTPトランスポートを使用するセッションを識別します。
+	transportStreamable = "streamable-http"
+
+	// mcpSessionHeader is the HTTP header carrying the Streamable HTTP session ID.
+	// mcpSessionHeaderはStreamable HTTPのセッションIDを運ぶHTTPヘッダーです。
+	mcpSessionHeader = "Mcp-Session-Id"
+
+	// streamableProtocolVersion is the MCP protocol revision that defines Streamable HTTP.
+	// streamableProtocolVersionはStreamable HTTPを定義するMCPプロトコルリビジョンです。
+	streamableProtocolVersion = "2025-03-26"
+
+	// streamableEventHistorySize is the number of stream events kept per session for resumption.
+	// streamableEventHistorySizeは再開のためにセッションごとに保持するストリームイベント数です。
+	streamableEventHistorySize = 100
+
+	// streamableSessionIdleTimeout is how long a Streamable HTTP session may stay idle
+	// (no requests and no open GET stream) before it is discarded.
+	//
+	// streamableSessionIdleTimeoutはStreamable HTTPセッションがアイドル状態
+	// （リクエストもGETストリームもない状態）で破棄されるまでの時間です。
+	streamableSessionIdleTimeout = 30 * time.Minute
+)
+
+// streamEvent is a single SSE event sent on a Streamable HTTP stream.
+// streamEventはStreamable HTTPストリームで送信される単一のSSEイベントです。
+type streamEvent struct {
+	// id is the SSE event ID, unique within the session
+	// idはセッション内で一意なSSEイベントIDです
+	id uint64
+
+	// data is the JSON-RPC message carried by the event
+	// dataはイベントが運ぶJSON-RPCメッセージです
+	data []byte
+}
+
+// eventLog is a bounded, ordered history of stream events for one session.
+// A client that reconnects with Last-Event-ID is replayed every event after that ID.
+//
+// eventLogは1つのセッションのストリームイベントの上限付き順序履歴です。
+// Last-Event-IDで再接続したクライアントには、そのID以降のすべてのイベントが再送されます。
+type eventLog struct {
+	mu     sync.Mutex
+	size   int
+	nextID uint64
+	events []streamEvent
+}
+
+// newEventLog creates an event log that keeps at most size events.
+// newEventLogは最大size件のイベントを保持するイベントログを作成します。
+func newEventLog(size int) *eventLog {
+	return &eventLog{size: size}
+}
+
+// append records data as a new event and returns its ID.
+// The oldest events are dropped once the log exceeds its size.
+//
+// appendはdataを新しいイベントとして記録し、そのIDを返します。
+// ログがサイズを超えると最も古いイベントから破棄されます。
+func (l *eventLog) append(data []byte) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	l.events = append(l.events, streamEvent{id: l.nextID, data: data})
+	if len(l.events) > l.size {
+		l.events = l.events[len(l.events)-l.size:]
+	}
+	return l.nextID
+}
+
+// since returns a copy of all recorded events with an ID greater than lastID.
+// sinceはIDがlastIDより大きい記録済みイベントすべてのコピーを返します。
+func (l *eventLog) since(lastID uint64) []streamEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var result []streamEvent
+	for _, ev := range l.events {
+		if ev.id > lastID {
+			result = append(result, ev)
+		}
+	}
+	return result
+}
+
+// handleStreamablePost handles JSON-RPC messages POSTed to the Streamable HTTP endpoint.
+//
+// The flow is:
+// 1. Decode the JSON-RPC message (batches are rejected)
+// 2. Create a new session for "initialize", otherwise resolve the Mcp-Session-Id header
+// 3. Acknowledge notifications and client responses with HTTP 202 (no body)
+// 4. Enforce initialization and process the request
+// 5. Return the response as JSON, or as an SSE stream when the client only accepts text/event-stream
+//
+// handleStreamablePostはStreamable HTTPエンドポイントにPOSTされたJSON-RPCメッセージを処理します。
+//
+// フローは以下の通りです：
+// 1. JSON-RPCメッセージをデコード（バッチは拒否）
+// 2. "initialize"なら新しいセッションを作成、それ以外はMcp-Session-Idヘッダーを解決
+// 3. 通知とクライアントからのレスポンスはHTTP 202（ボディなし）で受理
+// 4. 初期化を強制しリクエストを処理
+// 5. レスポンスをJSONで返す。クライアントがtext/event-streamのみ受け付ける場合はSSEストリームで返す
+func (s *Server) handleStreamablePost(w http.ResponseWriter, r *http.Request) {
+	// Read the raw request body for logging and decoding
+	// ログ出力とデコードのために生のリクエストボディを読み取る
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.Error("Failed to read request body", "error", err)
+		sendErrorWithStatus(w, http.StatusBadRequest, nil, -32700, "Parse error")
+		return
+	}
+
+	// JSON-RPC batches are optional in the specification and not supported here
+	// JSON-RPCバッチは仕様上オプションであり、ここではサポートしない
+	if trimmed := bytes.TrimSpace(bodyBytes); len(trimmed) > 0 && trimmed[0] == '[' {
+		sendErrorWithStatus(w, http.StatusBadRequest, nil, -32600, "Batch requests are not supported")
+		return
+	}
+
+	var req JSONRPCRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		slog.Error("Failed to decode JSON-RPC request", "error", err)
+		sendErrorWithStatus(w, http.StatusBadRequest, nil, -32700, "Parse error")
+		return
+	}
+	slog.Debug("Decoded JSON-RPC request", "method", req.Method, "id", req.ID)
+
+	// Notifications (no ID) and responses to server requests (no method) expect no reply
+	// 通知（IDなし）とサーバーリクエストへのレスポンス（メソッドなし）は返信を必要としない
+	noReply := req.ID == nil || req.Method == ""
+
+	// Resolve the session: "initialize" starts a new one, everything else must present its ID
+	// セッションを解決："initialize"は新規作成、それ以外はIDの提示が必要
+	sessionID := r.Header.Get(mcpSessionHeader)
+	var c *client
+	if req.Method == "initialize" {
+		if sessionID != "" || noReply {
+			sendErrorWithStatus(w, http.StatusBadRequest, req.ID, -32600, "Invalid initialize request")
+			return
+		}
+		c = s.newStreamableSession(r)
+	} else {
+		if sessionID == "" {
+			sendErrorWithStatus(w, http.StatusBadRequest, req.ID, -32600, "Missing "+mcpSessionHeader+" header")
+			return
+		}
+		var ok bool
+		c, ok = s.lookupStreamableSession(sessionID)
+		if !ok {
+			// 404 tells the client to start a new session with initialize
+			// 404はクライアントにinitializeで新しいセッションを開始するよう伝える
+			sendErrorWithStatus(w, http.StatusNotFound, req.ID, -32600, "Session not found")
+			return
+		}
+	}
+
+	if noReply {
+		if req.Method != "" {
+			s.handleNotification(c, &req)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Generate unique request number for log correlation
+	// ログの相関付けのために一意のリクエスト番号を生成
+	reqNum := atomic.AddUint64(&s.requestCounter, 1)
+	if s.verbosity >= 1 {
+		s.logVerboseRequest(c, &req, bodyBytes, reqNum)
+	}
+
+	// Copy the initialized state while holding the lock to avoid race condition
+	// 競合状態を避けるためにロックを保持したまま初期化状態をコピー
+	s.clientsMu.RLock()
+	clientInitialized := c.initialized
+	s.clientsMu.RUnlock()
+
+	stream := newPostStream(w, r, c)
+	resp := JSONRPCResponse{JSONRPC: "2.0", ID: req.ID}
+	if !clientInitialized && req.Method != "initialize" {
+		resp.Error = &JSONRPCError{Code: -32000, Message: "Client not initialized"}
+	} else if result, err := s.processRequest(c, &req, stream.send); errors.Is(err, errRequestCancelled) {
+		// A cancelled request gets no response: end the stream or acknowledge the POST
+		// キャンセルされたリクエストにはレスポンスを返さない：ストリームを終えるかPOSTの受理のみ通知
+		if !stream.finish(nil) {
+			w.WriteHeader(http.StatusAccepted)
+		}
+		return
+	} else if err != nil {
+		resp.Error = &JSONRPCError{Code: -32603, Message: err.Error()}
+		if req.Method == "initialize" {
+			s.removeStreamableSession(c)
+		}
+	} else {
+		resp.Result = result
+		if req.Method == "initialize" {
+			w.Header().Set(mcpSessionHeader, c.id)
+		}
+	}
+
+	if s.verbosity >= 1 {
+		s.logVerboseResponse(c, &resp, reqNum)
+	}
+	s.writeStreamableResponse(w, r, c, &resp, stream)
+}
+
+// handleStreamableGet opens an SSE stream for server-initiated messages on an existing
+// Streamable HTTP session. When the client sends Last-Event-ID, every recorded event after
+// that ID is replayed first so a dropped stream can be resumed without losing messages.
+//
+// handleStreamableGetは既存のStreamable HTTPセッションでサーバー起点のメッセージ用の
+// SSEストリームを開きます。クライアントがLast-Event-IDを送信した場合、そのID以降に記録された
+// すべてのイベントを最初に再送し、切断されたストリームをメッセージを失わずに再開できるようにします。
+func (s *Server) handleStreamableGet(w http.ResponseWriter, r *http.Request) {
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		http.Error(w, "Not Acceptable: GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	sessionID := r.Header.Get(mcpSessionHeader)
+	if sessionID == "" {
+		sendErrorWithStatus(w, http.StatusBadRequest, nil, -32600, "Missing "+mcpSessionHeader+" header")
+		return
+	}
+	c, ok := s.lookupStreamableSession(sessionID)
+	if !ok {
+		sendErrorWithStatus(w, http.StatusNotFound, nil, -32600, "Session not found")
+		return
+	}
+
+	// Track the open stream so the idle sweeper leaves this session alone
+	// アイドル掃除がこのセッションを破棄しないよう、開いているストリームを記録
+	s.clientsMu.Lock()
+	c.openStreams++
+	s.clientsMu.Unlock()
+	defer func() {
+		s.clientsMu.Lock()
+		c.openStreams--
+		c.lastActivity = time.Now()
+		s.clientsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	// Replay events the client missed since its last received event
+	// クライアントが最後に受信したイベント以降に取りこぼしたイベントを再送
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		if id, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+			for _, ev := range c.events.since(id) {
+				writeStreamEvent(w, ev.id, ev.data)
+			}
+		}
+	}
+
+	// Stream server-initiated messages until the client goes away or the session ends
+	// クライアントが切断するかセッションが終了するまでサーバー起点のメッセージをストリーミング
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.ctx.Done():
+			return
+		case msg := <-c.messages:
+			writeStreamEvent(w, c.events.append(msg), msg)
+		}
+	}
+}
+
+// handleStreamableDelete terminates a Streamable HTTP session at the client's request.
+// handleStreamableDeleteはクライアントの要求によりStreamable HTTPセッションを終了します。
+func (s *Server) handleStreamableDelete(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.Header.Get(mcpSessionHeader)
+	if sessionID == "" {
+		sendErrorWithStatus(w, http.StatusBadRequest, nil, -32600, "Missing "+mcpSessionHeader+" header")
+		return
+	}
+	c, ok := s.lookupStreamableSession(sessionID)
+	if !ok {
+		sendErrorWithStatus(w, http.StatusNotFound, nil, -32600, "Session not found")
+		return
+	}
+
+	s.removeStreamableSession(c)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStreamableResponse writes a JSON-RPC response to a Streamable HTTP POST.
+// If the request already switched the POST to an SSE stream by sending notifications,
+// the response is the last event on that stream. Otherwise JSON is used unless the
+// client's Accept header lists text/event-stream without application/json, in which
+// case the response is sent as a single SSE event (recorded in the session's event
+// log so it can be resumed).
+//
+// writeStreamableResponseはStreamable HTTPのPOSTにJSON-RPCレスポンスを書き込みます。
+// リクエストが通知の送信によってPOSTを既にSSEストリームに切り替えている場合、
+// レスポンスはそのストリームの最後のイベントになります。それ以外では、クライアントの
+// Acceptヘッダーがapplication/jsonなしでtext/event-streamを指定している場合を除き
+// JSONを使用します。その場合はレスポンスを単一のSSEイベントとして送信します
+// （再開できるようにセッションのイベントログに記録されます）。
+func (s *Server) writeStreamableResponse(w http.ResponseWriter, r *http.Request, c *client, resp *JSONRPCResponse, stream *postStream) {
+	respBytes, err := json.Marshal(resp)
+	if err != nil {
+		if !stream.finish(nil) {
+			sendError(w, resp.ID, -32603, "Failed to marshal response")
+		}
+		return
+	}
+	slog.Debug("JSON-RPC response", "id", resp.ID, "response_size", len(respBytes))
+
+	if stream.finish(respBytes) {
+		return
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/event-stream") && !strings.Contains(accept, "application/json") {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		writeStreamEvent(w, c.events.append(respBytes), respBytes)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBytes)
+}
+
+// writeStreamEvent writes one SSE "message" event with an ID and flushes it immediately.
+// writeStreamEventはIDつきのSSE "message"イベントを1つ書き込み、即座にフラッシュします。
+func writeStreamEvent(w http.ResponseWriter, id uint64, data []byte) {
+	fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", id, data)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// newStreamableSession creates and registers a new Streamable HTTP session.
+// Sessions are not bound to any single HTTP request, so they get their own context
+// which is cancelled on DELETE, idle expiry, or server shutdown.
+//
+// newStreamableSessionは新しいStreamable HTTPセッションを作成して登録します。
+// セッションは単一のHTTPリクエストに紐付かないため独自のコンテキストを持ち、
+// DELETE、アイドル期限切れ、またはサーバーシャットダウン時にキャンセルされます。
+func (s *Server) newStreamableSession(r *http.Request) *client {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	c := &client{
+		id:           generateSessionID(),
+		messages:     make(chan []byte, 10),
+		ctx:          ctx,
+		cancel:       cancel,
+		remoteAddr:   r.RemoteAddr,
+		userAgent:    r.UserAgent(),
+		connectedAt:  now,
+		transport:    transportStreamable,
+		events:       newEventLog(streamableEventHistorySize),
+		lastActivity: now,
+	}
+
+	s.clientsMu.Lock()
+	s.pruneIdleSessionsLocked(now)
+	s.clients[c.id] = c
+	s.clientsMu.Unlock()
+	return c
+}
+
+// lookupStreamableSession returns the Streamable HTTP session with the given ID and
+// records activity on it. Legacy SSE sessions are not reachable through this endpoint.
+//
+// lookupStreamableSessionは指定されたIDのStreamable HTTPセッションを返し、
+// アクティビティを記録します。レガシーSSEセッションはこのエンドポイントから参照できません。
+func (s *Server) lookupStreamableSession(id string) (*client, bool) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+
+	c, ok := s.clients[id]
+	if !ok || c.transport != transportStreamable {
+		return nil, false
+	}
+	c.lastActivity = time.Now()
+	return c, true
+}
+
+// removeStreamableSession unregisters a session, cancels its context and logs the disconnect.
+// removeStreamableSessionはセッションの登録を解除し、コンテキストをキャンセルして切断をログ出力します。
+func (s *Server) removeStreamableSession(c *client) {
+	s.clientsMu.Lock()
+	delete(s.clients, c.id)
+	s.clientsMu.Unlock()
+
+	c.cancel()
+	s.logClientDisconnect(c)
+}
+
+// pruneIdleSessionsLocked discards Streamable HTTP sessions that have had no requests and
+// no open stream for longer than streamableSessionIdleTimeout. Clients that vanish without
+// sending DELETE would otherwise accumulate forever. The caller must hold clientsMu.
+//
+// pruneIdleSessionsLockedはstreamableSessionIdleTimeoutより長くリクエストもストリームも
+// ないStreamable HTTPセッションを破棄します。DELETEを送らずに消えたクライアントが
+// 蓄積し続けるのを防ぎます。呼び出し元はclientsMuを保持している必要があります。
+func (s *Server) pruneIdleSessionsLocked(now time.Time) {
+	for id, c := range s.clients {
+		if c.transport != transportStreamable || c.openStreams > 0 {
+			continue
+		}
+		if now.Sub(c.lastActivity) > streamableSessionIdleTimeout {
+			delete(s.clients, id)
+			c.cancel()
+			s.logClientDisconnect(c)
+		}
+	}
+}
+
+// generateSessionID generates a cryptographically random Streamable HTTP session ID.
+// The specification requires session IDs to be globally unique and hard to guess,
+// since the ID alone authorizes requests on the session.
+//
+// generateSessionIDは暗号学的に安全なランダムStreamable HTTPセッションIDを生成します。
+// IDだけでセッション上のリクエストが許可されるため、仕様ではセッションIDが
+// グローバルに一意で推測困難であることが求められます。
+func generateSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to the timestamp ID
+		// crypto/randはサポート対象プラットフォームでは失敗しない。タイムスタンプIDにフォールバック
+		return generateClientID()
+	}
+	return "session-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,439 @@
+// streamable_test.go contains tests for the Streamable HTTP transport:
+// session creation, JSON and SSE response modes, notifications, session
+// termination, stream resumption with Last-Event-ID, and idle session expiry.
+//
+// streamable_test.goはStreamable HTTPトランスポートのテストを含みます：
+// セッション作成、JSONとSSEのレスポンスモード、通知、セッション終了、
+// Last-Event-IDによるストリーム再開、アイドルセッションの期限切れ。
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+)
+
+// newStreamableTestServer creates an MCP server and a test HTTP server exposing
+// both the Streamable HTTP endpoint and the legacy SSE endpoints.
+//
+// newStreamableTestServerはStreamable HTTPエンドポイントとレガシーSSEエンドポイントの
+// 両方を公開するMCPサーバーとテストHTTPサーバーを作成します。
+func newStreamableTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	return newStreamableTestServerWithDocker(t, &docker.Client{})
+}
+
+// newStreamableTestServerWithDocker is newStreamableTestServer with a custom Docker client.
+// newStreamableTestServerWithDockerは任意のDockerクライアントを使うnewStreamableTestServerです。
+func newStreamableTestServerWithDocker(t *testing.T, dockerClient docker.DockerClientInterface) (*Server, *httptest.Server) {
+	t.Helper()
+	server := NewServer(dockerClient, 0)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", server.handleSSE)
+	mux.HandleFunc("POST /message", server.handleMessage)
+	mux.HandleFunc("POST /mcp", server.handleStreamablePost)
+	mux.HandleFunc("GET /mcp", server.handleStreamableGet)
+	mux.HandleFunc("DELETE /mcp", server.handleStreamableDelete)
+
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return server, ts
+}
+
+// postMCP POSTs a JSON-RPC message to /mcp with the given session ID and Accept header.
+// postMCPは指定されたセッションIDとAcceptヘッダーでJSON-RPCメッセージを/mcpにPOSTします。
+func postMCP(t *testing.T, ts *httptest.Server, sessionID, accept string, msg any) *http.Response {
+	t.Helper()
+	body, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+	req, _ := http.NewRequest("POST", ts.URL+"/mcp", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	if sessionID != "" {
+		req.Header.Set(mcpSessionHeader, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /mcp failed: %v", err)
+	}
+	return resp
+}
+
+// initializeStreamable performs the initialize handshake and returns the session ID.
+// initializeStreamableは初期化ハンドシェイクを実行し、セッションIDを返します。
+func initializeStreamable(t *testing.T, ts *httptest.Server) string {
+	t.Helper()
+	resp := postMCP(t, ts, "", "application/json, text/event-stream", JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      0,
+		Method:  "initialize",
+		Params: map[string]any{
+			"clientInfo": map[string]string{"name": "test-client", "version": "1.0.0"},
+		},
+	})
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 on initialize, got %d", resp.StatusCode)
+	}
+	sessionID := resp.Header.Get(mcpSessionHeader)
+	if sessionID == "" {
+		t.Fatal("Expected Mcp-Session-Id header on initialize response")
+	}
+	return sessionID
+}
+
+// TestStreamableInitializeReturnsSessionAndJSON verifies that initialize over Streamable HTTP
+// creates a session, returns its ID in the header, and answers with a JSON body advertising
+// the Streamable HTTP protocol revision.
+//
+// TestStreamableInitializeReturnsSessionAndJSONは、Streamable HTTPでのinitializeが
+// セッションを作成し、そのIDをヘッダーで返し、Streamable HTTPのプロトコルリビジョンを
+// 示すJSONボディで応答することを検証します。
+func TestStreamableInitializeReturnsSessionAndJSON(t *testing.T) {
+	server, ts := newStreamableTestServer(t)
+
+	resp := postMCP(t, ts, "", "application/json, text/event-stream", JSONRPCRequest{
+		JSONRPC: "2.0", ID: 1, Method: "initialize",
+	})
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json response, got %q", ct)
+	}
+	sessionID := resp.Header.Get(mcpSessionHeader)
+	if !strings.HasPrefix(sessionID, "session-") {
+		t.Errorf("Expected generated session ID, got %q", sessionID)
+	}
+
+	var rpcResp struct {
+		Result map[string]any `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got := rpcResp.Result["protocolVersion"]; got != streamableProtocolVersion {
+		t.Errorf("Expected protocolVersion %q, got %v", streamableProtocolVersion, got)
+	}
+
+	server.clientsMu.RLock()
+	c, ok := server.clients[sessionID]
+	server.clientsMu.RUnlock()
+	if !ok {
+		t.Fatal("Session was not registered")
+	}
+	if !c.initialized || c.transport != transportStreamable {
+		t.Errorf("Expected initialized streamable session, got initialized=%v transport=%q", c.initialized, c.transport)
+	}
+}
+
+// TestStreamableSessionErrors verifies the HTTP status codes for missing and unknown sessions,
+// and that the legacy /message endpoint does not accept Streamable HTTP session IDs.
+//
+// TestStreamableSessionErrorsは、セッションなし・不明なセッションの場合のHTTPステータスコードと、
+// レガシーの/messageエンドポイントがStreamable HTTPのセッションIDを受け付けないことを検証します。
+func TestStreamableSessionErrors(t *testing.T) {
+	_, ts := newStreamableTestServer(t)
+	listReq := JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"}
+
+	tests := []struct {
+		name       string
+		sessionID  string
+		wantStatus int
+	}{
+		{name: "missing session header", sessionID: "", wantStatus: http.StatusBadRequest},
+		{name: "unknown session", sessionID: "session-unknown", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postMCP(t, ts, tt.sessionID, "application/json, text/event-stream", listReq)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	t.Run("legacy endpoint rejects streamable session", func(t *testing.T) {
+		sessionID := initializeStreamable(t, ts)
+		body, _ := json.Marshal(listReq)
+		resp, err := http.Post(ts.URL+"/message?sessionId="+sessionID, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /message failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var rpcResp JSONRPCResponse
+		json.NewDecoder(resp.Body).Decode(&rpcResp)
+		if rpcResp.Error == nil || rpcResp.Error.Message != "Invalid session ID" {
+			t.Errorf("Expected Invalid session ID error, got %+v", rpcResp.Error)
+		}
+	})
+}
+
+// TestStreamableNotificationAccepted verifies that notifications are acknowledged with
+// HTTP 202 and no body, and that requests before initialize completes are rejected.
+//
+// TestStreamableNotificationAcceptedは、通知がボディなしのHTTP 202で受理されることと、
+// 初期化完了前のリクエストが拒否されることを検証します。
+func TestStreamableNotificationAccepted(t *testing.T) {
+	server, ts := newStreamableTestServer(t)
+	sessionID := initializeStreamable(t, ts)
+
+	resp := postMCP(t, ts, sessionID, "application/json, text/event-stream", map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "notifications/initialized",
+	})
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected 202 for notification, got %d", resp.StatusCode)
+	}
+	if len(body) != 0 {
+		t.Errorf("Expected empty body for notification, got %q", body)
+	}
+
+	// A session whose initialize never completed must not process other methods
+	// initializeが完了していないセッションは他のメソッドを処理してはならない
+	server.clientsMu.Lock()
+	server.clients[sessionID].initialized = false
+	server.clientsMu.Unlock()
+
+	resp = postMCP(t, ts, sessionID, "application/json", JSONRPCRequest{JSONRPC: "2.0", ID: 2, Method: "tools/list"})
+	defer resp.Body.Close()
+	var rpcResp JSONRPCResponse
+	json.NewDecoder(resp.Body).Decode(&rpcResp)
+	if rpcResp.Error == nil || rpcResp.Error.Code != -32000 {
+		t.Errorf("Expected -32000 not initialized error, got %+v", rpcResp.Error)
+	}
+}
+
+// TestStreamableSSEResponseMode verifies that a client accepting only text/event-stream
+// receives the response as an SSE "message" event carrying an event ID.
+//
+// TestStreamableSSEResponseModeは、text/event-streamのみを受け付けるクライアントが
+// イベントIDつきのSSE "message"イベントとしてレスポンスを受け取ることを検証します。
+func TestStreamableSSEResponseMode(t *testing.T) {
+	_, ts := newStreamableTestServer(t)
+	sessionID := initializeStreamable(t, ts)
+
+	resp := postMCP(t, ts, sessionID, "text/event-stream", JSONRPCRequest{JSONRPC: "2.0", ID: 7, Method: "tools/list"})
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream response, got %q", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+	if !strings.Contains(text, "id: 1\nevent: message\ndata: ") {
+		t.Errorf("Expected SSE message event with ID, got %q", text)
+	}
+	if !strings.Contains(text, `"id":7`) || !strings.Contains(text, "list_containers") {
+		t.Errorf("Expected tools/list response for request 7, got %q", text)
+	}
+}
+
+// TestStreamableDeleteTerminatesSession verifies that DELETE removes the session
+// and that later requests on it receive 404.
+//
+// TestStreamableDeleteTerminatesSessionは、DELETEがセッションを削除し、
+// その後のリクエストが404を受け取ることを検証します。
+func TestStreamableDeleteTerminatesSession(t *testing.T) {
+	server, ts := newStreamableTestServer(t)
+	sessionID := initializeStreamable(t, ts)
+
+	req, _ := http.NewRequest("DELETE", ts.URL+"/mcp", nil)
+	req.Header.Set(mcpSessionHeader, sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /mcp failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 on DELETE, got %d", resp.StatusCode)
+	}
+
+	server.clientsMu.RLock()
+	_, exists := server.clients[sessionID]
+	server.clientsMu.RUnlock()
+	if exists {
+		t.Error("Session still registered after DELETE")
+	}
+
+	resp = postMCP(t, ts, sessionID, "application/json", JSONRPCRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 after DELETE, got %d", resp.StatusCode)
+	}
+}
+
+// TestStreamableGetStreamDeliversAndResumes verifies that the GET stream delivers
+// server-initiated messages and that reconnecting with Last-Event-ID replays only
+// the events after that ID.
+//
+// TestStreamableGetStreamDeliversAndResumesは、GETストリームがサーバー起点のメッセージを
+// 配信し、Last-Event-IDで再接続するとそのID以降のイベントのみが再送されることを検証します。
+func TestStreamableGetStreamDeliversAndResumes(t *testing.T) {
+	server, ts := newStreamableTestServer(t)
+	sessionID := initializeStreamable(t, ts)
+
+	server.clientsMu.RLock()
+	c := server.clients[sessionID]
+	server.clientsMu.RUnlock()
+
+	// openStream connects a GET stream and returns a channel of "id|data" pairs
+	// openStreamはGETストリームに接続し、"id|data"ペアのチャネルを返す
+	openStream := func(ctx context.Context, lastEventID string) <-chan string {
+		req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/mcp", nil)
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set(mcpSessionHeader, sessionID)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /mcp failed: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 on GET, got %d", resp.StatusCode)
+		}
+		events := make(chan string, 10)
+		go func() {
+			defer resp.Body.Close()
+			scanner := bufio.NewScanner(resp.Body)
+			var id string
+			for scanner.Scan() {
+				line := scanner.Text()
+				if strings.HasPrefix(line, "id: ") {
+					id = strings.TrimPrefix(line, "id: ")
+				}
+				if strings.HasPrefix(line, "data: ") {
+					events <- id + "|" + strings.TrimPrefix(line, "data: ")
+				}
+			}
+		}()
+		return events
+	}
+	receive := func(events <-chan string) string {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(3 * time.Second):
+			t.Fatal("Timeout waiting for stream event")
+			return ""
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := openStream(ctx, "")
+	c.messages <- []byte(`{"jsonrpc":"2.0","method":"notifications/first"}`)
+	c.messages <- []byte(`{"jsonrpc":"2.0","method":"notifications/second"}`)
+	if got := receive(events); !strings.HasPrefix(got, "1|") || !strings.Contains(got, "first") {
+		t.Errorf("Expected first event with ID 1, got %q", got)
+	}
+	if got := receive(events); !strings.HasPrefix(got, "2|") || !strings.Contains(got, "second") {
+		t.Errorf("Expected second event with ID 2, got %q", got)
+	}
+	cancel()
+
+	// Resume after event 1: only event 2 is replayed
+	// イベント1以降から再開：イベント2のみが再送される
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	resumed := openStream(ctx2, "1")
+	if got := receive(resumed); !strings.HasPrefix(got, "2|") || !strings.Contains(got, "second") {
+		t.Errorf("Expected replay of event 2, got %q", got)
+	}
+	select {
+	case ev := <-resumed:
+		t.Errorf("Unexpected extra replayed event %q", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestStreamableGetRequiresEventStream verifies that GET without Accept: text/event-stream is rejected.
+// TestStreamableGetRequiresEventStreamは、Accept: text/event-streamなしのGETが拒否されることを検証します。
+func TestStreamableGetRequiresEventStream(t *testing.T) {
+	_, ts := newStreamableTestServer(t)
+	sessionID := initializeStreamable(t, ts)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/mcp", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(mcpSessionHeader, sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /mcp failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotAcceptable {
+		t.Errorf("Expected 406, got %d", resp.StatusCode)
+	}
+}
+
+// TestPruneIdleSessions verifies that idle Streamable HTTP sessions are discarded while
+// sessions with an open stream and legacy SSE sessions are kept.
+//
+// TestPruneIdleSessionsは、アイドル状態のStreamable HTTPセッションが破棄され、
+// ストリームが開いているセッションとレガシーSSEセッションは保持されることを検証します。
+func TestPruneIdleSessions(t *testing.T) {
+	server := NewServer(&docker.Client{}, 0)
+	old := time.Now().Add(-2 * streamableSessionIdleTimeout)
+	newSession := func(id, transport string, openStreams int) *client {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := &client{id: id, ctx: ctx, cancel: cancel, transport: transport, lastActivity: old, openStreams: openStreams}
+		server.clients[id] = c
+		return c
+	}
+	idle := newSession("idle", transportStreamable, 0)
+	newSession("streaming", transportStreamable, 1)
+	newSession("legacy", transportSSE, 0)
+	newSession("recent", transportStreamable, 0).lastActivity = time.Now()
+
+	server.clientsMu.Lock()
+	server.pruneIdleSessionsLocked(time.Now())
+	server.clientsMu.Unlock()
+
+	if _, ok := server.clients["idle"]; ok {
+		t.Error("Idle session was not pruned")
+	}
+	if idle.ctx.Err() == nil {
+		t.Error("Pruned session context was not cancelled")
+	}
+	for _, id := range []string{"streaming", "legacy", "recent"} {
+		if _, ok := server.clients[id]; !ok {
+			t.Errorf("Session %q should not be pruned", id)
+		}
+	}
+}
+
+// TestEventLogBounded verifies that the event log keeps only the newest events
+// and that since() returns events strictly after the given ID.
+//
+// TestEventLogBoundedは、イベントログが最新のイベントのみを保持し、
+// since()が指定IDより後のイベントのみを返すことを検証します。
+func TestEventLogBounded(t *testing.T) {
+	log := newEventLog(3)
+	for i := 0; i < 5; i++ {
+		log.append([]byte{byte('a' + i)})
+	}
+
+	all := log.since(0)
+	if len(all) != 3 || all[0].id != 3 || all[2].id != 5 {
+		t.Fatalf("Expected events 3..5, got %+v", all)
+	}
+	after := log.since(4)
+	if len(after) != 1 || string(after[0].data) != "e" {
+		t.Errorf("Expected only event 5, got %+v", after)
+	}
+}
@@ -12,10 +12,16 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
 )
 
@@ -76,12 +82,104 @@ type ToolProperty struct {
 	Items *ToolPropertyItems `json:"items,omitempty"`
 }
 
+// minimum returns a Minimum constraint of n.
+// minimumはnのMinimum制約を返します。
+func minimum(n int) *int {
+	return &n
+}
+
 // ToolPropertyItems represents the schema for items in an array property.
 // ToolPropertyItemsは配列プロパティ内のアイテムのスキーマを表します。
 type ToolPropertyItems struct {
 	Type string `json:"type"`
 }
 
+// ToolOutputSchema is the JSON schema of a tool's structuredContent. It has the same
+// shape as an input schema: an object whose properties are the result fields.
+//
+// ToolOutputSchemaはツールのstructuredContentのJSONスキーマです。入力スキーマと
+// 同じ形で、結果のフィールドをプロパティとして持つオブジェクトです。
+type ToolOutputSchema = ToolInputSchema
+
+// outputSchema returns an object schema with the given result fields.
+// outputSchemaは指定された結果フィールドを持つオブジェクトスキーマを返します。
+func outputSchema(properties map[string]ToolProperty, required ...string) *ToolOutputSchema {
+	return &ToolOutputSchema{Type: "object", Properties: properties, Required: required}
+}
+
+// fileOutputSchema returns the schema of a file tool's result: the given fields plus the
+// container, path, error and block fields that every file tool may return.
+//
+// fileOutputSchemaはファイルツールの結果のスキーマを返します。指定されたフィールドに加えて、
+// すべてのファイルツールが返し得るcontainer、path、error、ブロックのフィールドを持ちます。
+func fileOutputSchema(properties map[string]ToolProperty) *ToolOutputSchema {
+	fields := map[string]ToolProperty{
+		"container": {Type: "string", Description: "Container the tool was called for"},
+		"path":      {Type: "string", Description: "Requested path"},
+		"error":     {Type: "string", Description: "Why the operation failed, if it did"},
+		"blocked":   {Type: "boolean", Description: "True when the security policy blocked the path"},
+		"reason":    {Type: "string", Description: "Block reason, when blocked"},
+		"hint":      {Type: "string", Description: "Explanation of the block, when blocked"},
+	}
+	for name, p := range properties {
+		fields[name] = p
+	}
+	return outputSchema(fields, "container", "path")
+}
+
+// fileWriteOutputSchema is the result schema of write_file and apply_patch.
+// fileWriteOutputSchemaはwrite_fileとapply_patchの結果のスキーマです。
+var fileWriteOutputSchema = fileOutputSchema(map[string]ToolProperty{
+	"changed":     {Type: "boolean", Description: "False when the file already had the content and nothing was written"},
+	"created":     {Type: "boolean", Description: "True when the file did not exist before"},
+	"backup_path": {Type: "string", Description: "Where the previous content was saved"},
+	"diff":        {Type: "string", Description: "Unified diff of the change, masked"},
+})
+
+// fileCopyOutputSchema is the result schema of copy_from_container and copy_to_container.
+// fileCopyOutputSchemaはcopy_from_containerとcopy_to_containerの結果のスキーマです。
+var fileCopyOutputSchema = fileOutputSchema(map[string]ToolProperty{
+	"host_path":       {Type: "string", Description: "Host path as requested, relative to the workspace"},
+	"files":           arrayOf("object", "Copied files with their path, size and sha256"),
+	"bytes":           {Type: "integer", Description: "Total number of bytes copied"},
+	"skipped_blocked": {Type: "integer", Description: "Number of blocked paths that were skipped"},
+	"skipped_special": {Type: "integer", Description: "Number of symlinks and special files that were skipped"},
+})
+
+// jobOutputSchema is the result schema of the job tools: the job's metadata plus the
+// given properties, which are also required.
+//
+// jobOutputSchemaはジョブツールの結果のスキーマです：ジョブのメタデータに指定された
+// プロパティ（これらも必須）を加えたものです。
+func jobOutputSchema(properties map[string]ToolProperty, required ...string) *ToolOutputSchema {
+	fields := map[string]ToolProperty{
+		"job_id":       {Type: "string", Description: "Job ID"},
+		"container":    {Type: "string", Description: "Container the command runs in"},
+		"command":      {Type: "string", Description: "Command of the job"},
+		"state":        {Type: "string", Description: "running, exited, timed_out, cancelled or failed"},
+		"started_at":   {Type: "string", Description: "When the job started (RFC 3339)"},
+		"ended_at":     {Type: "string", Description: "When the job ended (RFC 3339), once it has"},
+		"duration_ms":  {Type: "integer", Description: "How long the job has run, in milliseconds"},
+		"exit_code":    {Type: "integer", Description: "Exit code once the job exited; -1 when it timed out"},
+		"output_bytes": {Type: "integer", Description: "Size of the output kept so far, in bytes"},
+		"truncated":    {Type: "boolean", Description: "True when output beyond the exec_output limit was discarded"},
+		"error":        {Type: "string", Description: "Why the job failed, when it did"},
+		"workdir":      {Type: "string", Description: "Working directory, when one was requested"},
+		"env":          {Type: "object", Description: "Environment variables set, when any were requested"},
+		"user":         {Type: "string", Description: "User the command runs as, when one was requested"},
+	}
+	for name, p := range properties {
+		fields[name] = p
+	}
+	return outputSchema(fields, append([]string{"job_id", "container", "command", "state", "started_at", "duration_ms", "output_bytes", "truncated"}, required...)...)
+}
+
+// arrayOf returns an array property whose items are of the given type.
+// arrayOfは指定された型のアイテムを持つ配列プロパティを返します。
+func arrayOf(itemType, description string) ToolProperty {
+	return ToolProperty{Type: "array", Description: description, Items: &ToolPropertyItems{Type: itemType}}
+}
+
 // Tool represents an MCP tool that can be invoked by AI assistants.
 // Each tool has a name, description, and input schema that defines
 // what parameters it accepts.
@@ -101,6 +199,10 @@ type Tool struct {
 	// InputSchema defines the parameters this tool accepts
 	// InputSchemaはこのツールが受け入れるパラメータを定義します
 	InputSchema ToolInputSchema `json:"inputSchema"`
+
+	// OutputSchema describes the structuredContent of the tool's result, if it has one
+	// OutputSchemaはツールの結果のstructuredContentがある場合にそれを記述します
+	OutputSchema *ToolOutputSchema `json:"outputSchema,omitempty"`
 }
 
 // ClientInfo represents the client information sent during MCP initialization.
@@ -170,7 +272,12 @@ func (s *Server) initialize(params any) (any, string, string, error) {
 			"version": ServerVersion,
 		},
 		"capabilities": map[string]any{
-			"tools": map[string]bool{},
+			// The tool list changes when a configuration reload enables or disables host access
+			// 設定の再読み込みでホストアクセスが有効・無効になるとツール一覧が変わる
+			"tools": map[string]bool{"listChanged": true},
+			// follow_logs streams log lines as notifications/message
+			// follow_logsはログ行をnotifications/messageとしてストリームする
+			"logging": map[string]any{},
 		},
 	}
 	return response, clientName, clientVersion, nil
@@ -200,6 +307,19 @@ func GetTools() []Tool {
 					},
 				},
 			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"containers": arrayOf("object", "Accessible containers with their id, name, image, state and status"),
+			}, "containers"),
+		},
+		// list_services: Lists Docker Compose services with their replicas
+		// list_services: Docker Composeのサービスをレプリカと共に一覧表示
+		{
+			Name:        "list_services",
+			Description: "List accessible Docker Compose services grouped by project, with their replicas, state and health. Any tool's 'container' parameter also accepts a service name ('api') or 'project/service'.",
+			InputSchema: ToolInputSchema{
+				Type:       "object",
+				Properties: map[string]ToolProperty{},
+			},
 		},
 		// get_logs: Retrieves logs from a specific container
 		// get_logs: 特定のコンテナからログを取得
@@ -211,7 +331,7 @@ func GetTools() []Tool {
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 					"tail": {
 						Type:        "string",
@@ -226,6 +346,70 @@ func GetTools() []Tool {
 				Required: []string{"container"},
 			},
 		},
+		// follow_logs: Streams new log lines from a container as notifications
+		// follow_logs: コンテナの新しいログ行を通知としてストリーム
+		{
+			Name:        "follow_logs",
+			Description: "Follow logs from a container in real time. New lines are streamed as progress notifications (when a progressToken is given) or log messages until the duration or line limit is reached or the request is cancelled. The result contains all collected lines.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"tail": {
+						Type:        "string",
+						Description: "Number of existing lines to show before following (default: 0 = only new lines)",
+						Default:     "0",
+					},
+					"since": {
+						Type:        "string",
+						Description: "Show logs since timestamp (e.g., 2013-01-02T13:23:37Z) or relative (e.g., 42m for 42 minutes)",
+					},
+					"duration_seconds": {
+						Type:        "integer",
+						Description: "How long to follow the logs in seconds (default: 60, max: 600)",
+						Default:     60,
+					},
+					"max_lines": {
+						Type:        "integer",
+						Description: "Stop after this many lines (default: 1000)",
+						Default:     1000,
+					},
+				},
+				Required: []string{"container"},
+			},
+		},
+		// correlate_logs: Merges logs from several containers into one timeline
+		// correlate_logs: 複数コンテナのログを1つの時系列にマージ
+		{
+			Name:        "correlate_logs",
+			Description: "Get logs from several containers at once, merged by Docker timestamp into one interleaved timeline with a container prefix on each line. Useful for following a request across services. Defaults to all running accessible containers.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"containers": {
+						Type:        "array",
+						Description: "Container names, IDs or Compose services (default: all running accessible containers)",
+						Items:       &ToolPropertyItems{Type: "string"},
+					},
+					"tail": {
+						Type:        "string",
+						Description: "Number of lines to fetch from the end of each container's logs (default: 100)",
+						Default:     "100",
+					},
+					"since": {
+						Type:        "string",
+						Description: "Show logs since timestamp (e.g., '2024-01-01T10:00:00Z') or relative (e.g., '42m', '2h')",
+					},
+					"until": {
+						Type:        "string",
+						Description: "Show logs before timestamp (e.g., '2024-01-01T11:00:00Z') or relative (e.g., '10m')",
+					},
+				},
+			},
+		},
 		// get_stats: Gets resource usage statistics for a container
 		// get_stats: コンテナのリソース使用統計を取得
 		{
@@ -236,23 +420,144 @@ func GetTools() []Tool {
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 				},
 				Required: []string{"container"},
 			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"container": {Type: "string", Description: "Container the statistics are for"},
+				"stats":     {Type: "object", Description: "Docker stats snapshot (CPU, memory, network, block I/O)"},
+			}, "container", "stats"),
+		},
+		// sample_stats: Samples resource usage over a window and summarizes the trend
+		// sample_stats: 期間内のリソース使用量をサンプリングしてトレンドを要約
+		{
+			Name:        "sample_stats",
+			Description: "Sample resource usage of one or more containers several times over a window and summarize CPU %, memory %, memory usage, and network and block I/O rates as min/avg/max/last with a trend (rising, falling or stable). Use this instead of get_stats to tell whether memory is climbing. Defaults to all running accessible containers.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"containers": {
+						Type:        "array",
+						Description: "Container names, IDs or Compose services (default: all running accessible containers)",
+						Items:       &ToolPropertyItems{Type: "string"},
+					},
+					"samples": {
+						Type:        "integer",
+						Description: "Number of samples per container (default: 5, maximum: 60)",
+						Default:     sampleStatsDefaultSamples,
+						Minimum:     minimum(2),
+					},
+					"interval_seconds": {
+						Type:        "integer",
+						Description: "Seconds between samples (default: 2). The window, samples × interval, is capped at 5 minutes.",
+						Default:     int(sampleStatsDefaultInterval / time.Second),
+						Minimum:     minimum(1),
+					},
+				},
+			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"samples":          {Type: "integer", Description: "Samples taken per container"},
+				"interval_seconds": {Type: "integer", Description: "Seconds between samples"},
+				"containers":       arrayOf("object", "Per-container summaries: cpu_percent, memory_percent, memory_usage_bytes and the byte rates, each with min, avg, max, last and trend"),
+				"failed":           arrayOf("string", "Containers that could not be sampled, with the reason"),
+			}, "samples", "interval_seconds", "containers"),
+		},
+		// get_events: Returns recent Docker events (die, restart, health changes) of containers
+		// get_events: コンテナの最近のDockerイベント（停止、再起動、ヘルス変化）を返す
+		{
+			Name:        "get_events",
+			Description: "Get recent Docker events of accessible containers, oldest first: start, stop, restart, die (with exit code), oom, health_status (healthy/unhealthy) and similar. Use this to find out when and why a container stopped or restarted. Docker only keeps a limited number of past events.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service (default: all accessible containers)",
+					},
+					"types": {
+						Type:        "array",
+						Description: "Event types to return (default: " + strings.Join(docker.DefaultEventTypes, ", ") + ")",
+						Items:       &ToolPropertyItems{Type: "string"},
+					},
+					"since": {
+						Type:        "string",
+						Description: "Start of the range: timestamp (e.g., '2024-01-01T10:00:00Z') or relative (e.g., '42m', '12h') (default: 1h)",
+						Default:     getEventsDefaultSince,
+					},
+					"until": {
+						Type:        "string",
+						Description: "End of the range: timestamp or relative (default: now)",
+					},
+				},
+			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"events": arrayOf("object", "Events with time, container, action, and health, exit_code and image when known"),
+				"count":  {Type: "integer", Description: "Number of events returned"},
+			}, "events", "count"),
 		},
 		// exec_command: Executes a whitelisted command inside a container
 		// exec_command: コンテナ内でホワイトリストに登録されたコマンドを実行
 		{
 			Name:        "exec_command",
-			Description: "Execute a command inside a container. Only whitelisted commands are allowed based on security policy. Use dangerously=true to execute commands from exec_dangerously list (file paths are still checked against blocked_paths).",
+			Description: "Execute a command inside a container. Only whitelisted commands are allowed based on security policy. Commands are killed after their timeout (exec_timeout, default 300s), so do not run commands that never exit such as tail -f; use follow_logs instead. Use dangerously=true to execute commands from exec_dangerously list (file paths are still checked against blocked_paths). If a command is denied, explain_policy shows which rule applies.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"command": {
+						Type:        "string",
+						Description: "Command to execute (must be whitelisted in config, or in exec_dangerously list if dangerously=true)",
+					},
+					"dangerously": {
+						Type:        "boolean",
+						Description: "Enable dangerous mode to execute commands from exec_dangerously list. File paths in the command are still checked against blocked_paths. Pipes and redirects are not allowed.",
+					},
+					"workdir": {
+						Type:        "string",
+						Description: "Absolute working directory to run the command in (must be allowed by exec_options; see get_allowed_commands)",
+					},
+					"env": {
+						Type:        "object",
+						Description: "Environment variables to set, as {\"NAME\": \"value\"} (must be allowed by exec_options)",
+					},
+					"user": {
+						Type:        "string",
+						Description: "User to run the command as, e.g. 'node' or '1000:1000' (must be allowed by exec_options)",
+					},
+				},
+				Required: []string{"container", "command"},
+			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"container": {Type: "string", Description: "Container the command ran in"},
+				"command":   {Type: "string", Description: "Command that was run"},
+				"exit_code":   {Type: "integer", Description: "Exit code of the command"},
+				"output":      {Type: "string", Description: "Combined stdout and stderr in the order written, masked"},
+				"stdout":      {Type: "string", Description: "Standard output, masked"},
+				"stderr":      {Type: "string", Description: "Standard error, masked"},
+				"duration_ms": {Type: "integer", Description: "How long the command ran, in milliseconds"},
+				"truncated":   {Type: "boolean", Description: "True when output beyond the exec_output limit was discarded"},
+				"timed_out":   {Type: "boolean", Description: "True when the command ran past its timeout and was killed; exit_code is then -1"},
+				"workdir":     {Type: "string", Description: "Working directory, when one was requested"},
+				"env":         {Type: "object", Description: "Environment variables set, when any were requested"},
+				"user":        {Type: "string", Description: "User the command ran as, when one was requested"},
+			}, "container", "command", "exit_code", "output", "stdout", "stderr", "duration_ms", "truncated", "timed_out"),
+		},
+		// start_exec_job: Starts a whitelisted command in the background
+		// start_exec_job: ホワイトリストに登録されたコマンドをバックグラウンドで開始
+		{
+			Name:        "start_exec_job",
+			Description: "Start a whitelisted command inside a container as a background job and return its job_id at once. Use this instead of exec_command for commands that take minutes, such as a full test suite, then poll get_job_output until complete is true. Jobs are killed after their timeout (exec_jobs.timeout_seconds, default 3600s) and only visible to the MCP session that started them.",
 			InputSchema: ToolInputSchema{
 				Type: "object",
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 					"command": {
 						Type:        "string",
@@ -262,9 +567,89 @@ func GetTools() []Tool {
 						Type:        "boolean",
 						Description: "Enable dangerous mode to execute commands from exec_dangerously list. File paths in the command are still checked against blocked_paths. Pipes and redirects are not allowed.",
 					},
+					"workdir": {
+						Type:        "string",
+						Description: "Absolute working directory to run the command in (must be allowed by exec_options; see get_allowed_commands)",
+					},
+					"env": {
+						Type:        "object",
+						Description: "Environment variables to set, as {\"NAME\": \"value\"} (must be allowed by exec_options)",
+					},
+					"user": {
+						Type:        "string",
+						Description: "User to run the command as, e.g. 'node' or '1000:1000' (must be allowed by exec_options)",
+					},
 				},
 				Required: []string{"container", "command"},
 			},
+			OutputSchema: jobOutputSchema(nil),
+		},
+		// get_job_output: Returns the output of a job from an offset
+		// get_job_output: オフセットからジョブの出力を返す
+		{
+			Name:        "get_job_output",
+			Description: "Get the state and new output of a job started with start_exec_job. Pass the next_offset of the previous call as offset to receive only new output; output is masked and ends on a line boundary while the job runs. The job has ended and all output was returned when complete is true.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"job_id": {
+						Type:        "string",
+						Description: "Job ID returned by start_exec_job",
+					},
+					"offset": {
+						Type:        "integer",
+						Description: "Byte offset into the job's output to read from (default: 0)",
+						Default:     0,
+						Minimum:     minimum(0),
+					},
+					"max_bytes": {
+						Type:        "integer",
+						Description: fmt.Sprintf("Maximum bytes of output to return (default: %d)", jobOutputDefaultMaxBytes),
+						Default:     jobOutputDefaultMaxBytes,
+						Minimum:     minimum(1),
+					},
+				},
+				Required: []string{"job_id"},
+			},
+			OutputSchema: jobOutputSchema(map[string]ToolProperty{
+				"offset":      {Type: "integer", Description: "Offset the output was read from"},
+				"next_offset": {Type: "integer", Description: "Offset to pass to the next call"},
+				"complete":    {Type: "boolean", Description: "True when the job has ended and the output reaches its end"},
+				"output":      {Type: "string", Description: "Combined stdout and stderr from offset, masked"},
+			}, "offset", "next_offset", "complete", "output"),
+		},
+		// cancel_job: Stops a running job
+		// cancel_job: 実行中のジョブを停止
+		{
+			Name:        "cancel_job",
+			Description: "Cancel a job started with start_exec_job: the exec is torn down and the processes it started in the container are killed. Its output stays available through get_job_output.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"job_id": {
+						Type:        "string",
+						Description: "Job ID returned by start_exec_job",
+					},
+				},
+				Required: []string{"job_id"},
+			},
+			OutputSchema: jobOutputSchema(map[string]ToolProperty{
+				"was_running": {Type: "boolean", Description: "False when the job had already ended"},
+			}, "was_running"),
+		},
+		// list_jobs: Lists the jobs of the calling client
+		// list_jobs: 呼び出し元クライアントのジョブを一覧表示
+		{
+			Name:        "list_jobs",
+			Description: "List the jobs started in this MCP session with start_exec_job, oldest first, with their state. Finished jobs are kept for an hour.",
+			InputSchema: ToolInputSchema{
+				Type:       "object",
+				Properties: map[string]ToolProperty{},
+			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"jobs":  arrayOf("object", "Jobs with job_id, container, command, state, started_at, duration_ms and exit_code when ended"),
+				"count": {Type: "integer", Description: "Number of jobs"},
+			}, "jobs", "count"),
 		},
 		// inspect_container: Gets detailed information about a container
 		// inspect_container: コンテナに関する詳細情報を取得
@@ -276,23 +661,27 @@ func GetTools() []Tool {
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 				},
 				Required: []string{"container"},
 			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"container": {Type: "string", Description: "Container that was inspected"},
+				"info":      {Type: "object", Description: "Docker inspect result, masked"},
+			}, "container", "info"),
 		},
 		// get_allowed_commands: Lists whitelisted commands for a container
 		// get_allowed_commands: コンテナのホワイトリストに登録されたコマンドを一覧表示
 		{
 			Name:        "get_allowed_commands",
-			Description: "Get the list of whitelisted commands that can be executed in a container. Use this to discover what commands are available before using exec_command.",
+			Description: "Get the list of whitelisted commands that can be executed in a container, and the workdir, env and user values (exec_options) they may be run with. Use this to discover what commands are available before using exec_command.",
 			InputSchema: ToolInputSchema{
 				Type: "object",
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name. If not specified, returns commands for all containers.",
+						Description: "Container name or Compose service. If not specified, returns commands for all containers.",
 					},
 				},
 			},
@@ -311,17 +700,53 @@ func GetTools() []Tool {
 		// search_logs: コンテナログ内でパターンを検索
 		{
 			Name:        "search_logs",
-			Description: "Search container logs for a specific pattern. Returns matching lines with context.",
+			Description: "Search container logs for a specific pattern. Supports regular expressions, an exclude pattern, time windows, stdout/stderr selection, and filtering JSON structured logs by field. Returns match counts and matching lines with line numbers, timestamps and context.",
 			InputSchema: ToolInputSchema{
 				Type: "object",
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 					"pattern": {
 						Type:        "string",
-						Description: "Search pattern (case-insensitive substring match)",
+						Description: "Search pattern (case-insensitive substring match unless regex/case_sensitive are set). Use an empty string to match every line.",
+					},
+					"regex": {
+						Type:        "boolean",
+						Description: "Treat pattern and exclude as regular expressions (RE2 syntax) (default: false)",
+						Default:     false,
+					},
+					"case_sensitive": {
+						Type:        "boolean",
+						Description: "Match pattern and exclude case-sensitively (default: false)",
+						Default:     false,
+					},
+					"exclude": {
+						Type:        "string",
+						Description: "Drop lines matching this pattern (same matching mode as pattern)",
+					},
+					"since": {
+						Type:        "string",
+						Description: "Only search logs after this time: timestamp (e.g., '2024-01-01T10:00:00Z') or relative (e.g., '42m', '2h')",
+					},
+					"until": {
+						Type:        "string",
+						Description: "Only search logs before this time: timestamp (e.g., '2024-01-01T11:00:00Z') or relative (e.g., '10m')",
+					},
+					"stream": {
+						Type:        "string",
+						Description: "Which output to search: 'stdout', 'stderr' or 'all' (default: all)",
+						Default:     "all",
+					},
+					"structured": {
+						Type:        "boolean",
+						Description: "Parse each line as a JSON log entry; lines that are not JSON objects are skipped (default: false)",
+						Default:     false,
+					},
+					"fields": {
+						Type:        "object",
+						Description: "JSON field filters for structured logs, e.g. {\"level\": \"error\", \"request_id\": \"abc\"}. Values compare case-insensitively; use dots for nested fields. Implies structured.",
 					},
 					"tail": {
 						Type:        "string",
@@ -334,54 +759,307 @@ func GetTools() []Tool {
 						Default:     2,
 					},
 				},
-				Required: []string{"container", "pattern"},
+				Required: []string{"container", "pattern"},
+			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"container":      {Type: "string", Description: "Container whose logs were searched"},
+				"pattern":        {Type: "string", Description: "Search pattern"},
+				"total_lines":    {Type: "integer", Description: "Number of log lines searched"},
+				"matches_count":  {Type: "integer", Description: "Number of matching lines"},
+				"matches":        arrayOf("object", "Matching lines with line number, timestamp, stream and context"),
+				"non_json_lines": {Type: "integer", Description: "Lines skipped by a field filter because they are not JSON"},
+			}, "container", "pattern", "total_lines", "matches_count", "matches"),
+		},
+		// list_files: Lists files in a container directory
+		// list_files: コンテナディレクトリ内のファイルを一覧表示
+		{
+			Name:        "list_files",
+			Description: "List files in a container directory (name, type, size, mode, mtime, symlink target). Works in distroless and scratch images. Returns at most 1000 entries; the listing is marked truncated when it stops early. Blocked paths will be denied with detailed reason.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"path": {
+						Type:        "string",
+						Description: "Directory path to list (default: /)",
+						Default:     "/",
+					},
+				},
+				Required: []string{"container"},
+			},
+			OutputSchema: fileOutputSchema(map[string]ToolProperty{
+				"entries":   arrayOf("object", "Directory entries with name, type, size, mode and mtime"),
+				"truncated": {Type: "boolean", Description: "True when the directory tree was too large to scan completely"},
+				"note":      {Type: "string", Description: "Advice on truncated listings"},
+			}),
+		},
+		// read_file: Reads a file from a container
+		// read_file: コンテナからファイルを読み取る
+		{
+			Name:        "read_file",
+			Description: "Read a file, or a byte or line range of it, or its last lines, from a container. Works in distroless and scratch images. Content is capped per call; when more remains, the response includes a cursor to continue. Binary files return their size and SHA-256 instead of content. Blocked paths will be denied with detailed reason.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"path": {
+						Type:        "string",
+						Description: "File path to read",
+					},
+					"start_line": {
+						Type:        "integer",
+						Description: "First line to read, 1-based (default: 1)",
+					},
+					"max_lines": {
+						Type:        "integer",
+						Description: "Maximum number of lines to read (default: 0 = all)",
+						Default:     0,
+					},
+					"offset": {
+						Type:        "integer",
+						Description: "First byte to read, 0-based. Cannot be combined with start_line/max_lines",
+					},
+					"length": {
+						Type:        "integer",
+						Description: "Maximum number of bytes to read (default: 0 = to the end, capped by file_read.max_bytes)",
+					},
+					"tail": {
+						Type:        "integer",
+						Description: "Read the last N lines of the file. Cannot be combined with other ranges",
+						Minimum:     minimum(1),
+					},
+					"cursor": {
+						Type:        "string",
+						Description: "Continue a previous read from the next_cursor it returned. Can be combined with max_lines or length",
+					},
+				},
+				Required: []string{"container", "path"},
+			},
+			OutputSchema: fileOutputSchema(map[string]ToolProperty{
+				"content":     {Type: "string", Description: "File content, masked (absent for binary files)"},
+				"offset":      {Type: "integer", Description: "Byte offset of content in the file"},
+				"start_line":  {Type: "integer", Description: "Line number of the first line of content, for line and tail reads"},
+				"next_cursor": {Type: "string", Description: "Cursor that continues the read; absent at the end of the file"},
+				"truncated":   {Type: "boolean", Description: "True when content was cut at the size limit"},
+				"binary":      {Type: "boolean", Description: "True when the file holds binary content, which is not returned"},
+				"sha256":      {Type: "string", Description: "SHA-256 of a binary file"},
+				"size":        {Type: "integer", Description: "Size of a binary file in bytes"},
+			}),
+		},
+		// find_files: Searches a container directory tree by name and type
+		// find_files: コンテナのディレクトリツリーを名前と種別で検索
+		{
+			Name:        "find_files",
+			Description: "Find files in a container directory tree by name glob and type, instead of walking it with list_files. Works in distroless and scratch images. Blocked paths are skipped and blocked directories are not searched.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"path": {
+						Type:        "string",
+						Description: "Directory to search (default: /)",
+						Default:     "/",
+					},
+					"name": {
+						Type:        "string",
+						Description: "Glob matched against the base name, e.g. '*.go' or 'config.*' (default: any)",
+					},
+					"type": {
+						Type:        "string",
+						Description: "Only return entries of this type: 'file', 'dir' or 'symlink' (default: any)",
+					},
+					"max_depth": {
+						Type:        "integer",
+						Description: "Maximum depth below path to search (default: 0 = unlimited)",
+						Default:     0,
+						Minimum:     minimum(0),
+					},
+					"max_results": {
+						Type:        "integer",
+						Description: "Maximum number of entries to return (default: 100, max: 1000)",
+						Default:     100,
+						Minimum:     minimum(1),
+					},
+				},
+				Required: []string{"container"},
+			},
+			OutputSchema: fileOutputSchema(map[string]ToolProperty{
+				"entries":         arrayOf("object", "Matching entries with their path, type and size"),
+				"truncated":       {Type: "boolean", Description: "True when not all entries are shown"},
+				"note":            {Type: "string", Description: "Advice on truncated results"},
+				"skipped_blocked": {Type: "integer", Description: "Number of blocked paths that were skipped"},
+			}),
+		},
+		// grep_files: Searches the files in a container directory for a pattern
+		// grep_files: コンテナのディレクトリ内のファイルからパターンを検索
+		{
+			Name:        "grep_files",
+			Description: "Search the files under a container directory (or a single file) for lines matching a regular expression (RE2 syntax). Works in distroless and scratch images. Returns path, line number and the masked line. Binary files and blocked paths are skipped, and blocked directories are not searched.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"pattern": {
+						Type:        "string",
+						Description: "Regular expression to search for",
+					},
+					"path": {
+						Type:        "string",
+						Description: "Directory or file to search (default: /)",
+						Default:     "/",
+					},
+					"include": {
+						Type:        "string",
+						Description: "Only search files whose base name matches this glob, e.g. '*.yaml'",
+					},
+					"ignore_case": {
+						Type:        "boolean",
+						Description: "Match the pattern case-insensitively (default: false)",
+						Default:     false,
+					},
+					"max_depth": {
+						Type:        "integer",
+						Description: "Maximum depth below path to search (default: 0 = unlimited)",
+						Default:     0,
+						Minimum:     minimum(0),
+					},
+					"max_results": {
+						Type:        "integer",
+						Description: "Maximum number of matching lines to return (default: 100, max: 1000)",
+						Default:     100,
+						Minimum:     minimum(1),
+					},
+				},
+				Required: []string{"container", "pattern"},
+			},
+			OutputSchema: fileOutputSchema(map[string]ToolProperty{
+				"matches":         arrayOf("object", "Matching lines with their file path and line number"),
+				"truncated":       {Type: "boolean", Description: "True when not all matches are shown"},
+				"note":            {Type: "string", Description: "Advice on truncated results"},
+				"skipped_blocked": {Type: "integer", Description: "Number of blocked paths that were skipped"},
+			}),
+		},
+		// write_file: Writes a file in a container
+		// write_file: コンテナ内のファイルを書き込む
+		{
+			Name:        "write_file",
+			Description: "Replace the content of a file in a container, or create it. Requires the write permission, and the path must be covered by writable_paths for the container (see get_security_policy); blocked paths are never writable. The previous content is saved as <file>.dkmcp.bak (or the next free <file>.dkmcp.bak.N), and the diff is returned and audit-logged. Prefer apply_patch for small changes to large files.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"path": {
+						Type:        "string",
+						Description: "File path to write",
+					},
+					"content": {
+						Type:        "string",
+						Description: "New content of the file (text, at most 1 MiB)",
+					},
+				},
+				Required: []string{"container", "path", "content"},
+			},
+			OutputSchema: fileWriteOutputSchema,
+		},
+		// apply_patch: Applies a unified diff to a file in a container
+		// apply_patch: コンテナ内のファイルにunified diffを適用
+		{
+			Name:        "apply_patch",
+			Description: "Apply a unified diff (as produced by diff -u or git diff) to an existing file in a container. Every hunk's context must match the file exactly; hunks may have moved by some lines. Same permission and writable_paths rules as write_file; the previous content is saved as <file>.dkmcp.bak (or the next free <file>.dkmcp.bak.N), and the diff is returned and audit-logged.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"path": {
+						Type:        "string",
+						Description: "File path to patch",
+					},
+					"patch": {
+						Type:        "string",
+						Description: "Unified diff for this file, with @@ hunk headers; ---/+++ file headers are ignored",
+					},
+				},
+				Required: []string{"container", "path", "patch"},
 			},
+			OutputSchema: fileWriteOutputSchema,
 		},
-		// list_files: Lists files in a container directory
-		// list_files: コンテナディレクトリ内のファイルを一覧表示
+		// copy_from_container: Copies files from a container to the host workspace
+		// copy_from_container: コンテナからホストのワークスペースにファイルをコピー
 		{
-			Name:        "list_files",
-			Description: "List files in a container directory. Blocked paths will be denied with detailed reason.",
+			Name:        "copy_from_container",
+			Description: "Copy a file or directory (e.g. a heap dump or test report) from a container into the host workspace. Requires the copy permission. Blocked paths inside a directory are skipped, symlinks are not copied, and the total size is capped by file_copy.max_bytes. The host path must be inside the workspace and must not exist unless overwrite is set for a file. Every copied file's SHA-256 is returned and audit-logged.",
 			InputSchema: ToolInputSchema{
 				Type: "object",
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 					"path": {
 						Type:        "string",
-						Description: "Directory path to list (default: /)",
-						Default:     "/",
+						Description: "File or directory path in the container",
+					},
+					"host_path": {
+						Type:        "string",
+						Description: "Destination in the host workspace, relative to the workspace root (e.g. 'reports/junit')",
+					},
+					"overwrite": {
+						Type:        "boolean",
+						Description: "Replace an existing file at host_path (directories are never merged)",
+						Default:     false,
 					},
 				},
-				Required: []string{"container"},
+				Required: []string{"container", "path", "host_path"},
 			},
+			OutputSchema: fileCopyOutputSchema,
 		},
-		// read_file: Reads a file from a container
-		// read_file: コンテナからファイルを読み取る
+		// copy_to_container: Copies files from the host workspace to a container
+		// copy_to_container: ホストのワークスペースからコンテナにファイルをコピー
 		{
-			Name:        "read_file",
-			Description: "Read a file from a container. Blocked paths will be denied with detailed reason.",
+			Name:        "copy_to_container",
+			Description: "Copy a file or directory from the host workspace into a container. Requires the copy permission, and every destination path must be covered by writable_paths for the container; blocked paths are never written or read. The total size is capped by file_copy.max_bytes. Every copied file's SHA-256 is returned and audit-logged.",
 			InputSchema: ToolInputSchema{
 				Type: "object",
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"host_path": {
+						Type:        "string",
+						Description: "File or directory in the host workspace, relative to the workspace root",
 					},
 					"path": {
 						Type:        "string",
-						Description: "File path to read",
+						Description: "Destination path in the container (created with the source's name)",
 					},
-					"max_lines": {
-						Type:        "integer",
-						Description: "Maximum number of lines to read (default: 0 = all)",
-						Default:     0,
+					"overwrite": {
+						Type:        "boolean",
+						Description: "Replace path if it already exists in the container",
+						Default:     false,
 					},
 				},
-				Required: []string{"container", "path"},
+				Required: []string{"container", "host_path", "path"},
 			},
+			OutputSchema: fileCopyOutputSchema,
 		},
 		// get_blocked_paths: Returns the list of blocked file paths
 		// get_blocked_paths: ブロックされたファイルパスのリストを返す
@@ -393,9 +1071,49 @@ func GetTools() []Tool {
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name. If not specified, returns blocked paths for all containers.",
+						Description: "Container name or Compose service. If not specified, returns blocked paths for all containers.",
+					},
+				},
+			},
+		},
+		// explain_policy: Dry-runs the policy checks for a command or path
+		// explain_policy: コマンドまたはパスに対するポリシーチェックをドライラン
+		{
+			Name:        "explain_policy",
+			Description: "Explain whether exec_command, read_file or list_files would be allowed, without running anything. Runs the same policy checks, including exec_options and the resolution of symlinks and mounts in the container, and returns the decision, the matching exec_whitelist or exec_dangerously entry, and any blocked path with the file it was defined in and the resolved path that matched it. Use this when a command or file access is denied to find an allowed alternative.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
+					},
+					"command": {
+						Type:        "string",
+						Description: "Command to check as exec_command would run it",
+					},
+					"dangerously": {
+						Type:        "boolean",
+						Description: "Check the command against the exec_dangerously list instead of the whitelist",
+					},
+					"workdir": {
+						Type:        "string",
+						Description: "Working directory the command would run in, checked against exec_options as exec_command does",
+					},
+					"env": {
+						Type:        "object",
+						Description: "Environment variables the command would get, as {\"NAME\": \"value\"}, checked against exec_options",
+					},
+					"user": {
+						Type:        "string",
+						Description: "User the command would run as, checked against exec_options",
+					},
+					"path": {
+						Type:        "string",
+						Description: "File path to check as read_file or list_files would access it",
 					},
 				},
+				Required: []string{"container"},
 			},
 		},
 		// Container Lifecycle Operations
@@ -411,7 +1129,7 @@ func GetTools() []Tool {
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 					"timeout": {
 						Type:        "integer",
@@ -431,7 +1149,7 @@ func GetTools() []Tool {
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 					"timeout": {
 						Type:        "integer",
@@ -451,7 +1169,7 @@ func GetTools() []Tool {
 				Properties: map[string]ToolProperty{
 					"container": {
 						Type:        "string",
-						Description: "Container name or ID",
+						Description: "Container name, ID, or Compose service ('api' or 'project/service')",
 					},
 				},
 				Required: []string{"container"},
@@ -472,16 +1190,22 @@ func (s *Server) listTools() (any, error) {
 
 	// Append host tools if configured
 	// ホストツールが設定されている場合は追加
-	if s.hostToolsManager != nil && s.hostToolsManager.IsEnabled() {
+	if manager := s.hostTools(); manager != nil && manager.IsEnabled() {
 		tools = append(tools, GetHostTools()...)
 	}
 
 	// Append host command tools if configured
 	// ホストコマンドツールが設定されている場合は追加
-	if s.hostCommandPolicy != nil {
+	if policy, _, _ := s.hostCommands(); policy != nil {
 		tools = append(tools, GetHostCommandTools()...)
 	}
 
+	// Append the metrics history tool if the recorder is enabled
+	// レコーダーが有効な場合はメトリクス履歴ツールを追加
+	if s.metricsHistory != nil {
+		tools = append(tools, GetMetricsHistoryTools()...)
+	}
+
 	return map[string]any{
 		"tools": tools,
 	}, nil
@@ -517,12 +1241,30 @@ func (s *Server) callTool(ctx context.Context, params any) (any, error) {
 	switch toolName {
 	case "list_containers":
 		return s.toolListContainers(ctx, arguments)
+	case "list_services":
+		return s.toolListServices(ctx, arguments)
 	case "get_logs":
 		return s.toolGetLogs(ctx, arguments)
+	case "follow_logs":
+		return s.toolFollowLogs(ctx, arguments)
+	case "correlate_logs":
+		return s.toolCorrelateLogs(ctx, arguments)
 	case "get_stats":
 		return s.toolGetStats(ctx, arguments)
+	case "sample_stats":
+		return s.toolSampleStats(ctx, arguments)
+	case "get_events":
+		return s.toolGetEvents(ctx, arguments)
 	case "exec_command":
 		return s.toolExecCommand(ctx, arguments)
+	case "start_exec_job":
+		return s.toolStartExecJob(ctx, arguments)
+	case "get_job_output":
+		return s.toolGetJobOutput(ctx, arguments)
+	case "cancel_job":
+		return s.toolCancelJob(ctx, arguments)
+	case "list_jobs":
+		return s.toolListJobs(ctx, arguments)
 	case "inspect_container":
 		return s.toolInspectContainer(ctx, arguments)
 	case "get_allowed_commands":
@@ -535,8 +1277,22 @@ func (s *Server) callTool(ctx context.Context, params any) (any, error) {
 		return s.toolListFiles(ctx, arguments)
 	case "read_file":
 		return s.toolReadFile(ctx, arguments)
+	case "find_files":
+		return s.toolFindFiles(ctx, arguments)
+	case "grep_files":
+		return s.toolGrepFiles(ctx, arguments)
+	case "write_file":
+		return s.toolWriteFile(ctx, arguments)
+	case "apply_patch":
+		return s.toolApplyPatch(ctx, arguments)
+	case "copy_from_container":
+		return s.toolCopyFromContainer(ctx, arguments)
+	case "copy_to_container":
+		return s.toolCopyToContainer(ctx, arguments)
 	case "get_blocked_paths":
 		return s.toolGetBlockedPaths(ctx, arguments)
+	case "explain_policy":
+		return s.toolExplainPolicy(ctx, arguments)
 	// Container lifecycle operations
 	// コンテナライフサイクル操作
 	case "restart_container":
@@ -557,6 +1313,10 @@ func (s *Server) callTool(ctx context.Context, params any) (any, error) {
 	// ホストコマンド操作
 	case "exec_host_command":
 		return s.toolExecHostCommand(ctx, arguments)
+	// Metrics history operations
+	// メトリクス履歴操作
+	case "query_metrics":
+		return s.toolQueryMetrics(ctx, arguments)
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", toolName)
 	}
@@ -587,7 +1347,112 @@ func (s *Server) toolListContainers(ctx context.Context, args map[string]any) (a
 	// ホストパスマスキングを適用してホストOSのユーザー名やディレクトリ構造を隠す
 	maskedJSON := s.docker.GetPolicy().MaskHostPaths(string(jsonBytes))
 
-	return textResponse(maskedJSON), nil
+	containerList, err := decodeStructured(maskedJSON)
+	if err != nil {
+		return nil, err
+	}
+	return withStructuredContent(textResponse(maskedJSON), map[string]any{"containers": containerList}), nil
+}
+
+// ServiceInfo describes a Docker Compose service and its replicas in list_services output.
+// ServiceInfoはlist_servicesの出力におけるDocker Composeのサービスとそのレプリカを表します。
+type ServiceInfo struct {
+	// Project is the Compose project name
+	// ProjectはComposeのプロジェクト名
+	Project string `json:"project"`
+
+	// Service is the Compose service name
+	// ServiceはComposeのサービス名
+	Service string `json:"service"`
+
+	// Running is the number of running replicas
+	// Runningは実行中のレプリカ数
+	Running int `json:"running"`
+
+	// Health counts replicas by health status (omitted when no replica has a health check)
+	// Healthはヘルス状態ごとのレプリカ数（ヘルスチェックを持つレプリカがない場合は省略）
+	Health map[string]int `json:"health,omitempty"`
+
+	// Replicas lists the service's containers
+	// Replicasはサービスのコンテナを列挙します
+	Replicas []ServiceReplica `json:"replicas"`
+}
+
+// ServiceReplica is one container of a Compose service.
+// ServiceReplicaはComposeのサービスの1つのコンテナです。
+type ServiceReplica struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Status string `json:"status"`
+	Health string `json:"health,omitempty"`
+}
+
+// toolListServices implements the list_services tool.
+// It groups the accessible containers by Compose project and service.
+// Containers that are not part of a Compose project are listed separately.
+//
+// toolListServicesはlist_servicesツールを実装します。
+// アクセス可能なコンテナをComposeのプロジェクトとサービスでグループ化します。
+// Composeプロジェクトに属さないコンテナは別に列挙します。
+func (s *Server) toolListServices(ctx context.Context, args map[string]any) (any, error) {
+	slog.Debug("Listing services")
+	containers, err := s.docker.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Order by replica number so replicas are listed as api-1, api-2, ...
+	// レプリカがapi-1、api-2、...の順に並ぶようレプリカ番号で整列
+	ids := make([]security.ContainerIdentity, len(containers))
+	byName := make(map[string]docker.ContainerInfo, len(containers))
+	for i, c := range containers {
+		ids[i] = security.NewContainerIdentity(c.Name, c.Labels)
+		byName[c.Name] = c
+	}
+	security.SortReplicas(ids)
+
+	var services []*ServiceInfo
+	index := make(map[string]*ServiceInfo)
+	standalone := []string{}
+	for _, id := range ids {
+		c := byName[id.Name]
+		if !id.IsCompose() {
+			standalone = append(standalone, c.Name)
+			continue
+		}
+		svc, ok := index[id.ServiceRef()]
+		if !ok {
+			svc = &ServiceInfo{Project: id.Project, Service: id.Service}
+			index[id.ServiceRef()] = svc
+			services = append(services, svc)
+		}
+		svc.Replicas = append(svc.Replicas, ServiceReplica{Name: c.Name, State: c.State, Status: c.Status, Health: c.Health})
+		if c.State == "running" {
+			svc.Running++
+		}
+		if c.Health != "" {
+			if svc.Health == nil {
+				svc.Health = make(map[string]int)
+			}
+			svc.Health[c.Health]++
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Project+"/"+services[i].Service < services[j].Project+"/"+services[j].Service
+	})
+	sort.Strings(standalone)
+
+	jsonBytes, err := json.MarshalIndent(map[string]any{
+		"services":   services,
+		"standalone": standalone,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal service list: %w", err)
+	}
+
+	// Apply host path masking to hide host OS username and directory structure
+	// ホストパスマスキングを適用してホストOSのユーザー名やディレクトリ構造を隠す
+	return textResponse(s.docker.GetPolicy().MaskHostPaths(string(jsonBytes))), nil
 }
 
 // toolGetLogs implements the get_logs tool.
@@ -635,6 +1500,305 @@ func (s *Server) toolGetLogs(ctx context.Context, args map[string]any) (any, err
 	return textResponse(fmt.Sprintf("Logs from container '%s':\n\n%s", container, maskedLogs)), nil
 }
 
+// correlatedLine is one log line in the merged timeline of correlate_logs.
+// correlatedLineはcorrelate_logsのマージされた時系列における1行のログです。
+type correlatedLine struct {
+	container string
+	timestamp time.Time
+	message   string
+}
+
+// correlateLogsTimeFormat is a fixed-width timestamp format so merged lines align.
+// correlateLogsTimeFormatはマージした行が揃うように固定幅のタイムスタンプ形式です。
+const correlateLogsTimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// toolCorrelateLogs implements the correlate_logs tool.
+// It fetches logs from several containers in parallel, masks them, and merges them
+// by Docker timestamp into one timeline. Containers that cannot be read (e.g., access
+// denied) are reported alongside the merged logs instead of failing the whole call.
+//
+// toolCorrelateLogsはcorrelate_logsツールを実装します。
+// 複数のコンテナから並行してログを取得し、マスクした上で、Dockerのタイムスタンプで
+// 1つの時系列にマージします。読み取れないコンテナ（アクセス拒否など）は呼び出し全体を
+// 失敗させず、マージしたログと一緒に報告します。
+func (s *Server) toolCorrelateLogs(ctx context.Context, args map[string]any) (any, error) {
+	// Extract optional containers parameter
+	// オプションのcontainersパラメータを抽出
+	var containers []string
+	if raw, ok := args["containers"].([]any); ok {
+		for _, c := range raw {
+			if name, ok := c.(string); ok && name != "" {
+				containers = append(containers, name)
+			}
+		}
+	}
+
+	// Default to every running container the policy allows
+	// デフォルトはポリシーが許可する実行中のすべてのコンテナ
+	if len(containers) == 0 {
+		list, err := s.docker.ListContainers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			if c.State == "running" {
+				containers = append(containers, c.Name)
+			}
+		}
+		if len(containers) == 0 {
+			return textResponse("No running accessible containers found."), nil
+		}
+	}
+
+	tail := "100"
+	if t, ok := args["tail"].(string); ok {
+		tail = t
+	}
+	since, _ := args["since"].(string)
+	until, _ := args["until"].(string)
+
+	slog.Debug("Correlating logs", "containers", containers, "since", since, "until", until)
+
+	// Fetch all containers in parallel; each fetch applies that container's access check
+	// すべてのコンテナを並行して取得。各取得でそのコンテナのアクセスチェックが適用される
+	type fetchResult struct {
+		lines []docker.LogLine
+		err   error
+	}
+	results := make([]fetchResult, len(containers))
+	var wg sync.WaitGroup
+	for i, name := range containers {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			lines, err := s.docker.GetLogLines(ctx, name, tail, since, until)
+			results[i] = fetchResult{lines: lines, err: err}
+		}(i, name)
+	}
+	wg.Wait()
+
+	policy := s.docker.GetPolicy()
+	var (
+		merged  []correlatedLine
+		failed  []string
+		fetched []string
+		width   int
+	)
+	for i, name := range containers {
+		if results[i].err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, results[i].err))
+			continue
+		}
+		fetched = append(fetched, name)
+		if len(name) > width {
+			width = len(name)
+		}
+
+		// Lines without a timestamp inherit the previous line's so they stay next to it
+		// タイムスタンプのない行は直前の行のものを引き継ぎ、その行の隣に留まる
+		var last time.Time
+		for _, logLine := range results[i].lines {
+			ts, message, ok := docker.SplitTimestamp(logLine.Text)
+			if ok {
+				last = ts
+			}
+			merged = append(merged, correlatedLine{
+				container: name,
+				timestamp: last,
+				message:   policy.MaskLogs(message),
+			})
+		}
+	}
+
+	if len(fetched) == 0 {
+		return nil, fmt.Errorf("failed to get logs from any container: %s", strings.Join(failed, "; "))
+	}
+
+	// Stable sort keeps each container's own line order for equal timestamps
+	// 安定ソートにより、同じタイムスタンプでは各コンテナ内の行順が保たれる
+	sort.SliceStable(merged, func(a, b int) bool {
+		return merged[a].timestamp.Before(merged[b].timestamp)
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Correlated logs from %d containers (%s), %d lines:\n\n", len(fetched), strings.Join(fetched, ", "), len(merged))
+	for _, line := range merged {
+		fmt.Fprintf(&sb, "%s [%-*s] %s\n", line.timestamp.UTC().Format(correlateLogsTimeFormat), width, line.container, line.message)
+	}
+	if len(failed) > 0 {
+		sb.WriteString("\nSkipped containers:\n")
+		for _, f := range failed {
+			fmt.Fprintf(&sb, "  - %s\n", f)
+		}
+	}
+
+	// Host path masking also covers paths in the skipped containers' errors
+	// ホストパスマスキングはスキップしたコンテナのエラー内のパスにも適用される
+	return textResponse(policy.MaskHostPaths(sb.String())), nil
+}
+
+// Limits and batching for the follow_logs tool.
+// follow_logsツールの上限とバッチ処理の設定です。
+const (
+	// followLogsDefaultDuration is how long follow_logs runs when no duration is given
+	// followLogsDefaultDurationは期間が指定されない場合にfollow_logsが実行される時間です
+	followLogsDefaultDuration = 60 * time.Second
+
+	// followLogsMaxDuration caps duration_seconds so a forgotten call cannot run forever
+	// followLogsMaxDurationは忘れられた呼び出しが永久に続かないようduration_secondsを制限します
+	followLogsMaxDuration = 10 * time.Minute
+
+	// followLogsDefaultMaxLines is the line limit when max_lines is not given
+	// followLogsDefaultMaxLinesはmax_linesが指定されない場合の行数上限です
+	followLogsDefaultMaxLines = 1000
+
+	// followLogsFlushInterval is how often buffered lines are masked and sent as one chunk
+	// followLogsFlushIntervalはバッファされた行をマスクして1つのチャンクとして送信する間隔です
+	followLogsFlushInterval = 500 * time.Millisecond
+)
+
+// toolFollowLogs implements the follow_logs tool.
+// It follows a container's log stream and forwards new lines to the client as
+// notifications/progress (when the request has a progress token) or
+// notifications/message, in chunks flushed every followLogsFlushInterval.
+// Every chunk is masked with MaskLogs and MaskHostPaths before it leaves the server.
+//
+// Following stops when the duration elapses, max_lines is reached, the container
+// stops, or the client sends notifications/cancelled. The result contains all
+// collected (masked) lines and the reason for stopping.
+//
+// toolFollowLogsはfollow_logsツールを実装します。
+// コンテナのログストリームをフォローし、新しい行をnotifications/progress
+// （リクエストが進捗トークンを持つ場合）またはnotifications/messageとして、
+// followLogsFlushIntervalごとにまとめたチャンクでクライアントに転送します。
+// 各チャンクはサーバーから送出される前にMaskLogsとMaskHostPathsでマスクされます。
+//
+// フォローは期間の経過、max_linesへの到達、コンテナの停止、またはクライアントが
+// notifications/cancelledを送信した時点で停止します。結果には収集した（マスク済みの）
+// すべての行と停止理由が含まれます。
+func (s *Server) toolFollowLogs(ctx context.Context, args map[string]any) (any, error) {
+	// Extract required container parameter
+	// 必須のcontainerパラメータを抽出
+	container, ok := args["container"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+
+	// Extract optional parameters with defaults
+	// デフォルト値を持つオプションパラメータを抽出
+	tail := "0"
+	if t, ok := args["tail"].(string); ok {
+		tail = t
+	}
+	since := ""
+	if s, ok := args["since"].(string); ok {
+		since = s
+	}
+	duration := followLogsDefaultDuration
+	if d, ok := args["duration_seconds"].(float64); ok && d > 0 {
+		duration = time.Duration(d * float64(time.Second))
+	}
+	if duration > followLogsMaxDuration {
+		duration = followLogsMaxDuration
+	}
+	maxLines := followLogsDefaultMaxLines
+	if m, ok := args["max_lines"].(float64); ok && m > 0 {
+		maxLines = int(m)
+	}
+
+	slog.Debug("Following logs", "container", container, "duration", duration, "max_lines", maxLines)
+
+	policy := s.docker.GetPolicy()
+	notifier := notifierFromContext(ctx)
+	followCtx, stop := context.WithTimeout(ctx, duration)
+	defer stop()
+
+	// Read the stream in the background so chunks can be flushed on a timer
+	// タイマーでチャンクをフラッシュできるよう、ストリームはバックグラウンドで読み取る
+	lines := make(chan docker.LogLine, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.docker.FollowLogs(followCtx, container, tail, since, func(line docker.LogLine) error {
+			select {
+			case lines <- line:
+				return nil
+			case <-followCtx.Done():
+				return followCtx.Err()
+			}
+		})
+	}()
+
+	start := time.Now()
+	var chunks, pending []string
+	count := 0
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		// Mask per chunk so nothing unmasked is ever sent as a notification
+		// 未マスクのデータが通知として送られないようチャンクごとにマスク
+		chunk := policy.MaskHostPaths(policy.MaskLogs(strings.Join(pending, "\n")))
+		chunks = append(chunks, chunk)
+		pending = pending[:0]
+
+		var err error
+		if notifier.hasProgressToken() {
+			err = notifier.progress(float64(count), float64(maxLines), chunk)
+		} else {
+			err = notifier.log("info", "follow_logs", map[string]any{"container": container, "lines": chunk})
+		}
+		if err != nil {
+			slog.Debug("Failed to send follow_logs notification", "container", container, "error", err)
+		}
+	}
+
+	ticker := time.NewTicker(followLogsFlushInterval)
+	defer ticker.Stop()
+
+	var streamErr error
+	streamDone := false
+	for !streamDone && count < maxLines {
+		select {
+		case line := <-lines:
+			pending = append(pending, line.Text)
+			count++
+		case <-ticker.C:
+			flush()
+		case streamErr = <-done:
+			streamDone = true
+		}
+	}
+
+	// Stop the reader and collect lines it delivered before it returned
+	// リーダーを停止し、終了前に配信された行を回収
+	stop()
+	if !streamDone {
+		streamErr = <-done
+	}
+	for count < maxLines && len(lines) > 0 {
+		pending = append(pending, (<-lines).Text)
+		count++
+	}
+	flush()
+
+	var reason string
+	switch {
+	case count >= maxLines:
+		reason = "max_lines reached"
+	case ctx.Err() != nil:
+		reason = "cancelled"
+	case streamErr != nil && !errors.Is(streamErr, context.Canceled) && !errors.Is(streamErr, context.DeadlineExceeded):
+		return nil, streamErr
+	case time.Since(start) >= duration:
+		reason = "duration elapsed"
+	default:
+		reason = "log stream ended"
+	}
+
+	return textResponse(fmt.Sprintf("Followed logs from container '%s' for %s (%d lines, %s):\n\n%s",
+		container, time.Since(start).Round(100*time.Millisecond), count, reason, strings.Join(chunks, "\n"))), nil
+}
+
 // toolGetStats implements the get_stats tool.
 // It retrieves resource usage statistics (CPU, memory, network, etc.)
 // for a specific container.
@@ -659,7 +1823,129 @@ func (s *Server) toolGetStats(ctx context.Context, args map[string]any) (any, er
 	// 統計情報をJSONテキストでフォーマット
 	content := formatStats(stats)
 
-	return textResponse(content), nil
+	statsData, err := decodeStructured(content)
+	if err != nil {
+		return nil, err
+	}
+	return withStructuredContent(textResponse(content), map[string]any{
+		"container": container,
+		"stats":     statsData,
+	}), nil
+}
+
+const (
+	// sampleStatsDefaultSamples is the number of samples when samples is not given
+	// sampleStatsDefaultSamplesはsamplesが指定されない場合のサンプル数です
+	sampleStatsDefaultSamples = 5
+
+	// sampleStatsMaxSamples caps samples per container
+	// sampleStatsMaxSamplesはコンテナごとのサンプル数を制限します
+	sampleStatsMaxSamples = 60
+
+	// sampleStatsDefaultInterval is the time between samples when interval_seconds is not given
+	// sampleStatsDefaultIntervalはinterval_secondsが指定されない場合のサンプル間隔です
+	sampleStatsDefaultInterval = 2 * time.Second
+
+	// sampleStatsMaxWindow caps samples × interval so one call cannot hold a session for long
+	// sampleStatsMaxWindowは1回の呼び出しがセッションを長時間占有しないようsamples × intervalを制限します
+	sampleStatsMaxWindow = 5 * time.Minute
+)
+
+// toolSampleStats implements the sample_stats tool.
+// It samples the stats of several containers in parallel and summarizes each series.
+//
+// toolSampleStatsはsample_statsツールを実装します。
+// 複数のコンテナの統計を並行してサンプリングし、各系列を要約します。
+func (s *Server) toolSampleStats(ctx context.Context, args map[string]any) (any, error) {
+	var containers []string
+	if raw, ok := args["containers"].([]any); ok {
+		for _, c := range raw {
+			if name, ok := c.(string); ok && name != "" {
+				containers = append(containers, name)
+			}
+		}
+	}
+
+	// Default to every running container the policy allows
+	// デフォルトはポリシーが許可する実行中のすべてのコンテナ
+	if len(containers) == 0 {
+		list, err := s.docker.ListContainers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			if c.State == "running" {
+				containers = append(containers, c.Name)
+			}
+		}
+		if len(containers) == 0 {
+			return textResponse("No running accessible containers found."), nil
+		}
+	}
+
+	samples := sampleStatsDefaultSamples
+	if v, ok := args["samples"].(float64); ok {
+		samples = int(v)
+	}
+	if samples < 2 {
+		return nil, fmt.Errorf("samples must be at least 2")
+	}
+	if samples > sampleStatsMaxSamples {
+		samples = sampleStatsMaxSamples
+	}
+	interval := sampleStatsDefaultInterval
+	if v, ok := args["interval_seconds"].(float64); ok {
+		interval = time.Duration(v) * time.Second
+	}
+	if interval < time.Second {
+		return nil, fmt.Errorf("interval_seconds must be at least 1")
+	}
+	if window := time.Duration(samples-1) * interval; window > sampleStatsMaxWindow {
+		return nil, fmt.Errorf("sampling window %s exceeds the maximum of %s; use fewer samples or a shorter interval", window, sampleStatsMaxWindow)
+	}
+
+	slog.Debug("Sampling stats", "containers", containers, "samples", samples, "interval", interval)
+
+	// Sample all containers in parallel; each snapshot applies that container's access check
+	// すべてのコンテナを並行してサンプリング。各スナップショットでそのコンテナのアクセスチェックが適用される
+	type sampleResult struct {
+		points []docker.StatsPoint
+		err    error
+	}
+	results := make([]sampleResult, len(containers))
+	var wg sync.WaitGroup
+	for i, name := range containers {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			points, err := docker.SampleStats(ctx, s.docker, name, samples, interval)
+			results[i] = sampleResult{points: points, err: err}
+		}(i, name)
+	}
+	wg.Wait()
+
+	summaries := []docker.StatsSummary{}
+	var failed []string
+	for i, name := range containers {
+		if results[i].err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", name, results[i].err))
+			continue
+		}
+		summaries = append(summaries, docker.SummarizeStats(name, results[i].points))
+	}
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf("failed to sample stats from any container: %s", strings.Join(failed, "; "))
+	}
+
+	result := map[string]any{
+		"samples":          samples,
+		"interval_seconds": int(interval / time.Second),
+		"containers":       summaries,
+	}
+	if len(failed) > 0 {
+		result["failed"] = failed
+	}
+	return structuredResponse(result)
 }
 
 // toolExecCommand implements the exec_command tool.
@@ -693,6 +1979,13 @@ func (s *Server) toolExecCommand(ctx context.Context, args map[string]any) (any,
 		dangerously = d
 	}
 
+	// Extract the optional workdir, env and user parameters
+	// オプションのworkdir、env、userパラメータを抽出
+	opts, err := execOptionsArg(args)
+	if err != nil {
+		return nil, err
+	}
+
 	// Log the command execution for audit purposes
 	// Use WARN level for dangerous mode to make it stand out
 	// 監査目的でコマンド実行をログに記録
@@ -713,7 +2006,16 @@ func (s *Server) toolExecCommand(ctx context.Context, args map[string]any) (any,
 	// The Docker client checks the whitelist (or exec_dangerously list if dangerously=true)
 	// Dockerクライアントを通じてコマンドを実行
 	// Dockerクライアントはホワイトリスト（dangerously=trueの場合はexec_dangerouslyリスト）をチェック
-	result, err := s.docker.Exec(ctx, container, command, dangerously)
+	start := time.Now()
+	result, err := s.docker.Exec(ctx, container, command, dangerously, opts)
+	auditDetails := map[string]any{"command": command, "dangerously": dangerously}
+	addExecOptions(auditDetails, opts)
+	if err != nil && ctx.Err() != nil {
+		// The client cancelled the request; the exec and its processes were torn down
+		// クライアントがリクエストをキャンセルした。execとそのプロセスは終了済み
+		audit.LogToolCall(ctx, "exec_command", container, audit.ResultCancelled, audit.MeasureDuration(start), auditDetails)
+		return nil, err
+	}
 	if err != nil {
 		// Log the failure for audit and debugging purposes
 		// 監査とデバッグ目的で失敗をログに記録
@@ -726,20 +2028,107 @@ func (s *Server) toolExecCommand(ctx context.Context, args map[string]any) (any,
 		return nil, err
 	}
 
-	// Apply output masking to hide sensitive data in command output
-	// コマンド出力内の機密データを隠すために出力マスキングを適用
-	maskedOutput := s.docker.GetPolicy().MaskExec(result.Output)
-
-	// Apply host path masking to hide host OS username and directory structure
+	// Apply output masking to hide sensitive data in command output, then host path
+	// masking to hide host OS username and directory structure
+	// コマンド出力内の機密データを隠すために出力マスキングを適用し、続いて
 	// ホストパスマスキングを適用してホストOSのユーザー名やディレクトリ構造を隠す
-	maskedOutput = s.docker.GetPolicy().MaskHostPaths(maskedOutput)
+	policy := s.docker.GetPolicy()
+	mask := func(output string) string {
+		return policy.MaskHostPaths(policy.MaskExec(output))
+	}
+	maskedOutput := mask(result.Output)
+
+	auditDetails["exit_code"] = result.ExitCode
+	auditResult := audit.ResultSuccess
+	if result.TimedOut {
+		auditResult = audit.ResultTimeout
+		slog.Warn("Command timed out", "container", container, "command", command, "duration_ms", result.DurationMs)
+	}
+	audit.LogToolCall(ctx, "exec_command", container, auditResult, result.DurationMs, auditDetails)
+
+	// Format the result with command, exit code, duration and output
+	// コマンド、終了コード、実行時間、出力を含めて結果をフォーマット
+	content := fmt.Sprintf("Command: %s\n", command)
+	if opts.Workdir != "" {
+		content += fmt.Sprintf("Workdir: %s\n", opts.Workdir)
+	}
+	if opts.User != "" {
+		content += fmt.Sprintf("User: %s\n", opts.User)
+	}
+	content += fmt.Sprintf("Exit Code: %d\nDuration: %dms\n", result.ExitCode, result.DurationMs)
+	if result.TimedOut {
+		content += "Timed out: the command and its processes were killed (security.exec_timeout)\n"
+	}
+	if result.Truncated {
+		content += "Output truncated: security.exec_output limit reached\n"
+	}
+	content += "\nOutput:\n" + maskedOutput
+
+	structured := map[string]any{
+		"container":   container,
+		"command":     command,
+		"exit_code":   result.ExitCode,
+		"output":      maskedOutput,
+		"stdout":      mask(result.Stdout),
+		"stderr":      mask(result.Stderr),
+		"duration_ms": result.DurationMs,
+		"truncated":   result.Truncated,
+		"timed_out":   result.TimedOut,
+	}
+	addExecOptions(structured, opts)
+	return withStructuredContent(textResponse(content), structured), nil
+}
 
-	// Format the result with command, exit code, and output
-	// コマンド、終了コード、出力を含めて結果をフォーマット
-	content := fmt.Sprintf("Command: %s\nExit Code: %d\n\nOutput:\n%s",
-		command, result.ExitCode, maskedOutput)
+// execOptionsArg extracts the optional workdir, env and user parameters of exec_command
+// and start_exec_job. The policy checks them against exec_options when the command runs.
+//
+// execOptionsArgはexec_commandとstart_exec_jobのオプションのworkdir、env、userパラメータを
+// 抽出します。ポリシーはコマンドの実行時にそれらをexec_optionsに対してチェックします。
+func execOptionsArg(args map[string]any) (security.ExecOptions, error) {
+	var opts security.ExecOptions
+	if v, ok := args["workdir"]; ok {
+		workdir, ok := v.(string)
+		if !ok {
+			return opts, fmt.Errorf("invalid workdir parameter")
+		}
+		opts.Workdir = workdir
+	}
+	if v, ok := args["user"]; ok {
+		user, ok := v.(string)
+		if !ok {
+			return opts, fmt.Errorf("invalid user parameter")
+		}
+		opts.User = user
+	}
+	if v, ok := args["env"]; ok {
+		env, ok := v.(map[string]any)
+		if !ok {
+			return opts, fmt.Errorf("invalid env parameter: must be an object of strings")
+		}
+		opts.Env = make(map[string]string, len(env))
+		for name, value := range env {
+			str, ok := value.(string)
+			if !ok {
+				return opts, fmt.Errorf("invalid env parameter: value of %s must be a string", name)
+			}
+			opts.Env[name] = str
+		}
+	}
+	return opts, nil
+}
 
-	return textResponse(content), nil
+// addExecOptions adds the options that were requested to a tool result or audit details.
+// addExecOptionsは要求されたオプションをツールの結果または監査の詳細に追加します。
+func addExecOptions(m map[string]any, opts security.ExecOptions) {
+	if opts.Workdir != "" {
+		m["workdir"] = opts.Workdir
+	}
+	if len(opts.Env) > 0 {
+		m["env"] = opts.Env
+	}
+	if opts.User != "" {
+		m["user"] = opts.User
+	}
 }
 
 // toolInspectContainer implements the inspect_container tool.
@@ -777,9 +2166,17 @@ func (s *Server) toolInspectContainer(ctx context.Context, args map[string]any)
 	// ホストパスマスキングを適用してホストOSのユーザー名やディレクトリ構造を隠す
 	maskedJSON = s.docker.GetPolicy().MaskHostPaths(maskedJSON)
 
-	// Return masked JSON as text response
-	// マスクされたJSONをテキストレスポンスとして返す
-	return textResponse(maskedJSON), nil
+	infoData, err := decodeStructured(maskedJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return masked JSON as text and as structured content
+	// マスクされたJSONをテキストと構造化コンテンツとして返す
+	return withStructuredContent(textResponse(maskedJSON), map[string]any{
+		"container": container,
+		"info":      infoData,
+	}), nil
 }
 
 // formatStats formats container stats as JSON text.
@@ -824,7 +2221,13 @@ func (s *Server) toolGetAllowedCommands(ctx context.Context, args map[string]any
 		resultMap := map[string]any{
 			"container":        container,
 			"allowed_commands": commands,
-			"note":             "Commands with '*' wildcard match any suffix (e.g., 'echo *' matches 'echo hello')",
+			"note":             "Commands are matched argument by argument after quote parsing; '*' matches within an argument, and a trailing '*' also accepts further arguments (e.g., 'npm run *' matches 'npm run test --watch')",
+		}
+
+		// Add the working directories, environment variables and users commands may request
+		// コマンドが要求できる作業ディレクトリ、環境変数、ユーザーを追加
+		if execOptions := s.docker.GetPolicy().ExecOptionsSummary(container); execOptions != nil {
+			resultMap["exec_options"] = execOptions
 		}
 
 		// Add dangerous commands if enabled
@@ -833,7 +2236,7 @@ func (s *Server) toolGetAllowedCommands(ctx context.Context, args map[string]any
 			dangerousCommands := s.docker.GetDangerousCommandsForContainer(container)
 			resultMap["dangerous_commands"] = dangerousCommands
 			resultMap["dangerous_mode_enabled"] = true
-			resultMap["note"] = "Commands with '*' wildcard match any suffix. Dangerous commands require dangerously=true parameter."
+			resultMap["note"] = "Commands are matched argument by argument; a trailing '*' also accepts further arguments. Dangerous commands require dangerously=true parameter."
 		}
 
 		result = resultMap
@@ -843,7 +2246,11 @@ func (s *Server) toolGetAllowedCommands(ctx context.Context, args map[string]any
 		allCommands := s.docker.GetAllContainersWithCommands()
 		resultMap := map[string]any{
 			"containers": allCommands,
-			"note":       "The '*' key contains default commands available to all containers. Commands with '*' wildcard match any suffix.",
+			"note":       "The '*' key contains default commands available to all containers. Commands are matched argument by argument; a trailing '*' also accepts further arguments.",
+		}
+
+		if execOptions := s.docker.GetPolicy().AllExecOptions(); len(execOptions) > 0 {
+			resultMap["exec_options"] = execOptions
 		}
 
 		// Add dangerous commands if enabled
@@ -852,7 +2259,7 @@ func (s *Server) toolGetAllowedCommands(ctx context.Context, args map[string]any
 			dangerousCommands := s.docker.GetAllDangerousCommands()
 			resultMap["dangerous_containers"] = dangerousCommands
 			resultMap["dangerous_mode_enabled"] = true
-			resultMap["note"] = "The '*' key contains default commands available to all containers. Commands with '*' wildcard match any suffix. Dangerous commands require dangerously=true parameter."
+			resultMap["note"] = "The '*' key contains default commands available to all containers. Commands are matched argument by argument; a trailing '*' also accepts further arguments. Dangerous commands require dangerously=true parameter."
 		}
 
 		result = resultMap
@@ -871,29 +2278,90 @@ func (s *Server) toolGetAllowedCommands(ctx context.Context, args map[string]any
 func (s *Server) toolGetSecurityPolicy(ctx context.Context, args map[string]any) (any, error) {
 	slog.Debug("Getting security policy")
 
+	// Listing containers refreshes the labels the label-based access summary is built from
+	// コンテナの一覧取得でラベルに基づくアクセス概要の元となるラベルを更新
+	if _, err := s.docker.ListContainers(ctx); err != nil {
+		slog.Debug("Failed to refresh containers for security policy", "error", err)
+	}
+
 	policy := s.docker.GetSecurityPolicy()
 
 	// Convert to JSON for masking
 	// マスキングのためにJSONに変換
 	jsonBytes, err := json.MarshalIndent(policy, "", "  ")
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal security policy: %w", err)
+		return nil, fmt.Errorf("failed to marshal security policy: %w", err)
+	}
+
+	// Apply host path masking to hide host OS username and directory structure
+	// ホストパスマスキングを適用してホストOSのユーザー名やディレクトリ構造を隠す
+	maskedJSON := s.docker.GetPolicy().MaskHostPaths(string(jsonBytes))
+
+	return textResponse(fmt.Sprintf("Current Security Policy:\n```json\n%s\n```", maskedJSON)), nil
+}
+
+// toolExplainPolicy implements the explain_policy tool.
+// It runs the exec_command checks for a command and the read_file/list_files checks
+// for a path without executing anything, and returns one explanation per check.
+//
+// toolExplainPolicyはexplain_policyツールを実装します。
+// 何も実行せずに、コマンドに対してexec_commandのチェックを、パスに対してread_file/list_filesの
+// チェックを実行し、チェックごとに1つの説明を返します。
+func (s *Server) toolExplainPolicy(ctx context.Context, args map[string]any) (any, error) {
+	container, ok := args["container"].(string)
+	if !ok || container == "" {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+	command, _ := args["command"].(string)
+	path, _ := args["path"].(string)
+	if command == "" && path == "" {
+		return nil, fmt.Errorf("specify a command or a path to explain")
+	}
+	dangerously, _ := args["dangerously"].(bool)
+	opts, err := execOptionsArg(args)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("Explaining policy", "container", container, "command", command, "path", path, "dangerously", dangerously)
+
+	// Listing containers refreshes the Compose identities and labels the checks resolve against
+	// コンテナの一覧取得で、チェックが参照するComposeのIDとラベルを更新
+	if _, err := s.docker.ListContainers(ctx); err != nil {
+		slog.Debug("Failed to refresh containers for policy explanation", "error", err)
+	}
+
+	// The docker client also resolves the paths in the container, as the real tools do
+	// Dockerクライアントは実際のツールと同様に、コンテナ内のパスも解決する
+	var explanations []security.Explanation
+	if command != "" {
+		explanations = append(explanations, s.docker.ExplainExec(ctx, container, command, dangerously, opts))
+	}
+	if path != "" {
+		explanations = append(explanations, s.docker.ExplainPath(ctx, container, path))
+	}
+
+	jsonBytes, err := json.MarshalIndent(explanations, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal policy explanation: %w", err)
 	}
 
-	// Apply host path masking to hide host OS username and directory structure
-	// ホストパスマスキングを適用してホストOSのユーザー名やディレクトリ構造を隠す
+	// Blocked path sources may be host paths
+	// ブロックパスの定義元はホストのパスである場合がある
 	maskedJSON := s.docker.GetPolicy().MaskHostPaths(string(jsonBytes))
 
-	return textResponse(fmt.Sprintf("Current Security Policy:\n```json\n%s\n```", maskedJSON)), nil
+	return textResponse(fmt.Sprintf("Policy explanation:\n```json\n%s\n```", maskedJSON)), nil
 }
 
 // toolSearchLogs implements the search_logs tool.
 // It searches container logs for a pattern and returns matching lines
-// with surrounding context.
+// with surrounding context. Lines can be narrowed by time window, stream,
+// exclude pattern and, for JSON structured logs, by field values.
 //
 // toolSearchLogsはsearch_logsツールを実装します。
 // コンテナログ内でパターンを検索し、周囲のコンテキストと共に
-// マッチした行を返します。
+// マッチした行を返します。時間範囲、ストリーム、除外パターン、
+// およびJSON構造化ログではフィールド値で行を絞り込めます。
 func (s *Server) toolSearchLogs(ctx context.Context, args map[string]any) (any, error) {
 	// Extract required container parameter
 	// 必須のcontainerパラメータを抽出
@@ -909,6 +2377,11 @@ func (s *Server) toolSearchLogs(ctx context.Context, args map[string]any) (any,
 		return nil, fmt.Errorf("missing or invalid pattern parameter")
 	}
 
+	filter, err := newLogFilter(pattern, args)
+	if err != nil {
+		return nil, err
+	}
+
 	// Extract optional tail parameter with default
 	// デフォルト値を持つオプションのtailパラメータを抽出
 	tail := "1000"
@@ -923,37 +2396,61 @@ func (s *Server) toolSearchLogs(ctx context.Context, args map[string]any) (any,
 		contextLines = int(c)
 	}
 
-	slog.Debug("Searching logs", "container", container, "pattern", pattern)
+	// Extract optional time window, applied by Docker using its log timestamps
+	// オプションの時間範囲を抽出。Dockerがログのタイムスタンプを使って適用
+	since, _ := args["since"].(string)
+	until, _ := args["until"].(string)
+
+	slog.Debug("Searching logs", "container", container, "pattern", pattern, "since", since, "until", until)
 
 	// Get logs from the container
 	// コンテナからログを取得
-	logs, err := s.docker.GetLogs(ctx, container, tail, "", false)
+	logLines, err := s.docker.GetLogLines(ctx, container, tail, since, until)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply output masking before searching to hide sensitive data
-	// 検索前に機密データを隠すために出力マスキングを適用
-	maskedLogs := s.docker.GetPolicy().MaskLogs(logs)
-
-	// Apply host path masking to hide host OS username and directory structure
-	// ホストパスマスキングを適用してホストOSのユーザー名やディレクトリ構造を隠す
-	maskedLogs = s.docker.GetPolicy().MaskHostPaths(maskedLogs)
+	// Apply output masking before searching to hide sensitive data, and keep
+	// only the selected stream. Line numbers count the lines that remain.
+	// 検索前に機密データを隠すために出力マスキングを適用し、選択されたストリームのみを残す。
+	// 行番号は残った行を数える。
+	policy := s.docker.GetPolicy()
+	var (
+		lines      []string
+		timestamps []string
+		streams    []string
+	)
+	for _, logLine := range logLines {
+		if !filter.selectsStream(logLine.Stream) {
+			continue
+		}
+		timestamp := ""
+		ts, message, hasTimestamp := docker.SplitTimestamp(logLine.Text)
+		if hasTimestamp {
+			timestamp = ts.Format(time.RFC3339Nano)
+		}
+		lines = append(lines, policy.MaskHostPaths(policy.MaskLogs(message)))
+		timestamps = append(timestamps, timestamp)
+		streams = append(streams, logLine.Stream)
+	}
 
-	// Search for pattern in logs (case-insensitive)
-	// ログ内でパターンを検索（大文字小文字を区別しない）
-	lines := strings.Split(maskedLogs, "\n")
+	// Search for matching lines
+	// マッチする行を検索
 	var matches []SearchMatch
-	patternLower := strings.ToLower(pattern)
-
+	nonJSONLines := 0
 	for i, line := range lines {
-		if strings.Contains(strings.ToLower(line), patternLower) {
-			match := SearchMatch{
+		matched, isJSON := filter.matches(line)
+		if filter.structured && !isJSON {
+			nonJSONLines++
+		}
+		if matched {
+			matches = append(matches, SearchMatch{
 				LineNumber: i + 1,
+				Timestamp:  timestamps[i],
+				Stream:     streams[i],
 				Line:       line,
 				Context:    getContextLines(lines, i, contextLines),
-			}
-			matches = append(matches, match)
+			})
 		}
 	}
 
@@ -966,8 +2463,11 @@ func (s *Server) toolSearchLogs(ctx context.Context, args map[string]any) (any,
 		"matches_count": len(matches),
 		"matches":       matches,
 	}
+	if filter.structured {
+		result["non_json_lines"] = nonJSONLines
+	}
 
-	return jsonTextResponse(result)
+	return structuredResponse(result)
 }
 
 // SearchMatch represents a single match found in log search.
@@ -980,8 +2480,16 @@ type SearchMatch struct {
 	// LineNumberはマッチの1から始まる行番号
 	LineNumber int `json:"line_number"`
 
-	// Line is the full text of the matched line
-	// Lineはマッチした行の全文
+	// Timestamp is the Docker timestamp of the line (RFC 3339), if available
+	// Timestampは行のDockerタイムスタンプ（RFC 3339）です（利用可能な場合）
+	Timestamp string `json:"timestamp,omitempty"`
+
+	// Stream is "stdout" or "stderr"
+	// Streamは"stdout"または"stderr"
+	Stream string `json:"stream,omitempty"`
+
+	// Line is the full text of the matched line, without the timestamp
+	// Lineはマッチした行の全文（タイムスタンプを除く）
 	Line string `json:"line"`
 
 	// Context contains the surrounding lines for context
@@ -1066,16 +2574,39 @@ func (s *Server) toolListFiles(ctx context.Context, args map[string]any) (any, e
 	// If blocked by security policy, return detailed block information
 	// セキュリティポリシーによりブロックされた場合、詳細なブロック情報を返す
 	if result.Blocked {
-		return s.formatBlockedResponse(container, path, result.Block)
+		return s.formatBlockedResponse(container, path, result)
 	}
 
 	// If the operation failed for other reasons, return the error
 	// 他の理由で操作が失敗した場合、エラーを返す
 	if !result.Success {
-		return containerFileResponse("Error listing files in", container, path, result.Error), nil
+		return fileErrorResponse("Error listing files in", container, path, result.Error), nil
+	}
+
+	listing := map[string]any{
+		"entries": result.Entries,
+	}
+	if result.Entries == nil {
+		listing["entries"] = []docker.FileEntry{}
+	}
+	if result.Truncated {
+		listing["truncated"] = true
+		listing["note"] = "The listing stopped early: the directory has too many entries or its tree is too large to scan completely. Use find_files with a name pattern, or list a subdirectory, to see the rest."
+	}
+	jsonData, err := json.MarshalIndent(listing, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file listing: %w", err)
 	}
 
-	return containerFileResponse("Files in", container, path, result.Data), nil
+	// Symlink targets may point into host-mounted directories
+	// シンボリックリンクのリンク先はホストからマウントされたディレクトリを指す場合がある
+	maskedData := s.docker.GetPolicy().MaskHostPaths(string(jsonData))
+
+	structured, err := fileResult(container, path, maskedData)
+	if err != nil {
+		return nil, err
+	}
+	return withStructuredContent(containerFileResponse("Files in", container, path, "```json\n"+maskedData+"\n```"), structured), nil
 }
 
 // toolReadFile implements the read_file tool.
@@ -1100,16 +2631,31 @@ func (s *Server) toolReadFile(ctx context.Context, args map[string]any) (any, er
 		return nil, fmt.Errorf("missing or invalid path parameter")
 	}
 
-	// Extract optional max_lines parameter
-	// オプションのmax_linesパラメータを抽出
-	maxLines := 0
-	if m, ok := args["max_lines"].(float64); ok {
-		maxLines = int(m)
+	// Extract the optional line range or byte range
+	// オプションの行範囲またはバイト範囲を抽出
+	var opts docker.ReadOptions
+	if v, ok := args["start_line"].(float64); ok {
+		opts.StartLine = int(v)
+	}
+	if v, ok := args["max_lines"].(float64); ok {
+		opts.MaxLines = int(v)
+	}
+	if v, ok := args["offset"].(float64); ok {
+		opts.Offset = int64(v)
+	}
+	if v, ok := args["length"].(float64); ok {
+		opts.Length = int64(v)
+	}
+	if v, ok := args["tail"].(float64); ok {
+		opts.Tail = int(v)
+	}
+	if v, ok := args["cursor"].(string); ok {
+		opts.Cursor = v
 	}
 
 	slog.Debug("Reading file", "container", container, "path", path)
 
-	result, err := s.docker.ReadFile(ctx, container, path, maxLines)
+	result, err := s.docker.ReadFile(ctx, container, path, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1117,20 +2663,491 @@ func (s *Server) toolReadFile(ctx context.Context, args map[string]any) (any, er
 	// If blocked by security policy, return detailed block information
 	// セキュリティポリシーによりブロックされた場合、詳細なブロック情報を返す
 	if result.Blocked {
-		return s.formatBlockedResponse(container, path, result.Block)
+		return s.formatBlockedResponse(container, path, result)
 	}
 
 	// If the operation failed for other reasons, return the error
 	// 他の理由で操作が失敗した場合、エラーを返す
 	if !result.Success {
-		return containerFileResponse("Error reading file", container, path, result.Error), nil
+		return fileErrorResponse("Error reading file", container, path, result.Error), nil
+	}
+
+	// Binary content is described instead of returned
+	// バイナリ内容は返さずに説明する
+	if result.Binary {
+		info := map[string]any{
+			"binary": true,
+			"sha256": result.SHA256,
+			"note":   "The file holds binary content, which read_file does not return.",
+		}
+		if result.SHA256 == "" {
+			delete(info, "sha256")
+			info["note"] = "The file holds binary content and is larger than the read limit, so only its size is reported."
+		}
+		if result.File != nil {
+			info["size"] = result.File.Size
+			info["mode"] = result.File.Mode
+			info["mtime"] = result.File.ModTime
+		}
+		resp, err := jsonCodeBlockResponse(fmt.Sprintf("Binary file %s:%s", container, path), info)
+		if err != nil {
+			return nil, err
+		}
+		info["container"] = container
+		info["path"] = path
+		return withStructuredContent(resp, info), nil
 	}
 
 	// Apply host path masking to hide host OS username and directory structure in file contents
 	// ファイル内容内のホストOSのユーザー名やディレクトリ構造を隠すためにホストパスマスキングを適用
 	maskedData := s.docker.GetPolicy().MaskHostPaths(result.Data)
+	structured := map[string]any{
+		"container": container,
+		"path":      path,
+		"content":   maskedData,
+		"offset":    result.Offset,
+	}
+	if result.StartLine > 0 {
+		structured["start_line"] = result.StartLine
+	}
+	if result.NextCursor != "" {
+		structured["next_cursor"] = result.NextCursor
+	}
+	if result.Truncated {
+		structured["truncated"] = true
+	}
+	if result.NextCursor != "" {
+		reason := "end of the requested range"
+		if result.Truncated {
+			reason = "truncated at the size limit"
+		}
+		maskedData += fmt.Sprintf("\n[%s at byte %d: call read_file with cursor=%q to continue]",
+			reason, result.Offset+int64(len(result.Data)), result.NextCursor)
+	} else if result.Truncated {
+		maskedData += "\n[truncated at the size limit: use tail or a smaller range]"
+	}
+
+	return withStructuredContent(containerFileResponse("Contents of", container, path, maskedData), structured), nil
+}
+
+// toolFindFiles implements the find_files tool.
+// It searches a container directory tree for entries by name and type,
+// skipping blocked paths.
+//
+// toolFindFilesはfind_filesツールを実装します。
+// ブロックされたパスをスキップしながら、コンテナのディレクトリツリーから
+// 名前と種別でエントリを検索します。
+func (s *Server) toolFindFiles(ctx context.Context, args map[string]any) (any, error) {
+	container, ok := args["container"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+
+	path := "/"
+	if p, ok := args["path"].(string); ok && p != "" {
+		path = p
+	}
+
+	var opts docker.FindOptions
+	if v, ok := args["name"].(string); ok {
+		opts.Name = v
+	}
+	if v, ok := args["type"].(string); ok {
+		opts.Type = v
+	}
+	if v, ok := args["max_depth"].(float64); ok {
+		opts.MaxDepth = int(v)
+	}
+	if v, ok := args["max_results"].(float64); ok {
+		opts.MaxResults = int(v)
+	}
+
+	slog.Debug("Finding files", "container", container, "path", path, "name", opts.Name)
+
+	result, err := s.docker.FindFiles(ctx, container, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Blocked {
+		return s.formatBlockedResponse(container, path, result)
+	}
+	if !result.Success {
+		return fileErrorResponse("Error finding files in", container, path, result.Error), nil
+	}
+
+	found := map[string]any{
+		"entries": result.Entries,
+	}
+	if result.Entries == nil {
+		found["entries"] = []docker.FileEntry{}
+	}
+	addSearchNotes(found, result, "entries")
+	jsonData, err := json.MarshalIndent(found, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal found files: %w", err)
+	}
+
+	// Symlink targets may point into host-mounted directories
+	// シンボリックリンクのリンク先はホストからマウントされたディレクトリを指す場合がある
+	maskedData := s.docker.GetPolicy().MaskHostPaths(string(jsonData))
+
+	structured, err := fileResult(container, path, maskedData)
+	if err != nil {
+		return nil, err
+	}
+	return withStructuredContent(containerFileResponse("Files found in", container, path, "```json\n"+maskedData+"\n```"), structured), nil
+}
+
+// toolGrepFiles implements the grep_files tool.
+// It searches the files under a container directory for lines matching a
+// pattern, skipping blocked paths. Matched lines are masked by the docker client.
+//
+// toolGrepFilesはgrep_filesツールを実装します。
+// ブロックされたパスをスキップしながら、コンテナのディレクトリ配下のファイルから
+// パターンにマッチする行を検索します。マッチした行はdockerクライアントでマスクされます。
+func (s *Server) toolGrepFiles(ctx context.Context, args map[string]any) (any, error) {
+	container, ok := args["container"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+
+	var opts docker.GrepOptions
+	opts.Pattern, ok = args["pattern"].(string)
+	if !ok || opts.Pattern == "" {
+		return nil, fmt.Errorf("missing or invalid pattern parameter")
+	}
+
+	path := "/"
+	if p, ok := args["path"].(string); ok && p != "" {
+		path = p
+	}
+	if v, ok := args["include"].(string); ok {
+		opts.Include = v
+	}
+	if v, ok := args["ignore_case"].(bool); ok {
+		opts.IgnoreCase = v
+	}
+	if v, ok := args["max_depth"].(float64); ok {
+		opts.MaxDepth = int(v)
+	}
+	if v, ok := args["max_results"].(float64); ok {
+		opts.MaxResults = int(v)
+	}
+
+	slog.Debug("Searching files", "container", container, "path", path, "pattern", opts.Pattern)
+
+	result, err := s.docker.GrepFiles(ctx, container, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Blocked {
+		return s.formatBlockedResponse(container, path, result)
+	}
+	if !result.Success {
+		return fileErrorResponse("Error searching files in", container, path, result.Error), nil
+	}
+
+	found := map[string]any{
+		"matches": result.Matches,
+	}
+	if result.Matches == nil {
+		found["matches"] = []docker.GrepMatch{}
+	}
+	addSearchNotes(found, result, "matches")
+	jsonData, err := json.MarshalIndent(found, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal matches: %w", err)
+	}
+
+	// File contents may mention host paths, like read_file
+	// read_fileと同様に、ファイル内容にホストのパスが含まれる場合がある
+	maskedData := s.docker.GetPolicy().MaskHostPaths(string(jsonData))
+
+	structured, err := fileResult(container, path, maskedData)
+	if err != nil {
+		return nil, err
+	}
+	return withStructuredContent(containerFileResponse("Matches in", container, path, "```json\n"+maskedData+"\n```"), structured), nil
+}
+
+// addSearchNotes adds the truncation and skipped-path notes of a find_files or
+// grep_files result to its response.
+//
+// addSearchNotesはfind_filesまたはgrep_filesの結果の打ち切りとスキップしたパスの
+// 注記をレスポンスに追加します。
+func addSearchNotes(response map[string]any, result *docker.FileAccessResult, what string) {
+	if result.Truncated {
+		response["truncated"] = true
+		response["note"] = fmt.Sprintf("Not all %s are shown; narrow the path or pattern, or raise max_results.", what)
+	}
+	if result.SkippedBlocked > 0 {
+		response["skipped_blocked"] = result.SkippedBlocked
+		response["skipped_note"] = "Blocked paths were skipped and not searched; use get_blocked_paths to see them."
+	}
+}
+
+// toolWriteFile implements the write_file tool.
+// It replaces or creates a file in a container within writable_paths.
+//
+// toolWriteFileはwrite_fileツールを実装します。
+// writable_pathsの範囲でコンテナ内のファイルを置き換えるか作成します。
+func (s *Server) toolWriteFile(ctx context.Context, args map[string]any) (any, error) {
+	container, ok := args["container"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("missing or invalid path parameter")
+	}
+	content, ok := args["content"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid content parameter")
+	}
+
+	slog.Info("Writing file", "container", container, "path", path, "bytes", len(content))
+
+	result, err := s.docker.WriteFile(ctx, container, path, content)
+	return s.fileWriteResponse(ctx, "write_file", container, path, result, err)
+}
+
+// toolApplyPatch implements the apply_patch tool.
+// It applies a unified diff to a file in a container within writable_paths.
+//
+// toolApplyPatchはapply_patchツールを実装します。
+// writable_pathsの範囲でコンテナ内のファイルにunified diffを適用します。
+func (s *Server) toolApplyPatch(ctx context.Context, args map[string]any) (any, error) {
+	container, ok := args["container"].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("missing or invalid path parameter")
+	}
+	patch, ok := args["patch"].(string)
+	if !ok || patch == "" {
+		return nil, fmt.Errorf("missing or invalid patch parameter")
+	}
+
+	slog.Info("Patching file", "container", container, "path", path)
+
+	result, err := s.docker.ApplyPatch(ctx, container, path, patch)
+	return s.fileWriteResponse(ctx, "apply_patch", container, path, result, err)
+}
+
+// fileWriteResponse audit-logs the outcome of write_file or apply_patch and formats its
+// response. The diff is masked before it is logged or returned, since the old or new
+// content may hold secrets.
+//
+// fileWriteResponseはwrite_fileまたはapply_patchの結果を監査ログに記録し、レスポンスを
+// 整形します。古い内容や新しい内容にシークレットが含まれ得るため、diffは記録や返却の前に
+// マスクします。
+func (s *Server) fileWriteResponse(ctx context.Context, tool, container, path string, result *docker.FileAccessResult, err error) (any, error) {
+	policy := s.docker.GetPolicy()
+	details := map[string]any{"path": path}
+
+	if err != nil {
+		audit.LogFileWrite(ctx, tool, container, audit.ResultDenied, details, err.Error())
+		return nil, err
+	}
+	if result.Blocked {
+		audit.LogFileWrite(ctx, tool, container, audit.ResultDenied, details, "blocked path")
+		return s.formatBlockedResponse(container, path, result)
+	}
+	if !result.Success {
+		if result.BackupPath != "" {
+			details["backup_path"] = result.BackupPath
+		}
+		audit.LogFileWrite(ctx, tool, container, audit.ResultError, details, result.Error)
+		return fileErrorResponse("Error writing file", container, path, result.Error), nil
+	}
+
+	if result.Diff == "" {
+		return withStructuredContent(
+			containerFileResponse("No changes to", container, path, "The file already has this content; nothing was written."),
+			map[string]any{"container": container, "path": path, "changed": false},
+		), nil
+	}
+
+	diff := policy.MaskOutput(result.Diff)
+	details["diff"] = diff
+	details["created"] = result.Created
+	if result.ResolvedPath != "" {
+		details["resolved_path"] = result.ResolvedPath
+	}
+	if result.BackupPath != "" {
+		details["backup_path"] = result.BackupPath
+	}
+	audit.LogFileWrite(ctx, tool, container, audit.ResultSuccess, details, "")
+
+	operation := "Updated"
+	summary := fmt.Sprintf("Previous content saved to %s.", result.BackupPath)
+	if result.Created {
+		operation = "Created"
+		summary = "The file did not exist before."
+	}
+	text := fmt.Sprintf("%s\n\n```diff\n%s```", summary, diff)
+	structured := map[string]any{
+		"container": container,
+		"path":      path,
+		"changed":   true,
+		"created":   result.Created,
+		"diff":      policy.MaskHostPaths(diff),
+	}
+	if result.BackupPath != "" {
+		structured["backup_path"] = result.BackupPath
+	}
+	return withStructuredContent(containerFileResponse(operation, container, path, policy.MaskHostPaths(text)), structured), nil
+}
+
+// copyArgs holds the parameters shared by copy_from_container and copy_to_container.
+// copyArgsはcopy_from_containerとcopy_to_containerで共通のパラメータを保持します。
+type copyArgs struct {
+	container string // Container name / コンテナ名
+	path      string // Path in the container / コンテナ内のパス
+	hostPath  string // Host path as requested / 要求されたままのホストパス
+	overwrite bool   // Replace an existing destination / 既存のコピー先を置き換える
+}
+
+// parseCopyArgs reads the parameters of the copy tools.
+// parseCopyArgsはコピーツールのパラメータを読み取ります。
+func parseCopyArgs(args map[string]any) (copyArgs, error) {
+	var a copyArgs
+	var ok bool
+	if a.container, ok = args["container"].(string); !ok {
+		return a, fmt.Errorf("missing or invalid container parameter")
+	}
+	if a.path, ok = args["path"].(string); !ok || a.path == "" {
+		return a, fmt.Errorf("missing or invalid path parameter")
+	}
+	if a.hostPath, ok = args["host_path"].(string); !ok || a.hostPath == "" {
+		return a, fmt.Errorf("missing or invalid host_path parameter")
+	}
+	a.overwrite, _ = args["overwrite"].(bool)
+	return a, nil
+}
+
+// toolCopyFromContainer implements the copy_from_container tool.
+// It copies a container file or directory into the host workspace.
+//
+// toolCopyFromContainerはcopy_from_containerツールを実装します。
+// コンテナのファイルまたはディレクトリをホストのワークスペースにコピーします。
+func (s *Server) toolCopyFromContainer(ctx context.Context, args map[string]any) (any, error) {
+	a, err := parseCopyArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Copying from container", "container", a.container, "path", a.path, "host_path", a.hostPath)
+
+	hostPath, err := security.ResolveWorkspacePath(s.workspace(), a.hostPath)
+	if err != nil {
+		return s.fileCopyResponse(ctx, "copy_from_container", a, "", nil, err)
+	}
+	result, err := s.docker.CopyFromContainer(ctx, a.container, a.path, hostPath, a.overwrite)
+	return s.fileCopyResponse(ctx, "copy_from_container", a, hostPath, result, err)
+}
+
+// toolCopyToContainer implements the copy_to_container tool.
+// It copies a host workspace file or directory into a container within writable_paths.
+//
+// toolCopyToContainerはcopy_to_containerツールを実装します。
+// ホストのワークスペースのファイルまたはディレクトリをwritable_pathsの範囲でコンテナにコピーします。
+func (s *Server) toolCopyToContainer(ctx context.Context, args map[string]any) (any, error) {
+	a, err := parseCopyArgs(args)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Copying to container", "container", a.container, "host_path", a.hostPath, "path", a.path)
+
+	hostPath, err := security.ResolveWorkspacePath(s.workspace(), a.hostPath)
+	if err != nil {
+		return s.fileCopyResponse(ctx, "copy_to_container", a, "", nil, err)
+	}
+	result, err := s.docker.CopyToContainer(ctx, a.container, hostPath, a.path, a.overwrite)
+	return s.fileCopyResponse(ctx, "copy_to_container", a, hostPath, result, err)
+}
+
+// fileCopyResponse audit-logs the outcome of a copy with the size and SHA-256 of every
+// file, and formats its response. Host paths are reported as requested, relative to the
+// workspace, rather than as resolved absolute paths.
+//
+// fileCopyResponseはコピーの結果をすべてのファイルのサイズとSHA-256とともに監査ログに
+// 記録し、レスポンスを整形します。ホストのパスは解決した絶対パスではなく、要求された
+// ままのワークスペースからの相対パスで報告します。
+func (s *Server) fileCopyResponse(ctx context.Context, tool string, a copyArgs, hostPath string, result *docker.FileAccessResult, err error) (any, error) {
+	details := map[string]any{"path": a.path, "host_path": a.hostPath}
+
+	if err != nil {
+		audit.LogFileCopy(ctx, tool, a.container, audit.ResultDenied, details, err.Error())
+		return nil, err
+	}
+	if result.Blocked {
+		audit.LogFileCopy(ctx, tool, a.container, audit.ResultDenied, details, "blocked path")
+		if hostPath != "" && result.MatchedPath == hostPath {
+			hostBlock := *result
+			hostBlock.MatchedPath = ""
+			return s.formatBlockedResponse(a.container, a.hostPath, &hostBlock)
+		}
+		return s.formatBlockedResponse(a.container, a.path, result)
+	}
+	if !result.Success {
+		audit.LogFileCopy(ctx, tool, a.container, audit.ResultError, details, result.Error)
+		return fileErrorResponse("Error copying", a.container, a.path, s.docker.GetPolicy().MaskHostPaths(result.Error)), nil
+	}
+
+	var total int64
+	for _, f := range result.Copied {
+		total += f.Size
+	}
+	details["files"] = result.Copied
+	details["bytes"] = total
+	if result.ResolvedPath != "" {
+		details["resolved_path"] = result.ResolvedPath
+	}
+	if result.SkippedBlocked > 0 {
+		details["skipped_blocked"] = result.SkippedBlocked
+	}
+	audit.LogFileCopy(ctx, tool, a.container, audit.ResultSuccess, details, "")
 
-	return containerFileResponse("Contents of", container, path, maskedData), nil
+	direction := fmt.Sprintf("to workspace path %s", a.hostPath)
+	if tool == "copy_to_container" {
+		direction = fmt.Sprintf("from workspace path %s", a.hostPath)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d file(s), %d bytes copied %s.\n", len(result.Copied), total, direction)
+	if result.SkippedBlocked > 0 {
+		fmt.Fprintf(&b, "%d blocked path(s) were skipped.\n", result.SkippedBlocked)
+	}
+	if result.SkippedSpecial > 0 {
+		fmt.Fprintf(&b, "%d symlink(s) or special file(s) were skipped.\n", result.SkippedSpecial)
+	}
+	if len(result.Copied) > 0 {
+		b.WriteString("\nSHA-256:\n")
+		for _, f := range result.Copied {
+			fmt.Fprintf(&b, "%s  %s (%d bytes)\n", f.SHA256, f.Path, f.Size)
+		}
+	}
+	files := result.Copied
+	if files == nil {
+		files = []docker.CopiedFile{}
+	}
+	structured := map[string]any{
+		"container": a.container,
+		"path":      a.path,
+		"host_path": a.hostPath,
+		"files":     files,
+		"bytes":     total,
+	}
+	if result.SkippedBlocked > 0 {
+		structured["skipped_blocked"] = result.SkippedBlocked
+	}
+	if result.SkippedSpecial > 0 {
+		structured["skipped_special"] = result.SkippedSpecial
+	}
+	return withStructuredContent(containerFileResponse("Copied", a.container, a.path, s.docker.GetPolicy().MaskHostPaths(b.String())), structured), nil
 }
 
 // toolGetBlockedPaths implements the get_blocked_paths tool.
@@ -1266,12 +3283,17 @@ func (s *Server) toolStartContainer(ctx context.Context, args map[string]any) (a
 }
 
 // formatBlockedResponse formats a response for when a path is blocked by security policy.
-// It provides detailed information about why the path was blocked and helpful hints.
+// It provides detailed information about why the path was blocked and helpful hints,
+// including the real path when the requested one led to a blocked file indirectly
+// (through a symlink, /proc/<pid>/root or another mount).
 //
 // formatBlockedResponseはセキュリティポリシーによりパスがブロックされた場合の
 // レスポンスをフォーマットします。パスがブロックされた理由と役立つヒントに関する
-// 詳細情報を提供します。
-func (s *Server) formatBlockedResponse(container string, path string, block *security.BlockedPath) (any, error) {
+// 詳細情報を提供し、要求されたパスが間接的に（シンボリックリンク、/proc/<pid>/root、
+// 別のマウントを通じて）ブロックされたファイルに繋がった場合は実パスも含めます。
+func (s *Server) formatBlockedResponse(container string, path string, result *docker.FileAccessResult) (any, error) {
+	block := result.Block
+
 	// Build hint message based on the block reason
 	// ブロック理由に基づいてヒントメッセージを構築
 	hint := "This path is blocked by security policy."
@@ -1312,6 +3334,45 @@ func (s *Server) formatBlockedResponse(container string, path string, block *sec
 		},
 		"hint": hint,
 	}
+	if result.ResolvedPath != "" && result.ResolvedPath != path {
+		response["resolved_path"] = result.ResolvedPath
+	}
+	if result.MatchedPath != "" && result.MatchedPath != path && result.MatchedPath != result.ResolvedPath {
+		response["matched_path"] = result.MatchedPath
+	}
+
+	resp, err := jsonCodeBlockResponse("⚠️ Access Blocked", response)
+	if err != nil {
+		return nil, err
+	}
+	return withStructuredContent(resp, response), nil
+}
+
+// fileErrorResponse formats a failed file operation as text and as structuredContent.
+// fileErrorResponseは失敗したファイル操作をテキストとstructuredContentとして整形します。
+func fileErrorResponse(operation, container, path, message string) map[string]any {
+	return withStructuredContent(containerFileResponse(operation, container, path, message), map[string]any{
+		"container": container,
+		"path":      path,
+		"error":     message,
+	})
+}
 
-	return jsonCodeBlockResponse("⚠️ Access Blocked", response)
+// fileResult decodes the masked JSON result of a file tool into its structuredContent
+// and adds the container and path.
+//
+// fileResultはファイルツールのマスク済みJSONの結果をstructuredContentにデコードし、
+// コンテナとパスを追加します。
+func fileResult(container, path, maskedJSON string) (map[string]any, error) {
+	decoded, err := decodeStructured(maskedJSON)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("file result is not a JSON object")
+	}
+	data["container"] = container
+	data["path"] = path
+	return data, nil
 }
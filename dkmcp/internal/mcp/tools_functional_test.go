@@ -11,15 +11,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/audit"
 	configPkg "github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/docker"
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/history"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/hosttools"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/security"
 )
@@ -110,6 +114,16 @@ func TestToolListContainers_Functional(t *testing.T) {
 	if !strings.Contains(text, "test-db") {
 		t.Errorf("expected result to contain 'test-db', got: %s", text)
 	}
+
+	// Verify the structured result lists the same containers
+	// 構造化された結果が同じコンテナを列挙することを検証
+	structured, ok := resultMap["structuredContent"].(map[string]any)
+	if !ok {
+		t.Fatal("expected structuredContent in result")
+	}
+	if list, ok := structured["containers"].([]any); !ok || len(list) != 2 {
+		t.Errorf("structuredContent = %v, want 2 containers", structured)
+	}
 }
 
 // TestToolListContainers_Error tests error handling in list_containers.
@@ -310,19 +324,244 @@ func TestToolGetStats_Functional(t *testing.T) {
 	}
 }
 
+// TestToolSampleStats_Functional tests sampling several containers, summarizing each
+// series and reporting the containers that failed.
+//
+// TestToolSampleStats_Functionalは複数のコンテナのサンプリング、各系列の要約、
+// 失敗したコンテナの報告をテストします。
+func TestToolSampleStats_Functional(t *testing.T) {
+	policy := createTestPolicy()
+	mockClient := docker.NewMockClient(policy)
+	var calls int
+	mockClient.GetStatsFunc = func(ctx context.Context, name string) (*container.StatsResponse, error) {
+		if name != "test-api" {
+			return nil, errors.New("stats permission denied")
+		}
+		calls++
+		return &container.StatsResponse{
+			Read:        time.Now(),
+			MemoryStats: container.MemoryStats{Usage: uint64(calls) * 100 << 20, Limit: 1 << 30},
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolSampleStats(context.Background(), map[string]any{
+		"containers":       []any{"test-api", "test-db"},
+		"samples":          float64(2),
+		"interval_seconds": float64(1),
+	})
+	if err != nil {
+		t.Fatalf("toolSampleStats returned error: %v", err)
+	}
+
+	structured := result.(map[string]any)["structuredContent"].(map[string]any)
+	summaries := structured["containers"].([]docker.StatsSummary)
+	if len(summaries) != 1 || summaries[0].Container != "test-api" || summaries[0].Samples != 2 {
+		t.Fatalf("containers = %+v", summaries)
+	}
+	if got := summaries[0].MemoryUsage; got.Min != 100<<20 || got.Max != 200<<20 {
+		t.Errorf("memory_usage_bytes = %+v", got)
+	}
+	failed, ok := structured["failed"].([]string)
+	if !ok || len(failed) != 1 || !strings.HasPrefix(failed[0], "test-db: ") {
+		t.Errorf("failed = %v", structured["failed"])
+	}
+}
+
+// TestToolSampleStats_InvalidArgs tests the limits on samples and the sampling window.
+// TestToolSampleStats_InvalidArgsはサンプル数とサンプリング期間の制限をテストします。
+func TestToolSampleStats_InvalidArgs(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	server := createTestServer(mockClient)
+
+	tests := []struct {
+		name    string         // Test case name / テストケース名
+		args    map[string]any // Tool arguments / ツール引数
+		wantErr string         // Expected error substring / 期待されるエラーの部分文字列
+	}{
+		{"one sample", map[string]any{"containers": []any{"test-api"}, "samples": float64(1)}, "at least 2"},
+		{"sub-second interval", map[string]any{"containers": []any{"test-api"}, "interval_seconds": float64(0)}, "at least 1"},
+		{"window too long", map[string]any{"containers": []any{"test-api"}, "samples": float64(60), "interval_seconds": float64(10)}, "exceeds the maximum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.toolSampleStats(context.Background(), tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("toolSampleStats() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestToolQueryMetrics_Functional tests reading the recorded history, its listing and
+// the access checks.
+//
+// TestToolQueryMetrics_Functionalは記録された履歴の読み取り、ツールの一覧表示、
+// アクセスチェックをテストします。
+func TestToolQueryMetrics_Functional(t *testing.T) {
+	store, err := history.OpenStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	defer store.Close()
+	start := time.Now().UTC().Add(-30 * time.Minute)
+	for i := 0; i < 3; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		rec := history.Record{Time: at, Container: "test-worker", State: "running", Stats: &docker.StatsPoint{MemoryUsage: uint64(i+1) << 20}}
+		if i == 2 {
+			rec = history.Record{Time: at, Container: "test-worker", State: "exited", ExitCode: 137, OOMKilled: true}
+		}
+		if err := store.Append([]history.Record{rec}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	mockClient := docker.NewMockClient(createTestPolicy())
+	if hasTool(t, createTestServer(mockClient), "query_metrics") {
+		t.Error("query_metrics listed without a metrics history")
+	}
+	server := NewServer(mockClient, 8080, WithMetricsHistory(store))
+	if !hasTool(t, server, "query_metrics") {
+		t.Error("query_metrics not listed with a metrics history")
+	}
+
+	result, err := server.toolQueryMetrics(context.Background(), map[string]any{"container": "test-worker", "since": "1h"})
+	if err != nil {
+		t.Fatalf("toolQueryMetrics returned error: %v", err)
+	}
+	structured := result.(map[string]any)["structuredContent"].(map[string]any)
+	if structured["metric"] != "memory_usage_bytes" {
+		t.Errorf("metric = %v, want the default memory_usage_bytes", structured["metric"])
+	}
+	if points := structured["points"].([]history.Point); len(points) != 2 || points[1].Value != 2<<20 {
+		t.Errorf("points = %+v", points)
+	}
+	events := structured["events"].([]history.Event)
+	if len(events) == 0 || events[0].Reason != history.EventOOMKilled || events[0].ExitCode != 137 {
+		t.Errorf("events = %+v, want the OOM kill first", events)
+	}
+
+	// A Compose service reference reads the history of the container it resolves to
+	// Composeのサービス参照は解決先のコンテナの履歴を読み取る
+	mockClient.ResolveContainerFunc = func(ctx context.Context, ref string) string {
+		if ref == "worker" {
+			return "test-worker"
+		}
+		return ref
+	}
+	result, err = server.toolQueryMetrics(context.Background(), map[string]any{"container": "worker", "since": "1h"})
+	if err != nil {
+		t.Fatalf("toolQueryMetrics returned error for a service reference: %v", err)
+	}
+	structured = result.(map[string]any)["structuredContent"].(map[string]any)
+	if points := structured["points"].([]history.Point); len(points) != 2 {
+		t.Errorf("points for the service reference = %+v, want 2", points)
+	}
+
+	tests := []struct {
+		name    string         // Test case name / テストケース名
+		args    map[string]any // Tool arguments / ツール引数
+		wantErr string         // Expected error substring / 期待されるエラーの部分文字列
+	}{
+		{"container not allowed", map[string]any{"container": "prod-db"}, "prod-db"},
+		{"unknown metric", map[string]any{"container": "test-worker", "metric": "disk"}, "unknown metric"},
+		{"bad since", map[string]any{"container": "test-worker", "since": "yesterday"}, "invalid time"},
+		{"until before since", map[string]any{"container": "test-worker", "since": "1h", "until": "2h"}, "until must be after since"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.toolQueryMetrics(context.Background(), tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestToolGetEvents_Functional tests that get_events passes the filters through with
+// the default range and returns the events as structured content.
+//
+// TestToolGetEvents_Functionalはget_eventsがデフォルトの範囲とともにフィルタを渡し、
+// イベントを構造化コンテンツとして返すことをテストします。
+func TestToolGetEvents_Functional(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	exitCode := 137
+	var gotContainer, gotSince string
+	var gotTypes []string
+	mockClient.GetEventsFunc = func(ctx context.Context, containerName, since, until string, types []string) ([]docker.ContainerEvent, error) {
+		gotContainer, gotSince, gotTypes = containerName, since, types
+		if containerName == "prod-db" {
+			return nil, errors.New("access denied to container 'prod-db'")
+		}
+		return []docker.ContainerEvent{{Container: "test-worker", Action: "die", ExitCode: &exitCode}}, nil
+	}
+	server := createTestServer(mockClient)
+
+	result, err := server.toolGetEvents(context.Background(), map[string]any{
+		"container": "test-worker",
+		"types":     []any{"die", "oom"},
+	})
+	if err != nil {
+		t.Fatalf("toolGetEvents returned error: %v", err)
+	}
+	if gotContainer != "test-worker" || gotSince != getEventsDefaultSince || len(gotTypes) != 2 {
+		t.Errorf("GetEvents called with container=%q since=%q types=%v", gotContainer, gotSince, gotTypes)
+	}
+	structured := result.(map[string]any)["structuredContent"].(map[string]any)
+	if structured["count"] != 1 {
+		t.Errorf("count = %v, want 1", structured["count"])
+	}
+	if events := structured["events"].([]docker.ContainerEvent); *events[0].ExitCode != 137 {
+		t.Errorf("events = %+v", events)
+	}
+
+	if _, err := server.toolGetEvents(context.Background(), map[string]any{"container": "prod-db"}); err == nil {
+		t.Error("expected an error for a container that is not allowed")
+	}
+}
+
+// hasTool reports whether the server lists a tool.
+// hasToolはサーバーがツールを一覧に含むかどうかを返します。
+func hasTool(t *testing.T, server *Server, name string) bool {
+	t.Helper()
+	result, err := server.listTools()
+	if err != nil {
+		t.Fatalf("listTools() error = %v", err)
+	}
+	for _, tool := range result.(map[string]any)["tools"].([]Tool) {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // TestToolExecCommand_Functional tests the exec_command tool handler.
 // TestToolExecCommand_Functionalはexec_commandツールハンドラーをテストします。
 func TestToolExecCommand_Functional(t *testing.T) {
 	policy := createTestPolicy()
 	mockClient := docker.NewMockClient(policy)
-	mockClient.ExecFunc = func(ctx context.Context, name, cmd string, danger bool) (*docker.ExecResult, error) {
+	mockClient.ExecFunc = func(ctx context.Context, name, cmd string, danger bool, opts security.ExecOptions) (*docker.ExecResult, error) {
 		if name != "test-api" {
 			return nil, errors.New("container not found")
 		}
 		if cmd == "npm test" {
 			return &docker.ExecResult{
-				ExitCode: 0,
-				Output:   "All tests passed!\n5 tests, 0 failures\n",
+				ExitCode:   0,
+				Output:     "All tests passed!\n5 tests, 0 failures\n",
+				Stdout:     "All tests passed!\n5 tests, 0 failures\n",
+				DurationMs: 1200,
+			}, nil
+		}
+		if cmd == "npm run build" {
+			return &docker.ExecResult{
+				ExitCode:   1,
+				Output:     "building\nerror: out of memory\n",
+				Stdout:     "building\n",
+				Stderr:     "error: out of memory\n",
+				DurationMs: 300,
+				Truncated:  true,
 			}, nil
 		}
 		return nil, errors.New("command not allowed")
@@ -363,6 +602,113 @@ func TestToolExecCommand_Functional(t *testing.T) {
 	if !strings.Contains(text, "Exit Code: 0") {
 		t.Errorf("expected result to contain exit code, got: %s", text)
 	}
+
+	// Verify the structured result carries the exit code and the bare output
+	// 構造化された結果が終了コードと出力そのものを運ぶことを検証
+	structured, ok := resultMap["structuredContent"].(map[string]any)
+	if !ok {
+		t.Fatal("expected structuredContent in result")
+	}
+	if structured["exit_code"] != 0 || structured["output"] != "All tests passed!\n5 tests, 0 failures\n" {
+		t.Errorf("structuredContent = %v", structured)
+	}
+	if !strings.Contains(text, "Duration: 1200ms") || strings.Contains(text, "truncated") {
+		t.Errorf("expected the duration and no truncation note, got: %s", text)
+	}
+
+	// Verify stderr is reported separately and truncation is surfaced
+	// 標準エラー出力が別に報告され、打ち切りが示されることを検証
+	result, err = server.toolExecCommand(ctx, map[string]any{
+		"container": "test-api",
+		"command":   "npm run build",
+	})
+	if err != nil {
+		t.Fatalf("toolExecCommand returned error: %v", err)
+	}
+	resultMap = result.(map[string]any)
+	structured = resultMap["structuredContent"].(map[string]any)
+	if structured["stdout"] != "building\n" || structured["stderr"] != "error: out of memory\n" || structured["truncated"] != true || structured["duration_ms"] != int64(300) {
+		t.Errorf("structuredContent = %v", structured)
+	}
+	if text := resultMap["content"].([]map[string]any)[0]["text"].(string); !strings.Contains(text, "Output truncated") {
+		t.Errorf("expected a truncation note, got: %s", text)
+	}
+}
+
+// TestToolExecCommand_Options tests passing workdir, env and user to the exec and
+// showing the allowed values in get_allowed_commands.
+//
+// TestToolExecCommand_Optionsはworkdir、env、userのexecへの受け渡しと、許可される値の
+// get_allowed_commandsでの表示をテストします。
+func TestToolExecCommand_Options(t *testing.T) {
+	policy := security.NewPolicy(&configPkg.SecurityConfig{
+		Mode:        "moderate",
+		Permissions: configPkg.SecurityPermissions{Exec: true},
+		ExecWhitelist: map[string][]string{
+			"test-api": {"npm test"},
+		},
+		ExecOptions: map[string]configPkg.ExecOptionsConfig{
+			"test-api": {Workdirs: []string{"/app/packages/*"}, Env: []string{"NODE_ENV"}, Users: []string{"node"}},
+		},
+	})
+	mockClient := docker.NewMockClient(policy)
+	var got security.ExecOptions
+	mockClient.ExecFunc = func(ctx context.Context, name, cmd string, danger bool, opts security.ExecOptions) (*docker.ExecResult, error) {
+		opts, err := policy.CheckExecOptions(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		got = opts
+		return &docker.ExecResult{Output: "ok\n", Stdout: "ok\n"}, nil
+	}
+	server := createTestServer(mockClient)
+	ctx := context.Background()
+
+	result, err := server.toolExecCommand(ctx, map[string]any{
+		"container": "test-api",
+		"command":   "npm test",
+		"workdir":   "/app/packages/web",
+		"env":       map[string]any{"NODE_ENV": "test"},
+		"user":      "node",
+	})
+	if err != nil {
+		t.Fatalf("toolExecCommand returned error: %v", err)
+	}
+	if got.Workdir != "/app/packages/web" || got.User != "node" || got.Env["NODE_ENV"] != "test" {
+		t.Errorf("Exec got options %+v", got)
+	}
+	resultMap := result.(map[string]any)
+	structured := resultMap["structuredContent"].(map[string]any)
+	if structured["workdir"] != "/app/packages/web" || structured["user"] != "node" {
+		t.Errorf("structuredContent = %v", structured)
+	}
+	if text := resultMap["content"].([]map[string]any)[0]["text"].(string); !strings.Contains(text, "Workdir: /app/packages/web") {
+		t.Errorf("expected the workdir in the text, got: %s", text)
+	}
+
+	// Values outside exec_options and malformed parameters are refused
+	// exec_options外の値と不正なパラメータは拒否される
+	for _, args := range []map[string]any{
+		{"user": "root"},
+		{"workdir": "/"},
+		{"env": map[string]any{"NODE_OPTIONS": "--inspect"}},
+		{"env": map[string]any{"NODE_ENV": 1}},
+		{"env": "NODE_ENV=test"},
+	} {
+		args["container"] = "test-api"
+		args["command"] = "npm test"
+		if _, err := server.toolExecCommand(ctx, args); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+
+	result, err = server.toolGetAllowedCommands(ctx, map[string]any{"container": "test-api"})
+	if err != nil {
+		t.Fatalf("toolGetAllowedCommands returned error: %v", err)
+	}
+	if text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string); !strings.Contains(text, "exec_options") || !strings.Contains(text, "/app/packages/*") {
+		t.Errorf("expected exec_options in get_allowed_commands, got: %s", text)
+	}
 }
 
 // TestToolExecCommand_Blocked tests command rejection by security policy.
@@ -370,7 +716,7 @@ func TestToolExecCommand_Functional(t *testing.T) {
 func TestToolExecCommand_Blocked(t *testing.T) {
 	policy := createTestPolicy()
 	mockClient := docker.NewMockClient(policy)
-	mockClient.ExecFunc = func(ctx context.Context, name, cmd string, danger bool) (*docker.ExecResult, error) {
+	mockClient.ExecFunc = func(ctx context.Context, name, cmd string, danger bool, opts security.ExecOptions) (*docker.ExecResult, error) {
 		return nil, errors.New("exec permission denied: command not whitelisted")
 	}
 
@@ -394,6 +740,69 @@ func TestToolExecCommand_Blocked(t *testing.T) {
 	}
 }
 
+// TestToolExecCommand_TimeoutAndCancel tests that a timed-out command returns its partial
+// output, and that timeouts and cancellations are audited with their own results.
+//
+// TestToolExecCommand_TimeoutAndCancelは、タイムアウトしたコマンドが途中までの出力を返し、
+// タイムアウトとキャンセルがそれぞれ固有の結果で監査されることをテストします。
+func TestToolExecCommand_TimeoutAndCancel(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+	audit.ResetLogger()
+	if err := audit.Initialize(configPkg.AuditConfig{
+		Enabled: true,
+		File:    logFile,
+		Events:  configPkg.AuditEvents{ToolCalls: true},
+	}); err != nil {
+		t.Fatalf("audit.Initialize failed: %v", err)
+	}
+	defer audit.ResetLogger()
+
+	mockClient := docker.NewMockClient(createTestPolicy())
+	mockClient.ExecFunc = func(ctx context.Context, name, cmd string, danger bool, opts security.ExecOptions) (*docker.ExecResult, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &docker.ExecResult{ExitCode: -1, Output: "waiting\n", Stdout: "waiting\n", DurationMs: 300000, TimedOut: true}, nil
+	}
+	server := createTestServer(mockClient)
+
+	result, err := server.toolExecCommand(context.Background(), map[string]any{"container": "test-api", "command": "npm test"})
+	if err != nil {
+		t.Fatalf("toolExecCommand returned error: %v", err)
+	}
+	resultMap := result.(map[string]any)
+	if structured := resultMap["structuredContent"].(map[string]any); structured["timed_out"] != true || structured["output"] != "waiting\n" {
+		t.Errorf("structuredContent = %v", structured)
+	}
+	if text := resultMap["content"].([]map[string]any)[0]["text"].(string); !strings.Contains(text, "Timed out") {
+		t.Errorf("expected a timeout note, got: %s", text)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := server.toolExecCommand(ctx, map[string]any{"container": "test-api", "command": "npm test"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit events, got %d: %s", len(lines), data)
+	}
+	for i, want := range []string{"timeout", "cancelled"} {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("failed to parse audit event: %v", err)
+		}
+		if entry["event_type"] != "tool_call" || entry["tool"] != "exec_command" || entry["result"] != want {
+			t.Errorf("audit event %d = %v, want result %s", i, entry, want)
+		}
+	}
+}
+
 // TestToolInspectContainer_Functional tests the inspect_container tool handler.
 // TestToolInspectContainer_Functionalはinspect_containerツールハンドラーをテストします。
 func TestToolInspectContainer_Functional(t *testing.T) {
@@ -525,7 +934,11 @@ func TestToolListFiles_Functional(t *testing.T) {
 		}
 		return &docker.FileAccessResult{
 			Success: true,
-			Data:    "total 16\ndrwxr-xr-x 2 node node 4096 Jan 1 00:00 .\ndrwxr-xr-x 3 node node 4096 Jan 1 00:00 ..\n-rw-r--r-- 1 node node  123 Jan 1 00:00 package.json\n-rw-r--r-- 1 node node  456 Jan 1 00:00 index.js\n",
+			Entries: []docker.FileEntry{
+				{Name: "index.js", Type: "file", Size: 456, Mode: "-rw-r--r--"},
+				{Name: "package.json", Type: "file", Size: 123, Mode: "-rw-r--r--"},
+				{Name: "current", Type: "symlink", Mode: "Lrwxrwxrwx", LinkTarget: "/app/releases/2"},
+			},
 		}, nil
 	}
 
@@ -558,8 +971,10 @@ func TestToolListFiles_Functional(t *testing.T) {
 	}
 
 	text := content[0]["text"].(string)
-	if !strings.Contains(text, "package.json") {
-		t.Errorf("expected result to contain file listing, got: %s", text)
+	for _, want := range []string{`"name": "package.json"`, `"size": 123`, `"link_target": "/app/releases/2"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected result to contain %s, got: %s", want, text)
+		}
 	}
 }
 
@@ -612,6 +1027,449 @@ func TestToolListFiles_Blocked(t *testing.T) {
 	if !strings.Contains(text, "blocked") {
 		t.Errorf("expected result to indicate blocked access, got: %s", text)
 	}
+
+	structured, ok := resultMap["structuredContent"].(map[string]any)
+	if !ok || structured["blocked"] != true || structured["path"] != "/etc/secrets" {
+		t.Errorf("structuredContent = %v, want a blocked result", resultMap["structuredContent"])
+	}
+}
+
+// TestToolReadFile_Cursor tests that tail and cursor are passed through and a cursor is
+// returned when more content remains.
+//
+// TestToolReadFile_Cursorはtailとcursorが渡され、内容が残っている場合にカーソルが
+// 返されることをテストします。
+func TestToolReadFile_Cursor(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	var got []docker.ReadOptions
+	mockClient.ReadFileFunc = func(ctx context.Context, name, path string, opts docker.ReadOptions) (*docker.FileAccessResult, error) {
+		got = append(got, opts)
+		if opts.Tail > 0 {
+			return &docker.FileAccessResult{Success: true, Data: "last line\n", StartLine: 100}, nil
+		}
+		return &docker.FileAccessResult{Success: true, Data: "chunk", Offset: 10, NextCursor: "next-token", Truncated: true}, nil
+	}
+
+	server := createTestServer(mockClient)
+	ctx := context.Background()
+
+	result, err := server.toolReadFile(ctx, map[string]any{"container": "test-api", "path": "/var/log/app.log", "tail": float64(1)})
+	if err != nil {
+		t.Fatalf("toolReadFile returned error: %v", err)
+	}
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if !strings.Contains(text, "last line") || strings.Contains(text, "cursor") {
+		t.Errorf("expected the tail without a cursor, got: %s", text)
+	}
+
+	result, err = server.toolReadFile(ctx, map[string]any{"container": "test-api", "path": "/var/log/app.log", "cursor": "prev-token", "length": float64(5)})
+	if err != nil {
+		t.Fatalf("toolReadFile returned error: %v", err)
+	}
+	text = result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if !strings.Contains(text, `cursor="next-token"`) || !strings.Contains(text, "at byte 15") {
+		t.Errorf("expected a continuation cursor, got: %s", text)
+	}
+	structured := result.(map[string]any)["structuredContent"].(map[string]any)
+	if structured["content"] != "chunk" || structured["next_cursor"] != "next-token" || structured["offset"] != int64(10) {
+		t.Errorf("structuredContent = %v", structured)
+	}
+
+	want := []docker.ReadOptions{{Tail: 1}, {Cursor: "prev-token", Length: 5}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ReadFile called with %+v, want %+v", got, want)
+	}
+}
+
+// TestToolReadFile_Binary tests that binary files are described by size and hash.
+// TestToolReadFile_Binaryはバイナリファイルがサイズとハッシュで説明されることをテストします。
+func TestToolReadFile_Binary(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	mockClient.ReadFileFunc = func(ctx context.Context, name, path string, opts docker.ReadOptions) (*docker.FileAccessResult, error) {
+		return &docker.FileAccessResult{
+			Success: true,
+			File:    &docker.FileEntry{Name: "app", Type: "file", Size: 4096},
+			Binary:  true,
+			SHA256:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolReadFile(context.Background(), map[string]any{"container": "test-api", "path": "/usr/local/bin/app"})
+	if err != nil {
+		t.Fatalf("toolReadFile returned error: %v", err)
+	}
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	for _, want := range []string{`"binary": true`, `"size": 4096`, `"sha256": "e3b0c442`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected result to contain %s, got: %s", want, text)
+		}
+	}
+
+	// A binary file over the read limit is reported by size only
+	// 読み取り上限を超えるバイナリファイルはサイズのみで報告される
+	mockClient.ReadFileFunc = func(ctx context.Context, name, path string, opts docker.ReadOptions) (*docker.FileAccessResult, error) {
+		return &docker.FileAccessResult{
+			Success: true,
+			File:    &docker.FileEntry{Name: "app.img", Type: "file", Size: 8 << 30},
+			Binary:  true,
+		}, nil
+	}
+	result, err = server.toolReadFile(context.Background(), map[string]any{"container": "test-api", "path": "/data/app.img"})
+	if err != nil {
+		t.Fatalf("toolReadFile returned error: %v", err)
+	}
+	text = result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if strings.Contains(text, "sha256") || !strings.Contains(text, "larger than the read limit") {
+		t.Errorf("expected a size-only description, got: %s", text)
+	}
+}
+
+// TestToolReadFile_BlockedSymlink tests that a denial reports the resolved path.
+// TestToolReadFile_BlockedSymlinkは拒否時に解決したパスが報告されることをテストします。
+func TestToolReadFile_BlockedSymlink(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	mockClient.ReadFileFunc = func(ctx context.Context, name, path string, opts docker.ReadOptions) (*docker.FileAccessResult, error) {
+		return &docker.FileAccessResult{
+			Success:      false,
+			Blocked:      true,
+			Block:        &security.BlockedPath{Pattern: ".env", Reason: "global_pattern", Source: "dkmcp.yaml"},
+			ResolvedPath: "/app/.env",
+			MatchedPath:  "/app/.env",
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolReadFile(context.Background(), map[string]any{
+		"container": "test-api",
+		"path":      "/app/link-to-env",
+	})
+	if err != nil {
+		t.Fatalf("toolReadFile returned error: %v", err)
+	}
+
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if !strings.Contains(text, `"resolved_path": "/app/.env"`) {
+		t.Errorf("expected the denial to report the resolved path, got: %s", text)
+	}
+	if strings.Contains(text, "matched_path") {
+		t.Errorf("matched_path should be omitted when it equals the resolved path, got: %s", text)
+	}
+}
+
+// TestToolFindFiles_Functional tests the find_files tool handler.
+// TestToolFindFiles_Functionalはfind_filesツールハンドラーをテストします。
+func TestToolFindFiles_Functional(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	var gotOpts docker.FindOptions
+	mockClient.FindFilesFunc = func(ctx context.Context, name, dir string, opts docker.FindOptions) (*docker.FileAccessResult, error) {
+		gotOpts = opts
+		return &docker.FileAccessResult{
+			Success: true,
+			Entries: []docker.FileEntry{
+				{Name: "config.yaml", Path: "/app/config/config.yaml", Type: "file", Size: 42},
+			},
+			Truncated:      true,
+			SkippedBlocked: 2,
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolFindFiles(context.Background(), map[string]any{
+		"container": "test-api",
+		"path":      "/app",
+		"name":      "*.yaml",
+		"type":      "file",
+		"max_depth": float64(3),
+	})
+	if err != nil {
+		t.Fatalf("toolFindFiles returned error: %v", err)
+	}
+
+	if gotOpts.Name != "*.yaml" || gotOpts.Type != "file" || gotOpts.MaxDepth != 3 {
+		t.Errorf("FindFiles options = %+v", gotOpts)
+	}
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	for _, want := range []string{`"path": "/app/config/config.yaml"`, `"truncated": true`, `"skipped_blocked": 2`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s in result, got: %s", want, text)
+		}
+	}
+}
+
+// TestToolGrepFiles_Functional tests the grep_files tool handler.
+// TestToolGrepFiles_Functionalはgrep_filesツールハンドラーをテストします。
+func TestToolGrepFiles_Functional(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	var gotOpts docker.GrepOptions
+	mockClient.GrepFilesFunc = func(ctx context.Context, name, dir string, opts docker.GrepOptions) (*docker.FileAccessResult, error) {
+		gotOpts = opts
+		return &docker.FileAccessResult{
+			Success: true,
+			Matches: []docker.GrepMatch{
+				{Path: "/app/server.js", Line: 12, Text: "app.listen(PORT)"},
+			},
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolGrepFiles(context.Background(), map[string]any{
+		"container":   "test-api",
+		"pattern":     "listen",
+		"path":        "/app",
+		"include":     "*.js",
+		"ignore_case": true,
+	})
+	if err != nil {
+		t.Fatalf("toolGrepFiles returned error: %v", err)
+	}
+
+	if gotOpts.Pattern != "listen" || gotOpts.Include != "*.js" || !gotOpts.IgnoreCase {
+		t.Errorf("GrepFiles options = %+v", gotOpts)
+	}
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if !strings.Contains(text, `"line": 12`) || !strings.Contains(text, "app.listen(PORT)") {
+		t.Errorf("expected the match in result, got: %s", text)
+	}
+	if strings.Contains(text, "skipped_blocked") {
+		t.Errorf("skipped_blocked should be omitted when nothing was skipped, got: %s", text)
+	}
+
+	if _, err := server.toolGrepFiles(context.Background(), map[string]any{"container": "test-api"}); err == nil {
+		t.Error("expected an error without a pattern")
+	}
+}
+
+// TestToolWriteFile_Functional tests the write_file tool handler.
+// TestToolWriteFile_Functionalはwrite_fileツールハンドラーをテストします。
+func TestToolWriteFile_Functional(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	var gotContent string
+	mockClient.WriteFileFunc = func(ctx context.Context, name, path, content string) (*docker.FileAccessResult, error) {
+		gotContent = content
+		return &docker.FileAccessResult{
+			Success:    true,
+			BackupPath: path + ".dkmcp.bak",
+			Diff:       "--- a/app/config.yaml\n+++ b/app/config.yaml\n@@ -1,1 +1,1 @@\n-debug: false\n+debug: true\n",
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolWriteFile(context.Background(), map[string]any{
+		"container": "test-api",
+		"path":      "/app/config.yaml",
+		"content":   "debug: true\n",
+	})
+	if err != nil {
+		t.Fatalf("toolWriteFile returned error: %v", err)
+	}
+
+	if gotContent != "debug: true\n" {
+		t.Errorf("WriteFile content = %q", gotContent)
+	}
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	for _, want := range []string{"Updated test-api:/app/config.yaml", "/app/config.yaml.dkmcp.bak", "+debug: true"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in result, got: %s", want, text)
+		}
+	}
+
+	if _, err := server.toolWriteFile(context.Background(), map[string]any{"container": "test-api", "path": "/app/x"}); err == nil {
+		t.Error("expected an error without content")
+	}
+}
+
+// TestToolApplyPatch_Results tests the apply_patch responses for denied, blocked and
+// unchanged writes.
+//
+// TestToolApplyPatch_Resultsはapply_patchの拒否・ブロック・変更なしの書き込みに対する
+// レスポンスをテストします。
+func TestToolApplyPatch_Results(t *testing.T) {
+	tests := []struct {
+		name     string                   // Test case name / テストケース名
+		result   *docker.FileAccessResult // Result of ApplyPatch / ApplyPatchの結果
+		err      error                    // Error of ApplyPatch / ApplyPatchのエラー
+		wantText string                   // Expected response text ("" = error) / 期待されるレスポンス
+	}{
+		{
+			name: "not writable",
+			err:  errors.New("path is not writable: /app/server.js is not covered by writable_paths for container test-api"),
+		},
+		{
+			name: "blocked",
+			result: &docker.FileAccessResult{
+				Blocked: true,
+				Block:   &security.BlockedPath{Pattern: ".env", Reason: "global_pattern", Source: "dkmcp.yaml"},
+			},
+			wantText: `"blocked": true`,
+		},
+		{
+			name:     "patch does not apply",
+			result:   &docker.FileAccessResult{Error: "hunk 1 (@@ -2) does not apply: its context does not match the file"},
+			wantText: "does not apply",
+		},
+		{
+			name:     "unchanged",
+			result:   &docker.FileAccessResult{Success: true},
+			wantText: "No changes to test-api:/app/config.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockClient(createTestPolicy())
+			mockClient.ApplyPatchFunc = func(ctx context.Context, name, path, patch string) (*docker.FileAccessResult, error) {
+				return tt.result, tt.err
+			}
+
+			server := createTestServer(mockClient)
+			result, err := server.toolApplyPatch(context.Background(), map[string]any{
+				"container": "test-api",
+				"path":      "/app/config.yaml",
+				"patch":     "@@ -1,1 +1,1 @@\n-a\n+b\n",
+			})
+			if tt.wantText == "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toolApplyPatch returned error: %v", err)
+			}
+			text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+			if !strings.Contains(text, tt.wantText) {
+				t.Errorf("expected %q in result, got: %s", tt.wantText, text)
+			}
+		})
+	}
+}
+
+// TestToolCopyFromContainer_Functional tests that copy_from_container resolves the host
+// path inside the workspace and reports the hashes of the copied files.
+//
+// TestToolCopyFromContainer_Functionalはcopy_from_containerがホストのパスをワークスペース内で
+// 解決し、コピーしたファイルのハッシュを報告することをテストします。
+func TestToolCopyFromContainer_Functional(t *testing.T) {
+	workspace, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockClient := docker.NewMockClient(createTestPolicy())
+	var gotHostPath string
+	mockClient.CopyFromContainerFunc = func(ctx context.Context, name, src, hostPath string, overwrite bool) (*docker.FileAccessResult, error) {
+		gotHostPath = hostPath
+		return &docker.FileAccessResult{
+			Success:        true,
+			Copied:         []docker.CopiedFile{{Path: "/app/reports/junit.xml", Size: 13, SHA256: "abc123"}},
+			SkippedBlocked: 1,
+		}, nil
+	}
+
+	server := NewServer(mockClient, 8080, WithWorkspaceRoot(workspace))
+	result, err := server.toolCopyFromContainer(context.Background(), map[string]any{
+		"container": "test-api",
+		"path":      "/app/reports",
+		"host_path": "reports/junit",
+	})
+	if err != nil {
+		t.Fatalf("toolCopyFromContainer returned error: %v", err)
+	}
+	if gotHostPath != filepath.Join(workspace, "reports/junit") {
+		t.Errorf("host path = %q", gotHostPath)
+	}
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	for _, want := range []string{"Copied test-api:/app/reports", "1 file(s), 13 bytes copied to workspace path reports/junit", "1 blocked path(s)", "abc123  /app/reports/junit.xml"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in result, got: %s", want, text)
+		}
+	}
+
+	if _, err := server.toolCopyFromContainer(context.Background(), map[string]any{
+		"container": "test-api",
+		"path":      "/app/reports",
+		"host_path": "../outside",
+	}); err == nil || !strings.Contains(err.Error(), "outside the workspace") {
+		t.Errorf("expected a workspace error, got %v", err)
+	}
+
+	noWorkspace := createTestServer(mockClient)
+	if _, err := noWorkspace.toolCopyFromContainer(context.Background(), map[string]any{
+		"container": "test-api",
+		"path":      "/app/reports",
+		"host_path": "reports",
+	}); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("expected an error without a workspace, got %v", err)
+	}
+}
+
+// TestToolCopyToContainer_Results tests the copy_to_container responses for denied,
+// blocked and failed copies.
+//
+// TestToolCopyToContainer_Resultsはcopy_to_containerの拒否・ブロック・失敗したコピーに
+// 対するレスポンスをテストします。
+func TestToolCopyToContainer_Results(t *testing.T) {
+	workspace, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &security.BlockedPath{Pattern: ".env", Reason: "global_pattern", Source: "dkmcp.yaml"}
+
+	tests := []struct {
+		name     string                   // Test case name / テストケース名
+		result   *docker.FileAccessResult // Result of CopyToContainer / CopyToContainerの結果
+		err      error                    // Error of CopyToContainer / CopyToContainerのエラー
+		wantText string                   // Expected response text ("" = error) / 期待されるレスポンス
+	}{
+		{
+			name: "copy disabled",
+			err:  errors.New("file copies are disabled in security policy"),
+		},
+		{
+			name:     "blocked host file",
+			result:   &docker.FileAccessResult{Blocked: true, Block: block, MatchedPath: filepath.Join(workspace, "seed/.env")},
+			wantText: `"path": "seed/.env"`,
+		},
+		{
+			name:     "blocked container path",
+			result:   &docker.FileAccessResult{Blocked: true, Block: block},
+			wantText: `"path": "/app/testdata"`,
+		},
+		{
+			name:     "too large",
+			result:   &docker.FileAccessResult{Error: "copy is too large: 300000000 bytes (max 268435456)"},
+			wantText: "Error copying test-api:/app/testdata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := docker.NewMockClient(createTestPolicy())
+			mockClient.CopyToContainerFunc = func(ctx context.Context, name, hostPath, dest string, overwrite bool) (*docker.FileAccessResult, error) {
+				return tt.result, tt.err
+			}
+
+			server := NewServer(mockClient, 8080, WithWorkspaceRoot(workspace))
+			result, err := server.toolCopyToContainer(context.Background(), map[string]any{
+				"container": "test-api",
+				"host_path": "seed/.env",
+				"path":      "/app/testdata",
+			})
+			if tt.wantText == "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("toolCopyToContainer returned error: %v", err)
+			}
+			text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+			if !strings.Contains(text, tt.wantText) {
+				t.Errorf("expected %q in result, got: %s", tt.wantText, text)
+			}
+		})
+	}
 }
 
 // TestToolReadFile_Functional tests the read_file tool handler.
@@ -619,7 +1477,7 @@ func TestToolListFiles_Blocked(t *testing.T) {
 func TestToolReadFile_Functional(t *testing.T) {
 	policy := createTestPolicy()
 	mockClient := docker.NewMockClient(policy)
-	mockClient.ReadFileFunc = func(ctx context.Context, name, path string, maxLines int) (*docker.FileAccessResult, error) {
+	mockClient.ReadFileFunc = func(ctx context.Context, name, path string, opts docker.ReadOptions) (*docker.FileAccessResult, error) {
 		if name != "test-api" {
 			return nil, errors.New("container not found")
 		}
@@ -663,6 +1521,36 @@ func TestToolReadFile_Functional(t *testing.T) {
 	}
 }
 
+// TestToolReadFile_Range tests that read_file passes the requested range and reports truncation.
+// TestToolReadFile_Rangeはread_fileが要求された範囲を渡し、打ち切りを報告することをテストします。
+func TestToolReadFile_Range(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	var got docker.ReadOptions
+	mockClient.ReadFileFunc = func(ctx context.Context, name, path string, opts docker.ReadOptions) (*docker.FileAccessResult, error) {
+		got = opts
+		return &docker.FileAccessResult{Success: true, Data: "line 10\n", Truncated: true}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolReadFile(context.Background(), map[string]any{
+		"container":  "test-api",
+		"path":       "/app/server.log",
+		"start_line": float64(10),
+		"max_lines":  float64(5),
+	})
+	if err != nil {
+		t.Fatalf("toolReadFile returned error: %v", err)
+	}
+	if got != (docker.ReadOptions{StartLine: 10, MaxLines: 5}) {
+		t.Errorf("ReadFile called with %+v, want start_line 10 and max_lines 5", got)
+	}
+
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if !strings.Contains(text, "line 10") || !strings.Contains(text, "[truncated") {
+		t.Errorf("expected content with a truncation note, got: %s", text)
+	}
+}
+
 // TestToolGetAllowedCommands_Functional tests the get_allowed_commands tool handler.
 // TestToolGetAllowedCommands_Functionalはget_allowed_commandsツールハンドラーをテストします。
 func TestToolGetAllowedCommands_Functional(t *testing.T) {
@@ -737,6 +1625,59 @@ func TestToolGetSecurityPolicy_Functional(t *testing.T) {
 	}
 }
 
+// TestToolExplainPolicy_Functional tests the explain_policy tool handler.
+// TestToolExplainPolicy_Functionalはexplain_policyツールハンドラーをテストします。
+func TestToolExplainPolicy_Functional(t *testing.T) {
+	mockClient := docker.NewMockClient(createTestPolicy())
+	server := createTestServer(mockClient)
+	ctx := context.Background()
+
+	explain := func(args map[string]any) []security.Explanation {
+		t.Helper()
+		result, err := server.toolExplainPolicy(ctx, args)
+		if err != nil {
+			t.Fatalf("toolExplainPolicy returned error: %v", err)
+		}
+		text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+		start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+		var explanations []security.Explanation
+		if err := json.Unmarshal([]byte(text[start:end+1]), &explanations); err != nil {
+			t.Fatalf("failed to parse explanation: %v\n%s", err, text)
+		}
+		return explanations
+	}
+
+	got := explain(map[string]any{"container": "test-api", "command": "npm run lint", "path": "/app/src"})
+	if len(got) != 2 {
+		t.Fatalf("expected command and path explanations, got %+v", got)
+	}
+	if !got[0].Allowed || got[0].Rule == nil || got[0].Rule.Key != "test-api" || got[0].Rule.Entry != "npm run lint" {
+		t.Errorf("expected npm run lint allowed by exec_whitelist[test-api], got %+v", got[0])
+	}
+	if !got[1].Allowed || got[1].Path != "/app/src" {
+		t.Errorf("expected /app/src allowed, got %+v", got[1])
+	}
+
+	got = explain(map[string]any{"container": "test-api", "command": "rm -rf /"})
+	if got[0].Allowed || got[0].Rule != nil || !strings.Contains(got[0].Reason, "not whitelisted") {
+		t.Errorf("expected rm -rf / denied as not whitelisted, got %+v", got[0])
+	}
+
+	// Paths are explained by the docker client, which resolves them in the container
+	// パスはコンテナ内で解決するDockerクライアントによって説明される
+	mockClient.ExplainPathFunc = func(ctx context.Context, name, path string) security.Explanation {
+		return security.Explanation{Container: name, Path: path, ResolvedPath: "/app/.env", MatchedPath: "/app/.env", Reason: "path is blocked"}
+	}
+	got = explain(map[string]any{"container": "test-api", "path": "/app/link-to-env"})
+	if got[0].Allowed || got[0].ResolvedPath != "/app/.env" || got[0].MatchedPath != "/app/.env" {
+		t.Errorf("expected the link denied with its resolved path, got %+v", got[0])
+	}
+
+	if _, err := server.toolExplainPolicy(ctx, map[string]any{"container": "test-api"}); err == nil {
+		t.Error("expected error when neither command nor path is given")
+	}
+}
+
 // TestToolGetBlockedPaths_Functional tests the get_blocked_paths tool handler.
 // TestToolGetBlockedPaths_Functionalはget_blocked_pathsツールハンドラーをテストします。
 func TestToolGetBlockedPaths_Functional(t *testing.T) {
@@ -793,6 +1734,281 @@ func createTestPolicyWithHostPathMasking() *security.Policy {
 	return security.NewPolicy(cfg)
 }
 
+// TestToolFollowLogs_Functional tests that follow_logs streams masked chunks as progress
+// notifications and stops at max_lines.
+//
+// TestToolFollowLogs_Functionalは、follow_logsがマスク済みのチャンクを進捗通知として
+// ストリームし、max_linesで停止することをテストします。
+func TestToolFollowLogs_Functional(t *testing.T) {
+	policy := createTestPolicyWithHostPathMasking()
+	mockClient := docker.NewMockClient(policy)
+	mockClient.FollowLogsFunc = func(ctx context.Context, name, tail, since string, onLine func(docker.LogLine) error) error {
+		if tail != "0" {
+			t.Errorf("expected default tail \"0\", got %q", tail)
+		}
+		for i := 1; ; i++ {
+			line := docker.LogLine{Stream: "stdout", Text: fmt.Sprintf("line %d from /Users/suzu/app", i)}
+			if err := onLine(line); err != nil {
+				return err
+			}
+		}
+	}
+
+	server := createTestServer(mockClient)
+
+	// Capture notifications sent by the tool
+	// ツールが送信する通知を捕捉
+	var notifications []map[string]any
+	ctx := withNotifier(context.Background(), &requestNotifier{
+		progressToken: "token-1",
+		send: func(msg []byte) error {
+			var n map[string]any
+			json.Unmarshal(msg, &n)
+			notifications = append(notifications, n)
+			return nil
+		},
+	})
+
+	result, err := server.toolFollowLogs(ctx, map[string]any{
+		"container": "test-api",
+		"max_lines": float64(3),
+	})
+	if err != nil {
+		t.Fatalf("toolFollowLogs returned error: %v", err)
+	}
+
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if !strings.Contains(text, "3 lines, max_lines reached") || !strings.Contains(text, "line 3") {
+		t.Errorf("expected 3 lines stopped by max_lines, got: %s", text)
+	}
+	if strings.Contains(text, "line 4") {
+		t.Errorf("expected no lines beyond max_lines, got: %s", text)
+	}
+	if strings.Contains(text, "/Users/suzu") {
+		t.Errorf("expected host path to be masked in result, got: %s", text)
+	}
+
+	if len(notifications) == 0 {
+		t.Fatal("expected at least one progress notification")
+	}
+	for _, n := range notifications {
+		if n["method"] != "notifications/progress" {
+			t.Errorf("expected notifications/progress, got %v", n["method"])
+		}
+		params := n["params"].(map[string]any)
+		if params["progressToken"] != "token-1" {
+			t.Errorf("expected progressToken token-1, got %v", params["progressToken"])
+		}
+		if msg, _ := params["message"].(string); strings.Contains(msg, "/Users/suzu") {
+			t.Errorf("expected host path to be masked in notification, got: %s", msg)
+		}
+	}
+}
+
+// TestToolFollowLogs_StopsAfterDuration tests that follow_logs ends when the duration elapses
+// and sends log messages when no progress token is given.
+//
+// TestToolFollowLogs_StopsAfterDurationは、follow_logsが期間の経過で終了し、
+// 進捗トークンがない場合はログメッセージを送信することをテストします。
+func TestToolFollowLogs_StopsAfterDuration(t *testing.T) {
+	policy := createTestPolicy()
+	mockClient := docker.NewMockClient(policy)
+	mockClient.FollowLogsFunc = func(ctx context.Context, name, tail, since string, onLine func(docker.LogLine) error) error {
+		if err := onLine(docker.LogLine{Stream: "stdout", Text: "only line"}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return nil
+	}
+
+	server := createTestServer(mockClient)
+
+	var methods []string
+	ctx := withNotifier(context.Background(), &requestNotifier{
+		send: func(msg []byte) error {
+			var n map[string]any
+			json.Unmarshal(msg, &n)
+			methods = append(methods, n["method"].(string))
+			return nil
+		},
+	})
+
+	result, err := server.toolFollowLogs(ctx, map[string]any{
+		"container":        "test-api",
+		"duration_seconds": float64(1),
+	})
+	if err != nil {
+		t.Fatalf("toolFollowLogs returned error: %v", err)
+	}
+
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+	if !strings.Contains(text, "1 lines, duration elapsed") || !strings.Contains(text, "only line") {
+		t.Errorf("expected result stopped by duration, got: %s", text)
+	}
+	if len(methods) != 1 || methods[0] != "notifications/message" {
+		t.Errorf("expected one notifications/message, got %v", methods)
+	}
+}
+
+// TestToolCorrelateLogs_Functional tests that correlate_logs merges logs from several
+// containers by timestamp and reports containers that could not be read.
+//
+// TestToolCorrelateLogs_Functionalは、correlate_logsが複数コンテナのログをタイムスタンプで
+// マージし、読み取れなかったコンテナを報告することをテストします。
+func TestToolCorrelateLogs_Functional(t *testing.T) {
+	policy := createTestPolicy()
+	mockClient := docker.NewMockClient(policy)
+	mockClient.ListContainersFunc = func(ctx context.Context) ([]docker.ContainerInfo, error) {
+		return []docker.ContainerInfo{
+			{Name: "test-api", State: "running"},
+			{Name: "test-db", State: "running"},
+			{Name: "test-old", State: "exited"},
+		}, nil
+	}
+	mockClient.GetLogLinesFunc = func(ctx context.Context, name, tail, since, until string) ([]docker.LogLine, error) {
+		switch name {
+		case "test-api":
+			return []docker.LogLine{
+				{Stream: "stdout", Text: "2024-01-01T10:00:00Z GET /orders"},
+				{Stream: "stderr", Text: "2024-01-01T10:00:02Z error: query failed"},
+				{Stream: "stderr", Text: "  at handler (continued)"},
+			}, nil
+		case "test-db":
+			return []docker.LogLine{
+				{Stream: "stdout", Text: "2024-01-01T10:00:01Z slow query"},
+			}, nil
+		}
+		return nil, fmt.Errorf("access denied to container: %s", name)
+	}
+
+	server := createTestServer(mockClient)
+	ctx := context.Background()
+
+	t.Run("defaults to running containers", func(t *testing.T) {
+		result, err := server.toolCorrelateLogs(ctx, map[string]any{})
+		if err != nil {
+			t.Fatalf("toolCorrelateLogs returned error: %v", err)
+		}
+		text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+
+		want := []string{
+			"[test-api] GET /orders",
+			"[test-db ] slow query",
+			"[test-api] error: query failed",
+			"[test-api]   at handler (continued)",
+		}
+		last := -1
+		for _, w := range want {
+			idx := strings.Index(text, w)
+			if idx < 0 {
+				t.Fatalf("expected %q in result, got: %s", w, text)
+			}
+			if idx < last {
+				t.Errorf("expected %q after previous line, got: %s", w, text)
+			}
+			last = idx
+		}
+		if strings.Contains(text, "test-old") {
+			t.Errorf("expected stopped container to be skipped by default, got: %s", text)
+		}
+	})
+
+	t.Run("reports unreadable containers", func(t *testing.T) {
+		result, err := server.toolCorrelateLogs(ctx, map[string]any{
+			"containers": []any{"test-api", "secret-db"},
+		})
+		if err != nil {
+			t.Fatalf("toolCorrelateLogs returned error: %v", err)
+		}
+		text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+		if !strings.Contains(text, "Skipped containers") || !strings.Contains(text, "secret-db: access denied") {
+			t.Errorf("expected skipped container to be reported, got: %s", text)
+		}
+	})
+
+	t.Run("fails when no container is readable", func(t *testing.T) {
+		_, err := server.toolCorrelateLogs(ctx, map[string]any{
+			"containers": []any{"secret-db"},
+		})
+		if err == nil || !strings.Contains(err.Error(), "access denied") {
+			t.Errorf("expected access denied error, got %v", err)
+		}
+	})
+}
+
+// TestToolListServices_Functional tests grouping containers by Compose service.
+// TestToolListServices_FunctionalはComposeのサービスによるコンテナのグループ化をテストします。
+func TestToolListServices_Functional(t *testing.T) {
+	policy := createTestPolicy()
+	mockClient := docker.NewMockClient(policy)
+	labels := func(service, number string) map[string]string {
+		return map[string]string{
+			security.ComposeProjectLabel: "test",
+			security.ComposeServiceLabel: service,
+			security.ComposeNumberLabel:  number,
+		}
+	}
+	mockClient.ListContainersFunc = func(ctx context.Context) ([]docker.ContainerInfo, error) {
+		return []docker.ContainerInfo{
+			{Name: "test-api-2", State: "running", Health: "unhealthy", Labels: labels("api", "2")},
+			{Name: "test-api-1", State: "running", Health: "healthy", Labels: labels("api", "1")},
+			{Name: "test-db-1", State: "exited", Labels: labels("db", "1")},
+			{Name: "test-tools"},
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	result, err := server.toolListServices(context.Background(), map[string]any{})
+	if err != nil {
+		t.Fatalf("toolListServices returned error: %v", err)
+	}
+	text := result.(map[string]any)["content"].([]map[string]any)[0]["text"].(string)
+
+	var got struct {
+		Services   []ServiceInfo `json:"services"`
+		Standalone []string      `json:"standalone"`
+	}
+	if err := json.Unmarshal([]byte(text), &got); err != nil {
+		t.Fatalf("failed to parse result: %v\n%s", err, text)
+	}
+
+	if len(got.Services) != 2 {
+		t.Fatalf("expected 2 services, got %d: %s", len(got.Services), text)
+	}
+	api := got.Services[0]
+	if api.Service != "api" || api.Running != 2 || len(api.Replicas) != 2 {
+		t.Errorf("unexpected api service: %+v", api)
+	}
+	if api.Replicas[0].Name != "test-api-1" {
+		t.Errorf("expected replicas ordered by number, got %+v", api.Replicas)
+	}
+	if api.Health["healthy"] != 1 || api.Health["unhealthy"] != 1 {
+		t.Errorf("unexpected health summary: %v", api.Health)
+	}
+	if db := got.Services[1]; db.Service != "db" || db.Running != 0 {
+		t.Errorf("unexpected db service: %+v", db)
+	}
+	if len(got.Standalone) != 1 || got.Standalone[0] != "test-tools" {
+		t.Errorf("expected standalone [test-tools], got %v", got.Standalone)
+	}
+}
+
+// TestToolFollowLogs_Error tests that Docker errors are returned to the caller.
+// TestToolFollowLogs_ErrorはDockerのエラーが呼び出し元に返されることをテストします。
+func TestToolFollowLogs_Error(t *testing.T) {
+	policy := createTestPolicy()
+	mockClient := docker.NewMockClient(policy)
+	mockClient.FollowLogsFunc = func(ctx context.Context, name, tail, since string, onLine func(docker.LogLine) error) error {
+		return errors.New("access denied to container: " + name)
+	}
+
+	server := createTestServer(mockClient)
+	_, err := server.toolFollowLogs(context.Background(), map[string]any{"container": "secret-db"})
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected access denied error, got %v", err)
+	}
+}
+
 // TestToolListContainers_HostPathMasking tests that host paths are masked in list_containers output.
 // TestToolListContainers_HostPathMaskingはlist_containers出力でホストパスがマスクされることをテストします。
 func TestToolListContainers_HostPathMasking(t *testing.T) {
@@ -890,7 +2106,7 @@ func TestToolExecCommand_HostPathMasking(t *testing.T) {
 
 	// Mock exec returning host paths
 	// ホストパスを返すexecをモック
-	mockClient.ExecFunc = func(ctx context.Context, name, command string, dangerously bool) (*docker.ExecResult, error) {
+	mockClient.ExecFunc = func(ctx context.Context, name, command string, dangerously bool, opts security.ExecOptions) (*docker.ExecResult, error) {
 		return &docker.ExecResult{
 			Output:   "File path: /home/developer/projects/app/src/main.go",
 			ExitCode: 0,
@@ -972,6 +2188,89 @@ func TestToolInspectContainer_HostPathMasking(t *testing.T) {
 	if !strings.Contains(text, "[HOST_PATH]") {
 		t.Errorf("expected '[HOST_PATH]' in result, got: %s", text)
 	}
+
+	// The structured result must be masked the same way
+	// 構造化された結果も同じようにマスクされている必要がある
+	structured, err := json.Marshal(resultMap["structuredContent"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(structured), "/Users/jane") || !strings.Contains(string(structured), "[HOST_PATH]") {
+		t.Errorf("expected masked structuredContent, got: %s", structured)
+	}
+}
+
+// TestToolSearchLogs_Filters tests stream selection, time window pass-through,
+// structured field filtering and the structured content of search_logs.
+//
+// TestToolSearchLogs_Filtersはsearch_logsのストリーム選択、時間範囲の受け渡し、
+// 構造化フィールドによる絞り込み、および構造化コンテンツをテストします。
+func TestToolSearchLogs_Filters(t *testing.T) {
+	policy := createTestPolicy()
+	mockClient := docker.NewMockClient(policy)
+	var gotSince, gotUntil string
+	mockClient.GetLogLinesFunc = func(ctx context.Context, name, tail, since, until string) ([]docker.LogLine, error) {
+		gotSince, gotUntil = since, until
+		return []docker.LogLine{
+			{Stream: "stdout", Text: `2024-01-01T10:00:00Z {"level":"info","request_id":"abc","msg":"start"}`},
+			{Stream: "stderr", Text: `2024-01-01T10:00:01Z {"level":"error","request_id":"abc","msg":"db timeout"}`},
+			{Stream: "stderr", Text: "2024-01-01T10:00:02Z panic: not json"},
+			{Stream: "stdout", Text: `2024-01-01T10:00:03Z {"level":"error","request_id":"xyz","msg":"db timeout"}`},
+		}, nil
+	}
+
+	server := createTestServer(mockClient)
+	ctx := context.Background()
+
+	result, err := server.toolSearchLogs(ctx, map[string]any{
+		"container":     "test-api",
+		"pattern":       "timeout",
+		"since":         "2024-01-01T09:00:00Z",
+		"until":         "30m",
+		"stream":        "stderr",
+		"fields":        map[string]any{"level": "error", "request_id": "abc"},
+		"context_lines": float64(0),
+	})
+	if err != nil {
+		t.Fatalf("toolSearchLogs returned error: %v", err)
+	}
+
+	if gotSince != "2024-01-01T09:00:00Z" || gotUntil != "30m" {
+		t.Errorf("Expected since/until to be passed to Docker, got %q/%q", gotSince, gotUntil)
+	}
+
+	structured, ok := result.(map[string]any)["structuredContent"].(map[string]any)
+	if !ok {
+		t.Fatal("expected structuredContent in result")
+	}
+	if structured["total_lines"] != 2 || structured["matches_count"] != 1 || structured["non_json_lines"] != 1 {
+		t.Errorf("unexpected counts: total=%v matches=%v non_json=%v",
+			structured["total_lines"], structured["matches_count"], structured["non_json_lines"])
+	}
+	matches := structured["matches"].([]SearchMatch)
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	want := SearchMatch{
+		LineNumber: 1,
+		Timestamp:  "2024-01-01T10:00:01Z",
+		Stream:     "stderr",
+		Line:       `{"level":"error","request_id":"abc","msg":"db timeout"}`,
+	}
+	if matches[0].LineNumber != want.LineNumber || matches[0].Timestamp != want.Timestamp ||
+		matches[0].Stream != want.Stream || matches[0].Line != want.Line {
+		t.Errorf("match = %+v, want %+v", matches[0], want)
+	}
+
+	// Invalid regular expressions are reported as errors
+	// 不正な正規表現はエラーとして報告される
+	if _, err := server.toolSearchLogs(ctx, map[string]any{
+		"container": "test-api",
+		"pattern":   "(",
+		"regex":     true,
+	}); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("expected invalid pattern error, got %v", err)
+	}
 }
 
 // TestToolSearchLogs_HostPathMasking tests that host paths are masked in search_logs output.
@@ -1016,7 +2315,7 @@ func TestToolReadFile_HostPathMasking(t *testing.T) {
 	policy := createTestPolicyWithHostPathMasking()
 	mockClient := docker.NewMockClient(policy)
 
-	mockClient.ReadFileFunc = func(ctx context.Context, containerName, path string, maxLines int) (*docker.FileAccessResult, error) {
+	mockClient.ReadFileFunc = func(ctx context.Context, containerName, path string, opts docker.ReadOptions) (*docker.FileAccessResult, error) {
 		return &docker.FileAccessResult{
 			Success: true,
 			Data:    "source_path: /home/jenkins/workspace/project/src",
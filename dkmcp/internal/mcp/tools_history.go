@@ -0,0 +1,151 @@
+// tools_history.go provides the query_metrics MCP handler, which reads the container
+// history recorded in the background by `dkmcp serve`.
+//
+// tools_history.goはquery_metrics MCPハンドラーを提供します。このハンドラーは
+// `dkmcp serve`がバックグラウンドで記録したコンテナの履歴を読み取ります。
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/history"
+)
+
+// queryMetricsDefaultSince is the start of the range when since is not given.
+// queryMetricsDefaultSinceはsinceが指定されない場合の範囲の開始です。
+const queryMetricsDefaultSince = "1h"
+
+// GetMetricsHistoryTools returns the MCP tool definitions for the metrics history.
+// These are appended to the main tool list when the recorder is enabled.
+//
+// GetMetricsHistoryToolsはメトリクス履歴のMCPツール定義を返します。
+// レコーダーが有効な場合、メインのツールリストに追加されます。
+func GetMetricsHistoryTools() []Tool {
+	return []Tool{
+		{
+			Name:        "query_metrics",
+			Description: "Query the resource history recorded in the background for a container: one metric over a time range as points, a min/avg/max/last/trend summary, and the state changes in the range (restarts, exits with exit code, OOM kills). Use this for questions about the past, such as whether a container ran out of memory last night; use sample_stats for what is happening now.",
+			InputSchema: ToolInputSchema{
+				Type: "object",
+				Properties: map[string]ToolProperty{
+					"container": {
+						Type:        "string",
+						Description: "Container name",
+					},
+					"metric": {
+						Type:        "string",
+						Description: "Metric to return: " + strings.Join(history.MetricNames, ", "),
+						Default:     "memory_usage_bytes",
+					},
+					"since": {
+						Type:        "string",
+						Description: "Start of the range: timestamp (e.g., '2024-01-01T10:00:00Z') or relative (e.g., '42m', '12h') (default: 1h)",
+						Default:     queryMetricsDefaultSince,
+					},
+					"until": {
+						Type:        "string",
+						Description: "End of the range: timestamp or relative (default: now)",
+					},
+					"step_seconds": {
+						Type:        "integer",
+						Description: fmt.Sprintf("Average the points over steps of this many seconds (default: raw samples, or a step that keeps the points under %d)", history.MaxPoints),
+						Minimum:     minimum(1),
+					},
+				},
+				Required: []string{"container"},
+			},
+			OutputSchema: outputSchema(map[string]ToolProperty{
+				"container":    {Type: "string", Description: "Container the history was queried for"},
+				"metric":       {Type: "string", Description: "Metric of the points"},
+				"since":        {Type: "string", Description: "Start of the range"},
+				"until":        {Type: "string", Description: "End of the range"},
+				"step_seconds": {Type: "integer", Description: "Width the points were averaged over; 0 for raw samples"},
+				"points":       arrayOf("object", "Points with time and value"),
+				"summary":      {Type: "object", Description: "min, avg, max, last and trend of every recorded value in the range"},
+				"events":       arrayOf("object", "State changes with time, reason (state_changed, restarted, oom_killed), state, exit_code and restart_count"),
+			}, "container", "metric", "since", "until", "points", "summary", "events"),
+		},
+	}
+}
+
+// toolQueryMetrics implements the query_metrics MCP tool.
+// toolQueryMetricsはquery_metrics MCPツールを実装します。
+func (s *Server) toolQueryMetrics(ctx context.Context, args map[string]any) (any, error) {
+	if s.metricsHistory == nil {
+		return nil, fmt.Errorf("metrics history is not enabled")
+	}
+
+	container, ok := args["container"].(string)
+	if !ok || container == "" {
+		return nil, fmt.Errorf("missing or invalid container parameter")
+	}
+	metric := "memory_usage_bytes"
+	if m, ok := args["metric"].(string); ok && m != "" {
+		metric = m
+	}
+
+	// The history is recorded under container names; resolve Compose service references
+	// 履歴はコンテナ名で記録されるため、Composeのサービス参照を解決する
+	container = s.docker.ResolveContainer(ctx, container)
+
+	// The history was recorded under the policy of that time; check the current one too
+	// 履歴は当時のポリシーで記録されたため、現在のポリシーもチェックする
+	policy := s.docker.GetPolicy()
+	if err := policy.CheckContainerAccess(container); err != nil {
+		return nil, err
+	}
+	if !policy.CanGetStats(container) {
+		return nil, fmt.Errorf("stats permission denied")
+	}
+
+	now := time.Now().UTC()
+	sinceArg := queryMetricsDefaultSince
+	if v, ok := args["since"].(string); ok && v != "" {
+		sinceArg = v
+	}
+	since, err := history.ParseTime(sinceArg, now)
+	if err != nil {
+		return nil, err
+	}
+	until := now
+	if v, ok := args["until"].(string); ok && v != "" {
+		if until, err = history.ParseTime(v, now); err != nil {
+			return nil, err
+		}
+	}
+	if !until.After(since) {
+		return nil, fmt.Errorf("until must be after since")
+	}
+	var step time.Duration
+	if v, ok := args["step_seconds"].(float64); ok {
+		if v < 1 {
+			return nil, fmt.Errorf("step_seconds must be at least 1")
+		}
+		step = time.Duration(v) * time.Second
+	}
+
+	slog.Debug("Querying metrics history", "container", container, "metric", metric, "since", since, "until", until)
+	records, err := s.metricsHistory.Query(container, since, until)
+	if err != nil {
+		return nil, err
+	}
+	series, err := history.BuildSeries(container, metric, records, since, until, step)
+	if err != nil {
+		return nil, err
+	}
+
+	return structuredResponse(map[string]any{
+		"container":    series.Container,
+		"metric":       series.Metric,
+		"since":        series.Since,
+		"until":        series.Until,
+		"step_seconds": series.StepSeconds,
+		"points":       series.Points,
+		"summary":      series.Summary,
+		"events":       series.Events,
+	})
+}
@@ -69,12 +69,13 @@ func GetHostTools() []Tool {
 // toolListHostTools implements the list_host_tools MCP tool.
 // toolListHostToolsはlist_host_tools MCPツールを実装します。
 func (s *Server) toolListHostTools(ctx context.Context, args map[string]any) (any, error) {
-	if s.hostToolsManager == nil {
+	manager := s.hostTools()
+	if manager == nil {
 		return nil, fmt.Errorf("host tools are not configured")
 	}
 
 	slog.Debug("Listing host tools")
-	tools, err := s.hostToolsManager.ListTools()
+	tools, err := manager.ListTools()
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +90,8 @@ func (s *Server) toolListHostTools(ctx context.Context, args map[string]any) (an
 // toolGetHostToolInfo implements the get_host_tool_info MCP tool.
 // toolGetHostToolInfoはget_host_tool_info MCPツールを実装します。
 func (s *Server) toolGetHostToolInfo(ctx context.Context, args map[string]any) (any, error) {
-	if s.hostToolsManager == nil {
+	manager := s.hostTools()
+	if manager == nil {
 		return nil, fmt.Errorf("host tools are not configured")
 	}
 
@@ -99,7 +101,7 @@ func (s *Server) toolGetHostToolInfo(ctx context.Context, args map[string]any) (
 	}
 
 	slog.Debug("Getting host tool info", "name", name)
-	info, err := s.hostToolsManager.GetToolInfo(name)
+	info, err := manager.GetToolInfo(name)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +112,8 @@ func (s *Server) toolGetHostToolInfo(ctx context.Context, args map[string]any) (
 // toolRunHostTool implements the run_host_tool MCP tool.
 // toolRunHostToolはrun_host_tool MCPツールを実装します。
 func (s *Server) toolRunHostTool(ctx context.Context, args map[string]any) (any, error) {
-	if s.hostToolsManager == nil {
+	manager := s.hostTools()
+	if manager == nil {
 		return nil, fmt.Errorf("host tools are not configured")
 	}
 
@@ -131,7 +134,7 @@ func (s *Server) toolRunHostTool(ctx context.Context, args map[string]any) (any,
 	}
 
 	slog.Info("Running host tool", "name", name, "args", toolArgs)
-	result, err := s.hostToolsManager.RunTool(name, toolArgs)
+	result, err := manager.RunTool(name, toolArgs)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +180,8 @@ func GetHostCommandTools() []Tool {
 // toolExecHostCommand implements the exec_host_command MCP tool.
 // toolExecHostCommandはexec_host_command MCPツールを実装します。
 func (s *Server) toolExecHostCommand(ctx context.Context, args map[string]any) (any, error) {
-	if s.hostCommandPolicy == nil {
+	policy, workspaceRoot, timeout := s.hostCommands()
+	if policy == nil {
 		return nil, fmt.Errorf("host commands are not configured")
 	}
 
@@ -197,10 +201,10 @@ func (s *Server) toolExecHostCommand(ctx context.Context, args map[string]any) (
 	var err error
 	if dangerously {
 		slog.Warn("Executing host command (DANGEROUS MODE)", "command", command)
-		allowed, err = s.hostCommandPolicy.CanExecHostCommandDangerously(command)
+		allowed, err = policy.CanExecHostCommandDangerously(command)
 	} else {
 		slog.Info("Executing host command", "command", command)
-		allowed, err = s.hostCommandPolicy.CanExecHostCommand(command)
+		allowed, err = policy.CanExecHostCommand(command)
 	}
 
 	if err != nil {
@@ -214,13 +218,13 @@ func (s *Server) toolExecHostCommand(ctx context.Context, args map[string]any) (
 
 	// Defensive check: workspaceRoot must be set (should be caught by config.Validate)
 	// 防御的チェック: workspaceRootが設定されている必要がある（config.Validateで検出されるはず）
-	if s.workspaceRoot == "" {
+	if workspaceRoot == "" {
 		return nil, fmt.Errorf("workspace root is not configured")
 	}
 
 	// Execute the command
 	// コマンドを実行
-	result, err := hosttools.ExecHostCommand(command, s.workspaceRoot, s.hostCommandTimeout)
+	result, err := hosttools.ExecHostCommand(command, workspaceRoot, timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +91,7 @@ func TestGetTools(t *testing.T) {
 
 	// Verify the total number of tools
 	// ツールの総数を検証
-	expectedToolCount := 14
+	expectedToolCount := 30
 	if len(tools) != expectedToolCount {
 		t.Errorf("GetTools() returned %d tools, want %d", len(tools), expectedToolCount)
 	}
@@ -100,16 +100,32 @@ func TestGetTools(t *testing.T) {
 	// 期待されるすべてのツールが名前で存在することを確認
 	expectedNames := map[string]bool{
 		"list_containers":      false,
+		"list_services":        false,
 		"get_logs":             false,
+		"follow_logs":          false,
+		"correlate_logs":       false,
+		"sample_stats":         false,
 		"get_stats":            false,
+		"get_events":           false,
 		"exec_command":         false,
+		"start_exec_job":       false,
+		"get_job_output":       false,
+		"cancel_job":           false,
+		"list_jobs":            false,
 		"inspect_container":    false,
 		"get_allowed_commands": false,
 		"get_security_policy":  false,
 		"search_logs":          false,
 		"list_files":           false,
 		"read_file":            false,
+		"find_files":           false,
+		"grep_files":           false,
+		"write_file":           false,
+		"apply_patch":          false,
+		"copy_from_container":  false,
+		"copy_to_container":    false,
 		"get_blocked_paths":    false,
+		"explain_policy":       false,
 		"restart_container":    false,
 		"stop_container":       false,
 		"start_container":      false,
@@ -740,6 +756,40 @@ func TestReadFileTool_Structure(t *testing.T) {
 	}
 }
 
+// TestToolOutputSchemas verifies which tools declare an outputSchema and that every
+// required result field is a declared property.
+//
+// TestToolOutputSchemasはどのツールがoutputSchemaを宣言するか、またすべての必須の
+// 結果フィールドが宣言されたプロパティであることを検証します。
+func TestToolOutputSchemas(t *testing.T) {
+	structured := map[string]bool{
+		"list_containers": true, "get_stats": true, "sample_stats": true, "exec_command": true, "inspect_container": true,
+		"search_logs": true, "list_files": true, "read_file": true, "find_files": true, "grep_files": true,
+		"write_file": true, "apply_patch": true, "copy_from_container": true, "copy_to_container": true,
+		"get_events": true, "query_metrics": true, "start_exec_job": true, "get_job_output": true,
+		"cancel_job": true, "list_jobs": true,
+	}
+
+	for _, tool := range append(GetTools(), GetMetricsHistoryTools()...) {
+		schema := tool.OutputSchema
+		if !structured[tool.Name] {
+			if schema != nil {
+				t.Errorf("%s declares an outputSchema but returns text only", tool.Name)
+			}
+			continue
+		}
+		if schema == nil || schema.Type != "object" {
+			t.Errorf("%s should declare an object outputSchema, got %+v", tool.Name, schema)
+			continue
+		}
+		for _, name := range schema.Required {
+			if _, ok := schema.Properties[name]; !ok {
+				t.Errorf("%s requires undeclared result field %q", tool.Name, name)
+			}
+		}
+	}
+}
+
 // TestGetBlockedPathsTool_Structure verifies that the get_blocked_paths tool
 // has the correct structure with an optional "container" parameter.
 //
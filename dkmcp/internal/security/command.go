@@ -0,0 +1,25 @@
+// command.go exposes the shell-word parser shared by policy checks and execution.
+// exec_command never runs a shell: the command string is split into an argv once, and
+// the same argv is matched against exec_whitelist, checked for blocked paths and passed
+// to Docker, so the policy and the executed command cannot disagree.
+//
+// command.goはポリシーチェックと実行で共有するシェル単語パーサーを公開します。
+// exec_commandはシェルを実行しません：コマンド文字列は一度だけargvに分割され、
+// 同じargvがexec_whitelistと照合され、ブロックパスのチェックを受け、Dockerに渡されるため、
+// ポリシーと実行されるコマンドが食い違うことはありません。
+package security
+
+import "github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/cmdrule"
+
+// ParseCommand splits a command string into arguments the way a POSIX shell splits
+// words, without any expansion ($VAR, globs and command substitution stay literal).
+// See cmdrule.Split for the supported syntax; an unclosed quote or a trailing backslash
+// is an error.
+//
+// ParseCommandはPOSIXシェルの単語分割と同じようにコマンド文字列を引数に分割しますが、
+// 展開は一切行いません（$VAR、glob、コマンド置換はリテラルのまま）。
+// サポートされる構文はcmdrule.Splitを参照してください。閉じられていない引用符や
+// 末尾のバックスラッシュはエラーです。
+func ParseCommand(command string) ([]string, error) {
+	return cmdrule.Split(command)
+}
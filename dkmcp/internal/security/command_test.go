@@ -0,0 +1,150 @@
+// command_test.go contains tests for the shared command parser and exec authorization.
+// command_test.goは共有コマンドパーサーとexecの認可のテストを含みます。
+package security
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// TestParseCommand tests the command argument parsing with quotes and escapes.
+// TestParseCommandは引用符とエスケープを使用したコマンド引数解析をテストします。
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name    string   // Test case name / テストケース名
+		command string   // Input command / 入力コマンド
+		want    []string // Expected arguments / 期待される引数
+	}{
+		// Simple cases / シンプルなケース
+		{"empty command", "", nil},
+		{"single word", "echo", []string{"echo"}},
+		{"simple command", "tail -f /var/log/app.log", []string{"tail", "-f", "/var/log/app.log"}},
+		{"multiple spaces", "cat   file.txt", []string{"cat", "file.txt"}},
+		{"leading/trailing spaces", "  npm test  ", []string{"npm", "test"}},
+		{"tabs and newlines", "npm\ttest\n--watch", []string{"npm", "test", "--watch"}},
+
+		// Double quoted strings / ダブルクォート文字列
+		{"double quoted arg", `echo "hello world"`, []string{"echo", "hello world"}},
+		{"double quoted with spaces", `grep "error message" file`, []string{"grep", "error message", "file"}},
+		{"multiple double quoted", `echo "foo bar" "baz qux"`, []string{"echo", "foo bar", "baz qux"}},
+		{"empty double quoted", `echo "" file`, []string{"echo", "", "file"}},
+
+		// Single quoted strings / シングルクォート文字列
+		{"single quoted arg", `echo 'hello world'`, []string{"echo", "hello world"}},
+		{"single quoted with spaces", `grep 'error message' file`, []string{"grep", "error message", "file"}},
+		{"multiple single quoted", `echo 'foo bar' 'baz qux'`, []string{"echo", "foo bar", "baz qux"}},
+
+		// Mixed quotes / 混合クォート
+		{"mixed quotes", `echo 'single' "double"`, []string{"echo", "single", "double"}},
+		{"adjacent quoted", `echo "foo"'bar'`, []string{"echo", "foobar"}},
+
+		// Escaped characters / エスケープ文字
+		{"escaped space", `echo hello\ world`, []string{"echo", "hello world"}},
+		{"escaped backslash", `echo foo\\bar`, []string{"echo", `foo\bar`}},
+		{"escaped quote", `echo "hello \"world\""`, []string{"echo", `hello "world"`}},
+		{"escaped single in double", `echo "it's fine"`, []string{"echo", "it's fine"}},
+
+		// Paths with spaces / スペース付きパス
+		{"quoted path", `cat "/path/with spaces/file.txt"`, []string{"cat", "/path/with spaces/file.txt"}},
+		{"escaped path", `cat /path/with\ spaces/file.txt`, []string{"cat", "/path/with spaces/file.txt"}},
+
+		// Complex cases / 複雑なケース
+		{"json in quotes", `curl -d '{"key":"value"}'`, []string{"curl", "-d", `{"key":"value"}`}},
+		{"grep pattern", `grep "error\|warn" /var/log/app.log`, []string{"grep", `error\|warn`, "/var/log/app.log"}},
+		{"tar command", `tar -czvf "backup file.tar.gz" dir1 dir2`, []string{"tar", "-czvf", "backup file.tar.gz", "dir1", "dir2"}},
+
+		// No expansion / 展開なし
+		{"variable stays literal", `echo $HOME "$PATH"`, []string{"echo", "$HOME", "$PATH"}},
+		{"substitution stays literal", `echo "$(id)" *.log`, []string{"echo", "$(id)", "*.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommand(tt.command)
+			if err != nil {
+				t.Fatalf("ParseCommand(%q) error = %v", tt.command, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommand(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseCommand_Errors tests that malformed commands are rejected rather than guessed at.
+// TestParseCommand_Errorsは不正なコマンドが推測されずに拒否されることをテストします。
+func TestParseCommand_Errors(t *testing.T) {
+	for _, command := range []string{
+		`grep "connection refused /var/log/app.log`,
+		`echo 'unterminated`,
+		`cat /app/file\`,
+	} {
+		if args, err := ParseCommand(command); err == nil {
+			t.Errorf("ParseCommand(%q) = %q, want error", command, args)
+		}
+	}
+}
+
+// TestAuthorizeExec tests that the argv returned for execution is exactly the argv the
+// whitelist and blocked paths were checked against, so the policy and execution cannot
+// disagree about what a command means.
+//
+// TestAuthorizeExecは実行用に返されるargvが、ホワイトリストとブロックパスのチェック対象と
+// 完全に同じargvであり、ポリシーと実行がコマンドの意味について食い違わないことをテストします。
+func TestAuthorizeExec(t *testing.T) {
+	policy := NewPolicy(&config.SecurityConfig{
+		Mode:        "moderate",
+		Permissions: config.SecurityPermissions{Exec: true},
+		ExecWhitelist: map[string][]string{
+			"api": {`grep "connection refused" /var/log/app.log`, "echo *"},
+		},
+		ExecDangerously: config.ExecDangerouslyConfig{
+			Enabled:  true,
+			Commands: map[string][]string{"*": {"cat"}},
+		},
+		BlockedPaths: config.BlockedPathsConfig{
+			Manual: map[string][]string{"api": {"/app/my secrets.env"}},
+		},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatalf("InitBlockedPaths failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string   // Test case name / テストケース名
+		command     string   // Command / コマンド
+		dangerously bool     // Dangerous mode / 危険モード
+		want        []string // Expected argv (nil when denied) / 期待されるargv（拒否時はnil）
+	}{
+		{"quoted argument", `grep "connection refused" /var/log/app.log`, false,
+			[]string{"grep", "connection refused", "/var/log/app.log"}},
+		{"same argv with other quoting", `grep connection\ refused '/var/log/app.log'`, false,
+			[]string{"grep", "connection refused", "/var/log/app.log"}},
+		{"split argument is a different command", `grep connection refused /var/log/app.log`, false, nil},
+		{"wildcard keeps quoted argument whole", `echo "a b" c`, false, []string{"echo", "a b", "c"}},
+		{"unclosed quote", `echo "a b`, false, nil},
+		{"quoted blocked path", `cat "/app/my secrets.env"`, true, nil},
+		{"escaped blocked path", `cat /app/my\ secrets.env`, true, nil},
+		{"allowed dangerous command", `cat "/app/my notes.txt"`, true, []string{"cat", "/app/my notes.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.AuthorizeExec("api", tt.command, tt.dangerously)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("AuthorizeExec(%q) = %q, want denied", tt.command, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthorizeExec(%q) error = %v", tt.command, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AuthorizeExec(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
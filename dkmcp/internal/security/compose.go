@@ -0,0 +1,183 @@
+// compose.go lets policy entries address Docker Compose containers by service.
+// Compose names containers "<project>-<service>-<n>", so scaling a service or renaming
+// the project changes the names. Entries in allowed_containers, exec_whitelist,
+// exec_dangerously and blocked_paths may therefore use the container name, the service
+// name, or "project/service"; the Compose labels of known containers map these to
+// the actual containers.
+//
+// compose.goはポリシーのエントリがDocker Composeのコンテナをサービスで指定できるようにします。
+// Composeはコンテナを"<project>-<service>-<n>"と命名するため、サービスのスケールや
+// プロジェクト名の変更で名前が変わります。そのためallowed_containers、exec_whitelist、
+// exec_dangerously、blocked_pathsのエントリには、コンテナ名、サービス名、
+// または"project/service"を使用できます。既知のコンテナのComposeラベルにより、
+// これらを実際のコンテナに対応付けます。
+package security
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Docker Compose labels set on every container Compose creates.
+// Composeが作成するすべてのコンテナに設定されるDocker Composeのラベルです。
+const (
+	ComposeProjectLabel = "com.docker.compose.project"
+	ComposeServiceLabel = "com.docker.compose.service"
+	ComposeNumberLabel  = "com.docker.compose.container-number"
+)
+
+// ContainerIdentity describes how a container can be referred to.
+// ContainerIdentityはコンテナをどのように参照できるかを表します。
+type ContainerIdentity struct {
+	// Name is the container name without the leading slash
+	// Nameは先頭のスラッシュを除いたコンテナ名です
+	Name string
+
+	// Project is the Compose project name (empty for standalone containers)
+	// ProjectはComposeのプロジェクト名です（単独のコンテナでは空）
+	Project string
+
+	// Service is the Compose service name (empty for standalone containers)
+	// ServiceはComposeのサービス名です（単独のコンテナでは空）
+	Service string
+
+	// Number is the replica number within the service (0 when unknown)
+	// Numberはサービス内のレプリカ番号です（不明な場合は0）
+	Number int
+
+	// Labels are the container labels, used by allowed_labels and denied_labels
+	// Labelsはコンテナのラベルで、allowed_labelsとdenied_labelsで使用されます
+	Labels map[string]string
+}
+
+// NewContainerIdentity builds the identity of a container from its name and labels.
+// NewContainerIdentityはコンテナの名前とラベルからIDを構築します。
+func NewContainerIdentity(name string, labels map[string]string) ContainerIdentity {
+	id := ContainerIdentity{
+		Name:    name,
+		Project: labels[ComposeProjectLabel],
+		Service: labels[ComposeServiceLabel],
+		Labels:  labels,
+	}
+	id.Number, _ = strconv.Atoi(labels[ComposeNumberLabel])
+	return id
+}
+
+// IsCompose reports whether the container belongs to a Compose service.
+// IsComposeはコンテナがComposeのサービスに属するかどうかを報告します。
+func (id ContainerIdentity) IsCompose() bool {
+	return id.Service != ""
+}
+
+// ServiceRef returns "project/service" for Compose containers, or "" otherwise.
+// ServiceRefはComposeのコンテナでは"project/service"を、それ以外では""を返します。
+func (id ContainerIdentity) ServiceRef() string {
+	if !id.IsCompose() {
+		return ""
+	}
+	return id.Project + "/" + id.Service
+}
+
+// Aliases returns the names policy entries can use for the container, most specific
+// first: the container name, then "project/service" and "service" for Compose containers.
+//
+// Aliasesはポリシーのエントリがコンテナに使用できる名前を、具体的なものから順に返します：
+// コンテナ名、次にComposeのコンテナでは"project/service"と"service"。
+func (id ContainerIdentity) Aliases() []string {
+	if !id.IsCompose() {
+		return []string{id.Name}
+	}
+	return []string{id.Name, id.ServiceRef(), id.Service}
+}
+
+// MatchesRef reports whether ref addresses this container by service or project/service.
+// MatchesRefはrefがサービスまたはproject/serviceでこのコンテナを指しているかを報告します。
+func (id ContainerIdentity) MatchesRef(ref string) bool {
+	return id.IsCompose() && (ref == id.Service || ref == id.ServiceRef())
+}
+
+// containerRegistry holds the identities of the containers seen on the Docker host.
+// Its zero value is ready to use.
+//
+// containerRegistryはDockerホスト上で確認されたコンテナのIDを保持します。
+// ゼロ値のまま使用できます。
+type containerRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]ContainerIdentity
+}
+
+// SetContainerIdentities replaces the known containers used to resolve Compose
+// service references in policy entries. The Docker client calls it whenever it
+// lists containers, so the mapping follows scaling and project renames.
+//
+// SetContainerIdentitiesはポリシーのエントリ内のComposeサービス参照を解決するために
+// 使用する既知のコンテナを置き換えます。Dockerクライアントはコンテナを一覧するたびに
+// これを呼び出すため、対応付けはスケールやプロジェクト名の変更に追従します。
+func (p *Policy) SetContainerIdentities(ids []ContainerIdentity) {
+	byName := make(map[string]ContainerIdentity, len(ids))
+	for _, id := range ids {
+		byName[id.Name] = id
+	}
+	p.containers.mu.Lock()
+	p.containers.byName = byName
+	p.containers.mu.Unlock()
+}
+
+// ResolveContainer returns the identity for a container reference, which may be a
+// container name, a service name or "project/service". A service reference resolves to
+// its lowest-numbered replica. Unknown references are treated as plain container names.
+//
+// ResolveContainerはコンテナ参照（コンテナ名、サービス名、または"project/service"）の
+// IDを返します。サービス参照は最も番号の小さいレプリカに解決されます。
+// 不明な参照は単なるコンテナ名として扱われます。
+func (p *Policy) ResolveContainer(ref string) ContainerIdentity {
+	p.containers.mu.RLock()
+	defer p.containers.mu.RUnlock()
+
+	if id, ok := p.containers.byName[ref]; ok {
+		return id
+	}
+
+	var replicas []ContainerIdentity
+	for _, id := range p.containers.byName {
+		if id.MatchesRef(ref) {
+			replicas = append(replicas, id)
+		}
+	}
+	if len(replicas) == 0 {
+		return ContainerIdentity{Name: ref}
+	}
+	SortReplicas(replicas)
+	return replicas[0]
+}
+
+// containerAliases returns every name a policy entry may use for the referenced container.
+// containerAliasesは参照されたコンテナに対してポリシーのエントリが使用できるすべての名前を返します。
+func (p *Policy) containerAliases(ref string) []string {
+	return p.ResolveContainer(ref).Aliases()
+}
+
+// containerEntries collects the values of a per-container map (such as exec_whitelist)
+// for every alias of the container, excluding the "*" entry.
+//
+// containerEntriesはコンテナ単位のマップ（exec_whitelistなど）の値を、コンテナの
+// すべての別名について収集します。"*"エントリは含みません。
+func (p *Policy) containerEntries(entries map[string][]string, containerName string) []string {
+	var result []string
+	for _, alias := range p.containerAliases(containerName) {
+		result = append(result, entries[alias]...)
+	}
+	return result
+}
+
+// SortReplicas orders replicas by replica number, then by name.
+// SortReplicasはレプリカをレプリカ番号、次に名前の順に並べます。
+func SortReplicas(replicas []ContainerIdentity) {
+	sort.Slice(replicas, func(i, j int) bool {
+		if replicas[i].Number != replicas[j].Number {
+			return replicas[i].Number < replicas[j].Number
+		}
+		return replicas[i].Name < replicas[j].Name
+	})
+}
@@ -0,0 +1,164 @@
+// compose_test.go contains tests for addressing Docker Compose containers by service
+// in policy entries.
+//
+// compose_test.goはポリシーのエントリでDocker Composeのコンテナをサービスで
+// 指定する機能のテストを含みます。
+package security
+
+import (
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// composeLabels returns the labels Compose sets on a service replica.
+// composeLabelsはComposeがサービスのレプリカに設定するラベルを返します。
+func composeLabels(project, service, number string) map[string]string {
+	return map[string]string{
+		ComposeProjectLabel: project,
+		ComposeServiceLabel: service,
+		ComposeNumberLabel:  number,
+	}
+}
+
+// newComposePolicy creates a policy that knows a scaled "shop" project.
+// newComposePolicyはスケールされた"shop"プロジェクトを把握しているポリシーを作成します。
+func newComposePolicy(cfg *config.SecurityConfig) *Policy {
+	policy := NewPolicy(cfg)
+	policy.SetContainerIdentities([]ContainerIdentity{
+		NewContainerIdentity("shop-api-2", composeLabels("shop", "api", "2")),
+		NewContainerIdentity("shop-api-1", composeLabels("shop", "api", "1")),
+		NewContainerIdentity("shop-db-1", composeLabels("shop", "db", "1")),
+		NewContainerIdentity("legacy", nil),
+	})
+	return policy
+}
+
+// TestContainerIdentity_Aliases tests the names a container can be addressed by.
+// TestContainerIdentity_Aliasesはコンテナを指定できる名前をテストします。
+func TestContainerIdentity_Aliases(t *testing.T) {
+	id := NewContainerIdentity("shop-api-1", composeLabels("shop", "api", "1"))
+	want := []string{"shop-api-1", "shop/api", "api"}
+	got := id.Aliases()
+	if len(got) != len(want) {
+		t.Fatalf("Aliases() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Aliases()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if aliases := NewContainerIdentity("legacy", nil).Aliases(); len(aliases) != 1 || aliases[0] != "legacy" {
+		t.Errorf("standalone container should only have its name, got %v", aliases)
+	}
+}
+
+// TestResolveContainer tests resolving service references to replicas.
+// TestResolveContainerはサービス参照のレプリカへの解決をテストします。
+func TestResolveContainer(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{})
+
+	tests := []struct {
+		ref  string // Container reference / コンテナ参照
+		want string // Expected container name / 期待されるコンテナ名
+	}{
+		{"shop-api-2", "shop-api-2"},
+		{"api", "shop-api-1"},
+		{"shop/api", "shop-api-1"},
+		{"shop/db", "shop-db-1"},
+		{"other/api", "other/api"},
+		{"unknown", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := policy.ResolveContainer(tt.ref).Name; got != tt.want {
+			t.Errorf("ResolveContainer(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+// TestCanAccessContainer_ComposeService tests allowed_containers entries naming services.
+// TestCanAccessContainer_ComposeServiceはサービスを指定するallowed_containersのエントリをテストします。
+func TestCanAccessContainer_ComposeService(t *testing.T) {
+	tests := []struct {
+		name      string   // Test case name / テストケース名
+		allowed   []string // allowed_containers / 許可コンテナ
+		container string   // Container reference / コンテナ参照
+		want      bool     // Expected result / 期待される結果
+	}{
+		{"service name matches every replica", []string{"api"}, "shop-api-2", true},
+		{"project/service", []string{"shop/api"}, "shop-api-1", true},
+		{"project wildcard", []string{"shop/*"}, "shop-db-1", true},
+		{"other service denied", []string{"api"}, "shop-db-1", false},
+		{"other project denied", []string{"blog/*"}, "shop-api-1", false},
+		{"service reference", []string{"shop-api-*"}, "api", true},
+		{"standalone by name only", []string{"api"}, "legacy", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newComposePolicy(&config.SecurityConfig{AllowedContainers: tt.allowed})
+			if got := policy.CanAccessContainer(tt.container); got != tt.want {
+				t.Errorf("CanAccessContainer(%q) = %v, want %v", tt.container, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExecWhitelist_ComposeService tests exec_whitelist and exec_dangerously keyed by service.
+// TestExecWhitelist_ComposeServiceはサービスをキーとするexec_whitelistとexec_dangerouslyをテストします。
+func TestExecWhitelist_ComposeService(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		Mode:        "moderate",
+		Permissions: config.SecurityPermissions{Exec: true},
+		ExecWhitelist: map[string][]string{
+			"api":     {"npm test"},
+			"shop/db": {"pg_isready"},
+		},
+		ExecDangerously: config.ExecDangerouslyConfig{
+			Commands: map[string][]string{"api": {"tail"}},
+		},
+	})
+
+	if ok, err := policy.CanExec("shop-api-2", "npm test"); !ok {
+		t.Errorf("expected npm test to be allowed on shop-api-2: %v", err)
+	}
+	if ok, err := policy.CanExec("shop-db-1", "pg_isready"); !ok {
+		t.Errorf("expected pg_isready to be allowed on shop-db-1: %v", err)
+	}
+	if ok, _ := policy.CanExec("shop-db-1", "npm test"); ok {
+		t.Error("expected npm test to be denied on shop-db-1")
+	}
+	if cmds := policy.GetAllowedCommands("shop-api-1"); len(cmds) != 1 || cmds[0] != "npm test" {
+		t.Errorf("GetAllowedCommands(shop-api-1) = %v, want [npm test]", cmds)
+	}
+	if cmds := policy.GetDangerousCommandsForContainer("shop-api-1"); len(cmds) != 1 || cmds[0] != "tail" {
+		t.Errorf("GetDangerousCommandsForContainer(shop-api-1) = %v, want [tail]", cmds)
+	}
+}
+
+// TestIsPathBlocked_ComposeService tests blocked paths configured for a service.
+// TestIsPathBlocked_ComposeServiceはサービスに設定されたブロックパスをテストします。
+func TestIsPathBlocked_ComposeService(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		BlockedPaths: config.BlockedPathsConfig{
+			Manual: map[string][]string{
+				"shop/api": {"/app/.env"},
+				"*":        {"*.key"},
+			},
+		},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatalf("InitBlockedPaths failed: %v", err)
+	}
+
+	if policy.IsPathBlocked("shop-api-2", "/app/.env") == nil {
+		t.Error("expected /app/.env to be blocked on shop-api-2")
+	}
+	if policy.IsPathBlocked("shop-db-1", "/app/.env") != nil {
+		t.Error("expected /app/.env not to be blocked on shop-db-1")
+	}
+	if got := len(policy.GetBlockedPathsForContainer("shop-api-1")); got != 2 {
+		t.Errorf("GetBlockedPathsForContainer(shop-api-1) returned %d paths, want 2", got)
+	}
+}
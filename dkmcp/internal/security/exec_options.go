@@ -0,0 +1,192 @@
+// exec_options.go decides which working directory, environment variables and user a
+// command may run with. Commands run with the container's defaults unless the values
+// requested are listed in exec_options for the container, so an allowed command such as
+// "npm test" can be pointed at a package directory without also allowing it to run as
+// root or with an arbitrary NODE_OPTIONS.
+//
+// exec_options.goはコマンドを実行できる作業ディレクトリ、環境変数、ユーザーを決定します。
+// 要求された値がコンテナのexec_optionsに列挙されていない限り、コマンドはコンテナの
+// デフォルトで実行されます。これにより"npm test"のような許可されたコマンドを、rootとして
+// 実行したり任意のNODE_OPTIONSを付けたりすることは許可せずに、パッケージディレクトリに
+// 向けることができます。
+package security
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// ExecOptions are the working directory, environment and user requested for a command.
+// Zero values keep the container's defaults.
+//
+// ExecOptionsはコマンドに要求された作業ディレクトリ、環境、ユーザーです。
+// ゼロ値はコンテナのデフォルトのままにします。
+type ExecOptions struct {
+	// Workdir is the absolute working directory inside the container.
+	// Workdirはコンテナ内の絶対パスの作業ディレクトリです。
+	Workdir string
+
+	// Env holds the environment variables to set, by name.
+	// Envは設定する環境変数を名前をキーとして保持します。
+	Env map[string]string
+
+	// User is the user to run as, as given to docker exec --user.
+	// Userは実行するユーザーで、docker exec --userに渡す形式です。
+	User string
+}
+
+// EnvList returns Env as sorted "NAME=value" entries.
+// EnvListはEnvをソートされた"NAME=value"エントリとして返します。
+func (o ExecOptions) EnvList() []string {
+	env := make([]string, 0, len(o.Env))
+	for name, value := range o.Env {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// AllowedExecOptions returns the exec_options values that apply to a container,
+// including the "*" entry shared by all containers.
+//
+// AllowedExecOptionsはコンテナに適用されるexec_optionsの値を、すべてのコンテナで
+// 共有される"*"のエントリを含めて返します。
+func (p *Policy) AllowedExecOptions(containerName string) config.ExecOptionsConfig {
+	var allowed config.ExecOptionsConfig
+	keys := append(p.containerAliases(containerName), "*")
+	for _, key := range keys {
+		opts, ok := p.config.ExecOptions[key]
+		if !ok {
+			continue
+		}
+		allowed.Workdirs = append(allowed.Workdirs, opts.Workdirs...)
+		allowed.Env = append(allowed.Env, opts.Env...)
+		allowed.Users = append(allowed.Users, opts.Users...)
+	}
+	return allowed
+}
+
+// ExecOptionsSummary returns the exec_options values of a container keyed by
+// "workdirs", "env" and "users", for get_allowed_commands. It is nil when the
+// container has none.
+//
+// ExecOptionsSummaryはget_allowed_commands用に、コンテナのexec_optionsの値を
+// "workdirs"、"env"、"users"をキーとして返します。値がない場合はnilです。
+func (p *Policy) ExecOptionsSummary(containerName string) map[string][]string {
+	return execOptionsMap(p.AllowedExecOptions(containerName))
+}
+
+// AllExecOptions returns every exec_options entry in the form of ExecOptionsSummary,
+// keyed like the configuration ("*" = all containers).
+//
+// AllExecOptionsはすべてのexec_optionsのエントリをExecOptionsSummaryの形式で、
+// 設定と同じキー（"*" = すべてのコンテナ）で返します。
+func (p *Policy) AllExecOptions() map[string]map[string][]string {
+	result := make(map[string]map[string][]string, len(p.config.ExecOptions))
+	for key, opts := range p.config.ExecOptions {
+		if summary := execOptionsMap(opts); summary != nil {
+			result[key] = summary
+		}
+	}
+	return result
+}
+
+// execOptionsMap converts exec_options values to the map form used in tool results,
+// leaving out empty lists.
+//
+// execOptionsMapはexec_optionsの値をツールの結果で使用するマップ形式に変換します。
+// 空のリストは含めません。
+func execOptionsMap(opts config.ExecOptionsConfig) map[string][]string {
+	var result map[string][]string
+	for key, values := range map[string][]string{"workdirs": opts.Workdirs, "env": opts.Env, "users": opts.Users} {
+		if len(values) == 0 {
+			continue
+		}
+		if result == nil {
+			result = make(map[string][]string)
+		}
+		result[key] = values
+	}
+	return result
+}
+
+// CheckExecOptions checks the requested options against the container's exec_options
+// and returns them normalized (the working directory cleaned). Callers must run the
+// command with the returned options.
+//
+// CheckExecOptionsは要求されたオプションをコンテナのexec_optionsに対してチェックし、
+// 正規化した（作業ディレクトリを整理した）ものを返します。呼び出し元は返された
+// オプションでコマンドを実行する必要があります。
+func (p *Policy) CheckExecOptions(containerName string, opts ExecOptions) (ExecOptions, error) {
+	allowed := p.AllowedExecOptions(containerName)
+
+	if opts.Workdir != "" {
+		if !strings.HasPrefix(opts.Workdir, "/") {
+			return ExecOptions{}, fmt.Errorf("workdir must be an absolute path: %s", opts.Workdir)
+		}
+		opts.Workdir = path.Clean(opts.Workdir)
+		if !workdirAllowed(allowed.Workdirs, opts.Workdir) {
+			return ExecOptions{}, fmt.Errorf("workdir %s is not allowed by exec_options for container %s", opts.Workdir, containerName)
+		}
+	}
+
+	for name, value := range opts.Env {
+		if err := config.ValidateEnvName(name); err != nil {
+			return ExecOptions{}, err
+		}
+		if !envAllowed(allowed.Env, name, value) {
+			return ExecOptions{}, fmt.Errorf("environment variable %s=%s is not allowed by exec_options for container %s", name, value, containerName)
+		}
+	}
+
+	if opts.User != "" && !containsString(allowed.Users, opts.User) {
+		return ExecOptions{}, fmt.Errorf("user %s is not allowed by exec_options for container %s", opts.User, containerName)
+	}
+	return opts, nil
+}
+
+// workdirAllowed reports whether dir equals a workdirs entry or matches a glob pattern.
+// workdirAllowedはdirがworkdirsのエントリと等しいか、globパターンにマッチするかを報告します。
+func workdirAllowed(entries []string, dir string) bool {
+	for _, entry := range entries {
+		if strings.ContainsAny(entry, "*?[") {
+			if matched, err := path.Match(entry, dir); err == nil && matched {
+				return true
+			}
+			continue
+		}
+		if path.Clean(entry) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// envAllowed reports whether an env entry allows name=value: "NAME" allows any value,
+// "NAME=value" only that value.
+//
+// envAllowedはenvのエントリがname=valueを許可するかどうかを報告します："NAME"は
+// 任意の値を、"NAME=value"はその値のみを許可します。
+func envAllowed(entries []string, name, value string) bool {
+	for _, entry := range entries {
+		if entry == name || entry == name+"="+value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether list contains s.
+// containsStringはlistがsを含むかどうかを報告します。
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+// exec_options_test.go contains tests for the working directory, environment and user of execs.
+// exec_options_test.goはexecの作業ディレクトリ、環境、ユーザーのテストを含みます。
+package security
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// TestCheckExecOptions tests checking requested options against exec_options.
+// TestCheckExecOptionsは要求されたオプションのexec_optionsに対するチェックをテストします。
+func TestCheckExecOptions(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		ExecOptions: map[string]config.ExecOptionsConfig{
+			"api": {
+				Workdirs: []string{"/app", "/app/packages/*"},
+				Env:      []string{"NODE_ENV", "CI=true"},
+				Users:    []string{"node"},
+			},
+			"*": {Workdirs: []string{"/tmp"}},
+		},
+	})
+
+	tests := []struct {
+		name        string      // Test case name / テストケース名
+		container   string      // Container name / コンテナ名
+		opts        ExecOptions // Requested options / 要求されたオプション
+		wantWorkdir string      // Expected normalized workdir / 期待される正規化された作業ディレクトリ
+		wantErr     string      // Expected error substring ("" = allowed) / 期待されるエラーの部分文字列
+	}{
+		{"defaults", "shop-db-1", ExecOptions{}, "", ""},
+		{"exact workdir", "shop-api-1", ExecOptions{Workdir: "/app"}, "/app", ""},
+		{"trailing slash is cleaned", "shop-api-1", ExecOptions{Workdir: "/app/"}, "/app", ""},
+		{"glob workdir", "shop-api-2", ExecOptions{Workdir: "/app/packages/web"}, "/app/packages/web", ""},
+		{"glob does not cross directories", "shop-api-1", ExecOptions{Workdir: "/app/packages/web/src"}, "", "workdir /app/packages/web/src is not allowed"},
+		{"dot dot is cleaned", "shop-api-1", ExecOptions{Workdir: "/app/../etc"}, "", "workdir /etc is not allowed"},
+		{"relative workdir", "shop-api-1", ExecOptions{Workdir: "app"}, "", "absolute path"},
+		{"global workdir", "shop-db-1", ExecOptions{Workdir: "/tmp"}, "/tmp", ""},
+		{"other container's workdir", "shop-db-1", ExecOptions{Workdir: "/app"}, "", "not allowed"},
+		{"env with any value", "shop-api-1", ExecOptions{Env: map[string]string{"NODE_ENV": "test"}}, "", ""},
+		{"env with the allowed value", "shop-api-1", ExecOptions{Env: map[string]string{"CI": "true"}}, "", ""},
+		{"env with another value", "shop-api-1", ExecOptions{Env: map[string]string{"CI": "false"}}, "", "CI=false is not allowed"},
+		{"env not listed", "shop-api-1", ExecOptions{Env: map[string]string{"NODE_OPTIONS": "--inspect"}}, "", "not allowed"},
+		{"reserved env", "shop-api-1", ExecOptions{Env: map[string]string{"DKMCP_EXEC_ID": "x"}}, "", "reserved"},
+		{"allowed user", "shop-api-1", ExecOptions{User: "node"}, "", ""},
+		{"user not listed", "shop-api-1", ExecOptions{User: "root"}, "", "user root is not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.CheckExecOptions(tt.container, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("CheckExecOptions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckExecOptions() error = %v", err)
+			}
+			if got.Workdir != tt.wantWorkdir {
+				t.Errorf("Workdir = %q, want %q", got.Workdir, tt.wantWorkdir)
+			}
+		})
+	}
+}
+
+// TestAllowedExecOptions tests collecting exec_options for a container and EnvList.
+// TestAllowedExecOptionsはコンテナのexec_optionsの収集とEnvListをテストします。
+func TestAllowedExecOptions(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		ExecOptions: map[string]config.ExecOptionsConfig{
+			"api": {Workdirs: []string{"/app"}, Users: []string{"node"}},
+			"*":   {Workdirs: []string{"/tmp"}, Env: []string{"CI"}},
+		},
+	})
+
+	got := policy.AllowedExecOptions("shop-api-1")
+	want := config.ExecOptionsConfig{Workdirs: []string{"/app", "/tmp"}, Env: []string{"CI"}, Users: []string{"node"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AllowedExecOptions() = %+v, want %+v", got, want)
+	}
+
+	summary := policy.ExecOptionsSummary("shop-db-1")
+	if !reflect.DeepEqual(summary, map[string][]string{"workdirs": {"/tmp"}, "env": {"CI"}}) {
+		t.Errorf("ExecOptionsSummary() = %v", summary)
+	}
+	policy = newComposePolicy(&config.SecurityConfig{})
+	if summary := policy.ExecOptionsSummary("shop-db-1"); summary != nil {
+		t.Errorf("ExecOptionsSummary() without exec_options = %v, want nil", summary)
+	}
+
+	env := ExecOptions{Env: map[string]string{"NODE_ENV": "test", "CI": "true"}}.EnvList()
+	if !reflect.DeepEqual(env, []string{"CI=true", "NODE_ENV=test"}) {
+		t.Errorf("EnvList() = %v", env)
+	}
+}
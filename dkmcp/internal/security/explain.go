@@ -0,0 +1,219 @@
+// explain.go implements a dry run of the policy checks: for an exec command or a file
+// path it runs the same checks as exec_command, read_file and list_files without
+// touching the container, and reports the rule that decided the outcome (the
+// exec_whitelist or exec_dangerously entry, or the blocked path and the file it came
+// from). It backs the explain_policy tool and the 'policy check' command.
+//
+// explain.goはポリシーチェックのドライランを実装します：execコマンドやファイルパスに対して、
+// コンテナに触れずにexec_command、read_file、list_filesと同じチェックを実行し、
+// 結果を決めたルール（exec_whitelistやexec_dangerouslyのエントリ、またはブロックパスと
+// その定義元ファイル）を報告します。explain_policyツールと'policy check'コマンドで使用されます。
+package security
+
+import "fmt"
+
+// Policy lists a RuleMatch can come from.
+// RuleMatchの出所となるポリシーのリストです。
+const (
+	RuleListExecWhitelist   = "exec_whitelist"
+	RuleListExecDangerously = "exec_dangerously"
+	RuleListMode            = "mode"
+)
+
+// RuleMatch identifies the policy entry that matched a command.
+// RuleMatchはコマンドにマッチしたポリシーのエントリを識別します。
+type RuleMatch struct {
+	// List is one of the RuleList constants
+	// ListはRuleList定数のいずれかです
+	List string `json:"list"`
+
+	// Key is the container key of the entry: a container name, service, project/service or "*"
+	// Keyはエントリのコンテナキーです：コンテナ名、サービス、project/service、または"*"
+	Key string `json:"key,omitempty"`
+
+	// Entry is the matching command pattern (or the security mode for RuleListMode)
+	// Entryはマッチしたコマンドパターンです（RuleListModeの場合はセキュリティモード）
+	Entry string `json:"entry"`
+}
+
+// String returns the rule in configuration syntax (e.g., `exec_whitelist[api]: "npm test"`).
+// Stringはルールを設定の記法で返します（例: `exec_whitelist[api]: "npm test"`）。
+func (r RuleMatch) String() string {
+	if r.List == RuleListMode {
+		return fmt.Sprintf("mode: %s", r.Entry)
+	}
+	return fmt.Sprintf("%s[%s]: %q", r.List, r.Key, r.Entry)
+}
+
+// Explanation is the result of a policy dry run.
+// Explanationはポリシーのドライランの結果です。
+type Explanation struct {
+	// Container is the container name the reference resolved to
+	// Containerは参照が解決されたコンテナ名です
+	Container string `json:"container"`
+
+	// Command is the checked exec command (empty for path checks)
+	// Commandはチェックしたexecコマンドです（パスのチェックでは空）
+	Command string `json:"command,omitempty"`
+
+	// Dangerously reports whether the command was checked in dangerous mode
+	// Dangerouslyはコマンドを危険モードでチェックしたかどうかを報告します
+	Dangerously bool `json:"dangerously,omitempty"`
+
+	// Workdir, Env and User are the checked exec options (empty when not requested)
+	// Workdir、Env、Userはチェックしたexecオプションです（要求されていない場合は空）
+	Workdir string            `json:"workdir,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	User    string            `json:"user,omitempty"`
+
+	// Path is the checked file path (empty for command checks)
+	// Pathはチェックしたファイルパスです（コマンドのチェックでは空）
+	Path string `json:"path,omitempty"`
+
+	// Allowed reports whether the operation would be allowed
+	// Allowedは操作が許可されるかどうかを報告します
+	Allowed bool `json:"allowed"`
+
+	// Mode is the security mode
+	// Modeはセキュリティモードです
+	Mode string `json:"mode"`
+
+	// Access is the container access decision
+	// Accessはコンテナのアクセス判定です
+	Access AccessDecision `json:"access"`
+
+	// Rule is the exec_whitelist or exec_dangerously entry matching the command, if any
+	// Ruleはコマンドにマッチしたexec_whitelistまたはexec_dangerouslyのエントリです（ある場合）
+	Rule *RuleMatch `json:"matched_rule,omitempty"`
+
+	// BlockedPath is the blocked path entry that matched, with the file it came from
+	// BlockedPathはマッチしたブロックパスのエントリで、その定義元ファイルを含みます
+	BlockedPath *BlockedPath `json:"blocked_path,omitempty"`
+
+	// ResolvedPath is the real path the checked path or command argument resolves to in
+	// the container (symlinks, /proc/<pid>/root and mounts), when it differs
+	// ResolvedPathはチェックしたパスまたはコマンド引数がコンテナ内で解決される実パスです
+	// （シンボリックリンク、/proc/<pid>/root、マウント）。異なる場合のみ設定されます
+	ResolvedPath string `json:"resolved_path,omitempty"`
+
+	// MatchedPath is the name of the resolved path that matched the blocked path
+	// MatchedPathは解決したパスのうちブロックパスにマッチした名前です
+	MatchedPath string `json:"matched_path,omitempty"`
+
+	// Reason is the error the real operation would return when denied
+	// Reasonは拒否された場合に実際の操作が返すエラーです
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExplainExec runs the exec_command checks (CanExec, or CanExecDangerously when
+// dangerously is set, then CheckExecOptions for opts) for a command without executing it.
+//
+// ExplainExecはコマンドを実行せずに、exec_commandのチェック（CanExec、dangerouslyが
+// 設定されている場合はCanExecDangerously、続いてoptsに対するCheckExecOptions）を実行します。
+func (p *Policy) ExplainExec(containerRef, command string, dangerously bool, opts ExecOptions) Explanation {
+	name := p.ResolveContainer(containerRef).Name
+	e := Explanation{
+		Container:   name,
+		Command:     command,
+		Dangerously: dangerously,
+		Workdir:     opts.Workdir,
+		Env:         opts.Env,
+		User:        opts.User,
+		Mode:        p.config.Mode,
+		Access:      p.ContainerAccessDecision(name),
+	}
+
+	var err error
+	if dangerously {
+		e.Allowed, err = p.CanExecDangerously(name, command)
+		e.Rule = p.dangerousRule(name, extractBaseCommand(command))
+		for _, path := range extractPathsFromCommand(command) {
+			if blocked := p.IsPathBlocked(name, path); blocked != nil {
+				e.BlockedPath = blocked
+				break
+			}
+		}
+	} else {
+		e.Allowed, err = p.CanExec(name, command)
+		if p.config.Mode == "permissive" {
+			e.Rule = &RuleMatch{List: RuleListMode, Entry: p.config.Mode}
+		} else {
+			e.Rule = p.whitelistRule(name, command)
+		}
+	}
+
+	// An allowed command is still refused when its options are not allowed by exec_options
+	// 許可されたコマンドでも、オプションがexec_optionsで許可されていなければ拒否される
+	if err == nil && e.Allowed {
+		var checked ExecOptions
+		if checked, err = p.CheckExecOptions(name, opts); err != nil {
+			e.Allowed = false
+		} else {
+			e.Workdir = checked.Workdir
+		}
+	}
+	if err != nil {
+		e.Reason = err.Error()
+	}
+	return e
+}
+
+// ExplainPath runs the read_file and list_files checks for a path without reading it.
+// ExplainPathはパスを読み取らずに、read_fileとlist_filesのチェックを実行します。
+func (p *Policy) ExplainPath(containerRef, path string) Explanation {
+	name := p.ResolveContainer(containerRef).Name
+	e := Explanation{
+		Container: name,
+		Path:      path,
+		Mode:      p.config.Mode,
+		Access:    p.ContainerAccessDecision(name),
+	}
+
+	if err := p.CheckContainerAccess(name); err != nil {
+		e.Reason = err.Error()
+		return e
+	}
+	if blocked := p.IsPathBlocked(name, path); blocked != nil {
+		e.BlockedPath = blocked
+		e.Reason = fmt.Sprintf("path is blocked: %s (reason: %s)", path, blocked.Reason)
+		return e
+	}
+	e.Allowed = true
+	return e
+}
+
+// whitelistRule returns the exec_whitelist entry that allows command for the container,
+// checking the container's own entries (by name, project/service and service) before "*".
+//
+// whitelistRuleはコンテナに対してcommandを許可するexec_whitelistのエントリを返します。
+// "*"より先にコンテナ自身のエントリ（名前、project/service、サービス）をチェックします。
+func (p *Policy) whitelistRule(containerName, command string) *RuleMatch {
+	args, err := ParseCommand(command)
+	if err != nil || len(args) == 0 {
+		return nil
+	}
+	for _, key := range append(p.containerAliases(containerName), "*") {
+		for _, entry := range p.config.ExecWhitelist[key] {
+			if p.matchesWhitelist(args, key, entry) {
+				return &RuleMatch{List: RuleListExecWhitelist, Key: key, Entry: entry}
+			}
+		}
+	}
+	return nil
+}
+
+// dangerousRule returns the exec_dangerously entry that allows baseCommand for the
+// container, checking the container's own entries before "*".
+//
+// dangerousRuleはコンテナに対してbaseCommandを許可するexec_dangerouslyのエントリを返します。
+// "*"より先にコンテナ自身のエントリをチェックします。
+func (p *Policy) dangerousRule(containerName, baseCommand string) *RuleMatch {
+	for _, key := range append(p.containerAliases(containerName), "*") {
+		for _, entry := range p.config.ExecDangerously.Commands[key] {
+			if entry == baseCommand {
+				return &RuleMatch{List: RuleListExecDangerously, Key: key, Entry: entry}
+			}
+		}
+	}
+	return nil
+}
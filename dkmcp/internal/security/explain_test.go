@@ -0,0 +1,178 @@
+// explain_test.go contains tests for the policy dry run used by explain_policy.
+// explain_test.goはexplain_policyで使用するポリシーのドライランのテストを含みます。
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// newExplainPolicy returns a Compose-aware policy with whitelists, dangerous commands
+// and blocked paths configured.
+//
+// newExplainPolicyはホワイトリスト、危険コマンド、ブロックパスを設定した
+// Compose対応のポリシーを返します。
+func newExplainPolicy(t *testing.T) *Policy {
+	t.Helper()
+	policy := newComposePolicy(&config.SecurityConfig{
+		Mode:              "moderate",
+		AllowedContainers: []string{"shop-*"},
+		Permissions:       config.SecurityPermissions{Exec: true},
+		ExecWhitelist: map[string][]string{
+			"api": {"npm test", "npm run *"},
+			"*":   {"pwd"},
+		},
+		ExecDangerously: config.ExecDangerouslyConfig{
+			Enabled:  true,
+			Commands: map[string][]string{"*": {"cat", "tail"}},
+		},
+		BlockedPaths: config.BlockedPathsConfig{
+			Manual: map[string][]string{"shop/api": {"/app/.env"}},
+		},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatalf("InitBlockedPaths failed: %v", err)
+	}
+	return policy
+}
+
+// TestExplainExec tests that the dry run reports the decision and the matching rule.
+// TestExplainExecはドライランが判定とマッチしたルールを報告することをテストします。
+func TestExplainExec(t *testing.T) {
+	policy := newExplainPolicy(t)
+
+	tests := []struct {
+		name        string // Test case name / テストケース名
+		container   string // Container reference / コンテナ参照
+		command     string // Command / コマンド
+		dangerously bool   // Dangerous mode / 危険モード
+		wantAllowed bool   // Expected decision / 期待される判定
+		wantRule    string // Expected rule (RuleMatch.String) / 期待されるルール
+		wantReason  string // Substring of the expected reason / 期待される理由の部分文字列
+		wantBlocked string // Expected blocked pattern / 期待されるブロックパターン
+	}{
+		{"service whitelist entry", "api", "npm test", false, true, `exec_whitelist[api]: "npm test"`, "", ""},
+		{"wildcard whitelist entry", "shop-api-2", "npm run build", false, true, `exec_whitelist[api]: "npm run *"`, "", ""},
+		{"global whitelist entry", "shop-db-1", "pwd", false, true, `exec_whitelist[*]: "pwd"`, "", ""},
+		{"not whitelisted with dangerous hint", "api", "cat /etc/hosts", false, false, "", "dangerously=true", ""},
+		{"dangerous command allowed", "api", "tail /var/log/app.log", true, true, `exec_dangerously[*]: "tail"`, "", ""},
+		{"dangerous command on blocked path", "api", "cat /app/.env", true, false, `exec_dangerously[*]: "cat"`, "path is blocked", "/app/.env"},
+		{"container not allowed", "legacy", "pwd", false, false, `exec_whitelist[*]: "pwd"`, "access denied", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := policy.ExplainExec(tt.container, tt.command, tt.dangerously, ExecOptions{})
+			if e.Allowed != tt.wantAllowed {
+				t.Errorf("Allowed = %v, want %v (reason: %s)", e.Allowed, tt.wantAllowed, e.Reason)
+			}
+			rule := ""
+			if e.Rule != nil {
+				rule = e.Rule.String()
+			}
+			if rule != tt.wantRule {
+				t.Errorf("Rule = %q, want %q", rule, tt.wantRule)
+			}
+			if !strings.Contains(e.Reason, tt.wantReason) {
+				t.Errorf("Reason = %q, want it to contain %q", e.Reason, tt.wantReason)
+			}
+			blocked := ""
+			if e.BlockedPath != nil {
+				blocked = e.BlockedPath.Pattern
+			}
+			if blocked != tt.wantBlocked {
+				t.Errorf("BlockedPath = %q, want %q", blocked, tt.wantBlocked)
+			}
+
+			// The dry run must agree with the real check
+			// ドライランは実際のチェックと一致しなければならない
+			var allowed bool
+			if tt.dangerously {
+				allowed, _ = policy.CanExecDangerously(e.Container, tt.command)
+			} else {
+				allowed, _ = policy.CanExec(e.Container, tt.command)
+			}
+			if allowed != e.Allowed {
+				t.Errorf("ExplainExec allowed = %v, but real check = %v", e.Allowed, allowed)
+			}
+		})
+	}
+}
+
+// TestExplainExec_Permissive tests that permissive mode is reported as the deciding rule.
+// TestExplainExec_Permissiveはpermissiveモードが判定したルールとして報告されることをテストします。
+func TestExplainExec_Permissive(t *testing.T) {
+	policy := NewPolicy(&config.SecurityConfig{Mode: "permissive", Permissions: config.SecurityPermissions{Exec: true}})
+	e := policy.ExplainExec("anything", "rm -rf /tmp/x", false, ExecOptions{})
+	if !e.Allowed || e.Rule == nil || e.Rule.String() != "mode: permissive" {
+		t.Errorf("ExplainExec() = %+v, want allowed by mode: permissive", e)
+	}
+}
+
+// TestExplainExec_Options tests that the dry run checks the exec options like exec_command.
+// TestExplainExec_Optionsはドライランがexec_commandと同様にexecオプションをチェックすることをテストします。
+func TestExplainExec_Options(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		Mode:              "moderate",
+		AllowedContainers: []string{"shop-*"},
+		Permissions:       config.SecurityPermissions{Exec: true},
+		ExecWhitelist:     map[string][]string{"api": {"npm test"}},
+		ExecOptions: map[string]config.ExecOptionsConfig{
+			"api": {Workdirs: []string{"/app/*"}, Env: []string{"CI=true"}, Users: []string{"node"}},
+		},
+	})
+
+	tests := []struct {
+		name        string      // Test case name / テストケース名
+		opts        ExecOptions // Requested options / 要求されたオプション
+		wantAllowed bool        // Expected decision / 期待される判定
+		wantReason  string      // Substring of the expected reason / 期待される理由の部分文字列
+	}{
+		{"no options", ExecOptions{}, true, ""},
+		{"allowed options", ExecOptions{Workdir: "/app/web/", Env: map[string]string{"CI": "true"}, User: "node"}, true, ""},
+		{"workdir not allowed", ExecOptions{Workdir: "/etc"}, false, "workdir /etc is not allowed"},
+		{"env value not allowed", ExecOptions{Env: map[string]string{"CI": "false"}}, false, "CI=false is not allowed"},
+		{"user not allowed", ExecOptions{User: "root"}, false, "user root is not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := policy.ExplainExec("api", "npm test", false, tt.opts)
+			if e.Allowed != tt.wantAllowed || !strings.Contains(e.Reason, tt.wantReason) {
+				t.Errorf("ExplainExec() = allowed %v, reason %q; want %v, %q", e.Allowed, e.Reason, tt.wantAllowed, tt.wantReason)
+			}
+
+			// The dry run must agree with the real check
+			// ドライランは実際のチェックと一致しなければならない
+			_, err := policy.CheckExecOptions(e.Container, tt.opts)
+			if (err == nil) != e.Allowed {
+				t.Errorf("ExplainExec allowed = %v, but CheckExecOptions error = %v", e.Allowed, err)
+			}
+		})
+	}
+	if e := policy.ExplainExec("api", "npm test", false, ExecOptions{Workdir: "/app/web/"}); e.Workdir != "/app/web" {
+		t.Errorf("Workdir = %q, want the cleaned /app/web", e.Workdir)
+	}
+}
+
+// TestExplainPath tests the read_file/list_files dry run.
+// TestExplainPathはread_file/list_filesのドライランをテストします。
+func TestExplainPath(t *testing.T) {
+	policy := newExplainPolicy(t)
+
+	e := policy.ExplainPath("api", "/app/.env")
+	if e.Allowed || e.BlockedPath == nil || e.Container != "shop-api-1" {
+		t.Errorf("ExplainPath(api, /app/.env) = %+v, want blocked on shop-api-1", e)
+	}
+	if e.BlockedPath != nil && e.BlockedPath.Source == "" {
+		t.Error("Expected the blocked path to report its source")
+	}
+
+	if e := policy.ExplainPath("shop-db-1", "/app/.env"); !e.Allowed {
+		t.Errorf("ExplainPath(shop-db-1, /app/.env) = %+v, want allowed", e)
+	}
+	if e := policy.ExplainPath("legacy", "/etc/hosts"); e.Allowed || !strings.Contains(e.Reason, "access denied") {
+		t.Errorf("ExplainPath(legacy) = %+v, want access denied", e)
+	}
+}
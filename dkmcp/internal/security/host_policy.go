@@ -7,9 +7,11 @@ package security
 
 import (
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"strings"
 
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/cmdrule"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
 )
 
@@ -17,12 +19,49 @@ import (
 // HostCommandPolicyはホストコマンド実行のセキュリティルールを適用します。
 type HostCommandPolicy struct {
 	config *config.HostCommandsConfig
+
+	// whitelist and deny hold the compiled whitelist and deny patterns per base command
+	// whitelistとdenyはベースコマンドごとのコンパイル済みのホワイトリストと拒否パターンを保持します
+	whitelist map[string][]*cmdrule.Rule
+	deny      map[string][]*cmdrule.Prefix
 }
 
 // NewHostCommandPolicy creates a new HostCommandPolicy from config.
+// The whitelist and deny patterns are compiled once here. config.Validate rejects
+// invalid patterns at load; should one still get here, a whitelist pattern never
+// matches and a deny pattern denies every use of its command.
+//
 // NewHostCommandPolicyは設定から新しいHostCommandPolicyを作成します。
+// ホワイトリストと拒否パターンはここで一度だけコンパイルされます。config.Validateは
+// 読み込み時に不正なパターンを拒否します。それでもここに来た場合、ホワイトリストの
+// パターンは決してマッチせず、拒否パターンはそのコマンドのすべての使用を拒否します。
 func NewHostCommandPolicy(cfg *config.HostCommandsConfig) *HostCommandPolicy {
-	return &HostCommandPolicy{config: cfg}
+	p := &HostCommandPolicy{
+		config:    cfg,
+		whitelist: make(map[string][]*cmdrule.Rule),
+		deny:      make(map[string][]*cmdrule.Prefix),
+	}
+	for baseCmd, patterns := range cfg.Whitelist {
+		for _, pattern := range patterns {
+			rule, err := cmdrule.Compile(pattern, cfg.ForbiddenFlags[baseCmd][pattern])
+			if err != nil {
+				slog.Warn("Ignoring invalid host_commands whitelist rule", "command", baseCmd, "error", err)
+				continue
+			}
+			p.whitelist[baseCmd] = append(p.whitelist[baseCmd], rule)
+		}
+	}
+	for baseCmd, patterns := range cfg.Deny {
+		for _, pattern := range patterns {
+			prefix, err := cmdrule.CompilePrefix(pattern)
+			if err != nil {
+				slog.Warn("Denying every use of a command with an invalid host_commands deny rule", "command", baseCmd, "error", err)
+				prefix, _ = cmdrule.CompilePrefix("*")
+			}
+			p.deny[baseCmd] = append(p.deny[baseCmd], prefix)
+		}
+	}
+	return p
 }
 
 // CanExecHostCommand checks if a command is allowed in normal mode.
@@ -148,23 +187,54 @@ func (p *HostCommandPolicy) GetAllowedHostCommands() map[string][]string {
 }
 
 // matchesWhitelist checks if a command matches any pattern in the whitelist.
+// The arguments are split like the executed command and matched against each compiled
+// pattern, the same way exec_whitelist commands are (typed placeholders and the
+// entry's forbidden flags included).
+//
 // matchesWhitelistはコマンドがホワイトリスト内のパターンにマッチするかチェックします。
+// 引数は実行されるコマンドと同様に分割され、exec_whitelistのコマンドと同じように
+// （型付きプレースホルダーとエントリの禁止フラグを含めて）コンパイル済みの各パターンと照合されます。
 func (p *HostCommandPolicy) matchesWhitelist(baseCmd, args string) bool {
-	patterns, ok := p.config.Whitelist[baseCmd]
+	rules, ok := p.whitelist[baseCmd]
 	if !ok {
 		return false
 	}
-	return matchesPatterns(args, patterns)
+	argv, err := cmdrule.Split(args)
+	if err != nil {
+		return false
+	}
+	for _, rule := range rules {
+		if rule.Match(argv) {
+			return true
+		}
+	}
+	return false
 }
 
 // matchesDenyList checks if a command matches any pattern in the deny list.
+// The arguments are split like the executed command, so quoting or extra whitespace
+// cannot slip past a pattern (see cmdrule.CompilePrefix). Arguments that cannot be
+// split are treated as denied.
+//
 // matchesDenyListはコマンドが拒否リスト内のパターンにマッチするかチェックします。
+// 引数は実行されるコマンドと同様に分割されるため、クォートや余分な空白で
+// パターンをすり抜けることはできません（cmdrule.CompilePrefixを参照）。
+// 分割できない引数は拒否として扱います。
 func (p *HostCommandPolicy) matchesDenyList(baseCmd, args string) bool {
-	patterns, ok := p.config.Deny[baseCmd]
+	patterns, ok := p.deny[baseCmd]
 	if !ok {
 		return false
 	}
-	return matchesPatterns(args, patterns)
+	argv, err := cmdrule.Split(args)
+	if err != nil {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern.Match(argv) {
+			return true
+		}
+	}
+	return false
 }
 
 // matchesDangerousList checks if a command matches the dangerous commands list.
@@ -242,33 +312,6 @@ func (p *HostCommandPolicy) isContainerAllowed(name string) bool {
 	return false
 }
 
-// matchesPatterns checks if an args string matches any pattern.
-// Uses prefix matching with * wildcard (same as Policy.matchesWhitelist).
-//
-// matchesPatternsは引数文字列がパターンにマッチするかチェックします。
-// *ワイルドカードによるプレフィックスマッチングを使用します（Policy.matchesWhitelistと同じ）。
-func matchesPatterns(args string, patterns []string) bool {
-	args = strings.TrimSpace(args)
-
-	for _, pattern := range patterns {
-		pattern = strings.TrimSpace(pattern)
-
-		// Exact match
-		if args == pattern {
-			return true
-		}
-
-		// Wildcard matching
-		if strings.Contains(pattern, "*") {
-			prefix := strings.Split(pattern, "*")[0]
-			if strings.HasPrefix(args, prefix) {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 // splitCommand splits a command string into base command and arguments.
 // splitCommandはコマンド文字列をベースコマンドと引数に分割します。
 func splitCommand(command string) (string, string, error) {
@@ -347,6 +347,85 @@ func TestHostCommandPolicy_WildcardPatterns(t *testing.T) {
 	}
 }
 
+// TestHostCommandPolicy_WhitelistRules verifies that host whitelist patterns are matched
+// argument by argument with typed placeholders and forbidden flags, like exec_whitelist.
+//
+// TestHostCommandPolicy_WhitelistRulesはホストのホワイトリストパターンが、exec_whitelistと
+// 同様に型付きプレースホルダーと禁止フラグを使って引数ごとに照合されることを検証します。
+func TestHostCommandPolicy_WhitelistRules(t *testing.T) {
+	cfg := newTestHostConfig()
+	cfg.Whitelist["curl"] = []string{"-s http://localhost:<int:1024-65535>/*"}
+	cfg.Whitelist["uptime"] = []string{""}
+	cfg.ForbiddenFlags = map[string]map[string][]string{
+		"curl": {"-s http://localhost:<int:1024-65535>/*": {"-o", "--output"}},
+	}
+	p := NewHostCommandPolicy(cfg)
+
+	tests := []struct {
+		command string // Command to execute / 実行するコマンド
+		want    bool   // Should be allowed / 許可されるべきか
+	}{
+		{"curl -s http://localhost:8080/health", true},
+		{`curl -s "http://localhost:8080/health"`, true},
+		{"curl -s http://localhost:8080/health -H Accept:application/json", true},
+		{"curl -s http://localhost:80/health", false},
+		{"curl -s http://localhost:8080/health -o /tmp/out", false},
+		{"curl -s http://localhost:8080/health --output=/tmp/out", false},
+		{"curl -s http://evil.example/", false},
+		{"uptime", true},
+		{"uptime -p", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			ok, err := p.CanExecHostCommand(tt.command)
+			if ok != tt.want {
+				t.Errorf("CanExecHostCommand(%q) = %v, %v, want %v", tt.command, ok, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestHostCommandPolicy_DenyListSplitArgs verifies that deny patterns are matched against
+// the split arguments, so quoting and extra whitespace cannot bypass them.
+//
+// TestHostCommandPolicy_DenyListSplitArgsは拒否パターンが分割された引数と照合され、
+// クォートや余分な空白で回避できないことを検証します。
+func TestHostCommandPolicy_DenyListSplitArgs(t *testing.T) {
+	cfg := newTestHostConfig()
+	cfg.Dangerously.Commands["git"] = append(cfg.Dangerously.Commands["git"], "push")
+	cfg.Deny["git"] = []string{"push --force*", "push -f *"}
+	p := NewHostCommandPolicy(cfg)
+
+	tests := []struct {
+		command string // Command / コマンド
+		denied  bool   // Whether the deny list should match / 拒否リストにマッチすべきか
+	}{
+		{"git push origin main", false},
+		{"git push --force", true},
+		{`git push "--force"`, true},
+		{"git push '--force-with-lease' origin", true},
+		{"git  push --force", true},
+		{"git push  --force", true},
+		{"git \"push\" --force", true},
+		{"git push -f origin", true},
+		{"git push -f", true},
+		{`git push "--force`, true},
+		{"git push --forced-tags", true},
+		{"git push origin --force", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			ok, err := p.CanExecHostCommandDangerously(tt.command)
+			gotDenied := err != nil && searchSubstring(err.Error(), "command denied")
+			if gotDenied != tt.denied {
+				t.Errorf("CanExecHostCommandDangerously(%q) = %v, %v; want denied %v", tt.command, ok, err, tt.denied)
+			}
+		})
+	}
+}
+
 // --- Container restriction tests ---
 
 // TestHostCommandPolicy_ContainerRestrictions verifies that container name restrictions
@@ -0,0 +1,195 @@
+// labels.go implements label-based container access: allowed_labels opts containers in
+// by label selector (e.g., "dkmcp.access=readonly"), and denied_labels keeps containers
+// out even when their name is allowed. The decision, including the rule that produced
+// it, is exposed for get_security_policy and access-denied audit events.
+//
+// labels.goはラベルに基づくコンテナアクセスを実装します：allowed_labelsはラベルセレクタ
+// （例: "dkmcp.access=readonly"）でコンテナをオプトインし、denied_labelsは名前が許可されて
+// いてもコンテナを除外します。判定は、それを導いたルールとともに、get_security_policyと
+// アクセス拒否の監査イベントに公開されます。
+package security
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Reasons reported in an AccessDecision.
+// AccessDecisionで報告される理由です。
+const (
+	AccessReasonUnrestricted     = "no_restrictions"
+	AccessReasonAllowedContainer = "allowed_container"
+	AccessReasonAllowedLabel     = "allowed_label"
+	AccessReasonDeniedLabel      = "denied_label"
+	AccessReasonNotAllowed       = "not_allowed"
+)
+
+// AccessDecision explains whether a container can be accessed and why.
+// AccessDecisionはコンテナにアクセスできるかどうかとその理由を説明します。
+type AccessDecision struct {
+	// Allowed reports whether the container can be accessed
+	// Allowedはコンテナにアクセスできるかどうかを報告します
+	Allowed bool `json:"allowed"`
+
+	// Reason is one of the AccessReason constants
+	// ReasonはAccessReason定数のいずれかです
+	Reason string `json:"reason"`
+
+	// Rule is the allowed_containers pattern or label selector that decided access
+	// Ruleはアクセスを決定したallowed_containersのパターンまたはラベルセレクタです
+	Rule string `json:"rule,omitempty"`
+}
+
+// String returns a short human-readable form of the decision.
+// Stringは判定の短い人が読める形式を返します。
+func (d AccessDecision) String() string {
+	switch d.Reason {
+	case AccessReasonDeniedLabel:
+		return fmt.Sprintf("denied by label %s", d.Rule)
+	case AccessReasonAllowedLabel:
+		return fmt.Sprintf("allowed by label %s", d.Rule)
+	case AccessReasonAllowedContainer:
+		return fmt.Sprintf("allowed by pattern %s", d.Rule)
+	case AccessReasonNotAllowed:
+		return "not in allowed_containers or allowed_labels"
+	}
+	return "no access restrictions configured"
+}
+
+// AccessDeniedError is returned when a container is not accessible.
+// It carries the decision so callers can record why access was denied.
+//
+// AccessDeniedErrorはコンテナにアクセスできない場合に返されます。
+// 呼び出し元がアクセス拒否の理由を記録できるよう判定を保持します。
+type AccessDeniedError struct {
+	Container string
+	Decision  AccessDecision
+}
+
+// Error implements the error interface.
+// Errorはerrorインターフェースを実装します。
+func (e *AccessDeniedError) Error() string {
+	return fmt.Sprintf("access denied to container: %s (%s)", e.Container, e.Decision)
+}
+
+// matchLabelSelector reports whether labels satisfy a selector: "key" requires the
+// label to be present, "key=value" requires its value to match the glob pattern value.
+//
+// matchLabelSelectorはラベルがセレクタを満たすかを報告します："key"はラベルの存在を、
+// "key=value"はその値がglobパターンvalueにマッチすることを要求します。
+func matchLabelSelector(selector string, labels map[string]string) bool {
+	key, pattern, hasValue := strings.Cut(selector, "=")
+	value, ok := labels[strings.TrimSpace(key)]
+	if !ok {
+		return false
+	}
+	if !hasValue {
+		return true
+	}
+	matched, err := filepath.Match(pattern, value)
+	return err == nil && matched
+}
+
+// firstMatchingSelector returns the first selector satisfied by labels, or "".
+// firstMatchingSelectorはラベルが満たす最初のセレクタを返します。ない場合は""です。
+func firstMatchingSelector(selectors []string, labels map[string]string) string {
+	for _, selector := range selectors {
+		if matchLabelSelector(selector, labels) {
+			return selector
+		}
+	}
+	return ""
+}
+
+// ContainerAccessDecision decides whether a container can be accessed:
+//  1. a matching denied_labels selector denies access
+//  2. with neither allowed_containers nor allowed_labels configured, access is allowed
+//  3. a matching allowed_containers pattern (by name or Compose service) allows access
+//  4. a matching allowed_labels selector allows access
+//  5. otherwise access is denied
+//
+// Labels come from the containers known to the policy (see SetContainerIdentities),
+// so label rules never match a container DockMCP has not listed.
+//
+// ContainerAccessDecisionはコンテナにアクセスできるかを判定します：
+//  1. denied_labelsのセレクタにマッチすればアクセスを拒否
+//  2. allowed_containersもallowed_labelsも設定されていなければアクセスを許可
+//  3. allowed_containersのパターンに（名前またはComposeサービスで）マッチすれば許可
+//  4. allowed_labelsのセレクタにマッチすれば許可
+//  5. それ以外はアクセスを拒否
+//
+// ラベルはポリシーが把握しているコンテナから取得するため（SetContainerIdentities参照）、
+// DockMCPが一覧していないコンテナにラベルのルールがマッチすることはありません。
+func (p *Policy) ContainerAccessDecision(containerName string) AccessDecision {
+	id := p.ResolveContainer(containerName)
+
+	if selector := firstMatchingSelector(p.config.DeniedLabels, id.Labels); selector != "" {
+		return AccessDecision{Allowed: false, Reason: AccessReasonDeniedLabel, Rule: selector}
+	}
+
+	if len(p.config.AllowedContainers) == 0 && len(p.config.AllowedLabels) == 0 {
+		return AccessDecision{Allowed: true, Reason: AccessReasonUnrestricted}
+	}
+
+	// Check against whitelist using glob pattern matching
+	// globパターンマッチングを使用してホワイトリストをチェック
+	for _, alias := range id.Aliases() {
+		for _, pattern := range p.config.AllowedContainers {
+			matched, err := filepath.Match(pattern, alias)
+			if err != nil {
+				continue // Skip invalid patterns / 無効なパターンはスキップ
+			}
+			if matched {
+				return AccessDecision{Allowed: true, Reason: AccessReasonAllowedContainer, Rule: pattern}
+			}
+		}
+	}
+
+	if selector := firstMatchingSelector(p.config.AllowedLabels, id.Labels); selector != "" {
+		return AccessDecision{Allowed: true, Reason: AccessReasonAllowedLabel, Rule: selector}
+	}
+
+	return AccessDecision{Allowed: false, Reason: AccessReasonNotAllowed}
+}
+
+// CheckContainerAccess returns an *AccessDeniedError when the container cannot be accessed.
+// CheckContainerAccessはコンテナにアクセスできない場合に*AccessDeniedErrorを返します。
+func (p *Policy) CheckContainerAccess(containerName string) error {
+	decision := p.ContainerAccessDecision(containerName)
+	if decision.Allowed {
+		return nil
+	}
+	return &AccessDeniedError{Container: containerName, Decision: decision}
+}
+
+// labelAccessSummary reports, for get_security_policy, which rule grants access to each
+// accessible known container. Denied containers are only counted so that their names
+// are not revealed; the reason for each denial is recorded in the audit log instead.
+//
+// labelAccessSummaryはget_security_policy向けに、アクセス可能な既知のコンテナごとに
+// どのルールがアクセスを許可しているかを報告します。拒否されたコンテナは名前を明かさないよう
+// 件数のみを数えます。個々の拒否理由は代わりに監査ログに記録されます。
+func (p *Policy) labelAccessSummary() map[string]any {
+	p.containers.mu.RLock()
+	names := make([]string, 0, len(p.containers.byName))
+	for name := range p.containers.byName {
+		names = append(names, name)
+	}
+	p.containers.mu.RUnlock()
+
+	allowed := make(map[string]AccessDecision, len(names))
+	denied := 0
+	for _, name := range names {
+		decision := p.ContainerAccessDecision(name)
+		if decision.Allowed {
+			allowed[name] = decision
+		} else {
+			denied++
+		}
+	}
+	return map[string]any{
+		"allowed": allowed,
+		"denied":  denied,
+	}
+}
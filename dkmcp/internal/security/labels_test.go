@@ -0,0 +1,157 @@
+// labels_test.go contains tests for label-based container access.
+// labels_test.goはラベルに基づくコンテナアクセスのテストを含みます。
+package security
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// newLabelPolicy creates a policy that knows containers with dkmcp.access labels.
+// newLabelPolicyはdkmcp.accessラベルを持つコンテナを把握しているポリシーを作成します。
+func newLabelPolicy(cfg *config.SecurityConfig) *Policy {
+	policy := NewPolicy(cfg)
+	policy.SetContainerIdentities([]ContainerIdentity{
+		NewContainerIdentity("shop-api-1", map[string]string{"dkmcp.access": "readonly", "team": "shop"}),
+		NewContainerIdentity("shop-vault-1", map[string]string{"dkmcp.access": "none", "team": "shop"}),
+		NewContainerIdentity("demo-app", map[string]string{"team": "demo"}),
+		NewContainerIdentity("plain", nil),
+	})
+	return policy
+}
+
+// TestContainerAccessDecision tests the evaluation order of name and label rules.
+// TestContainerAccessDecisionは名前とラベルのルールの評価順序をテストします。
+func TestContainerAccessDecision(t *testing.T) {
+	tests := []struct {
+		name       string   // Test case name / テストケース名
+		allowed    []string // allowed_containers / 許可コンテナ
+		allowedLbl []string // allowed_labels / 許可ラベル
+		deniedLbl  []string // denied_labels / 拒否ラベル
+		container  string   // Container name / コンテナ名
+		want       AccessDecision
+	}{
+		{
+			name:      "no restrictions",
+			container: "plain",
+			want:      AccessDecision{Allowed: true, Reason: AccessReasonUnrestricted},
+		},
+		{
+			name:       "allowed by label value",
+			allowedLbl: []string{"dkmcp.access=readonly"},
+			container:  "shop-api-1",
+			want:       AccessDecision{Allowed: true, Reason: AccessReasonAllowedLabel, Rule: "dkmcp.access=readonly"},
+		},
+		{
+			name:       "allowed by label presence",
+			allowedLbl: []string{"team"},
+			container:  "demo-app",
+			want:       AccessDecision{Allowed: true, Reason: AccessReasonAllowedLabel, Rule: "team"},
+		},
+		{
+			name:       "label value glob",
+			allowedLbl: []string{"team=sh*"},
+			container:  "demo-app",
+			want:       AccessDecision{Allowed: false, Reason: AccessReasonNotAllowed},
+		},
+		{
+			name:       "name pattern checked before labels",
+			allowed:    []string{"shop-*"},
+			allowedLbl: []string{"team"},
+			container:  "shop-api-1",
+			want:       AccessDecision{Allowed: true, Reason: AccessReasonAllowedContainer, Rule: "shop-*"},
+		},
+		{
+			name:      "denied label overrides name pattern",
+			allowed:   []string{"shop-*"},
+			deniedLbl: []string{"dkmcp.access=none"},
+			container: "shop-vault-1",
+			want:      AccessDecision{Allowed: false, Reason: AccessReasonDeniedLabel, Rule: "dkmcp.access=none"},
+		},
+		{
+			name:      "denied label without allow lists",
+			deniedLbl: []string{"dkmcp.access=none"},
+			container: "plain",
+			want:      AccessDecision{Allowed: true, Reason: AccessReasonUnrestricted},
+		},
+		{
+			name:       "unknown container never matches labels",
+			allowedLbl: []string{"team"},
+			container:  "unknown",
+			want:       AccessDecision{Allowed: false, Reason: AccessReasonNotAllowed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newLabelPolicy(&config.SecurityConfig{
+				AllowedContainers: tt.allowed,
+				AllowedLabels:     tt.allowedLbl,
+				DeniedLabels:      tt.deniedLbl,
+			})
+			if got := policy.ContainerAccessDecision(tt.container); got != tt.want {
+				t.Errorf("ContainerAccessDecision(%q) = %+v, want %+v", tt.container, got, tt.want)
+			}
+			if got := policy.CanAccessContainer(tt.container); got != tt.want.Allowed {
+				t.Errorf("CanAccessContainer(%q) = %v, want %v", tt.container, got, tt.want.Allowed)
+			}
+		})
+	}
+}
+
+// TestCheckContainerAccess tests that denials carry the deciding rule.
+// TestCheckContainerAccessは拒否が決定したルールを保持することをテストします。
+func TestCheckContainerAccess(t *testing.T) {
+	policy := newLabelPolicy(&config.SecurityConfig{
+		Mode:         "moderate",
+		DeniedLabels: []string{"dkmcp.access=none"},
+		Permissions:  config.SecurityPermissions{Exec: true},
+	})
+
+	if err := policy.CheckContainerAccess("shop-api-1"); err != nil {
+		t.Errorf("expected shop-api-1 to be accessible, got %v", err)
+	}
+
+	_, err := policy.CanExec("shop-vault-1", "ls")
+	var denied *AccessDeniedError
+	if !errors.As(err, &denied) {
+		t.Fatalf("expected *AccessDeniedError from CanExec, got %v", err)
+	}
+	if denied.Container != "shop-vault-1" || denied.Decision.Rule != "dkmcp.access=none" {
+		t.Errorf("unexpected denial: %+v", denied)
+	}
+	if want := "access denied to container: shop-vault-1 (denied by label dkmcp.access=none)"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+// TestGetSecurityPolicy_LabelAccess tests the label access summary in get_security_policy.
+// TestGetSecurityPolicy_LabelAccessはget_security_policyのラベルアクセス概要をテストします。
+func TestGetSecurityPolicy_LabelAccess(t *testing.T) {
+	policy := newLabelPolicy(&config.SecurityConfig{
+		AllowedLabels: []string{"team=shop"},
+		DeniedLabels:  []string{"dkmcp.access=none"},
+	})
+
+	result := policy.GetSecurityPolicy()
+	summary, ok := result["container_access"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected container_access in policy, got %v", result)
+	}
+	allowed := summary["allowed"].(map[string]AccessDecision)
+	if len(allowed) != 1 || allowed["shop-api-1"].Rule != "team=shop" {
+		t.Errorf("unexpected allowed containers: %v", allowed)
+	}
+	if _, leaked := allowed["shop-vault-1"]; leaked {
+		t.Error("denied container must not be listed by name")
+	}
+	if summary["denied"] != 3 {
+		t.Errorf("denied = %v, want 3", summary["denied"])
+	}
+
+	if _, ok := NewPolicy(&config.SecurityConfig{}).GetSecurityPolicy()["container_access"]; ok {
+		t.Error("container_access should only be shown when label rules are configured")
+	}
+}
@@ -0,0 +1,218 @@
+// permissions.go resolves the operations allowed on each container. The global
+// permissions apply to every container; container_permissions entries override
+// individual fields for containers matching their key, so that, for example,
+// lifecycle can be enabled for a throwaway worker without enabling it for the database.
+//
+// permissions.goは各コンテナで許可される操作を解決します。グローバルな権限は
+// すべてのコンテナに適用され、container_permissionsのエントリはキーにマッチする
+// コンテナについて個々のフィールドを上書きします。これにより、例えばデータベースでは
+// 有効にせずに、使い捨てのworkerでのみlifecycleを有効にできます。
+package security
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// permissionMatch is a container_permissions entry that applies to a container.
+// permissionMatchはコンテナに適用されるcontainer_permissionsのエントリです。
+type permissionMatch struct {
+	key       string
+	exact     bool
+	aliasRank int
+}
+
+// matchingContainerKeys returns the keys of a per-container map (such as
+// container_permissions) matching the container, ordered from least to most specific:
+// patterns before exact names, shorter patterns before longer ones, and service names
+// before "project/service" before container names.
+//
+// matchingContainerKeysはコンテナ単位のマップ（container_permissionsなど）のうち
+// コンテナにマッチするキーを、具体性の低いものから高いものの順に返します：パターンは
+// 完全一致の名前より前、短いパターンは長いパターンより前、サービス名は"project/service"より前、
+// それはコンテナ名より前です。
+func matchingContainerKeys[V any](p *Policy, containerName string, entries map[string]V) []string {
+	aliases := p.containerAliases(containerName)
+
+	var matches []permissionMatch
+	for key := range entries {
+		for rank, alias := range aliases {
+			exact := key == alias
+			if !exact && !strings.ContainsAny(key, "*?[") {
+				continue
+			}
+			if matched, err := filepath.Match(key, alias); err != nil || !matched {
+				continue
+			}
+			// Aliases are ordered most specific first, so invert the rank
+			// 別名は具体的なものから順に並んでいるため順位を反転
+			matches = append(matches, permissionMatch{key: key, exact: exact, aliasRank: len(aliases) - rank})
+			break
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.exact != b.exact {
+			return !a.exact
+		}
+		if a.exact {
+			return a.aliasRank < b.aliasRank
+		}
+		if len(a.key) != len(b.key) {
+			return len(a.key) < len(b.key)
+		}
+		return a.key < b.key
+	})
+
+	keys := make([]string, len(matches))
+	for i, m := range matches {
+		keys[i] = m.key
+	}
+	return keys
+}
+
+// EffectivePermissions returns the permissions that apply to a container after
+// applying every matching container_permissions override to the global permissions.
+//
+// EffectivePermissionsはマッチするすべてのcontainer_permissionsの上書きをグローバルな
+// 権限に適用した後の、コンテナに適用される権限を返します。
+func (p *Policy) EffectivePermissions(containerName string) config.SecurityPermissions {
+	perms := p.config.Permissions
+	for _, key := range matchingContainerKeys(p, containerName, p.config.ContainerPermissions) {
+		perms = p.config.ContainerPermissions[key].Apply(perms)
+	}
+	return perms
+}
+
+// MaxReadBytes returns the cap on the content read_file returns for a container:
+// the most specific file_read.container_max_bytes entry, else file_read.max_bytes.
+//
+// MaxReadBytesはコンテナに対してread_fileが返す内容の上限を返します：最も具体的な
+// file_read.container_max_bytesのエントリ、なければfile_read.max_bytesです。
+func (p *Policy) MaxReadBytes(containerName string) int64 {
+	limit := p.config.FileRead.MaxBytes
+	if keys := matchingContainerKeys(p, containerName, p.config.FileRead.ContainerMaxBytes); len(keys) > 0 {
+		limit = p.config.FileRead.ContainerMaxBytes[keys[len(keys)-1]]
+	}
+	if limit <= 0 {
+		limit = config.DefaultFileReadMaxBytes
+	}
+	return limit
+}
+
+// MaxExecOutputBytes returns the cap on the output exec_command keeps for a container:
+// the most specific exec_output.container_max_bytes entry, else exec_output.max_bytes.
+//
+// MaxExecOutputBytesはコンテナに対してexec_commandが保持する出力の上限を返します：最も具体的な
+// exec_output.container_max_bytesのエントリ、なければexec_output.max_bytesです。
+func (p *Policy) MaxExecOutputBytes(containerName string) int64 {
+	limit := p.config.ExecOutput.MaxBytes
+	if keys := matchingContainerKeys(p, containerName, p.config.ExecOutput.ContainerMaxBytes); len(keys) > 0 {
+		limit = p.config.ExecOutput.ContainerMaxBytes[keys[len(keys)-1]]
+	}
+	if limit <= 0 {
+		limit = config.DefaultExecOutputMaxBytes
+	}
+	return limit
+}
+
+// ExecTimeout returns how long a command may run in a container: the timeout on the
+// exec_whitelist entry that allows it, else the most specific
+// exec_timeout.container_seconds entry, else exec_timeout.seconds.
+//
+// ExecTimeoutはコンテナでコマンドを実行できる時間を返します：コマンドを許可する
+// exec_whitelistエントリのタイムアウト、なければ最も具体的な
+// exec_timeout.container_secondsのエントリ、なければexec_timeout.secondsです。
+func (p *Policy) ExecTimeout(containerName, command string) time.Duration {
+	if rule := p.whitelistRule(containerName, command); rule != nil {
+		if seconds := p.config.ExecCommandTimeouts[rule.Key][rule.Entry]; seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	seconds := p.config.ExecTimeout.Seconds
+	if keys := matchingContainerKeys(p, containerName, p.config.ExecTimeout.ContainerSeconds); len(keys) > 0 {
+		seconds = p.config.ExecTimeout.ContainerSeconds[keys[len(keys)-1]]
+	}
+	if seconds <= 0 {
+		seconds = config.DefaultExecTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// ExecJobTimeout returns how long an exec job may run: the timeout on the exec_whitelist
+// entry that allows the command, else exec_jobs.timeout_seconds.
+//
+// ExecJobTimeoutはexecジョブを実行できる時間を返します：コマンドを許可する
+// exec_whitelistエントリのタイムアウト、なければexec_jobs.timeout_secondsです。
+func (p *Policy) ExecJobTimeout(containerName, command string) time.Duration {
+	if rule := p.whitelistRule(containerName, command); rule != nil {
+		if seconds := p.config.ExecCommandTimeouts[rule.Key][rule.Entry]; seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	seconds := p.config.ExecJobs.TimeoutSeconds
+	if seconds <= 0 {
+		seconds = config.DefaultExecJobTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// MaxExecJobs returns how many exec jobs the server keeps at once.
+// MaxExecJobsはサーバーが同時に保持するexecジョブの数を返します。
+func (p *Policy) MaxExecJobs() int {
+	if p.config.ExecJobs.MaxJobs <= 0 {
+		return config.DefaultExecJobsMax
+	}
+	return p.config.ExecJobs.MaxJobs
+}
+
+// MaxCopyBytes returns the cap on the total size of the files moved by one copy.
+// MaxCopyBytesは1回のコピーで移動するファイルの合計サイズの上限を返します。
+func (p *Policy) MaxCopyBytes() int64 {
+	if p.config.FileCopy.MaxBytes <= 0 {
+		return config.DefaultFileCopyMaxBytes
+	}
+	return p.config.FileCopy.MaxBytes
+}
+
+// PermissionMatrix returns the effective permissions of every accessible container
+// known to the policy, keyed by container name.
+//
+// PermissionMatrixはポリシーが把握しているアクセス可能なすべてのコンテナの
+// 実効権限を、コンテナ名をキーとして返します。
+func (p *Policy) PermissionMatrix() map[string]map[string]bool {
+	p.containers.mu.RLock()
+	names := make([]string, 0, len(p.containers.byName))
+	for name := range p.containers.byName {
+		names = append(names, name)
+	}
+	p.containers.mu.RUnlock()
+
+	matrix := make(map[string]map[string]bool, len(names))
+	for _, name := range names {
+		if !p.CanAccessContainer(name) {
+			continue
+		}
+		matrix[name] = permissionsMap(p.EffectivePermissions(name))
+	}
+	return matrix
+}
+
+// permissionsMap converts permissions to the map form used in get_security_policy.
+// permissionsMapは権限をget_security_policyで使用するマップ形式に変換します。
+func permissionsMap(perms config.SecurityPermissions) map[string]bool {
+	return map[string]bool{
+		"logs":      perms.Logs,
+		"inspect":   perms.Inspect,
+		"stats":     perms.Stats,
+		"exec":      perms.Exec,
+		"lifecycle": perms.Lifecycle,
+		"write":     perms.Write,
+		"copy":      perms.Copy,
+	}
+}
@@ -0,0 +1,233 @@
+// permissions_test.go contains tests for per-container permission overrides.
+// permissions_test.goはコンテナごとの権限の上書きのテストを含みます。
+package security
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// boolPtr returns a pointer to b.
+// boolPtrはbへのポインタを返します。
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+// newPermissionPolicy creates a policy with lifecycle enabled only for workers
+// and logs disabled for the database.
+//
+// newPermissionPolicyはworkerでのみlifecycleを有効にし、データベースでは
+// logsを無効にしたポリシーを作成します。
+func newPermissionPolicy() *Policy {
+	policy := NewPolicy(&config.SecurityConfig{
+		Mode: "moderate",
+		Permissions: config.SecurityPermissions{
+			Logs: true, Inspect: true, Stats: true, Exec: true,
+		},
+		ContainerPermissions: map[string]config.PermissionOverrides{
+			"worker-*":     {Lifecycle: boolPtr(true)},
+			"worker-gpu-*": {Lifecycle: boolPtr(false), Stats: boolPtr(false)},
+			"worker-gpu-1": {Lifecycle: boolPtr(true)},
+			"db":           {Logs: boolPtr(false), Exec: boolPtr(false)},
+		},
+	})
+	policy.SetContainerIdentities([]ContainerIdentity{
+		NewContainerIdentity("worker-1", nil),
+		NewContainerIdentity("worker-gpu-1", nil),
+		NewContainerIdentity("worker-gpu-2", nil),
+		NewContainerIdentity("shop-db-1", composeLabels("shop", "db", "1")),
+		NewContainerIdentity("api", nil),
+	})
+	return policy
+}
+
+// TestEffectivePermissions tests how overrides combine with the global permissions.
+// TestEffectivePermissionsは上書きとグローバルな権限の組み合わせをテストします。
+func TestEffectivePermissions(t *testing.T) {
+	policy := newPermissionPolicy()
+
+	tests := []struct {
+		container string                     // Container name / コンテナ名
+		want      config.SecurityPermissions // Expected permissions / 期待される権限
+	}{
+		{"api", config.SecurityPermissions{Logs: true, Inspect: true, Stats: true, Exec: true}},
+		{"worker-1", config.SecurityPermissions{Logs: true, Inspect: true, Stats: true, Exec: true, Lifecycle: true}},
+		{"worker-gpu-2", config.SecurityPermissions{Logs: true, Inspect: true, Exec: true}},
+		{"worker-gpu-1", config.SecurityPermissions{Logs: true, Inspect: true, Exec: true, Lifecycle: true}},
+		{"shop-db-1", config.SecurityPermissions{Inspect: true, Stats: true}},
+	}
+
+	for _, tt := range tests {
+		if got := policy.EffectivePermissions(tt.container); got != tt.want {
+			t.Errorf("EffectivePermissions(%q) = %+v, want %+v", tt.container, got, tt.want)
+		}
+	}
+
+	if policy.CanGetLogs("shop-db-1") || !policy.CanGetLogs("api") {
+		t.Error("CanGetLogs should follow the db override")
+	}
+	if policy.CanGetStats("worker-gpu-2") || !policy.CanInspect("worker-gpu-2") {
+		t.Error("CanGetStats/CanInspect should follow the worker-gpu-* override")
+	}
+}
+
+// TestCanLifecycle_ContainerPermissions tests lifecycle enabled for one container only.
+// TestCanLifecycle_ContainerPermissionsは1つのコンテナでのみ有効なlifecycleをテストします。
+func TestCanLifecycle_ContainerPermissions(t *testing.T) {
+	policy := newPermissionPolicy()
+
+	if ok, err := policy.CanLifecycle("worker-1"); !ok {
+		t.Errorf("expected lifecycle on worker-1, got %v", err)
+	}
+	ok, err := policy.CanLifecycle("api")
+	if ok || err == nil || !strings.Contains(err.Error(), "disabled in security policy") {
+		t.Errorf("expected global lifecycle denial for api, got %v, %v", ok, err)
+	}
+	if ok, _ := policy.CanLifecycle("worker-gpu-2"); ok {
+		t.Error("expected lifecycle denied on worker-gpu-2 by the more specific override")
+	}
+	if ok, err := policy.CanExec("shop-db-1", "ls"); ok || !strings.Contains(err.Error(), "container_permissions") {
+		t.Errorf("expected exec denied on shop-db-1 by override, got %v, %v", ok, err)
+	}
+}
+
+// TestGetSecurityPolicy_EffectivePermissions tests the permission matrix in get_security_policy.
+// TestGetSecurityPolicy_EffectivePermissionsはget_security_policyの権限マトリクスをテストします。
+func TestGetSecurityPolicy_EffectivePermissions(t *testing.T) {
+	policy := newPermissionPolicy()
+
+	result := policy.GetSecurityPolicy()
+	matrix, ok := result["effective_permissions"].(map[string]map[string]bool)
+	if !ok {
+		t.Fatalf("expected effective_permissions in policy, got %v", result)
+	}
+	if len(matrix) != 5 {
+		t.Errorf("expected 5 containers in matrix, got %d", len(matrix))
+	}
+	if !matrix["worker-1"]["lifecycle"] || matrix["api"]["lifecycle"] || matrix["shop-db-1"]["logs"] {
+		t.Errorf("unexpected matrix: %v", matrix)
+	}
+	if _, ok := result["container_permissions"]; !ok {
+		t.Error("expected container_permissions in policy")
+	}
+}
+
+// TestMaxReadBytes tests the per-container read_file size limit.
+// TestMaxReadBytesはコンテナごとのread_fileのサイズ上限をテストします。
+func TestMaxReadBytes(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		FileRead: config.FileReadConfig{
+			MaxBytes: 4096,
+			ContainerMaxBytes: map[string]int64{
+				"shop-*":     1 << 20,
+				"shop/db":    512,
+				"shop-api-2": 8192,
+			},
+		},
+	})
+
+	tests := []struct {
+		container string // Container name / コンテナ名
+		want      int64  // Expected limit / 期待される上限
+	}{
+		{"legacy", 4096},
+		{"shop-api-1", 1 << 20},
+		{"shop-api-2", 8192},
+		{"shop-db-1", 512},
+	}
+	for _, tt := range tests {
+		if got := policy.MaxReadBytes(tt.container); got != tt.want {
+			t.Errorf("MaxReadBytes(%q) = %d, want %d", tt.container, got, tt.want)
+		}
+	}
+
+	if got := NewPolicy(&config.SecurityConfig{}).MaxReadBytes("any"); got != config.DefaultFileReadMaxBytes {
+		t.Errorf("MaxReadBytes() without configuration = %d, want the default %d", got, config.DefaultFileReadMaxBytes)
+	}
+}
+
+// TestExecTimeout tests the order of the exec_whitelist, per-container and global timeouts.
+// TestExecTimeoutはexec_whitelist、コンテナごと、グローバルのタイムアウトの優先順位をテストします。
+func TestExecTimeout(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		Mode:                "moderate",
+		ExecWhitelist:       map[string][]string{"shop/api": {"npm test", "npm run lint"}},
+		ExecCommandTimeouts: map[string]map[string]int{"shop/api": {"npm test": 600}},
+		ExecTimeout: config.ExecTimeoutConfig{
+			Seconds:          60,
+			ContainerSeconds: map[string]int{"shop-api-*": 120},
+		},
+	})
+
+	tests := []struct {
+		container string        // Container name / コンテナ名
+		command   string        // Command / コマンド
+		want      time.Duration // Expected timeout / 期待されるタイムアウト
+	}{
+		{"shop-api-1", "npm test", 600 * time.Second},
+		{"shop-api-1", "npm run lint", 120 * time.Second},
+		{"legacy", "npm test", 60 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.ExecTimeout(tt.container, tt.command); got != tt.want {
+			t.Errorf("ExecTimeout(%q, %q) = %v, want %v", tt.container, tt.command, got, tt.want)
+		}
+	}
+
+	if got := NewPolicy(&config.SecurityConfig{}).ExecTimeout("any", "pwd"); got != config.DefaultExecTimeoutSeconds*time.Second {
+		t.Errorf("ExecTimeout() without configuration = %v, want the default", got)
+	}
+}
+
+// TestExecJobTimeout tests the time limit and table size of exec jobs.
+// TestExecJobTimeoutはexecジョブの時間制限とテーブルサイズをテストします。
+func TestExecJobTimeout(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		Mode:                "moderate",
+		ExecWhitelist:       map[string][]string{"shop/api": {"npm test", "npm run lint"}},
+		ExecCommandTimeouts: map[string]map[string]int{"shop/api": {"npm test": 600}},
+		ExecTimeout:         config.ExecTimeoutConfig{Seconds: 60},
+		ExecJobs:            config.ExecJobsConfig{MaxJobs: 4, TimeoutSeconds: 1800},
+	})
+
+	if got := policy.ExecJobTimeout("shop-api-1", "npm test"); got != 600*time.Second {
+		t.Errorf("ExecJobTimeout(npm test) = %v, want the whitelist entry's 10m", got)
+	}
+	if got := policy.ExecJobTimeout("shop-api-1", "npm run lint"); got != 1800*time.Second {
+		t.Errorf("ExecJobTimeout(npm run lint) = %v, want exec_jobs.timeout_seconds", got)
+	}
+	if got := policy.MaxExecJobs(); got != 4 {
+		t.Errorf("MaxExecJobs() = %d, want 4", got)
+	}
+
+	defaults := NewPolicy(&config.SecurityConfig{})
+	if got := defaults.ExecJobTimeout("any", "pwd"); got != config.DefaultExecJobTimeoutSeconds*time.Second {
+		t.Errorf("ExecJobTimeout() without configuration = %v, want the default", got)
+	}
+	if got := defaults.MaxExecJobs(); got != config.DefaultExecJobsMax {
+		t.Errorf("MaxExecJobs() without configuration = %d, want the default", got)
+	}
+}
+
+// TestMaxExecOutputBytes tests the per-container exec_command output limit.
+// TestMaxExecOutputBytesはコンテナごとのexec_commandの出力上限をテストします。
+func TestMaxExecOutputBytes(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		ExecOutput: config.ExecOutputConfig{
+			MaxBytes:          4096,
+			ContainerMaxBytes: map[string]int64{"shop/api": 1 << 20},
+		},
+	})
+	if got := policy.MaxExecOutputBytes("shop-api-1"); got != 1<<20 {
+		t.Errorf("MaxExecOutputBytes(shop-api-1) = %d, want the Compose service override", got)
+	}
+	if got := policy.MaxExecOutputBytes("legacy"); got != 4096 {
+		t.Errorf("MaxExecOutputBytes(legacy) = %d, want 4096", got)
+	}
+	if got := NewPolicy(&config.SecurityConfig{}).MaxExecOutputBytes("any"); got != config.DefaultExecOutputMaxBytes {
+		t.Errorf("MaxExecOutputBytes() without configuration = %d, want the default %d", got, config.DefaultExecOutputMaxBytes)
+	}
+}
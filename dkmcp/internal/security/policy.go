@@ -14,9 +14,10 @@ package security
 
 import (
 	"fmt"
-	"path/filepath"
+	"log/slog"
 	"strings"
 
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/cmdrule"
 	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
 )
 
@@ -39,6 +40,14 @@ type Policy struct {
 	// outputMasker handles masking of sensitive data in output
 	// outputMaskerは出力内の機密データのマスキングを処理します
 	outputMasker *OutputMasker
+
+	// containers maps Compose service references to known containers
+	// containersはComposeのサービス参照を既知のコンテナに対応付けます
+	containers containerRegistry
+
+	// execRules holds the compiled exec_whitelist entries by whitelist key and entry
+	// execRulesはホワイトリストのキーとエントリごとにコンパイル済みのexec_whitelistのエントリを保持します
+	execRules map[string]map[string]*cmdrule.Rule
 }
 
 // NewPolicy creates a new security policy with the given configuration.
@@ -57,7 +66,31 @@ func NewPolicy(cfg *config.SecurityConfig) *Policy {
 		config:              cfg,
 		blockedPathsManager: nil,
 		outputMasker:        masker,
+		execRules:           compileExecRules(cfg),
+	}
+}
+
+// compileExecRules compiles every exec_whitelist entry once, when the policy is created.
+// config.Validate rejects invalid entries at load, so one that still fails here is logged
+// and never matches.
+//
+// compileExecRulesはポリシーの作成時にすべてのexec_whitelistのエントリを一度だけコンパイルします。
+// config.Validateは読み込み時に不正なエントリを拒否するため、それでもここで失敗するものは
+// ログに記録され、決してマッチしません。
+func compileExecRules(cfg *config.SecurityConfig) map[string]map[string]*cmdrule.Rule {
+	rules := make(map[string]map[string]*cmdrule.Rule, len(cfg.ExecWhitelist))
+	for key, entries := range cfg.ExecWhitelist {
+		rules[key] = make(map[string]*cmdrule.Rule, len(entries))
+		for _, entry := range entries {
+			rule, err := cmdrule.Compile(entry, cfg.ExecForbiddenFlags[key][entry])
+			if err != nil {
+				slog.Warn("Ignoring invalid exec_whitelist rule", "container", key, "error", err)
+				continue
+			}
+			rules[key][entry] = rule
+		}
 	}
+	return rules
 }
 
 // InitBlockedPaths initializes the blocked paths manager with the given container list.
@@ -83,7 +116,14 @@ func (p *Policy) IsPathBlocked(containerName string, path string) *BlockedPath {
 	if p.blockedPathsManager == nil {
 		return nil
 	}
-	return p.blockedPathsManager.IsPathBlocked(containerName, path)
+	// Entries may name the container, its service or project/service
+	// エントリはコンテナ、そのサービス、またはproject/serviceを指定できる
+	for _, alias := range p.containerAliases(containerName) {
+		if blocked := p.blockedPathsManager.IsPathBlocked(alias, path); blocked != nil {
+			return blocked
+		}
+	}
+	return nil
 }
 
 // GetBlockedPaths returns all configured blocked paths across all containers.
@@ -107,70 +147,99 @@ func (p *Policy) GetBlockedPathsForContainer(containerName string) []BlockedPath
 	if p.blockedPathsManager == nil {
 		return nil
 	}
-	return p.blockedPathsManager.GetBlockedPathsForContainer(containerName)
+	aliases := p.containerAliases(containerName)
+	result := p.blockedPathsManager.GetBlockedPathsForContainer(aliases[0])
+
+	// Add entries addressing the container by service; global entries are already included
+	// サービスでコンテナを指定するエントリを追加。グローバルエントリは既に含まれている
+	for _, alias := range aliases[1:] {
+		for _, blocked := range p.blockedPathsManager.GetBlockedPathsForContainer(alias) {
+			if blocked.Container == alias {
+				result = append(result, blocked)
+			}
+		}
+	}
+	return result
 }
 
 // CanAccessContainer checks if a container can be accessed based on the allowed list.
 // Uses glob pattern matching (e.g., "app-*" matches "app-web", "app-api").
-// If no allowed list is configured, all containers are accessible.
+// Compose containers also match by service ("api") or project/service ("shop/*").
+// Containers can also be allowed or denied by label (allowed_labels, denied_labels).
+// If no allowed list is configured, all containers not denied by label are accessible.
+// See ContainerAccessDecision for the full evaluation order.
 //
 // CanAccessContainerは許可リストに基づいてコンテナにアクセスできるかチェックします。
 // globパターンマッチングを使用します（例: "app-*"は"app-web"、"app-api"にマッチ）。
-// 許可リストが設定されていない場合、全てのコンテナにアクセス可能です。
+// Composeのコンテナはサービス（"api"）やproject/service（"shop/*"）でもマッチします。
+// ラベル（allowed_labels、denied_labels）でもコンテナを許可または拒否できます。
+// 許可リストが設定されていない場合、ラベルで拒否されていない全てのコンテナにアクセス可能です。
+// 評価順序の詳細はContainerAccessDecisionを参照してください。
 func (p *Policy) CanAccessContainer(containerName string) bool {
-	// If no whitelist is specified, allow all containers
-	// ホワイトリストが指定されていない場合、全コンテナを許可
-	if len(p.config.AllowedContainers) == 0 {
+	return p.ContainerAccessDecision(containerName).Allowed
+}
+
+// CanGetLogs checks if retrieving logs from the container is allowed.
+// This is controlled by the permissions.logs setting and container_permissions overrides.
+//
+// CanGetLogsはコンテナからのログの取得が許可されているかチェックします。
+// これはpermissions.logs設定とcontainer_permissionsの上書きで制御されます。
+func (p *Policy) CanGetLogs(containerName string) bool {
+	return p.EffectivePermissions(containerName).Logs
+}
+
+// CanInspect checks if inspecting the container is allowed.
+// This is controlled by the permissions.inspect setting and container_permissions overrides.
+//
+// CanInspectはコンテナの検査が許可されているかチェックします。
+// これはpermissions.inspect設定とcontainer_permissionsの上書きで制御されます。
+func (p *Policy) CanInspect(containerName string) bool {
+	return p.EffectivePermissions(containerName).Inspect
+}
+
+// CanListContainers checks if listing containers is allowed. Listing is allowed when
+// inspect is enabled globally or for at least one container_permissions entry.
+//
+// CanListContainersはコンテナの一覧表示が許可されているかチェックします。inspectが
+// グローバルに、または少なくとも1つのcontainer_permissionsのエントリで有効な場合に許可されます。
+func (p *Policy) CanListContainers() bool {
+	if p.config.Permissions.Inspect {
 		return true
 	}
-
-	// Check against whitelist using glob pattern matching
-	// globパターンマッチングを使用してホワイトリストをチェック
-	for _, pattern := range p.config.AllowedContainers {
-		matched, err := filepath.Match(pattern, containerName)
-		if err != nil {
-			continue // Skip invalid patterns / 無効なパターンはスキップ
-		}
-		if matched {
+	for _, override := range p.config.ContainerPermissions {
+		if override.Inspect != nil && *override.Inspect {
 			return true
 		}
 	}
-
 	return false
 }
 
-// CanGetLogs checks if retrieving container logs is allowed.
-// This is controlled by the permissions.logs setting.
-//
-// CanGetLogsはコンテナログの取得が許可されているかチェックします。
-// これはpermissions.logs設定で制御されます。
-func (p *Policy) CanGetLogs() bool {
-	return p.config.Permissions.Logs
-}
-
-// CanInspect checks if container inspection is allowed.
-// This is controlled by the permissions.inspect setting.
+// CanGetStats checks if retrieving stats of the container is allowed.
+// This is controlled by the permissions.stats setting and container_permissions overrides.
 //
-// CanInspectはコンテナ検査が許可されているかチェックします。
-// これはpermissions.inspect設定で制御されます。
-func (p *Policy) CanInspect() bool {
-	return p.config.Permissions.Inspect
+// CanGetStatsはコンテナの統計の取得が許可されているかチェックします。
+// これはpermissions.stats設定とcontainer_permissionsの上書きで制御されます。
+func (p *Policy) CanGetStats(containerName string) bool {
+	return p.EffectivePermissions(containerName).Stats
 }
 
-// CanGetStats checks if retrieving container stats is allowed.
-// This is controlled by the permissions.stats setting.
+// disabledError reports an operation disabled for a container, distinguishing a global
+// setting from a container_permissions override.
 //
-// CanGetStatsはコンテナ統計の取得が許可されているかチェックします。
-// これはpermissions.stats設定で制御されます。
-func (p *Policy) CanGetStats() bool {
-	return p.config.Permissions.Stats
+// disabledErrorはコンテナで無効な操作を報告し、グローバル設定と
+// container_permissionsによる上書きを区別します。
+func (p *Policy) disabledError(operation string, globallyEnabled bool, containerName string) error {
+	if !globallyEnabled {
+		return fmt.Errorf("%s disabled in security policy", operation)
+	}
+	return fmt.Errorf("%s disabled for container %s by container_permissions", operation, containerName)
 }
 
 // CanLifecycle checks if container lifecycle operations (start/stop/restart) are allowed
 // for the specified container. Uses Docker API directly (no shell execution).
 //
 // This involves multiple checks:
-//   1. Is lifecycle globally enabled? (permissions.lifecycle)
+//   1. Is lifecycle enabled for the container? (permissions.lifecycle, container_permissions)
 //   2. Is the container accessible? (allowed_containers)
 //   3. Does the security mode allow lifecycle? (denied in strict mode)
 //
@@ -178,12 +247,12 @@ func (p *Policy) CanGetStats() bool {
 // 指定されたコンテナに対して許可されているかチェックします。
 // Docker APIを直接使用します（シェル実行なし）。
 func (p *Policy) CanLifecycle(containerName string) (bool, error) {
-	if !p.config.Permissions.Lifecycle {
-		return false, fmt.Errorf("lifecycle operations are disabled in security policy")
+	if !p.EffectivePermissions(containerName).Lifecycle {
+		return false, p.disabledError("lifecycle operations are", p.config.Permissions.Lifecycle, containerName)
 	}
 
-	if !p.CanAccessContainer(containerName) {
-		return false, fmt.Errorf("container not in allowed list: %s", containerName)
+	if err := p.CheckContainerAccess(containerName); err != nil {
+		return false, err
 	}
 
 	if p.config.Mode == "strict" {
@@ -193,30 +262,84 @@ func (p *Policy) CanLifecycle(containerName string) (bool, error) {
 	return true, nil
 }
 
+// CanWrite checks if modifying files (write_file, apply_patch) is allowed for the
+// specified container. Which paths may be written is decided by IsPathWritable.
+//
+// This involves multiple checks:
+//   1. Is write enabled for the container? (permissions.write, container_permissions)
+//   2. Is the container accessible? (allowed_containers)
+//   3. Does the security mode allow writes? (denied in strict mode)
+//
+// CanWriteは指定されたコンテナでファイルの変更（write_file、apply_patch）が
+// 許可されているかチェックします。どのパスに書き込めるかはIsPathWritableで決まります。
+func (p *Policy) CanWrite(containerName string) (bool, error) {
+	if !p.EffectivePermissions(containerName).Write {
+		return false, p.disabledError("file writes are", p.config.Permissions.Write, containerName)
+	}
+
+	if err := p.CheckContainerAccess(containerName); err != nil {
+		return false, err
+	}
+
+	if p.config.Mode == "strict" {
+		return false, fmt.Errorf("file writes are not allowed in strict mode")
+	}
+
+	return true, nil
+}
+
+// CanCopy checks if copying files between the host workspace and the specified container
+// (copy_from_container, copy_to_container) is allowed. Which container paths a copy may
+// write is decided by IsPathWritable, and which host paths by ResolveWorkspacePath.
+//
+// This involves multiple checks:
+//   1. Is copy enabled for the container? (permissions.copy, container_permissions)
+//   2. Is the container accessible? (allowed_containers)
+//   3. Does the security mode allow copies? (denied in strict mode)
+//
+// CanCopyは指定されたコンテナとホストのワークスペース間のファイルのコピー
+// （copy_from_container、copy_to_container）が許可されているかチェックします。コピーが
+// 書き込めるコンテナ内パスはIsPathWritableで、ホストのパスはResolveWorkspacePathで決まります。
+func (p *Policy) CanCopy(containerName string) (bool, error) {
+	if !p.EffectivePermissions(containerName).Copy {
+		return false, p.disabledError("file copies are", p.config.Permissions.Copy, containerName)
+	}
+
+	if err := p.CheckContainerAccess(containerName); err != nil {
+		return false, err
+	}
+
+	if p.config.Mode == "strict" {
+		return false, fmt.Errorf("file copies are not allowed in strict mode")
+	}
+
+	return true, nil
+}
+
 // CanExec checks if executing a command in a container is allowed.
 // This involves multiple checks:
-//   1. Is exec globally enabled? (permissions.exec)
+//   1. Is exec enabled for the container? (permissions.exec, container_permissions)
 //   2. Is the container accessible? (allowed_containers)
 //   3. Does the security mode allow exec?
 //   4. Is the command whitelisted? (in moderate mode)
 //
 // CanExecはコンテナ内でのコマンド実行が許可されているかチェックします。
 // これは複数のチェックを含みます：
-//   1. execがグローバルに有効か？（permissions.exec）
+//   1. コンテナでexecが有効か？（permissions.exec、container_permissions）
 //   2. コンテナにアクセス可能か？（allowed_containers）
 //   3. セキュリティモードがexecを許可しているか？
 //   4. コマンドがホワイトリストに登録されているか？（moderateモード）
 func (p *Policy) CanExec(containerName string, command string) (bool, error) {
-	// Check if exec is globally enabled
-	// execがグローバルに有効かチェック
-	if !p.config.Permissions.Exec {
-		return false, fmt.Errorf("exec is disabled in security policy")
+	// Check if exec is enabled for the container
+	// コンテナでexecが有効かチェック
+	if !p.EffectivePermissions(containerName).Exec {
+		return false, p.disabledError("exec is", p.config.Permissions.Exec, containerName)
 	}
 
 	// Check if container is accessible
 	// コンテナにアクセス可能かチェック
-	if !p.CanAccessContainer(containerName) {
-		return false, fmt.Errorf("container not in allowed list: %s", containerName)
+	if err := p.CheckContainerAccess(containerName); err != nil {
+		return false, err
 	}
 
 	// In strict mode, exec is never allowed regardless of whitelist
@@ -225,6 +348,12 @@ func (p *Policy) CanExec(containerName string, command string) (bool, error) {
 		return false, fmt.Errorf("exec is not allowed in strict mode")
 	}
 
+	// The command must parse the same way it will be executed
+	// コマンドは実行時と同じように解析できなければならない
+	if _, err := ParseCommand(command); err != nil {
+		return false, err
+	}
+
 	// In permissive mode, allow any command
 	// permissiveモードでは、任意のコマンドを許可
 	if p.config.Mode == "permissive" {
@@ -246,19 +375,17 @@ func (p *Policy) CanExec(containerName string, command string) (bool, error) {
 // isCommandWhitelistedはコマンドが特定のコンテナに対してホワイトリスト登録されているかチェックします。
 // まずコンテナ固有のホワイトリストをチェックし、次にグローバルホワイトリスト（*）にフォールバックします。
 func (p *Policy) isCommandWhitelisted(containerName string, command string) (bool, error) {
-	// Check container-specific whitelist first
-	// まずコンテナ固有のホワイトリストをチェック
-	if whitelist, ok := p.config.ExecWhitelist[containerName]; ok {
-		if p.matchesWhitelist(command, whitelist) {
-			return true, nil
-		}
+	// Check container-specific whitelist first, then the default whitelist (*)
+	// まずコンテナ固有のホワイトリスト、次にデフォルトホワイトリスト（*）をチェック
+	if p.whitelistRule(containerName, command) != nil {
+		return true, nil
 	}
 
-	// Check default whitelist (applies to all containers)
-	// デフォルトホワイトリストをチェック（全コンテナに適用）
-	if whitelist, ok := p.config.ExecWhitelist["*"]; ok {
-		if p.matchesWhitelist(command, whitelist) {
-			return true, nil
+	// Explain a denial caused by a forbidden flag
+	// 禁止フラグによる拒否を説明
+	if args, err := ParseCommand(command); err == nil {
+		if hint := p.forbiddenFlagHint(containerName, args); hint != "" {
+			return false, fmt.Errorf("command not whitelisted: %s (%s)", command, hint)
 		}
 	}
 
@@ -271,39 +398,44 @@ func (p *Policy) isCommandWhitelisted(containerName string, command string) (boo
 	return false, fmt.Errorf("command not whitelisted: %s", command)
 }
 
-// matchesWhitelist checks if a command matches any pattern in the whitelist.
-// Supports exact matches and wildcard patterns (e.g., "echo *" matches "echo hello").
+// matchesWhitelist reports whether a parsed command matches the exec_whitelist entry
+// listed under key. The entry was compiled with cmdrule, so it is compared argument by
+// argument: quoting does not matter (`grep "a b" f` and `grep 'a b' f` are the same
+// command), a wildcard only spans the arguments it stands for (e.g., "npm run test:*"
+// matches "npm run test:unit"), typed placeholders such as <int> or <path:/app> constrain
+// single arguments, and the entry's forbidden flags are never accepted.
 //
-// matchesWhitelistはコマンドがホワイトリスト内のいずれかのパターンに一致するかチェックします。
-// 完全一致とワイルドカードパターンをサポートします（例: "echo *"は"echo hello"にマッチ）。
-func (p *Policy) matchesWhitelist(command string, whitelist []string) bool {
-	// Normalize command by trimming whitespace
-	// ホワイトスペースを削除してコマンドを正規化
-	command = strings.TrimSpace(command)
-
-	for _, pattern := range whitelist {
-		pattern = strings.TrimSpace(pattern)
-
-		// Exact match check
-		// 完全一致チェック
-		if command == pattern {
-			return true
-		}
+// matchesWhitelistは解析されたコマンドがkeyの下に列挙されたexec_whitelistのエントリに
+// 一致するかどうかを報告します。エントリはcmdruleでコンパイル済みのため引数ごとに比較されます：
+// 引用符の違いは影響せず（`grep "a b" f`と`grep 'a b' f`は同じコマンド）、ワイルドカードは
+// それが表す引数にのみ及び（例: "npm run test:*"は"npm run test:unit"にマッチ）、<int>や
+// <path:/app>のような型付きプレースホルダーは1つの引数を制約し、エントリの禁止フラグは
+// 決して受け付けられません。
+func (p *Policy) matchesWhitelist(args []string, key, entry string) bool {
+	rule := p.execRules[key][entry]
+	return rule != nil && rule.Match(args)
+}
 
-		// Pattern with wildcards - check prefix matching
-		// For example, "echo *" matches any command starting with "echo "
-		//
-		// ワイルドカード付きパターン - プレフィックスマッチングをチェック
-		// 例えば、"echo *"は"echo "で始まる任意のコマンドにマッチ
-		if strings.Contains(pattern, "*") {
-			prefix := strings.Split(pattern, "*")[0]
-			if strings.HasPrefix(command, prefix) {
-				return true
+// forbiddenFlagHint explains why a command that an exec_whitelist entry would otherwise
+// allow is denied: it uses one of the entry's forbidden flags. It is "" when no entry
+// forbids a flag the command uses.
+//
+// forbiddenFlagHintは、exec_whitelistのエントリが本来許可するコマンドが拒否される理由
+// （エントリの禁止フラグのいずれかを使用している）を説明します。コマンドが使用するフラグを
+// 禁止するエントリがない場合は""です。
+func (p *Policy) forbiddenFlagHint(containerName string, args []string) string {
+	for _, key := range append(p.containerAliases(containerName), "*") {
+		for _, entry := range p.config.ExecWhitelist[key] {
+			rule := p.execRules[key][entry]
+			if rule == nil || !rule.MatchPattern(args) {
+				continue
+			}
+			if flag := rule.ForbiddenFlag(args); flag != "" {
+				return fmt.Sprintf("flag %s is forbidden by exec_whitelist entry %q", flag, entry)
 			}
 		}
 	}
-
-	return false
+	return ""
 }
 
 // GetMode returns the current security mode (strict/moderate/permissive).
@@ -322,9 +454,7 @@ func (p *Policy) GetAllowedCommands(containerName string) []string {
 
 	// Add container-specific commands
 	// コンテナ固有のコマンドを追加
-	if whitelist, ok := p.config.ExecWhitelist[containerName]; ok {
-		commands = append(commands, whitelist...)
-	}
+	commands = append(commands, p.containerEntries(p.config.ExecWhitelist, containerName)...)
 
 	// Add default commands (available to all containers)
 	// デフォルトコマンドを追加（全コンテナで利用可能）
@@ -341,18 +471,37 @@ func (p *Policy) GetAllowedCommands(containerName string) []string {
 // GetSecurityPolicyは現在のセキュリティポリシー設定をマップとして返します。
 // これはMCPのget_security_policyツール経由でポリシーを公開するのに便利です。
 func (p *Policy) GetSecurityPolicy() map[string]any {
-	return map[string]any{
+	policy := map[string]any{
 		"mode":               p.config.Mode,
 		"allowed_containers": p.config.AllowedContainers,
-		"permissions": map[string]bool{
-			"logs":      p.config.Permissions.Logs,
-			"inspect":   p.config.Permissions.Inspect,
-			"stats":     p.config.Permissions.Stats,
-			"exec":      p.config.Permissions.Exec,
-			"lifecycle": p.config.Permissions.Lifecycle,
-		},
+		"allowed_labels":     p.config.AllowedLabels,
+		"denied_labels":      p.config.DeniedLabels,
+		"permissions":    permissionsMap(p.config.Permissions),
 		"exec_whitelist": p.config.ExecWhitelist,
 	}
+	if len(p.config.ExecForbiddenFlags) > 0 {
+		policy["exec_forbidden_flags"] = p.config.ExecForbiddenFlags
+	}
+	if len(p.config.WritablePaths) > 0 {
+		policy["writable_paths"] = p.config.WritablePaths
+	}
+	if len(p.config.ExecOptions) > 0 {
+		policy["exec_options"] = p.AllExecOptions()
+	}
+
+	// Show the per-container overrides and the permissions they result in
+	// コンテナごとの上書きとその結果の権限を表示
+	if len(p.config.ContainerPermissions) > 0 {
+		policy["container_permissions"] = p.config.ContainerPermissions
+	}
+	policy["effective_permissions"] = p.PermissionMatrix()
+
+	// Show how label rules apply to the containers currently on the host
+	// ラベルのルールが現在ホスト上にあるコンテナにどう適用されるかを表示
+	if len(p.config.AllowedLabels) > 0 || len(p.config.DeniedLabels) > 0 {
+		policy["container_access"] = p.labelAccessSummary()
+	}
+	return policy
 }
 
 // GetAllContainersWithCommands returns all containers that have whitelisted commands.
@@ -406,6 +555,20 @@ func (p *Policy) MaskExec(output string) string {
 	return p.outputMasker.MaskExec(output)
 }
 
+// MaskOutput masks sensitive data in file content, such as lines matched by grep_files.
+// It applies whenever output masking is enabled, regardless of apply_to.
+// Returns the original string if masking is disabled or masker is not initialized.
+//
+// MaskOutputはgrep_filesがマッチした行などのファイル内容内の機密データをマスクします。
+// apply_toに関係なく、出力マスキングが有効な場合に常に適用されます。
+// マスキングが無効またはマスカーが初期化されていない場合は元の文字列を返します。
+func (p *Policy) MaskOutput(output string) string {
+	if p.outputMasker == nil {
+		return output
+	}
+	return p.outputMasker.MaskOutput(output)
+}
+
 // MaskInspect masks sensitive data in container inspection output.
 // Returns the original string if masking is disabled or masker is not initialized.
 //
@@ -444,7 +607,7 @@ func (p *Policy) GetOutputMaskingStatus() map[string]any {
 //
 // Security checks performed:
 //  1. Is exec_dangerously globally enabled?
-//  2. Is exec globally enabled?
+//  2. Is exec enabled for the container?
 //  3. Is the container accessible?
 //  4. Is the base command in exec_dangerously.commands list?
 //  5. Are there any pipes or redirects? (forbidden)
@@ -457,7 +620,7 @@ func (p *Policy) GetOutputMaskingStatus() map[string]any {
 //
 // 実行されるセキュリティチェック:
 //  1. exec_dangerouslyがグローバルに有効か？
-//  2. execがグローバルに有効か？
+//  2. コンテナでexecが有効か？
 //  3. コンテナにアクセス可能か？
 //  4. ベースコマンドがexec_dangerously.commandsリストにあるか？
 //  5. パイプやリダイレクトがあるか？（禁止）
@@ -470,16 +633,16 @@ func (p *Policy) CanExecDangerously(containerName string, command string) (bool,
 		return false, fmt.Errorf("dangerous mode is not enabled in security policy")
 	}
 
-	// Check if exec is globally enabled
-	// execがグローバルに有効かチェック
-	if !p.config.Permissions.Exec {
-		return false, fmt.Errorf("exec is disabled in security policy")
+	// Check if exec is enabled for the container
+	// コンテナでexecが有効かチェック
+	if !p.EffectivePermissions(containerName).Exec {
+		return false, p.disabledError("exec is", p.config.Permissions.Exec, containerName)
 	}
 
 	// Check if container is accessible
 	// コンテナにアクセス可能かチェック
-	if !p.CanAccessContainer(containerName) {
-		return false, fmt.Errorf("container not in allowed list: %s", containerName)
+	if err := p.CheckContainerAccess(containerName); err != nil {
+		return false, err
 	}
 
 	// In strict mode, dangerous exec is never allowed
@@ -502,6 +665,12 @@ func (p *Policy) CanExecDangerously(containerName string, command string) (bool,
 		return false, fmt.Errorf("path traversal (..) is not allowed in dangerous mode")
 	}
 
+	// The command must parse the same way it will be executed
+	// コマンドは実行時と同じように解析できなければならない
+	if _, err := ParseCommand(command); err != nil {
+		return false, err
+	}
+
 	// Extract base command name
 	// ベースコマンド名を抽出
 	baseCommand := extractBaseCommand(command)
@@ -527,6 +696,38 @@ func (p *Policy) CanExecDangerously(containerName string, command string) (bool,
 	return true, nil
 }
 
+// AuthorizeExec runs the exec_command checks (CanExec, or CanExecDangerously when
+// dangerously is set) and returns the argv to execute. The argv is the one the checks
+// were made against, so callers must execute it rather than re-parse the command.
+//
+// AuthorizeExecはexec_commandのチェック（CanExec、dangerouslyが設定されている場合は
+// CanExecDangerously）を実行し、実行するargvを返します。このargvはチェック対象と
+// 同じものなので、呼び出し元はコマンドを再解析せずにこれを実行する必要があります。
+func (p *Policy) AuthorizeExec(containerName string, command string, dangerously bool) ([]string, error) {
+	var allowed bool
+	var err error
+	if dangerously {
+		allowed, err = p.CanExecDangerously(containerName, command)
+	} else {
+		allowed, err = p.CanExec(containerName, command)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf("exec permission denied")
+	}
+
+	args, err := ParseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
+
 // isDangerousCommandAvailable checks if a command could be executed in dangerous mode.
 // This is used to provide helpful hints when a command is not whitelisted.
 // It extracts the base command and checks if it's in the exec_dangerously list.
@@ -537,11 +738,11 @@ func (p *Policy) CanExecDangerously(containerName string, command string) (bool,
 func (p *Policy) isDangerousCommandAvailable(containerName string, command string) bool {
 	// Extract base command (first word)
 	// ベースコマンド（最初の単語）を抽出
-	baseCommand := strings.Fields(command)
-	if len(baseCommand) == 0 {
+	baseCommand := extractBaseCommand(command)
+	if baseCommand == "" {
 		return false
 	}
-	return p.isDangerousCommandAllowed(containerName, baseCommand[0])
+	return p.isDangerousCommandAllowed(containerName, baseCommand)
 }
 
 // isDangerousCommandAllowed checks if a base command is in the exec_dangerously list.
@@ -550,138 +751,7 @@ func (p *Policy) isDangerousCommandAvailable(containerName string, command strin
 // isDangerousCommandAllowedはベースコマンドがexec_dangerouslyリストにあるかチェックします。
 // まずコンテナ固有のリストをチェックし、次にグローバルリスト（*）にフォールバックします。
 func (p *Policy) isDangerousCommandAllowed(containerName string, baseCommand string) bool {
-	// Check container-specific list first
-	// まずコンテナ固有のリストをチェック
-	if commands, ok := p.config.ExecDangerously.Commands[containerName]; ok {
-		for _, cmd := range commands {
-			if cmd == baseCommand {
-				return true
-			}
-		}
-	}
-
-	// Check global list (*)
-	// グローバルリスト（*）をチェック
-	if commands, ok := p.config.ExecDangerously.Commands["*"]; ok {
-		for _, cmd := range commands {
-			if cmd == baseCommand {
-				return true
-			}
-		}
-	}
-
-	return false
-}
-
-// parseCommandArgs parses a command string into individual arguments,
-// handling quoted strings and backslash escapes properly.
-// This provides more robust parsing than simple whitespace splitting.
-//
-// Supported syntax:
-//   - Unquoted arguments are split by whitespace
-//   - Single-quoted strings: 'arg with spaces' (content is literal)
-//   - Double-quoted strings: "arg with spaces" (content is literal)
-//   - Backslash escapes: arg\ with\ spaces (escapes the next character)
-//
-// parseCommandArgsはコマンド文字列を個々の引数に解析し、
-// 引用符で囲まれた文字列とバックスラッシュエスケープを適切に処理します。
-// これは単純な空白分割よりも堅牢な解析を提供します。
-//
-// サポートされる構文：
-//   - 引用符なしの引数は空白で分割される
-//   - シングルクォート文字列: 'arg with spaces'（内容はリテラル）
-//   - ダブルクォート文字列: "arg with spaces"（内容はリテラル）
-//   - バックスラッシュエスケープ: arg\ with\ spaces（次の文字をエスケープ）
-func parseCommandArgs(command string) []string {
-	command = strings.TrimSpace(command)
-	if command == "" {
-		return nil
-	}
-
-	var args []string
-	var current strings.Builder
-	inSingleQuote := false
-	inDoubleQuote := false
-	escaped := false
-	// Track if we're building an argument (needed for empty quoted strings like "")
-	// 引数を構築中かどうかを追跡（""のような空の引用符文字列に必要）
-	hasContent := false
-
-	for i := 0; i < len(command); i++ {
-		ch := command[i]
-
-		// Handle escape sequences
-		// エスケープシーケンスを処理
-		if escaped {
-			current.WriteByte(ch)
-			escaped = false
-			hasContent = true
-			continue
-		}
-
-		// Handle backslash escape (outside quotes or inside double quotes)
-		// バックスラッシュエスケープを処理（引用符外またはダブルクォート内）
-		if ch == '\\' && !inSingleQuote {
-			// In double quotes, only escape certain characters (", \, $, `)
-			// ダブルクォート内では特定の文字のみエスケープ（", \, $, `）
-			if inDoubleQuote {
-				if i+1 < len(command) {
-					next := command[i+1]
-					if next == '"' || next == '\\' || next == '$' || next == '`' {
-						escaped = true
-						continue
-					}
-				}
-				// If not a special escape, treat backslash as literal
-				// 特殊エスケープでない場合、バックスラッシュをリテラルとして扱う
-				current.WriteByte(ch)
-				hasContent = true
-				continue
-			}
-			escaped = true
-			continue
-		}
-
-		// Handle single quotes (not inside double quotes)
-		// シングルクォートを処理（ダブルクォート内ではない場合）
-		if ch == '\'' && !inDoubleQuote {
-			inSingleQuote = !inSingleQuote
-			hasContent = true // Even empty quotes count as content
-			continue
-		}
-
-		// Handle double quotes (not inside single quotes)
-		// ダブルクォートを処理（シングルクォート内ではない場合）
-		if ch == '"' && !inSingleQuote {
-			inDoubleQuote = !inDoubleQuote
-			hasContent = true // Even empty quotes count as content
-			continue
-		}
-
-		// Handle whitespace (argument separator when not in quotes)
-		// 空白を処理（引用符内でない場合は引数の区切り）
-		if (ch == ' ' || ch == '\t') && !inSingleQuote && !inDoubleQuote {
-			if hasContent {
-				args = append(args, current.String())
-				current.Reset()
-				hasContent = false
-			}
-			continue
-		}
-
-		// Regular character
-		// 通常の文字
-		current.WriteByte(ch)
-		hasContent = true
-	}
-
-	// Add the last argument if any
-	// 最後の引数があれば追加
-	if hasContent {
-		args = append(args, current.String())
-	}
-
-	return args
+	return p.dangerousRule(containerName, baseCommand) != nil
 }
 
 // extractBaseCommand extracts the base command name from a command string.
@@ -690,9 +760,9 @@ func parseCommandArgs(command string) []string {
 // extractBaseCommandはコマンド文字列からベースコマンド名を抽出します。
 // 例えば、"tail -f /var/log/app.log"は"tail"を返します。
 func extractBaseCommand(command string) string {
-	// Use the robust parser to handle quoted commands
-	// 引用符付きコマンドを処理するために堅牢なパーサーを使用
-	parts := parseCommandArgs(command)
+	// Use the same parser as execution to handle quoted commands
+	// 引用符付きコマンドを処理するために実行時と同じパーサーを使用
+	parts, _ := ParseCommand(command)
 	if len(parts) == 0 {
 		return ""
 	}
@@ -706,7 +776,7 @@ func extractBaseCommand(command string) string {
 //   - Arguments that don't start with "-" (options) and contain "/" (relative paths)
 //   - Arguments starting with "." (hidden files like .env, .gitignore)
 //
-// This function uses parseCommandArgs to properly handle quoted strings and escapes,
+// This function uses ParseCommand to properly handle quoted strings and escapes,
 // preventing bypass attempts like `cat '/app/.env'` or `cat /app/.e\ nv`.
 //
 // extractPathsFromCommandはコマンド文字列からファイルパスを抽出します。
@@ -715,12 +785,21 @@ func extractBaseCommand(command string) string {
 //   - "-" で始まらず（オプション）、"/" を含む引数（相対パス）
 //   - "." で始まる引数（.envや.gitignoreなどの隠しファイル）
 //
-// この関数はparseCommandArgsを使用して引用符で囲まれた文字列とエスケープを
+// この関数はParseCommandを使用して引用符で囲まれた文字列とエスケープを
 // 適切に処理し、`cat '/app/.env'`や`cat /app/.e\ nv`のようなバイパス試行を防ぎます。
 func extractPathsFromCommand(command string) []string {
-	// Use the robust parser to handle quoted paths and escapes
-	// 引用符付きパスとエスケープを処理するために堅牢なパーサーを使用
-	parts := parseCommandArgs(command)
+	// Use the same parser as execution to handle quoted paths and escapes
+	// 引用符付きパスとエスケープを処理するために実行時と同じパーサーを使用
+	parts, _ := ParseCommand(command)
+	return CommandPaths(parts)
+}
+
+// CommandPaths returns the arguments of an argv (excluding the command name) that look
+// like file paths, using the same rules as extractPathsFromCommand.
+//
+// CommandPathsはargv（コマンド名を除く）のうちファイルパスのように見える引数を、
+// extractPathsFromCommandと同じ規則で返します。
+func CommandPaths(parts []string) []string {
 	if len(parts) <= 1 {
 		return nil
 	}
@@ -753,6 +832,25 @@ func extractPathsFromCommand(command string) []string {
 	return paths
 }
 
+// CommandOperands returns every argument of an argv (excluding the command name) that is
+// not an option. Any of them may name a file relative to the working directory, so the
+// check after resolving paths in the container looks at all of them, not only those
+// CommandPaths recognizes as paths (`cat key.pem` run in /secrets reads /secrets/key.pem).
+//
+// CommandOperandsはargv（コマンド名を除く）のうちオプションでないすべての引数を返します。
+// いずれも作業ディレクトリからの相対パスでファイルを指す可能性があるため、コンテナ内での
+// パス解決後のチェックはCommandPathsがパスと認識するものだけでなく、そのすべてを対象にします
+// （/secretsで実行される`cat key.pem`は/secrets/key.pemを読みます）。
+func CommandOperands(parts []string) []string {
+	var operands []string
+	for i := 1; i < len(parts); i++ {
+		if !strings.HasPrefix(parts[i], "-") {
+			operands = append(operands, parts[i])
+		}
+	}
+	return operands
+}
+
 // GetDangerousCommandsForContainer returns the dangerous commands allowed for a container.
 // Includes both container-specific commands and global commands (*).
 //
@@ -763,9 +861,7 @@ func (p *Policy) GetDangerousCommandsForContainer(containerName string) []string
 
 	// Add container-specific commands
 	// コンテナ固有のコマンドを追加
-	if cmds, ok := p.config.ExecDangerously.Commands[containerName]; ok {
-		commands = append(commands, cmds...)
-	}
+	commands = append(commands, p.containerEntries(p.config.ExecDangerously.Commands, containerName)...)
 
 	// Add global commands
 	// グローバルコマンドを追加
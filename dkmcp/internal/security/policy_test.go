@@ -196,6 +196,59 @@ func TestCanExec_Whitelist(t *testing.T) {
 	}
 }
 
+// TestCanExec_WhitelistRules tests exec_whitelist entries with typed placeholders and
+// forbidden flags.
+//
+// TestCanExec_WhitelistRulesは型付きプレースホルダーと禁止フラグを持つexec_whitelistの
+// エントリをテストします。
+func TestCanExec_WhitelistRules(t *testing.T) {
+	policy := NewPolicy(&config.SecurityConfig{
+		Mode: "moderate",
+		ExecWhitelist: map[string][]string{
+			"demo-app": {
+				"curl -s http://localhost:<int:1024-65535>/health",
+				"tail -n <int:1-1000> <path:/app/logs>",
+				"npm run *",
+			},
+		},
+		ExecForbiddenFlags: map[string]map[string][]string{
+			"demo-app": {"npm run *": {"--inspect", "--prefix"}},
+		},
+		Permissions: config.SecurityPermissions{Exec: true},
+	})
+
+	tests := []struct {
+		name    string // Test case name / テストケース名
+		command string // Command to execute / 実行するコマンド
+		wantErr string // Expected error substring ("" = allowed) / 期待されるエラーの部分文字列
+	}{
+		{"port in range", "curl -s http://localhost:8080/health", ""},
+		{"port out of range", "curl -s http://localhost:22/health", "not whitelisted"},
+		{"other host", "curl -s http://example.com:8080/health", "not whitelisted"},
+		{"log under prefix", "tail -n 200 /app/logs/api.log", ""},
+		{"path outside prefix", "tail -n 200 /app/config/.env", "not whitelisted"},
+		{"path traversal", "tail -n 200 /app/logs/../config/.env", "not whitelisted"},
+		{"script with allowed flags", "npm run test -- --watch", ""},
+		{"forbidden flag", "npm run test --inspect", "flag --inspect is forbidden by exec_whitelist entry"},
+		{"forbidden flag with value", "npm run build --prefix=/tmp", "flag --prefix is forbidden"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := policy.CanExec("demo-app", tt.command)
+			if tt.wantErr == "" {
+				if !allowed || err != nil {
+					t.Errorf("CanExec(%q) = %v, %v, want allowed", tt.command, allowed, err)
+				}
+				return
+			}
+			if allowed || err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CanExec(%q) = %v, %v, want error %q", tt.command, allowed, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // TestCanExec_NoWhitelistForContainer tests that containers without whitelists cannot exec.
 // Even if the container is in the allowed list, it needs an explicit exec whitelist.
 //
@@ -300,7 +353,7 @@ func TestCanLogs_Permission(t *testing.T) {
 
 			policy := NewPolicy(cfg)
 
-			allowed := policy.CanGetLogs()
+			allowed := policy.CanGetLogs("test-container")
 			if allowed != tt.want {
 				t.Errorf("CanGetLogs() = %v, want %v", allowed, tt.want)
 			}
@@ -332,7 +385,7 @@ func TestCanInspect_Permission(t *testing.T) {
 
 			policy := NewPolicy(cfg)
 
-			allowed := policy.CanInspect()
+			allowed := policy.CanInspect("test-container")
 			if allowed != tt.want {
 				t.Errorf("CanInspect() = %v, want %v", allowed, tt.want)
 			}
@@ -364,7 +417,7 @@ func TestCanStats_Permission(t *testing.T) {
 
 			policy := NewPolicy(cfg)
 
-			allowed := policy.CanGetStats()
+			allowed := policy.CanGetStats("test-container")
 			if allowed != tt.want {
 				t.Errorf("CanGetStats() = %v, want %v", allowed, tt.want)
 			}
@@ -475,17 +528,15 @@ func TestCanLifecycle_Permission(t *testing.T) {
 // TestIsCommandWhitelistedは内部のホワイトリストマッチングロジックをテストします。
 // 完全一致、ワイルドカードパターン、コンテナ固有とグローバルホワイトリストを検証します。
 func TestIsCommandWhitelisted(t *testing.T) {
-	policy := &Policy{
-		config: &config.SecurityConfig{
-			ExecWhitelist: map[string][]string{
-				"demo-app": {
-					"npm test",
-					"echo *",              // Wildcard pattern / ワイルドカードパターン
-					"pytest /app/tests",
-				},
+	policy := NewPolicy(&config.SecurityConfig{
+		ExecWhitelist: map[string][]string{
+			"demo-app": {
+				"npm test",
+				"echo *",            // Wildcard pattern / ワイルドカードパターン
+				"pytest /app/tests",
 			},
 		},
-	}
+	})
 
 	tests := []struct {
 		name      string // Test case name / テストケース名
@@ -1005,22 +1056,20 @@ func TestIsDangerousCommandAvailable(t *testing.T) {
 // TestIsCommandWhitelistedHintはホワイトリストにないコマンドが危険モードで
 // 利用可能な場合にエラーメッセージにヒントが含まれることをテストします。
 func TestIsCommandWhitelistedHint(t *testing.T) {
-	policy := &Policy{
-		config: &config.SecurityConfig{
-			Mode: "moderate",
-			AllowedContainers: []string{"demo-*"},
-			ExecWhitelist: map[string][]string{
-				"demo-app": {"npm test"},
-			},
-			ExecDangerously: config.ExecDangerouslyConfig{
-				Enabled: true,
-				Commands: map[string][]string{
-					"demo-app": {"tail", "cat"},
-					"*":        {"ls"},
-				},
+	policy := NewPolicy(&config.SecurityConfig{
+		Mode:              "moderate",
+		AllowedContainers: []string{"demo-*"},
+		ExecWhitelist: map[string][]string{
+			"demo-app": {"npm test"},
+		},
+		ExecDangerously: config.ExecDangerouslyConfig{
+			Enabled: true,
+			Commands: map[string][]string{
+				"demo-app": {"tail", "cat"},
+				"*":        {"ls"},
 			},
 		},
-	}
+	})
 
 	tests := []struct {
 		name        string // Test case name / テストケース名
@@ -1506,76 +1555,6 @@ func TestIsHostPathMaskingEnabled(t *testing.T) {
 	}
 }
 
-// TestParseCommandArgs tests the command argument parsing with quotes and escapes.
-// TestParseCommandArgsは引用符とエスケープを使用したコマンド引数解析をテストします。
-func TestParseCommandArgs(t *testing.T) {
-	tests := []struct {
-		name    string   // Test case name / テストケース名
-		command string   // Input command / 入力コマンド
-		want    []string // Expected arguments / 期待される引数
-	}{
-		// Simple cases / シンプルなケース
-		{"empty command", "", nil},
-		{"single word", "echo", []string{"echo"}},
-		{"simple command", "tail -f /var/log/app.log", []string{"tail", "-f", "/var/log/app.log"}},
-		{"multiple spaces", "cat   file.txt", []string{"cat", "file.txt"}},
-		{"leading/trailing spaces", "  npm test  ", []string{"npm", "test"}},
-
-		// Double quoted strings / ダブルクォート文字列
-		{"double quoted arg", `echo "hello world"`, []string{"echo", "hello world"}},
-		{"double quoted with spaces", `grep "error message" file`, []string{"grep", "error message", "file"}},
-		{"multiple double quoted", `echo "foo bar" "baz qux"`, []string{"echo", "foo bar", "baz qux"}},
-		{"empty double quoted", `echo "" file`, []string{"echo", "", "file"}},
-
-		// Single quoted strings / シングルクォート文字列
-		{"single quoted arg", `echo 'hello world'`, []string{"echo", "hello world"}},
-		{"single quoted with spaces", `grep 'error message' file`, []string{"grep", "error message", "file"}},
-		{"multiple single quoted", `echo 'foo bar' 'baz qux'`, []string{"echo", "foo bar", "baz qux"}},
-
-		// Mixed quotes / 混合クォート
-		{"mixed quotes", `echo 'single' "double"`, []string{"echo", "single", "double"}},
-		{"adjacent quoted", `echo "foo"'bar'`, []string{"echo", "foobar"}},
-
-		// Escaped characters / エスケープ文字
-		{"escaped space", `echo hello\ world`, []string{"echo", "hello world"}},
-		{"escaped backslash", `echo foo\\bar`, []string{"echo", `foo\bar`}},
-		{"escaped quote", `echo "hello \"world\""`, []string{"echo", `hello "world"`}},
-		{"escaped single in double", `echo "it's fine"`, []string{"echo", "it's fine"}},
-
-		// Paths with spaces / スペース付きパス
-		{"quoted path", `cat "/path/with spaces/file.txt"`, []string{"cat", "/path/with spaces/file.txt"}},
-		{"escaped path", `cat /path/with\ spaces/file.txt`, []string{"cat", "/path/with spaces/file.txt"}},
-
-		// Complex cases / 複雑なケース
-		{"json in quotes", `curl -d '{"key":"value"}'`, []string{"curl", "-d", `{"key":"value"}`}},
-		{"grep pattern", `grep "error\|warn" /var/log/app.log`, []string{"grep", `error\|warn`, "/var/log/app.log"}},
-		{"tar command", `tar -czvf "backup file.tar.gz" dir1 dir2`, []string{"tar", "-czvf", "backup file.tar.gz", "dir1", "dir2"}},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := parseCommandArgs(tt.command)
-
-			// Check length
-			// 長さをチェック
-			if len(got) != len(tt.want) {
-				t.Errorf("parseCommandArgs(%q) returned %d args, want %d: got %v, want %v",
-					tt.command, len(got), len(tt.want), got, tt.want)
-				return
-			}
-
-			// Check each argument
-			// 各引数をチェック
-			for i := range got {
-				if got[i] != tt.want[i] {
-					t.Errorf("parseCommandArgs(%q)[%d] = %q, want %q",
-						tt.command, i, got[i], tt.want[i])
-				}
-			}
-		})
-	}
-}
-
 // TestExtractPathsFromCommand_QuotedPaths tests path extraction with quoted paths.
 // TestExtractPathsFromCommand_QuotedPathsは引用符付きパスのパス抽出をテストします。
 func TestExtractPathsFromCommand_QuotedPaths(t *testing.T) {
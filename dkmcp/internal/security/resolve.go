@@ -0,0 +1,90 @@
+// resolve.go checks blocked_paths against every name a file is reachable by. A path the
+// AI passes may reach a blocked file through a symlink ("/app/link-to-env"), "..", the
+// /proc/<pid>/root view of the filesystem or another mount of the same host directory,
+// so the docker package resolves it first and the policy matches all of the results.
+//
+// resolve.goはファイルに到達できるすべての名前に対してblocked_pathsをチェックします。
+// AIが渡すパスは、シンボリックリンク（"/app/link-to-env"）、".."、/proc/<pid>/rootから見た
+// ファイルシステム、または同じホストディレクトリの別のマウントを通じてブロックされた
+// ファイルに到達し得るため、dockerパッケージが先にパスを解決し、ポリシーはその結果すべてを照合します。
+package security
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// PathResolution describes where a path requested in a container really leads.
+// PathResolutionはコンテナ内で要求されたパスが実際にどこに繋がるかを表します。
+type PathResolution struct {
+	// Requested is the path as passed by the client
+	// Requestedはクライアントが渡したままのパスです
+	Requested string
+
+	// Resolved is the real path in the container, with symlinks and ".." resolved
+	// Resolvedはシンボリックリンクと".."を解決したコンテナ内の実パスです
+	Resolved string
+
+	// HostPath is the host path backing Resolved, when it is inside a mount
+	// HostPathはResolvedがマウント内にある場合に、それを提供するホストのパスです
+	HostPath string
+
+	// Aliases are other container paths backed by the same host file
+	// Aliasesは同じホストファイルを提供する他のコンテナ内パスです
+	Aliases []string
+}
+
+// IsResolvedPathBlocked checks every name of a resolved path against the blocked paths:
+// the requested path, the resolved path, its aliases through other mounts and the host
+// path relative to the workspace root (which matches blocked paths imported from
+// workspace files such as "demo-apps/api/.env"). It returns the block and the name that
+// matched, or nil if none of them is blocked.
+//
+// IsResolvedPathBlockedは解決したパスのすべての名前をブロックパスに対してチェックします：
+// 要求されたパス、解決したパス、他のマウントを通じた別名、およびワークスペースルートからの
+// 相対ホストパス（"demo-apps/api/.env"のようにワークスペースのファイルから取り込んだ
+// ブロックパスに一致します）。ブロックとマッチした名前を返し、どれもブロックされていない
+// 場合はnilを返します。
+func (p *Policy) IsResolvedPathBlocked(containerName string, r PathResolution) (*BlockedPath, string) {
+	candidates := []string{r.Requested, r.Resolved}
+	candidates = append(candidates, r.Aliases...)
+	if rel := p.workspaceRelative(r.HostPath); rel != "" {
+		candidates = append(candidates, rel)
+	}
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if blocked := p.IsPathBlocked(containerName, candidate); blocked != nil {
+			return blocked, candidate
+		}
+	}
+	return nil, ""
+}
+
+// workspaceRelative returns hostPath relative to the auto-import workspace root, or ""
+// when it is empty or outside the workspace. Absolute host paths are never matched
+// directly, since patterns such as "/app/*" are meant for container paths.
+//
+// workspaceRelativeはhostPathを自動インポートのワークスペースルートからの相対パスで返し、
+// 空またはワークスペース外の場合は""を返します。"/app/*"のようなパターンはコンテナ内パス
+// 向けのため、絶対ホストパスを直接照合することはありません。
+func (p *Policy) workspaceRelative(hostPath string) string {
+	if !filepath.IsAbs(hostPath) {
+		return ""
+	}
+	root := p.config.BlockedPaths.AutoImport.WorkspaceRoot
+	if root == "" {
+		root = "."
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(root, hostPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return rel
+}
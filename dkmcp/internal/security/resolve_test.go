@@ -0,0 +1,88 @@
+// resolve_test.go contains tests for checking resolved paths against blocked paths.
+// resolve_test.goは解決したパスのブロックパスに対するチェックのテストを含みます。
+package security
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// TestIsResolvedPathBlocked tests that every name of a resolved path is checked.
+// TestIsResolvedPathBlockedは解決したパスのすべての名前がチェックされることをテストします。
+func TestIsResolvedPathBlocked(t *testing.T) {
+	workspace := t.TempDir()
+	policy := newComposePolicy(&config.SecurityConfig{
+		BlockedPaths: config.BlockedPathsConfig{
+			Manual: map[string][]string{
+				"api": {"/app/.env"},
+				"*":   {"demo-apps/api/secrets/*"},
+			},
+			AutoImport: config.AutoImportConfig{WorkspaceRoot: workspace},
+		},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatalf("InitBlockedPaths failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string         // Test case name / テストケース名
+		resolution  PathResolution // Resolved path / 解決したパス
+		wantMatched string         // Expected matching name ("" = allowed) / 期待されるマッチした名前
+	}{
+		{
+			name:        "requested path",
+			resolution:  PathResolution{Requested: "/app/.env", Resolved: "/app/.env"},
+			wantMatched: "/app/.env",
+		},
+		{
+			name:        "symlink to blocked file",
+			resolution:  PathResolution{Requested: "/app/link-to-env", Resolved: "/app/.env"},
+			wantMatched: "/app/.env",
+		},
+		{
+			name: "alias through another mount",
+			resolution: PathResolution{
+				Requested: "/workspace/api/.env",
+				Resolved:  "/workspace/api/.env",
+				HostPath:  "/srv/api/.env",
+				Aliases:   []string{"/app/.env"},
+			},
+			wantMatched: "/app/.env",
+		},
+		{
+			name: "host path inside the workspace",
+			resolution: PathResolution{
+				Requested: "/data/key.pem",
+				Resolved:  "/data/key.pem",
+				HostPath:  filepath.Join(workspace, "demo-apps/api/secrets/key.pem"),
+			},
+			wantMatched: "demo-apps/api/secrets/key.pem",
+		},
+		{
+			name: "host path outside the workspace",
+			resolution: PathResolution{
+				Requested: "/data/key.pem",
+				Resolved:  "/data/key.pem",
+				HostPath:  "/elsewhere/demo-apps/api/secrets/key.pem",
+			},
+		},
+		{
+			name:       "allowed file",
+			resolution: PathResolution{Requested: "/app/link", Resolved: "/app/package.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocked, matched := policy.IsResolvedPathBlocked("shop-api-1", tt.resolution)
+			if matched != tt.wantMatched {
+				t.Errorf("IsResolvedPathBlocked() matched %q, want %q", matched, tt.wantMatched)
+			}
+			if (blocked != nil) != (tt.wantMatched != "") {
+				t.Errorf("IsResolvedPathBlocked() = %+v, want blocked %v", blocked, tt.wantMatched != "")
+			}
+		})
+	}
+}
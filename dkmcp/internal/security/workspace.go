@@ -0,0 +1,107 @@
+// workspace.go confines the host side of copy_from_container and copy_to_container to the
+// host workspace. A host path is resolved the same way as a container path: symlinks and
+// ".." are followed before the check, so a link inside the workspace cannot lead a copy
+// to ~/.ssh or another directory outside it.
+//
+// workspace.goはcopy_from_containerとcopy_to_containerのホスト側をホストのワークスペースに
+// 限定します。ホストのパスはコンテナ内パスと同様に解決されます：チェックの前に
+// シンボリックリンクと".."をたどるため、ワークスペース内のリンクでコピーを~/.sshなど
+// ワークスペース外のディレクトリに向けることはできません。
+package security
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveWorkspacePath returns the real absolute path of requested, which is relative to
+// workspaceRoot or absolute, and fails unless it lies inside the workspace. The path need
+// not exist; its longest existing ancestor is resolved and the rest is appended.
+//
+// ResolveWorkspacePathはworkspaceRootからの相対パスまたは絶対パスであるrequestedの
+// 実際の絶対パスを返し、ワークスペース内にない場合は失敗します。パスは存在しなくても
+// 構いません。存在する最も長い祖先を解決し、残りを付け加えます。
+func ResolveWorkspacePath(workspaceRoot, requested string) (string, error) {
+	if workspaceRoot == "" {
+		return "", fmt.Errorf("host workspace is not configured (host_access.workspace_root or --workspace)")
+	}
+	if requested == "" {
+		return "", fmt.Errorf("host path is required")
+	}
+
+	root, err := filepath.Abs(workspaceRoot)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(root)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace root: %w", err)
+	}
+
+	target := requested
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(root, target)
+	}
+	resolved, err := evalExistingPrefix(filepath.Clean(target))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve host path %s: %w", requested, err)
+	}
+
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("host path %s is outside the workspace", requested)
+	}
+	return resolved, nil
+}
+
+// evalExistingPrefix resolves the symlinks in the longest existing ancestor of the clean
+// absolute path p and appends the components that do not exist yet.
+//
+// evalExistingPrefixはクリーンな絶対パスpの存在する最も長い祖先のシンボリックリンクを
+// 解決し、まだ存在しない要素を付け加えます。
+func evalExistingPrefix(p string) (string, error) {
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", err
+		}
+		// A dangling symlink must not be replaced by a new file at its target
+		// 壊れたシンボリックリンクをリンク先の新しいファイルで置き換えてはならない
+		if info, lerr := os.Lstat(p); lerr == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return "", fmt.Errorf("%s is a dangling symlink", p)
+		}
+		missing = append(missing, filepath.Base(p))
+		p = parent
+	}
+}
+
+// IsHostPathBlocked checks a host path against the blocked paths of a container through
+// its path relative to the auto-import workspace root, which matches blocked paths
+// imported from workspace files such as "demo-apps/api/.env". It returns nil for paths
+// outside that workspace.
+//
+// IsHostPathBlockedはホストのパスを、自動インポートのワークスペースルートからの相対パスを
+// 通じてコンテナのブロックパスに対してチェックします。これは"demo-apps/api/.env"のように
+// ワークスペースのファイルから取り込んだブロックパスに一致します。そのワークスペース外の
+// パスに対してはnilを返します。
+func (p *Policy) IsHostPathBlocked(containerName, hostPath string) *BlockedPath {
+	rel := p.workspaceRelative(hostPath)
+	if rel == "" {
+		return nil
+	}
+	return p.IsPathBlocked(containerName, filepath.ToSlash(rel))
+}
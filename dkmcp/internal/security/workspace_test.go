@@ -0,0 +1,118 @@
+// workspace_test.go contains tests for confining host paths to the workspace.
+// workspace_test.goはホストのパスをワークスペースに限定する処理のテストを含みます。
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// TestResolveWorkspacePath tests resolving host paths inside and outside the workspace.
+// TestResolveWorkspacePathはワークスペースの内外のホストパスの解決をテストします。
+func TestResolveWorkspacePath(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	workspace := filepath.Join(base, "workspace")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(workspace, "reports"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(workspace, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(workspace, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string // Test case name / テストケース名
+		root      string // Workspace root / ワークスペースルート
+		requested string // Requested host path / 要求されたホストパス
+		want      string // Expected path ("" = error) / 期待されるパス
+		wantErr   string // Expected error substring / 期待されるエラーの部分文字列
+	}{
+		{"relative existing dir", workspace, "reports", filepath.Join(workspace, "reports"), ""},
+		{"new nested file", workspace, "reports/heap/dump.hprof", filepath.Join(workspace, "reports/heap/dump.hprof"), ""},
+		{"absolute inside", workspace, filepath.Join(workspace, "reports/a.xml"), filepath.Join(workspace, "reports/a.xml"), ""},
+		{"dot dot escape", workspace, "../outside/a.txt", "", "outside the workspace"},
+		{"absolute outside", workspace, "/etc/passwd", "", "outside the workspace"},
+		{"symlink escape", workspace, "escape/a.txt", "", "outside the workspace"},
+		{"dangling symlink", workspace, "dangling", "", "dangling symlink"},
+		{"no workspace", "", "reports", "", "not configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveWorkspacePath(tt.root, tt.requested)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveWorkspacePath() = %q, %v, want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ResolveWorkspacePath() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsHostPathBlocked tests matching host paths through their workspace-relative path.
+// TestIsHostPathBlockedはワークスペースからの相対パスを通じたホストパスの照合をテストします。
+func TestIsHostPathBlocked(t *testing.T) {
+	workspace := t.TempDir()
+	policy := NewPolicy(&config.SecurityConfig{
+		BlockedPaths: config.BlockedPathsConfig{
+			Manual:     map[string][]string{"*": {"demo-apps/api/.env"}},
+			AutoImport: config.AutoImportConfig{WorkspaceRoot: workspace},
+		},
+	})
+	if err := policy.InitBlockedPaths(nil); err != nil {
+		t.Fatalf("InitBlockedPaths failed: %v", err)
+	}
+
+	if policy.IsHostPathBlocked("api", filepath.Join(workspace, "demo-apps/api/.env")) == nil {
+		t.Error("expected the workspace .env to be blocked")
+	}
+	if blocked := policy.IsHostPathBlocked("api", filepath.Join(workspace, "demo-apps/api/report.xml")); blocked != nil {
+		t.Errorf("expected report.xml to be allowed, got %+v", blocked)
+	}
+	if blocked := policy.IsHostPathBlocked("api", "/elsewhere/demo-apps/api/.env"); blocked != nil {
+		t.Errorf("expected a path outside the workspace to be ignored, got %+v", blocked)
+	}
+}
+
+// TestCanCopy tests the copy permission, its overrides and strict mode.
+// TestCanCopyはコピー権限、その上書き、strictモードをテストします。
+func TestCanCopy(t *testing.T) {
+	enabled := true
+	policy := newComposePolicy(&config.SecurityConfig{
+		Mode: "moderate",
+		ContainerPermissions: map[string]config.PermissionOverrides{
+			"api": {Copy: &enabled},
+		},
+	})
+
+	if ok, err := policy.CanCopy("shop-api-1"); !ok {
+		t.Errorf("expected copy allowed on shop-api-1 by override, got %v", err)
+	}
+	if ok, err := policy.CanCopy("shop-db-1"); ok || err == nil || !strings.Contains(err.Error(), "file copies are disabled") {
+		t.Errorf("expected copy denied on shop-db-1, got %v, %v", ok, err)
+	}
+
+	strict := NewPolicy(&config.SecurityConfig{
+		Mode:        "strict",
+		Permissions: config.SecurityPermissions{Copy: true},
+	})
+	if ok, err := strict.CanCopy("api"); ok || err == nil || !strings.Contains(err.Error(), "strict mode") {
+		t.Errorf("expected copy denied in strict mode, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,59 @@
+// writable.go decides which container paths write_file and apply_patch may modify.
+// Writes are denied unless the path is listed in writable_paths for the container, and
+// blocked paths stay unwritable even when listed, so a broad entry such as "/app" cannot
+// be used to replace a secret file.
+//
+// writable.goはwrite_fileとapply_patchが変更できるコンテナ内パスを決定します。
+// パスがコンテナのwritable_pathsに列挙されていない限り書き込みは拒否され、ブロックされた
+// パスは列挙されていても書き込めないため、"/app"のような広いエントリを使って
+// シークレットファイルを置き換えることはできません。
+package security
+
+import (
+	"path"
+	"strings"
+)
+
+// WritablePaths returns the writable_paths entries that apply to a container, including
+// the "*" entries shared by all containers.
+//
+// WritablePathsはコンテナに適用されるwritable_pathsのエントリを、すべてのコンテナで
+// 共有される"*"のエントリを含めて返します。
+func (p *Policy) WritablePaths(containerName string) []string {
+	paths := p.containerEntries(p.config.WritablePaths, containerName)
+	return append(paths, p.config.WritablePaths["*"]...)
+}
+
+// IsPathWritable reports whether the absolute container path target is covered by the
+// container's writable_paths, and returns the entry that allows it. It does not check
+// blocked paths; callers check them separately with IsResolvedPathBlocked.
+//
+// IsPathWritableは絶対パスのコンテナ内パスtargetがコンテナのwritable_pathsで
+// カバーされているかどうかを報告し、それを許可するエントリを返します。ブロックパスは
+// チェックしないため、呼び出し側がIsResolvedPathBlockedで別途チェックします。
+func (p *Policy) IsPathWritable(containerName, target string) (string, bool) {
+	target = path.Clean(target)
+	for _, entry := range p.WritablePaths(containerName) {
+		if writableEntryMatches(entry, target) {
+			return entry, true
+		}
+	}
+	return "", false
+}
+
+// writableEntryMatches reports whether a writable_paths entry covers target: a glob
+// pattern matches the path itself, and a directory covers everything below it.
+//
+// writableEntryMatchesはwritable_pathsのエントリがtargetをカバーするかどうかを報告します：
+// globパターンはパス自体にマッチし、ディレクトリはその配下すべてをカバーします。
+func writableEntryMatches(entry, target string) bool {
+	if strings.ContainsAny(entry, "*?[") {
+		matched, err := path.Match(entry, target)
+		return err == nil && matched
+	}
+	dir := path.Clean(entry)
+	if dir == "/" {
+		return true
+	}
+	return target == dir || strings.HasPrefix(target, dir+"/")
+}
@@ -0,0 +1,78 @@
+// writable_test.go contains tests for the write permission and writable_paths.
+// writable_test.goは書き込み権限とwritable_pathsのテストを含みます。
+package security
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/YujiSuzuki/ai-sandbox-dkmcp/dkmcp/internal/config"
+)
+
+// TestIsPathWritable tests matching paths against writable_paths entries.
+// TestIsPathWritableはwritable_pathsのエントリに対するパスの照合をテストします。
+func TestIsPathWritable(t *testing.T) {
+	policy := newComposePolicy(&config.SecurityConfig{
+		WritablePaths: map[string][]string{
+			"api":        {"/app/config", "/app/*.json"},
+			"shop-db-1":  {"/etc/postgresql/"},
+			"*":          {"/tmp"},
+			"other-name": {"/"},
+		},
+	})
+
+	tests := []struct {
+		name      string // Test case name / テストケース名
+		container string // Container name / コンテナ名
+		path      string // Path to write / 書き込むパス
+		wantEntry string // Expected allowing entry ("" = not writable) / 期待される許可したエントリ
+	}{
+		{"inside directory", "shop-api-1", "/app/config/app.yaml", "/app/config"},
+		{"nested inside directory", "shop-api-1", "/app/config/env/prod.yaml", "/app/config"},
+		{"directory prefix is not a parent", "shop-api-1", "/app/configuration.yaml", ""},
+		{"glob", "shop-api-2", "/app/package.json", "/app/*.json"},
+		{"glob does not cross directories", "shop-api-2", "/app/src/data.json", ""},
+		{"trailing slash", "shop-db-1", "/etc/postgresql/postgresql.conf", "/etc/postgresql/"},
+		{"global entry", "legacy", "/tmp/scratch.txt", "/tmp"},
+		{"other container's entry", "shop-db-1", "/app/config/app.yaml", ""},
+		{"dot dot is cleaned", "shop-api-1", "/app/config/../server.js", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := policy.IsPathWritable(tt.container, tt.path)
+			if entry != tt.wantEntry || ok != (tt.wantEntry != "") {
+				t.Errorf("IsPathWritable(%q, %q) = %q, %v, want %q", tt.container, tt.path, entry, ok, tt.wantEntry)
+			}
+		})
+	}
+}
+
+// TestCanWrite tests the write permission, its overrides and strict mode.
+// TestCanWriteは書き込み権限、その上書き、strictモードをテストします。
+func TestCanWrite(t *testing.T) {
+	enabled := true
+	policy := newComposePolicy(&config.SecurityConfig{
+		Mode:        "moderate",
+		Permissions: config.SecurityPermissions{Write: false},
+		ContainerPermissions: map[string]config.PermissionOverrides{
+			"api": {Write: &enabled},
+		},
+	})
+
+	if ok, err := policy.CanWrite("shop-api-1"); !ok {
+		t.Errorf("expected write allowed on shop-api-1 by override, got %v", err)
+	}
+	ok, err := policy.CanWrite("shop-db-1")
+	if ok || err == nil || !strings.Contains(err.Error(), "disabled in security policy") {
+		t.Errorf("expected write denied on shop-db-1, got %v, %v", ok, err)
+	}
+
+	strict := NewPolicy(&config.SecurityConfig{
+		Mode:        "strict",
+		Permissions: config.SecurityPermissions{Write: true},
+	})
+	if ok, err := strict.CanWrite("api"); ok || err == nil || !strings.Contains(err.Error(), "strict mode") {
+		t.Errorf("expected write denied in strict mode, got %v, %v", ok, err)
+	}
+}